@@ -111,7 +111,10 @@ const (
 	TargetUid          = "targetUid"
 	Campaign           = "campaign"
 	CampaignUid        = "campaignUid"
+	ParentActivation   = "parentActivation"
 	StagedTarget       = "staged_target"
+	// SyncOrigin labels catalogs synced from a parent site with the id of the parent
+	SyncOrigin = GroupPrefix + "/sync-origin"
 )
 
 // Environment variables keys
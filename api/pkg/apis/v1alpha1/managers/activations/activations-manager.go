@@ -32,6 +32,9 @@ var lock sync.Mutex
 
 var log = logger.NewLogger("coa.runtime")
 
+// ActivationFinishedTopic is the topic of the event published when an activation finishes
+const ActivationFinishedTopic = "activation-finished"
+
 type ActivationsManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
@@ -254,6 +257,7 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, name
 		return err
 	}
 
+	wasFinished := activationState.Status != nil && isActivationFinished(activationState.Status.Status)
 	current.UpdateTime = time.Now().Format(time.RFC3339) // TODO: is this correct? Shouldn't it be reported?
 	activationState.Status = &current
 	if activationState.ObjectMeta.Labels == nil {
@@ -281,9 +285,120 @@ func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, name
 	if err != nil {
 		return err
 	}
+	if !wasFinished && isActivationFinished(current.Status) {
+		t.publishFinished(ctx, activationState)
+	}
 	return nil
 }
 
+// ControlActivation sets the desired state of an activation to running, paused or cancelled. Cancelling an
+// activation also marks its status as Cancelled, which is final. Setting the state an activation already
+// has is a no-op, so the request can be repeated safely.
+func (t *ActivationsManager) ControlActivation(ctx context.Context, name string, namespace string, state string) (model.ActivationState, error) {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "ControlActivation",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	lock.Lock()
+	defer lock.Unlock()
+
+	log.InfofCtx(ctx, "ControlActivation for activation %s in namespace %s to %s", name, namespace, state)
+
+	if !model.IsValidActivationState(state) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid activation state %s", state), v1alpha2.BadRequest)
+		return model.ActivationState{}, err
+	}
+	if state == "" {
+		state = model.ActivationStateRunning
+	}
+
+	var activationState model.ActivationState
+	activationState, err = t.GetState(ctx, name, namespace)
+	if err != nil {
+		return model.ActivationState{}, err
+	}
+
+	current := activationState.Spec.State
+	if current == "" {
+		current = model.ActivationStateRunning
+	}
+	if current == state {
+		return activationState, nil
+	}
+	if current == model.ActivationStateCancelled {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is cancelled", name), v1alpha2.BadRequest)
+		return activationState, err
+	}
+	if isActivationFinished(activationState.Status.Status) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s has already finished", name), v1alpha2.BadRequest)
+		return activationState, err
+	}
+
+	activationState.Spec.State = state
+	if state == model.ActivationStateCancelled {
+		activationState.Status.Status = v1alpha2.Cancelled
+		activationState.Status.StatusMessage = v1alpha2.Cancelled.String()
+		activationState.Status.UpdateTime = time.Now().Format(time.RFC3339)
+		if activationState.ObjectMeta.Labels == nil {
+			activationState.ObjectMeta.Labels = make(map[string]string)
+		}
+		activationState.ObjectMeta.Labels[constants.StatusMessage] = utils.ConvertStringToValidLabel(v1alpha2.Cancelled.String())
+	}
+
+	var entry states.StateEntry
+	entry.ID = activationState.ObjectMeta.Name
+	entry.Body = activationState
+	entry.ETag = activationState.ObjectMeta.ETag
+
+	upsertRequest := states.UpsertRequest{
+		Value: entry,
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.WorkflowGroup,
+			"resource":  "activations",
+			"namespace": activationState.ObjectMeta.Namespace,
+			"kind":      "Activation",
+		},
+	}
+	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to update state in state store for activation %s in namespace %s: %v", name, namespace, err)
+		return activationState, err
+	}
+	if state == model.ActivationStateCancelled {
+		t.publishFinished(ctx, activationState)
+	}
+	return activationState, nil
+}
+
+// publishFinished publishes an activation-finished event once the status of an activation becomes final,
+// so that campaign-level notifications can be sent
+func (t *ActivationsManager) publishFinished(ctx context.Context, activationState model.ActivationState) {
+	if t.Context == nil {
+		return
+	}
+	log.InfofCtx(ctx, "Activation %s in namespace %s finished as %s", activationState.ObjectMeta.Name, activationState.ObjectMeta.Namespace, activationState.Status.StatusMessage)
+	err := t.Context.Publish(ActivationFinishedTopic, v1alpha2.Event{
+		Body:    activationState,
+		Context: ctx,
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to publish activation-finished event for activation %s in namespace %s: %v", activationState.ObjectMeta.Name, activationState.ObjectMeta.Namespace, err)
+	}
+}
+
+// isActivationFinished checks if an activation status is final. Failed stages end an activation as well.
+func isActivationFinished(status v1alpha2.State) bool {
+	switch status {
+	case 0, v1alpha2.OK, v1alpha2.Untouched, v1alpha2.Running, v1alpha2.Paused, v1alpha2.Delayed:
+		return false
+	default:
+		return true
+	}
+}
+
 func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string, namespace string, current model.StageStatus) error {
 	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
 		"method": "ReportStageStatus",
@@ -302,6 +417,7 @@ func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string,
 		return err
 	}
 
+	wasFinished := isActivationFinished(activationState.Status.Status)
 	activationState.Status.UpdateTime = time.Now().Format(time.RFC3339) // TODO: is this correct? Shouldn't it be reported?
 
 	err = mergeStageStatus(ctx, &activationState, current)
@@ -314,7 +430,9 @@ func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string,
 		activationState.ObjectMeta.Labels = make(map[string]string)
 	}
 	// label doesn't allow space, so remove space
-	activationState.ObjectMeta.Labels[constants.StatusMessage] = utils.ConvertStringToValidLabel(current.Status.String())
+	if current.Hook == "" {
+		activationState.ObjectMeta.Labels[constants.StatusMessage] = utils.ConvertStringToValidLabel(current.Status.String())
+	}
 
 	var entry states.StateEntry
 	entry.ID = activationState.ObjectMeta.Name
@@ -336,6 +454,9 @@ func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string,
 		log.ErrorfCtx(ctx, "Failed to update status in state store for activation %s in namespace %s: %v", name, namespace, err)
 		return err
 	}
+	if !wasFinished && isActivationFinished(activationState.Status.Status) {
+		t.publishFinished(ctx, activationState)
+	}
 	return nil
 }
 
@@ -367,9 +488,14 @@ func mergeStageStatus(ctx context.Context, activationState *model.ActivationStat
 			}
 		}
 
-		if len(activationState.Status.StageHistory) == 0 {
+		if index := findActiveBranchStage(activationState.Status.StageHistory, current); index >= 0 {
+			// A stage of a parallel branch finishes while other branches have reported since it started,
+			// move it to the end of the history so the history stays in report order
+			history := append(activationState.Status.StageHistory[:index:index], activationState.Status.StageHistory[index+1:]...)
+			activationState.Status.StageHistory = append(history, current)
+		} else if len(activationState.Status.StageHistory) == 0 {
 			activationState.Status.StageHistory = append(activationState.Status.StageHistory, current)
-		} else if activationState.Status.StageHistory[len(activationState.Status.StageHistory)-1].Stage != current.Stage {
+		} else if last := activationState.Status.StageHistory[len(activationState.Status.StageHistory)-1]; last.Stage != current.Stage || last.Branch != current.Branch || last.Hook != current.Hook {
 			if len(activationState.Status.StageHistory)+1 > activationHistorySize {
 				oldestStage := activationState.Status.StageHistory[0].Stage
 				activationState.Status.StageHistory = activationState.Status.StageHistory[1:]
@@ -427,8 +553,17 @@ func mergeStageStatus(ctx context.Context, activationState *model.ActivationStat
 		parentStageStatus.StatusMessage = parentStageStatus.Status.String()
 	}
 
+	if current.Hook != "" {
+		// Hook stages run once the activation is finished, they don't change its status
+		return nil
+	}
 	latestStage := &activationState.Status.StageHistory[len(activationState.Status.StageHistory)-1]
-	if latestStage.NextStage != "" {
+	if activationState.Spec != nil && activationState.Spec.State == model.ActivationStateCancelled {
+		// Cancelled is final, whatever the stage that was running reports afterwards
+		activationState.Status.Status = v1alpha2.Cancelled
+	} else if hasBranches(activationState.Status.StageHistory) {
+		activationState.Status.Status = getBranchesStatus(activationState.Status.StageHistory)
+	} else if latestStage.NextStage != "" {
 		activationState.Status.Status = v1alpha2.Running
 	} else {
 		activationState.Status.Status = latestStage.Status
@@ -437,6 +572,91 @@ func mergeStageStatus(ctx context.Context, activationState *model.ActivationStat
 	return nil
 }
 
+// findActiveBranchStage finds the active entry of the stage in the same branch when it isn't the latest entry
+func findActiveBranchStage(history []model.StageStatus, current model.StageStatus) int {
+	if current.Branch == "" && !hasBranches(history) {
+		return -1
+	}
+	for i := len(history) - 2; i >= 0; i-- {
+		if history[i].Stage == current.Stage && history[i].Branch == current.Branch && history[i].Hook == current.Hook && history[i].IsActive {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasBranches(history []model.StageStatus) bool {
+	for _, s := range history {
+		if s.Branch != "" || len(s.NextStages) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getBranchesStatus gets the activation status when stages run in parallel branches. A failed branch
+// fails the activation, otherwise the activation keeps running as long as a branch is running or has
+// a next stage that hasn't started yet.
+func getBranchesStatus(history []model.StageStatus) v1alpha2.State {
+	latest := make(map[string]int)
+	for i, s := range history {
+		latest[s.Branch] = i
+	}
+	failed := -1
+	paused := false
+	for _, i := range latest {
+		switch history[i].Status {
+		case v1alpha2.Done, v1alpha2.OK, v1alpha2.Running, v1alpha2.Delayed, v1alpha2.Untouched:
+		case v1alpha2.Paused:
+			paused = true
+		default:
+			if i > failed {
+				failed = i
+			}
+		}
+	}
+	if failed >= 0 {
+		return history[failed].Status
+	}
+	for i, s := range history {
+		// A join stage waiting for branches that never arrive doesn't keep the activation running
+		if s.IsActive && s.Status != v1alpha2.Delayed {
+			return v1alpha2.Running
+		}
+		nextStages := append([]string{}, s.NextStages...)
+		if s.NextStage != "" {
+			nextStages = append(nextStages, s.NextStage)
+		}
+		for _, next := range nextStages {
+			if !isNextStageStarted(history, i, next) {
+				return v1alpha2.Running
+			}
+		}
+	}
+	if paused {
+		return v1alpha2.Paused
+	}
+	return history[len(history)-1].Status
+}
+
+// isNextStageStarted checks if the next stage of the entry at index has reported after it. A join stage
+// in a parent branch that was triggered before the entry reported doesn't wait for it anymore.
+func isNextStageStarted(history []model.StageStatus, index int, next string) bool {
+	for i, s := range history {
+		if s.Stage == next && (i > index || (isParentBranch(s.Branch, history[index].Branch) && s.Status != v1alpha2.Delayed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isParentBranch(parent string, branch string) bool {
+	if parent == "" {
+		return branch != ""
+	}
+	return strings.HasPrefix(branch, parent+"/")
+}
+
 func (t *ActivationsManager) CampaignLookup(ctx context.Context, name string, namespace string) (interface{}, error) {
 	return states.GetObjectState(ctx, t.StateProvider, validation.Campaign, name, namespace)
 }
@@ -9,9 +9,12 @@ package activations
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -109,6 +112,44 @@ func TestUpdateStageStatus(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestUpdateStageStatusWithBranches(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertState(context.Background(), "test", model.ActivationState{Spec: &model.ActivationSpec{}})
+	assert.Nil(t, err)
+	report := func(status model.StageStatus, expected v1alpha2.State) {
+		status.StatusMessage = status.Status.String()
+		err := manager.ReportStageStatus(context.Background(), "test", "default", status)
+		assert.Nil(t, err)
+		state, err := manager.GetState(context.Background(), "test", "default")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, state.Status.Status, "after %s reported %s", status.Stage, status.Status)
+	}
+	report(model.StageStatus{Stage: "start", Status: v1alpha2.Done, NextStages: []string{"a", "b"}}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "a", Branch: "a", Status: v1alpha2.Running, IsActive: true}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "b", Branch: "b", Status: v1alpha2.Running, IsActive: true}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "a", Branch: "a", Status: v1alpha2.Done, NextStage: "join"}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "join", Status: v1alpha2.Delayed, IsActive: true}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "b", Branch: "b", Status: v1alpha2.Done, NextStage: "join"}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "join", Status: v1alpha2.Running, IsActive: true}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "join", Status: v1alpha2.Done}, v1alpha2.Done)
+
+	state, err := manager.GetState(context.Background(), "test", "default")
+	assert.Nil(t, err)
+	stages := make([]string, 0)
+	for _, s := range state.Status.StageHistory {
+		stages = append(stages, s.Branch+":"+s.Stage)
+	}
+	assert.Equal(t, []string{":start", "a:a", "b:b", ":join"}, stages)
+
+	// a failed branch fails the activation
+	report(model.StageStatus{Stage: "start", Status: v1alpha2.Done, NextStages: []string{"a", "b"}}, v1alpha2.Running)
+	report(model.StageStatus{Stage: "a", Branch: "a", Status: v1alpha2.InternalError}, v1alpha2.InternalError)
+}
+
 func TestUpdateStageStatusRemote(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
 	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
@@ -277,3 +318,158 @@ func TestUpdateActivationWithRunningStatus(t *testing.T) {
 	assert.Contains(t, err.Error(), "spec is immutable: stage doesn't match")
 }
 */
+
+func TestControlActivation(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertState(context.Background(), "test", model.ActivationState{Spec: &model.ActivationSpec{Campaign: "campaign"}})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "test1",
+		Status:        v1alpha2.Running,
+		StatusMessage: v1alpha2.Running.String(),
+	})
+	assert.Nil(t, err)
+
+	state, err := manager.ControlActivation(context.Background(), "test", "default", model.ActivationStatePaused)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationStatePaused, state.Spec.State)
+	state, err = manager.GetState(context.Background(), "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationStatePaused, state.Spec.State)
+	assert.Equal(t, "campaign", state.Spec.Campaign)
+	assert.Equal(t, 1, len(state.Status.StageHistory))
+
+	_, err = manager.ControlActivation(context.Background(), "test", "default", "stopped")
+	assert.NotNil(t, err)
+
+	state, err = manager.ControlActivation(context.Background(), "test", "default", model.ActivationStateCancelled)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+
+	// a stage that ends after the cancellation doesn't change the final state
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "test1",
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+	})
+	assert.Nil(t, err)
+	state, err = manager.GetState(context.Background(), "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.True(t, v1alpha2.Cancelled.EqualsWithString(state.Status.StatusMessage))
+
+	_, err = manager.ControlActivation(context.Background(), "test", "default", model.ActivationStateRunning)
+	assert.NotNil(t, err)
+	_, err = manager.ControlActivation(context.Background(), "test", "default", model.ActivationStateCancelled)
+	assert.Nil(t, err)
+}
+
+func TestControlFinishedActivation(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertState(context.Background(), "test", model.ActivationState{Spec: &model.ActivationSpec{}})
+	assert.Nil(t, err)
+	err = manager.ReportStatus(context.Background(), "test", "default", model.ActivationStatus{
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+	})
+	assert.Nil(t, err)
+	_, err = manager.ControlActivation(context.Background(), "test", "default", model.ActivationStatePaused)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
+
+func TestActivationFinishedEvent(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	pubSubProvider := &memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	manager.Context = &contexts.ManagerContext{
+		PubsubProvider: pubSubProvider,
+	}
+	finished := make(chan model.ActivationState, 2)
+	pubSubProvider.Subscribe(ActivationFinishedTopic, v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			finished <- event.Body.(model.ActivationState)
+			return nil
+		},
+	})
+
+	err := manager.UpsertState(context.Background(), "test", model.ActivationState{Spec: &model.ActivationSpec{Campaign: "campaign"}})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "test1",
+		NextStage:     "test2",
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "test2",
+		Status:        v1alpha2.InternalError,
+		StatusMessage: v1alpha2.InternalError.String(),
+		ErrorMessage:  "failed",
+	})
+	assert.Nil(t, err)
+	// reporting the final status again doesn't raise another event
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "test2",
+		Status:        v1alpha2.InternalError,
+		StatusMessage: v1alpha2.InternalError.String(),
+	})
+	assert.Nil(t, err)
+
+	select {
+	case activation := <-finished:
+		assert.Equal(t, "test", activation.ObjectMeta.Name)
+		assert.Equal(t, v1alpha2.InternalError, activation.Status.Status)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "activation-finished event is not published")
+	}
+	select {
+	case <-finished:
+		assert.Fail(t, "activation-finished event is published twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReportHookStageStatus(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	err := manager.UpsertState(context.Background(), "test", model.ActivationState{Spec: &model.ActivationSpec{Campaign: "campaign"}})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "deploy",
+		Status:        v1alpha2.InternalError,
+		StatusMessage: v1alpha2.InternalError.String(),
+		ErrorMessage:  "failed",
+	})
+	assert.Nil(t, err)
+	// a hook stage with the same name as the failed stage gets an entry of its own
+	err = manager.ReportStageStatus(context.Background(), "test", "default", model.StageStatus{
+		Stage:         "deploy",
+		Hook:          model.HookOnFailure,
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+	})
+	assert.Nil(t, err)
+
+	activation, err := manager.GetState(context.Background(), "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, activation.Status.Status)
+	assert.Equal(t, 2, len(activation.Status.StageHistory))
+	assert.Equal(t, model.HookOnFailure, activation.Status.StageHistory[1].Hook)
+}
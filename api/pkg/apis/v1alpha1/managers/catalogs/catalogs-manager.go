@@ -10,16 +10,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -34,6 +38,8 @@ type CatalogsManager struct {
 	GraphProvider    graph.IGraphProvider
 	needValidate     bool
 	CatalogValidator validation.CatalogValidator
+	// revisions keeps the history of catalog writes
+	revisions *revisionStore
 }
 
 func (s *CatalogsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -58,6 +64,45 @@ func (s *CatalogsManager) Init(context *contexts.VendorContext, config managers.
 		// s.CatalogValidator = validation.NewCatalogValidator(s.CatalogLookup, s.CatalogContainerLookup, s.ChildCatalogLookup)
 		s.CatalogValidator = validation.NewCatalogValidator(s.CatalogLookup, nil, s.ChildCatalogLookup)
 	}
+	return s.initRevisions(config, providers)
+}
+
+// initRevisions sets up the history of catalog writes, which is kept in the state provider named by
+// providers.revisionstate. Without one, the history is kept in memory and lost on restart. revisions.enabled
+// turns it off with false.
+func (s *CatalogsManager) initRevisions(config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	if enabled, ok := config.Properties["revisions.enabled"]; ok && enabled == "false" {
+		return nil
+	}
+	store := &revisionStore{}
+	if name, ok := config.Properties["providers.revisionstate"]; ok {
+		provider, ok := providers[name].(states.IStateProvider)
+		if !ok {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("revision state provider '%s' is not a state provider", name), v1alpha2.BadConfig)
+		}
+		store.provider = provider
+	} else {
+		provider := &memorystate.MemoryStateProvider{}
+		if err := provider.Init(memorystate.MemoryStateProviderConfig{}); err != nil {
+			return err
+		}
+		store.provider = provider
+	}
+	if v, ok := config.Properties["revisions.maxCount"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return v1alpha2.NewCOAError(err, "revisions.maxCount is not a positive number", v1alpha2.BadConfig)
+		}
+		store.maxCount = n
+	}
+	if v, ok := config.Properties["revisions.maxAge"]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return v1alpha2.NewCOAError(err, "revisions.maxAge is not a valid duration", v1alpha2.BadConfig)
+		}
+		store.maxAge = d
+	}
+	s.revisions = store
 	return nil
 }
 
@@ -110,6 +155,10 @@ func getCatalogState(body interface{}) (model.CatalogState, error) {
 }
 
 func (m *CatalogsManager) UpsertState(ctx context.Context, name string, state model.CatalogState) error {
+	return m.upsertState(ctx, name, state, model.CatalogRevisionUpsert, 0)
+}
+
+func (m *CatalogsManager) upsertState(ctx context.Context, name string, state model.CatalogState, operation string, restoredFrom int64) error {
 	ctx, span := observability.StartSpan("Catalogs Manager", ctx, &map[string]string{
 		"method": "UpsertState",
 	})
@@ -165,6 +214,11 @@ func (m *CatalogsManager) UpsertState(ctx context.Context, name string, state mo
 	if err != nil {
 		return err
 	}
+	var previous *model.CatalogState
+	if getStateErr == nil {
+		previous = &oldState
+	}
+	m.recordRevision(ctx, operation, name, state.ObjectMeta.Namespace, previous, &state, restoredFrom)
 	m.Context.Publish("catalog", v1alpha2.Event{
 		Metadata: map[string]string{
 			"objectType": state.Spec.CatalogType,
@@ -193,7 +247,11 @@ func (m *CatalogsManager) DeleteState(ctx context.Context, name string, namespac
 		}
 	}
 
-	//TODO: publish DELETE event
+	catalogType := ""
+	oldState, getErr := m.GetState(ctx, name, namespace)
+	if getErr == nil {
+		catalogType = oldState.Spec.CatalogType
+	}
 	err = m.StateProvider.Delete(ctx, states.DeleteRequest{
 		ID: name,
 		Metadata: map[string]interface{}{
@@ -204,7 +262,95 @@ func (m *CatalogsManager) DeleteState(ctx context.Context, name string, namespac
 			"kind":      "Catalog",
 		},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	if getErr == nil {
+		m.recordRevision(ctx, model.CatalogRevisionDelete, name, namespace, &oldState, nil, 0)
+	}
+	m.Context.Publish("catalog", v1alpha2.Event{
+		Metadata: map[string]string{
+			"objectType": catalogType,
+		},
+		Body: v1alpha2.JobData{
+			Id:     name,
+			Action: v1alpha2.JobDelete,
+			Body: model.CatalogState{
+				ObjectMeta: model.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+			},
+		},
+		Context: ctx,
+	})
+	return nil
+}
+
+// UpsertSyncedState writes a catalog synced from a parent site. The catalog is named with the parent as prefix
+// and labeled with the parent, so it can be found when the parent deletes it.
+func (m *CatalogsManager) UpsertSyncedState(ctx context.Context, origin string, catalog model.CatalogState) error {
+	name := syncedCatalogName(origin, catalog.ObjectMeta.Name)
+	catalog.ObjectMeta.Name = name
+	// The ETag is the version of the catalog on the parent, which means nothing here
+	catalog.ObjectMeta.ETag = ""
+	if catalog.ObjectMeta.Labels == nil {
+		catalog.ObjectMeta.Labels = make(map[string]string)
+	}
+	catalog.ObjectMeta.Labels[constants.SyncOrigin] = origin
+	if catalog.Spec == nil {
+		catalog.Spec = &model.CatalogSpec{}
+	}
+	catalog.Spec.RootResource = validation.GetRootResourceFromName(name)
+	if catalog.Spec.ParentName != "" {
+		catalog.Spec.ParentName = syncedCatalogName(origin, catalog.Spec.ParentName)
+	}
+	// the revisions of synced catalogs are authored by the site they came from
+	ctx = context.WithValue(ctx, v1alpha2.COAUserKey, "site:"+origin)
+	return m.UpsertState(ctx, name, catalog)
+}
+
+// DeleteSyncedState deletes a catalog synced from a parent site. Catalogs that are already gone are ignored.
+func (m *CatalogsManager) DeleteSyncedState(ctx context.Context, origin string, name string, namespace string) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+	ctx = context.WithValue(ctx, v1alpha2.COAUserKey, "site:"+origin)
+	err := m.DeleteState(ctx, syncedCatalogName(origin, name), namespace)
+	if err != nil && !utils.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// PruneSyncedStates deletes the catalogs synced from a parent site that aren't in a full sync from it
+func (m *CatalogsManager) PruneSyncedStates(ctx context.Context, origin string, namespace string, names []string) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[syncedCatalogName(origin, name)] = true
+	}
+	catalogs, err := m.ListState(ctx, namespace, "label", constants.SyncOrigin+"="+origin)
+	if err != nil {
+		return err
+	}
+	for _, catalog := range catalogs {
+		if keep[catalog.ObjectMeta.Name] {
+			continue
+		}
+		log.InfofCtx(ctx, " M (Catalogs): deleting catalog %s, which was deleted on site %s", catalog.ObjectMeta.Name, origin)
+		err = m.DeleteState(ctx, catalog.ObjectMeta.Name, namespace)
+		if err != nil && !utils.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncedCatalogName(origin string, name string) string {
+	return fmt.Sprintf("%s-%s", origin, name)
 }
 
 func (t *CatalogsManager) ListState(ctx context.Context, namespace string, filterType string, filterValue string) ([]model.CatalogState, error) {
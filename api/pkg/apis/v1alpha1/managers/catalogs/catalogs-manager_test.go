@@ -217,7 +217,7 @@ func TestDelete(t *testing.T) {
 			err := json.Unmarshal(jData, &job)
 			assert.Nil(t, err)
 			assert.Equal(t, "catalog", event.Metadata["objectType"])
-			assert.Equal(t, "name1", job.Id)
+			assert.Equal(t, catalogState.ObjectMeta.Name, job.Id)
 			assert.Equal(t, true, job.Action == v1alpha2.JobUpdate || job.Action == v1alpha2.JobDelete)
 			return nil
 		},
@@ -322,6 +322,78 @@ func TestSchemaCheck(t *testing.T) {
 	assert.True(t, strings.Contains(err.Error(), "email: property does not match pattern"))
 }
 
+func TestJsonSchemaCheck(t *testing.T) {
+	err := initalizeManager()
+	assert.Nil(t, err)
+	manager.CatalogValidator.CatalogContainerLookupFunc = nil
+	schemaCatalog := model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "DeviceSchema-v-version1",
+			Namespace: "default",
+		},
+		Spec: &model.CatalogSpec{
+			RootResource: "DeviceSchema",
+			CatalogType:  "schema",
+			Properties: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"network"},
+					"properties": map[string]interface{}{
+						"network": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"port":     map[string]interface{}{"type": "integer"},
+								"protocol": map[string]interface{}{"enum": []interface{}{"tcp", "udp"}, "default": "tcp"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	err = manager.UpsertState(context.Background(), schemaCatalog.ObjectMeta.Name, schemaCatalog)
+	assert.Nil(t, err)
+
+	catalog := model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "Device-v-version1",
+			Namespace: "default",
+		},
+		Spec: &model.CatalogSpec{
+			RootResource: "Device",
+			CatalogType:  "catalog",
+			Metadata: map[string]string{
+				"schema": "DeviceSchema:version1",
+			},
+			Properties: map[string]interface{}{
+				"network": map[string]interface{}{"port": "http"},
+			},
+		},
+	}
+	err = manager.UpsertState(context.Background(), catalog.ObjectMeta.Name, catalog)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "/network/port: Invalid type")
+
+	catalog.Spec.Properties = map[string]interface{}{
+		"network": map[string]interface{}{"port": 80},
+	}
+	err = manager.UpsertState(context.Background(), catalog.ObjectMeta.Name, catalog)
+	assert.Nil(t, err)
+	// the default of the schema is filled in
+	stored, err := manager.GetState(context.Background(), catalog.ObjectMeta.Name, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", stored.Spec.Properties["network"].(map[string]interface{})["protocol"])
+
+	schemaCatalog.ObjectMeta.Name = "BrokenSchema-v-version1"
+	schemaCatalog.Spec.RootResource = "BrokenSchema"
+	schemaCatalog.Spec.Properties = map[string]interface{}{
+		"spec": map[string]interface{}{"type": 12},
+	}
+	err = manager.UpsertState(context.Background(), schemaCatalog.ObjectMeta.Name, schemaCatalog)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid schema")
+}
+
 func TestParentCatalog(t *testing.T) {
 	err := initalizeManager()
 	assert.Nil(t, err)
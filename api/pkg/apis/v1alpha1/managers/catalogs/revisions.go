@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package catalogs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+const defaultRevisionMaxCount = 50
+
+// revisionStore keeps the revisions of each catalog in one state entry. The oldest revisions are dropped when
+// there are more than maxCount, or when they're older than maxAge, but the last revision is always kept.
+type revisionStore struct {
+	lock     sync.Mutex
+	provider states.IStateProvider
+	maxCount int
+	maxAge   time.Duration
+}
+
+type catalogHistory struct {
+	Revisions []model.CatalogRevision `json:"revisions"`
+}
+
+func (s *revisionStore) metadata(namespace string) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace": namespace,
+		"group":     model.FederationGroup,
+		"version":   "v1",
+		"resource":  "catalogrevisions",
+		"kind":      "CatalogRevision",
+	}
+}
+
+// entryID keeps the history apart from the catalog itself in state providers that key entries by id only
+func (s *revisionStore) entryID(name string) string {
+	return "r_" + name
+}
+
+func (s *revisionStore) get(ctx context.Context, name string, namespace string) (catalogHistory, error) {
+	entry, err := s.provider.Get(ctx, states.GetRequest{
+		ID:       s.entryID(name),
+		Metadata: s.metadata(namespace),
+	})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return catalogHistory{}, nil
+		}
+		return catalogHistory{}, err
+	}
+	var history catalogHistory
+	jData, _ := json.Marshal(entry.Body)
+	if err = json.Unmarshal(jData, &history); err != nil {
+		return catalogHistory{}, err
+	}
+	return history, nil
+}
+
+// record adds a revision after the last one of the catalog, then applies the retention
+func (s *revisionStore) record(ctx context.Context, revision model.CatalogRevision) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	history, err := s.get(ctx, revision.Name, revision.Namespace)
+	if err != nil {
+		return 0, err
+	}
+	revision.Revision = 1
+	if n := len(history.Revisions); n > 0 {
+		revision.Revision = history.Revisions[n-1].Revision + 1
+	}
+	history.Revisions = append(history.Revisions, revision)
+	history.Revisions = s.retain(history.Revisions)
+	_, err = s.provider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   s.entryID(revision.Name),
+			Body: history,
+		},
+		Metadata: s.metadata(revision.Namespace),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revision.Revision, nil
+}
+
+func (s *revisionStore) retain(revisions []model.CatalogRevision) []model.CatalogRevision {
+	maxCount := s.maxCount
+	if maxCount <= 0 {
+		maxCount = defaultRevisionMaxCount
+	}
+	if len(revisions) > maxCount {
+		revisions = revisions[len(revisions)-maxCount:]
+	}
+	if s.maxAge > 0 {
+		expiry := time.Now().Add(-s.maxAge)
+		for len(revisions) > 1 {
+			t, err := time.Parse(time.RFC3339, revisions[0].Timestamp)
+			if err != nil || !t.Before(expiry) {
+				break
+			}
+			revisions = revisions[1:]
+		}
+	}
+	return revisions
+}
+
+// recordRevision records a write to a catalog, with the changes from the catalog it replaced. Failing to record
+// a revision doesn't fail the write, which is already done.
+func (m *CatalogsManager) recordRevision(ctx context.Context, operation string, name string, namespace string, previous *model.CatalogState, current *model.CatalogState, restoredFrom int64) {
+	if m.revisions == nil {
+		return
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	revision := model.CatalogRevision{
+		Name:         name,
+		Namespace:    namespace,
+		Operation:    operation,
+		Author:       authorFromContext(ctx),
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		RestoredFrom: restoredFrom,
+	}
+	var oldSpec, newSpec *model.CatalogSpec
+	if previous != nil {
+		oldSpec = previous.Spec
+	}
+	if current != nil {
+		snapshot := copyCatalog(*current)
+		snapshot.ObjectMeta.ETag = ""
+		revision.Catalog = &snapshot
+		newSpec = snapshot.Spec
+	}
+	revision.Changes = diffSpecs(oldSpec, newSpec)
+	if _, err := m.revisions.record(ctx, revision); err != nil {
+		log.ErrorfCtx(ctx, " M (Catalogs): failed to record a revision of catalog %s: %v", name, err)
+	}
+}
+
+// ListRevisions gets the revisions of a catalog, newest first. Revisions in the list don't have the catalog
+// as it was written, which GetRevision gets.
+func (m *CatalogsManager) ListRevisions(ctx context.Context, name string, namespace string) ([]model.CatalogRevision, error) {
+	history, err := m.history(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]model.CatalogRevision, 0, len(history.Revisions))
+	for i := len(history.Revisions) - 1; i >= 0; i-- {
+		revision := history.Revisions[i]
+		revision.Catalog = nil
+		ret = append(ret, revision)
+	}
+	return ret, nil
+}
+
+// GetRevision gets a revision of a catalog. Revision 0 is the last one.
+func (m *CatalogsManager) GetRevision(ctx context.Context, name string, namespace string, revision int64) (model.CatalogRevision, error) {
+	history, err := m.history(ctx, name, namespace)
+	if err != nil {
+		return model.CatalogRevision{}, err
+	}
+	if revision == 0 {
+		return history.Revisions[len(history.Revisions)-1], nil
+	}
+	for _, r := range history.Revisions {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return model.CatalogRevision{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("revision %d of catalog '%s' is not found", revision, name), v1alpha2.NotFound)
+}
+
+// DiffRevisions gets the changes to the spec of a catalog from one revision to another. Revision 0 is the last
+// one.
+func (m *CatalogsManager) DiffRevisions(ctx context.Context, name string, namespace string, from int64, to int64) ([]model.CatalogRevisionChange, error) {
+	fromRevision, err := m.GetRevision(ctx, name, namespace, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := m.GetRevision(ctx, name, namespace, to)
+	if err != nil {
+		return nil, err
+	}
+	var oldSpec, newSpec *model.CatalogSpec
+	if fromRevision.Catalog != nil {
+		oldSpec = fromRevision.Catalog.Spec
+	}
+	if toRevision.Catalog != nil {
+		newSpec = toRevision.Catalog.Spec
+	}
+	return diffSpecs(oldSpec, newSpec), nil
+}
+
+// RestoreRevision writes a revision of a catalog again, as a new revision. The catalog is validated like any
+// other write.
+func (m *CatalogsManager) RestoreRevision(ctx context.Context, name string, namespace string, revision int64) error {
+	r, err := m.GetRevision(ctx, name, namespace, revision)
+	if err != nil {
+		return err
+	}
+	if r.Catalog == nil {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("revision %d of catalog '%s' is a deletion, which can't be restored", r.Revision, name), v1alpha2.BadRequest)
+	}
+	state := copyCatalog(*r.Catalog)
+	state.ObjectMeta.ETag = ""
+	return m.upsertState(ctx, name, state, model.CatalogRevisionRestore, r.Revision)
+}
+
+func (m *CatalogsManager) history(ctx context.Context, name string, namespace string) (catalogHistory, error) {
+	if m.revisions == nil {
+		return catalogHistory{}, v1alpha2.NewCOAError(nil, "catalog revisions are not enabled", v1alpha2.BadConfig)
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	history, err := m.revisions.get(ctx, name, namespace)
+	if err != nil {
+		return catalogHistory{}, err
+	}
+	if len(history.Revisions) == 0 {
+		return catalogHistory{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("catalog '%s' has no revisions", name), v1alpha2.NotFound)
+	}
+	return history, nil
+}
+
+// authorFromContext gets the user a request was authenticated as
+func authorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	user, _ := ctx.Value(v1alpha2.COAUserKey).(string)
+	return user
+}
+
+func copyCatalog(catalog model.CatalogState) model.CatalogState {
+	var ret model.CatalogState
+	jData, _ := json.Marshal(catalog)
+	_ = json.Unmarshal(jData, &ret)
+	return ret
+}
+
+// diffSpecs gets the changes from one catalog spec to another. Objects are compared key by key, and other
+// values, including arrays, as a whole.
+func diffSpecs(old *model.CatalogSpec, new *model.CatalogSpec) []model.CatalogRevisionChange {
+	changes := make([]model.CatalogRevisionChange, 0)
+	diffValues("", toJsonValue(old), toJsonValue(new), &changes)
+	return changes
+}
+
+func toJsonValue(spec *model.CatalogSpec) map[string]interface{} {
+	ret := make(map[string]interface{})
+	if spec == nil {
+		return ret
+	}
+	jData, _ := json.Marshal(spec)
+	_ = json.Unmarshal(jData, &ret)
+	return ret
+}
+
+func diffValues(path string, old interface{}, new interface{}, changes *[]model.CatalogRevisionChange) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if !reflect.DeepEqual(old, new) {
+			*changes = append(*changes, model.CatalogRevisionChange{Path: path, Op: "replace", Old: old, New: new})
+		}
+		return
+	}
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		childPath := path + "/" + strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1")
+		oldValue, inOld := oldMap[k]
+		newValue, inNew := newMap[k]
+		switch {
+		case !inOld:
+			*changes = append(*changes, model.CatalogRevisionChange{Path: childPath, Op: "add", New: newValue})
+		case !inNew:
+			*changes = append(*changes, model.CatalogRevisionChange{Path: childPath, Op: "remove", Old: oldValue})
+		default:
+			diffValues(childPath, oldValue, newValue, changes)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package catalogs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func configCatalog(properties map[string]interface{}) model.CatalogState {
+	return model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name: "config-v-version1",
+		},
+		Spec: &model.CatalogSpec{
+			CatalogType:  "config",
+			RootResource: "config",
+			Properties:   properties,
+		},
+	}
+}
+
+func TestCatalogRevisions(t *testing.T) {
+	err := initalizeManager()
+	assert.Nil(t, err)
+	ctx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "admin")
+
+	err = manager.UpsertState(ctx, "config-v-version1", configCatalog(map[string]interface{}{
+		"image": "app:v1",
+		"limits": map[string]interface{}{
+			"cpu": "1",
+		},
+	}))
+	assert.Nil(t, err)
+	err = manager.UpsertState(ctx, "config-v-version1", configCatalog(map[string]interface{}{
+		"image": "app:v2",
+		"limits": map[string]interface{}{
+			"memory": "1Gi",
+		},
+	}))
+	assert.Nil(t, err)
+
+	revisions, err := manager.ListRevisions(ctx, "config-v-version1", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, int64(2), revisions[0].Revision)
+	assert.Equal(t, model.CatalogRevisionUpsert, revisions[0].Operation)
+	assert.Equal(t, "admin", revisions[0].Author)
+	assert.Nil(t, revisions[0].Catalog)
+	assert.Equal(t, []model.CatalogRevisionChange{
+		{Path: "/properties/image", Op: "replace", Old: "app:v1", New: "app:v2"},
+		{Path: "/properties/limits/cpu", Op: "remove", Old: "1"},
+		{Path: "/properties/limits/memory", Op: "add", New: "1Gi"},
+	}, revisions[0].Changes)
+
+	changes, err := manager.DiffRevisions(ctx, "config-v-version1", "", 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(changes))
+	assert.Equal(t, model.CatalogRevisionChange{Path: "/properties/image", Op: "replace", Old: "app:v2", New: "app:v1"}, changes[0])
+
+	_, err = manager.GetRevision(ctx, "config-v-version1", "", 3)
+	assert.Equal(t, v1alpha2.NotFound, err.(v1alpha2.COAError).State)
+
+	err = manager.RestoreRevision(ctx, "config-v-version1", "", 1)
+	assert.Nil(t, err)
+	state, err := manager.GetState(ctx, "config-v-version1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "app:v1", state.Spec.Properties["image"])
+	latest, err := manager.GetRevision(ctx, "config-v-version1", "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), latest.Revision)
+	assert.Equal(t, model.CatalogRevisionRestore, latest.Operation)
+	assert.Equal(t, int64(1), latest.RestoredFrom)
+
+	err = manager.DeleteState(ctx, "config-v-version1", "default")
+	assert.Nil(t, err)
+	latest, err = manager.GetRevision(ctx, "config-v-version1", "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, model.CatalogRevisionDelete, latest.Operation)
+	assert.Nil(t, latest.Catalog)
+	err = manager.RestoreRevision(ctx, "config-v-version1", "", 0)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	// A deleted catalog can be brought back from an earlier revision
+	err = manager.RestoreRevision(ctx, "config-v-version1", "", 2)
+	assert.Nil(t, err)
+	state, err = manager.GetState(ctx, "config-v-version1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "app:v2", state.Spec.Properties["image"])
+}
+
+func TestCatalogRevisionRetention(t *testing.T) {
+	store := &revisionStore{maxCount: 2, maxAge: time.Hour}
+	now := time.Now().UTC()
+	revisions := []model.CatalogRevision{
+		{Revision: 1, Timestamp: now.Add(-3 * time.Hour).Format(time.RFC3339)},
+		{Revision: 2, Timestamp: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+		{Revision: 3, Timestamp: now.Format(time.RFC3339)},
+	}
+	retained := store.retain(revisions)
+	assert.Equal(t, 1, len(retained))
+	assert.Equal(t, int64(3), retained[0].Revision)
+
+	// The last revision is kept however old it is
+	retained = store.retain(revisions[:2])
+	assert.Equal(t, 1, len(retained))
+	assert.Equal(t, int64(2), retained[0].Revision)
+
+	store.maxAge = 0
+	retained = store.retain(revisions)
+	assert.Equal(t, []int64{2, 3}, []int64{retained[0].Revision, retained[1].Revision})
+}
+
+func TestCatalogRevisionsDisabled(t *testing.T) {
+	err := initalizeManager()
+	assert.Nil(t, err)
+	manager.revisions = nil
+	err = manager.UpsertState(context.Background(), "config-v-version1", configCatalog(map[string]interface{}{"image": "app:v1"}))
+	assert.Nil(t, err)
+	_, err = manager.ListRevisions(context.Background(), "config-v-version1", "")
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
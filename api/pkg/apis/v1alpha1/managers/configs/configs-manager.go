@@ -28,6 +28,8 @@ type ConfigsManager struct {
 	managers.Manager
 	ConfigProviders map[string]config.IConfigProvider
 	Precedence      []string
+	environments    *environmentChain
+	promotions      *promotionStore
 }
 
 func (s *ConfigsManager) Init(context *contexts.VendorContext, cfg managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -63,7 +65,7 @@ func (s *ConfigsManager) Init(context *contexts.VendorContext, cfg managers.Mana
 			return v1alpha2.NewCOAError(nil, "Precedence does not match with config providers", v1alpha2.BadConfig)
 		}
 	}
-	return nil
+	return s.initPromotions(cfg, providers)
 }
 
 func (s *ConfigsManager) Get(ctx context.Context, object string, field string, overlays []string, localContext interface{}) (interface{}, error) {
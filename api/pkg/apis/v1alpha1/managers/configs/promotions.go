@@ -0,0 +1,428 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package configs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/google/uuid"
+)
+
+const defaultEnvironmentFormat = "{object}-{environment}"
+
+// environmentChain is the ordered list of environments configs are promoted through. Each environment has its
+// own copy of a config object, which overlays the copies of the environments after it and the object itself.
+type environmentChain struct {
+	environments []string
+	approvals    map[string]bool
+	format       string
+}
+
+// promotionStore keeps the record of each promotion
+type promotionStore struct {
+	lock     sync.Mutex
+	provider states.IStateProvider
+}
+
+func (s *ConfigsManager) initPromotions(cfg managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	val, ok := cfg.Properties["environments"]
+	if !ok || val == "" {
+		return nil
+	}
+	chain := environmentChain{
+		approvals: make(map[string]bool),
+		format:    defaultEnvironmentFormat,
+	}
+	for _, environment := range strings.Split(val, ",") {
+		environment = strings.TrimSpace(environment)
+		if environment == "" || chain.index(environment) >= 0 {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("environment '%s' is empty or repeated", environment), v1alpha2.BadConfig)
+		}
+		chain.environments = append(chain.environments, environment)
+	}
+	if val, ok := cfg.Properties["environments.approval"]; ok && val != "" {
+		for _, environment := range strings.Split(val, ",") {
+			environment = strings.TrimSpace(environment)
+			if chain.index(environment) < 0 {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("environment '%s' that needs approval isn't in the environments", environment), v1alpha2.BadConfig)
+			}
+			chain.approvals[environment] = true
+		}
+	}
+	if val, ok := cfg.Properties["environments.format"]; ok {
+		if !strings.Contains(val, "{object}") || !strings.Contains(val, "{environment}") {
+			return v1alpha2.NewCOAError(nil, "environments.format needs both {object} and {environment}", v1alpha2.BadConfig)
+		}
+		chain.format = val
+	}
+	store := &promotionStore{}
+	if name, ok := cfg.Properties["providers.promotionstate"]; ok {
+		provider, ok := providers[name].(states.IStateProvider)
+		if !ok {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("promotion state provider '%s' is not a state provider", name), v1alpha2.BadConfig)
+		}
+		store.provider = provider
+	} else {
+		provider := &memorystate.MemoryStateProvider{}
+		if err := provider.Init(memorystate.MemoryStateProviderConfig{}); err != nil {
+			return err
+		}
+		store.provider = provider
+	}
+	s.environments = &chain
+	s.promotions = store
+	return nil
+}
+
+func (c *environmentChain) index(environment string) int {
+	for i, e := range c.environments {
+		if e == environment {
+			return i
+		}
+	}
+	return -1
+}
+
+// objectName gets the name of the copy of a config object in an environment
+func (c *environmentChain) objectName(object string, environment string) string {
+	name := strings.ReplaceAll(c.format, "{object}", object)
+	return strings.ReplaceAll(name, "{environment}", environment)
+}
+
+// Environments gets the environments configs are promoted through, in order
+func (s *ConfigsManager) Environments() []string {
+	if s.environments == nil {
+		return nil
+	}
+	return s.environments.environments
+}
+
+// GetEnvironmentObject gets the effective config of an object in an environment. The copy of the object in the
+// environment overlays the copies in the environments after it, which overlay the object itself. Copies that
+// don't exist are skipped.
+func (s *ConfigsManager) GetEnvironmentObject(ctx context.Context, object string, environment string, localContext interface{}) (map[string]interface{}, error) {
+	ctx, span := observability.StartSpan("Config Manager", ctx, &map[string]string{
+		"method": "GetEnvironmentObject",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugfCtx(ctx, " M (Config): GetEnvironmentObject %v in %s", object, environment)
+
+	var index int
+	index, err = s.environmentIndex(environment)
+	if err != nil {
+		return nil, err
+	}
+	base, err := s.GetObject(ctx, object, nil, localContext)
+	if err != nil && !utils.IsNotFound(err) {
+		return nil, err
+	}
+	ret := copyValues(base)
+	found := err == nil
+	err = nil
+	chain := s.environments.environments
+	for i := len(chain) - 1; i >= index; i-- {
+		layer, lErr := s.GetObject(ctx, s.environments.objectName(object, chain[i]), nil, localContext)
+		if lErr != nil {
+			if utils.IsNotFound(lErr) {
+				continue
+			}
+			err = lErr
+			return nil, err
+		}
+		found = true
+		for k, v := range layer {
+			ret[k] = v
+		}
+	}
+	if !found {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("config object '%s' is not found in environment '%s'", object, environment), v1alpha2.NotFound)
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PreviewPromotion works out a promotion without writing it. The values of the source environment's copy of the
+// object are written to the target environment's copy, which must be the next environment.
+func (s *ConfigsManager) PreviewPromotion(ctx context.Context, request model.ConfigPromotionRequest, localContext interface{}) (model.ConfigPromotion, error) {
+	ctx, span := observability.StartSpan("Config Manager", ctx, &map[string]string{
+		"method": "PreviewPromotion",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var promotion model.ConfigPromotion
+	promotion, err = s.previewPromotion(ctx, request, localContext)
+	return promotion, err
+}
+
+func (s *ConfigsManager) previewPromotion(ctx context.Context, request model.ConfigPromotionRequest, localContext interface{}) (model.ConfigPromotion, error) {
+	if request.Object == "" {
+		return model.ConfigPromotion{}, v1alpha2.NewCOAError(nil, "promotion object is not specified", v1alpha2.BadRequest)
+	}
+	sourceIndex, err := s.environmentIndex(request.Source)
+	if err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	targetIndex, err := s.environmentIndex(request.Target)
+	if err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	if targetIndex != sourceIndex+1 {
+		return model.ConfigPromotion{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("'%s' can only be promoted to the environment after it", request.Source), v1alpha2.BadRequest)
+	}
+	source, err := s.GetObject(ctx, s.environments.objectName(request.Object, request.Source), nil, localContext)
+	if err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	values := copyValues(source)
+	if len(request.Keys) > 0 {
+		all := values
+		values = make(map[string]interface{}, len(request.Keys))
+		for _, key := range request.Keys {
+			v, ok := all[key]
+			if !ok {
+				return model.ConfigPromotion{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("key '%s' is not found in environment '%s'", key, request.Source), v1alpha2.BadRequest)
+			}
+			values[key] = v
+		}
+	}
+	before, err := s.GetEnvironmentObject(ctx, request.Object, request.Target, localContext)
+	if err != nil && !utils.IsNotFound(err) {
+		return model.ConfigPromotion{}, err
+	}
+	after := make(map[string]interface{}, len(before)+len(values))
+	for k, v := range before {
+		after[k] = v
+	}
+	for k, v := range values {
+		after[k] = v
+	}
+	return model.ConfigPromotion{
+		ConfigPromotionRequest: request,
+		Values:                 values,
+		Changes:                utils.DiffProperties(before, after),
+	}, nil
+}
+
+// Promote promotes the values of a config object to the next environment. Promotions to environments that
+// need approval are kept pending until they're approved by someone other than who requested them.
+func (s *ConfigsManager) Promote(ctx context.Context, request model.ConfigPromotionRequest, localContext interface{}) (model.ConfigPromotion, error) {
+	ctx, span := observability.StartSpan("Config Manager", ctx, &map[string]string{
+		"method": "Promote",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Config): Promote %s from %s to %s", request.Object, request.Source, request.Target)
+
+	var promotion model.ConfigPromotion
+	promotion, err = s.previewPromotion(ctx, request, localContext)
+	if err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	promotion.Id = uuid.New().String()
+	promotion.RequestedBy = userFromContext(ctx)
+	promotion.RequestedAt = time.Now().UTC().Format(time.RFC3339)
+	if s.environments.approvals[request.Target] {
+		promotion.State = model.PromotionPending
+	} else {
+		s.applyPromotion(ctx, &promotion)
+	}
+	if err = s.promotions.upsert(ctx, promotion); err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	if promotion.State == model.PromotionFailed {
+		err = v1alpha2.NewCOAError(nil, promotion.Error, v1alpha2.InternalError)
+	}
+	return promotion, err
+}
+
+// ReviewPromotion approves or rejects a pending promotion. An approved promotion writes the values that were
+// reviewed, even if the source environment has changed since.
+func (s *ConfigsManager) ReviewPromotion(ctx context.Context, id string, approve bool, comment string) (model.ConfigPromotion, error) {
+	ctx, span := observability.StartSpan("Config Manager", ctx, &map[string]string{
+		"method": "ReviewPromotion",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Config): ReviewPromotion %s, approve: %t", id, approve)
+
+	if s.promotions == nil {
+		err = v1alpha2.NewCOAError(nil, "environments are not configured", v1alpha2.BadConfig)
+		return model.ConfigPromotion{}, err
+	}
+	s.promotions.lock.Lock()
+	defer s.promotions.lock.Unlock()
+	var promotion model.ConfigPromotion
+	promotion, err = s.promotions.get(ctx, id)
+	if err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	if promotion.State != model.PromotionPending {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("promotion '%s' is %s, not pending", id, promotion.State), v1alpha2.BadRequest)
+		return model.ConfigPromotion{}, err
+	}
+	reviewer := userFromContext(ctx)
+	if reviewer == "" || reviewer == promotion.RequestedBy {
+		err = v1alpha2.NewCOAError(nil, "a promotion needs to be reviewed by someone other than who requested it", v1alpha2.Forbidden)
+		return model.ConfigPromotion{}, err
+	}
+	promotion.ReviewedBy = reviewer
+	promotion.ReviewedAt = time.Now().UTC().Format(time.RFC3339)
+	promotion.Comment = comment
+	if approve {
+		s.applyPromotion(ctx, &promotion)
+	} else {
+		promotion.State = model.PromotionRejected
+	}
+	if err = s.promotions.upsert(ctx, promotion); err != nil {
+		return model.ConfigPromotion{}, err
+	}
+	if promotion.State == model.PromotionFailed {
+		err = v1alpha2.NewCOAError(nil, promotion.Error, v1alpha2.InternalError)
+	}
+	return promotion, err
+}
+
+// applyPromotion writes the values of a promotion to the target environment, and records the result
+func (s *ConfigsManager) applyPromotion(ctx context.Context, promotion *model.ConfigPromotion) {
+	target := s.environments.objectName(promotion.Object, promotion.Target)
+	keys := make([]string, 0, len(promotion.Values))
+	for k := range promotion.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := s.Set(ctx, target, k, promotion.Values[k]); err != nil {
+			log.ErrorfCtx(ctx, " M (Config): failed to promote %s to %s: %v", k, target, err)
+			promotion.State = model.PromotionFailed
+			promotion.Error = fmt.Sprintf("failed to write '%s' to '%s': %s", k, target, err.Error())
+			return
+		}
+	}
+	promotion.State = model.PromotionPromoted
+	promotion.PromotedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// GetPromotion gets the record of a promotion
+func (s *ConfigsManager) GetPromotion(ctx context.Context, id string) (model.ConfigPromotion, error) {
+	if s.promotions == nil {
+		return model.ConfigPromotion{}, v1alpha2.NewCOAError(nil, "environments are not configured", v1alpha2.BadConfig)
+	}
+	return s.promotions.get(ctx, id)
+}
+
+// ListPromotions gets the records of the promotions of an object, or of all objects, oldest first
+func (s *ConfigsManager) ListPromotions(ctx context.Context, object string) ([]model.ConfigPromotion, error) {
+	if s.promotions == nil {
+		return nil, v1alpha2.NewCOAError(nil, "environments are not configured", v1alpha2.BadConfig)
+	}
+	entries, _, err := s.promotions.provider.List(ctx, states.ListRequest{Metadata: promotionMetadata})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]model.ConfigPromotion, 0)
+	for _, entry := range entries {
+		promotion, err := toPromotion(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		if object == "" || promotion.Object == object {
+			ret = append(ret, promotion)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].RequestedAt < ret[j].RequestedAt
+	})
+	return ret, nil
+}
+
+func (s *ConfigsManager) environmentIndex(environment string) (int, error) {
+	if s.environments == nil {
+		return -1, v1alpha2.NewCOAError(nil, "environments are not configured", v1alpha2.BadConfig)
+	}
+	index := s.environments.index(environment)
+	if index < 0 {
+		return -1, v1alpha2.NewCOAError(nil, fmt.Sprintf("environment '%s' is not found", environment), v1alpha2.BadRequest)
+	}
+	return index, nil
+}
+
+var promotionMetadata = map[string]interface{}{
+	"version":  "v1",
+	"group":    model.FederationGroup,
+	"resource": "configpromotions",
+	"kind":     "ConfigPromotion",
+}
+
+func (s *promotionStore) get(ctx context.Context, id string) (model.ConfigPromotion, error) {
+	entry, err := s.provider.Get(ctx, states.GetRequest{
+		ID:       id,
+		Metadata: promotionMetadata,
+	})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return model.ConfigPromotion{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("promotion '%s' is not found", id), v1alpha2.NotFound)
+		}
+		return model.ConfigPromotion{}, err
+	}
+	return toPromotion(entry.Body)
+}
+
+func (s *promotionStore) upsert(ctx context.Context, promotion model.ConfigPromotion) error {
+	_, err := s.provider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   promotion.Id,
+			Body: promotion,
+		},
+		Metadata: promotionMetadata,
+	})
+	return err
+}
+
+// copyValues copies config values, so that they don't share maps with what the config providers hold
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(values))
+	if len(values) == 0 {
+		return ret
+	}
+	jData, _ := json.Marshal(values)
+	_ = json.Unmarshal(jData, &ret)
+	return ret
+}
+
+func toPromotion(body interface{}) (model.ConfigPromotion, error) {
+	var promotion model.ConfigPromotion
+	jData, _ := json.Marshal(body)
+	err := json.Unmarshal(jData, &promotion)
+	return promotion, err
+}
+
+// userFromContext gets the user a request was authenticated as
+func userFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(v1alpha2.COAUserKey).(string)
+	return user
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package configs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	memory "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/memoryconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPromotionManager(t *testing.T) (*ConfigsManager, *memory.MemoryConfigProvider) {
+	configProvider := &memory.MemoryConfigProvider{}
+	configProvider.Init(memory.MemoryConfigProviderConfig{})
+	manager := &ConfigsManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"environments":          "dev,staging,prod",
+			"environments.approval": "prod",
+		},
+	}, map[string]providers.IProvider{
+		"memory": configProvider,
+	})
+	assert.Nil(t, err)
+	configProvider.SetObject(context.Background(), "app", map[string]interface{}{"image": "app:v1", "replicas": 1})
+	configProvider.SetObject(context.Background(), "app-prod", map[string]interface{}{"replicas": 3})
+	configProvider.SetObject(context.Background(), "app-staging", map[string]interface{}{"endpoint": "staging.contoso.com"})
+	configProvider.SetObject(context.Background(), "app-dev", map[string]interface{}{"image": "app:v2", "endpoint": "dev.contoso.com"})
+	return manager, configProvider
+}
+
+func TestPromotionInit(t *testing.T) {
+	configProvider := &memory.MemoryConfigProvider{}
+	configProvider.Init(memory.MemoryConfigProviderConfig{})
+	for _, properties := range []map[string]string{
+		{"environments": "dev,dev"},
+		{"environments": "dev,prod", "environments.approval": "staging"},
+		{"environments": "dev,prod", "environments.format": "{object}"},
+		{"environments": "dev,prod", "providers.promotionstate": "memory"},
+	} {
+		manager := ConfigsManager{}
+		err := manager.Init(nil, managers.ManagerConfig{Properties: properties}, map[string]providers.IProvider{
+			"memory": configProvider,
+		})
+		assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	}
+
+	manager := ConfigsManager{}
+	err := manager.Init(nil, managers.ManagerConfig{}, map[string]providers.IProvider{"memory": configProvider})
+	assert.Nil(t, err)
+	assert.Nil(t, manager.Environments())
+	_, err = manager.GetEnvironmentObject(context.Background(), "app", "dev", nil)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestGetEnvironmentObject(t *testing.T) {
+	manager, _ := newPromotionManager(t)
+	assert.Equal(t, []string{"dev", "staging", "prod"}, manager.Environments())
+
+	dev, err := manager.GetEnvironmentObject(context.Background(), "app", "dev", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"image": "app:v2", "replicas": 3, "endpoint": "dev.contoso.com"}, dev)
+	prod, err := manager.GetEnvironmentObject(context.Background(), "app", "prod", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"image": "app:v1", "replicas": 3}, prod)
+
+	_, err = manager.GetEnvironmentObject(context.Background(), "app", "test", nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	_, err = manager.GetEnvironmentObject(context.Background(), "other", "dev", nil)
+	assert.Equal(t, v1alpha2.NotFound, err.(v1alpha2.COAError).State)
+}
+
+func TestPromote(t *testing.T) {
+	manager, configProvider := newPromotionManager(t)
+	ctx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "dev-user")
+
+	_, err := manager.PreviewPromotion(ctx, model.ConfigPromotionRequest{Object: "app", Source: "dev", Target: "prod"}, nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	_, err = manager.PreviewPromotion(ctx, model.ConfigPromotionRequest{Object: "app", Source: "dev", Target: "staging", Keys: []string{"tag"}}, nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	// The endpoint of dev stays in dev
+	request := model.ConfigPromotionRequest{Object: "app", Source: "dev", Target: "staging", Keys: []string{"image"}}
+	preview, err := manager.PreviewPromotion(ctx, request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.PropertyChange{{Path: "/image", Op: "replace", Old: "app:v1", New: "app:v2"}}, preview.Changes)
+	assert.Equal(t, "", preview.State)
+	_, ok := configProvider.ConfigData["app-staging"]["image"]
+	assert.False(t, ok)
+
+	promotion, err := manager.Promote(ctx, request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.PromotionPromoted, promotion.State)
+	assert.Equal(t, "dev-user", promotion.RequestedBy)
+	assert.Equal(t, "app:v2", configProvider.ConfigData["app-staging"]["image"])
+	assert.Equal(t, "staging.contoso.com", configProvider.ConfigData["app-staging"]["endpoint"])
+
+	_, err = manager.ReviewPromotion(ctx, promotion.Id, true, "")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	promotions, err := manager.ListPromotions(ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(promotions))
+	assert.Equal(t, promotion.Id, promotions[0].Id)
+}
+
+func TestPromoteWithApproval(t *testing.T) {
+	manager, configProvider := newPromotionManager(t)
+	ctx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "dev-user")
+	configProvider.Set(ctx, "app-staging", "image", "app:v2")
+
+	request := model.ConfigPromotionRequest{Object: "app", Source: "staging", Target: "prod"}
+	promotion, err := manager.Promote(ctx, request, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.PromotionPending, promotion.State)
+	assert.Equal(t, []model.PropertyChange{
+		{Path: "/endpoint", Op: "add", New: "staging.contoso.com"},
+		{Path: "/image", Op: "replace", Old: "app:v1", New: "app:v2"},
+	}, promotion.Changes)
+	_, ok := configProvider.ConfigData["app-prod"]["image"]
+	assert.False(t, ok)
+
+	// Promotions can't be approved by who requested them
+	_, err = manager.ReviewPromotion(ctx, promotion.Id, true, "")
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+	_, err = manager.ReviewPromotion(context.Background(), promotion.Id, true, "")
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+	_, err = manager.ReviewPromotion(ctx, "missing", true, "")
+	assert.Equal(t, v1alpha2.NotFound, err.(v1alpha2.COAError).State)
+
+	// The reviewed values are promoted, not what staging has now
+	configProvider.Set(ctx, "app-staging", "image", "app:v3")
+	approverCtx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "ops-user")
+	approved, err := manager.ReviewPromotion(approverCtx, promotion.Id, true, "looks good")
+	assert.Nil(t, err)
+	assert.Equal(t, model.PromotionPromoted, approved.State)
+	assert.Equal(t, "ops-user", approved.ReviewedBy)
+	assert.Equal(t, "looks good", approved.Comment)
+	assert.Equal(t, "app:v2", configProvider.ConfigData["app-prod"]["image"])
+
+	rejected, err := manager.Promote(ctx, request, nil)
+	assert.Nil(t, err)
+	rejected, err = manager.ReviewPromotion(approverCtx, rejected.Id, false, "not yet")
+	assert.Nil(t, err)
+	assert.Equal(t, model.PromotionRejected, rejected.State)
+	assert.Equal(t, "app:v2", configProvider.ConfigData["app-prod"]["image"])
+
+	promotions, err := manager.ListPromotions(ctx, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(promotions))
+	promotion, err = manager.GetPromotion(ctx, rejected.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "not yet", promotion.Comment)
+}
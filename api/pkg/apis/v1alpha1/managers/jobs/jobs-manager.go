@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
@@ -406,6 +407,101 @@ func (s *JobsManager) HandleScheduleEvent(ctx context.Context, event v1alpha2.Ev
 	}
 	return err
 }
+
+// HandleSecretEvent publishes an update job for each instance and target in the namespace of a secret that
+// references the secret with $secret(), so that they pick up the secret after it's written or deleted.
+func (s *JobsManager) HandleSecretEvent(ctx context.Context, event v1alpha2.Event) error {
+	ctx, span := observability.StartSpan("Job Manager", ctx, &map[string]string{
+		"method": "HandleSecretEvent",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var job v1alpha2.JobData
+	jData, _ := json.Marshal(event.Body)
+	err = json.Unmarshal(jData, &job)
+	if err != nil || job.Id == "" {
+		log.ErrorfCtx(ctx, " M (Job): secret event body is not a job: %v", event.Body)
+		err = v1alpha2.NewCOAError(nil, "event body is not a job", v1alpha2.BadRequest)
+		return err
+	}
+	namespace := model.ReadProperty(event.Metadata, "namespace", nil)
+	if namespace == "" {
+		namespace = job.Scope
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	reference := secretReference(event.Metadata["provider"], job.Id)
+	log.InfofCtx(ctx, " M (Job): handling secret %s event in namespace %s", job.Id, namespace)
+
+	var instances []model.InstanceState
+	instances, err = s.apiClient.GetInstances(ctx, namespace, s.user, s.password)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Job): error getting instances in namespace %s: %s", namespace, err.Error())
+		return err
+	}
+	for _, instance := range instances {
+		referenced := referencesSecret(reference, instance.Spec)
+		if !referenced && instance.Spec != nil {
+			solutionName := api_utils.ConvertReferenceToObjectName(instance.Spec.Solution)
+			if solution, sErr := s.apiClient.GetSolution(ctx, solutionName, namespace, s.user, s.password); sErr == nil {
+				referenced = referencesSecret(reference, solution.Spec)
+			}
+		}
+		if referenced {
+			s.publishUpdateJob(ctx, "instance", instance.ObjectMeta.Name, namespace)
+		}
+	}
+	var targets []model.TargetState
+	targets, err = s.apiClient.GetTargets(ctx, namespace, s.user, s.password)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Job): error getting targets in namespace %s: %s", namespace, err.Error())
+		return err
+	}
+	for _, target := range targets {
+		if referencesSecret(reference, target.Spec) {
+			s.publishUpdateJob(ctx, "target", target.ObjectMeta.Name, namespace)
+		}
+	}
+	return nil
+}
+
+func (s *JobsManager) publishUpdateJob(ctx context.Context, objectType string, name string, namespace string) {
+	log.InfofCtx(ctx, " M (Job): publishing update job of %s %s for a secret change", objectType, name)
+	s.Context.Publish("job", v1alpha2.Event{
+		Metadata: map[string]string{
+			"objectType": objectType,
+			"namespace":  namespace,
+		},
+		Body: v1alpha2.JobData{
+			Id:     name,
+			Action: v1alpha2.JobUpdate,
+			Scope:  namespace,
+		},
+		Context: ctx,
+	})
+}
+
+// secretReference matches the first argument of a $secret() call that names the secret, with or without
+// the provider and a version, quoted or not. Quotes are escaped when the expression is in a JSON string.
+func secretReference(provider string, name string) *regexp.Regexp {
+	providerPattern := `[\w.-]+::`
+	if provider != "" {
+		providerPattern = regexp.QuoteMeta(provider) + "::"
+	}
+	return regexp.MustCompile(`\$secret\(\s*(?:\\?['"])?(?:` + providerPattern + `)?` + regexp.QuoteMeta(name) + `(?::\w+)?(?:\\?['"])?\s*,`)
+}
+
+func referencesSecret(reference *regexp.Regexp, spec interface{}) bool {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return false
+	}
+	return reference.Match(data)
+}
+
 func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event) error {
 	ctx, span := observability.StartSpan("Job Manager", ctx, &map[string]string{
 		"method": "HandleJobEvent",
@@ -20,6 +20,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
@@ -230,6 +231,156 @@ func TestDelayOrSkipJobPoll(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestHandleSecretEvent(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	ts := initializeMockSecretReferencesAPI()
+	defer ts.Close()
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	jobManager := JobsManager{}
+	err := jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate":   "state",
+			"providers.persistentstate": "state",
+			"baseUrl":                   ts.URL + "/",
+			"password":                  "",
+			"user":                      "admin",
+			"interval":                  "#15",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+	})
+	assert.Nil(t, err)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	jobManager.Context.PubsubProvider = &pubSubProvider
+	jobs := make(chan string, 10)
+	jobManager.Context.Subscribe("job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var job v1alpha2.JobData
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &job)
+			assert.Nil(t, err)
+			assert.Equal(t, v1alpha2.JobUpdate, job.Action)
+			assert.Equal(t, "default", event.Metadata["namespace"])
+			jobs <- event.Metadata["objectType"] + "/" + job.Id
+			return nil
+		},
+	})
+
+	err = jobManager.HandleSecretEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{
+			"namespace": "default",
+			"provider":  "encrypted",
+		},
+		Body: v1alpha2.JobData{
+			Id:     "db",
+			Action: v1alpha2.JobUpdate,
+		},
+	})
+	assert.Nil(t, err)
+	received := make([]string, 0)
+	for len(received) < 3 {
+		select {
+		case job := <-jobs:
+			received = append(received, job)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 3 jobs, got %v", received)
+		}
+	}
+	assert.ElementsMatch(t, []string{"instance/instance1", "instance/instance2", "target/target1"}, received)
+	select {
+	case job := <-jobs:
+		t.Fatalf("unexpected job %s", job)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	err = jobManager.HandleSecretEvent(context.Background(), v1alpha2.Event{
+		Body: "not a job",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestReferencesSecret(t *testing.T) {
+	reference := secretReference("", "db")
+	assert.True(t, referencesSecret(reference, map[string]string{"password": "${{$secret(db, password)}}"}))
+	assert.True(t, referencesSecret(reference, map[string]string{"password": "${{$secret('db:previous', password)}}"}))
+	assert.True(t, referencesSecret(reference, map[string]string{"password": "${{$secret(\"vault::db\", password)}}"}))
+	assert.False(t, referencesSecret(reference, map[string]string{"password": "${{$secret(db2, password)}}"}))
+	assert.False(t, referencesSecret(reference, map[string]string{"password": "${{$config(db, password)}}"}))
+	reference = secretReference("encrypted", "db")
+	assert.True(t, referencesSecret(reference, map[string]string{"password": "${{$secret(encrypted::db, password)}}"}))
+	assert.False(t, referencesSecret(reference, map[string]string{"password": "${{$secret(vault::db, password)}}"}))
+}
+
+// initializeMockSecretReferencesAPI serves instances and targets that reference the db secret directly, through
+// a solution, or not at all
+func initializeMockSecretReferencesAPI() *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/instances":
+			response = []model.InstanceState{
+				{
+					ObjectMeta: model.ObjectMeta{Name: "instance1", Namespace: "default"},
+					Spec: &model.InstanceSpec{
+						Solution:   "solution1",
+						Parameters: map[string]string{"password": "${{$secret(db, password)}}"},
+					},
+				},
+				{
+					ObjectMeta: model.ObjectMeta{Name: "instance2", Namespace: "default"},
+					Spec:       &model.InstanceSpec{Solution: "solution2:v1"},
+				},
+				{
+					ObjectMeta: model.ObjectMeta{Name: "instance3", Namespace: "default"},
+					Spec:       &model.InstanceSpec{Solution: "solution1"},
+				},
+			}
+		case "/solutions/solution1":
+			response = model.SolutionState{
+				ObjectMeta: model.ObjectMeta{Name: "solution1", Namespace: "default"},
+				Spec:       &model.SolutionSpec{},
+			}
+		case "/solutions/solution2-v-v1":
+			response = model.SolutionState{
+				ObjectMeta: model.ObjectMeta{Name: "solution2-v-v1", Namespace: "default"},
+				Spec: &model.SolutionSpec{
+					Components: []model.ComponentSpec{{
+						Name:       "app",
+						Properties: map[string]interface{}{"password": "${{$secret('encrypted::db:current', password)}}"},
+					}},
+				},
+			}
+		case "/targets/registry":
+			response = []model.TargetState{
+				{
+					ObjectMeta: model.ObjectMeta{Name: "target1", Namespace: "default"},
+					Spec: &model.TargetSpec{
+						Properties: map[string]string{"token": "${{$secret(db, token)}}"},
+					},
+				},
+				{
+					ObjectMeta: model.ObjectMeta{Name: "target2", Namespace: "default"},
+					Spec: &model.TargetSpec{
+						Properties: map[string]string{"token": "${{$secret(vault::db, token)}}"},
+					},
+				},
+			}
+		default:
+			response = AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+				Username:    "test-user",
+				Roles:       []string{"role1", "role2"},
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	return ts
+}
+
 type AuthResponse struct {
 	AccessToken string   `json:"accessToken"`
 	TokenType   string   `json:"tokenType"`
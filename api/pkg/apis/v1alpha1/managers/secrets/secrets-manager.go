@@ -9,7 +9,9 @@ package secrets
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -19,6 +21,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
@@ -115,3 +118,156 @@ func (s *SecretsManager) Get(ctx context.Context, object string, field string, l
 	err = v1alpha2.NewCOAError(nil, fmt.Sprintf("No provider found for object: %s", object), v1alpha2.NotFound)
 	return "", err
 }
+
+// Write adds a version of a secret to a secret provider that can be written to, and publishes a secret
+// event so that what references the secret picks up the new version. The object can name the provider like
+// in Get. Otherwise, the first provider in precedence order that can be written to is used.
+func (s *SecretsManager) Write(ctx context.Context, object string, fields map[string]string, gracePeriod time.Duration, namespace string) (secret.SecretVersion, error) {
+	ctx, span := observability.StartSpan("Secret Manager", ctx, &map[string]string{
+		"method": "Write",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, " M (Secret): Write %s in namespace %s", object, namespace)
+	name, writers, err := s.getWriters(object)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Secret): failed to write secret %s: %+v", object, err)
+		return secret.SecretVersion{}, err
+	}
+	providerKey := writers[0]
+	writer := s.SecretProviders[providerKey].(secret.ISecretWriter)
+	version, err := writer.Write(ctx, name, fields, gracePeriod, coa_utils.EvaluationContext{Namespace: namespace})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Secret): failed to write secret %s: %+v", object, err)
+		return secret.SecretVersion{}, err
+	}
+	s.publish(ctx, providerKey, name, namespace, v1alpha2.JobUpdate, version)
+	return version, nil
+}
+
+// Delete deletes a secret with all its versions. Without a provider in the object, the secret is deleted
+// from the first provider in precedence order that has it.
+func (s *SecretsManager) Delete(ctx context.Context, object string, namespace string) error {
+	ctx, span := observability.StartSpan("Secret Manager", ctx, &map[string]string{
+		"method": "Delete",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, " M (Secret): Delete %s in namespace %s", object, namespace)
+	name, writers, err := s.getWriters(object)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Secret): failed to delete secret %s: %+v", object, err)
+		return err
+	}
+	for _, key := range writers {
+		err = s.SecretProviders[key].(secret.ISecretWriter).Delete(ctx, name, coa_utils.EvaluationContext{Namespace: namespace})
+		if err == nil {
+			s.publish(ctx, key, name, namespace, v1alpha2.JobDelete, nil)
+			return nil
+		}
+		if !v1alpha2.IsNotFound(err) {
+			log.ErrorfCtx(ctx, " M (Secret): failed to delete secret %s: %+v", object, err)
+			return err
+		}
+	}
+	err = v1alpha2.NewCOAError(nil, fmt.Sprintf("secret %s is not found", object), v1alpha2.NotFound)
+	return err
+}
+
+// List lists the secrets of the providers that can be written to, or of the given provider, without their
+// values.
+func (s *SecretsManager) List(ctx context.Context, provider string, namespace string) ([]secret.SecretInfo, error) {
+	ctx, span := observability.StartSpan("Secret Manager", ctx, &map[string]string{
+		"method": "List",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	object := ""
+	if provider != "" {
+		object = provider + "::"
+	}
+	_, writers, err := s.getWriters(object)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Secret): failed to list secrets: %+v", err)
+		return nil, err
+	}
+	ret := make([]secret.SecretInfo, 0)
+	for _, key := range writers {
+		var secrets []secret.SecretInfo
+		secrets, err = s.SecretProviders[key].(secret.ISecretWriter).List(ctx, coa_utils.EvaluationContext{Namespace: namespace})
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Secret): failed to list secrets of provider %s: %+v", key, err)
+			return nil, err
+		}
+		for _, info := range secrets {
+			info.Provider = key
+			ret = append(ret, info)
+		}
+	}
+	return ret, nil
+}
+
+// getWriters returns the secret name of the object and the keys of the providers that can be written to, in
+// precedence order. Only the named provider is returned when the object names one.
+func (s *SecretsManager) getWriters(object string) (string, []string, error) {
+	if strings.Index(object, "::") >= 0 {
+		parts := strings.Split(object, "::")
+		if len(parts) != 2 || parts[0] == "" {
+			return "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid object: %s", object), v1alpha2.BadRequest)
+		}
+		key := parts[0]
+		provider, ok := s.SecretProviders[key]
+		if !ok {
+			return "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("Invalid provider: %s", key), v1alpha2.BadRequest)
+		}
+		if _, ok := provider.(secret.ISecretWriter); !ok {
+			return "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret provider %s is read-only", key), v1alpha2.BadRequest)
+		}
+		return parts[1], []string{key}, nil
+	}
+	keys := s.Precedence
+	if len(keys) == 0 {
+		for k := range s.SecretProviders {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	writers := make([]string, 0)
+	for _, k := range keys {
+		if _, ok := s.SecretProviders[k].(secret.ISecretWriter); ok {
+			writers = append(writers, k)
+		}
+	}
+	if len(writers) == 0 {
+		return "", nil, v1alpha2.NewCOAError(nil, "no secret provider can be written to", v1alpha2.BadRequest)
+	}
+	return object, writers, nil
+}
+
+func (s *SecretsManager) publish(ctx context.Context, provider string, name string, namespace string, action v1alpha2.JobAction, body interface{}) {
+	if s.Context == nil {
+		return
+	}
+	err := s.Context.Publish("secret", v1alpha2.Event{
+		Metadata: map[string]string{
+			"namespace": namespace,
+			"provider":  provider,
+		},
+		Body: v1alpha2.JobData{
+			Id:     name,
+			Scope:  namespace,
+			Action: action,
+			Body:   body,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Secret): failed to publish secret event of %s: %+v", name, err)
+	}
+}
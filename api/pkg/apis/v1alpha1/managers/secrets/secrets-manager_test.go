@@ -2,11 +2,18 @@ package secrets
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/encryptedsecret"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/stretchr/testify/assert"
 )
@@ -75,3 +82,108 @@ func TestObjectFieldGetWithMoreProviders(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "obj>>field", val)
 }
+
+func createWritableSecretsManager(t *testing.T) SecretsManager {
+	readOnly := mocksecret.MockSecretProvider{}
+	err := readOnly.Init(mocksecret.MockSecretProviderConfig{})
+	assert.Nil(t, err)
+	writable := encryptedsecret.EncryptedSecretProvider{}
+	err = writable.Init(encryptedsecret.EncryptedSecretProviderConfig{
+		Key: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+	})
+	assert.Nil(t, err)
+	return SecretsManager{
+		SecretProviders: map[string]secret.ISecretProvider{
+			"mock":      &readOnly,
+			"encrypted": &writable,
+		},
+		Precedence: []string{"mock", "encrypted"},
+	}
+}
+
+func TestWriteReadAndDelete(t *testing.T) {
+	manager := createWritableSecretsManager(t)
+	version, err := manager.Write(ctx, "db", map[string]string{"password": "p@ss"}, 0, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), version.Version)
+	val, err := manager.Get(ctx, "encrypted::db", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", val)
+
+	version, err = manager.Write(ctx, "encrypted::db", map[string]string{"password": "n3w"}, time.Hour, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), version.Version)
+	val, err = manager.Get(ctx, "encrypted::db:previous", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", val)
+
+	secrets, err := manager.List(ctx, "", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(secrets))
+	assert.Equal(t, "db", secrets[0].Name)
+	assert.Equal(t, "encrypted", secrets[0].Provider)
+	assert.Equal(t, 2, len(secrets[0].Versions))
+	secrets, err = manager.List(ctx, "", "other")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(secrets))
+
+	err = manager.Delete(ctx, "db", "default")
+	assert.Nil(t, err)
+	err = manager.Delete(ctx, "db", "default")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestWriteToReadOnlyProvider(t *testing.T) {
+	manager := createWritableSecretsManager(t)
+	_, err := manager.Write(ctx, "mock::db", map[string]string{"password": "p@ss"}, 0, "default")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	_, err = manager.Write(ctx, "vault::db", map[string]string{"password": "p@ss"}, 0, "default")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	_, err = manager.List(ctx, "mock", "default")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	provider := mocksecret.MockSecretProvider{}
+	err = provider.Init(mocksecret.MockSecretProviderConfig{})
+	assert.Nil(t, err)
+	manager = SecretsManager{
+		SecretProviders: map[string]secret.ISecretProvider{
+			"mock": &provider,
+		},
+	}
+	_, err = manager.Write(ctx, "db", map[string]string{"password": "p@ss"}, 0, "default")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+}
+
+func TestWritePublishesSecretEvent(t *testing.T) {
+	manager := createWritableSecretsManager(t)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager.Context = &contexts.ManagerContext{}
+	manager.Context.PubsubProvider = &pubSubProvider
+	sig := make(chan v1alpha2.JobData)
+	manager.Context.Subscribe("secret", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var job v1alpha2.JobData
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &job)
+			assert.Nil(t, err)
+			assert.Equal(t, "scope1", event.Metadata["namespace"])
+			assert.Equal(t, "encrypted", event.Metadata["provider"])
+			sig <- job
+			return nil
+		},
+	})
+
+	_, err := manager.Write(ctx, "db", map[string]string{"password": "p@ss"}, 0, "scope1")
+	assert.Nil(t, err)
+	job := <-sig
+	assert.Equal(t, "db", job.Id)
+	assert.Equal(t, "scope1", job.Scope)
+	assert.Equal(t, v1alpha2.JobUpdate, job.Action)
+
+	err = manager.Delete(ctx, "db", "scope1")
+	assert.Nil(t, err)
+	job = <-sig
+	assert.Equal(t, "db", job.Id)
+	assert.Equal(t, v1alpha2.JobDelete, job.Action)
+}
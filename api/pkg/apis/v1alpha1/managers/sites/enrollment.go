@@ -0,0 +1,410 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sites
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+)
+
+const (
+	defaultJoinTokenTTL  = time.Hour
+	defaultCertValidity  = 30 * 24 * time.Hour
+	defaultRotationGrace = time.Hour
+)
+
+var errInvalidJoinToken = v1alpha2.NewCOAError(nil, "join token is invalid", v1alpha2.Unauthorized)
+
+func parseDuration(config managers.ManagerConfig, key string, defaultValue time.Duration) (time.Duration, error) {
+	v, ok := config.Properties[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("%s is not a valid duration", key), v1alpha2.BadConfig)
+	}
+	return d, nil
+}
+
+// CreateJoinToken creates the one-time token a site enrolls with, replacing the one it had. The site is registered
+// if it isn't yet. A ttl of 0 uses the configured one.
+func (m *SitesManager) CreateJoinToken(ctx context.Context, site string, ttl time.Duration) (model.SiteJoinTokenGrant, error) {
+	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
+		"method": "CreateJoinToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if m.CertSigner == nil {
+		err = v1alpha2.NewCOAError(nil, "site enrollment needs a certs provider", v1alpha2.BadConfig)
+		return model.SiteJoinTokenGrant{}, err
+	}
+	if site == "" {
+		err = v1alpha2.NewCOAError(nil, "site is required", v1alpha2.BadRequest)
+		return model.SiteJoinTokenGrant{}, err
+	}
+	if ttl <= 0 {
+		ttl = m.tokenTTL
+	}
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return model.SiteJoinTokenGrant{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().UTC().Add(ttl).Format(time.RFC3339)
+
+	m.enrollLock.Lock()
+	defer m.enrollLock.Unlock()
+	var state model.SiteState
+	state, err = m.GetState(ctx, site)
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			return model.SiteJoinTokenGrant{}, err
+		}
+		state = model.SiteState{
+			Id:         site,
+			ObjectMeta: model.ObjectMeta{Name: site},
+			Spec:       &model.SiteSpec{Name: site},
+		}
+	}
+	state.Spec.JoinToken = &model.SiteJoinToken{Hash: hashJoinToken(token), ExpiresAt: expiresAt}
+	err = m.upsertSite(ctx, site, state)
+	if err != nil {
+		return model.SiteJoinTokenGrant{}, err
+	}
+	log.InfofCtx(ctx, " M (Sites): created join token for site %s, expires at %s", site, expiresAt)
+	return model.SiteJoinTokenGrant{Site: site, Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// Enroll issues the first client certificate of a site in exchange for its join token, which can't be used again
+func (m *SitesManager) Enroll(ctx context.Context, request model.SiteEnrollmentRequest) (model.SiteEnrollment, error) {
+	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
+		"method": "Enroll",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if m.CertSigner == nil {
+		err = v1alpha2.NewCOAError(nil, "site enrollment needs a certs provider", v1alpha2.BadConfig)
+		return model.SiteEnrollment{}, err
+	}
+
+	m.enrollLock.Lock()
+	defer m.enrollLock.Unlock()
+	var state model.SiteState
+	state, err = m.GetState(ctx, request.Site)
+	if err != nil {
+		if utils.IsNotFound(err) {
+			err = errInvalidJoinToken
+		}
+		return model.SiteEnrollment{}, err
+	}
+	joinToken := state.Spec.JoinToken
+	if joinToken == nil || request.Token == "" ||
+		subtle.ConstantTimeCompare([]byte(hashJoinToken(request.Token)), []byte(joinToken.Hash)) != 1 {
+		err = errInvalidJoinToken
+		return model.SiteEnrollment{}, err
+	}
+	expiresAt, parseErr := time.Parse(time.RFC3339, joinToken.ExpiresAt)
+	if parseErr != nil || time.Now().After(expiresAt) {
+		err = v1alpha2.NewCOAError(parseErr, "join token has expired", v1alpha2.Unauthorized)
+		return model.SiteEnrollment{}, err
+	}
+	state.Spec.JoinToken = nil
+	var enrollment model.SiteEnrollment
+	enrollment, err = m.issueCertificate(ctx, state, []byte(request.CSR), time.Time{})
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	log.InfofCtx(ctx, " M (Sites): site %s enrolled with certificate %s", request.Site, enrollment.Serial)
+	return enrollment, nil
+}
+
+// RotateCertificate issues a new client certificate to a site. The certificates it had are accepted for the
+// rotation grace period, so requests in flight don't fail.
+func (m *SitesManager) RotateCertificate(ctx context.Context, request model.SiteEnrollmentRequest) (model.SiteEnrollment, error) {
+	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
+		"method": "RotateCertificate",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if m.CertSigner == nil {
+		err = v1alpha2.NewCOAError(nil, "site enrollment needs a certs provider", v1alpha2.BadConfig)
+		return model.SiteEnrollment{}, err
+	}
+
+	m.enrollLock.Lock()
+	defer m.enrollLock.Unlock()
+	var state model.SiteState
+	state, err = m.GetState(ctx, request.Site)
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	var enrollment model.SiteEnrollment
+	enrollment, err = m.issueCertificate(ctx, state, []byte(request.CSR), time.Now().Add(m.rotationGrace))
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	log.InfofCtx(ctx, " M (Sites): rotated certificate of site %s to %s", request.Site, enrollment.Serial)
+	return enrollment, nil
+}
+
+// RevokeCertificates stops accepting a client certificate of a site, or all of them when serial is empty. Revoking
+// all certificates also drops the join token, so the site needs a new one to enroll again.
+func (m *SitesManager) RevokeCertificates(ctx context.Context, site string, serial string) error {
+	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
+		"method": "RevokeCertificates",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	m.enrollLock.Lock()
+	defer m.enrollLock.Unlock()
+	var state model.SiteState
+	state, err = m.GetState(ctx, site)
+	if err != nil {
+		return err
+	}
+	if serial == "" {
+		state.Spec.Certificates = nil
+		state.Spec.JoinToken = nil
+	} else {
+		certificates := make([]model.SiteCertificate, 0, len(state.Spec.Certificates))
+		for _, c := range state.Spec.Certificates {
+			if c.Serial != serial {
+				certificates = append(certificates, c)
+			}
+		}
+		if len(certificates) == len(state.Spec.Certificates) {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("certificate %s of site %s is not found", serial, site), v1alpha2.NotFound)
+			return err
+		}
+		state.Spec.Certificates = certificates
+	}
+	err = m.upsertSite(ctx, site, state)
+	if err != nil {
+		return err
+	}
+	log.InfofCtx(ctx, " M (Sites): revoked certificate '%s' of site %s", serial, site)
+	return nil
+}
+
+// VerifySiteCertificate checks a client certificate chain presented by a child site and returns the site it was
+// issued to. The certificate has to be signed by the certs provider and still be active on the site.
+func (m *SitesManager) VerifySiteCertificate(ctx context.Context, chain []*x509.Certificate) (string, error) {
+	if m.CertSigner == nil {
+		return "", v1alpha2.NewCOAError(nil, "client certificates are not accepted", v1alpha2.Unauthorized)
+	}
+	if len(chain) == 0 {
+		return "", v1alpha2.NewCOAError(nil, "client certificate is missing", v1alpha2.Unauthorized)
+	}
+	caPEM, err := m.CertSigner.GetCACert()
+	if err != nil {
+		return "", err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return "", v1alpha2.NewCOAError(nil, "CA certificate of the certs provider is invalid", v1alpha2.InternalError)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, "client certificate is not trusted", v1alpha2.Unauthorized)
+	}
+	site := chain[0].Subject.CommonName
+	state, err := m.GetState(ctx, site)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("site %s is not registered", site), v1alpha2.Unauthorized)
+	}
+	serial := certificateSerial(chain[0])
+	now := time.Now()
+	for _, c := range state.Spec.Certificates {
+		if c.Serial != serial {
+			continue
+		}
+		if notAfter, err := time.Parse(time.RFC3339, c.NotAfter); err == nil && now.Before(notAfter) {
+			return site, nil
+		}
+	}
+	return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("certificate %s of site %s has been revoked", serial, site), v1alpha2.Unauthorized)
+}
+
+// issueCertificate signs a certificate request of a site and adds it to the site's certificates. Expired ones
+// are dropped, and when graceEnd is set, the others are only accepted until then.
+func (m *SitesManager) issueCertificate(ctx context.Context, state model.SiteState, csr []byte, graceEnd time.Time) (model.SiteEnrollment, error) {
+	certPEM, err := m.CertSigner.SignCSR(csr, state.Id, m.certValidity)
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	caPEM, err := m.CertSigner.GetCACert()
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+
+	now := time.Now()
+	certificates := make([]model.SiteCertificate, 0, len(state.Spec.Certificates)+1)
+	for _, c := range state.Spec.Certificates {
+		notAfter, err := time.Parse(time.RFC3339, c.NotAfter)
+		if err != nil || !now.Before(notAfter) {
+			continue
+		}
+		if !graceEnd.IsZero() && notAfter.After(graceEnd) {
+			c.NotAfter = graceEnd.UTC().Format(time.RFC3339)
+		}
+		certificates = append(certificates, c)
+	}
+	issued := model.SiteCertificate{
+		Serial:   certificateSerial(cert),
+		NotAfter: cert.NotAfter.UTC().Format(time.RFC3339),
+	}
+	state.Spec.Certificates = append(certificates, issued)
+	err = m.upsertSite(ctx, state.Id, state)
+	if err != nil {
+		return model.SiteEnrollment{}, err
+	}
+	return model.SiteEnrollment{
+		Site:          state.Id,
+		Certificate:   string(certPEM),
+		CACertificate: string(caPEM),
+		Serial:        issued.Serial,
+		NotAfter:      issued.NotAfter,
+	}, nil
+}
+
+// ensureCertificate enrolls this site with its parent when it has a join token but no client certificate yet,
+// and rotates the certificate once a third of its lifetime is left
+func (s *SitesManager) ensureCertificate(ctx context.Context) error {
+	parent := s.VendorContext.SiteInfo.ParentSite
+	if parent.CertFile == "" || parent.KeyFile == "" {
+		return nil
+	}
+	siteId := s.VendorContext.SiteInfo.SiteId
+	certPEM, err := os.ReadFile(parent.CertFile)
+	if err != nil {
+		if !os.IsNotExist(err) || parent.JoinToken == "" {
+			return err
+		}
+		csr, key, err := certs.GenerateCSR(siteId)
+		if err != nil {
+			return err
+		}
+		enrollment, err := s.apiClient.EnrollSite(ctx, model.SiteEnrollmentRequest{
+			Site:  siteId,
+			Token: parent.JoinToken,
+			CSR:   string(csr),
+		})
+		if err != nil {
+			return err
+		}
+		log.InfofCtx(ctx, " M (Sites): enrolled with the parent site, certificate %s", enrollment.Serial)
+		if err := writeCACertificate(parent.CAFile, []byte(enrollment.CACertificate)); err != nil {
+			return err
+		}
+		return writeCertificate(parent.CertFile, parent.KeyFile, []byte(enrollment.Certificate), key)
+	}
+
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+	if time.Until(cert.NotAfter) > cert.NotAfter.Sub(cert.NotBefore)/3 {
+		return nil
+	}
+	csr, key, err := certs.GenerateCSR(siteId)
+	if err != nil {
+		return err
+	}
+	enrollment, err := s.apiClient.RotateSiteCertificate(ctx, model.SiteEnrollmentRequest{
+		Site: siteId,
+		CSR:  string(csr),
+	}, parent.Username, parent.Password)
+	if err != nil {
+		return err
+	}
+	log.InfofCtx(ctx, " M (Sites): rotated client certificate to %s", enrollment.Serial)
+	if err := writeCACertificate(parent.CAFile, []byte(enrollment.CACertificate)); err != nil {
+		return err
+	}
+	return writeCertificate(parent.CertFile, parent.KeyFile, []byte(enrollment.Certificate), key)
+}
+
+// writeCertificate replaces the client certificate files. Each file is renamed into place, so it's never read
+// half written.
+func writeCertificate(certFile string, keyFile string, certPEM []byte, keyPEM []byte) error {
+	if err := os.WriteFile(keyFile+".tmp", keyPEM, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile+".tmp", certPEM, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(keyFile+".tmp", keyFile); err != nil {
+		return err
+	}
+	return os.Rename(certFile+".tmp", certFile)
+}
+
+// writeCACertificate replaces the CA certificate file of the parent, when the site keeps one
+func writeCACertificate(caFile string, caPEM []byte) error {
+	if caFile == "" || len(caPEM) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(caFile+".tmp", caPEM, 0644); err != nil {
+		return err
+	}
+	return os.Rename(caFile+".tmp", caFile)
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, v1alpha2.NewCOAError(nil, "certificate is not PEM encoded", v1alpha2.BadRequest)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func certificateSerial(cert *x509.Certificate) string {
+	return cert.SerialNumber.Text(16)
+}
+
+func hashJoinToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sites
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEnrollmentManager() *SitesManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	signer := &autogen.AutoGenCertProvider{}
+	signer.Init(autogen.AutoGenCertProviderConfig{Name: "certs"})
+	return &SitesManager{
+		StateProvider: stateProvider,
+		CertSigner:    signer,
+		tokenTTL:      defaultJoinTokenTTL,
+		certValidity:  defaultCertValidity,
+		rotationGrace: defaultRotationGrace,
+	}
+}
+
+func enroll(t *testing.T, manager *SitesManager, site string, token string) (model.SiteEnrollment, error) {
+	csr, _, err := certs.GenerateCSR(site)
+	assert.Nil(t, err)
+	return manager.Enroll(context.Background(), model.SiteEnrollmentRequest{Site: site, Token: token, CSR: string(csr)})
+}
+
+func certificateChain(t *testing.T, enrollment model.SiteEnrollment) []*x509.Certificate {
+	cert, err := parseCertificate([]byte(enrollment.Certificate))
+	assert.Nil(t, err)
+	return []*x509.Certificate{cert}
+}
+
+func TestEnroll(t *testing.T) {
+	manager := newEnrollmentManager()
+	ctx := context.Background()
+
+	grant, err := manager.CreateJoinToken(ctx, "child1", 0)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, grant.Token)
+
+	_, err = enroll(t, manager, "child1", "wrong-token")
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	_, err = enroll(t, manager, "child2", grant.Token)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	enrollment, err := enroll(t, manager, "child1", grant.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, "child1", enrollment.Site)
+	assert.NotEmpty(t, enrollment.CACertificate)
+	chain := certificateChain(t, enrollment)
+	assert.Equal(t, "child1", chain[0].Subject.CommonName)
+	assert.Equal(t, enrollment.Serial, certificateSerial(chain[0]))
+
+	// The join token can only be used once
+	_, err = enroll(t, manager, "child1", grant.Token)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	site, err := manager.VerifySiteCertificate(ctx, chain)
+	assert.Nil(t, err)
+	assert.Equal(t, "child1", site)
+
+	// Updating the site doesn't change its certificates
+	err = manager.UpsertState(ctx, "child1", model.SiteState{
+		Spec: &model.SiteSpec{Name: "child1", Certificates: []model.SiteCertificate{{Serial: "1", NotAfter: "2099-01-01T00:00:00Z"}}},
+	})
+	assert.Nil(t, err)
+	state, err := manager.GetState(ctx, "child1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(state.Spec.Certificates))
+	assert.Equal(t, enrollment.Serial, state.Spec.Certificates[0].Serial)
+}
+
+func TestEnrollExpiredToken(t *testing.T) {
+	manager := newEnrollmentManager()
+	grant, err := manager.CreateJoinToken(context.Background(), "child1", time.Nanosecond)
+	assert.Nil(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = enroll(t, manager, "child1", grant.Token)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestEnrollWithoutSigner(t *testing.T) {
+	manager := newEnrollmentManager()
+	manager.CertSigner = nil
+	_, err := manager.CreateJoinToken(context.Background(), "child1", 0)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	_, err = manager.VerifySiteCertificate(context.Background(), nil)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestRotateAndRevokeCertificates(t *testing.T) {
+	manager := newEnrollmentManager()
+	ctx := context.Background()
+	grant, err := manager.CreateJoinToken(ctx, "child1", 0)
+	assert.Nil(t, err)
+	first, err := enroll(t, manager, "child1", grant.Token)
+	assert.Nil(t, err)
+
+	csr, _, err := certs.GenerateCSR("child1")
+	assert.Nil(t, err)
+	second, err := manager.RotateCertificate(ctx, model.SiteEnrollmentRequest{Site: "child1", CSR: string(csr)})
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Serial, second.Serial)
+
+	// The old certificate is accepted for the grace period
+	state, err := manager.GetState(ctx, "child1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(state.Spec.Certificates))
+	notAfter, err := time.Parse(time.RFC3339, state.Spec.Certificates[0].NotAfter)
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(defaultRotationGrace), notAfter, time.Minute)
+	_, err = manager.VerifySiteCertificate(ctx, certificateChain(t, first))
+	assert.Nil(t, err)
+
+	err = manager.RevokeCertificates(ctx, "child1", first.Serial)
+	assert.Nil(t, err)
+	_, err = manager.VerifySiteCertificate(ctx, certificateChain(t, first))
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	_, err = manager.VerifySiteCertificate(ctx, certificateChain(t, second))
+	assert.Nil(t, err)
+
+	err = manager.RevokeCertificates(ctx, "child1", first.Serial)
+	assert.Equal(t, v1alpha2.NotFound, err.(v1alpha2.COAError).State)
+
+	err = manager.RevokeCertificates(ctx, "child1", "")
+	assert.Nil(t, err)
+	_, err = manager.VerifySiteCertificate(ctx, certificateChain(t, second))
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestVerifyUntrustedCertificate(t *testing.T) {
+	manager := newEnrollmentManager()
+	other := newEnrollmentManager()
+	ctx := context.Background()
+	grant, err := other.CreateJoinToken(ctx, "child1", 0)
+	assert.Nil(t, err)
+	enrollment, err := enroll(t, other, "child1", grant.Token)
+	assert.Nil(t, err)
+
+	_, err = manager.CreateJoinToken(ctx, "child1", 0)
+	assert.Nil(t, err)
+	_, err = manager.VerifySiteCertificate(ctx, certificateChain(t, enrollment))
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+// parentClient calls the enrollment methods of a parent sites manager
+type parentClient struct {
+	utils.ApiClient
+	parent *SitesManager
+}
+
+func (p *parentClient) EnrollSite(ctx context.Context, request model.SiteEnrollmentRequest) (model.SiteEnrollment, error) {
+	return p.parent.Enroll(ctx, request)
+}
+
+func (p *parentClient) RotateSiteCertificate(ctx context.Context, request model.SiteEnrollmentRequest, user string, password string) (model.SiteEnrollment, error) {
+	return p.parent.RotateCertificate(ctx, request)
+}
+
+func TestEnsureCertificate(t *testing.T) {
+	parent := newEnrollmentManager()
+	ctx := context.Background()
+	grant, err := parent.CreateJoinToken(ctx, "child1", 0)
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	child := &SitesManager{
+		Manager: managers.Manager{
+			VendorContext: &contexts.VendorContext{
+				SiteInfo: v1alpha2.SiteInfo{
+					SiteId: "child1",
+					ParentSite: v1alpha2.SiteConnection{
+						CertFile:  filepath.Join(dir, "site.crt"),
+						KeyFile:   filepath.Join(dir, "site.key"),
+						CAFile:    filepath.Join(dir, "parent-ca.crt"),
+						JoinToken: grant.Token,
+					},
+				},
+			},
+		},
+		apiClient: &parentClient{parent: parent},
+	}
+
+	err = child.ensureCertificate(ctx)
+	assert.Nil(t, err)
+	certPEM, err := os.ReadFile(filepath.Join(dir, "site.crt"))
+	assert.Nil(t, err)
+	first, err := parseCertificate(certPEM)
+	assert.Nil(t, err)
+	info, err := os.Stat(filepath.Join(dir, "site.key"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	_, err = parent.VerifySiteCertificate(ctx, []*x509.Certificate{first})
+	assert.Nil(t, err)
+	// The CA of the parent is kept to verify its bundles
+	caPEM, err := os.ReadFile(filepath.Join(dir, "parent-ca.crt"))
+	assert.Nil(t, err)
+	parentCA, err := parent.CertSigner.GetCACert()
+	assert.Nil(t, err)
+	assert.Equal(t, parentCA, caPEM)
+
+	// A fresh certificate is kept
+	err = child.ensureCertificate(ctx)
+	assert.Nil(t, err)
+	certPEM, err = os.ReadFile(filepath.Join(dir, "site.crt"))
+	assert.Nil(t, err)
+	cert, err := parseCertificate(certPEM)
+	assert.Nil(t, err)
+	assert.Equal(t, certificateSerial(first), certificateSerial(cert))
+
+	// A certificate close to its expiry is rotated
+	parent.certValidity = time.Minute
+	csr, key, err := certs.GenerateCSR("child1")
+	assert.Nil(t, err)
+	shortLived, err := parent.RotateCertificate(ctx, model.SiteEnrollmentRequest{Site: "child1", CSR: string(csr)})
+	assert.Nil(t, err)
+	err = writeCertificate(filepath.Join(dir, "site.crt"), filepath.Join(dir, "site.key"), []byte(shortLived.Certificate), key)
+	assert.Nil(t, err)
+	err = child.ensureCertificate(ctx)
+	assert.Nil(t, err)
+	certPEM, err = os.ReadFile(filepath.Join(dir, "site.crt"))
+	assert.Nil(t, err)
+	cert, err = parseCertificate(certPEM)
+	assert.Nil(t, err)
+	assert.NotEqual(t, shortLived.Serial, certificateSerial(cert))
+}
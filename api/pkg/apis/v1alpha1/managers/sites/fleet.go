@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sites
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// defaultOfflineAfter is how long a child site can go without reporting before it's considered offline
+const defaultOfflineAfter = 5 * time.Minute
+
+// InstanceLister lists the instances of the site, whose statuses are reported to the parent site
+type InstanceLister interface {
+	ListState(ctx context.Context, namespace string) ([]model.InstanceState, error)
+}
+
+// TargetLister lists the targets of the site, whose statuses are reported to the parent site
+type TargetLister interface {
+	ListState(ctx context.Context, namespace string) ([]model.TargetState, error)
+}
+
+// FleetFilter selects sites, instances and targets of the fleet. Empty fields don't filter.
+type FleetFilter struct {
+	// Properties are site properties the sites must have
+	Properties map[string]string
+	// Online keeps the sites that are online, or the ones that are offline
+	Online *bool
+	// StaleFor keeps the sites that haven't reported for at least this long
+	StaleFor time.Duration
+	// State keeps instances and targets with a state of this name, such as "OK" or "Update Failed". "failed"
+	// keeps the ones in any failed state.
+	State string
+	// Solution keeps instances of a solution, given as <name> for any version or as <name>:<version>
+	Solution string
+}
+
+// Fleet gets the status of the sites this site sees: itself, its child sites, and the sites below them as the
+// children reported them. Sites below a child that is offline are offline too, as their status is stale.
+func (m *SitesManager) Fleet(ctx context.Context) ([]model.SiteSummary, error) {
+	self := m.VendorContext.SiteInfo.SiteId
+	local, err := m.localStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fleet := []model.SiteSummary{{
+		Name:             self,
+		Properties:       m.VendorContext.SiteInfo.Properties,
+		IsOnline:         true,
+		LastReported:     time.Now().UTC().Format(time.RFC3339),
+		TargetStatuses:   local.TargetStatuses,
+		InstanceStatuses: local.InstanceStatuses,
+	}}
+	sites, err := m.ListState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		return sites[i].Id < sites[j].Id
+	})
+	for _, site := range sites {
+		if site.Id == self || site.Spec == nil || site.Spec.IsSelf {
+			continue
+		}
+		summary := model.SiteSummary{
+			Name:       site.Id,
+			Parent:     self,
+			Properties: site.Spec.Properties,
+		}
+		if site.Status != nil {
+			summary.LastReported = site.Status.LastReported
+			summary.TargetStatuses = site.Status.TargetStatuses
+			summary.InstanceStatuses = site.Status.InstanceStatuses
+			summary.IsOnline = reportedWithin(site.Status.LastReported, m.offlineAfter)
+		}
+		fleet = append(fleet, summary)
+		if site.Status == nil {
+			continue
+		}
+		for _, descendant := range site.Status.Descendants {
+			descendant.IsOnline = descendant.IsOnline && summary.IsOnline
+			fleet = append(fleet, descendant)
+		}
+	}
+	return fleet, nil
+}
+
+// RollUp gets the status this site reports to its parent: the statuses of its own instances and targets, and the
+// sites below it as descendants
+func (m *SitesManager) RollUp(ctx context.Context) (model.SiteStatus, error) {
+	fleet, err := m.Fleet(ctx)
+	if err != nil {
+		return model.SiteStatus{}, err
+	}
+	return model.SiteStatus{
+		IsOnline:         true,
+		TargetStatuses:   fleet[0].TargetStatuses,
+		InstanceStatuses: fleet[0].InstanceStatuses,
+		Descendants:      fleet[1:],
+	}, nil
+}
+
+// localStatus gets the statuses of the instances and targets of this site
+func (m *SitesManager) localStatus(ctx context.Context) (model.SiteStatus, error) {
+	status := model.SiteStatus{}
+	if m.Instances != nil {
+		instances, err := m.Instances.ListState(ctx, "")
+		if err != nil {
+			return status, err
+		}
+		status.InstanceStatuses = make(map[string]model.SiteInstanceStatus, len(instances))
+		for _, instance := range instances {
+			state, reason := deploymentState(instance.Status)
+			solution := ""
+			if instance.Spec != nil {
+				solution = strings.Replace(instance.Spec.Solution, constants.ResourceSeperator, constants.ReferenceSeparator, 1)
+			}
+			status.InstanceStatuses[statusKey(instance.ObjectMeta)] = model.SiteInstanceStatus{
+				State:    state,
+				Reason:   reason,
+				Solution: solution,
+			}
+		}
+	}
+	if m.Targets != nil {
+		targets, err := m.Targets.ListState(ctx, "")
+		if err != nil {
+			return status, err
+		}
+		status.TargetStatuses = make(map[string]model.SiteTargetStatus, len(targets))
+		for _, target := range targets {
+			state, reason := deploymentState(target.Status)
+			status.TargetStatuses[statusKey(target.ObjectMeta)] = model.SiteTargetStatus{
+				State:  state,
+				Reason: reason,
+			}
+		}
+	}
+	return status, nil
+}
+
+// FilterSites gets the sites of the fleet that match a filter. With a state or a solution, sites need an
+// instance or a target that matches it.
+func FilterSites(fleet []model.SiteSummary, filter FleetFilter) []model.SiteSummary {
+	ret := make([]model.SiteSummary, 0)
+	for _, site := range fleet {
+		if !filter.matchesSite(site) {
+			continue
+		}
+		if filter.State != "" || filter.Solution != "" {
+			one := []model.SiteSummary{site}
+			matched := len(FilterInstances(one, filter)) > 0
+			if !matched && filter.Solution == "" {
+				matched = len(FilterTargets(one, filter)) > 0
+			}
+			if !matched {
+				continue
+			}
+		}
+		ret = append(ret, site)
+	}
+	return ret
+}
+
+// FilterInstances gets the instances of the sites of the fleet that match a filter
+func FilterInstances(fleet []model.SiteSummary, filter FleetFilter) []model.FleetInstance {
+	ret := make([]model.FleetInstance, 0)
+	for _, site := range fleet {
+		if !filter.matchesSite(site) {
+			continue
+		}
+		for _, name := range sortedKeys(site.InstanceStatuses) {
+			status := site.InstanceStatuses[name]
+			if !filter.matchesState(status.State) || !filter.matchesSolution(status.Solution) {
+				continue
+			}
+			ret = append(ret, model.FleetInstance{
+				Site:     site.Name,
+				Name:     name,
+				Solution: status.Solution,
+				State:    status.State,
+				Reason:   status.Reason,
+			})
+		}
+	}
+	return ret
+}
+
+// FilterTargets gets the targets of the sites of the fleet that match a filter
+func FilterTargets(fleet []model.SiteSummary, filter FleetFilter) []model.FleetTarget {
+	ret := make([]model.FleetTarget, 0)
+	for _, site := range fleet {
+		if !filter.matchesSite(site) {
+			continue
+		}
+		for _, name := range sortedKeys(site.TargetStatuses) {
+			status := site.TargetStatuses[name]
+			if !filter.matchesState(status.State) {
+				continue
+			}
+			ret = append(ret, model.FleetTarget{
+				Site:   site.Name,
+				Name:   name,
+				State:  status.State,
+				Reason: status.Reason,
+			})
+		}
+	}
+	return ret
+}
+
+func (f FleetFilter) matchesSite(site model.SiteSummary) bool {
+	for k, v := range f.Properties {
+		if site.Properties[k] != v {
+			return false
+		}
+	}
+	if f.Online != nil && site.IsOnline != *f.Online {
+		return false
+	}
+	if f.StaleFor > 0 && reportedWithin(site.LastReported, f.StaleFor) {
+		return false
+	}
+	return true
+}
+
+func (f FleetFilter) matchesState(state v1alpha2.State) bool {
+	if f.State == "" {
+		return true
+	}
+	if strings.EqualFold(f.State, "failed") {
+		return isFailedState(state)
+	}
+	return strings.EqualFold(f.State, state.String())
+}
+
+func (f FleetFilter) matchesSolution(solution string) bool {
+	if f.Solution == "" {
+		return true
+	}
+	wanted := strings.Replace(f.Solution, constants.ResourceSeperator, constants.ReferenceSeparator, 1)
+	if strings.Contains(wanted, constants.ReferenceSeparator) {
+		return solution == wanted
+	}
+	return solution == wanted || strings.HasPrefix(solution, wanted+constants.ReferenceSeparator)
+}
+
+// deploymentState maps the status of an instance or a target to a state
+func deploymentState(status model.DeployableStatusV2) (v1alpha2.State, string) {
+	reason := status.StatusDetails
+	switch status.Status {
+	case "Succeeded":
+		return v1alpha2.OK, reason
+	case "Failed":
+		if reason == "" {
+			reason = status.ProvisioningStatus.FailureCause
+		}
+		return v1alpha2.UpdateFailed, reason
+	case "":
+		return v1alpha2.Untouched, reason
+	}
+	return v1alpha2.Running, reason
+}
+
+func isFailedState(state v1alpha2.State) bool {
+	switch state {
+	case v1alpha2.None, v1alpha2.OK, v1alpha2.Accepted, v1alpha2.Updated, v1alpha2.Deleted, v1alpha2.Running,
+		v1alpha2.Paused, v1alpha2.Done, v1alpha2.Delayed, v1alpha2.Untouched:
+		return false
+	}
+	return true
+}
+
+// reportedWithin tells if a site reported less than a duration ago
+func reportedWithin(lastReported string, d time.Duration) bool {
+	t, err := time.Parse(time.RFC3339, lastReported)
+	if err != nil {
+		return false
+	}
+	return time.Since(t) < d
+}
+
+// statusKey is the key of an object in the statuses of a site: its name, prefixed with its namespace unless
+// it's the default one
+func statusKey(meta model.ObjectMeta) string {
+	if meta.Namespace == "" || meta.Namespace == "default" {
+		return meta.Name
+	}
+	return meta.Namespace + "/" + meta.Name
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sites
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+type instanceList []model.InstanceState
+
+func (l instanceList) ListState(ctx context.Context, namespace string) ([]model.InstanceState, error) {
+	return l, nil
+}
+
+type targetList []model.TargetState
+
+func (l targetList) ListState(ctx context.Context, namespace string) ([]model.TargetState, error) {
+	return l, nil
+}
+
+func newFleetManager(siteId string, properties map[string]string) *SitesManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	return &SitesManager{
+		Manager: managers.Manager{
+			VendorContext: &contexts.VendorContext{
+				SiteInfo: v1alpha2.SiteInfo{
+					SiteId:     siteId,
+					Properties: properties,
+				},
+			},
+		},
+		StateProvider: stateProvider,
+		offlineAfter:  defaultOfflineAfter,
+	}
+}
+
+// upsertChild stores a child site as it last reported to this site
+func upsertChild(t *testing.T, m *SitesManager, state model.SiteState) {
+	_, err := m.StateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: state.Id, Body: state},
+		Metadata: map[string]interface{}{
+			"version":  "v1",
+			"group":    model.FederationGroup,
+			"resource": "sites",
+		},
+	})
+	assert.Nil(t, err)
+}
+
+func TestFleetLocalStatus(t *testing.T) {
+	m := newFleetManager("root", map[string]string{"region": "west"})
+	m.Instances = instanceList{
+		{
+			ObjectMeta: model.ObjectMeta{Name: "instance1", Namespace: "default"},
+			Spec:       &model.InstanceSpec{Solution: "app-v-v1"},
+			Status:     model.InstanceStatus{Status: "Succeeded"},
+		},
+		{
+			ObjectMeta: model.ObjectMeta{Name: "instance2", Namespace: "edge"},
+			Spec:       &model.InstanceSpec{Solution: "app-v-v2"},
+			Status: model.InstanceStatus{Status: "Failed", ProvisioningStatus: model.ProvisioningStatus{
+				FailureCause: "image pull failed",
+			}},
+		},
+	}
+	m.Targets = targetList{
+		{
+			ObjectMeta: model.ObjectMeta{Name: "target1", Namespace: "default"},
+			Status:     model.TargetStatus{Status: "Reconciling"},
+		},
+	}
+
+	fleet, err := m.Fleet(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(fleet))
+	assert.Equal(t, "root", fleet[0].Name)
+	assert.True(t, fleet[0].IsOnline)
+	assert.Equal(t, "west", fleet[0].Properties["region"])
+	assert.Equal(t, model.SiteInstanceStatus{State: v1alpha2.OK, Solution: "app:v1"}, fleet[0].InstanceStatuses["instance1"])
+	assert.Equal(t, model.SiteInstanceStatus{State: v1alpha2.UpdateFailed, Reason: "image pull failed", Solution: "app:v2"}, fleet[0].InstanceStatuses["edge/instance2"])
+	assert.Equal(t, v1alpha2.Running, fleet[0].TargetStatuses["target1"].State)
+}
+
+func TestFleetChildrenAndDescendants(t *testing.T) {
+	m := newFleetManager("root", nil)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	upsertChild(t, m, model.SiteState{
+		Id:   "child2",
+		Spec: &model.SiteSpec{Properties: map[string]string{"region": "east"}},
+		Status: &model.SiteStatus{
+			LastReported: now.Add(-time.Hour).Format(time.RFC3339),
+			Descendants: []model.SiteSummary{
+				{Name: "grandchild2", Parent: "child2", IsOnline: true},
+			},
+		},
+	})
+	upsertChild(t, m, model.SiteState{
+		Id:   "child1",
+		Spec: &model.SiteSpec{Properties: map[string]string{"region": "west"}},
+		Status: &model.SiteStatus{
+			LastReported: now.Format(time.RFC3339),
+			Descendants: []model.SiteSummary{
+				{Name: "grandchild1", Parent: "child1", IsOnline: true},
+			},
+		},
+	})
+	err := m.UpsertState(ctx, "root", model.SiteState{
+		Id:   "root",
+		Spec: &model.SiteSpec{IsSelf: true},
+	})
+	assert.Nil(t, err)
+
+	fleet, err := m.Fleet(ctx)
+	assert.Nil(t, err)
+	names := make([]string, 0, len(fleet))
+	for _, site := range fleet {
+		names = append(names, site.Name)
+	}
+	assert.Equal(t, []string{"root", "child1", "grandchild1", "child2", "grandchild2"}, names)
+	assert.Equal(t, "root", fleet[1].Parent)
+	assert.True(t, fleet[1].IsOnline)
+	assert.True(t, fleet[2].IsOnline)
+	// child2 hasn't reported for an hour, so neither it nor the sites below it are online
+	assert.False(t, fleet[3].IsOnline)
+	assert.False(t, fleet[4].IsOnline)
+
+	status, err := m.RollUp(ctx)
+	assert.Nil(t, err)
+	assert.True(t, status.IsOnline)
+	assert.Equal(t, 4, len(status.Descendants))
+	assert.Equal(t, "child1", status.Descendants[0].Name)
+}
+
+func TestFilterFleet(t *testing.T) {
+	online := true
+	offline := false
+	now := time.Now().UTC()
+	fleet := []model.SiteSummary{
+		{
+			Name:         "root",
+			IsOnline:     true,
+			LastReported: now.Format(time.RFC3339),
+			Properties:   map[string]string{"region": "west", "tier": "hub"},
+			InstanceStatuses: map[string]model.SiteInstanceStatus{
+				"instance1": {State: v1alpha2.OK, Solution: "app:v1"},
+			},
+		},
+		{
+			Name:         "child1",
+			Parent:       "root",
+			IsOnline:     false,
+			LastReported: now.Add(-time.Hour).Format(time.RFC3339),
+			Properties:   map[string]string{"region": "east"},
+			InstanceStatuses: map[string]model.SiteInstanceStatus{
+				"instance2": {State: v1alpha2.UpdateFailed, Reason: "image pull failed", Solution: "app:v2"},
+				"instance3": {State: v1alpha2.OK, Solution: "other:v1"},
+			},
+			TargetStatuses: map[string]model.SiteTargetStatus{
+				"target1": {State: v1alpha2.Running},
+			},
+		},
+	}
+
+	assert.Equal(t, 2, len(FilterSites(fleet, FleetFilter{})))
+	assert.Equal(t, "root", FilterSites(fleet, FleetFilter{Properties: map[string]string{"region": "west"}})[0].Name)
+	assert.Equal(t, "root", FilterSites(fleet, FleetFilter{Online: &online})[0].Name)
+	assert.Equal(t, "child1", FilterSites(fleet, FleetFilter{Online: &offline})[0].Name)
+	assert.Equal(t, "child1", FilterSites(fleet, FleetFilter{StaleFor: 10 * time.Minute})[0].Name)
+	assert.Equal(t, "child1", FilterSites(fleet, FleetFilter{State: "failed"})[0].Name)
+	assert.Equal(t, 1, len(FilterSites(fleet, FleetFilter{State: "Running"})))
+	assert.Equal(t, 0, len(FilterSites(fleet, FleetFilter{State: "Running", Solution: "app"})))
+
+	instances := FilterInstances(fleet, FleetFilter{Solution: "app"})
+	assert.Equal(t, 2, len(instances))
+	assert.Equal(t, model.FleetInstance{Site: "root", Name: "instance1", Solution: "app:v1", State: v1alpha2.OK}, instances[0])
+	instances = FilterInstances(fleet, FleetFilter{Solution: "app:v2"})
+	assert.Equal(t, 1, len(instances))
+	assert.Equal(t, "image pull failed", instances[0].Reason)
+	instances = FilterInstances(fleet, FleetFilter{State: "OK"})
+	assert.Equal(t, []string{"instance1", "instance3"}, []string{instances[0].Name, instances[1].Name})
+
+	targets := FilterTargets(fleet, FleetFilter{})
+	assert.Equal(t, []model.FleetTarget{{Site: "child1", Name: "target1", State: v1alpha2.Running}}, targets)
+	assert.Equal(t, 0, len(FilterTargets(fleet, FleetFilter{State: "failed"})))
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -20,16 +21,35 @@ import (
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
 var log = logger.NewLogger("coa.runtime")
 
+// ChannelReporter sends messages to the parent site over the push channel of the site
+type ChannelReporter interface {
+	Connected() bool
+	Report(ctx context.Context, messages ...model.ChannelMessage) error
+}
+
 type SitesManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
 	apiClient     utils.ApiClient
+	// Channel sends heartbeats to the parent site while the push channel is connected
+	Channel ChannelReporter
+	// CertSigner issues the client certificates child sites authenticate with
+	CertSigner certs.ICertSigner
+	// Instances and Targets list the deployments of the site, whose statuses are reported to the parent site
+	Instances     InstanceLister
+	Targets       TargetLister
+	tokenTTL      time.Duration
+	certValidity  time.Duration
+	rotationGrace time.Duration
+	offlineAfter  time.Duration
+	enrollLock    sync.Mutex
 }
 
 func (s *SitesManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -43,10 +63,27 @@ func (s *SitesManager) Init(context *contexts.VendorContext, config managers.Man
 	} else {
 		return err
 	}
-	s.apiClient, err = utils.GetParentApiClient(s.VendorContext.SiteInfo.ParentSite.BaseUrl)
+	s.apiClient, err = utils.GetParentApiClient(s.VendorContext.SiteInfo.ParentSite)
 	if err != nil {
 		return err
 	}
+	for _, provider := range providers {
+		if p, ok := provider.(certs.ICertSigner); ok {
+			s.CertSigner = p
+		}
+	}
+	if s.tokenTTL, err = parseDuration(config, "enrollment.tokenTTL", defaultJoinTokenTTL); err != nil {
+		return err
+	}
+	if s.certValidity, err = parseDuration(config, "enrollment.certValidity", defaultCertValidity); err != nil {
+		return err
+	}
+	if s.rotationGrace, err = parseDuration(config, "enrollment.rotationGrace", defaultRotationGrace); err != nil {
+		return err
+	}
+	if s.offlineAfter, err = parseDuration(config, "fleet.offlineAfter", defaultOfflineAfter); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -147,6 +184,7 @@ func (t *SitesManager) ReportState(ctx context.Context, current model.SiteState)
 		siteState.Status.IsOnline = current.Status.IsOnline
 		siteState.Status.InstanceStatuses = current.Status.InstanceStatuses
 		siteState.Status.TargetStatuses = current.Status.TargetStatuses
+		siteState.Status.Descendants = current.Status.Descendants
 	}
 	siteState.Status.LastReported = time.Now().UTC().Format(time.RFC3339)
 
@@ -181,11 +219,28 @@ func (m *SitesManager) UpsertState(ctx context.Context, name string, state model
 		return v1alpha2.NewCOAError(nil, fmt.Sprintf("Name in metadata (%s) does not match name in request (%s)", state.ObjectMeta.Name, name), v1alpha2.BadRequest)
 	}
 
-	oldState, getStateErr := m.GetState(ctx, state.ObjectMeta.Name)
+	oldState, getStateErr := m.GetState(ctx, name)
 	if getStateErr == nil {
 		state.ObjectMeta.PreserveSystemMetadata(oldState.ObjectMeta)
 	}
+	// certificates and join tokens are only changed through enrollment
+	spec := model.SiteSpec{}
+	if state.Spec != nil {
+		spec = *state.Spec
+	}
+	spec.Certificates = nil
+	spec.JoinToken = nil
+	if getStateErr == nil {
+		spec.Certificates = oldState.Spec.Certificates
+		spec.JoinToken = oldState.Spec.JoinToken
+	}
+	state.Spec = &spec
 
+	err = m.upsertSite(ctx, name, state)
+	return err
+}
+
+func (m *SitesManager) upsertSite(ctx context.Context, name string, state model.SiteState) error {
 	upsertRequest := states.UpsertRequest{
 		Value: states.StateEntry{
 			ID: name,
@@ -206,11 +261,8 @@ func (m *SitesManager) UpsertState(ctx context.Context, name string, state model
 			"kind":      "Site",
 		},
 	}
-	_, err = m.StateProvider.Upsert(ctx, upsertRequest)
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err := m.StateProvider.Upsert(ctx, upsertRequest)
+	return err
 }
 
 func (m *SitesManager) DeleteSpec(ctx context.Context, name string) error {
@@ -278,6 +330,10 @@ func (s *SitesManager) Poll() []error {
 	defer observ_utils.CloseSpanWithError(span, &err)
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
+	if certErr := s.ensureCertificate(ctx); certErr != nil {
+		log.WarnfCtx(ctx, " M (Sites): failed to get a client certificate from the parent site: %s", certErr.Error())
+	}
+
 	var thisSite model.SiteState
 	thisSite, err = s.GetState(ctx, s.VendorContext.SiteInfo.SiteId)
 	if err != nil {
@@ -285,6 +341,23 @@ func (s *SitesManager) Poll() []error {
 		return nil
 	}
 	thisSite.Spec.IsSelf = false
+	status, statusErr := s.RollUp(ctx)
+	if statusErr != nil {
+		log.WarnfCtx(ctx, " M (Sites): failed to get the status to report to the parent site: %s", statusErr.Error())
+	} else {
+		thisSite.Status = &status
+	}
+	if s.Channel != nil && s.Channel.Connected() {
+		err = s.Channel.Report(ctx, model.ChannelMessage{
+			Type: model.ChannelHeartbeat,
+			Site: &thisSite,
+		})
+		if err == nil {
+			return nil
+		}
+		log.WarnfCtx(ctx, " M (Sites): failed to send heartbeat over the push channel, falling back to a request: %s", err.Error())
+		err = nil
+	}
 	jData, _ := json.Marshal(thisSite)
 	s.apiClient.UpdateSite(
 		ctx,
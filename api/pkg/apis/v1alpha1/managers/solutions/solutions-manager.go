@@ -49,8 +49,8 @@ func (s *SolutionsManager) Init(context *contexts.VendorContext, config managers
 	s.needValidate = managers.NeedObjectValidate(config, providers)
 	if s.needValidate {
 		// Turn off validation of differnt types: https://github.com/eclipse-symphony/symphony/issues/445
-		// s.SolutionValidator = validation.NewSolutionValidator(s.solutionInstanceLookup, s.solutionContainerLookup, s.uniqueNameSolutionLookup)
-		s.SolutionValidator = validation.NewSolutionValidator(nil, nil, s.uniqueNameSolutionLookup)
+		// s.SolutionValidator = validation.NewSolutionValidator(s.solutionInstanceLookup, s.solutionContainerLookup, s.uniqueNameSolutionLookup, s.catalogLookup)
+		s.SolutionValidator = validation.NewSolutionValidator(nil, nil, s.uniqueNameSolutionLookup, s.catalogLookup)
 	}
 	return nil
 }
@@ -238,3 +238,7 @@ func (t *SolutionsManager) solutionContainerLookup(ctx context.Context, name str
 func (t *SolutionsManager) uniqueNameSolutionLookup(ctx context.Context, displayName string, namespace string) (interface{}, error) {
 	return states.GetObjectStateWithUniqueName(ctx, t.StateProvider, validation.Solution, displayName, namespace)
 }
+
+func (t *SolutionsManager) catalogLookup(ctx context.Context, name string, namespace string) (interface{}, error) {
+	return states.GetObjectState(ctx, t.StateProvider, validation.Catalog, name, namespace)
+}
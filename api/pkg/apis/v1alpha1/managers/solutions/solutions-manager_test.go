@@ -12,6 +12,7 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,7 +46,7 @@ func TestCreateSolutionWithMissingContainer(t *testing.T) {
 		StateProvider: stateProvider,
 		needValidate:  true,
 	}
-	manager.SolutionValidator = validation.NewSolutionValidator(nil, manager.solutionContainerLookup, nil)
+	manager.SolutionValidator = validation.NewSolutionValidator(nil, manager.solutionContainerLookup, nil, nil)
 	err := manager.UpsertState(context.Background(), "test-v-version1", model.SolutionState{
 		ObjectMeta: model.ObjectMeta{
 			Name:      "test-v-version1",
@@ -68,7 +69,7 @@ func TestCreateSolutionWithMissingContainer(t *testing.T) {
 			StateProvider: stateProvider,
 			needValidate:  true,
 		}
-		manager.SolutionValidator = validation.NewSolutionValidator(nil, manager.solutionContainerLookup, nil)
+		manager.SolutionValidator = validation.NewSolutionValidator(nil, manager.solutionContainerLookup, nil, nil)
 		stateProvider.Upsert(context.Background(), states.UpsertRequest{
 			Value: states.StateEntry{
 				ID: "test",
@@ -112,7 +113,7 @@ func TestCreateSolutionWithSameDisplayName(t *testing.T) {
 		StateProvider: stateProvider,
 		needValidate:  true,
 	}
-	manager.SolutionValidator = validation.NewSolutionValidator(nil, nil, manager.uniqueNameSolutionLookup)
+	manager.SolutionValidator = validation.NewSolutionValidator(nil, nil, manager.uniqueNameSolutionLookup, nil)
 	err := manager.UpsertState(context.Background(), "test-v-version1", model.SolutionState{
 		ObjectMeta: model.ObjectMeta{
 			Name:      "test-v-version1",
@@ -147,7 +148,7 @@ func TestDeleteSolutionWithInstance(t *testing.T) {
 		StateProvider: stateProvider,
 		needValidate:  true,
 	}
-	manager.SolutionValidator = validation.NewSolutionValidator(manager.solutionInstanceLookup, nil, nil)
+	manager.SolutionValidator = validation.NewSolutionValidator(manager.solutionInstanceLookup, nil, nil, nil)
 	stateProvider.Upsert(context.Background(), states.UpsertRequest{
 		Value: states.StateEntry{
 			ID: "test",
@@ -193,3 +194,69 @@ func TestDeleteSolutionWithInstance(t *testing.T) {
 	assert.Contains(t, err.Error(), "Solution has one or more associated instances")
 }
 */
+
+func TestCreateSolutionWithComponentSchema(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := SolutionsManager{
+		StateProvider: stateProvider,
+		needValidate:  true,
+	}
+	manager.SolutionValidator = validation.NewSolutionValidator(nil, nil, nil, manager.catalogLookup)
+	_, err := stateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "web-schema-v-v1",
+			Body: map[string]interface{}{
+				"metadata": model.ObjectMeta{Name: "web-schema-v-v1", Namespace: "default"},
+				"spec": model.CatalogSpec{
+					CatalogType: "schema",
+					Properties: map[string]interface{}{
+						"spec": map[string]interface{}{
+							"type":     "object",
+							"required": []interface{}{"image"},
+							"properties": map[string]interface{}{
+								"image":    map[string]interface{}{"type": "string"},
+								"replicas": map[string]interface{}{"type": "integer", "default": 1},
+							},
+						},
+					},
+				},
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+			"group":     model.FederationGroup,
+			"version":   "v1",
+			"resource":  "catalogs",
+			"kind":      "Catalog",
+		},
+	})
+	assert.Nil(t, err)
+
+	solution := model.SolutionState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "web-v-v1",
+			Namespace: "default",
+		},
+		Spec: &model.SolutionSpec{
+			RootResource: "web",
+			Components: []model.ComponentSpec{
+				{
+					Name:     "frontend",
+					Metadata: map[string]string{"schema": "web-schema:v1"},
+				},
+			},
+		},
+	}
+	err = manager.UpsertState(context.Background(), "web-v-v1", solution)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "spec.components[0].properties")
+	assert.Contains(t, err.Error(), "/image: image is required")
+
+	solution.Spec.Components[0].Properties = map[string]interface{}{"image": "nginx"}
+	err = manager.UpsertState(context.Background(), "web-v-v1", solution)
+	assert.Nil(t, err)
+	stored, err := manager.GetState(context.Background(), "web-v-v1", "default")
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, stored.Spec.Components[0].Properties["replicas"])
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	coalogcontexts "github.com/eclipse-symphony/symphony/coa/pkg/logger/contexts"
+)
+
+// activationControl tracks pause and cancel requests for an activation in this process. The state itself
+// is persisted through the state provider, this only caches it for the stages running here.
+type activationControl struct {
+	state string
+	// resumed is closed when a paused activation is resumed or cancelled
+	resumed chan struct{}
+	// cancels cancel the contexts of the stages that are running for the activation
+	cancels map[uint64]context.CancelFunc
+}
+
+// controlPollInterval is how often a paused task dispatch checks the persisted state, so that a resume
+// applied by another process is noticed
+var controlPollInterval = 5 * time.Second
+
+func controlKey(namespace string, activation string) string {
+	return fmt.Sprintf("%s/%s", namespace, activation)
+}
+
+func heldTriggerID(activation string) string {
+	return fmt.Sprintf("%s-held", activation)
+}
+
+func controlStateID(activation string) string {
+	return fmt.Sprintf("%s-control", activation)
+}
+
+func (s *StageManager) getControl(key string) *activationControl {
+	if s.controls == nil {
+		s.controls = make(map[string]*activationControl)
+	}
+	c, ok := s.controls[key]
+	if !ok {
+		c = &activationControl{
+			state:   model.ActivationStateRunning,
+			cancels: make(map[uint64]context.CancelFunc),
+		}
+		s.controls[key] = c
+	}
+	return c
+}
+
+// applyControl moves the cached control of an activation to the given state, waking up paused task
+// dispatches and cancelling running stages as needed. The caller must hold controlLock.
+func (s *StageManager) applyControl(key string, state string) {
+	c := s.getControl(key)
+	switch state {
+	case model.ActivationStatePaused:
+		if c.state == model.ActivationStateRunning {
+			c.state = state
+			c.resumed = make(chan struct{})
+		}
+	case model.ActivationStateRunning:
+		if c.state == model.ActivationStatePaused {
+			c.state = state
+			close(c.resumed)
+		}
+		s.releaseControl(key, c)
+	case model.ActivationStateCancelled:
+		if c.state == model.ActivationStatePaused {
+			close(c.resumed)
+		}
+		c.state = state
+		for _, cancel := range c.cancels {
+			cancel()
+		}
+	}
+}
+
+// releaseControl drops the control of a running activation once no stage is running for it
+func (s *StageManager) releaseControl(key string, c *activationControl) {
+	if c.state == model.ActivationStateRunning && len(c.cancels) == 0 {
+		delete(s.controls, key)
+	}
+}
+
+// ControlActivation applies the desired state of an activation. Pausing holds back the next stage and
+// the next task dispatch; cancelling also cancels the context of the stage that is running. Resuming
+// returns the stage that was held back while the activation was paused, which needs to be triggered again.
+func (s *StageManager) ControlActivation(ctx context.Context, activation string, namespace string, state string) (*v1alpha2.ActivationData, error) {
+	if state == "" {
+		state = model.ActivationStateRunning
+	}
+	if !model.IsValidActivationState(state) {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid activation state %s", state), v1alpha2.BadRequest)
+	}
+	log.InfofCtx(ctx, " M (Stage): ControlActivation for activation %s in namespace %s to %s", activation, namespace, state)
+
+	previous, err := s.loadControlState(ctx, namespace, activation)
+	if err != nil {
+		return nil, err
+	}
+	if previous == model.ActivationStateCancelled && state != model.ActivationStateCancelled {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is cancelled", activation), v1alpha2.BadRequest)
+	}
+	// The state is persisted before it is applied, so that a concurrent read never brings back the previous one
+	err = s.saveControlState(ctx, namespace, activation, state)
+	if err != nil {
+		return nil, err
+	}
+
+	s.controlLock.Lock()
+	s.applyControl(controlKey(namespace, activation), state)
+	s.controlLock.Unlock()
+
+	switch state {
+	case model.ActivationStateRunning:
+		return s.takeHeldTrigger(ctx, activation, namespace)
+	case model.ActivationStateCancelled:
+		_, err := s.takeHeldTrigger(ctx, activation, namespace)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// getActivationControlState reads the persisted state of an activation and brings the cached control in
+// line with it, in case the state was changed by another process. If the state can't be read, the cached
+// state is used.
+func (s *StageManager) getActivationControlState(ctx context.Context, namespace string, activation string) string {
+	key := controlKey(namespace, activation)
+	state, err := s.loadControlState(ctx, namespace, activation)
+
+	s.controlLock.Lock()
+	defer s.controlLock.Unlock()
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Stage): failed to read the state of activation %s, using the cached state: %v", activation, err)
+		if c, ok := s.controls[key]; ok {
+			return c.state
+		}
+		return model.ActivationStateRunning
+	}
+	if _, ok := s.controls[key]; ok || state != model.ActivationStateRunning {
+		s.applyControl(key, state)
+	}
+	return state
+}
+
+func (s *StageManager) loadControlState(ctx context.Context, namespace string, activation string) (string, error) {
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: controlStateID(activation),
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+		},
+	})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return model.ActivationStateRunning, nil
+		}
+		return "", err
+	}
+	state, ok := entry.Body.(string)
+	if !ok || !model.IsValidActivationState(state) {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid state of activation %s", activation), v1alpha2.InternalError)
+	}
+	if state == "" {
+		state = model.ActivationStateRunning
+	}
+	return state, nil
+}
+
+// saveControlState persists the state of a paused or cancelled activation. A running activation has no
+// persisted state.
+func (s *StageManager) saveControlState(ctx context.Context, namespace string, activation string, state string) error {
+	if state == model.ActivationStateRunning {
+		err := s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: controlStateID(activation),
+			Metadata: map[string]interface{}{
+				"namespace": namespace,
+			},
+		})
+		if err != nil && !utils.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   controlStateID(activation),
+			Body: state,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+		},
+	})
+	return err
+}
+
+// trackActivation derives a context that is cancelled when the activation is cancelled. The returned
+// function must be called once the stage is finished.
+func (s *StageManager) trackActivation(ctx context.Context, namespace string, activation string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := controlKey(namespace, activation)
+
+	s.controlLock.Lock()
+	c := s.getControl(key)
+	s.nextCancelID++
+	id := s.nextCancelID
+	c.cancels[id] = cancel
+	if c.state == model.ActivationStateCancelled {
+		cancel()
+	}
+	s.controlLock.Unlock()
+
+	return ctx, func() {
+		cancel()
+		s.controlLock.Lock()
+		defer s.controlLock.Unlock()
+		delete(c.cancels, id)
+		if s.controls[key] == c {
+			s.releaseControl(key, c)
+		}
+	}
+}
+
+// waitWhilePaused blocks while the activation is paused. It returns false if the context is done before
+// the activation is resumed, which is the case when the activation is cancelled.
+func (s *StageManager) waitWhilePaused(ctx context.Context, namespace string, activation string) bool {
+	// Reading the state starts spans, which write to the diagnostic log context that the running tasks share
+	ctx = withOwnDiagnosticLogContext(ctx)
+	key := controlKey(namespace, activation)
+	logged := false
+	for s.getActivationControlState(ctx, namespace, activation) == model.ActivationStatePaused {
+		s.controlLock.Lock()
+		var resumed chan struct{}
+		if c, ok := s.controls[key]; ok && c.state == model.ActivationStatePaused {
+			resumed = c.resumed
+		}
+		s.controlLock.Unlock()
+		if resumed == nil {
+			continue
+		}
+		if !logged {
+			log.InfofCtx(ctx, " M (Stage): activation %s is paused, waiting to dispatch the next task", activation)
+			logged = true
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return false
+		case <-time.After(controlPollInterval):
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// withOwnDiagnosticLogContext gives the context a copy of its diagnostic log context
+func withOwnDiagnosticLogContext(ctx context.Context) context.Context {
+	if diagCtx, ok := ctx.Value(coalogcontexts.DiagnosticLogContextKey).(*coalogcontexts.DiagnosticLogContext); ok {
+		return coalogcontexts.OverrideDiagnosticLogContextToCurrentContext(diagCtx.DeepCopy(), ctx)
+	}
+	return ctx
+}
+
+// holdTrigger saves a stage that is triggered while its activation is paused so that it can be triggered
+// again when the activation is resumed
+func (s *StageManager) holdTrigger(ctx context.Context, triggerData v1alpha2.ActivationData) error {
+	_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   heldTriggerID(triggerData.Activation),
+			Body: triggerData,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": triggerData.Namespace,
+		},
+	})
+	return err
+}
+
+func (s *StageManager) takeHeldTrigger(ctx context.Context, activation string, namespace string) (*v1alpha2.ActivationData, error) {
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: heldTriggerID(activation),
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+		},
+	})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = s.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID: heldTriggerID(activation),
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var triggerData v1alpha2.ActivationData
+	jData, _ := json.Marshal(entry.Body)
+	err = json.Unmarshal(jData, &triggerData)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "invalid held stage", v1alpha2.InternalError)
+	}
+	return &triggerData, nil
+}
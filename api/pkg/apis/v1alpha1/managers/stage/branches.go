@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+// joinRecord tracks the predecessors that have arrived at a join stage of an activation
+type joinRecord struct {
+	Arrivals map[string]joinArrival `json:"arrivals"`
+	// Fired is set once the join stage is triggered. Predecessors that arrive afterwards are ignored.
+	Fired bool `json:"fired,omitempty"`
+}
+
+type joinArrival struct {
+	Branch  string                            `json:"branch,omitempty"`
+	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
+}
+
+func joinRecordID(triggerData v1alpha2.ActivationData) string {
+	return fmt.Sprintf("%s-%s-%s-join-%s", triggerData.Campaign, triggerData.Activation, triggerData.ActivationGeneration, triggerData.Stage)
+}
+
+// branchName gets the name of the branch that starts with the stage. Nested branches are separated by slashes.
+func branchName(parent string, stage string) string {
+	if parent == "" {
+		return stage
+	}
+	return fmt.Sprintf("%s/%s", parent, stage)
+}
+
+func parentBranch(branch string) string {
+	if i := strings.LastIndex(branch, "/"); i >= 0 {
+		return branch[:i]
+	}
+	return ""
+}
+
+// copyOutputs copies the outputs context so that parallel branches don't share it
+func copyOutputs(outputs map[string]map[string]interface{}) map[string]map[string]interface{} {
+	ret := make(map[string]map[string]interface{}, len(outputs))
+	for stage, stageOutputs := range outputs {
+		ret[stage] = make(map[string]interface{}, len(stageOutputs))
+		for k, v := range stageOutputs {
+			ret[stage][k] = v
+		}
+	}
+	return ret
+}
+
+// HandleTriggerEventWithBranches runs the triggered stage and returns the next stages to trigger. A stage
+// with NextStages fans out to parallel branches, which a join stage merges back once enough of its
+// predecessors have arrived. A join stage that is still waiting reports Delayed; a predecessor that
+// arrives after the join stage is triggered gets an Untouched status, which doesn't need to be reported.
+func (s *StageManager) HandleTriggerEventWithBranches(ctx context.Context, campaign model.CampaignSpec, triggerData v1alpha2.ActivationData) (model.StageStatus, []v1alpha2.ActivationData) {
+	var arrivals map[string]joinArrival
+	if currentStage, ok := campaign.Stages[triggerData.Stage]; ok && currentStage.Join != nil {
+		var status *model.StageStatus
+		triggerData, arrivals, status = s.arriveAtJoin(ctx, *currentStage.Join, triggerData)
+		if status != nil {
+			return *status, nil
+		}
+	}
+
+	// The stage adds its outputs to the outputs context, which the next stages get
+	if triggerData.Outputs == nil {
+		triggerData.Outputs = make(map[string]map[string]interface{})
+	}
+	status, activationData := s.runStage(ctx, campaign, triggerData, nil)
+	status.Branch = triggerData.Branch
+
+	// The outputs of the predecessors of a join stage are merged under their stage names
+	for predecessor, arrival := range arrivals {
+		for k, v := range arrival.Outputs[predecessor] {
+			if strings.HasPrefix(k, "__") || strings.HasPrefix(k, "header.") {
+				continue
+			}
+			key := fmt.Sprintf("%s.%s", predecessor, k)
+			if _, ok := status.Outputs[key]; !ok {
+				status.Outputs[key] = v
+			}
+			if stageOutputs, ok := triggerData.Outputs[triggerData.Stage]; ok {
+				if _, ok := stageOutputs[key]; !ok {
+					stageOutputs[key] = v
+				}
+			}
+		}
+	}
+
+	if activationData != nil {
+		activationData.Branch = triggerData.Branch
+		return status, []v1alpha2.ActivationData{*activationData}
+	}
+	if len(status.NextStages) == 0 {
+		return status, nil
+	}
+	activations := make([]v1alpha2.ActivationData, 0, len(status.NextStages))
+	for _, nextStageName := range status.NextStages {
+		nextStage := campaign.Stages[nextStageName]
+		activations = append(activations, v1alpha2.ActivationData{
+			Campaign:             triggerData.Campaign,
+			Activation:           triggerData.Activation,
+			ActivationGeneration: triggerData.ActivationGeneration,
+			Stage:                nextStageName,
+			Inputs:               triggerData.Inputs,
+			Outputs:              copyOutputs(triggerData.Outputs),
+			Provider:             nextStage.Provider,
+			Config:               nextStage.Config,
+			TriggeringStage:      triggerData.Stage,
+			Schedule:             nextStage.Schedule,
+			Namespace:            triggerData.Namespace,
+			Branch:               branchName(triggerData.Branch, nextStageName),
+		})
+	}
+	return status, activations
+}
+
+// arriveAtJoin records the arrival of a predecessor at a join stage. It returns the status to report when
+// the join stage isn't triggered yet, otherwise the trigger data with the merged outputs of the branches.
+func (s *StageManager) arriveAtJoin(ctx context.Context, join model.JoinSpec, triggerData v1alpha2.ActivationData) (v1alpha2.ActivationData, map[string]joinArrival, *model.StageStatus) {
+	status := model.StageStatus{
+		Stage:    triggerData.Stage,
+		Branch:   parentBranch(triggerData.Branch),
+		Outputs:  map[string]interface{}{},
+		IsActive: true,
+	}
+	predecessor := triggerData.TriggeringStage
+	found := false
+	for _, p := range join.Predecessors {
+		if p == predecessor {
+			found = true
+			break
+		}
+	}
+	if !found {
+		s.setStageStatus(&status, "", v1alpha2.BadRequest, fmt.Sprintf("stage %s is not a predecessor of join stage %s", predecessor, triggerData.Stage))
+		log.ErrorfCtx(ctx, " M (Stage): %s", status.ErrorMessage)
+		return triggerData, nil, &status
+	}
+
+	s.joinLock.Lock()
+	defer s.joinLock.Unlock()
+
+	record, err := s.getJoinRecord(ctx, triggerData)
+	if err != nil {
+		s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+		log.ErrorfCtx(ctx, " M (Stage): failed to get join record of stage %s: %v", triggerData.Stage, err)
+		return triggerData, nil, &status
+	}
+	if record.Fired {
+		record.Arrivals[predecessor] = joinArrival{Branch: triggerData.Branch}
+		err = s.saveJoinRecord(ctx, triggerData, record, len(record.Arrivals) >= len(join.Predecessors))
+		if err != nil {
+			s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+			log.ErrorfCtx(ctx, " M (Stage): failed to save join record of stage %s: %v", triggerData.Stage, err)
+			return triggerData, nil, &status
+		}
+		log.InfofCtx(ctx, " M (Stage): join stage %s is already triggered, ignoring predecessor %s", triggerData.Stage, predecessor)
+		s.setStageStatus(&status, "", v1alpha2.Untouched, "")
+		return triggerData, nil, &status
+	}
+
+	record.Arrivals[predecessor] = joinArrival{
+		Branch:  triggerData.Branch,
+		Outputs: triggerData.Outputs,
+	}
+	if len(record.Arrivals) < join.RequiredCount() {
+		err = s.saveJoinRecord(ctx, triggerData, record, false)
+		if err != nil {
+			s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+			log.ErrorfCtx(ctx, " M (Stage): failed to save join record of stage %s: %v", triggerData.Stage, err)
+			return triggerData, nil, &status
+		}
+		log.InfofCtx(ctx, " M (Stage): join stage %s is waiting, %d of %d predecessors arrived", triggerData.Stage, len(record.Arrivals), join.RequiredCount())
+		status.Status = v1alpha2.Delayed
+		status.StatusMessage = v1alpha2.Delayed.String()
+		return triggerData, nil, &status
+	}
+
+	// Remove the record once all predecessors arrived so the join stage can run again in a loop
+	record.Fired = true
+	err = s.saveJoinRecord(ctx, triggerData, record, len(record.Arrivals) >= len(join.Predecessors))
+	if err != nil {
+		s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+		log.ErrorfCtx(ctx, " M (Stage): failed to save join record of stage %s: %v", triggerData.Stage, err)
+		return triggerData, nil, &status
+	}
+	outputs := make(map[string]map[string]interface{})
+	for _, p := range join.Predecessors {
+		if arrival, ok := record.Arrivals[p]; ok {
+			for stage, stageOutputs := range arrival.Outputs {
+				outputs[stage] = stageOutputs
+			}
+		}
+	}
+	log.InfofCtx(ctx, " M (Stage): join stage %s is triggered, %d of %d predecessors arrived", triggerData.Stage, len(record.Arrivals), len(join.Predecessors))
+	triggerData.Outputs = outputs
+	triggerData.Branch = status.Branch
+	return triggerData, record.Arrivals, nil
+}
+
+func (s *StageManager) getJoinRecord(ctx context.Context, triggerData v1alpha2.ActivationData) (joinRecord, error) {
+	record := joinRecord{
+		Arrivals: make(map[string]joinArrival),
+	}
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: joinRecordID(triggerData),
+		Metadata: map[string]interface{}{
+			"namespace": triggerData.Namespace,
+		},
+	})
+	if err != nil {
+		if utils.IsNotFound(err) {
+			return record, nil
+		}
+		return record, err
+	}
+	jData, _ := json.Marshal(entry.Body)
+	err = json.Unmarshal(jData, &record)
+	if err != nil {
+		return record, v1alpha2.NewCOAError(err, "invalid join record", v1alpha2.InternalError)
+	}
+	if record.Arrivals == nil {
+		record.Arrivals = make(map[string]joinArrival)
+	}
+	return record, nil
+}
+
+func (s *StageManager) saveJoinRecord(ctx context.Context, triggerData v1alpha2.ActivationData, record joinRecord, done bool) error {
+	if done {
+		err := s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: joinRecordID(triggerData),
+			Metadata: map[string]interface{}{
+				"namespace": triggerData.Namespace,
+			},
+		})
+		if err != nil && !utils.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   joinRecordID(triggerData),
+			Body: record,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": triggerData.Namespace,
+		},
+	})
+	return err
+}
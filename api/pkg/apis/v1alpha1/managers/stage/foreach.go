@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/remote"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+)
+
+// ForEachItemHandler implements TaskHandler to run the provider of a stage for an item of its forEach list.
+// Items are dispatched as tasks named after their index.
+type ForEachItemHandler struct {
+	manager     *StageManager
+	triggerData v1alpha2.ActivationData
+	triggers    map[string]interface{}
+	items       []interface{}
+}
+
+func NewForEachItemHandler(manager *StageManager, triggerData v1alpha2.ActivationData, triggers map[string]interface{}, items []interface{}) *ForEachItemHandler {
+	return &ForEachItemHandler{
+		manager:     manager,
+		triggerData: triggerData,
+		triggers:    triggers,
+		items:       items,
+	}
+}
+
+func (h *ForEachItemHandler) HandleTask(ctx context.Context, task model.TaskSpec, inputs map[string]interface{}, siteName string) (map[string]interface{}, error) {
+	index, err := strconv.Atoi(task.Name)
+	if err != nil || index < 0 || index >= len(h.items) {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("item %s is not found", task.Name), v1alpha2.InternalError)
+	}
+
+	// Bind the item, then evaluate the stage inputs for it
+	itemInputs := make(map[string]interface{}, len(inputs)+2)
+	for k, v := range inputs {
+		itemInputs[k] = v
+	}
+	itemInputs["__item"] = h.items[index]
+	itemInputs["__index"] = index
+	for k, v := range itemInputs {
+		if k == "__item" {
+			continue
+		}
+		val, err := h.manager.traceValue(ctx, v, h.triggerData.Namespace, itemInputs, h.triggers, h.triggerData.Outputs)
+		if err != nil {
+			return nil, err
+		}
+		itemInputs[k] = val
+	}
+
+	// Each item gets a provider of its own, as items run concurrently
+	provider, err := h.manager.createProvider(h.triggerData.Provider, h.triggerData.Config, h.triggerData.Stage)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, v1alpha2.COAError{
+			State:   v1alpha2.BadConfig,
+			Message: fmt.Sprintf("provider %s is not found, skipping item %d for site %s", h.triggerData.Provider, index, siteName),
+		}
+	}
+	if _, ok := provider.(stage.IStageProvider); !ok {
+		return nil, v1alpha2.COAError{
+			State:   v1alpha2.BadConfig,
+			Message: fmt.Sprintf("non-stage provider cannot be used with forEach, skipping item %d for site %s", index, siteName),
+		}
+	}
+	if _, ok := provider.(*remote.RemoteStageProvider); ok {
+		return nil, v1alpha2.COAError{
+			State:   v1alpha2.BadConfig,
+			Message: fmt.Sprintf("remote stage provider cannot be used with forEach, skipping item %d for site %s", index, siteName),
+		}
+	}
+	if _, ok := provider.(contexts.IWithManagerContext); ok {
+		provider.(contexts.IWithManagerContext).SetContext(h.manager.Manager.Context)
+	}
+
+	outputs, _, err := provider.(stage.IStageProvider).Process(ctx, *h.manager.Manager.Context, itemInputs)
+	return outputs, err
+}
+
+// evaluateForEach evaluates the forEach expression of a stage to the list of items the stage runs for
+func (s *StageManager) evaluateForEach(ctx context.Context, currentStage model.StageSpec, triggerData v1alpha2.ActivationData) ([]interface{}, error) {
+	parser := utils.NewParser(currentStage.ForEach)
+	eCtx := s.VendorContext.EvaluationContext.Clone()
+	eCtx.Context = ctx
+	eCtx.Namespace = triggerData.Namespace
+	eCtx.Triggers = triggerData.Inputs
+	eCtx.Inputs = currentStage.Inputs
+	if eCtx.Inputs != nil {
+		if v, ok := eCtx.Inputs["context"]; ok {
+			eCtx.Value = v
+		}
+	}
+	eCtx.Outputs = triggerData.Outputs
+	val, err := parser.Eval(*eCtx)
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case nil:
+		return []interface{}{}, nil
+	case []interface{}:
+		return v, nil
+	case []string:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return items, nil
+	case string:
+		// An empty string is an empty list, other strings need to be JSON arrays
+		if v == "" {
+			return []interface{}{}, nil
+		}
+		var items []interface{}
+		if err := json.Unmarshal([]byte(v), &items); err == nil {
+			return items, nil
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("forEach %s doesn't evaluate to a list", currentStage.ForEach), v1alpha2.BadConfig)
+}
+
+// processForEach runs the provider of a stage for every item. Items are dispatched like tasks, following the
+// concurrency and the error action in the task option of the stage; the concurrency defaults to 1, so items
+// run one after another. The outputs of the items are returned in the "items" list, in the order of the items.
+func (s *StageManager) processForEach(ctx context.Context, currentStage model.StageSpec, items []interface{}, inputCopy map[string]interface{}, triggerData v1alpha2.ActivationData, triggers map[string]interface{}, siteName string) (map[string]interface{}, error) {
+	log.InfofCtx(ctx, " M (Stage): processing %d items of stage %s for site %s", len(items), triggerData.Stage, siteName)
+
+	tasks := make([]model.TaskSpec, 0, len(items))
+	for i := range items {
+		tasks = append(tasks, model.TaskSpec{Name: strconv.Itoa(i)})
+	}
+	concurrency := currentStage.TaskOption.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	processor := NewGoRoutineTaskProcessor(s, ctx)
+	handler := NewForEachItemHandler(s, triggerData, triggers, items)
+	results, err := processor.Process(ctx, tasks, inputCopy, handler, currentStage.TaskOption.ErrorAction, concurrency, siteName)
+
+	// Items that weren't dispatched because of the error action have no outputs
+	itemOutputs := make([]interface{}, len(items))
+	for i := range items {
+		if result, ok := results[strconv.Itoa(i)]; ok {
+			itemOutputs[i] = result
+		}
+	}
+	return map[string]interface{}{
+		"items": itemOutputs,
+	}, err
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+)
+
+// hookRun describes a stage that runs as a lifecycle hook of a finished activation
+type hookRun struct {
+	// Name is the hook the stage runs for, such as onFailure
+	Name string
+	// Inputs are added to the inputs of the stage
+	Inputs map[string]interface{}
+}
+
+// hookStage is a stage to run as a lifecycle hook
+type hookStage struct {
+	hook  string
+	stage string
+}
+
+// getHookStages gets the hook stages to run for a finished activation, in the order they run
+func getHookStages(hooks *model.CampaignHooks, status v1alpha2.State) []hookStage {
+	ret := make([]hookStage, 0)
+	if hooks == nil {
+		return ret
+	}
+	switch GetActivationEvent(status) {
+	case model.NotificationEventSuccess:
+		for _, stage := range hooks.OnSuccess {
+			ret = append(ret, hookStage{hook: model.HookOnSuccess, stage: stage})
+		}
+	case model.NotificationEventFailure:
+		for _, stage := range hooks.OnFailure {
+			ret = append(ret, hookStage{hook: model.HookOnFailure, stage: stage})
+		}
+	}
+	for _, stage := range hooks.Finally {
+		ret = append(ret, hookStage{hook: model.HookFinally, stage: stage})
+	}
+	return ret
+}
+
+// getFinishingStage gets the stage that finished an activation, which is the last stage that reported the
+// status of the activation. Later entries may belong to other branches or hooks.
+func getFinishingStage(history []model.StageStatus, status v1alpha2.State) model.StageStatus {
+	ret := model.StageStatus{}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Hook != "" {
+			continue
+		}
+		if ret.Stage == "" {
+			ret = history[i]
+		}
+		if history[i].Status == status {
+			return history[i]
+		}
+	}
+	return ret
+}
+
+// RunHooks runs the lifecycle hooks of a campaign once an activation is finished. The onSuccess or onFailure
+// stages run first, then the finally stages. Hook stages get the outputs of all stages through $output(),
+// and these inputs:
+//
+//	__hook: the hook the stage runs for
+//	__status: the status of the activation
+//	__failedStage: the stage that finished the activation
+//	__error: the error of that stage
+//	__failedOutputs: the outputs of that stage
+//
+// A failed hook stage doesn't stop the others, nor does it change the status of the activation. The statuses
+// of the hook stages are returned in the order they ran.
+func (s *StageManager) RunHooks(ctx context.Context, campaign model.CampaignSpec, activation model.ActivationState) ([]model.StageStatus, error) {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "RunHooks",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	statuses := make([]model.StageStatus, 0)
+	if activation.Status == nil {
+		return statuses, nil
+	}
+	hookStages := getHookStages(campaign.Hooks, activation.Status.Status)
+	if len(hookStages) == 0 {
+		return statuses, nil
+	}
+	log.InfofCtx(ctx, " M (Stage): running %d hook stages for activation %s in namespace %s", len(hookStages), activation.ObjectMeta.Name, activation.ObjectMeta.Namespace)
+
+	var triggers map[string]interface{}
+	campaignName := ""
+	if activation.Spec != nil {
+		triggers = activation.Spec.Inputs
+		campaignName = activation.Spec.Campaign
+	}
+	outputs := make(map[string]map[string]interface{})
+	for _, status := range activation.Status.StageHistory {
+		outputs[status.Stage] = status.Outputs
+	}
+	finishingStage := getFinishingStage(activation.Status.StageHistory, activation.Status.Status)
+
+	// Hook stages run once, whatever their stage selectors and next stages
+	hookCampaign := campaign
+	hookCampaign.SelfDriving = false
+
+	errs := make([]error, 0)
+	for _, hs := range hookStages {
+		stageSpec, ok := campaign.Stages[hs.stage]
+		if !ok {
+			status := model.StageStatus{Stage: hs.stage, Hook: hs.hook, Outputs: map[string]interface{}{}}
+			s.setStageStatus(&status, "", v1alpha2.BadRequest, fmt.Sprintf("hook stage %s is not found", hs.stage))
+			log.ErrorfCtx(ctx, " M (Stage): %s", status.ErrorMessage)
+			statuses = append(statuses, status)
+			errs = append(errs, errors.New(status.ErrorMessage))
+			continue
+		}
+		triggerData := v1alpha2.ActivationData{
+			Campaign:             campaignName,
+			Activation:           activation.ObjectMeta.Name,
+			ActivationGeneration: activation.Status.ActivationGeneration,
+			Stage:                hs.stage,
+			Inputs:               triggers,
+			Outputs:              outputs,
+			Provider:             stageSpec.Provider,
+			Config:               stageSpec.Config,
+			TriggeringStage:      finishingStage.Stage,
+			Namespace:            activation.ObjectMeta.Namespace,
+		}
+		status, _ := s.runStage(ctx, hookCampaign, triggerData, &hookRun{
+			Name: hs.hook,
+			Inputs: map[string]interface{}{
+				"__hook":          hs.hook,
+				"__status":        activation.Status.Status.String(),
+				"__failedStage":   finishingStage.Stage,
+				"__error":         finishingStage.ErrorMessage,
+				"__failedOutputs": finishingStage.Outputs,
+			},
+		})
+		status.Hook = hs.hook
+		statuses = append(statuses, status)
+		if status.Status != v1alpha2.Done {
+			log.ErrorfCtx(ctx, " M (Stage): hook stage %s of activation %s failed: %s", hs.stage, activation.ObjectMeta.Name, status.ErrorMessage)
+			errs = append(errs, fmt.Errorf("hook stage %s: %s", hs.stage, status.ErrorMessage))
+			continue
+		}
+		log.InfofCtx(ctx, " M (Stage): hook stage %s of activation %s is done", hs.stage, activation.ObjectMeta.Name)
+	}
+	if len(errs) > 0 {
+		err = v1alpha2.NewCOAError(errors.Join(errs...), "failed to run hook stages", v1alpha2.InternalError)
+	}
+	return statuses, err
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+)
+
+const defaultNotificationProvider = "providers.stage.notification"
+
+// GetActivationEvent gets the campaign-level event raised by a finished activation
+func GetActivationEvent(status v1alpha2.State) string {
+	switch status {
+	case v1alpha2.Done, v1alpha2.OK:
+		return model.NotificationEventSuccess
+	case v1alpha2.Cancelled:
+		return model.NotificationEventCancelled
+	default:
+		return model.NotificationEventFailure
+	}
+}
+
+// SendNotifications sends the notifications of a campaign that listen to the event raised by a finished
+// activation. Notification inputs are evaluated like stage inputs: $trigger() gets the activation inputs and
+// $output() gets the outputs of the stages. The notification provider also gets the status of the activation,
+// the error and the name of the last stage, and the outputs of all stages. Notifications are sent one after
+// another; a failed notification doesn't stop the others.
+func (s *StageManager) SendNotifications(ctx context.Context, campaign model.CampaignSpec, activation model.ActivationState) error {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "SendNotifications",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if len(campaign.Notifications) == 0 || activation.Status == nil {
+		return nil
+	}
+	event := GetActivationEvent(activation.Status.Status)
+	log.InfofCtx(ctx, " M (Stage): activation %s in namespace %s raised event %s", activation.ObjectMeta.Name, activation.ObjectMeta.Namespace, event)
+
+	var triggers map[string]interface{}
+	campaignName := ""
+	if activation.Spec != nil {
+		triggers = activation.Spec.Inputs
+		campaignName = activation.Spec.Campaign
+	}
+	if triggers == nil {
+		triggers = make(map[string]interface{})
+	}
+	outputs := make(map[string]map[string]interface{})
+	for _, status := range activation.Status.StageHistory {
+		outputs[status.Stage] = status.Outputs
+	}
+	lastStage := getFinishingStage(activation.Status.StageHistory, activation.Status.Status)
+	stageOutputs := make(map[string]interface{}, len(outputs))
+	for k, v := range outputs {
+		stageOutputs[k] = v
+	}
+
+	errs := make([]error, 0)
+	for i, notification := range campaign.Notifications {
+		if !listensTo(notification, event) {
+			continue
+		}
+		name := notification.Name
+		if name == "" {
+			name = fmt.Sprintf("notification-%d", i)
+		}
+		inputs := make(map[string]interface{}, len(notification.Inputs)+8)
+		for k, v := range notification.Inputs {
+			inputs[k] = v
+		}
+		inputs["__campaign"] = campaignName
+		inputs["__activation"] = activation.ObjectMeta.Name
+		inputs["__namespace"] = activation.ObjectMeta.Namespace
+		inputs["__stage"] = lastStage.Stage
+		inputs["__event"] = event
+		inputs["__status"] = activation.Status.Status.String()
+		inputs["__error"] = lastStage.ErrorMessage
+		inputs["__outputs"] = stageOutputs
+		for k, v := range notification.Inputs {
+			var val interface{}
+			val, err = s.traceValue(ctx, v, activation.ObjectMeta.Namespace, inputs, triggers, outputs)
+			if err != nil {
+				break
+			}
+			inputs[k] = val
+		}
+		if err == nil {
+			err = s.sendNotification(ctx, name, notification, inputs)
+		}
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Stage): failed to send notification %s for activation %s: %v", name, activation.ObjectMeta.Name, err)
+			errs = append(errs, fmt.Errorf("notification %s: %w", name, err))
+			err = nil
+			continue
+		}
+		log.InfofCtx(ctx, " M (Stage): sent notification %s for activation %s", name, activation.ObjectMeta.Name)
+	}
+	if len(errs) > 0 {
+		err = v1alpha2.NewCOAError(errors.Join(errs...), "failed to send notifications", v1alpha2.InternalError)
+	}
+	return err
+}
+
+func (s *StageManager) sendNotification(ctx context.Context, name string, notification model.NotificationSpec, inputs map[string]interface{}) error {
+	providerName := notification.Provider
+	if providerName == "" {
+		providerName = defaultNotificationProvider
+	}
+	provider, err := s.createProvider(providerName, notification.Config, name)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("provider %s is not found", providerName), v1alpha2.BadConfig)
+	}
+	if _, ok := provider.(stage.IStageProvider); !ok {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("provider %s is not a stage provider", providerName), v1alpha2.BadConfig)
+	}
+	if _, ok := provider.(contexts.IWithManagerContext); ok {
+		provider.(contexts.IWithManagerContext).SetContext(s.Manager.Context)
+	}
+	_, _, err = provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputs)
+	return err
+}
+
+func listensTo(notification model.NotificationSpec, event string) bool {
+	for _, on := range notification.On {
+		if on == event || on == model.NotificationEventFinished {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package stage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+)
+
+const (
+	defaultSimulationSteps = 100
+	simulationActivation   = "simulation"
+)
+
+// simulation holds the scripted results of the stages and tasks of a simulated campaign
+type simulation struct {
+	lock    sync.Mutex
+	results map[string][]model.SimulatedStageResult
+	runs    map[string]int
+}
+
+// next gets the result of the next run of a stage or task. The last result is repeated once the results are
+// used up; stages and tasks without results succeed without outputs.
+func (s *simulation) next(script string) model.SimulatedStageResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	results := s.results[script]
+	if len(results) == 0 {
+		return model.SimulatedStageResult{}
+	}
+	run := s.runs[script]
+	s.runs[script] = run + 1
+	if run >= len(results) {
+		run = len(results) - 1
+	}
+	return results[run]
+}
+
+type simulatedStageProvider struct {
+	simulation *simulation
+	script     string
+}
+
+func (i *simulatedStageProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+
+func (i *simulatedStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	result := i.simulation.next(i.script)
+	outputs := make(map[string]interface{}, len(result.Outputs))
+	for k, v := range result.Outputs {
+		outputs[k] = v
+	}
+	if result.Error != "" {
+		return outputs, false, v1alpha2.NewCOAError(nil, result.Error, v1alpha2.InternalError)
+	}
+	return outputs, result.Pause, nil
+}
+
+var _ stage.IStageProvider = (*simulatedStageProvider)(nil)
+
+// SimulateCampaign runs a campaign with all stage providers replaced by providers that return the scripted
+// results in the simulation spec. Stages run one after another in the order they are triggered, through the
+// same state machine as an activation, so stage selectors, branches and joins behave the same. The simulation
+// stops when no stage is left to run, a stage pauses, or the step limit is reached.
+func (s *StageManager) SimulateCampaign(ctx context.Context, name string, namespace string, campaign model.CampaignSpec, spec model.CampaignSimulationSpec) (model.CampaignSimulationResult, error) {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "SimulateCampaign",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, " M (Stage): SimulateCampaign for campaign %s in namespace %s", name, namespace)
+
+	result := model.CampaignSimulationResult{
+		StageHistory: []model.StageStatus{},
+	}
+	validator := validation.NewCampaignValidator(nil, nil)
+	if errorFields := validator.ValidateSpec(model.CampaignState{Spec: &campaign}); len(errorFields) > 0 {
+		err = v1alpha2.NewCOAError(nil, "campaign is invalid: "+validation.ConvertErrorFieldsToString(errorFields), v1alpha2.BadRequest)
+		log.ErrorfCtx(ctx, " M (Stage): failed to simulate campaign %s: %v", name, err)
+		return result, err
+	}
+	if campaign.FirstStage == "" {
+		result.Status = v1alpha2.Done
+		result.StatusMessage = v1alpha2.Done.String()
+		return result, nil
+	}
+
+	// The simulation runs in a manager of its own, so that join records and paused stages are kept in memory
+	stateProvider := &memorystate.MemoryStateProvider{}
+	err = stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	if err != nil {
+		return result, err
+	}
+	simulator := &StageManager{
+		Manager:       s.Manager,
+		StateProvider: stateProvider,
+		apiClient:     s.apiClient,
+		simulation: &simulation{
+			results: spec.Stages,
+			runs:    make(map[string]int),
+		},
+	}
+	maxSteps := spec.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultSimulationSteps
+	}
+
+	firstStage := campaign.Stages[campaign.FirstStage]
+	queue := []v1alpha2.ActivationData{
+		{
+			Campaign:             name,
+			Activation:           simulationActivation,
+			ActivationGeneration: "1",
+			Stage:                campaign.FirstStage,
+			Inputs:               spec.Inputs,
+			Provider:             firstStage.Provider,
+			Config:               firstStage.Config,
+			Namespace:            namespace,
+		},
+	}
+	steps := 0
+	for len(queue) > 0 {
+		if steps >= maxSteps {
+			result.Status = v1alpha2.TimedOut
+			result.StatusMessage = v1alpha2.TimedOut.String()
+			result.ErrorMessage = fmt.Sprintf("simulation stopped after %d stages, the campaign may loop without an exit", steps)
+			log.InfofCtx(ctx, " M (Stage): %s", result.ErrorMessage)
+			return result, nil
+		}
+		triggerData := queue[0]
+		queue = queue[1:]
+		// Schedules are ignored, scheduled stages run right away
+		triggerData.Schedule = ""
+		status, activations := simulator.HandleTriggerEventWithBranches(ctx, campaign, triggerData)
+		steps++
+		if status.Status == v1alpha2.Untouched {
+			continue
+		}
+		result.StageHistory = append(result.StageHistory, status)
+		if status.Status == v1alpha2.Paused {
+			result.Status = v1alpha2.Paused
+			result.StatusMessage = v1alpha2.Paused.String()
+			return result, nil
+		}
+		queue = append(queue, activations...)
+	}
+
+	result.Status = v1alpha2.Done
+	for _, status := range result.StageHistory {
+		if status.Status != v1alpha2.Done && status.Status != v1alpha2.Delayed {
+			result.Status = status.Status
+			result.ErrorMessage = status.ErrorMessage
+			break
+		}
+	}
+	result.StatusMessage = result.Status.String()
+
+	// Hook stages run once the activation is finished, they don't change its status
+	hookStatuses, _ := simulator.RunHooks(ctx, campaign, model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      simulationActivation,
+			Namespace: namespace,
+		},
+		Spec: &model.ActivationSpec{
+			Campaign: name,
+			Inputs:   spec.Inputs,
+		},
+		Status: &model.ActivationStatus{
+			ActivationGeneration: "1",
+			Status:               result.Status,
+			StageHistory:         result.StageHistory,
+		},
+	})
+	result.StageHistory = append(result.StageHistory, hookStatuses...)
+	log.InfofCtx(ctx, " M (Stage): simulation of campaign %s finished as %s after %d stages", name, result.StatusMessage, steps)
+	return result, nil
+}
@@ -36,6 +36,12 @@ type StageManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
 	apiClient     utils.ApiClient
+	controlLock   sync.Mutex
+	controls      map[string]*activationControl
+	nextCancelID  uint64
+	joinLock      sync.Mutex
+	// simulation replaces all stage providers with scripted results when the manager runs a simulation
+	simulation *simulation
 }
 
 type StageResult struct {
@@ -95,8 +101,7 @@ func (h *CampaignTaskHandler) HandleTask(ctx context.Context, task model.TaskSpe
 	}
 
 	// Create task provider
-	factory := symproviders.SymphonyProviderFactory{}
-	taskProvider, err := factory.CreateProvider(task.Provider, task.Config)
+	taskProvider, err := h.manager.createProvider(task.Provider, task.Config, fmt.Sprintf("%s.%s", h.triggerData.Stage, task.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -236,6 +241,9 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 
 		// Initially dispatch up to `concurrency` tasks
 		for i := 0; i < concurrency && i < len(tasks); i++ {
+			if !p.waitWhilePaused(taskCtx, inputs) {
+				return
+			}
 			taskQueue <- tasks[taskIndex]
 			taskIndex++
 			pendingTasks++
@@ -258,8 +266,11 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 					continue
 				}
 
-				// Dispatch next task if available
+				// Dispatch next task if available, unless the activation is paused or cancelled
 				if taskIndex < len(tasks) {
+					if !p.waitWhilePaused(taskCtx, inputs) {
+						return
+					}
 					taskQueue <- tasks[taskIndex]
 					taskIndex++
 					pendingTasks++
@@ -309,6 +320,16 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 	return taskProcessor.TaskResults, nil
 }
 
+// waitWhilePaused holds the dispatch of the next task while the activation of the stage is paused
+func (p *GoRoutineTaskProcessor) waitWhilePaused(ctx context.Context, inputs map[string]interface{}) bool {
+	if p.manager == nil {
+		return ctx.Err() == nil
+	}
+	activation, _ := inputs["__activation"].(string)
+	namespace, _ := inputs["__namespace"].(string)
+	return p.manager.waitWhilePaused(ctx, namespace, activation)
+}
+
 func (s *StageManager) processTasks(ctx context.Context, currentStage model.StageSpec, inputCopy map[string]interface{}, triggerData v1alpha2.ActivationData, triggers map[string]interface{}, siteName string) (map[string]interface{}, error) {
 	if len(currentStage.Tasks) == 0 {
 		return make(map[string]interface{}), nil
@@ -700,7 +721,19 @@ func carryOutPutsToErrorStatus(outputs map[string]interface{}, err error, siteOr
 	return ret
 }
 
+// HandleTriggerEvent runs the triggered stage and returns the next stage to trigger. Stages that fan out to
+// parallel branches need HandleTriggerEventWithBranches, which returns all of them.
 func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.CampaignSpec, triggerData v1alpha2.ActivationData) (model.StageStatus, *v1alpha2.ActivationData) {
+	status, activations := s.HandleTriggerEventWithBranches(ctx, campaign, triggerData)
+	if len(activations) == 0 {
+		return status, nil
+	}
+	return status, &activations[0]
+}
+
+// runStage runs the triggered stage. A stage that runs as a hook of a finished activation isn't subject to
+// pause and cancel requests, and can't pause.
+func (s *StageManager) runStage(ctx context.Context, campaign model.CampaignSpec, triggerData v1alpha2.ActivationData, hook *hookRun) (model.StageStatus, *v1alpha2.ActivationData) {
 	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
 		"method": "HandleTriggerEvent",
 	})
@@ -719,6 +752,34 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		IsActive:      true,
 	}
 	var activationData *v1alpha2.ActivationData
+
+	// Pause and cancel requests are honoured between stages
+	controlState := model.ActivationStateRunning
+	if hook == nil {
+		controlState = s.getActivationControlState(ctx, triggerData.Namespace, triggerData.Activation)
+	}
+	switch controlState {
+	case model.ActivationStatePaused:
+		err = s.holdTrigger(ctx, triggerData)
+		if err != nil {
+			s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+			log.ErrorfCtx(ctx, " M (Stage): failed to hold stage %s of paused activation %s: %v", triggerData.Stage, triggerData.Activation, err)
+			return status, activationData
+		}
+		log.InfofCtx(ctx, " M (Stage): activation %s is paused, holding stage %s", triggerData.Activation, triggerData.Stage)
+		s.setStageStatus(&status, "", v1alpha2.Paused, "")
+		return status, activationData
+	case model.ActivationStateCancelled:
+		log.InfofCtx(ctx, " M (Stage): activation %s is cancelled, skipping stage %s", triggerData.Activation, triggerData.Stage)
+		s.setStageStatus(&status, "", v1alpha2.Cancelled, fmt.Sprintf("activation %s is cancelled", triggerData.Activation))
+		return status, activationData
+	}
+	if hook == nil {
+		var stopTracking func()
+		ctx, stopTracking = s.trackActivation(ctx, triggerData.Namespace, triggerData.Activation)
+		defer stopTracking()
+	}
+
 	if currentStage, ok := campaign.Stages[triggerData.Stage]; ok {
 		sites := make([]string, 0)
 		// 1. According to campaign.Contexts, find out which sites will be executed
@@ -769,6 +830,21 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		}
 		log.DebugfCtx(ctx, " M (Stage): HandleTriggerEvent for campaign %s, activation %s, stage %s, executed on sites {%s}", triggerData.Campaign, triggerData.Activation, triggerData.Stage, strings.Join(sites, ", "))
 
+		// 1.1. If currentStage.ForEach is defined, find out which items the provider will run for
+		var items []interface{}
+		if currentStage.ForEach != "" {
+			items, err = s.evaluateForEach(ctx, currentStage, triggerData)
+			if err != nil {
+				status.Status = v1alpha2.BadConfig
+				status.StatusMessage = v1alpha2.BadConfig.String()
+				status.ErrorMessage = err.Error()
+				status.IsActive = false
+				log.ErrorfCtx(ctx, " M (Stage): failed to evaluate forEach: %v", err)
+				return status, activationData
+			}
+			log.InfofCtx(ctx, " M (Stage): evaluated forEach %s to %d items", currentStage.ForEach, len(items))
+		}
+
 		// 2. According to triggerData.Inputs and currentStage.Inputs, together with default inputs to generate the runtime inputs
 		triggers := triggerData.Inputs
 		if triggers == nil {
@@ -790,20 +866,31 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		if triggerData.Schedule != "" {
 			inputs["__schedule"] = triggerData.Schedule
 		}
+		if triggerData.Branch != "" {
+			inputs["__branch"] = triggerData.Branch
+		}
 		inputs["__target"] = currentStage.Target
+		if hook != nil {
+			for k, v := range hook.Inputs {
+				inputs[k] = v
+			}
+		}
 
-		for k, v := range inputs {
-			var val interface{}
-			val, err = s.traceValue(ctx, v, triggerData.Namespace, inputs, triggers, triggerData.Outputs)
-			if err != nil {
-				status.Status = v1alpha2.InternalError
-				status.StatusMessage = v1alpha2.InternalError.String()
-				status.ErrorMessage = err.Error()
-				status.IsActive = false
-				log.ErrorfCtx(ctx, " M (Stage): failed to evaluate input: %v", err)
-				return status, activationData
+		// inputs of a forEach stage are evaluated for each item, as they may refer to the item
+		if currentStage.ForEach == "" {
+			for k, v := range inputs {
+				var val interface{}
+				val, err = s.traceValue(ctx, v, triggerData.Namespace, inputs, triggers, triggerData.Outputs)
+				if err != nil {
+					status.Status = v1alpha2.InternalError
+					status.StatusMessage = v1alpha2.InternalError.String()
+					status.ErrorMessage = err.Error()
+					status.IsActive = false
+					log.ErrorfCtx(ctx, " M (Stage): failed to evaluate input: %v", err)
+					return status, activationData
+				}
+				inputs[k] = val
 			}
-			inputs[k] = val
 		}
 
 		if triggerData.Outputs != nil {
@@ -842,8 +929,7 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		// 5. If campaign.provider exists, initialize a provider
 		var provider providers.IProvider
 		if triggerData.Provider != "" {
-			factory := symproviders.SymphonyProviderFactory{}
-			provider, err = factory.CreateProvider(triggerData.Provider, triggerData.Config)
+			provider, err = s.createProvider(triggerData.Provider, triggerData.Config, triggerData.Stage)
 			if err != nil {
 				status.Status = v1alpha2.InternalError
 				status.StatusMessage = v1alpha2.InternalError.String()
@@ -884,23 +970,25 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 				}
 				inputCopy["__site"] = site
 
-				for k, v := range inputCopy {
-					var val interface{}
-					val, err = s.traceValue(ctx, v, triggerData.Namespace, inputCopy, triggers, triggerData.Outputs)
-					if err != nil {
-						status.Status = v1alpha2.InternalError
-						status.StatusMessage = v1alpha2.InternalError.String()
-						status.ErrorMessage = err.Error()
-						status.IsActive = false
-						log.ErrorfCtx(ctx, " M (Stage): failed to evaluate input: %v", err)
-						results <- StageResult{
-							Outputs: nil,
-							Error:   err,
-							Site:    site,
+				if currentStage.ForEach == "" {
+					for k, v := range inputCopy {
+						var val interface{}
+						val, err = s.traceValue(ctx, v, triggerData.Namespace, inputCopy, triggers, triggerData.Outputs)
+						if err != nil {
+							status.Status = v1alpha2.InternalError
+							status.StatusMessage = v1alpha2.InternalError.String()
+							status.ErrorMessage = err.Error()
+							status.IsActive = false
+							log.ErrorfCtx(ctx, " M (Stage): failed to evaluate input: %v", err)
+							results <- StageResult{
+								Outputs: nil,
+								Error:   err,
+								Site:    site,
+							}
+							return
 						}
-						return
+						inputCopy[k] = val
 					}
-					inputCopy[k] = val
 				}
 
 				var remoteStageProviderDefined bool = false
@@ -929,7 +1017,32 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 				}
 
 				// 6.1. If triggerData.provider exists, follow current flow to process, collect the output.
-				if provider != nil {
+				if provider != nil && currentStage.ForEach != "" {
+					// 6.1.1. If currentStage.ForEach exists, process the items with concurrency
+					if remoteStageProviderDefined {
+						log.ErrorfCtx(ctx, " M (Stage): remote stage provider cannot be used with forEach, skipping items for site %s", site)
+						results <- StageResult{
+							Outputs: allOutputs,
+							Error: v1alpha2.COAError{
+								State:   v1alpha2.BadConfig,
+								Message: "remote stage provider cannot be used with forEach",
+							},
+							Site: site,
+						}
+						return
+					}
+					var outputs map[string]interface{}
+					outputs, err = s.processForEach(ctx, currentStage, items, inputCopy, triggerData, triggers, site)
+					allOutputs = utils.MergeCollection_StringAny(allOutputs, outputs)
+					if err != nil {
+						results <- StageResult{
+							Outputs: allOutputs,
+							Error:   err,
+							Site:    site,
+						}
+						return
+					}
+				} else if provider != nil {
 					var outputs map[string]interface{}
 					var pause bool
 					outputs, pause, err = provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputCopy)
@@ -1033,6 +1146,19 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		}
 		triggerData.Outputs[triggerData.Stage] = outputs
 
+		// If the activation is cancelled while the stage is running, the stage ends as cancelled
+		if hook == nil && s.getActivationControlState(ctx, triggerData.Namespace, triggerData.Activation) == model.ActivationStateCancelled {
+			s.setStageStatus(&status, "", v1alpha2.Cancelled, fmt.Sprintf("activation %s is cancelled", triggerData.Activation))
+			log.InfofCtx(ctx, " M (Stage): stage %s is cancelled", triggerData.Stage)
+			return status, activationData
+		}
+
+		if pauseRequested && hook != nil {
+			s.setStageStatus(&status, "", v1alpha2.BadRequest, fmt.Sprintf("hook stage %s can't pause", triggerData.Stage))
+			log.ErrorfCtx(ctx, " M (Stage): %s", status.ErrorMessage)
+			return status, activationData
+		}
+
 		// If stage is paused, save the pending task and return paused status
 		if pauseRequested {
 			pendingTask := PendingTask{
@@ -1063,6 +1189,25 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 		}
 
 		if campaign.SelfDriving {
+			// A stage that fans out triggers all its next stages, which run in parallel branches
+			if len(currentStage.NextStages) > 0 {
+				if hasStageError {
+					s.setStageStatus(&status, "", v1alpha2.InternalError, fmt.Sprintf("stage %s failed", triggerData.Stage))
+					log.ErrorfCtx(ctx, " M (Stage): failed to process stage outputs: %v", status.ErrorMessage)
+					return status, activationData
+				}
+				for _, nextStageName := range currentStage.NextStages {
+					if _, ok := campaign.Stages[nextStageName]; !ok {
+						s.setStageStatus(&status, "", v1alpha2.BadRequest, fmt.Sprintf("stage %s is not found", nextStageName))
+						log.ErrorfCtx(ctx, " M (Stage): failed to find next stage: %s", nextStageName)
+						return status, activationData
+					}
+				}
+				s.setStageStatus(&status, "", v1alpha2.Done, "")
+				status.NextStages = currentStage.NextStages
+				log.InfofCtx(ctx, " M (Stage): stage %s is done, fanning out to %v", triggerData.Stage, currentStage.NextStages)
+				return status, activationData
+			}
 			parser := utils.NewParser(currentStage.StageSelector)
 			eCtx := s.VendorContext.EvaluationContext.Clone()
 			eCtx.Context = ctx
@@ -1149,6 +1294,16 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaign model.Ca
 	return status, activationData
 }
 
+// createProvider creates the provider of a stage or a task. A simulation replaces it with a provider that
+// returns the scripted results of the stage or task.
+func (s *StageManager) createProvider(name string, config interface{}, script string) (providers.IProvider, error) {
+	if s.simulation != nil {
+		return &simulatedStageProvider{simulation: s.simulation, script: script}, nil
+	}
+	factory := symproviders.SymphonyProviderFactory{}
+	return factory.CreateProvider(name, config)
+}
+
 func (s *StageManager) setStageStatus(status *model.StageStatus, nextStage string, state v1alpha2.State, errMsg string) {
 	status.NextStage = nextStage
 	status.Status = state
@@ -1170,6 +1325,10 @@ func (s *StageManager) traceValue(ctx context.Context, v interface{}, namespace
 			if v, ok := context.Inputs["context"]; ok {
 				context.Value = v
 			}
+			// the item of a forEach stage takes precedence
+			if v, ok := context.Inputs["__item"]; ok {
+				context.Value = v
+			}
 		}
 		context.Triggers = triggers
 		context.Outputs = outputs
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	coalogcontexts "github.com/eclipse-symphony/symphony/coa/pkg/logger/contexts"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -1774,6 +1776,348 @@ func TestTaskHandlerInterface(t *testing.T) {
 	assert.NotNil(t, outputs)
 }
 
+func TestPauseHoldsNextStageUntilResumed(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+	campaign := model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.mock",
+				Inputs: map[string]interface{}{
+					"foo": 1,
+				},
+			},
+		},
+	}
+	triggerData := v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "test",
+		Provider:             "providers.stage.mock",
+		Namespace:            "default",
+	}
+
+	resumed, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStatePaused)
+	assert.Nil(t, err)
+	assert.Nil(t, resumed)
+
+	status, next := manager.HandleTriggerEvent(ctx, campaign, triggerData)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	assert.Equal(t, "test", status.Stage)
+	assert.Nil(t, next)
+
+	resumed, err = manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	assert.NotNil(t, resumed)
+	assert.Equal(t, "test", resumed.Stage)
+	assert.Equal(t, "providers.stage.mock", resumed.Provider)
+
+	status, _ = manager.HandleTriggerEvent(ctx, campaign, *resumed)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, int64(2), status.Outputs["foo"])
+
+	// the held stage is only returned once
+	resumed, err = manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	assert.Nil(t, resumed)
+}
+
+func TestCancelledActivationSkipsStages(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+
+	_, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateCancelled)
+	assert.Nil(t, err)
+
+	status, next := manager.HandleTriggerEvent(ctx, model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider:      "providers.stage.mock",
+				StageSelector: "test",
+			},
+		},
+	}, v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "test",
+		Provider:   "providers.stage.mock",
+		Namespace:  "default",
+	})
+	assert.Equal(t, v1alpha2.Cancelled, status.Status)
+	assert.True(t, v1alpha2.Cancelled.EqualsWithString(status.StatusMessage))
+	assert.Nil(t, next)
+
+	_, err = manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.NotNil(t, err)
+}
+
+func TestControlStateSurvivesRestart(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+	campaign := model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.mock",
+			},
+		},
+	}
+	triggerData := v1alpha2.ActivationData{
+		Campaign:   "test-campaign",
+		Activation: "test-activation",
+		Stage:      "test",
+		Provider:   "providers.stage.mock",
+		Namespace:  "default",
+	}
+
+	_, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStatePaused)
+	assert.Nil(t, err)
+
+	// a new manager sharing the state provider, as after a restart or on another replica
+	restarted := prepareManager()
+	restarted.StateProvider = manager.StateProvider
+	status, _ := restarted.HandleTriggerEvent(ctx, campaign, triggerData)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+
+	resumed, err := restarted.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	assert.NotNil(t, resumed)
+	assert.Equal(t, model.ActivationStateRunning, manager.getActivationControlState(ctx, "default", "test-activation"))
+
+	_, err = manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateCancelled)
+	assert.Nil(t, err)
+	restarted = prepareManager()
+	restarted.StateProvider = manager.StateProvider
+	status, _ = restarted.HandleTriggerEvent(ctx, campaign, triggerData)
+	assert.Equal(t, v1alpha2.Cancelled, status.Status)
+	_, err = restarted.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.NotNil(t, err)
+}
+
+func TestPausedTaskDispatchNoticesResumeFromAnotherManager(t *testing.T) {
+	pollInterval := controlPollInterval
+	controlPollInterval = 50 * time.Millisecond
+	defer func() { controlPollInterval = pollInterval }()
+
+	manager := prepareManager()
+	other := prepareManager()
+	other.StateProvider = manager.StateProvider
+	ctx := context.Background()
+
+	_, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStatePaused)
+	assert.Nil(t, err)
+
+	done := make(chan bool)
+	go func() {
+		done <- manager.waitWhilePaused(ctx, "default", "test-activation")
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = other.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	select {
+	case ok := <-done:
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("task dispatch wasn't resumed")
+	}
+}
+
+// controlTaskHandler records the tasks it handles and runs a hook on the first one
+type controlTaskHandler struct {
+	lock      sync.Mutex
+	handled   []string
+	onFirst   func(ctx context.Context) error
+	firstDone bool
+}
+
+func (h *controlTaskHandler) HandleTask(ctx context.Context, task model.TaskSpec, inputs map[string]interface{}, siteName string) (map[string]interface{}, error) {
+	h.lock.Lock()
+	h.handled = append(h.handled, task.Name)
+	first := !h.firstDone
+	h.firstDone = true
+	h.lock.Unlock()
+	if first && h.onFirst != nil {
+		if err := h.onFirst(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{"name": task.Name}, nil
+}
+
+func (h *controlTaskHandler) count() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.handled)
+}
+
+func TestPauseHoldsTaskDispatch(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+	inputs := map[string]interface{}{
+		"__activation": "test-activation",
+		"__namespace":  "default",
+	}
+	tasks := []model.TaskSpec{{Name: "task1"}, {Name: "task2"}, {Name: "task3"}}
+	handler := &controlTaskHandler{
+		onFirst: func(ctx context.Context) error {
+			_, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStatePaused)
+			return err
+		},
+	}
+
+	done := make(chan map[string]interface{})
+	go func() {
+		results, _ := NewGoRoutineTaskProcessor(manager, ctx).Process(ctx, tasks, inputs, handler, model.ErrorAction{
+			Mode: model.ErrorActionMode_StopOnAnyFailure,
+		}, 1, "test-site")
+		done <- results
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, handler.count())
+
+	_, err := manager.ControlActivation(ctx, "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	select {
+	case results := <-done:
+		assert.Equal(t, 3, len(results))
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks were not dispatched after the activation was resumed")
+	}
+}
+
+func TestCancelStopsTaskDispatch(t *testing.T) {
+	manager := prepareManager()
+	ctx, stopTracking := manager.trackActivation(context.Background(), "default", "test-activation")
+	defer stopTracking()
+	inputs := map[string]interface{}{
+		"__activation": "test-activation",
+		"__namespace":  "default",
+	}
+	tasks := []model.TaskSpec{{Name: "task1"}, {Name: "task2"}, {Name: "task3"}}
+	handler := &controlTaskHandler{
+		onFirst: func(taskCtx context.Context) error {
+			_, err := manager.ControlActivation(taskCtx, "test-activation", "default", model.ActivationStateCancelled)
+			if err != nil {
+				return err
+			}
+			// a running stage provider sees its context cancelled
+			<-taskCtx.Done()
+			return taskCtx.Err()
+		},
+	}
+
+	_, err := NewGoRoutineTaskProcessor(manager, ctx).Process(ctx, tasks, inputs, handler, model.ErrorAction{
+		Mode: model.ErrorActionMode_SilentlyContinue,
+	}, 1, "test-site")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, handler.count())
+	assert.NotNil(t, ctx.Err())
+}
+
+// loggingTaskHandler logs with the context of its tasks for a while, like stage providers do
+type loggingTaskHandler struct {
+	controlTaskHandler
+}
+
+func (h *loggingTaskHandler) HandleTask(ctx context.Context, task model.TaskSpec, inputs map[string]interface{}, siteName string) (map[string]interface{}, error) {
+	outputs, err := h.controlTaskHandler.HandleTask(ctx, task, inputs, siteName)
+	logTask(ctx, task.Name, 10)
+	return outputs, err
+}
+
+func logTask(ctx context.Context, name string, times int) {
+	for i := 0; i < times; i++ {
+		log.InfofCtx(ctx, " M (Stage): handling task %s", name)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForTasks waits until the handler has started count tasks
+func (h *controlTaskHandler) waitForTasks(count int) {
+	for i := 0; i < 500 && h.count() < count; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPauseHoldsParallelTaskDispatch(t *testing.T) {
+	pollInterval := controlPollInterval
+	controlPollInterval = 10 * time.Millisecond
+	defer func() { controlPollInterval = pollInterval }()
+
+	manager := prepareManager()
+	ctx := coalogcontexts.PopulateResourceIdAndCorrelationIdToDiagnosticLogContext("correlation", "resource", context.Background())
+	inputs := map[string]interface{}{
+		"__activation": "test-activation",
+		"__namespace":  "default",
+	}
+	tasks := []model.TaskSpec{{Name: "task1"}, {Name: "task2"}, {Name: "task3"}, {Name: "task4"}}
+	handler := &loggingTaskHandler{}
+	handler.onFirst = func(ctx context.Context) error {
+		// Pause once both tasks run, and keep logging while the dispatch of the next task is held
+		handler.waitForTasks(2)
+		_, err := manager.ControlActivation(context.Background(), "test-activation", "default", model.ActivationStatePaused)
+		logTask(ctx, "task1", 20)
+		return err
+	}
+
+	done := make(chan map[string]interface{})
+	go func() {
+		results, _ := NewGoRoutineTaskProcessor(manager, ctx).Process(ctx, tasks, inputs, handler, model.ErrorAction{
+			Mode: model.ErrorActionMode_StopOnAnyFailure,
+		}, 2, "test-site")
+		done <- results
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 2, handler.count())
+
+	_, err := manager.ControlActivation(context.Background(), "test-activation", "default", model.ActivationStateRunning)
+	assert.Nil(t, err)
+	select {
+	case results := <-done:
+		assert.Equal(t, 4, len(results))
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks were not dispatched after the activation was resumed")
+	}
+}
+
+func TestCancelStopsParallelTaskDispatch(t *testing.T) {
+	manager := prepareManager()
+	ctx := coalogcontexts.PopulateResourceIdAndCorrelationIdToDiagnosticLogContext("correlation", "resource", context.Background())
+	ctx, stopTracking := manager.trackActivation(ctx, "default", "test-activation")
+	defer stopTracking()
+	inputs := map[string]interface{}{
+		"__activation": "test-activation",
+		"__namespace":  "default",
+	}
+	tasks := []model.TaskSpec{{Name: "task1"}, {Name: "task2"}, {Name: "task3"}, {Name: "task4"}}
+	handler := &loggingTaskHandler{}
+	handler.onFirst = func(taskCtx context.Context) error {
+		handler.waitForTasks(2)
+		_, err := manager.ControlActivation(context.Background(), "test-activation", "default", model.ActivationStateCancelled)
+		if err != nil {
+			return err
+		}
+		<-taskCtx.Done()
+		return taskCtx.Err()
+	}
+
+	_, err := NewGoRoutineTaskProcessor(manager, ctx).Process(ctx, tasks, inputs, handler, model.ErrorAction{
+		Mode: model.ErrorActionMode_SilentlyContinue,
+	}, 2, "test-site")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, handler.count())
+	assert.NotNil(t, ctx.Err())
+}
+
 type AuthResponse struct {
 	AccessToken string   `json:"accessToken"`
 	TokenType   string   `json:"tokenType"`
@@ -1831,3 +2175,560 @@ func InitializeMockSymphonyAPI() *httptest.Server {
 	}))
 	return ts
 }
+
+func branchesCampaign(count int) model.CampaignSpec {
+	return model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "start",
+		Stages: map[string]model.StageSpec{
+			"start": {
+				Provider:   "providers.stage.mock",
+				NextStages: []string{"a", "b"},
+				Inputs: map[string]interface{}{
+					"foo": 0,
+				},
+			},
+			"a": {
+				Provider:      "providers.stage.mock",
+				StageSelector: "join",
+				Inputs: map[string]interface{}{
+					"foo": 10,
+				},
+			},
+			"b": {
+				Provider:      "providers.stage.mock",
+				StageSelector: "join",
+				Inputs: map[string]interface{}{
+					"foo": 20,
+				},
+			},
+			"join": {
+				Provider: "providers.stage.mock",
+				Join: &model.JoinSpec{
+					Predecessors: []string{"a", "b"},
+					Count:        count,
+				},
+				Inputs: map[string]interface{}{
+					"foo": "${{$output(a,foo)}}",
+				},
+			},
+		},
+	}
+}
+
+func runBranches(t *testing.T, manager *StageManager, campaign model.CampaignSpec) []v1alpha2.ActivationData {
+	ctx := context.Background()
+	status, activations := manager.HandleTriggerEventWithBranches(ctx, campaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "start",
+		Provider:             "providers.stage.mock",
+		Namespace:            "default",
+	})
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, []string{"a", "b"}, status.NextStages)
+	assert.Equal(t, "", status.NextStage)
+	assert.Equal(t, 2, len(activations))
+
+	joins := make([]v1alpha2.ActivationData, 0)
+	for _, activation := range activations {
+		assert.Equal(t, activation.Stage, activation.Branch)
+		assert.Equal(t, "start", activation.TriggeringStage)
+		status, next := manager.HandleTriggerEventWithBranches(ctx, campaign, activation)
+		assert.Equal(t, v1alpha2.Done, status.Status)
+		assert.Equal(t, activation.Stage, status.Branch)
+		assert.Equal(t, 1, len(next))
+		assert.Equal(t, "join", next[0].Stage)
+		assert.Equal(t, activation.Branch, next[0].Branch)
+		joins = append(joins, next[0])
+	}
+	// branches don't share their outputs
+	_, ok := joins[0].Outputs["b"]
+	assert.False(t, ok)
+	return joins
+}
+
+func TestFanOutAndJoinAllPredecessors(t *testing.T) {
+	manager := prepareManager()
+	campaign := branchesCampaign(0)
+	joins := runBranches(t, manager, campaign)
+
+	status, next := manager.HandleTriggerEventWithBranches(context.Background(), campaign, joins[0])
+	assert.Equal(t, v1alpha2.Delayed, status.Status)
+	assert.True(t, status.IsActive)
+	assert.Equal(t, "", status.Branch)
+	assert.Nil(t, next)
+
+	status, next = manager.HandleTriggerEventWithBranches(context.Background(), campaign, joins[1])
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "join", status.Stage)
+	assert.Equal(t, "", status.Branch)
+	assert.Nil(t, next)
+	assert.Equal(t, int64(12), status.Outputs["foo"])
+	assert.EqualValues(t, 11, status.Outputs["a.foo"])
+	assert.EqualValues(t, 21, status.Outputs["b.foo"])
+}
+
+func TestJoinWithCount(t *testing.T) {
+	manager := prepareManager()
+	campaign := branchesCampaign(1)
+	joins := runBranches(t, manager, campaign)
+
+	status, _ := manager.HandleTriggerEventWithBranches(context.Background(), campaign, joins[0])
+	assert.Equal(t, v1alpha2.Done, status.Status)
+
+	// the late predecessor is ignored
+	status, next := manager.HandleTriggerEventWithBranches(context.Background(), campaign, joins[1])
+	assert.Equal(t, v1alpha2.Untouched, status.Status)
+	assert.False(t, status.IsActive)
+	assert.Nil(t, next)
+
+	// the join stage can run again once all predecessors arrived
+	status, _ = manager.HandleTriggerEventWithBranches(context.Background(), campaign, joins[1])
+	assert.Equal(t, v1alpha2.Done, status.Status)
+}
+
+func TestJoinFromUnknownPredecessor(t *testing.T) {
+	manager := prepareManager()
+	campaign := branchesCampaign(0)
+	status, next := manager.HandleTriggerEventWithBranches(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "join",
+		TriggeringStage:      "start",
+		Namespace:            "default",
+	})
+	assert.Equal(t, v1alpha2.BadRequest, status.Status)
+	assert.Nil(t, next)
+}
+
+func retryCampaign() model.CampaignSpec {
+	return model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Name:          "deploy",
+				Provider:      "providers.stage.http",
+				StageSelector: "verify",
+				Inputs: map[string]interface{}{
+					"app": "${{$trigger(app, web)}}",
+				},
+			},
+			"verify": {
+				Name:          "verify",
+				Provider:      "providers.stage.http",
+				StageSelector: "${{$if($equal($output(verify,healthy), true), '', deploy)}}",
+			},
+		},
+	}
+}
+
+func TestSimulateCampaign(t *testing.T) {
+	manager := prepareManager()
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", retryCampaign(), model.CampaignSimulationSpec{
+		Inputs: map[string]interface{}{
+			"app": "api",
+		},
+		Stages: map[string][]model.SimulatedStageResult{
+			"deploy": {
+				{Outputs: map[string]interface{}{"revision": 1}},
+				{Outputs: map[string]interface{}{"revision": 2}},
+			},
+			"verify": {
+				{Outputs: map[string]interface{}{"healthy": false}},
+				{Outputs: map[string]interface{}{"healthy": true}},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, result.Status)
+	assert.Equal(t, 4, len(result.StageHistory))
+	stages := make([]string, 0)
+	for _, status := range result.StageHistory {
+		stages = append(stages, status.Stage)
+	}
+	assert.Equal(t, []string{"deploy", "verify", "deploy", "verify"}, stages)
+	assert.Equal(t, "api", result.StageHistory[0].Inputs["app"])
+	assert.Equal(t, 2, result.StageHistory[2].Outputs["revision"])
+	assert.Equal(t, "verify", result.StageHistory[2].NextStage)
+}
+
+func TestSimulateCampaignStageError(t *testing.T) {
+	manager := prepareManager()
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", retryCampaign(), model.CampaignSimulationSpec{
+		Stages: map[string][]model.SimulatedStageResult{
+			"deploy": {
+				{Error: "quota exceeded"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, result.Status)
+	assert.Equal(t, 1, len(result.StageHistory))
+	assert.Contains(t, result.ErrorMessage, "stage deploy failed")
+}
+
+func TestSimulateCampaignStepLimit(t *testing.T) {
+	manager := prepareManager()
+	// verify is never healthy, so the campaign keeps deploying
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", retryCampaign(), model.CampaignSimulationSpec{
+		Stages: map[string][]model.SimulatedStageResult{
+			"verify": {
+				{Outputs: map[string]interface{}{"healthy": false}},
+			},
+		},
+		MaxSteps: 6,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.TimedOut, result.Status)
+	assert.Equal(t, 6, len(result.StageHistory))
+}
+
+func TestSimulateCampaignWithBranches(t *testing.T) {
+	manager := prepareManager()
+	campaign := branchesCampaign(0)
+	for name, stage := range campaign.Stages {
+		stage.Name = name
+		campaign.Stages[name] = stage
+	}
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", campaign, model.CampaignSimulationSpec{
+		Stages: map[string][]model.SimulatedStageResult{
+			"a": {{Outputs: map[string]interface{}{"region": "east", "foo": 1}}},
+			"b": {{Outputs: map[string]interface{}{"region": "west"}}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, result.Status, result.ErrorMessage)
+	last := result.StageHistory[len(result.StageHistory)-1]
+	assert.Equal(t, "join", last.Stage)
+	assert.Equal(t, "east", last.Outputs["a.region"])
+	assert.Equal(t, "west", last.Outputs["b.region"])
+}
+
+func TestSimulateInvalidCampaign(t *testing.T) {
+	manager := prepareManager()
+	campaign := retryCampaign()
+	campaign.Stages["verify"] = model.StageSpec{
+		Name:          "verify",
+		StageSelector: "${{$if($equal($output(verfy,healthy), true), '', deploy)}}",
+	}
+	_, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", campaign, model.CampaignSimulationSpec{})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err))
+	assert.Contains(t, err.Error(), "refers to stage verfy")
+}
+
+func forEachCampaign(concurrency int) model.CampaignSpec {
+	return model.CampaignSpec{
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				Name:     "deploy",
+				Provider: "providers.stage.mock",
+				ForEach:  "${{$trigger(regions, '')}}",
+				TaskOption: model.TaskOption{
+					Concurrency: concurrency,
+					ErrorAction: model.ErrorAction{
+						Mode: model.ErrorActionMode_StopOnAnyFailure,
+					},
+				},
+				Inputs: map[string]interface{}{
+					"region": "${{$val(name)}}",
+					"foo":    "${{$val(foo)}}",
+				},
+			},
+		},
+	}
+}
+
+func runForEach(manager *StageManager, campaign model.CampaignSpec, regions interface{}) model.StageStatus {
+	status, _ := manager.HandleTriggerEventWithBranches(context.Background(), campaign, v1alpha2.ActivationData{
+		Campaign:             "test-campaign",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "deploy",
+		Provider:             "providers.stage.mock",
+		Namespace:            "default",
+		Inputs: map[string]interface{}{
+			"regions": regions,
+		},
+	})
+	return status
+}
+
+func TestForEachStage(t *testing.T) {
+	for _, concurrency := range []int{0, 3} {
+		manager := prepareManager()
+		status := runForEach(manager, forEachCampaign(concurrency), []interface{}{
+			map[string]interface{}{"name": "east", "foo": 1},
+			map[string]interface{}{"name": "west", "foo": 10},
+			map[string]interface{}{"name": "north", "foo": 20},
+		})
+		assert.Equal(t, v1alpha2.Done, status.Status, status.ErrorMessage)
+		items, ok := status.Outputs["items"].([]interface{})
+		assert.True(t, ok)
+		assert.Equal(t, 3, len(items))
+		assert.Equal(t, "east", items[0].(map[string]interface{})["region"])
+		assert.Equal(t, int64(2), items[0].(map[string]interface{})["foo"])
+		assert.Equal(t, 0, items[0].(map[string]interface{})["__index"])
+		assert.Equal(t, "north", items[2].(map[string]interface{})["region"])
+		assert.Equal(t, int64(21), items[2].(map[string]interface{})["foo"])
+		assert.Equal(t, 2, items[2].(map[string]interface{})["__index"])
+	}
+}
+
+func TestForEachStageWithEmptyList(t *testing.T) {
+	manager := prepareManager()
+	status := runForEach(manager, forEachCampaign(1), "")
+	assert.Equal(t, v1alpha2.Done, status.Status, status.ErrorMessage)
+	assert.Equal(t, []interface{}{}, status.Outputs["items"])
+}
+
+func TestForEachStageNotAList(t *testing.T) {
+	manager := prepareManager()
+	status := runForEach(manager, forEachCampaign(1), map[string]interface{}{"name": "east"})
+	assert.Equal(t, v1alpha2.BadConfig, status.Status)
+	assert.Contains(t, status.ErrorMessage, "doesn't evaluate to a list")
+}
+
+func TestForEachStageStopOnAnyFailure(t *testing.T) {
+	manager := prepareManager()
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", forEachCampaign(1), model.CampaignSimulationSpec{
+		Inputs: map[string]interface{}{
+			"regions": []interface{}{
+				map[string]interface{}{"name": "east", "foo": 1},
+				map[string]interface{}{"name": "west", "foo": 1},
+				map[string]interface{}{"name": "north", "foo": 1},
+			},
+		},
+		Stages: map[string][]model.SimulatedStageResult{
+			"deploy": {
+				{Outputs: map[string]interface{}{"deployed": true}},
+				{Error: "quota exceeded"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, result.Status)
+	assert.Equal(t, 1, len(result.StageHistory))
+	items, ok := result.StageHistory[0].Outputs["items"].([]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(items))
+	assert.Equal(t, true, items[0].(map[string]interface{})["deployed"])
+	// the last item isn't dispatched after the failure
+	assert.Nil(t, items[2])
+}
+
+func TestSendNotifications(t *testing.T) {
+	messages := make(chan map[string]interface{}, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		messages <- payload
+	}))
+	defer server.Close()
+
+	manager := prepareManager()
+	campaign := model.CampaignSpec{
+		Notifications: []model.NotificationSpec{
+			{
+				Name: "on-failure",
+				On:   []string{model.NotificationEventFailure},
+				Config: map[string]interface{}{
+					"channel": "webhook",
+					"url":     server.URL,
+				},
+				Inputs: map[string]interface{}{
+					"app":      "${{$trigger(app, '')}}",
+					"message":  "{{.Stage}} of {{.Inputs.app}} failed at revision {{.Inputs.revision}}: {{.Error}}",
+					"revision": "${{$output(build, revision)}}",
+				},
+			},
+			{
+				Name: "on-success",
+				On:   []string{model.NotificationEventSuccess},
+				Config: map[string]interface{}{
+					"channel": "webhook",
+					"url":     server.URL,
+				},
+			},
+		},
+	}
+	activation := model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "test-activation",
+			Namespace: "default",
+		},
+		Spec: &model.ActivationSpec{
+			Campaign: "test-campaign",
+			Inputs: map[string]interface{}{
+				"app": "web",
+			},
+		},
+		Status: &model.ActivationStatus{
+			Status: v1alpha2.InternalError,
+			StageHistory: []model.StageStatus{
+				{
+					Stage:   "build",
+					Status:  v1alpha2.Done,
+					Outputs: map[string]interface{}{"revision": "42"},
+				},
+				{
+					Stage:        "deploy",
+					Status:       v1alpha2.InternalError,
+					ErrorMessage: "quota exceeded",
+				},
+			},
+		},
+	}
+	err := manager.SendNotifications(context.Background(), campaign, activation)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+	payload := <-messages
+	assert.Equal(t, "deploy of web failed at revision 42: quota exceeded", payload["message"])
+	assert.Equal(t, "Internal Error", payload["status"])
+	assert.Equal(t, "test-campaign", payload["campaign"])
+
+	activation.Status.Status = v1alpha2.Done
+	activation.Status.StageHistory = activation.Status.StageHistory[:1]
+	err = manager.SendNotifications(context.Background(), campaign, activation)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(messages))
+	payload = <-messages
+	assert.Equal(t, "Campaign test-campaign activation test-activation is Done", payload["message"])
+}
+
+func TestSendNotificationsFailure(t *testing.T) {
+	manager := prepareManager()
+	campaign := model.CampaignSpec{
+		Notifications: []model.NotificationSpec{
+			{
+				Name: "broken",
+				On:   []string{model.NotificationEventFinished},
+				Config: map[string]interface{}{
+					"channel": "pager",
+				},
+			},
+		},
+	}
+	err := manager.SendNotifications(context.Background(), campaign, model.ActivationState{
+		Spec: &model.ActivationSpec{Campaign: "test-campaign"},
+		Status: &model.ActivationStatus{
+			Status: v1alpha2.Cancelled,
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "notification broken")
+}
+
+func hooksCampaign() model.CampaignSpec {
+	campaign := retryCampaign()
+	campaign.Stages["rollback"] = model.StageSpec{
+		Name:     "rollback",
+		Provider: "providers.stage.http",
+		Inputs: map[string]interface{}{
+			"revision": "${{$output(deploy, revision)}}",
+			"reason":   "${{$input(__error)}}",
+		},
+	}
+	campaign.Stages["announce"] = model.StageSpec{
+		Name:     "announce",
+		Provider: "providers.stage.http",
+	}
+	campaign.Stages["cleanup"] = model.StageSpec{
+		Name:     "cleanup",
+		Provider: "providers.stage.http",
+		Inputs: map[string]interface{}{
+			"hook": "${{$input(__hook)}}",
+		},
+	}
+	campaign.Hooks = &model.CampaignHooks{
+		OnSuccess: []string{"announce"},
+		OnFailure: []string{"rollback"},
+		Finally:   []string{"cleanup"},
+	}
+	return campaign
+}
+
+func TestSimulateCampaignWithFailureHooks(t *testing.T) {
+	manager := prepareManager()
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", hooksCampaign(), model.CampaignSimulationSpec{
+		Stages: map[string][]model.SimulatedStageResult{
+			"deploy": {
+				{Outputs: map[string]interface{}{"revision": 3}, Error: "quota exceeded"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, result.Status)
+	assert.Equal(t, 3, len(result.StageHistory))
+	rollback := result.StageHistory[1]
+	assert.Equal(t, "rollback", rollback.Stage)
+	assert.Equal(t, model.HookOnFailure, rollback.Hook)
+	assert.Equal(t, v1alpha2.Done, rollback.Status)
+	assert.Equal(t, 3, rollback.Inputs["revision"])
+	assert.Contains(t, rollback.Inputs["reason"], "stage deploy failed")
+	cleanup := result.StageHistory[2]
+	assert.Equal(t, "cleanup", cleanup.Stage)
+	assert.Equal(t, model.HookFinally, cleanup.Hook)
+	assert.Equal(t, model.HookFinally, cleanup.Inputs["hook"])
+}
+
+func TestSimulateCampaignWithSuccessHooks(t *testing.T) {
+	manager := prepareManager()
+	result, err := manager.SimulateCampaign(context.Background(), "test-campaign", "default", hooksCampaign(), model.CampaignSimulationSpec{
+		Stages: map[string][]model.SimulatedStageResult{
+			"verify": {
+				{Outputs: map[string]interface{}{"healthy": true}},
+			},
+			"cleanup": {
+				{Error: "disk is busy"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	// a failed hook stage doesn't change the status of the activation
+	assert.Equal(t, v1alpha2.Done, result.Status)
+	stages := make([]string, 0)
+	for _, status := range result.StageHistory {
+		stages = append(stages, status.Stage)
+	}
+	assert.Equal(t, []string{"deploy", "verify", "announce", "cleanup"}, stages)
+	assert.Equal(t, v1alpha2.InternalError, result.StageHistory[3].Status)
+}
+
+func TestRunHooksOnCancelledActivation(t *testing.T) {
+	manager := prepareManager()
+	campaign := hooksCampaign()
+	for name, stage := range campaign.Stages {
+		stage.Provider = "providers.stage.mock"
+		campaign.Stages[name] = stage
+	}
+	_, err := manager.ControlActivation(context.Background(), "test-activation", "default", model.ActivationStateCancelled)
+	assert.Nil(t, err)
+	statuses, err := manager.RunHooks(context.Background(), campaign, model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "test-activation",
+			Namespace: "default",
+		},
+		Spec: &model.ActivationSpec{
+			Campaign: "test-campaign",
+		},
+		Status: &model.ActivationStatus{
+			Status: v1alpha2.Cancelled,
+			StageHistory: []model.StageStatus{
+				{Stage: "deploy", Status: v1alpha2.Cancelled},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	// only the finally stages run for a cancelled activation, and they are not cancelled
+	assert.Equal(t, 1, len(statuses))
+	assert.Equal(t, "cleanup", statuses[0].Stage)
+	assert.Equal(t, v1alpha2.Done, statuses[0].Status, statuses[0].ErrorMessage)
+	assert.Equal(t, model.HookFinally, statuses[0].Outputs["hook"])
+}
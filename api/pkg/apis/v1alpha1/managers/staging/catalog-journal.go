@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package staging
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/google/uuid"
+)
+
+const defaultTombstoneRetention = 24 * time.Hour
+
+const catalogJournalID = "catalog-journal"
+
+// CatalogChange is the last change of a catalog in the journal
+type CatalogChange struct {
+	Revision  int64
+	Name      string
+	Namespace string
+	Deleted   bool
+	Time      time.Time
+}
+
+// journalState is what the journal persists through the state provider. Acks are the revisions each child
+// site has acknowledged, per namespace, and floors are the highest revisions of dropped tombstones, per
+// namespace.
+type journalState struct {
+	Epoch    string                      `json:"epoch"`
+	Revision int64                       `json:"revision"`
+	Changes  map[string]CatalogChange    `json:"changes"`
+	Acks     map[string]map[string]int64 `json:"acks"`
+	Floors   map[string]int64            `json:"floors"`
+}
+
+// catalogJournal gives a revision to each catalog change, so child sites can ask for the changes after the
+// last revision they applied. Only the last change of a catalog is kept. Deletions are kept as tombstones
+// until all child sites syncing the namespace from the journal have acknowledged them, or until the retention
+// expires. The journal is persisted through the state provider, so it outlives restarts and is shared by
+// replicas; child sites only do a full resync when the journal is lost and gets a new epoch.
+type catalogJournal struct {
+	lock      sync.Mutex
+	retention time.Duration
+}
+
+// load reads the journal from the state provider, creating it with a new epoch if it doesn't exist
+func (j *catalogJournal) load(ctx context.Context, provider states.IStateProvider) (journalState, error) {
+	if j.retention == 0 {
+		j.retention = defaultTombstoneRetention
+	}
+	var state journalState
+	entry, err := provider.Get(ctx, states.GetRequest{
+		ID: catalogJournalID,
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+		},
+	})
+	if err != nil {
+		if !utils.IsNotFound(err) {
+			return state, err
+		}
+		state.Epoch = uuid.New().String()
+		err = j.save(ctx, provider, &state)
+		return state, err
+	}
+	jData, _ := json.Marshal(entry.Body)
+	if err = json.Unmarshal(jData, &state); err != nil {
+		return state, v1alpha2.NewCOAError(err, "catalog journal is invalid", v1alpha2.InternalError)
+	}
+	j.ensureMaps(&state)
+	return state, nil
+}
+
+func (j *catalogJournal) ensureMaps(state *journalState) {
+	if state.Changes == nil {
+		state.Changes = make(map[string]CatalogChange)
+	}
+	if state.Acks == nil {
+		state.Acks = make(map[string]map[string]int64)
+	}
+	if state.Floors == nil {
+		state.Floors = make(map[string]int64)
+	}
+}
+
+func (j *catalogJournal) save(ctx context.Context, provider states.IStateProvider, state *journalState) error {
+	j.ensureMaps(state)
+	_, err := provider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   catalogJournalID,
+			Body: *state,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+		},
+	})
+	return err
+}
+
+// record adds a catalog change and returns its revision
+func (j *catalogJournal) record(ctx context.Context, provider states.IStateProvider, name string, namespace string, deleted bool) (int64, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	state, err := j.load(ctx, provider)
+	if err != nil {
+		return 0, err
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	state.Revision++
+	state.Changes[namespace+"/"+name] = CatalogChange{
+		Revision:  state.Revision,
+		Name:      name,
+		Namespace: namespace,
+		Deleted:   deleted,
+		Time:      time.Now().UTC(),
+	}
+	return state.Revision, j.save(ctx, provider, &state)
+}
+
+// since gets up to count changes in a namespace after a revision, in revision order. It returns the revision the
+// changes bring a child to, and whether there are more changes. fullSync is true when the changes can't be
+// computed, because the epoch is different or tombstones the child hasn't seen were dropped.
+func (j *catalogJournal) since(ctx context.Context, provider states.IStateProvider, site string, epoch string, revision int64, namespace string, count int) (changes []CatalogChange, watermark int64, more bool, fullSync bool, err error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	state, err := j.load(ctx, provider)
+	if err != nil {
+		return nil, 0, false, false, err
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	if epoch != state.Epoch || revision < state.Floors[namespace] || revision > state.Revision {
+		return nil, state.Revision, false, true, nil
+	}
+	j.acknowledge(&state, site, namespace, revision)
+	j.prune(&state)
+	if err = j.save(ctx, provider, &state); err != nil {
+		return nil, 0, false, false, err
+	}
+
+	for _, c := range state.Changes {
+		if c.Revision > revision && c.Namespace == namespace {
+			changes = append(changes, c)
+		}
+	}
+	sort.Slice(changes, func(a, b int) bool {
+		return changes[a].Revision < changes[b].Revision
+	})
+	if count > 0 && len(changes) > count {
+		return changes[:count], changes[count-1].Revision, true, false, nil
+	}
+	return changes, state.Revision, false, false, nil
+}
+
+// hasSite tells if a site syncs from the journal
+func (j *catalogJournal) hasSite(ctx context.Context, provider states.IStateProvider, site string) (bool, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	state, err := j.load(ctx, provider)
+	if err != nil {
+		return false, err
+	}
+	_, ok := state.Acks[site]
+	return ok, nil
+}
+
+// snapshot gets the epoch and the current revision, for a full sync of a namespace. The site is recorded as
+// being at the current revision in the namespace, as it gets all the catalogs.
+func (j *catalogJournal) snapshot(ctx context.Context, provider states.IStateProvider, site string, namespace string) (string, int64, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	state, err := j.load(ctx, provider)
+	if err != nil {
+		return "", 0, err
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	j.acknowledge(&state, site, namespace, state.Revision)
+	return state.Epoch, state.Revision, j.save(ctx, provider, &state)
+}
+
+func (j *catalogJournal) acknowledge(state *journalState, site string, namespace string, revision int64) {
+	if state.Acks[site] == nil {
+		state.Acks[site] = make(map[string]int64)
+	}
+	state.Acks[site][namespace] = revision
+}
+
+// prune drops tombstones acknowledged by all sites syncing their namespace, and tombstones older than the
+// retention. The floor of the namespace is raised to the highest revision dropped, so sites behind it do a
+// full resync instead of missing deletions.
+func (j *catalogJournal) prune(state *journalState) {
+	expiry := time.Now().Add(-j.retention)
+	for key, c := range state.Changes {
+		if !c.Deleted {
+			continue
+		}
+		acked := state.Revision
+		for _, namespaces := range state.Acks {
+			if r, ok := namespaces[c.Namespace]; ok && r < acked {
+				acked = r
+			}
+		}
+		if c.Revision <= acked || c.Time.Before(expiry) {
+			delete(state.Changes, key)
+			if c.Revision > state.Floors[c.Namespace] {
+				state.Floors[c.Namespace] = c.Revision
+			}
+		}
+	}
+}
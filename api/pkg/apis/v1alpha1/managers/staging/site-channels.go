@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package staging
+
+import "sync"
+
+// siteChannels wakes up the push channels child sites have open, when there are jobs or catalog changes to
+// send to them. A wake-up is dropped when one is already pending, as the channel sends everything queued.
+type siteChannels struct {
+	lock     sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+	closed   bool
+}
+
+// watch registers a channel of a site. The returned function unregisters it.
+func (c *siteChannels) watch(site string) (<-chan struct{}, func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ch := make(chan struct{}, 1)
+	if c.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if c.watchers == nil {
+		c.watchers = make(map[string]map[chan struct{}]struct{})
+	}
+	if c.watchers[site] == nil {
+		c.watchers[site] = make(map[chan struct{}]struct{})
+	}
+	c.watchers[site][ch] = struct{}{}
+	return ch, func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if _, ok := c.watchers[site][ch]; ok {
+			delete(c.watchers[site], ch)
+			if len(c.watchers[site]) == 0 {
+				delete(c.watchers, site)
+			}
+		}
+	}
+}
+
+// notify wakes up the channels of a site, or of all sites when site is empty
+func (c *siteChannels) notify(site string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for s, watchers := range c.watchers {
+		if site != "" && s != site {
+			continue
+		}
+		for ch := range watchers {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// connected tells if a site has a channel open
+func (c *siteChannels) connected(site string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.watchers[site]) > 0
+}
+
+// close closes all channels, so the streams end
+func (c *siteChannels) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, watchers := range c.watchers {
+		for ch := range watchers {
+			close(ch)
+		}
+	}
+	c.watchers = nil
+	c.closed = true
+}
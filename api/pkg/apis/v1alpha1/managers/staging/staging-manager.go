@@ -9,6 +9,7 @@ package staging
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -31,10 +32,25 @@ type StagingManager struct {
 	QueueProvider queue.IQueueProvider
 	StateProvider states.IStateProvider
 	apiClient     utils.ApiClient
+	journal       catalogJournal
+	channels      siteChannels
+}
+
+// CatalogChanges are the catalog changes a child site hasn't applied
+type CatalogChanges struct {
+	Epoch     string
+	Watermark int64
+	Changes   []CatalogChange
+	// FullSync means the child needs all the catalogs, as the changes can't be computed from its watermark
+	FullSync bool
+	More     bool
 }
 
 const Site_Job_Queue = "site-job-queue"
 
+// Site_Outbox_Queue holds the reports an offline site keeps for its parent until they're exported in a bundle
+const Site_Outbox_Queue = "site-outbox-queue"
+
 func (s *StagingManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
 	err := s.Manager.Init(context, config, providers)
 	if err != nil {
@@ -56,6 +72,12 @@ func (s *StagingManager) Init(context *contexts.VendorContext, config managers.M
 	if err != nil {
 		return err
 	}
+	if v, ok := config.Properties["sync.tombstoneRetention"]; ok {
+		s.journal.retention, err = time.ParseDuration(v)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "sync.tombstoneRetention is not a valid duration", v1alpha2.BadConfig)
+		}
+	}
 	return nil
 }
 func (s *StagingManager) Enabled() bool {
@@ -80,6 +102,16 @@ func (s *StagingManager) Poll() []error {
 		return []error{err}
 	}
 	siteId := utils.FormatAsString(site)
+	var journalSite bool
+	journalSite, err = s.journal.hasSite(ctx, s.StateProvider, siteId)
+	if err != nil {
+		log.Errorf(" M (Staging): Failed to read the catalog journal: %s", err.Error())
+		return []error{err}
+	}
+	if journalSite {
+		// The site syncs catalogs from the change journal
+		return nil
+	}
 	var catalogs []model.CatalogState
 	catalogs, err = s.apiClient.GetCatalogs(ctx, "",
 		s.VendorContext.SiteInfo.CurrentSite.Username,
@@ -151,8 +183,23 @@ func (s *StagingManager) HandleJobEvent(ctx context.Context, event v1alpha2.Even
 		err = v1alpha2.NewCOAError(nil, "event body is not a job", v1alpha2.BadRequest)
 		return err
 	}
+	if job.Action != v1alpha2.JobRun {
+		var journalSite bool
+		journalSite, err = s.journal.hasSite(ctx, s.StateProvider, event.Metadata["site"])
+		if err != nil {
+			return err
+		}
+		if journalSite {
+			// The site syncs catalogs from the change journal
+			return nil
+		}
+	}
 	s.QueueProvider.Enqueue(Site_Job_Queue, event.Metadata["site"])
-	return s.QueueProvider.Enqueue(event.Metadata["site"], job)
+	err = s.QueueProvider.Enqueue(event.Metadata["site"], job)
+	if err == nil {
+		s.channels.notify(event.Metadata["site"])
+	}
+	return err
 }
 func (s *StagingManager) GetABatchForSite(site string, count int) ([]v1alpha2.JobData, error) {
 	//TODO: this should return a group of jobs as optimization
@@ -179,3 +226,87 @@ func (s *StagingManager) GetABatchForSite(site string, count int) ([]v1alpha2.Jo
 	}
 	return items, nil
 }
+
+// QueueReport keeps a report for the parent of an offline site, until it's exported in a bundle
+func (s *StagingManager) QueueReport(message model.ChannelMessage) error {
+	return s.QueueProvider.Enqueue(Site_Outbox_Queue, message)
+}
+
+// DrainReports takes all the reports queued for the parent of an offline site
+func (s *StagingManager) DrainReports() ([]model.ChannelMessage, error) {
+	messages := make([]model.ChannelMessage, 0)
+	for s.QueueProvider.Size(Site_Outbox_Queue) > 0 {
+		element, err := s.QueueProvider.Dequeue(Site_Outbox_Queue)
+		if err != nil {
+			return messages, err
+		}
+		// Queue providers that persist elements give them back as maps
+		var message model.ChannelMessage
+		jData, _ := json.Marshal(element)
+		if err = json.Unmarshal(jData, &message); err != nil {
+			log.Errorf(" M (Staging): dropping a report that isn't a channel message: %s", err.Error())
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// RecordCatalogChange adds a catalog update or deletion to the change journal child sites sync from
+func (s *StagingManager) RecordCatalogChange(ctx context.Context, job v1alpha2.JobData) (int64, error) {
+	var catalog model.CatalogState
+	jData, _ := json.Marshal(job.Body)
+	if err := json.Unmarshal(jData, &catalog); err != nil {
+		return 0, v1alpha2.NewCOAError(err, "job body is not a catalog", v1alpha2.BadRequest)
+	}
+	revision, err := s.journal.record(ctx, s.StateProvider, job.Id, catalog.ObjectMeta.Namespace, job.Action == v1alpha2.JobDelete)
+	if err != nil {
+		return 0, err
+	}
+	log.DebugfCtx(ctx, " M (Staging): recorded %s of catalog %s as revision %d", job.Action, job.Id, revision)
+	s.channels.notify("")
+	return revision, nil
+}
+
+// WatchSite registers a push channel of a child site. The returned channel is signaled when there are jobs or
+// catalog changes for the site, and closed when the manager shuts down. The returned function unregisters it.
+func (s *StagingManager) WatchSite(site string) (<-chan struct{}, func()) {
+	return s.channels.watch(site)
+}
+
+// IsSiteConnected tells if a child site has a push channel open
+func (s *StagingManager) IsSiteConnected(site string) bool {
+	return s.channels.connected(site)
+}
+
+func (s *StagingManager) Shutdown(ctx context.Context) error {
+	s.channels.close()
+	return nil
+}
+
+// GetCatalogChanges gets up to count catalog changes in a namespace after the watermark of a child site
+func (s *StagingManager) GetCatalogChanges(ctx context.Context, site string, watermark model.SyncWatermark, namespace string, count int) (CatalogChanges, error) {
+	if !watermark.Resync {
+		changes, revision, more, fullSync, err := s.journal.since(ctx, s.StateProvider, site, watermark.Epoch, watermark.Revision, namespace, count)
+		if err != nil {
+			return CatalogChanges{}, err
+		}
+		if !fullSync {
+			return CatalogChanges{
+				Epoch:     watermark.Epoch,
+				Watermark: revision,
+				Changes:   changes,
+				More:      more,
+			}, nil
+		}
+	}
+	epoch, revision, err := s.journal.snapshot(ctx, s.StateProvider, site, namespace)
+	if err != nil {
+		return CatalogChanges{}, err
+	}
+	return CatalogChanges{
+		Epoch:     epoch,
+		Watermark: revision,
+		FullSync:  true,
+	}, nil
+}
@@ -186,3 +186,260 @@ func InitializeMockSymphonyAPI() *httptest.Server {
 	}))
 	return ts
 }
+
+func newJournalManager() StagingManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	return StagingManager{
+		StateProvider: stateProvider,
+	}
+}
+
+func getCatalogChanges(t *testing.T, manager *StagingManager, site string, watermark model.SyncWatermark, namespace string, count int) CatalogChanges {
+	changes, err := manager.GetCatalogChanges(context.Background(), site, watermark, namespace, count)
+	assert.Nil(t, err)
+	return changes
+}
+
+func TestGetCatalogChangesFullSyncForNewSite(t *testing.T) {
+	manager := newJournalManager()
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1"}},
+	})
+	changes := getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10)
+	assert.True(t, changes.FullSync)
+	assert.NotEmpty(t, changes.Epoch)
+	assert.Equal(t, int64(1), changes.Watermark)
+}
+
+func TestGetCatalogChangesDelta(t *testing.T) {
+	manager := newJournalManager()
+	changes := getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10)
+	watermark := model.SyncWatermark{Epoch: changes.Epoch, Revision: changes.Watermark}
+
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1"}},
+	})
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog2",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog2"}},
+	})
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobDelete,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1", Namespace: "default"}},
+	})
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog3",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog3", Namespace: "other"}},
+	})
+
+	changes = getCatalogChanges(t, &manager, "child", watermark, "default", 1)
+	assert.False(t, changes.FullSync)
+	assert.True(t, changes.More)
+	assert.Equal(t, 1, len(changes.Changes))
+	assert.Equal(t, "catalog2", changes.Changes[0].Name)
+	assert.Equal(t, int64(2), changes.Watermark)
+
+	watermark.Revision = changes.Watermark
+	changes = getCatalogChanges(t, &manager, "child", watermark, "default", 10)
+	assert.False(t, changes.FullSync)
+	assert.False(t, changes.More)
+	assert.Equal(t, 1, len(changes.Changes))
+	assert.Equal(t, "catalog1", changes.Changes[0].Name)
+	assert.True(t, changes.Changes[0].Deleted)
+	assert.Equal(t, int64(4), changes.Watermark)
+
+	watermark.Revision = changes.Watermark
+	changes = getCatalogChanges(t, &manager, "child", watermark, "default", 10)
+	assert.False(t, changes.FullSync)
+	assert.Equal(t, 0, len(changes.Changes))
+}
+
+func TestGetCatalogChangesAfterTombstonesArePruned(t *testing.T) {
+	manager := newJournalManager()
+	epoch := getCatalogChanges(t, &manager, "child1", model.SyncWatermark{}, "default", 10).Epoch
+	getCatalogChanges(t, &manager, "child2", model.SyncWatermark{}, "default", 10)
+	manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobDelete,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1"}},
+	})
+
+	// child1 sees the tombstone, but it's kept until child2 has seen it too
+	changes := getCatalogChanges(t, &manager, "child1", model.SyncWatermark{Epoch: epoch}, "default", 10)
+	assert.Equal(t, 1, len(changes.Changes))
+	changes = getCatalogChanges(t, &manager, "child1", model.SyncWatermark{Epoch: epoch, Revision: 1}, "default", 10)
+	assert.Equal(t, 0, len(changes.Changes))
+	changes = getCatalogChanges(t, &manager, "child2", model.SyncWatermark{Epoch: epoch}, "default", 10)
+	assert.Equal(t, 1, len(changes.Changes))
+	changes = getCatalogChanges(t, &manager, "child2", model.SyncWatermark{Epoch: epoch, Revision: 1}, "default", 10)
+	assert.False(t, changes.FullSync)
+
+	// The tombstone is dropped, so a site behind it needs a full resync
+	changes = getCatalogChanges(t, &manager, "child3", model.SyncWatermark{Epoch: epoch}, "default", 10)
+	assert.True(t, changes.FullSync)
+}
+
+func TestGetCatalogChangesResync(t *testing.T) {
+	manager := newJournalManager()
+	changes := getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10)
+	changes = getCatalogChanges(t, &manager, "child", model.SyncWatermark{Epoch: changes.Epoch, Revision: changes.Watermark}, "default", 10)
+	assert.False(t, changes.FullSync)
+	changes = getCatalogChanges(t, &manager, "child", model.SyncWatermark{Epoch: changes.Epoch, Revision: changes.Watermark, Resync: true}, "default", 10)
+	assert.True(t, changes.FullSync)
+	changes = getCatalogChanges(t, &manager, "child", model.SyncWatermark{Epoch: "old", Revision: changes.Watermark}, "default", 10)
+	assert.True(t, changes.FullSync)
+}
+
+func TestGetCatalogChangesAfterRestart(t *testing.T) {
+	manager := newJournalManager()
+	changes := getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10)
+	watermark := model.SyncWatermark{Epoch: changes.Epoch, Revision: changes.Watermark}
+	_, err := manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1"}},
+	})
+	assert.Nil(t, err)
+
+	// Another replica, or the same one after a restart, continues the journal from the state provider
+	restarted := StagingManager{
+		StateProvider: manager.StateProvider,
+	}
+	changes = getCatalogChanges(t, &restarted, "child", watermark, "default", 10)
+	assert.False(t, changes.FullSync)
+	assert.Equal(t, watermark.Epoch, changes.Epoch)
+	assert.Equal(t, 1, len(changes.Changes))
+	assert.Equal(t, "catalog1", changes.Changes[0].Name)
+	assert.Equal(t, int64(1), changes.Watermark)
+}
+
+func TestGetCatalogChangesAcksPerNamespace(t *testing.T) {
+	manager := newJournalManager()
+	epoch := getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10).Epoch
+	getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "other", 10)
+	_, err := manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobDelete,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1", Namespace: "other"}},
+	})
+	assert.Nil(t, err)
+
+	// Syncing the default namespace doesn't acknowledge the tombstone in the other namespace
+	changes := getCatalogChanges(t, &manager, "child", model.SyncWatermark{Epoch: epoch, Revision: 1}, "default", 10)
+	assert.False(t, changes.FullSync)
+	changes = getCatalogChanges(t, &manager, "child", model.SyncWatermark{Epoch: epoch}, "other", 10)
+	assert.False(t, changes.FullSync)
+	assert.Equal(t, 1, len(changes.Changes))
+	assert.True(t, changes.Changes[0].Deleted)
+}
+
+func TestHandleJobEventSkipsCatalogsForJournalSites(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+	manager := newJournalManager()
+	manager.QueueProvider = queueProvider
+	getCatalogChanges(t, &manager, "child", model.SyncWatermark{}, "default", 10)
+	err := manager.HandleJobEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{
+			"site": "child",
+		},
+		Body: v1alpha2.JobData{
+			Id:     "catalog1",
+			Action: v1alpha2.JobUpdate,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, queueProvider.Size("child"))
+
+	err = manager.HandleJobEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{
+			"site": "child",
+		},
+		Body: v1alpha2.JobData{
+			Id:     "job1",
+			Action: v1alpha2.JobRun,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, queueProvider.Size("child"))
+}
+
+func TestWatchSite(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+	manager := newJournalManager()
+	manager.QueueProvider = queueProvider
+	child1, stop1 := manager.WatchSite("child1")
+	child2, stop2 := manager.WatchSite("child2")
+	defer stop2()
+	assert.True(t, manager.IsSiteConnected("child1"))
+
+	// Jobs wake up the channels of their site
+	err := manager.HandleJobEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{
+			"site": "child1",
+		},
+		Body: v1alpha2.JobData{
+			Id:     "job1",
+			Action: v1alpha2.JobRun,
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(child1))
+	assert.Equal(t, 0, len(child2))
+
+	// Catalog changes wake up the channels of all sites, and pending wake-ups aren't stacked
+	_, err = manager.RecordCatalogChange(context.Background(), v1alpha2.JobData{
+		Id:     "catalog1",
+		Action: v1alpha2.JobUpdate,
+		Body:   model.CatalogState{ObjectMeta: model.ObjectMeta{Name: "catalog1"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(child1))
+	assert.Equal(t, 1, len(child2))
+
+	stop1()
+	assert.False(t, manager.IsSiteConnected("child1"))
+
+	manager.Shutdown(context.Background())
+	<-child2
+	_, ok := <-child2
+	assert.False(t, ok)
+}
+
+func TestDrainReports(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+	manager := StagingManager{
+		QueueProvider: queueProvider,
+	}
+	err := manager.QueueReport(model.ChannelMessage{
+		Type:   model.ChannelStatus,
+		Status: &model.StageStatus{Stage: "deploy"},
+	})
+	assert.Nil(t, err)
+	// Reports that come back from a persistent queue as maps are read as well
+	err = queueProvider.Enqueue(Site_Outbox_Queue, map[string]interface{}{
+		"type":   model.ChannelTrail,
+		"trails": []interface{}{map[string]interface{}{"origin": "child1", "catalog": "catalog1", "type": "deploy"}},
+	})
+	assert.Nil(t, err)
+
+	reports, err := manager.DrainReports()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(reports))
+	assert.Equal(t, "deploy", reports[0].Status.Stage)
+	assert.Equal(t, "catalog1", reports[1].Trails[0].Catalog)
+
+	reports, err = manager.DrainReports()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(reports))
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sync
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+)
+
+// SignBundle signs a sync package. sign signs with the key of the PEM certificate, which the bundle carries so
+// that the site importing it can check who signed it.
+func SignBundle(pack model.SyncPackage, certPEM []byte, sign func(data []byte) ([]byte, error)) (model.SyncBundle, error) {
+	if len(certPEM) == 0 || sign == nil {
+		return model.SyncBundle{}, v1alpha2.NewCOAError(nil, "bundle signing certificate is not configured", v1alpha2.BadConfig)
+	}
+	payload, err := json.Marshal(pack)
+	if err != nil {
+		return model.SyncBundle{}, v1alpha2.NewCOAError(err, "failed to marshal the bundle", v1alpha2.InternalError)
+	}
+	signature, err := sign(payload)
+	if err != nil {
+		return model.SyncBundle{}, err
+	}
+	return model.SyncBundle{
+		Payload:     payload,
+		Certificate: string(certPEM),
+		Signature:   hex.EncodeToString(signature),
+	}, nil
+}
+
+// OpenBundle verifies the signature of a bundle with the certificate it carries and gets its sync package. The
+// certificate is returned so that the caller checks it's trusted to sign the package.
+func OpenBundle(bundle model.SyncBundle) (model.SyncPackage, *x509.Certificate, error) {
+	var pack model.SyncPackage
+	block, _ := pem.Decode([]byte(bundle.Certificate))
+	if block == nil {
+		return pack, nil, v1alpha2.NewCOAError(nil, "bundle certificate is not PEM encoded", v1alpha2.Unauthorized)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return pack, nil, v1alpha2.NewCOAError(err, "bundle certificate is not valid", v1alpha2.Unauthorized)
+	}
+	signature, err := hex.DecodeString(bundle.Signature)
+	if err != nil || certs.VerifyData(cert, bundle.Payload, signature) != nil {
+		return pack, nil, v1alpha2.NewCOAError(nil, "bundle signature is not valid", v1alpha2.Unauthorized)
+	}
+	if err = json.Unmarshal(bundle.Payload, &pack); err != nil {
+		return pack, nil, v1alpha2.NewCOAError(err, "bundle payload is not a sync package", v1alpha2.BadRequest)
+	}
+	return pack, cert, nil
+}
+
+// VerifyBundleCA checks that a bundle is signed by a CA in caPEM, the way a parent site signs the bundles of its
+// child sites
+func VerifyBundleCA(cert *x509.Certificate, caPEM []byte) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return v1alpha2.NewCOAError(nil, "CA certificate of the parent site is not valid", v1alpha2.BadConfig)
+	}
+	if !cert.IsCA {
+		return v1alpha2.NewCOAError(nil, "bundle is not signed by a CA", v1alpha2.Unauthorized)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "bundle is not signed by the parent site", v1alpha2.Unauthorized)
+	}
+	return nil
+}
+
+// ImportPackage applies the jobs and catalogs of a bundle from the parent, the same way as a package it syncs
+func (s *SyncManager) ImportPackage(ctx context.Context, pack model.SyncPackage) error {
+	return s.applyPackage(ctx, pack)
+}
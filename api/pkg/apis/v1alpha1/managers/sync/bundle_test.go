@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package sync
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndOpenBundle(t *testing.T) {
+	caPEM, caKeyPEM, err := certs.GenerateCA("parent-ca", time.Hour)
+	assert.Nil(t, err)
+	_, caKey, err := certs.ParseCA(caPEM, caKeyPEM)
+	assert.Nil(t, err)
+	sign := func(data []byte) ([]byte, error) {
+		return certs.SignData(caKey, data)
+	}
+	pack := model.SyncPackage{Origin: "parent", Site: "child1", CreatedAt: time.Now().Format(time.RFC3339Nano)}
+	_, err = SignBundle(pack, nil, sign)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+
+	bundle, err := SignBundle(pack, caPEM, sign)
+	assert.Nil(t, err)
+	opened, cert, err := OpenBundle(bundle)
+	assert.Nil(t, err)
+	assert.Equal(t, pack, opened)
+	assert.Nil(t, VerifyBundleCA(cert, caPEM))
+
+	// The bundle isn't trusted by a site with another CA
+	otherCA, _, err := certs.GenerateCA("other-ca", time.Hour)
+	assert.Nil(t, err)
+	err = VerifyBundleCA(cert, otherCA)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	tampered := bundle
+	tampered.Payload = []byte(`{"origin":"other","site":"child1"}`)
+	_, _, err = OpenBundle(tampered)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	// Swapping the certificate doesn't make the signature valid
+	swapped := bundle
+	swapped.Certificate = string(otherCA)
+	_, _, err = OpenBundle(swapped)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestVerifyBundleCANeedsCA(t *testing.T) {
+	caPEM, caKeyPEM, err := certs.GenerateCA("parent-ca", time.Hour)
+	assert.Nil(t, err)
+	caCert, caKey, err := certs.ParseCA(caPEM, caKeyPEM)
+	assert.Nil(t, err)
+	csr, _, err := certs.GenerateCSR("child1")
+	assert.Nil(t, err)
+	certPEM, err := certs.SignCSR(caCert, caKey, csr, "child1", time.Hour)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+
+	// A child site can't sign bundles for other child sites with its client certificate
+	err = VerifyBundleCA(cert, caPEM)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+}
+
+func TestImportPackage(t *testing.T) {
+	vendorContext := &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "child1",
+		},
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+	jobs := make(chan v1alpha2.JobData, 10)
+	vendorContext.Subscribe("remote-job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			jobs <- event.Body.(v1alpha2.JobData)
+			return nil
+		},
+	})
+
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	catalogsManager := &catalogs.CatalogsManager{}
+	err := catalogsManager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{
+		"StateProvider": stateProvider,
+	})
+	assert.Nil(t, err)
+	manager := SyncManager{CatalogsManager: catalogsManager}
+	err = manager.Init(vendorContext, managers.ManagerConfig{}, nil)
+	assert.Nil(t, err)
+
+	pack := model.SyncPackage{
+		Origin:    "parent",
+		Site:      "child1",
+		CreatedAt: time.Now().Format(time.RFC3339Nano),
+		Epoch:     "epoch1",
+		Watermark: 3,
+		FullSync:  true,
+		Catalogs: []model.CatalogState{
+			{ObjectMeta: model.ObjectMeta{Name: "catalog1-v-v1"}, Spec: &model.CatalogSpec{CatalogType: "config"}},
+		},
+		Jobs: []v1alpha2.JobData{{Id: "instance1", Action: v1alpha2.JobRun}},
+	}
+	err = manager.ImportPackage(context.Background(), pack)
+	assert.Nil(t, err)
+	assert.Equal(t, model.SyncWatermark{Epoch: "epoch1", Revision: 3}, manager.Watermark())
+	catalog, err := catalogsManager.GetState(context.Background(), "parent-catalog1-v-v1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "config", catalog.Spec.CatalogType)
+	select {
+	case job := <-jobs:
+		assert.Equal(t, "instance1", job.Id)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "job of the bundle wasn't published")
+	}
+}
@@ -8,7 +8,12 @@ package sync
 
 import (
 	"context"
+	"encoding/json"
+	gosync "sync"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -16,11 +21,42 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
+var log = logger.NewLogger("coa.runtime")
+
+// maxSyncRounds limits how many packages are fetched in a poll when the parent has more changes
+const maxSyncRounds = 10
+
+const (
+	// defaultChannelIdleTimeout is how long the push channel can go without a frame before it's reconnected.
+	// The parent pings every 30 seconds by default.
+	defaultChannelIdleTimeout = 90 * time.Second
+	minChannelRetry           = time.Second
+	maxChannelRetry           = 30 * time.Second
+)
+
+var errChannelNotConnected = v1alpha2.NewCOAError(nil, "push channel is not connected", v1alpha2.InternalError)
+
 type SyncManager struct {
 	managers.Manager
 	apiClient utils.ApiClient
+	// CatalogsManager applies catalog changes from the parent. Without it, catalogs are published as catalog-sync
+	// events and the watermark isn't kept.
+	CatalogsManager *catalogs.CatalogsManager
+	lock            gosync.Mutex
+	watermark       model.SyncWatermark
+	// applyLock makes packages from polls and from the push channel apply one at a time
+	applyLock gosync.Mutex
+
+	channelEnabled     bool
+	channelIdleTimeout time.Duration
+	channelOnce        gosync.Once
+	connected          bool
+	// reconnect drops the push channel, so it's opened again with the current watermark
+	reconnect context.CancelFunc
+	cancel    context.CancelFunc
 }
 
 func (s *SyncManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -31,15 +67,59 @@ func (s *SyncManager) Init(context *contexts.VendorContext, config managers.Mana
 	if s.Context.SiteInfo.SiteId == "" {
 		return v1alpha2.NewCOAError(nil, "siteId is required", v1alpha2.BadConfig)
 	}
-	s.apiClient, err = utils.GetParentApiClient(s.VendorContext.SiteInfo.ParentSite.BaseUrl)
+	s.apiClient, err = utils.GetParentApiClient(s.VendorContext.SiteInfo.ParentSite)
 	if err != nil {
 		return err
 	}
+	s.channelEnabled = config.Properties["sync.channel"] == "true"
+	s.channelIdleTimeout = defaultChannelIdleTimeout
+	if v, ok := config.Properties["sync.channelIdleTimeout"]; ok {
+		s.channelIdleTimeout, err = time.ParseDuration(v)
+		if err != nil || s.channelIdleTimeout <= 0 {
+			return v1alpha2.NewCOAError(err, "sync.channelIdleTimeout is not a valid duration", v1alpha2.BadConfig)
+		}
+	}
 	return nil
 }
 func (s *SyncManager) Enabled() bool {
 	return s.Config.Properties["sync.enabled"] == "true"
 }
+
+// RequestResync makes the next poll get all catalogs from the parent, such as after the site lost data. The
+// push channel is reopened to ask for them.
+func (s *SyncManager) RequestResync() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.watermark.Resync = true
+	if s.reconnect != nil {
+		s.reconnect()
+	}
+}
+
+// Connected tells if the push channel to the parent is open. Polls are skipped while it is.
+func (s *SyncManager) Connected() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connected
+}
+
+// Report sends messages to the parent over the push channel
+func (s *SyncManager) Report(ctx context.Context, messages ...model.ChannelMessage) error {
+	if !s.Connected() {
+		return errChannelNotConnected
+	}
+	return s.apiClient.SendChannelMessages(ctx, s.VendorContext.SiteInfo.SiteId, messages,
+		s.VendorContext.SiteInfo.ParentSite.Username,
+		s.VendorContext.SiteInfo.ParentSite.Password)
+}
+
+// Watermark gets how far the site has applied the catalog changes of its parent
+func (s *SyncManager) Watermark() model.SyncWatermark {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.watermark
+}
+
 func (s *SyncManager) Poll() []error {
 	ctx, span := observability.StartSpan("Sync Manager", context.Background(), &map[string]string{
 		"method": "Poll",
@@ -50,44 +130,211 @@ func (s *SyncManager) Poll() []error {
 	if s.VendorContext.SiteInfo.ParentSite.BaseUrl == "" {
 		return nil
 	}
-	batch, err := s.apiClient.GetABatchForSite(ctx, s.VendorContext.SiteInfo.SiteId,
+	if s.channelEnabled {
+		s.channelOnce.Do(func() {
+			channelCtx, cancel := context.WithCancel(context.Background())
+			s.lock.Lock()
+			s.cancel = cancel
+			s.lock.Unlock()
+			go s.runChannel(channelCtx)
+		})
+		if s.Connected() {
+			// The parent pushes packages over the channel
+			return nil
+		}
+	}
+	if s.CatalogsManager == nil {
+		var batch model.SyncPackage
+		batch, err = s.apiClient.GetABatchForSite(ctx, s.VendorContext.SiteInfo.SiteId,
+			s.VendorContext.SiteInfo.ParentSite.Username,
+			s.VendorContext.SiteInfo.ParentSite.Password)
+		if err != nil {
+			return []error{err}
+		}
+		s.publishJobs(ctx, batch)
+		s.publishCatalogs(ctx, batch)
+		return nil
+	}
+	for round := 0; round < maxSyncRounds; round++ {
+		var batch model.SyncPackage
+		batch, err = s.apiClient.GetSyncPackageForSite(ctx, s.VendorContext.SiteInfo.SiteId, s.Watermark(),
+			s.VendorContext.SiteInfo.ParentSite.Username,
+			s.VendorContext.SiteInfo.ParentSite.Password)
+		if err != nil {
+			return []error{err}
+		}
+		err = s.applyPackage(ctx, batch)
+		if err != nil {
+			return []error{err}
+		}
+		if batch.Epoch == "" || !batch.More {
+			break
+		}
+	}
+	return nil
+}
+
+// applyPackage publishes the jobs of a package and applies its catalog changes, then moves the watermark.
+// Packages older than the watermark only have their jobs published, as a newer package already brought the
+// catalogs they change.
+func (s *SyncManager) applyPackage(ctx context.Context, batch model.SyncPackage) error {
+	s.applyLock.Lock()
+	defer s.applyLock.Unlock()
+	// Jobs are removed from the queue of the parent when they're sent, so they're published even if
+	// catalog changes fail to apply
+	s.publishJobs(ctx, batch)
+	if batch.Epoch == "" {
+		// The parent doesn't keep a change journal
+		s.publishCatalogs(ctx, batch)
+		return nil
+	}
+	current := s.Watermark()
+	if !batch.FullSync && !current.Resync && batch.Epoch == current.Epoch && batch.Watermark < current.Revision {
+		log.DebugfCtx(ctx, " M (Sync): skipping catalog changes up to revision %d, already at revision %d", batch.Watermark, current.Revision)
+		return nil
+	}
+	err := s.applyCatalogs(ctx, batch)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Sync): failed to apply catalog changes up to revision %d: %s", batch.Watermark, err.Error())
+		return err
+	}
+	s.lock.Lock()
+	s.watermark = model.SyncWatermark{
+		Epoch:    batch.Epoch,
+		Revision: batch.Watermark,
+		// A resync requested while the package was applied is kept
+		Resync: s.watermark.Resync && !batch.FullSync,
+	}
+	s.lock.Unlock()
+	return nil
+}
+
+// runChannel keeps the push channel to the parent open. While it's down, polls fetch packages instead.
+func (s *SyncManager) runChannel(ctx context.Context) {
+	retry := minChannelRetry
+	for {
+		opened, err := s.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if opened {
+			// The parent closes the channel when it reaches its maximum duration, so it's reopened quickly
+			retry = minChannelRetry
+		}
+		log.InfofCtx(ctx, " M (Sync): push channel is down, polling until it reconnects in %s: %v", retry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > maxChannelRetry {
+			retry = maxChannelRetry
+		}
+	}
+}
+
+// listen opens the push channel and applies the packages the parent sends until the channel drops. It returns
+// whether the channel was opened, and why it dropped.
+func (s *SyncManager) listen(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := s.apiClient.OpenSyncChannel(ctx, s.VendorContext.SiteInfo.SiteId, s.Watermark(),
 		s.VendorContext.SiteInfo.ParentSite.Username,
 		s.VendorContext.SiteInfo.ParentSite.Password)
 	if err != nil {
-		return []error{err}
-	}
-	if batch.Catalogs != nil {
-		for _, catalog := range batch.Catalogs {
-			s.Context.Publish("catalog-sync", v1alpha2.Event{
-				Metadata: map[string]string{
-					"objectType": catalog.Spec.CatalogType,
-					"origin":     batch.Origin,
-				},
-				Body: v1alpha2.JobData{
-					Id:     catalog.ObjectMeta.Name,
-					Action: v1alpha2.JobUpdate, //TODO: handle deletion, this probably requires BetBachForSites return flags
-					Body:   catalog,
-				},
-				Context: ctx,
-			})
+		return false, err
+	}
+	defer stream.Close()
+
+	s.lock.Lock()
+	s.connected = true
+	s.reconnect = cancel
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		s.connected = false
+		s.reconnect = nil
+		s.lock.Unlock()
+	}()
+	log.InfofCtx(ctx, " M (Sync): push channel is open")
+
+	// The request is canceled when the parent goes quiet, which ends the read
+	idle := time.AfterFunc(s.channelIdleTimeout, cancel)
+	defer idle.Stop()
+	decoder := json.NewDecoder(stream)
+	for {
+		var message model.ChannelMessage
+		if err := decoder.Decode(&message); err != nil {
+			return true, err
+		}
+		idle.Reset(s.channelIdleTimeout)
+		if message.Type == model.ChannelSync && message.Package != nil {
+			if err := s.applyPackage(ctx, *message.Package); err != nil {
+				return true, err
+			}
 		}
 	}
-	if batch.Jobs != nil {
-		for _, job := range batch.Jobs {
-			s.Context.Publish("remote-job", v1alpha2.Event{
-				Metadata: map[string]string{
-					"origin": batch.Origin,
-				},
-				Body:    job,
-				Context: ctx,
-			})
+}
+
+// applyCatalogs applies the catalog changes of a package in order. When the package is a full sync, catalogs
+// synced from the parent that aren't in it are deleted.
+func (s *SyncManager) applyCatalogs(ctx context.Context, batch model.SyncPackage) error {
+	names := make([]string, 0, len(batch.Catalogs))
+	for _, catalog := range batch.Catalogs {
+		if err := s.CatalogsManager.UpsertSyncedState(ctx, batch.Origin, catalog); err != nil {
+			return err
 		}
+		names = append(names, catalog.ObjectMeta.Name)
 	}
-	if err != nil {
-		return []error{err}
+	for _, tombstone := range batch.Deletions {
+		if err := s.CatalogsManager.DeleteSyncedState(ctx, batch.Origin, tombstone.Name, tombstone.Namespace); err != nil {
+			return err
+		}
+	}
+	if batch.FullSync {
+		return s.CatalogsManager.PruneSyncedStates(ctx, batch.Origin, "default", names)
 	}
 	return nil
 }
+
+func (s *SyncManager) publishCatalogs(ctx context.Context, batch model.SyncPackage) {
+	for _, catalog := range batch.Catalogs {
+		s.Context.Publish("catalog-sync", v1alpha2.Event{
+			Metadata: map[string]string{
+				"objectType": catalog.Spec.CatalogType,
+				"origin":     batch.Origin,
+			},
+			Body: v1alpha2.JobData{
+				Id:     catalog.ObjectMeta.Name,
+				Action: v1alpha2.JobUpdate,
+				Body:   catalog,
+			},
+			Context: ctx,
+		})
+	}
+}
+
+func (s *SyncManager) publishJobs(ctx context.Context, batch model.SyncPackage) {
+	for _, job := range batch.Jobs {
+		s.Context.Publish("remote-job", v1alpha2.Event{
+			Metadata: map[string]string{
+				"origin": batch.Origin,
+			},
+			Body:    job,
+			Context: ctx,
+		})
+	}
+}
 func (s *SyncManager) Reconcil() []error {
 	return nil
 }
+
+func (s *SyncManager) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return nil
+}
@@ -7,18 +7,25 @@
 package sync
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	gosync "sync"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -205,3 +212,251 @@ func TestPoll(t *testing.T) {
 	assert.Equal(t, "catalog1", catalog1.ObjectMeta.Name)
 	assert.Equal(t, "job1", job1.Id)
 }
+
+func TestPollAppliesCatalogChanges(t *testing.T) {
+	siteId := "fake"
+	requests := []url.Values{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/federation/sync/" + siteId:
+			query := r.URL.Query()
+			requests = append(requests, query)
+			if query.Get("epoch") != "epoch1" || query.Get("resync") == "true" {
+				response = model.SyncPackage{
+					Origin:    "parent",
+					Epoch:     "epoch1",
+					Watermark: 5,
+					FullSync:  true,
+					Catalogs: []model.CatalogState{
+						{ObjectMeta: model.ObjectMeta{Name: "catalog1-v-v1"}, Spec: &model.CatalogSpec{CatalogType: "config"}},
+						{ObjectMeta: model.ObjectMeta{Name: "catalog2-v-v1"}, Spec: &model.CatalogSpec{CatalogType: "config"}},
+					},
+				}
+			} else {
+				response = model.SyncPackage{
+					Origin:    "parent",
+					Epoch:     "epoch1",
+					Watermark: 7,
+					Catalogs: []model.CatalogState{
+						{ObjectMeta: model.ObjectMeta{Name: "catalog3-v-v1"}, Spec: &model.CatalogSpec{CatalogType: "config"}},
+					},
+					Deletions: []model.CatalogTombstone{
+						{Name: "catalog1-v-v1", Namespace: "default", Revision: 6},
+					},
+				}
+			}
+		case "/users/auth":
+			response = AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+
+	vendorContext := &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: siteId,
+			ParentSite: v1alpha2.SiteConnection{
+				BaseUrl:  ts.URL + "/",
+				Username: "admin",
+			},
+		},
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	catalogsManager := &catalogs.CatalogsManager{}
+	err := catalogsManager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{
+		"StateProvider": stateProvider,
+	})
+	assert.Nil(t, err)
+	// A catalog synced before the site lost track of its parent, which the parent no longer has
+	err = catalogsManager.UpsertSyncedState(context.Background(), "parent", model.CatalogState{
+		ObjectMeta: model.ObjectMeta{Name: "stale-v-v1"},
+		Spec:       &model.CatalogSpec{CatalogType: "config"},
+	})
+	assert.Nil(t, err)
+
+	manager := SyncManager{CatalogsManager: catalogsManager}
+	err = manager.Init(vendorContext, managers.ManagerConfig{}, nil)
+	assert.Nil(t, err)
+
+	errs := manager.Poll()
+	assert.Nil(t, errs)
+	assert.Equal(t, "0", requests[0].Get("since"))
+	assert.Equal(t, model.SyncWatermark{Epoch: "epoch1", Revision: 5}, manager.Watermark())
+	list, err := catalogsManager.ListState(context.Background(), "default", "", "")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"parent-catalog1-v-v1", "parent-catalog2-v-v1"}, catalogNames(list))
+	assert.Equal(t, "parent", list[0].ObjectMeta.Labels[constants.SyncOrigin])
+
+	errs = manager.Poll()
+	assert.Nil(t, errs)
+	assert.Equal(t, "5", requests[1].Get("since"))
+	assert.Equal(t, "epoch1", requests[1].Get("epoch"))
+	assert.Equal(t, model.SyncWatermark{Epoch: "epoch1", Revision: 7}, manager.Watermark())
+	list, err = catalogsManager.ListState(context.Background(), "default", "", "")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"parent-catalog2-v-v1", "parent-catalog3-v-v1"}, catalogNames(list))
+
+	manager.RequestResync()
+	errs = manager.Poll()
+	assert.Nil(t, errs)
+	assert.Equal(t, "true", requests[2].Get("resync"))
+	assert.False(t, manager.Watermark().Resync)
+	list, err = catalogsManager.ListState(context.Background(), "default", "", "")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"parent-catalog1-v-v1", "parent-catalog2-v-v1"}, catalogNames(list))
+}
+
+func TestChannelAppliesPushedPackages(t *testing.T) {
+	siteId := "fake"
+	var lock gosync.Mutex
+	polls := 0
+	messages := []model.ChannelMessage{}
+	push := make(chan model.SyncPackage)
+	drop := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/federation/channel/" + siteId:
+			if r.Method == http.MethodPost {
+				decoder := json.NewDecoder(r.Body)
+				for {
+					var message model.ChannelMessage
+					if err := decoder.Decode(&message); err != nil {
+						break
+					}
+					lock.Lock()
+					messages = append(messages, message)
+					lock.Unlock()
+				}
+				return
+			}
+			encoder := json.NewEncoder(w)
+			encoder.Encode(model.ChannelMessage{Type: model.ChannelPing})
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case pack := <-push:
+					encoder.Encode(model.ChannelMessage{Type: model.ChannelSync, Package: &pack})
+					w.(http.Flusher).Flush()
+				case <-drop:
+					return
+				case <-r.Context().Done():
+					return
+				}
+			}
+		case "/federation/sync/" + siteId:
+			lock.Lock()
+			polls++
+			lock.Unlock()
+			json.NewEncoder(w).Encode(model.SyncPackage{Origin: "parent", Epoch: "epoch1", Watermark: 5})
+		case "/users/auth":
+			json.NewEncoder(w).Encode(AuthResponse{AccessToken: "test-token", TokenType: "Bearer"})
+		}
+	}))
+	defer ts.Close()
+
+	vendorContext := &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: siteId,
+			ParentSite: v1alpha2.SiteConnection{
+				BaseUrl:  ts.URL + "/",
+				Username: "admin",
+			},
+		},
+		Logger: logger.NewLogger("coa.runtime"),
+	}
+	vendorContext.PubsubProvider = &memory.InMemoryPubSubProvider{}
+	vendorContext.PubsubProvider.Init(memory.InMemoryPubSubConfig{})
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	catalogsManager := &catalogs.CatalogsManager{}
+	err := catalogsManager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{
+		"StateProvider": stateProvider,
+	})
+	assert.Nil(t, err)
+
+	manager := SyncManager{CatalogsManager: catalogsManager}
+	err = manager.Init(vendorContext, managers.ManagerConfig{
+		Properties: map[string]string{
+			"sync.channel": "true",
+		},
+	}, nil)
+	assert.Nil(t, err)
+	defer manager.Shutdown(context.Background())
+
+	// The first poll opens the channel
+	assert.Nil(t, manager.Poll())
+	assert.Eventually(t, manager.Connected, 5*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	pollsWhileConnecting := polls
+	lock.Unlock()
+
+	push <- model.SyncPackage{
+		Origin:    "parent",
+		Epoch:     "epoch1",
+		Watermark: 3,
+		FullSync:  true,
+		Catalogs: []model.CatalogState{
+			{ObjectMeta: model.ObjectMeta{Name: "catalog1-v-v1"}, Spec: &model.CatalogSpec{CatalogType: "config"}},
+		},
+	}
+	assert.Eventually(t, func() bool {
+		return manager.Watermark().Revision == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	list, err := catalogsManager.ListState(context.Background(), "default", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"parent-catalog1-v-v1"}, catalogNames(list))
+
+	// Polls are skipped while the channel is open
+	assert.Nil(t, manager.Poll())
+	lock.Lock()
+	assert.Equal(t, pollsWhileConnecting, polls)
+	lock.Unlock()
+
+	err = manager.Report(context.Background(), model.ChannelMessage{
+		Type:   model.ChannelStatus,
+		Status: &model.StageStatus{Stage: "stage1"},
+	})
+	assert.Nil(t, err)
+	lock.Lock()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, "stage1", messages[0].Status.Stage)
+	lock.Unlock()
+
+	// Polling takes over when the channel drops
+	close(drop)
+	assert.Eventually(t, func() bool {
+		return !manager.Connected()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, manager.Poll())
+	lock.Lock()
+	assert.Equal(t, pollsWhileConnecting+1, polls)
+	lock.Unlock()
+	assert.Equal(t, int64(5), manager.Watermark().Revision)
+}
+
+func catalogNames(list []model.CatalogState) []string {
+	names := make([]string, 0, len(list))
+	for _, catalog := range list {
+		names = append(names, catalog.ObjectMeta.Name)
+	}
+	return names
+}
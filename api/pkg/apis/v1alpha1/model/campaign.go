@@ -65,6 +65,23 @@ type TaskOption struct {
 	ErrorAction ErrorAction `json:"errorAction,omitempty"`
 }
 
+// JoinSpec makes a stage wait for its predecessors, which run in parallel branches
+// +kubebuilder:object:generate=true
+type JoinSpec struct {
+	// Predecessors are the stages the join stage waits for
+	Predecessors []string `json:"predecessors,omitempty"`
+	// Count is the number of predecessors that need to finish before the join stage runs. All of them when it's 0.
+	Count int `json:"count,omitempty"`
+}
+
+// RequiredCount gets the number of predecessors a join waits for
+func (j JoinSpec) RequiredCount() int {
+	if j.Count > 0 && j.Count < len(j.Predecessors) {
+		return j.Count
+	}
+	return len(j.Predecessors)
+}
+
 type TaskSpec struct {
 	Name     string                 `json:"name,omitempty"`
 	Provider string                 `json:"provider,omitempty"`
@@ -73,6 +90,43 @@ type TaskSpec struct {
 	Target   string                 `json:"target,omitempty"`
 }
 
+const (
+	// NotificationEventSuccess is raised when an activation finishes as Done
+	NotificationEventSuccess = "success"
+	// NotificationEventFailure is raised when an activation finishes with an error
+	NotificationEventFailure = "failure"
+	// NotificationEventCancelled is raised when an activation is cancelled
+	NotificationEventCancelled = "cancelled"
+	// NotificationEventFinished is raised when an activation finishes, whatever its status
+	NotificationEventFinished = "finished"
+)
+
+// NotificationSpec runs a stage provider, the notification provider by default, when an activation of the
+// campaign raises one of the events in On. It doesn't need a stage of the campaign.
+type NotificationSpec struct {
+	Name     string                 `json:"name,omitempty"`
+	On       []string               `json:"on,omitempty"`
+	Provider string                 `json:"provider,omitempty"`
+	Config   interface{}            `json:"config,omitempty"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"`
+}
+
+const (
+	HookOnSuccess = "onSuccess"
+	HookOnFailure = "onFailure"
+	HookFinally   = "finally"
+)
+
+// CampaignHooks names the stages that run once an activation is finished. OnSuccess stages run when the
+// activation is done, OnFailure stages when it fails, and Finally stages afterwards in all cases, including
+// cancellation. Hook stages run one after another; their stage selectors are not used.
+// +kubebuilder:object:generate=true
+type CampaignHooks struct {
+	OnSuccess []string `json:"onSuccess,omitempty"`
+	OnFailure []string `json:"onFailure,omitempty"`
+	Finally   []string `json:"finally,omitempty"`
+}
+
 type StageSpec struct {
 	Name          string                 `json:"name,omitempty"`
 	Contexts      string                 `json:"contexts,omitempty"`
@@ -85,6 +139,12 @@ type StageSpec struct {
 	Target        string                 `json:"target,omitempty"`
 	Tasks         []TaskSpec             `json:"tasks,omitempty"`
 	TaskOption    TaskOption             `json:"taskOption,omitempty"`
+	// ForEach is an expression that evaluates to a list. The provider of the stage runs once for each item,
+	// which $val() gets in the inputs of the stage.
+	ForEach string `json:"forEach,omitempty"`
+	// NextStages fans out to stages that run in parallel branches. StageSelector is not used when it's set.
+	NextStages []string  `json:"nextStages,omitempty"`
+	Join       *JoinSpec `json:"join,omitempty"`
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for StageSpec
@@ -144,6 +204,10 @@ func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if s.ForEach != otherS.ForEach {
+		return false, nil
+	}
+
 	if !reflect.DeepEqual(s.Inputs, otherS.Inputs) {
 		return false, nil
 	}
@@ -152,6 +216,14 @@ func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if !reflect.DeepEqual(s.NextStages, otherS.NextStages) {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(s.Join, otherS.Join) {
+		return false, nil
+	}
+
 	return true, nil
 }
 
@@ -165,18 +237,65 @@ type ActivationStatus struct {
 type StageStatus struct {
 	Stage         string                 `json:"stage,omitempty"`
 	NextStage     string                 `json:"nextStage,omitempty"`
+	NextStages    []string               `json:"nextStages,omitempty"`
+	Branch        string                 `json:"branch,omitempty"`
 	Inputs        map[string]interface{} `json:"inputs,omitempty"`
 	Outputs       map[string]interface{} `json:"outputs,omitempty"`
 	Status        v1alpha2.State         `json:"status,omitempty"`
 	IsActive      bool                   `json:"isActive,omitempty"`
 	StatusMessage string                 `json:"statusMessage,omitempty"`
 	ErrorMessage  string                 `json:"errorMessage,omitempty"`
+	// Hook is set when the stage runs as a lifecycle hook of the activation
+	Hook string `json:"hook,omitempty"`
+}
+
+// Desired states of an activation. An empty state is the same as running.
+const (
+	ActivationStateRunning   = "running"
+	ActivationStatePaused    = "paused"
+	ActivationStateCancelled = "cancelled"
+)
+
+func IsValidActivationState(state string) bool {
+	switch state {
+	case "", ActivationStateRunning, ActivationStatePaused, ActivationStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CampaignSimulationSpec describes a dry run of a campaign, in which every stage provider is replaced with a
+// mock that returns scripted results. It lets authors test the stage selectors, branches and loops of a campaign.
+type CampaignSimulationSpec struct {
+	// Campaign is the campaign to simulate when no stored campaign is named
+	Campaign *CampaignSpec          `json:"campaign,omitempty"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"`
+	// Stages maps stage names, or stage.task names for tasks, to the results of their consecutive runs.
+	// The last result is repeated once the results are used up.
+	Stages map[string][]SimulatedStageResult `json:"stages,omitempty"`
+	// MaxSteps limits the number of stages that are run, it defaults to 100
+	MaxSteps int `json:"maxSteps,omitempty"`
+}
+
+type SimulatedStageResult struct {
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Pause   bool                   `json:"pause,omitempty"`
+}
+
+type CampaignSimulationResult struct {
+	Status        v1alpha2.State `json:"status"`
+	StatusMessage string         `json:"statusMessage,omitempty"`
+	ErrorMessage  string         `json:"errorMessage,omitempty"`
+	StageHistory  []StageStatus  `json:"stageHistory,omitempty"`
 }
 
 type ActivationSpec struct {
 	Campaign string                 `json:"campaign,omitempty"`
 	Stage    string                 `json:"stage,omitempty"`
 	Inputs   map[string]interface{} `json:"inputs,omitempty"`
+	State    string                 `json:"state,omitempty"`
 }
 
 func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -197,6 +316,8 @@ func (c ActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, errors.New("inputs doesn't match")
 	}
 
+	// State is not compared: it is the only field of the spec that can change after creation
+
 	return true, nil
 }
 func (c ActivationState) DeepEquals(other IDeepEquals) (bool, error) {
@@ -223,6 +344,10 @@ type CampaignSpec struct {
 	SelfDriving  bool                 `json:"selfDriving,omitempty"`
 	Version      string               `json:"version,omitempty"`
 	RootResource string               `json:"rootResource,omitempty"`
+	// Notifications are sent on campaign-level events, such as an activation failing
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+	// Hooks are stages that run once an activation is finished
+	Hooks *CampaignHooks `json:"hooks,omitempty"`
 }
 
 func (c CampaignSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -243,6 +368,14 @@ func (c CampaignSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if !reflect.DeepEqual(c.Notifications, otherC.Notifications) {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(c.Hooks, otherC.Hooks) {
+		return false, nil
+	}
+
 	for i, stage := range c.Stages {
 		otherStage := otherC.Stages[i]
 
@@ -44,6 +44,39 @@ type CatalogStatus struct {
 	Properties map[string]string `json:"properties"`
 }
 
+const (
+	CatalogRevisionUpsert  = "upsert"
+	CatalogRevisionDelete  = "delete"
+	CatalogRevisionRestore = "restore"
+)
+
+// CatalogRevision is the record of a write to a catalog. Revisions aren't changed once they're recorded.
+type CatalogRevision struct {
+	Revision  int64  `json:"revision"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Operation is upsert, delete or restore
+	Operation string `json:"operation"`
+	// Author is the user who made the write, or the site a synced catalog came from
+	Author    string `json:"author,omitempty"`
+	Timestamp string `json:"timestamp"`
+	// RestoredFrom is the revision a restore wrote again
+	RestoredFrom int64 `json:"restoredFrom,omitempty"`
+	// Changes are the changes to the spec of the catalog from the previous revision
+	Changes []CatalogRevisionChange `json:"changes,omitempty"`
+	// Catalog is the catalog as it was written. Deletions don't have one.
+	Catalog *CatalogState `json:"catalog,omitempty"`
+}
+
+// CatalogRevisionChange is a change to a value of a catalog spec, located by its JSON pointer
+type CatalogRevisionChange struct {
+	Path string `json:"path"`
+	// Op is add, remove or replace
+	Op  string      `json:"op"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
 func (c CatalogSpec) DeepEquals(other IDeepEquals) (bool, error) {
 	otherC, ok := other.(CatalogSpec)
 	if !ok {
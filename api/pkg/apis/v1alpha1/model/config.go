@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+const (
+	PromotionPending  = "Pending"
+	PromotionRejected = "Rejected"
+	PromotionPromoted = "Promoted"
+	PromotionFailed   = "Failed"
+)
+
+// PropertyChange is a change to a property value, located by its JSON pointer
+type PropertyChange struct {
+	Path string `json:"path"`
+	// Op is add, remove or replace
+	Op  string      `json:"op"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// ConfigPromotionRequest asks to promote the values of a config object from one environment to the next
+type ConfigPromotionRequest struct {
+	Object string `json:"object"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Keys limits the promotion to these top-level keys. All keys of the source environment are promoted
+	// without it.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// ConfigPromotion is the record of a promotion, kept as its audit trail
+type ConfigPromotion struct {
+	Id string `json:"id"`
+	ConfigPromotionRequest
+	// Values are the values written to the target environment
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Changes are the changes to the effective config of the target environment
+	Changes     []PropertyChange `json:"changes,omitempty"`
+	State       string           `json:"state,omitempty"`
+	RequestedBy string           `json:"requestedBy,omitempty"`
+	RequestedAt string           `json:"requestedAt,omitempty"`
+	ReviewedBy  string           `json:"reviewedBy,omitempty"`
+	ReviewedAt  string           `json:"reviewedAt,omitempty"`
+	PromotedAt  string           `json:"promotedAt,omitempty"`
+	Comment     string           `json:"comment,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
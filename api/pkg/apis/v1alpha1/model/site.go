@@ -25,6 +25,8 @@ type SiteTargetStatus struct {
 type SiteInstanceStatus struct {
 	State  v1alpha2.State `json:"state,omitempty"`
 	Reason string         `json:"reason,omitempty"`
+	// Solution is the solution the instance deploys, as <name>:<version>
+	Solution string `json:"solution,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -33,6 +35,39 @@ type SiteStatus struct {
 	TargetStatuses   map[string]SiteTargetStatus   `json:"targetStatuses,omitempty"`
 	InstanceStatuses map[string]SiteInstanceStatus `json:"instanceStatuses,omitempty"`
 	LastReported     string                        `json:"lastReported,omitempty"`
+	// Descendants are the sites below the site, as it last reported them. A site passes them to its parent with
+	// its own status, so a root site sees the whole tree.
+	Descendants []SiteSummary `json:"descendants,omitempty"`
+}
+
+// SiteSummary is the status of a site in the fleet a site sees: itself, its child sites, and the sites below them
+// +kubebuilder:object:generate=true
+type SiteSummary struct {
+	Name string `json:"name"`
+	// Parent is the site the site reports to
+	Parent           string                        `json:"parent,omitempty"`
+	Properties       map[string]string             `json:"properties,omitempty"`
+	IsOnline         bool                          `json:"isOnline"`
+	LastReported     string                        `json:"lastReported,omitempty"`
+	TargetStatuses   map[string]SiteTargetStatus   `json:"targetStatuses,omitempty"`
+	InstanceStatuses map[string]SiteInstanceStatus `json:"instanceStatuses,omitempty"`
+}
+
+// FleetInstance is an instance on a site of the fleet
+type FleetInstance struct {
+	Site     string         `json:"site"`
+	Name     string         `json:"name"`
+	Solution string         `json:"solution,omitempty"`
+	State    v1alpha2.State `json:"state,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
+}
+
+// FleetTarget is a target on a site of the fleet
+type FleetTarget struct {
+	Site   string         `json:"site"`
+	Name   string         `json:"name"`
+	State  v1alpha2.State `json:"state,omitempty"`
+	Reason string         `json:"reason,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -41,6 +76,58 @@ type SiteSpec struct {
 	IsSelf     bool              `json:"isSelf,omitempty"`
 	PublicKey  string            `json:"secretHash,omitempty"`
 	Properties map[string]string `json:"properties,omitempty"`
+	// Certificates are the client certificates the site can authenticate to its parent with. They're managed by
+	// enrollment, rotation and revocation, and kept when the site is updated.
+	Certificates []SiteCertificate `json:"certificates,omitempty"`
+	// JoinToken is the one-time token the site can enroll with
+	JoinToken *SiteJoinToken `json:"joinToken,omitempty"`
+}
+
+// SiteCertificate is a client certificate issued to a site
+// +kubebuilder:object:generate=true
+type SiteCertificate struct {
+	Serial string `json:"serial"`
+	// NotAfter is when the certificate stops being accepted, in RFC 3339 format. It's brought forward when the
+	// certificate is rotated.
+	NotAfter string `json:"notAfter"`
+}
+
+// SiteJoinToken is a one-time token a site enrolls with. Only its hash is kept.
+// +kubebuilder:object:generate=true
+type SiteJoinToken struct {
+	Hash      string `json:"hash"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// SiteJoinTokenRequest asks for a join token for a site
+type SiteJoinTokenRequest struct {
+	Site string `json:"site"`
+	// TTL is how long the token can be used, as a duration such as "1h"
+	TTL string `json:"ttl,omitempty"`
+}
+
+// SiteJoinTokenGrant is a join token created for a site
+type SiteJoinTokenGrant struct {
+	Site      string `json:"site"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// SiteEnrollmentRequest is what a site sends to get a client certificate: a PEM certificate request, with a join
+// token when it enrolls
+type SiteEnrollmentRequest struct {
+	Site  string `json:"site"`
+	Token string `json:"token,omitempty"`
+	CSR   string `json:"csr"`
+}
+
+// SiteEnrollment is a client certificate issued to a site
+type SiteEnrollment struct {
+	Site          string `json:"site"`
+	Certificate   string `json:"certificate"`
+	CACertificate string `json:"caCertificate"`
+	Serial        string `json:"serial"`
+	NotAfter      string `json:"notAfter"`
 }
 
 func (s SiteSpec) DeepEquals(other IDeepEquals) (bool, error) {
@@ -12,4 +12,94 @@ type SyncPackage struct {
 	Origin   string             `json:"origin,omitempty"`
 	Catalogs []CatalogState     `json:"catalogs,omitempty"`
 	Jobs     []v1alpha2.JobData `json:"jobs,omitempty"`
+	// Deletions are the catalogs deleted since the watermark the child sent
+	Deletions []CatalogTombstone `json:"deletions,omitempty"`
+	// Epoch identifies the change journal of the parent. Watermarks of another epoch aren't comparable.
+	Epoch string `json:"epoch,omitempty"`
+	// Watermark is the revision the child has caught up to once it applies the package
+	Watermark int64 `json:"watermark,omitempty"`
+	// FullSync means Catalogs has all the catalogs of the parent, so synced catalogs that aren't in it are deleted
+	FullSync bool `json:"fullSync,omitempty"`
+	// More means there are more changes after the watermark
+	More bool `json:"more,omitempty"`
+
+	// The fields below are only used by offline bundles, which carry everything a site needs when it has no
+	// network path to the other side.
+
+	// Site is the child site a bundle is for. Bundles a child site exports for its parent leave it empty.
+	Site string `json:"site,omitempty"`
+	// CreatedAt is when the bundle was exported, in RFC 3339 format. Bundles older than the last one imported
+	// from the same origin are rejected.
+	CreatedAt          string                   `json:"createdAt,omitempty"`
+	Solutions          []SolutionState          `json:"solutions,omitempty"`
+	SolutionContainers []SolutionContainerState `json:"solutionContainers,omitempty"`
+	CatalogContainers  []CatalogContainerState  `json:"catalogContainers,omitempty"`
+	Campaigns          []CampaignState          `json:"campaigns,omitempty"`
+	CampaignContainers []CampaignContainerState `json:"campaignContainers,omitempty"`
+	Artifacts          []BundleArtifact         `json:"artifacts,omitempty"`
+	// Reports are the status reports, heartbeats and trails a child site queued for its parent while offline
+	Reports []ChannelMessage `json:"reports,omitempty"`
+}
+
+// SyncBundle is a sync package signed to be carried between sites that can't reach each other
+type SyncBundle struct {
+	// Payload is the JSON of the SyncPackage. It's kept as bytes so the signature covers exactly what was signed.
+	Payload []byte `json:"payload"`
+	// Certificate is the PEM certificate of the signer: the CA of the parent site for a bundle to a child site, or
+	// the client certificate of a child site for its bundle to the parent
+	Certificate string `json:"certificate"`
+	// Signature is the hex-encoded signature of the SHA-256 digest of the payload with the key of the certificate
+	Signature string `json:"signature"`
+}
+
+// BundleArtifact is a file an offline bundle carries, such as a chart or an image archive a solution references
+type BundleArtifact struct {
+	Name string `json:"name"`
+	// Digest is the hex-encoded SHA-256 of the data
+	Digest string `json:"digest,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// BundleExportRequest lists the artifacts to add to an offline bundle. Artifacts without data are read from the
+// artifact directory of the exporting site.
+type BundleExportRequest struct {
+	Artifacts []BundleArtifact `json:"artifacts,omitempty"`
+}
+
+// SyncWatermark is what a child site has applied from the change journal of its parent
+type SyncWatermark struct {
+	Epoch    string `json:"epoch,omitempty"`
+	Revision int64  `json:"revision"`
+	// Resync asks the parent for all of its catalogs, such as after the child lost data
+	Resync bool `json:"resync,omitempty"`
+}
+
+// CatalogTombstone tells a child site that a catalog was deleted on the parent
+type CatalogTombstone struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Revision  int64  `json:"revision"`
+}
+
+const (
+	// ChannelSync carries a sync package from the parent to a child site
+	ChannelSync = "sync"
+	// ChannelPing keeps the channel open when the parent has nothing to send
+	ChannelPing = "ping"
+	// ChannelStatus carries a stage status from a child site to its parent
+	ChannelStatus = "status"
+	// ChannelHeartbeat carries the state of a child site to its parent
+	ChannelHeartbeat = "heartbeat"
+	// ChannelTrail carries trails from a child site to its parent
+	ChannelTrail = "trail"
+)
+
+// ChannelMessage is a frame of the push channel a child site opens to its parent. Frames are sent as
+// newline-delimited JSON.
+type ChannelMessage struct {
+	Type    string           `json:"type"`
+	Package *SyncPackage     `json:"package,omitempty"`
+	Status  *StageStatus     `json:"status,omitempty"`
+	Site    *SiteState       `json:"site,omitempty"`
+	Trails  []v1alpha2.Trail `json:"trails,omitempty"`
 }
@@ -34,6 +34,36 @@ func (in *BindingSpec) DeepCopy() *BindingSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CampaignHooks) DeepCopyInto(out *CampaignHooks) {
+	*out = *in
+	if in.OnSuccess != nil {
+		in, out := &in.OnSuccess, &out.OnSuccess
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OnFailure != nil {
+		in, out := &in.OnFailure, &out.OnFailure
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Finally != nil {
+		in, out := &in.Finally, &out.Finally
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CampaignHooks.
+func (in *CampaignHooks) DeepCopy() *CampaignHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(CampaignHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentError) DeepCopyInto(out *ComponentError) {
 	*out = *in
@@ -181,6 +211,26 @@ func (in *InstanceSpec) DeepCopy() *InstanceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JoinSpec) DeepCopyInto(out *JoinSpec) {
+	*out = *in
+	if in.Predecessors != nil {
+		in, out := &in.Predecessors, &out.Predecessors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JoinSpec.
+func (in *JoinSpec) DeepCopy() *JoinSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(JoinSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModelSpec) DeepCopyInto(out *ModelSpec) {
 	*out = *in
@@ -342,6 +392,36 @@ func (in *RouteSpec) DeepCopy() *RouteSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteCertificate) DeepCopyInto(out *SiteCertificate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteCertificate.
+func (in *SiteCertificate) DeepCopy() *SiteCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteJoinToken) DeepCopyInto(out *SiteJoinToken) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteJoinToken.
+func (in *SiteJoinToken) DeepCopy() *SiteJoinToken {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteJoinToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SiteSpec) DeepCopyInto(out *SiteSpec) {
 	*out = *in
@@ -352,6 +432,16 @@ func (in *SiteSpec) DeepCopyInto(out *SiteSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]SiteCertificate, len(*in))
+		copy(*out, *in)
+	}
+	if in.JoinToken != nil {
+		in, out := &in.JoinToken, &out.JoinToken
+		*out = new(SiteJoinToken)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteSpec.
@@ -381,6 +471,13 @@ func (in *SiteStatus) DeepCopyInto(out *SiteStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.Descendants != nil {
+		in, out := &in.Descendants, &out.Descendants
+		*out = make([]SiteSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteStatus.
@@ -393,6 +490,42 @@ func (in *SiteStatus) DeepCopy() *SiteStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SiteSummary) DeepCopyInto(out *SiteSummary) {
+	*out = *in
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TargetStatuses != nil {
+		in, out := &in.TargetStatuses, &out.TargetStatuses
+		*out = make(map[string]SiteTargetStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InstanceStatuses != nil {
+		in, out := &in.InstanceStatuses, &out.InstanceStatuses
+		*out = make(map[string]SiteInstanceStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SiteSummary.
+func (in *SiteSummary) DeepCopy() *SiteSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(SiteSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SkillPackageSpec) DeepCopyInto(out *SkillPackageSpec) {
 	*out = *in
@@ -40,6 +40,13 @@ const (
 	ARMDeploymentPropertyOperation    string = "ARMDeploymentProperty"
 	ARMCreateDeploymentOperation      string = "ARMCreateDeployment"
 	ARMCleanUpDeploymentOperation     string = "ARMCleanUpDeploymentOperation"
+	PruneResourceOperation            string = "PruneResource"
+	RenderKustomizeOperation          string = "RenderKustomize"
+	HelmValuesOperation               string = "HelmValues"
+	HelmTestOperation                 string = "HelmTest"
+	PullArtifactOperation             string = "PullArtifact"
+	PluginOperation                   string = "Plugin"
+	GitOperation                      string = "Git"
 
 	ProcessOperation string = "Process"
 	ApplyOperation   string = "Apply"
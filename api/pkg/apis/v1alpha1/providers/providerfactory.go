@@ -13,13 +13,18 @@ import (
 	catalogconfig "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/config/catalog"
 	memorygraph "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph/memory"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/secret"
+	campaignstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/campaign"
 	counterstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/counter"
 	symphonystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/create"
 	delaystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/delay"
+	gitstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/git"
 	httpstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/http"
+	jobstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/job"
 	liststage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/list"
 	materialize "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/materialize"
 	mockstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/mock"
+	notificationstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/notification"
+	ocistage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/oci"
 	patchstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/patch"
 	remotestage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/remote"
 	scriptstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/script"
@@ -30,6 +35,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/azure/iotedge"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/configmap"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/docker"
+	targetgrpc "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/helm"
 	targethttp "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/http"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/ingress"
@@ -45,6 +51,8 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	cp "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	autogencerts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	localfilecerts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
 	mockconfig "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/mock"
 	memorykeylock "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/keylock/memory"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
@@ -57,6 +65,8 @@ import (
 	k8sref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/k8s"
 	httpreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/http"
 	k8sreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/k8s"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/encryptedsecret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/filesecret"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/httpstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
@@ -227,6 +237,12 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
+	case "providers.target.grpc":
+		mProvider := &targetgrpc.GrpcTargetProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
 	case "providers.target.win10.sideload":
 		mProvider := &sideload.Win10SideLoadProvider{}
 		err := mProvider.Init(config)
@@ -293,6 +309,30 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
+	case "providers.secret.file":
+		mProvider := &filesecret.FileSecretProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.secret.encrypted":
+		mProvider := &encryptedsecret.EncryptedSecretProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.certs.autogen":
+		mProvider := &autogencerts.AutoGenCertProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.certs.localfile":
+		mProvider := &localfilecerts.LocalCertFileProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
 	case "providers.pubsub.memory":
 		mProvider := &mempubsub.InMemoryPubSubProvider{}
 		err = mProvider.Init(config)
@@ -353,6 +393,12 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
+	case "providers.stage.campaign":
+		mProvider := &campaignstage.CampaignStageProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
 	case "providers.stage.delay":
 		mProvider := &delaystage.DelayStageProvider{}
 		err = mProvider.Init(config)
@@ -365,6 +411,30 @@ func (s SymphonyProviderFactory) CreateProvider(providerType string, config cp.I
 		if err == nil {
 			return mProvider, nil
 		}
+	case "providers.stage.oci":
+		mProvider := &ocistage.OCIStageProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.stage.notification":
+		mProvider := &notificationstage.NotificationStageProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.stage.job":
+		mProvider := &jobstage.JobStageProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
+	case "providers.stage.git":
+		mProvider := &gitstage.GitStageProvider{}
+		err = mProvider.Init(config)
+		if err == nil {
+			return mProvider, nil
+		}
 	case "providers.queue.memory":
 		mProvider := &memoryqueue.MemoryQueueProvider{}
 		err = mProvider.Init(config)
@@ -468,6 +538,14 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.target.grpc":
+					provider := &targetgrpc.GrpcTargetProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				case "providers.target.win10.sideload":
 					provider := &sideload.Win10SideLoadProvider{}
 					err := provider.InitWithMap(binding.Config)
@@ -724,6 +802,14 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.stage.campaign":
+					provider := &campaignstage.CampaignStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				case "providers.stage.materialize":
 					provider := &materialize.MaterializeStageProvider{}
 					err := provider.InitWithMap(binding.Config)
@@ -732,6 +818,38 @@ func CreateProviderForTargetRole(context *contexts.ManagerContext, role string,
 					}
 					provider.Context = context
 					return provider, nil
+				case "providers.stage.oci":
+					provider := &ocistage.OCIStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.notification":
+					provider := &notificationstage.NotificationStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.job":
+					provider := &jobstage.JobStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
+				case "providers.stage.git":
+					provider := &gitstage.GitStageProvider{}
+					err := provider.InitWithMap(binding.Config)
+					if err != nil {
+						return nil, err
+					}
+					provider.Context = context
+					return provider, nil
 				case "providers.queue.memory":
 					provider := &memoryqueue.MemoryQueueProvider{}
 					err := provider.InitWithMap(binding.Config)
@@ -39,6 +39,8 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/script"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/staging"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/win10/sideload"
+	autogencerts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	localfilecerts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
 	mockconfig "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/mock"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/probe/rtsp"
@@ -49,6 +51,8 @@ import (
 	k8sref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/k8s"
 	httpreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/http"
 	k8sreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/k8s"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/encryptedsecret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/filesecret"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/httpstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
@@ -222,6 +226,22 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*mocksecret.MockSecretProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.secret.file", filesecret.FileSecretProviderConfig{Path: t.TempDir(), DisableReload: true})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*filesecret.FileSecretProvider))
+
+	provider, err = providerfactory.CreateProvider("providers.secret.encrypted", encryptedsecret.EncryptedSecretProviderConfig{Key: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*encryptedsecret.EncryptedSecretProvider))
+
+	provider, err = providerfactory.CreateProvider("providers.certs.autogen", autogencerts.AutoGenCertProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*autogencerts.AutoGenCertProvider))
+
+	provider, err = providerfactory.CreateProvider("providers.certs.localfile", localfilecerts.LocalCertFileProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*localfilecerts.LocalCertFileProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.pubsub.memory", mempubsub.InMemoryPubSubConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*mempubsub.InMemoryPubSubProvider))
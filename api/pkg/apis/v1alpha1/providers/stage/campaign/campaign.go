@@ -0,0 +1,373 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+const (
+	loggerName   = "providers.stage.campaign"
+	providerName = "P (Campaign Stage)"
+	campaign     = "campaign"
+)
+
+var (
+	log                      = logger.NewLogger(loggerName)
+	mcLock                   sync.Mutex
+	once                     sync.Once
+	providerOperationMetrics *metrics.Metrics
+)
+
+// defaultWaitInterval is the number of seconds between two checks of the child activation
+const defaultWaitInterval = 5
+
+type CampaignStageProviderConfig struct {
+	User         string `json:"user"`
+	Password     string `json:"password"`
+	WaitInterval int    `json:"wait.interval,omitempty"`
+	WaitCount    int    `json:"wait.count,omitempty"`
+}
+
+// CampaignStageProvider runs another campaign as a stage. It creates a child activation of the campaign,
+// waits for it to finish and returns the outputs of its last stage.
+type CampaignStageProvider struct {
+	Config    CampaignStageProviderConfig
+	Context   *contexts.ManagerContext
+	ApiClient api_utils.ApiClient
+}
+
+func (s *CampaignStageProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("[Stage] Campaign Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	mcLock.Lock()
+	defer mcLock.Unlock()
+	var campaignConfig CampaignStageProviderConfig
+	campaignConfig, err = toCampaignStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if campaignConfig.WaitInterval < 0 {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("wait interval %d can't be negative", campaignConfig.WaitInterval), v1alpha2.BadConfig)
+		return err
+	}
+	if campaignConfig.WaitInterval == 0 {
+		campaignConfig.WaitInterval = defaultWaitInterval
+	}
+	s.Config = campaignConfig
+	s.ApiClient, err = api_utils.GetApiClient()
+	if err != nil {
+		return err
+	}
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				log.ErrorfCtx(ctx, "  P (Campaign Stage): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	return err
+}
+func (s *CampaignStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toCampaignStageProviderConfig(config providers.IProviderConfig) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *CampaignStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := CampaignStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func CampaignStageProviderConfigFromMap(properties map[string]string) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+
+	user, err := api_utils.GetString(properties, "user")
+	if err != nil {
+		return ret, err
+	}
+	ret.User = user
+	if ret.User == "" {
+		return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+	}
+	password, err := api_utils.GetString(properties, "password")
+	if err != nil {
+		return ret, err
+	}
+	ret.Password = password
+
+	if v, ok := properties["wait.interval"]; ok {
+		interval, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse wait interval %v", v), v1alpha2.BadConfig)
+		}
+		if interval < 0 {
+			return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("wait interval %v can't be negative", v), v1alpha2.BadConfig)
+		}
+		ret.WaitInterval = interval
+	}
+	if v, ok := properties["wait.count"]; ok {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse wait count %v", v), v1alpha2.BadConfig)
+		}
+		ret.WaitCount = count
+	}
+	return ret, nil
+}
+
+// Process creates a child activation of the campaign in the "campaign" input, or of the version in the
+// "version" input of the campaign container in the "campaign" input. The "inputs" input is passed to the
+// child activation. Process waits for the child activation to finish; if the stage is cancelled in the
+// meantime, the child activation is cancelled too.
+func (i *CampaignStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Campaign Process Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfoCtx(ctx, "  P (Campaign Stage): processing inputs")
+	processTime := time.Now().UTC()
+	functionName := observ_utils.GetFunctionName()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		processTime,
+		campaign,
+		metrics.ProcessOperation,
+		metrics.RunOperationType,
+		functionName,
+	)
+
+	if ctx.Err() != nil {
+		err = v1alpha2.NewCOAError(ctx.Err(), "stage is cancelled", v1alpha2.Cancelled)
+		return nil, false, err
+	}
+	// Calls to the Symphony API aren't cancelled with the stage, which only stops waiting for the child activation
+	apiCtx := context.WithoutCancel(ctx)
+
+	var childName, campaignName string
+	var childInputs map[string]interface{}
+	namespace := stage.GetNamespace(inputs)
+	if namespace == "" {
+		namespace = "default"
+	}
+	childName, campaignName, childInputs, err = i.readInputs(apiCtx, inputs, namespace)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Campaign Stage): invalid inputs: %v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			campaign,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.ValidateOperationType,
+			v1alpha2.BadConfig.String(),
+		)
+		return nil, false, err
+	}
+
+	err = i.startChildActivation(apiCtx, childName, campaignName, childInputs, namespace, stage.ReadInputString(inputs, "__activation"))
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Campaign Stage): failed to start activation %s of campaign %s: %v", childName, campaignName, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			campaign,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return nil, false, err
+	}
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Campaign Stage): started activation %s of campaign %s in namespace %s", childName, campaignName, namespace)
+
+	var child model.ActivationState
+	child, err = i.waitForChildActivation(ctx, apiCtx, childName, namespace)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Campaign Stage): failed to wait for activation %s: %v", childName, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			campaign,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return map[string]interface{}{
+			"activation": childName,
+			"campaign":   campaignName,
+		}, false, err
+	}
+
+	outputs := make(map[string]interface{})
+	errorMessage := ""
+	if len(child.Status.StageHistory) > 0 {
+		last := child.Status.StageHistory[len(child.Status.StageHistory)-1]
+		for k, v := range last.Outputs {
+			if !strings.HasPrefix(k, "__") {
+				outputs[k] = v
+			}
+		}
+		errorMessage = last.ErrorMessage
+	}
+	outputs["activation"] = childName
+	outputs["campaign"] = campaignName
+	outputs["activationStatus"] = child.Status.Status.String()
+	if child.Status.Status != v1alpha2.Done {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s of campaign %s finished as %s: %s", childName, campaignName, child.Status.Status.String(), errorMessage), child.Status.Status)
+		log.ErrorfCtx(ctx, "  P (Campaign Stage): %v", err)
+		return outputs, false, err
+	}
+	log.InfofCtx(ctx, "  P (Campaign Stage): activation %s of campaign %s is done", childName, campaignName)
+	return outputs, false, nil
+}
+
+func (i *CampaignStageProvider) readInputs(ctx context.Context, inputs map[string]interface{}, namespace string) (string, string, map[string]interface{}, error) {
+	campaignName := stage.ReadInputString(inputs, "campaign")
+	if campaignName == "" {
+		return "", "", nil, v1alpha2.NewCOAError(nil, "campaign is required", v1alpha2.BadRequest)
+	}
+	// A version pins the campaign to a version of the campaign container
+	if version := stage.ReadInputString(inputs, "version"); version != "" {
+		_, err := i.ApiClient.GetCampaignContainer(ctx, campaignName, namespace, i.Config.User, i.Config.Password)
+		if err != nil {
+			return "", "", nil, v1alpha2.NewCOAError(err, fmt.Sprintf("campaign container %s is not found", campaignName), v1alpha2.BadRequest)
+		}
+		campaignName = campaignName + constants.ReferenceSeparator + version
+	}
+	_, err := i.ApiClient.GetCampaign(ctx, api_utils.ConvertReferenceToObjectName(campaignName), namespace, i.Config.User, i.Config.Password)
+	if err != nil {
+		return "", "", nil, v1alpha2.NewCOAError(err, fmt.Sprintf("campaign %s is not found", campaignName), v1alpha2.BadRequest)
+	}
+
+	var childInputs map[string]interface{}
+	if v, ok := inputs["inputs"]; ok && v != nil {
+		childInputs, ok = v.(map[string]interface{})
+		if !ok {
+			return "", "", nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("inputs is not a valid map: %v", v), v1alpha2.BadRequest)
+		}
+	}
+
+	childName := stage.ReadInputString(inputs, "activation")
+	if childName == "" {
+		parent := stage.ReadInputString(inputs, "__activation")
+		parentStage := stage.ReadInputString(inputs, "__stage")
+		if parent == "" || parentStage == "" {
+			return "", "", nil, v1alpha2.NewCOAError(nil, "activation is required when the stage isn't run by an activation", v1alpha2.BadRequest)
+		}
+		childName = fmt.Sprintf("%s-%s", parent, parentStage)
+	}
+	return childName, campaignName, childInputs, nil
+}
+
+// startChildActivation creates the child activation. An activation of the same parent that is still running
+// is waited for again, which is the case when the stage is retried; a finished one is replaced.
+func (i *CampaignStageProvider) startChildActivation(ctx context.Context, childName string, campaignName string, childInputs map[string]interface{}, namespace string, parent string) error {
+	existing, err := i.ApiClient.GetActivation(ctx, childName, namespace, i.Config.User, i.Config.Password)
+	if err == nil {
+		if parent == "" || existing.ObjectMeta.Labels[constants.ParentActivation] != parent {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s already exists", childName), v1alpha2.BadRequest)
+		}
+		if !isActivationFinished(existing) {
+			log.InfofCtx(ctx, "  P (Campaign Stage): activation %s is already running", childName)
+			return nil
+		}
+		err = i.ApiClient.DeleteActivation(ctx, childName, namespace, i.Config.User, i.Config.Password)
+		if err != nil {
+			return err
+		}
+	} else if !api_utils.IsNotFound(err) {
+		return err
+	}
+
+	activation := model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      childName,
+			Namespace: namespace,
+		},
+		Spec: &model.ActivationSpec{
+			Campaign: campaignName,
+			Inputs:   childInputs,
+		},
+	}
+	if parent != "" {
+		activation.ObjectMeta.Labels = map[string]string{
+			constants.ParentActivation: parent,
+		}
+	}
+	payload, _ := json.Marshal(activation)
+	return i.ApiClient.CreateActivation(ctx, childName, payload, namespace, i.Config.User, i.Config.Password)
+}
+
+func (i *CampaignStageProvider) waitForChildActivation(ctx context.Context, apiCtx context.Context, childName string, namespace string) (model.ActivationState, error) {
+	for counter := 0; counter < i.Config.WaitCount || i.Config.WaitCount == 0; counter++ {
+		child, err := i.ApiClient.GetActivation(apiCtx, childName, namespace, i.Config.User, i.Config.Password)
+		if err != nil {
+			if api_utils.IsNotFound(err) {
+				return child, v1alpha2.NewCOAError(err, fmt.Sprintf("activation %s got deleted", childName), v1alpha2.NotFound)
+			}
+			return child, err
+		}
+		if isActivationFinished(child) {
+			return child, nil
+		}
+		select {
+		case <-ctx.Done():
+			// The parent activation is cancelled, so is the child activation
+			log.InfofCtx(ctx, "  P (Campaign Stage): stage is cancelled, cancelling activation %s", childName)
+			err = i.ApiClient.ControlActivation(apiCtx, childName, namespace, model.ActivationStateCancelled, i.Config.User, i.Config.Password)
+			if err != nil {
+				log.ErrorfCtx(ctx, "  P (Campaign Stage): failed to cancel activation %s: %v", childName, err)
+			}
+			return child, v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("waiting for activation %s is cancelled", childName), v1alpha2.Cancelled)
+		case <-time.After(time.Duration(i.Config.WaitInterval) * time.Second):
+		}
+	}
+	return model.ActivationState{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s didn't finish in time", childName), v1alpha2.TimedOut)
+}
+
+func isActivationFinished(activation model.ActivationState) bool {
+	if activation.Status == nil {
+		return false
+	}
+	switch activation.Status.Status {
+	case 0, v1alpha2.OK, v1alpha2.Untouched, v1alpha2.Running, v1alpha2.Paused, v1alpha2.Delayed:
+		return false
+	default:
+		return true
+	}
+}
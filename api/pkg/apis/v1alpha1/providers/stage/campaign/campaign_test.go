@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthResponse struct {
+	AccessToken string   `json:"accessToken"`
+	TokenType   string   `json:"tokenType"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+}
+
+// mockSymphonyAPI serves a campaign container "deploy" with version "v2" and a child activation that
+// reports the given final status after a number of polls
+type mockSymphonyAPI struct {
+	lock        sync.Mutex
+	finalStatus v1alpha2.State
+	pollsToDone int
+	polls       int
+	created     *model.ActivationState
+	cancelled   bool
+	onPoll      func()
+}
+
+func (m *mockSymphonyAPI) start() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		var response interface{}
+		switch r.URL.Path {
+		case "/campaigncontainers/deploy":
+			response = model.CampaignContainerState{ObjectMeta: model.ObjectMeta{Name: "deploy"}}
+		case "/campaigns/deploy-v-v2":
+			response = model.CampaignState{ObjectMeta: model.ObjectMeta{Name: "deploy-v-v2"}, Spec: &model.CampaignSpec{}}
+		case "/activations/registry/parent-sub":
+			switch r.Method {
+			case http.MethodPost:
+				body, _ := io.ReadAll(r.Body)
+				var activation model.ActivationState
+				json.Unmarshal(body, &activation)
+				m.created = &activation
+				response = activation
+			case http.MethodDelete:
+				m.created = nil
+				response = map[string]string{}
+			default:
+				if m.created == nil {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte("not found"))
+					return
+				}
+				activation := *m.created
+				m.polls++
+				if m.onPoll != nil {
+					m.onPoll()
+				}
+				activation.Status = &model.ActivationStatus{Status: v1alpha2.Running}
+				if m.polls >= m.pollsToDone {
+					activation.Status.Status = m.finalStatus
+					activation.Status.StageHistory = []model.StageStatus{
+						{
+							Stage:        "verify",
+							Status:       m.finalStatus,
+							ErrorMessage: "verify failed",
+							Outputs: map[string]interface{}{
+								"endpoint":    "http://app",
+								"__namespace": "default",
+							},
+						},
+					}
+				}
+				response = activation
+			}
+		case "/activations/cancel/parent-sub":
+			m.cancelled = true
+			response = map[string]string{}
+		case "/users/auth":
+			response = AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+				Username:    "test-user",
+				Roles:       []string{"role1", "role2"},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func createProvider(t *testing.T, api *mockSymphonyAPI, waitCount string) *CampaignStageProvider {
+	ts := api.start()
+	t.Cleanup(ts.Close)
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	provider := &CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"user":          "admin",
+		"password":      "",
+		"wait.interval": "1",
+		"wait.count":    waitCount,
+	})
+	assert.Nil(t, err)
+	return provider
+}
+
+func subCampaignInputs() map[string]interface{} {
+	return map[string]interface{}{
+		"campaign": "deploy",
+		"version":  "v2",
+		"inputs": map[string]interface{}{
+			"app": "web",
+		},
+		"__activation": "parent",
+		"__stage":      "sub",
+		"__namespace":  "default",
+	}
+}
+
+func TestCampaignInitWithMap(t *testing.T) {
+	config, err := CampaignStageProviderConfigFromMap(map[string]string{
+		"user":          "admin",
+		"password":      "",
+		"wait.interval": "5",
+		"wait.count":    "10",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", config.User)
+	assert.Equal(t, 5, config.WaitInterval)
+	assert.Equal(t, 10, config.WaitCount)
+
+	_, err = CampaignStageProviderConfigFromMap(map[string]string{})
+	assert.NotNil(t, err)
+
+	_, err = CampaignStageProviderConfigFromMap(map[string]string{
+		"user":          "admin",
+		"password":      "",
+		"wait.interval": "abc",
+	})
+	assert.NotNil(t, err)
+
+	_, err = CampaignStageProviderConfigFromMap(map[string]string{
+		"user":          "admin",
+		"password":      "",
+		"wait.interval": "-1",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestCampaignInitDefaultWaitInterval(t *testing.T) {
+	os.Setenv(constants.SymphonyAPIUrlEnvName, "http://localhost:8082/v1alpha2/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	provider := &CampaignStageProvider{}
+	err := provider.Init(CampaignStageProviderConfig{User: "admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWaitInterval, provider.Config.WaitInterval)
+
+	err = provider.Init(CampaignStageProviderConfig{User: "admin", WaitInterval: -1})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestCampaignProcess(t *testing.T) {
+	api := &mockSymphonyAPI{finalStatus: v1alpha2.Done, pollsToDone: 3}
+	provider := createProvider(t, api, "10")
+
+	outputs, paused, err := provider.Process(context.Background(), contexts.ManagerContext{}, subCampaignInputs())
+	assert.Nil(t, err)
+	assert.False(t, paused)
+	assert.Equal(t, "parent-sub", outputs["activation"])
+	assert.Equal(t, "deploy:v2", outputs["campaign"])
+	assert.Equal(t, v1alpha2.Done.String(), outputs["activationStatus"])
+	assert.Equal(t, "http://app", outputs["endpoint"])
+	_, ok := outputs["__namespace"]
+	assert.False(t, ok)
+
+	assert.NotNil(t, api.created)
+	assert.Equal(t, "deploy:v2", api.created.Spec.Campaign)
+	assert.Equal(t, "web", api.created.Spec.Inputs["app"])
+	assert.Equal(t, "parent", api.created.ObjectMeta.Labels[constants.ParentActivation])
+
+	// a finished child activation is replaced when the stage runs again
+	api.polls = 0
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, subCampaignInputs())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, api.polls)
+}
+
+func TestCampaignProcessChildFailed(t *testing.T) {
+	api := &mockSymphonyAPI{finalStatus: v1alpha2.InternalError, pollsToDone: 1}
+	provider := createProvider(t, api, "10")
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, subCampaignInputs())
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, err.(v1alpha2.COAError).State)
+	assert.Contains(t, err.Error(), "verify failed")
+	assert.Equal(t, v1alpha2.InternalError.String(), outputs["activationStatus"])
+}
+
+func TestCampaignProcessTimeout(t *testing.T) {
+	api := &mockSymphonyAPI{finalStatus: v1alpha2.Done, pollsToDone: 100}
+	provider := createProvider(t, api, "2")
+
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, subCampaignInputs())
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.TimedOut, err.(v1alpha2.COAError).State)
+}
+
+func TestCampaignProcessCancelled(t *testing.T) {
+	api := &mockSymphonyAPI{finalStatus: v1alpha2.Done, pollsToDone: 100}
+	provider := createProvider(t, api, "0")
+
+	// the stage is cancelled while it waits for the child activation
+	ctx, cancel := context.WithCancel(context.Background())
+	api.onPoll = cancel
+	_, _, err := provider.Process(ctx, contexts.ManagerContext{}, subCampaignInputs())
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, err.(v1alpha2.COAError).State)
+	assert.True(t, api.cancelled)
+
+	// a cancelled stage doesn't start the child activation
+	api.created = nil
+	_, _, err = provider.Process(ctx, contexts.ManagerContext{}, subCampaignInputs())
+	assert.NotNil(t, err)
+	assert.Nil(t, api.created)
+}
+
+func TestCampaignProcessInvalidInputs(t *testing.T) {
+	api := &mockSymphonyAPI{finalStatus: v1alpha2.Done, pollsToDone: 1}
+	provider := createProvider(t, api, "1")
+
+	inputs := subCampaignInputs()
+	delete(inputs, "campaign")
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.NotNil(t, err)
+
+	inputs = subCampaignInputs()
+	inputs["campaign"] = "rollback"
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "campaign container rollback is not found")
+
+	inputs = subCampaignInputs()
+	inputs["version"] = "v3"
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "campaign deploy:v3 is not found")
+
+	inputs = subCampaignInputs()
+	inputs["inputs"] = "app=web"
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,751 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	loggerName   = "providers.stage.git"
+	providerName = "P (Git Stage)"
+	git          = "git"
+
+	ReadAction = "read"
+	PushAction = "push"
+
+	defaultWorkDir       = "/tmp/symphony/git"
+	defaultTimeout       = 5 * time.Minute
+	defaultMaxInlineSize = 1 << 20
+	defaultPushAttempts  = 3
+	defaultAuthorName    = "Symphony"
+	defaultAuthorEmail   = "symphony@localhost"
+	defaultBranch        = "main"
+
+	repositoryAnnotation = "symphony/git-repository"
+	commitAnnotation     = "symphony/git-commit"
+	pathAnnotation       = "symphony/git-path"
+)
+
+var (
+	msLock                   sync.Mutex
+	mLog                     = logger.NewLogger(loggerName)
+	once                     sync.Once
+	providerOperationMetrics *metrics.Metrics
+	defaultGlobs             = []string{"**/*.yaml", "**/*.yml", "**/*.json"}
+)
+
+type GitStageProviderConfig struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// WorkDir keeps a work tree per repository, so later stages only fetch new commits
+	WorkDir string `json:"workDir,omitempty"`
+	// Timeout bounds each git command, such as a fetch or a push
+	Timeout     string `json:"timeout,omitempty"`
+	AuthorName  string `json:"authorName,omitempty"`
+	AuthorEmail string `json:"authorEmail,omitempty"`
+}
+
+// GitStageProvider reads desired state from a Git repository, and commits and pushes changes to it
+type GitStageProvider struct {
+	Config    GitStageProviderConfig
+	Context   *contexts.ManagerContext
+	ApiClient api_utils.ApiClient
+}
+
+// SecretReference locates repository credentials in the secret provider
+type SecretReference struct {
+	Name          string `json:"name"`
+	UsernameField string `json:"usernameField,omitempty"`
+	PasswordField string `json:"passwordField,omitempty"`
+}
+
+func (s *GitStageProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("[Stage] Git Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	msLock.Lock()
+	defer msLock.Unlock()
+	var gitConfig GitStageProviderConfig
+	gitConfig, err = toGitStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if gitConfig.Timeout != "" {
+		if d, perr := time.ParseDuration(gitConfig.Timeout); perr != nil || d <= 0 {
+			err = v1alpha2.NewCOAError(perr, fmt.Sprintf("timeout %s is not a positive duration", gitConfig.Timeout), v1alpha2.BadConfig)
+			return err
+		}
+	}
+	s.Config = gitConfig
+	s.ApiClient, err = api_utils.GetApiClient()
+	if err != nil {
+		return err
+	}
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				mLog.ErrorfCtx(ctx, "  P (Git Stage): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	return err
+}
+func (s *GitStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toGitStageProviderConfig(config providers.IProviderConfig) (GitStageProviderConfig, error) {
+	ret := GitStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *GitStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := GitStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func GitStageProviderConfigFromMap(properties map[string]string) (GitStageProviderConfig, error) {
+	ret := GitStageProviderConfig{}
+	if api_utils.ShouldUseUserCreds() {
+		user, err := api_utils.GetString(properties, "user")
+		if err != nil {
+			return ret, err
+		}
+		ret.User = user
+		if ret.User == "" && !api_utils.ShouldUseSATokens() {
+			return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+		}
+		password, err := api_utils.GetString(properties, "password")
+		ret.Password = password
+		if err != nil {
+			return ret, err
+		}
+	}
+	ret.WorkDir = properties["workDir"]
+	ret.Timeout = properties["timeout"]
+	ret.AuthorName = properties["authorName"]
+	ret.AuthorEmail = properties["authorEmail"]
+	return ret, nil
+}
+
+// Process runs the action in the action input. The read action checks out a ref and emits the YAML and JSON
+// files matching the glob input as outputs, or upserts them as catalogs or solutions. The push action writes
+// and deletes files on a branch, then commits and pushes the change.
+func (i *GitStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Git provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	mLog.InfofCtx(ctx, "  P (Git Stage) process started")
+	processTime := time.Now().UTC()
+	functionName := observ_utils.GetFunctionName()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		processTime,
+		git,
+		metrics.ProcessOperation,
+		metrics.RunOperationType,
+		functionName,
+	)
+
+	var outputs map[string]interface{}
+	var repo *repository
+	repo, err = i.openRepository(ctx, mgrContext, inputs)
+	if err == nil {
+		action := strings.ToLower(stage.ReadInputString(inputs, "action"))
+		switch action {
+		case "", ReadAction:
+			outputs, err = i.read(ctx, repo, inputs)
+		case PushAction:
+			outputs, err = i.push(ctx, repo, inputs)
+		default:
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("Unsupported action: %s", action), v1alpha2.BadRequest)
+		}
+	}
+	if err != nil {
+		mLog.ErrorfCtx(ctx, "  P (Git Stage) process failed, error: %+v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			git,
+			functionName,
+			metrics.GitOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return outputs, false, err
+	}
+	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+	return outputs, false, nil
+}
+
+// openRepository gets the work tree of the repository input, with the credentials of the stage
+func (i *GitStageProvider) openRepository(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (*repository, error) {
+	url := stage.ReadInputString(inputs, "repository")
+	if url == "" {
+		return nil, v1alpha2.NewCOAError(nil, "repository input is required", v1alpha2.BadRequest)
+	}
+	if strings.HasPrefix(url, "-") {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%q is not a valid repository", url), v1alpha2.BadRequest)
+	}
+	cred, err := i.getCredential(ctx, mgrContext, inputs)
+	if err != nil {
+		return nil, err
+	}
+	timeout := defaultTimeout
+	if i.Config.Timeout != "" {
+		timeout, _ = time.ParseDuration(i.Config.Timeout)
+	}
+	workDir := i.Config.WorkDir
+	if workDir == "" {
+		workDir = defaultWorkDir
+	}
+	return newRepository(workDir, url, cred, timeout), nil
+}
+
+// getCredential gets the credentials of the username and password inputs, or reads them from the secret
+// provider when the secret input references a secret
+func (i *GitStageProvider) getCredential(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (*credential, error) {
+	ref, ok := inputs["secret"]
+	if !ok || ref == nil || ref == "" {
+		return &credential{
+			Username: stage.ReadInputString(inputs, "username"),
+			Password: stage.ReadInputString(inputs, "password"),
+		}, nil
+	}
+	var secretRef SecretReference
+	data, _ := json.Marshal(ref)
+	if s, ok := ref.(string); ok {
+		data = []byte(s)
+	}
+	if err := json.Unmarshal(data, &secretRef); err != nil || secretRef.Name == "" {
+		return nil, v1alpha2.NewCOAError(err, "secret input must have a name", v1alpha2.BadRequest)
+	}
+	var provider secret.IExtSecretProvider
+	if mgrContext.VencorContext != nil && mgrContext.VencorContext.EvaluationContext != nil {
+		provider = mgrContext.VencorContext.EvaluationContext.SecretProvider
+	}
+	if provider == nil {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret provider is not available to read repository secret %s", secretRef.Name), v1alpha2.MissingConfig)
+	}
+	if secretRef.UsernameField == "" {
+		secretRef.UsernameField = "username"
+	}
+	if secretRef.PasswordField == "" {
+		secretRef.PasswordField = "password"
+	}
+	evaluationContext := utils2.EvaluationContext{
+		Namespace: stage.GetNamespace(inputs),
+		Context:   ctx,
+	}
+	password, err := provider.Get(ctx, secretRef.Name, secretRef.PasswordField, evaluationContext)
+	if err != nil {
+		return nil, err
+	}
+	// A token alone is enough for most Git servers, so the username is optional
+	username, err := provider.Get(ctx, secretRef.Name, secretRef.UsernameField, evaluationContext)
+	if err != nil {
+		username = ""
+	}
+	return &credential{Username: username, Password: password}, nil
+}
+
+// read checks out the ref input and reads the files matching the glob input
+func (i *GitStageProvider) read(ctx context.Context, repo *repository, inputs map[string]interface{}) (map[string]interface{}, error) {
+	outputs := make(map[string]interface{})
+	globs, err := readStringList(inputs, "glob")
+	if err != nil {
+		return outputs, err
+	}
+	if len(globs) == 0 {
+		globs = defaultGlobs
+	}
+	objectType := strings.ToLower(stage.ReadInputString(inputs, "objectType"))
+	if objectType != "" && objectType != "catalog" && objectType != "solution" {
+		return outputs, v1alpha2.NewCOAError(nil, fmt.Sprintf("Unsupported object type: %s", objectType), v1alpha2.BadRequest)
+	}
+
+	unlock := repo.lock()
+	defer unlock()
+	if err = repo.fetch(ctx); err != nil {
+		return outputs, err
+	}
+	ref := stage.ReadInputString(inputs, "ref")
+	if ref == "" {
+		if ref, err = repo.defaultBranch(ctx); err != nil {
+			return outputs, err
+		}
+		if ref == "" {
+			return outputs, v1alpha2.NewCOAError(nil, fmt.Sprintf("repository %s doesn't have a default branch, set the ref input", repo.url), v1alpha2.BadRequest)
+		}
+	}
+	var commit string
+	if commit, err = repo.resolve(ctx, ref); err != nil {
+		return outputs, err
+	}
+	if commit == "" {
+		return outputs, v1alpha2.NewCOAError(nil, fmt.Sprintf("ref %s is not found in repository %s", ref, repo.url), v1alpha2.NotFound)
+	}
+	if err = repo.checkout(ctx, commit); err != nil {
+		return outputs, err
+	}
+	var paths []string
+	if paths, err = repo.listFiles(globs); err != nil {
+		return outputs, err
+	}
+
+	maxInlineSize := getMaxInlineSize(inputs)
+	var size int64
+	files := make(map[string]interface{}, len(paths))
+	documents := make(map[string][]interface{}, len(paths))
+	for _, path := range paths {
+		var data []byte
+		if data, err = os.ReadFile(filepath.Join(repo.dir, filepath.FromSlash(path))); err != nil {
+			return outputs, err
+		}
+		size += int64(len(data))
+		if objectType == "" && size > maxInlineSize {
+			return outputs, v1alpha2.NewCOAError(nil, fmt.Sprintf("files matching %s are larger than %d bytes, narrow the glob", strings.Join(globs, ", "), maxInlineSize), v1alpha2.BadRequest)
+		}
+		var docs []interface{}
+		if docs, err = parseDocuments(path, data); err != nil {
+			return outputs, err
+		}
+		documents[path] = docs
+		if len(docs) == 1 {
+			files[path] = docs[0]
+		} else {
+			files[path] = docs
+		}
+	}
+
+	outputs["commit"] = commit
+	outputs["ref"] = ref
+	outputs["paths"] = toInterfaceList(paths)
+	if objectType == "" {
+		outputs["files"] = files
+		mLog.InfofCtx(ctx, "  P (Git Stage) read %d files from %s at %s", len(paths), repo.url, commit)
+		return outputs, nil
+	}
+
+	objects := []interface{}{}
+	for _, path := range paths {
+		for _, doc := range documents[path] {
+			var name string
+			if objectType == "catalog" {
+				name, err = i.upsertCatalog(ctx, doc, path, repo.url, commit, inputs)
+			} else {
+				name, err = i.upsertSolution(ctx, doc, path, repo.url, commit, inputs)
+			}
+			if err != nil {
+				return outputs, err
+			}
+			objects = append(objects, name)
+		}
+	}
+	outputs["objectType"] = objectType
+	outputs["objects"] = objects
+	mLog.InfofCtx(ctx, "  P (Git Stage) upserted %d %ss from %s at %s", len(objects), objectType, repo.url, commit)
+	return outputs, nil
+}
+
+// push writes the files input and deletes the paths in the delete input on the branch input, then commits and
+// pushes the change. When another writer pushes to the branch first, the change is applied again on top of it.
+func (i *GitStageProvider) push(ctx context.Context, repo *repository, inputs map[string]interface{}) (map[string]interface{}, error) {
+	outputs := make(map[string]interface{})
+	files, ok := inputs["files"].(map[string]interface{})
+	if v, isString := inputs["files"].(string); isString && v != "" {
+		if err := json.Unmarshal([]byte(v), &files); err != nil {
+			return outputs, v1alpha2.NewCOAError(err, "files input must be a map of paths to contents", v1alpha2.BadRequest)
+		}
+		ok = true
+	}
+	deletes, err := readStringList(inputs, "delete")
+	if err != nil {
+		return outputs, err
+	}
+	if (!ok || len(files) == 0) && len(deletes) == 0 {
+		return outputs, v1alpha2.NewCOAError(nil, "push requires the files or delete input", v1alpha2.BadRequest)
+	}
+	contents := make(map[string][]byte, len(files))
+	for path, content := range files {
+		if _, err = repo.workTreePath(path); err != nil {
+			return outputs, err
+		}
+		if contents[path], err = formatContent(path, content); err != nil {
+			return outputs, err
+		}
+	}
+	for _, path := range deletes {
+		if _, err = repo.workTreePath(path); err != nil {
+			return outputs, err
+		}
+	}
+
+	message := stage.ReadInputString(inputs, "message")
+	if message == "" {
+		message = fmt.Sprintf("Update from campaign %s activation %s", stage.ReadInputString(inputs, "__campaign"), stage.ReadInputString(inputs, "__activation"))
+	}
+	authorName := firstNonEmpty(stage.ReadInputString(inputs, "authorName"), i.Config.AuthorName, defaultAuthorName)
+	authorEmail := firstNonEmpty(stage.ReadInputString(inputs, "authorEmail"), i.Config.AuthorEmail, defaultAuthorEmail)
+	identity := []string{
+		"GIT_AUTHOR_NAME=" + authorName,
+		"GIT_AUTHOR_EMAIL=" + authorEmail,
+		"GIT_COMMITTER_NAME=" + authorName,
+		"GIT_COMMITTER_EMAIL=" + authorEmail,
+	}
+
+	unlock := repo.lock()
+	defer unlock()
+	branch := stage.ReadInputString(inputs, "branch")
+	for attempt := 1; ; attempt++ {
+		if err = repo.fetch(ctx); err != nil {
+			return outputs, err
+		}
+		if branch == "" {
+			if branch, err = repo.defaultBranch(ctx); err != nil {
+				return outputs, err
+			}
+			if branch == "" {
+				branch = defaultBranch
+			}
+		}
+		if err = validateRef(branch); err != nil {
+			return outputs, err
+		}
+		// A new branch starts from the default branch, unless the repository is empty
+		var base string
+		if base, err = repo.branchCommit(ctx, branch); err != nil {
+			return outputs, err
+		}
+		if base == "" {
+			var head string
+			if head, err = repo.defaultBranch(ctx); err != nil {
+				return outputs, err
+			}
+			if base, err = repo.branchCommit(ctx, head); err != nil {
+				return outputs, err
+			}
+		}
+		if err = repo.checkout(ctx, base); err != nil {
+			return outputs, err
+		}
+		for path, content := range contents {
+			target, _ := repo.workTreePath(path)
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return outputs, err
+			}
+			if err = os.WriteFile(target, content, 0644); err != nil {
+				return outputs, err
+			}
+		}
+		for _, path := range deletes {
+			target, _ := repo.workTreePath(path)
+			if err = os.RemoveAll(target); err != nil {
+				return outputs, err
+			}
+		}
+		if _, err = repo.git(ctx, nil, "add", "-A"); err != nil {
+			return outputs, err
+		}
+		var status string
+		if status, err = repo.git(ctx, nil, "status", "--porcelain"); err != nil {
+			return outputs, err
+		}
+		outputs["branch"] = branch
+		if status == "" {
+			outputs["commit"] = base
+			outputs["changed"] = false
+			mLog.InfofCtx(ctx, "  P (Git Stage) branch %s of %s is up to date", branch, repo.url)
+			return outputs, nil
+		}
+		if _, err = repo.git(ctx, identity, "commit", "-q", "-m", message); err != nil {
+			return outputs, err
+		}
+		var commit string
+		if commit, err = repo.git(ctx, nil, "rev-parse", "HEAD"); err != nil {
+			return outputs, err
+		}
+		observ_utils.EmitUserAuditsLogs(ctx, "  P (Git Stage): Start to push commit %s to branch %s of %s", commit, branch, repo.url)
+		if _, err = repo.git(ctx, nil, "push", "-q", "origin", "HEAD:refs/heads/"+branch); err != nil {
+			if attempt < defaultPushAttempts && isRejected(err) {
+				mLog.InfofCtx(ctx, "  P (Git Stage) push to branch %s of %s was rejected, retrying: %s", branch, repo.url, err.Error())
+				continue
+			}
+			return outputs, err
+		}
+		outputs["commit"] = commit
+		outputs["changed"] = true
+		mLog.InfofCtx(ctx, "  P (Git Stage) pushed commit %s to branch %s of %s", commit, branch, repo.url)
+		return outputs, nil
+	}
+}
+
+// upsertCatalog writes a catalog read from a file, creating its catalog container first when needed
+func (i *GitStageProvider) upsertCatalog(ctx context.Context, doc interface{}, path string, url string, commit string, inputs map[string]interface{}) (string, error) {
+	var catalog model.CatalogState
+	if err := decodeObject(doc, path, "Catalog", &catalog); err != nil {
+		return "", err
+	}
+	if catalog.Spec == nil {
+		catalog.Spec = &model.CatalogSpec{}
+	}
+	name, rootResource, version, err := getObjectName(catalog.ObjectMeta.Name, catalog.Spec.RootResource, catalog.Spec.Version, path)
+	if err != nil {
+		return "", err
+	}
+	catalog.ObjectMeta.Name = name
+	catalog.ObjectMeta.Namespace = getObjectNamespace(catalog.ObjectMeta.Namespace, inputs)
+	catalog.Spec.RootResource = rootResource
+	catalog.Spec.Version = version
+	if catalog.Spec.CatalogType == "" {
+		catalog.Spec.CatalogType = firstNonEmpty(stage.ReadInputString(inputs, "catalogType"), "config")
+	}
+	annotate(&catalog.ObjectMeta, path, url, commit)
+	namespace := catalog.ObjectMeta.Namespace
+
+	_, err = i.ApiClient.GetCatalogContainer(ctx, rootResource, namespace, i.Config.User, i.Config.Password)
+	if err != nil && api_utils.IsNotFound(err) {
+		mLog.DebugfCtx(ctx, "Catalog container %s doesn't exist: %s", rootResource, err.Error())
+		containerObjectData, _ := json.Marshal(model.CatalogContainerState{ObjectMeta: model.ObjectMeta{Name: rootResource, Namespace: namespace}})
+		if err = i.ApiClient.CreateCatalogContainer(ctx, rootResource, containerObjectData, namespace, i.Config.User, i.Config.Password); err != nil {
+			mLog.ErrorfCtx(ctx, "Failed to create catalog container %s: %s", rootResource, err.Error())
+			return "", err
+		}
+	} else if err != nil {
+		mLog.ErrorfCtx(ctx, "Failed to get catalog container %s: %s", rootResource, err.Error())
+		return "", err
+	}
+	objectData, _ := json.Marshal(catalog)
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Git Stage): Start to upsert catalog %s in namespace %s", name, namespace)
+	return name, i.ApiClient.UpsertCatalog(ctx, name, objectData, i.Config.User, i.Config.Password)
+}
+
+// upsertSolution writes a solution read from a file, creating its solution container first when needed
+func (i *GitStageProvider) upsertSolution(ctx context.Context, doc interface{}, path string, url string, commit string, inputs map[string]interface{}) (string, error) {
+	var solution model.SolutionState
+	if err := decodeObject(doc, path, "Solution", &solution); err != nil {
+		return "", err
+	}
+	if solution.Spec == nil {
+		solution.Spec = &model.SolutionSpec{}
+	}
+	name, rootResource, version, err := getObjectName(solution.ObjectMeta.Name, solution.Spec.RootResource, solution.Spec.Version, path)
+	if err != nil {
+		return "", err
+	}
+	solution.ObjectMeta.Name = name
+	solution.ObjectMeta.Namespace = getObjectNamespace(solution.ObjectMeta.Namespace, inputs)
+	solution.Spec.RootResource = rootResource
+	solution.Spec.Version = version
+	annotate(&solution.ObjectMeta, path, url, commit)
+	namespace := solution.ObjectMeta.Namespace
+
+	_, err = i.ApiClient.GetSolutionContainer(ctx, rootResource, namespace, i.Config.User, i.Config.Password)
+	if err != nil && api_utils.IsNotFound(err) {
+		mLog.DebugfCtx(ctx, "Solution container %s doesn't exist: %s", rootResource, err.Error())
+		containerObjectData, _ := json.Marshal(model.SolutionContainerState{ObjectMeta: model.ObjectMeta{Name: rootResource, Namespace: namespace}})
+		if err = i.ApiClient.CreateSolutionContainer(ctx, rootResource, containerObjectData, namespace, i.Config.User, i.Config.Password); err != nil {
+			mLog.ErrorfCtx(ctx, "Failed to create solution container %s: %s", rootResource, err.Error())
+			return "", err
+		}
+	} else if err != nil {
+		mLog.ErrorfCtx(ctx, "Failed to get solution container %s: %s", rootResource, err.Error())
+		return "", err
+	}
+	objectData, _ := json.Marshal(solution)
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Git Stage): Start to upsert solution %s in namespace %s", name, namespace)
+	return name, i.ApiClient.UpsertSolution(ctx, name, objectData, namespace, i.Config.User, i.Config.Password)
+}
+
+// decodeObject converts a document to a Symphony object, rejecting documents of another kind
+func decodeObject(doc interface{}, path string, kind string, object interface{}) error {
+	if m, ok := doc.(map[string]interface{}); ok {
+		if k, ok := m["kind"].(string); ok && k != "" && !strings.EqualFold(k, kind) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("file %s holds a %s, not a %s", path, k, kind), v1alpha2.BadRequest)
+		}
+	} else {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("file %s doesn't hold a %s object", path, kind), v1alpha2.BadRequest)
+	}
+	data, _ := json.Marshal(doc)
+	if err := json.Unmarshal(data, object); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("file %s doesn't hold a valid %s", path, kind), v1alpha2.BadRequest)
+	}
+	return nil
+}
+
+// getObjectName gets the object name, root resource and version of an object, which can be named as
+// <name>:<version>, as <name>-v-<version>, or with the root resource and version in the spec
+func getObjectName(name string, rootResource string, version string, path string) (string, string, string, error) {
+	if name == "" && rootResource != "" && version != "" {
+		name = rootResource + ":" + version
+	}
+	if name == "" {
+		return "", "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("object in file %s doesn't have a name", path), v1alpha2.BadRequest)
+	}
+	if rootResource == "" || version == "" {
+		rootResource, version = api_utils.GetSolutionAndContainerName(name)
+	}
+	if rootResource == "" || version == "" {
+		return "", "", "", v1alpha2.NewCOAError(nil, fmt.Sprintf("object %s in file %s must be named <name>:<version>", name, path), v1alpha2.BadRequest)
+	}
+	return api_utils.ConvertReferenceToObjectName(name), rootResource, version, nil
+}
+
+func getObjectNamespace(namespace string, inputs map[string]interface{}) string {
+	return firstNonEmpty(namespace, stage.GetNamespace(inputs), "default")
+}
+
+func annotate(meta *model.ObjectMeta, path string, url string, commit string) {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[repositoryAnnotation] = url
+	meta.Annotations[commitAnnotation] = commit
+	meta.Annotations[pathAnnotation] = path
+}
+
+// parseDocuments parses a JSON file, or a YAML file with one or more documents
+func parseDocuments(path string, data []byte) ([]interface{}, error) {
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	docs := []interface{}{}
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("file %s is not valid JSON or YAML", path), v1alpha2.BadRequest)
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// formatContent converts a file content to bytes. Strings are written as they are, other values are written as
+// JSON to .json files and as YAML to other files.
+func formatContent(path string, content interface{}) ([]byte, error) {
+	if s, ok := content.(string); ok {
+		return []byte(s), nil
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("content of %s can't be written as JSON", path), v1alpha2.BadRequest)
+		}
+		return append(data, '\n'), nil
+	}
+	data, err := yaml.Marshal(content)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("content of %s can't be written as YAML", path), v1alpha2.BadRequest)
+	}
+	return data, nil
+}
+
+// isRejected tells if a push failed because the branch moved since it was fetched
+func isRejected(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "rejected") || strings.Contains(message, "non-fast-forward") || strings.Contains(message, "fetch first")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func toInterfaceList(values []string) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func readStringList(inputs map[string]interface{}, key string) ([]string, error) {
+	v, ok := inputs[key]
+	if !ok || v == nil || v == "" {
+		return nil, nil
+	}
+	switch tv := v.(type) {
+	case []string:
+		return tv, nil
+	case []interface{}:
+		ret := make([]string, 0, len(tv))
+		for _, item := range tv {
+			ret = append(ret, utils2.FormatAsString(item))
+		}
+		return ret, nil
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(tv), "[") {
+			var ret []interface{}
+			if err := json.Unmarshal([]byte(tv), &ret); err != nil {
+				return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("%s is not a valid list", key), v1alpha2.BadRequest)
+			}
+			return readStringList(map[string]interface{}{key: ret}, key)
+		}
+		return []string{tv}, nil
+	default:
+		return []string{utils2.FormatAsString(tv)}, nil
+	}
+}
+
+func getMaxInlineSize(inputs map[string]interface{}) int64 {
+	switch v := inputs["maxInlineSize"].(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case string:
+		if size, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return size
+		}
+	}
+	return defaultMaxInlineSize
+}
@@ -0,0 +1,463 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthResponse struct {
+	AccessToken string   `json:"accessToken"`
+	TokenType   string   `json:"tokenType"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+}
+
+type testSecretProvider struct {
+	secrets map[string]map[string]string
+}
+
+func (t *testSecretProvider) Get(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	if v, ok := t.secrets[name][field]; ok {
+		return v, nil
+	}
+	return "", v1alpha2.NewCOAError(nil, "secret is not found", v1alpha2.NotFound)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@contoso.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@contoso.com")
+	out, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// newTestRepository creates a bare repository whose main branch holds the given files, and returns its path
+// and the path of a work tree cloned from it
+func newTestRepository(t *testing.T, files map[string]string) (string, string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	runGit(t, root, "init", "-q", "--bare", "--initial-branch=main", remote)
+	local := filepath.Join(root, "local")
+	runGit(t, root, "clone", "-q", remote, local)
+	runGit(t, local, "checkout", "-q", "-b", "main")
+	if len(files) > 0 {
+		commitFiles(t, local, files, "initial commit")
+	}
+	return remote, local
+}
+
+func commitFiles(t *testing.T, local string, files map[string]string, message string) string {
+	for path, content := range files {
+		target := filepath.Join(local, filepath.FromSlash(path))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(target), 0755))
+		assert.Nil(t, os.WriteFile(target, []byte(content), 0644))
+	}
+	runGit(t, local, "add", "-A")
+	runGit(t, local, "commit", "-q", "-m", message)
+	runGit(t, local, "push", "-q", "origin", "HEAD:refs/heads/main")
+	return runGit(t, local, "rev-parse", "HEAD")
+}
+
+// initializeMockSymphonyAPI records the catalogs and solutions upserted through the Symphony API
+func initializeMockSymphonyAPI(t *testing.T, objects map[string]map[string]interface{}) *httptest.Server {
+	var lock sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		var response interface{}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/catalogcontainers/"), strings.HasPrefix(r.URL.Path, "/solutioncontainers/"):
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case strings.HasPrefix(r.URL.Path, "/catalogs/registry/"), strings.HasPrefix(r.URL.Path, "/solutions/"):
+			body, _ := io.ReadAll(r.Body)
+			var object map[string]interface{}
+			json.Unmarshal(body, &object)
+			objects[r.URL.Path] = object
+		default:
+			response = AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+				Username:    "test-user",
+				Roles:       []string{"role1", "role2"},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestProvider(t *testing.T, objects map[string]map[string]interface{}) *GitStageProvider {
+	ts := initializeMockSymphonyAPI(t, objects)
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	provider := &GitStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+		"workDir":  t.TempDir(),
+	})
+	assert.Nil(t, err)
+	return provider
+}
+
+func TestGitInitFromVendorMap(t *testing.T) {
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	config, err := GitStageProviderConfigFromMap(map[string]string{
+		"user":        "admin",
+		"password":    "",
+		"workDir":     "/tmp/git",
+		"timeout":     "1m",
+		"authorName":  "Rollout Bot",
+		"authorEmail": "rollout@contoso.com",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp/git", config.WorkDir)
+	assert.Equal(t, "Rollout Bot", config.AuthorName)
+
+	provider := &GitStageProvider{}
+	err = provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+		"timeout":  "soon",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+}
+
+func TestGitReadFiles(t *testing.T) {
+	remote, _ := newTestRepository(t, map[string]string{
+		"apps/web/config.yaml":    "replicas: 3\nimage: web:1.0\n",
+		"apps/web/settings.json":  `{"level": "debug"}`,
+		"apps/db/manifests.yaml":  "name: a\n---\nname: b\n",
+		"README.md":               "not desired state",
+		"apps/web/notes/todo.txt": "ignored",
+	})
+	provider := newTestProvider(t, nil)
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+	assert.Equal(t, "main", outputs["ref"])
+	assert.Len(t, outputs["commit"], 40)
+	assert.Equal(t, []interface{}{"apps/db/manifests.yaml", "apps/web/config.yaml", "apps/web/settings.json"}, outputs["paths"])
+	files := outputs["files"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"replicas": float64(3), "image": "web:1.0"}, files["apps/web/config.yaml"])
+	assert.Equal(t, map[string]interface{}{"level": "debug"}, files["apps/web/settings.json"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}}, files["apps/db/manifests.yaml"])
+
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"glob":       `["apps/web/*.yaml"]`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"apps/web/config.yaml"}, outputs["paths"])
+}
+
+func TestGitReadRef(t *testing.T) {
+	remote, local := newTestRepository(t, map[string]string{
+		"config.yaml": "version: 1\n",
+	})
+	runGit(t, local, "tag", "v1")
+	runGit(t, local, "push", "-q", "origin", "v1")
+	latest := commitFiles(t, local, map[string]string{"config.yaml": "version: 2\n"}, "version 2")
+	provider := newTestProvider(t, nil)
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"ref":        "v1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), outputs["files"].(map[string]interface{})["config.yaml"].(map[string]interface{})["version"])
+
+	// the work tree is reused and fetches the new commit
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"ref":        "main",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, latest, outputs["commit"])
+	assert.Equal(t, float64(2), outputs["files"].(map[string]interface{})["config.yaml"].(map[string]interface{})["version"])
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"ref":        "v9",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+}
+
+func TestGitReadCatalogs(t *testing.T) {
+	remote, _ := newTestRepository(t, map[string]string{
+		"catalogs/web.yaml": `
+apiVersion: federation.symphony/v1
+kind: Catalog
+metadata:
+  name: web-config:v1
+spec:
+  properties:
+    replicas: 3
+---
+metadata:
+  name: db-config-v-v2
+  namespace: data
+spec:
+  catalogType: schema
+  properties:
+    size: small
+`,
+	})
+	objects := map[string]map[string]interface{}{}
+	provider := newTestProvider(t, objects)
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"glob":       "catalogs/**",
+		"objectType": "catalog",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"web-config-v-v1", "db-config-v-v2"}, outputs["objects"])
+	assert.Nil(t, outputs["files"])
+
+	web := objects["/catalogs/registry/web-config-v-v1"]
+	assert.NotNil(t, web)
+	spec := web["spec"].(map[string]interface{})
+	assert.Equal(t, "config", spec["catalogType"])
+	assert.Equal(t, "web-config", spec["rootResource"])
+	assert.Equal(t, "v1", spec["version"])
+	metadata := web["metadata"].(map[string]interface{})
+	assert.Equal(t, "default", metadata["namespace"])
+	annotations := metadata["annotations"].(map[string]interface{})
+	assert.Equal(t, remote, annotations[repositoryAnnotation])
+	assert.Equal(t, "catalogs/web.yaml", annotations[pathAnnotation])
+	assert.Equal(t, outputs["commit"], annotations[commitAnnotation])
+
+	db := objects["/catalogs/registry/db-config-v-v2"]
+	assert.Equal(t, "data", db["metadata"].(map[string]interface{})["namespace"])
+	assert.Equal(t, "schema", db["spec"].(map[string]interface{})["catalogType"])
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": remote,
+		"objectType": "solution",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "holds a Catalog, not a Solution")
+}
+
+func TestGitReadSolutions(t *testing.T) {
+	remote, _ := newTestRepository(t, map[string]string{
+		"solutions/web.json": `{"metadata": {"name": "web:v3"}, "spec": {"components": [{"name": "web", "type": "container"}]}}`,
+	})
+	objects := map[string]map[string]interface{}{}
+	provider := newTestProvider(t, objects)
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository":  remote,
+		"objectType":  "solution",
+		"__namespace": "apps",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"web-v-v3"}, outputs["objects"])
+	solution := objects["/solutions/web-v-v3"]
+	assert.NotNil(t, solution)
+	assert.Equal(t, "apps", solution["metadata"].(map[string]interface{})["namespace"])
+	assert.Equal(t, "web", solution["spec"].(map[string]interface{})["rootResource"])
+}
+
+func TestGitPush(t *testing.T) {
+	remote, local := newTestRepository(t, map[string]string{
+		"status/old.json": "{}",
+	})
+	provider := newTestProvider(t, nil)
+	provider.Config.AuthorName = "Rollout Bot"
+
+	inputs := map[string]interface{}{
+		"action":       "push",
+		"repository":   remote,
+		"__campaign":   "rollout",
+		"__activation": "rollout-1",
+		"files": map[string]interface{}{
+			"status/web.json":   map[string]interface{}{"status": "deployed", "revision": 4},
+			"status/notes.yaml": map[string]interface{}{"owner": "web-team"},
+			"status/raw.txt":    "rolled out",
+		},
+		"delete": []interface{}{"status/old.json"},
+	}
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.Nil(t, err)
+	assert.Equal(t, true, outputs["changed"])
+	assert.Equal(t, "main", outputs["branch"])
+
+	runGit(t, local, "pull", "-q", "origin", "main")
+	assert.Equal(t, outputs["commit"], runGit(t, local, "rev-parse", "HEAD"))
+	assert.Equal(t, "Rollout Bot|Update from campaign rollout activation rollout-1", runGit(t, local, "log", "-1", "--format=%an|%s"))
+	data, _ := os.ReadFile(filepath.Join(local, "status", "web.json"))
+	assert.JSONEq(t, `{"status": "deployed", "revision": 4}`, string(data))
+	data, _ = os.ReadFile(filepath.Join(local, "status", "notes.yaml"))
+	assert.Equal(t, "owner: web-team\n", string(data))
+	_, err = os.Stat(filepath.Join(local, "status", "old.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	// pushing the same content again doesn't create a commit
+	unchanged, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.Nil(t, err)
+	assert.Equal(t, false, unchanged["changed"])
+	assert.Equal(t, outputs["commit"], unchanged["commit"])
+}
+
+func TestGitPushRebasesOnNewCommits(t *testing.T) {
+	remote, local := newTestRepository(t, map[string]string{
+		"a.txt": "a",
+	})
+	provider := newTestProvider(t, nil)
+	inputs := map[string]interface{}{
+		"action":     "push",
+		"repository": remote,
+		"branch":     "main",
+		"files":      map[string]interface{}{"b.txt": "b"},
+	}
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.Nil(t, err)
+
+	// another writer moves the branch, the next push starts from the new commit
+	runGit(t, local, "pull", "-q", "origin", "main")
+	commitFiles(t, local, map[string]string{"c.txt": "c"}, "another writer")
+	inputs["files"] = map[string]interface{}{"b.txt": "b2"}
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.Nil(t, err)
+	runGit(t, local, "pull", "-q", "origin", "main")
+	for path, content := range map[string]string{"a.txt": "a", "b.txt": "b2", "c.txt": "c"} {
+		data, _ := os.ReadFile(filepath.Join(local, path))
+		assert.Equal(t, content, string(data))
+	}
+}
+
+func TestGitPushToEmptyRepository(t *testing.T) {
+	remote, local := newTestRepository(t, nil)
+	provider := newTestProvider(t, nil)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"action":     "push",
+		"repository": remote,
+		"branch":     "results",
+		"files":      `{"result.txt": "done"}`,
+		"message":    "Record results",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, outputs["changed"])
+	runGit(t, local, "fetch", "-q", "origin")
+	assert.Equal(t, "done", runGit(t, local, "show", "origin/results:result.txt"))
+}
+
+func TestGitInvalidInputs(t *testing.T) {
+	remote, _ := newTestRepository(t, map[string]string{"a.yaml": "a: 1"})
+	provider := newTestProvider(t, nil)
+	for _, inputs := range []map[string]interface{}{
+		{},
+		{"repository": "--upload-pack=touch /tmp/pwned"},
+		{"repository": remote, "ref": "--output=/tmp/x"},
+		{"repository": remote, "action": "merge"},
+		{"repository": remote, "objectType": "target"},
+		{"repository": remote, "action": "push"},
+		{"repository": remote, "action": "push", "files": map[string]interface{}{"../escape.txt": "x"}},
+		{"repository": remote, "action": "push", "files": map[string]interface{}{".git/config": "x"}},
+		{"repository": remote, "action": "push", "delete": "/etc/passwd"},
+	} {
+		_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+		assert.NotNil(t, err, inputs)
+		assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err), inputs)
+	}
+
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"repository": "ext::sh -c touch% /tmp/pwned",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.GitOperationFailed, v1alpha2.GetErrorState(err))
+}
+
+func TestGitCredentialFromSecret(t *testing.T) {
+	provider := &GitStageProvider{}
+	mgrContext := contexts.ManagerContext{
+		VencorContext: &contexts.VendorContext{
+			EvaluationContext: &coa_utils.EvaluationContext{
+				SecretProvider: &testSecretProvider{secrets: map[string]map[string]string{
+					"git-token": {"token": "s3cret"},
+				}},
+			},
+		},
+	}
+	cred, err := provider.getCredential(context.Background(), mgrContext, map[string]interface{}{
+		"secret": map[string]interface{}{"name": "git-token", "passwordField": "token"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cred.Username)
+	assert.Equal(t, "s3cret", cred.Password)
+
+	repo := newRepository(t.TempDir(), "https://git.contoso.com/config.git", cred, defaultTimeout)
+	header := base64.StdEncoding.EncodeToString([]byte("git:s3cret"))
+	assert.Contains(t, repo.env, "GIT_CONFIG_VALUE_0=Authorization: Basic "+header)
+
+	_, err = provider.getCredential(context.Background(), mgrContext, map[string]interface{}{
+		"secret": map[string]interface{}{"name": "missing"},
+	})
+	assert.NotNil(t, err)
+	_, err = provider.getCredential(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"secret": map[string]interface{}{"name": "git-token"},
+	})
+	assert.Equal(t, v1alpha2.MissingConfig, v1alpha2.GetErrorState(err))
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.yaml", "a.yaml", true},
+		{"*.yaml", "dir/a.yaml", false},
+		{"**/*.yaml", "a.yaml", true},
+		{"**/*.yaml", "dir/sub/a.yaml", true},
+		{"apps/**", "apps/web/a.json", true},
+		{"apps/*/config.yaml", "apps/web/config.yaml", true},
+		{"apps/*/config.yaml", "apps/web/v1/config.yaml", false},
+		{"a?.json", "ab.json", true},
+		{"a.json", "aXjson", false},
+	} {
+		r, err := globToRegexp(c.pattern)
+		assert.Nil(t, err)
+		assert.Equal(t, c.match, r.MatchString(c.path), c.pattern+" "+c.path)
+	}
+}
@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+const (
+	// allowedProtocols keeps git from running helpers such as ext:: named in a repository URL
+	allowedProtocols = "file:git:http:https:ssh"
+	defaultUsername  = "git"
+	orphanBranch     = "refs/heads/symphony-orphan"
+)
+
+// workTreeLocks serializes git commands on a work tree, which is shared by all stages using the same repository
+var workTreeLocks sync.Map
+
+// credential is sent to HTTP(S) remotes with basic authentication
+type credential struct {
+	Username string
+	Password string
+}
+
+// repository is a work tree of a remote repository in the work directory of the provider
+type repository struct {
+	url     string
+	dir     string
+	timeout time.Duration
+	env     []string
+}
+
+func newRepository(workDir string, url string, cred *credential, timeout time.Duration) *repository {
+	hash := sha256.Sum256([]byte(url))
+	env := []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ALLOW_PROTOCOL=" + allowedProtocols,
+	}
+	// Credentials are passed as configuration in the environment, so they are neither on the command line nor
+	// stored in the configuration of the work tree
+	if cred != nil && (cred.Username != "" || cred.Password != "") {
+		username := cred.Username
+		if username == "" {
+			username = defaultUsername
+		}
+		token := base64.StdEncoding.EncodeToString([]byte(username + ":" + cred.Password))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+token,
+		)
+	}
+	return &repository{
+		url:     url,
+		dir:     filepath.Join(workDir, hex.EncodeToString(hash[:8])),
+		timeout: timeout,
+		env:     env,
+	}
+}
+
+// lock locks the work tree until the returned function is called
+func (r *repository) lock() func() {
+	l, _ := workTreeLocks.LoadOrStore(r.dir, &sync.Mutex{})
+	mutex := l.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+func (r *repository) git(ctx context.Context, extraEnv []string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(append(os.Environ(), r.env...), extraEnv...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", v1alpha2.NewCOAError(err, fmt.Sprintf("git %s timed out after %s", args[0], r.timeout), v1alpha2.TimedOut)
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("git %s failed: %s", args[0], message), v1alpha2.GitOperationFailed)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// fetch creates the work tree on first use and fetches the branches and tags of the remote
+func (r *repository) fetch(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(r.dir, 0755); err != nil {
+			return err
+		}
+		if _, err := r.git(ctx, nil, "init", "-q"); err != nil {
+			return err
+		}
+		if _, err := r.git(ctx, nil, "remote", "add", "origin", r.url); err != nil {
+			return err
+		}
+	}
+	_, err := r.git(ctx, nil, "fetch", "-q", "--prune", "--force", "origin", "+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*")
+	return err
+}
+
+// defaultBranch gets the branch HEAD of the remote points to, or an empty string when the remote doesn't say
+func (r *repository) defaultBranch(ctx context.Context) (string, error) {
+	out, err := r.git(ctx, nil, "ls-remote", "--symref", "origin", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "ref: refs/heads/") {
+			return strings.TrimSpace(strings.Fields(strings.TrimPrefix(line, "ref: refs/heads/"))[0]), nil
+		}
+	}
+	return "", nil
+}
+
+// resolve gets the commit of a branch, tag or commit id, or an empty string when the ref doesn't exist
+func (r *repository) resolve(ctx context.Context, ref string) (string, error) {
+	if err := validateRef(ref); err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref} {
+		if commit, err := r.git(ctx, nil, "rev-parse", "--verify", "--quiet", candidate+"^{commit}"); err == nil && commit != "" {
+			return commit, nil
+		}
+	}
+	return "", nil
+}
+
+// branchCommit gets the commit of a branch of the remote, or an empty string when the branch doesn't exist
+func (r *repository) branchCommit(ctx context.Context, branch string) (string, error) {
+	if branch == "" {
+		return "", nil
+	}
+	if err := validateRef(branch); err != nil {
+		return "", err
+	}
+	commit, err := r.git(ctx, nil, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch+"^{commit}")
+	if err != nil {
+		return "", nil
+	}
+	return commit, nil
+}
+
+// checkout resets the work tree to a commit, or to an empty tree when the commit is empty
+func (r *repository) checkout(ctx context.Context, commit string) error {
+	if commit == "" {
+		if _, err := r.git(ctx, nil, "symbolic-ref", "HEAD", orphanBranch); err != nil {
+			return err
+		}
+		// A commit left by a failed push to an empty repository is dropped
+		r.git(ctx, nil, "update-ref", "-d", orphanBranch)
+		if _, err := r.git(ctx, nil, "read-tree", "--empty"); err != nil {
+			return err
+		}
+	} else if _, err := r.git(ctx, nil, "checkout", "-q", "--force", "--detach", commit); err != nil {
+		return err
+	}
+	_, err := r.git(ctx, nil, "clean", "-q", "-ffdx")
+	return err
+}
+
+// validateRef rejects refs that git could take as options or revision expressions
+func validateRef(ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n~^:?*[\\") || strings.Contains(ref, "..") {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("%q is not a valid git ref", ref), v1alpha2.BadRequest)
+	}
+	return nil
+}
+
+// workTreePath converts a slash separated path relative to the repository root to a path in the work tree,
+// rejecting paths that leave the work tree or point into the .git directory
+func (r *repository) workTreePath(path string) (string, error) {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	if path == "" || filepath.IsAbs(filepath.FromSlash(path)) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") ||
+		clean == ".git" || strings.HasPrefix(clean, ".git/") {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("%q is not a valid file path in the repository", path), v1alpha2.BadRequest)
+	}
+	return filepath.Join(r.dir, filepath.FromSlash(clean)), nil
+}
+
+// listFiles gets the paths of the files in the work tree matching any of the glob patterns, in lexical order
+func (r *repository) listFiles(patterns []string) ([]string, error) {
+	matchers := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		matcher, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	ret := []string{}
+	err := filepath.WalkDir(r.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, matcher := range matchers {
+			if matcher.MatchString(rel) {
+				ret = append(ret, rel)
+				break
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// globToRegexp converts a glob pattern to a regular expression matching slash separated paths. "*" and "?"
+// don't match "/", while "**" matches any number of directories.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, v1alpha2.NewCOAError(nil, "glob pattern can't be empty", v1alpha2.BadRequest)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,765 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	loggerName   = "providers.stage.job"
+	providerName = "P (Job Stage)"
+	jobProvider  = "job"
+
+	defaultNamespace     = "default"
+	defaultTimeout       = 30 * time.Minute
+	defaultPollInterval  = 2 * time.Second
+	defaultLogLines      = 100
+	defaultTTL           = 3600
+	defaultContainerName = "main"
+	// maxLogBytes limits the logs copied into the outputs
+	maxLogBytes  = 64 * 1024
+	jobNameLabel = "job-name"
+)
+
+var (
+	msLock                   sync.Mutex
+	log                      = logger.NewLogger(loggerName)
+	once                     sync.Once
+	providerOperationMetrics *metrics.Metrics
+	invalidNameChars         = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+type JobStageProviderConfig struct {
+	ConfigType string `json:"configType,omitempty"`
+	ConfigData string `json:"configData,omitempty"`
+	InCluster  bool   `json:"inCluster"`
+	// Namespace of the jobs that don't name one, "default" by default
+	Namespace string `json:"namespace,omitempty"`
+	// Timeout is how long a job may run, such as 10m. It defaults to 30m.
+	Timeout string `json:"timeout,omitempty"`
+	// PollInterval is how often the status of a job is checked, it defaults to 2s
+	PollInterval string `json:"pollInterval,omitempty"`
+	// TTLSecondsAfterFinished is how long a finished job is kept, it defaults to an hour. 0 deletes it right away.
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	// LogLines is the number of log lines copied into the outputs, it defaults to 100
+	LogLines int `json:"logLines,omitempty"`
+}
+
+// JobStageProvider runs a container to completion as a Kubernetes Job
+type JobStageProvider struct {
+	Config  JobStageProviderConfig
+	Context *contexts.ManagerContext
+	Client  kubernetes.Interface
+}
+
+// jobRequest is a job to run, built from the stage inputs
+type jobRequest struct {
+	job              *batchv1.Job
+	container        string
+	timeout          time.Duration
+	ttl              int32
+	logLines         int64
+	successExitCodes map[int32]bool
+}
+
+// jobResult is the outcome of a finished job
+type jobResult struct {
+	succeeded bool
+	timedOut  bool
+	pod       string
+	exitCode  *int32
+	reason    string
+	message   string
+	logs      string
+}
+
+func (s *JobStageProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("[Stage] Job Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	msLock.Lock()
+	defer msLock.Unlock()
+	var jobConfig JobStageProviderConfig
+	jobConfig, err = toJobStageProviderConfig(config)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): expected JobStageProviderConfig: %+v", err)
+		return err
+	}
+	err = validateConfig(jobConfig)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): invalid config: %+v", err)
+		return err
+	}
+	s.Config = jobConfig
+
+	var kConfig *rest.Config
+	if s.Config.InCluster {
+		kConfig, err = rest.InClusterConfig()
+	} else {
+		switch s.Config.ConfigType {
+		case "path":
+			if s.Config.ConfigData == "" {
+				if home := homedir.HomeDir(); home != "" {
+					s.Config.ConfigData = filepath.Join(home, ".kube", "config")
+				} else {
+					err = v1alpha2.NewCOAError(nil, "can't locate home direction to read default kubernetes config file, to run in cluster, set inCluster config setting to true", v1alpha2.BadConfig)
+					log.ErrorfCtx(ctx, "  P (Job Stage): %+v", err)
+					return err
+				}
+			}
+			kConfig, err = clientcmd.BuildConfigFromFlags("", s.Config.ConfigData)
+		case "inline":
+			if s.Config.ConfigData != "" {
+				kConfig, err = clientcmd.RESTConfigFromKubeConfig([]byte(s.Config.ConfigData))
+			} else {
+				err = v1alpha2.NewCOAError(nil, "config data is not supplied", v1alpha2.BadConfig)
+			}
+		default:
+			err = v1alpha2.NewCOAError(nil, "unrecognized config type, accepted values are: path and inline", v1alpha2.BadConfig)
+		}
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): failed to get the cluster config: %+v", err)
+		return err
+	}
+	s.Client, err = kubernetes.NewForConfig(kConfig)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): failed to create a new clientset: %+v", err)
+		return err
+	}
+
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				log.ErrorfCtx(ctx, "  P (Job Stage): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	return err
+}
+func (s *JobStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toJobStageProviderConfig(config providers.IProviderConfig) (JobStageProviderConfig, error) {
+	ret := JobStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *JobStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := JobStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func JobStageProviderConfigFromMap(properties map[string]string) (JobStageProviderConfig, error) {
+	ret := JobStageProviderConfig{}
+	ret.ConfigType = properties["configType"]
+	ret.ConfigData = properties["configData"]
+	ret.Namespace = properties["namespace"]
+	ret.Timeout = properties["timeout"]
+	ret.PollInterval = properties["pollInterval"]
+	if v, ok := properties["inCluster"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'inCluster' setting of job provider", v1alpha2.BadConfig)
+		}
+		ret.InCluster = bVal
+	}
+	if v, ok := properties["ttlSecondsAfterFinished"]; ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse ttlSecondsAfterFinished %v", v), v1alpha2.BadConfig)
+		}
+		ttl := int32(n)
+		ret.TTLSecondsAfterFinished = &ttl
+	}
+	if v, ok := properties["logLines"]; ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse logLines %v", v), v1alpha2.BadConfig)
+		}
+		ret.LogLines = n
+	}
+	return ret, nil
+}
+
+func validateConfig(config JobStageProviderConfig) error {
+	for key, v := range map[string]string{"timeout": config.Timeout, "pollInterval": config.PollInterval} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("%s %s is not a positive duration", key, v), v1alpha2.BadConfig)
+		}
+	}
+	if config.TTLSecondsAfterFinished != nil && *config.TTLSecondsAfterFinished < 0 {
+		return v1alpha2.NewCOAError(nil, "ttlSecondsAfterFinished can't be negative", v1alpha2.BadConfig)
+	}
+	if config.LogLines < 0 {
+		return v1alpha2.NewCOAError(nil, "logLines can't be negative", v1alpha2.BadConfig)
+	}
+	return nil
+}
+
+func (i *JobStageProvider) pollInterval() time.Duration {
+	if d, err := time.ParseDuration(i.Config.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return defaultPollInterval
+}
+
+// Process creates a job from the inputs, waits for it to finish and gets its exit code, termination message
+// and logs. A job that doesn't finish within its timeout is deleted and fails the stage as TimedOut.
+func (i *JobStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Job Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	processTime := time.Now().UTC()
+	functionName := observ_utils.GetFunctionName()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		processTime,
+		jobProvider,
+		metrics.ProcessOperation,
+		metrics.RunOperationType,
+		functionName,
+	)
+
+	var request jobRequest
+	request, err = i.buildJob(inputs)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): invalid job: %+v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			jobProvider,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.ValidateOperationType,
+			v1alpha2.BadConfig.String(),
+		)
+		return nil, false, err
+	}
+	namespace := request.job.Namespace
+	name := request.job.Name
+
+	log.InfofCtx(ctx, "  P (Job Stage): creating job %s in namespace %s", name, namespace)
+	_, err = i.Client.BatchV1().Jobs(namespace).Create(ctx, request.job, metav1.CreateOptions{})
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): failed to create job %s: %+v", name, err)
+		err = v1alpha2.NewCOAError(err, fmt.Sprintf("failed to create job %s", name), v1alpha2.InternalError)
+		providerOperationMetrics.ProviderOperationErrors(
+			jobProvider,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.InternalError.String(),
+		)
+		return nil, false, err
+	}
+
+	var result jobResult
+	result, err = i.waitForJob(ctx, request)
+	outputs := map[string]interface{}{
+		"jobName":   name,
+		"namespace": namespace,
+	}
+	if err != nil || result.timedOut || request.ttl == 0 {
+		// Jobs that are cancelled or time out are stopped, jobs without a TTL are deleted once they are finished
+		i.deleteJob(ctx, namespace, name)
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): failed to wait for job %s: %+v", name, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			jobProvider,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return outputs, false, err
+	}
+
+	for k, v := range result.outputs() {
+		outputs[k] = v
+	}
+	switch {
+	case result.timedOut:
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("job %s didn't finish in %s", name, request.timeout), v1alpha2.TimedOut)
+	case !result.succeeded:
+		msg := fmt.Sprintf("job %s failed", name)
+		if result.exitCode != nil {
+			msg = fmt.Sprintf("%s with exit code %d", msg, *result.exitCode)
+		}
+		if result.reason != "" {
+			msg = fmt.Sprintf("%s: %s", msg, result.reason)
+		}
+		err = v1alpha2.NewCOAError(nil, msg, v1alpha2.InternalError)
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Job Stage): %+v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			jobProvider,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return outputs, false, err
+	}
+	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+	log.InfofCtx(ctx, "  P (Job Stage): job %s succeeded", name)
+	return outputs, false, nil
+}
+
+// outputs gets the outputs of a finished job. A termination message that is a JSON object is merged into the
+// outputs, other messages are returned as the message output.
+func (r jobResult) outputs() map[string]interface{} {
+	ret := map[string]interface{}{
+		"logs": r.logs,
+	}
+	if r.pod != "" {
+		ret["pod"] = r.pod
+	}
+	if r.exitCode != nil {
+		ret["exitCode"] = int(*r.exitCode)
+	}
+	if r.reason != "" {
+		ret["reason"] = r.reason
+	}
+	if r.message == "" {
+		return ret
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(r.message), &fields); err != nil {
+		ret["message"] = r.message
+		return ret
+	}
+	for k, v := range fields {
+		if _, ok := ret[k]; !ok {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// buildJob builds the job to create from the "job" input, which is a complete Job manifest, or from the image,
+// command, args and env inputs
+func (i *JobStageProvider) buildJob(inputs map[string]interface{}) (jobRequest, error) {
+	request := jobRequest{
+		container:        readString(inputs, "container"),
+		successExitCodes: map[int32]bool{0: true},
+	}
+	job := &batchv1.Job{}
+	if v, ok := inputs["job"]; ok && v != nil && v != "" {
+		var data []byte
+		var err error
+		if s, ok := v.(string); ok {
+			data, err = yaml.YAMLToJSON([]byte(s))
+		} else {
+			data, err = json.Marshal(v)
+		}
+		if err == nil {
+			err = json.Unmarshal(data, job)
+		}
+		if err != nil {
+			return request, v1alpha2.NewCOAError(err, "job is not a valid Job manifest", v1alpha2.BadConfig)
+		}
+		if len(job.Spec.Template.Spec.Containers) == 0 {
+			return request, v1alpha2.NewCOAError(nil, "job doesn't have a container", v1alpha2.BadConfig)
+		}
+	} else {
+		image := readString(inputs, "image")
+		if image == "" {
+			return request, v1alpha2.NewCOAError(nil, "image is required when job isn't supplied", v1alpha2.BadConfig)
+		}
+		container := corev1.Container{
+			Name:  defaultContainerName,
+			Image: image,
+		}
+		var err error
+		if container.Command, err = readStringList(inputs, "command"); err != nil {
+			return request, err
+		}
+		if container.Args, err = readStringList(inputs, "args"); err != nil {
+			return request, err
+		}
+		if env, ok := inputs["env"].(map[string]interface{}); ok {
+			names := make([]string, 0, len(env))
+			for name := range env {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: utils2.FormatAsString(env[name])})
+			}
+		}
+		job.Spec.Template.Spec.Containers = []corev1.Container{container}
+		job.Spec.Template.Spec.ServiceAccountName = readString(inputs, "serviceAccount")
+	}
+	if job.Spec.Template.Spec.RestartPolicy == "" {
+		job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+
+	if name := readString(inputs, "name"); name != "" {
+		job.Name = name
+	}
+	if job.Name == "" {
+		job.Name = generateJobName(readString(inputs, "__stage"), readString(inputs, "__activation"))
+	}
+	if namespace := readString(inputs, "namespace"); namespace != "" {
+		job.Namespace = namespace
+	}
+	if job.Namespace == "" {
+		job.Namespace = i.Config.Namespace
+	}
+	if job.Namespace == "" {
+		job.Namespace = defaultNamespace
+	}
+	if job.Labels == nil {
+		job.Labels = make(map[string]string)
+	}
+	for label, input := range map[string]string{"symphony/campaign": "__campaign", "symphony/activation": "__activation", "symphony/stage": "__stage"} {
+		if v := labelValue(readString(inputs, input)); v != "" {
+			job.Labels[label] = v
+		}
+	}
+
+	// The container that reports the result is the first one unless it's named
+	index := 0
+	if request.container != "" {
+		index = -1
+		for n, c := range job.Spec.Template.Spec.Containers {
+			if c.Name == request.container {
+				index = n
+			}
+		}
+		if index < 0 {
+			return request, v1alpha2.NewCOAError(nil, fmt.Sprintf("job doesn't have a container %s", request.container), v1alpha2.BadConfig)
+		}
+	}
+	request.container = job.Spec.Template.Spec.Containers[index].Name
+	// Kubernetes reads the termination message from the output file once the container exits
+	if outputFile := readString(inputs, "outputFile"); outputFile != "" {
+		job.Spec.Template.Spec.Containers[index].TerminationMessagePath = outputFile
+	}
+	if job.Spec.Template.Spec.Containers[index].TerminationMessagePolicy == "" {
+		job.Spec.Template.Spec.Containers[index].TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	}
+
+	backoffLimit, ok, err := readInt(inputs, "backoffLimit")
+	if err != nil {
+		return request, err
+	}
+	if ok {
+		limit := int32(backoffLimit)
+		job.Spec.BackoffLimit = &limit
+	} else if job.Spec.BackoffLimit == nil {
+		var limit int32 = 0
+		job.Spec.BackoffLimit = &limit
+	}
+
+	request.timeout = defaultTimeout
+	timeout := readString(inputs, "timeout")
+	if timeout == "" {
+		timeout = i.Config.Timeout
+	}
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return request, v1alpha2.NewCOAError(err, fmt.Sprintf("timeout %s is not a positive duration", timeout), v1alpha2.BadConfig)
+		}
+		request.timeout = d
+	}
+	// Kubernetes stops the job at the deadline as well, in case the provider isn't waiting for it anymore
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		deadline := int64(math.Ceil(request.timeout.Seconds()))
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+
+	request.ttl = defaultTTL
+	if i.Config.TTLSecondsAfterFinished != nil {
+		request.ttl = *i.Config.TTLSecondsAfterFinished
+	}
+	ttl, ok, err := readInt(inputs, "ttlSecondsAfterFinished")
+	if err != nil {
+		return request, err
+	}
+	if ok {
+		if ttl < 0 {
+			return request, v1alpha2.NewCOAError(nil, "ttlSecondsAfterFinished can't be negative", v1alpha2.BadConfig)
+		}
+		request.ttl = int32(ttl)
+	}
+	// A TTL of 0 is applied by the provider, so that the job isn't deleted before its results are read
+	if request.ttl > 0 && job.Spec.TTLSecondsAfterFinished == nil {
+		job.Spec.TTLSecondsAfterFinished = &request.ttl
+	}
+
+	request.logLines = defaultLogLines
+	if i.Config.LogLines > 0 {
+		request.logLines = int64(i.Config.LogLines)
+	}
+	logLines, ok, err := readInt(inputs, "logLines")
+	if err != nil {
+		return request, err
+	}
+	if ok {
+		request.logLines = logLines
+	}
+
+	codes, err := readStringList(inputs, "successExitCodes")
+	if err != nil {
+		return request, err
+	}
+	for _, code := range codes {
+		n, err := strconv.ParseInt(code, 10, 32)
+		if err != nil {
+			return request, v1alpha2.NewCOAError(err, fmt.Sprintf("exit code %s is not a number", code), v1alpha2.BadConfig)
+		}
+		request.successExitCodes[int32(n)] = true
+	}
+	request.job = job
+	return request, nil
+}
+
+// waitForJob polls the job until it finishes, the timeout expires or the context is cancelled
+func (i *JobStageProvider) waitForJob(ctx context.Context, request jobRequest) (jobResult, error) {
+	namespace := request.job.Namespace
+	name := request.job.Name
+	deadline := time.NewTimer(request.timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(i.pollInterval())
+	defer ticker.Stop()
+	for {
+		job, err := i.Client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil && ctx.Err() == nil {
+			return jobResult{}, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to get job %s", name), v1alpha2.InternalError)
+		}
+		if err == nil {
+			if finished, succeeded, reason := jobFinished(job); finished {
+				log.InfofCtx(ctx, "  P (Job Stage): job %s finished, succeeded: %t", name, succeeded)
+				result := i.collectResult(ctx, request)
+				result.succeeded = succeeded || (result.exitCode != nil && request.successExitCodes[*result.exitCode])
+				// The deadline of the job is the timeout of the stage
+				result.timedOut = !result.succeeded && reason == "DeadlineExceeded"
+				if result.reason == "" {
+					result.reason = reason
+				}
+				return result, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return jobResult{}, v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("stage is cancelled while job %s is running", name), v1alpha2.Cancelled)
+		case <-deadline.C:
+			log.InfofCtx(ctx, "  P (Job Stage): job %s didn't finish in %s", name, request.timeout)
+			result := i.collectResult(ctx, request)
+			result.timedOut = true
+			return result, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobFinished checks the conditions of a job. It returns whether the job is finished, whether it succeeded and
+// the reason it failed.
+func jobFinished(job *batchv1.Job) (bool, bool, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, true, ""
+		case batchv1.JobFailed:
+			return true, false, condition.Reason
+		}
+	}
+	return false, false, ""
+}
+
+// collectResult gets the exit code, termination message and logs of the container from the latest pod of the job
+func (i *JobStageProvider) collectResult(ctx context.Context, request jobRequest) jobResult {
+	result := jobResult{}
+	namespace := request.job.Namespace
+	pods, err := i.Client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", jobNameLabel, request.job.Name),
+	})
+	if err != nil || len(pods.Items) == 0 {
+		log.InfofCtx(ctx, "  P (Job Stage): no pod is found for job %s: %v", request.job.Name, err)
+		return result
+	}
+	sort.Slice(pods.Items, func(a, b int) bool {
+		return pods.Items[b].CreationTimestamp.Before(&pods.Items[a].CreationTimestamp)
+	})
+	pod := pods.Items[0]
+	result.pod = pod.Name
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != request.container || status.State.Terminated == nil {
+			continue
+		}
+		exitCode := status.State.Terminated.ExitCode
+		result.exitCode = &exitCode
+		result.reason = status.State.Terminated.Reason
+		result.message = strings.TrimSpace(status.State.Terminated.Message)
+	}
+	if request.logLines > 0 {
+		result.logs = i.readLogs(ctx, namespace, pod.Name, request.container, request.logLines)
+	}
+	return result
+}
+
+func (i *JobStageProvider) readLogs(ctx context.Context, namespace string, pod string, container string, lines int64) string {
+	stream, err := i.Client.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+	}).Stream(ctx)
+	if err != nil {
+		log.InfofCtx(ctx, "  P (Job Stage): failed to read logs of pod %s: %v", pod, err)
+		return ""
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(io.LimitReader(stream, maxLogBytes))
+	if err != nil {
+		log.InfofCtx(ctx, "  P (Job Stage): failed to read logs of pod %s: %v", pod, err)
+	}
+	logs := string(data)
+	for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+		log.DebugfCtx(ctx, "  P (Job Stage): [%s] %s", pod, line)
+	}
+	return logs
+}
+
+func (i *JobStageProvider) deleteJob(ctx context.Context, namespace string, name string) {
+	// The stage context may be cancelled already
+	ctx = context.WithoutCancel(ctx)
+	propagation := metav1.DeletePropagationBackground
+	err := i.Client.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil && !kerrors.IsNotFound(err) {
+		log.ErrorfCtx(ctx, "  P (Job Stage): failed to delete job %s: %+v", name, err)
+		return
+	}
+	log.InfofCtx(ctx, "  P (Job Stage): deleted job %s", name)
+}
+
+// generateJobName generates a job name from the stage and activation, with a random suffix so that loops
+// and retries don't collide
+func generateJobName(stage string, activation string) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(strings.Trim(stage+"-"+activation, "-")), "-")
+	if len(name) > 56 {
+		name = name[:56]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "symphony-job"
+	}
+	return fmt.Sprintf("%s-%s", name, rand.String(5))
+}
+
+func labelValue(s string) string {
+	value := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(value) > 63 {
+		value = strings.Trim(value[:63], "-")
+	}
+	return value
+}
+
+func readString(inputs map[string]interface{}, key string) string {
+	if v, ok := inputs[key]; ok && v != nil {
+		return utils2.FormatAsString(v)
+	}
+	return ""
+}
+
+func readInt(inputs map[string]interface{}, key string) (int64, bool, error) {
+	v, ok := inputs[key]
+	if !ok || v == nil || v == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(utils2.FormatAsString(v), 10, 64)
+	if err != nil {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			return int64(f), true, nil
+		}
+		return 0, false, v1alpha2.NewCOAError(err, fmt.Sprintf("%s %v is not a number", key, v), v1alpha2.BadConfig)
+	}
+	return n, true, nil
+}
+
+// readStringList reads a list input, which can also be a JSON array or a single string
+func readStringList(inputs map[string]interface{}, key string) ([]string, error) {
+	v, ok := inputs[key]
+	if !ok || v == nil || v == "" {
+		return nil, nil
+	}
+	switch tv := v.(type) {
+	case []string:
+		return tv, nil
+	case []interface{}:
+		ret := make([]string, 0, len(tv))
+		for _, item := range tv {
+			ret = append(ret, utils2.FormatAsString(item))
+		}
+		return ret, nil
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(tv), "[") {
+			var ret []interface{}
+			if err := json.Unmarshal([]byte(tv), &ret); err != nil {
+				return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("%s is not a valid list", key), v1alpha2.BadConfig)
+			}
+			return readStringList(map[string]interface{}{key: ret}, key)
+		}
+		return []string{tv}, nil
+	default:
+		return []string{utils2.FormatAsString(tv)}, nil
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestProvider() (*JobStageProvider, *fake.Clientset) {
+	client := fake.NewSimpleClientset()
+	return &JobStageProvider{
+		Config: JobStageProviderConfig{
+			PollInterval: "10ms",
+		},
+		Client: client,
+	}, client
+}
+
+// finishJob waits for the job to be created, then adds a pod whose container exits with the exit code and
+// message, and marks the job as complete or failed
+func finishJob(t *testing.T, client *fake.Clientset, namespace string, exitCode int32, message string) {
+	go func() {
+		ctx := context.Background()
+		var job batchv1.Job
+		for {
+			jobs, err := client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+			if err == nil && len(jobs.Items) > 0 {
+				job = jobs.Items[0]
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		_, err := client.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job.Name + "-abcde",
+				Namespace: namespace,
+				Labels:    map[string]string{jobNameLabel: job.Name},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: job.Spec.Template.Spec.Containers[0].Name,
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{
+								ExitCode: exitCode,
+								Message:  message,
+							},
+						},
+					},
+				},
+			},
+		}, metav1.CreateOptions{})
+		assert.Nil(t, err)
+		condition := batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}
+		if exitCode != 0 {
+			condition = batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}
+		}
+		job.Status.Conditions = append(job.Status.Conditions, condition)
+		_, err = client.BatchV1().Jobs(namespace).UpdateStatus(ctx, &job, metav1.UpdateOptions{})
+		assert.Nil(t, err)
+	}()
+}
+
+func TestJobInitWithMap(t *testing.T) {
+	config, err := JobStageProviderConfigFromMap(map[string]string{
+		"inCluster":               "true",
+		"namespace":               "jobs",
+		"timeout":                 "10m",
+		"ttlSecondsAfterFinished": "0",
+		"logLines":                "20",
+	})
+	assert.Nil(t, err)
+	assert.True(t, config.InCluster)
+	assert.Equal(t, "jobs", config.Namespace)
+	assert.Equal(t, int32(0), *config.TTLSecondsAfterFinished)
+	assert.Equal(t, 20, config.LogLines)
+
+	provider := JobStageProvider{}
+	err = provider.InitWithMap(map[string]string{
+		"configType": "inline",
+		"timeout":    "soon",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+
+	err = provider.InitWithMap(map[string]string{
+		"configType": "inline",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestJobSucceeded(t *testing.T) {
+	provider, client := newTestProvider()
+	finishJob(t, client, "default", 0, `{"version": "42"}`)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__campaign":   "db",
+		"__activation": "db-Upgrade-1",
+		"__stage":      "migrate",
+		"image":        "contoso/migrate:1.0",
+		"command":      []interface{}{"migrate", "up"},
+		"env":          map[string]interface{}{"TARGET": "prod", "DRY_RUN": false},
+		"outputFile":   "/tmp/outputs.json",
+	})
+	assert.Nil(t, err)
+	assert.False(t, pause)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+	assert.Equal(t, 0, outputs["exitCode"])
+	assert.Equal(t, "42", outputs["version"])
+	assert.Equal(t, "fake logs", outputs["logs"])
+	assert.Equal(t, "default", outputs["namespace"])
+
+	job, err := client.BatchV1().Jobs("default").Get(context.Background(), outputs["jobName"].(string), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, job.Name, "migrate-db-upgrade-1-")
+	assert.Equal(t, "db-upgrade-1", job.Labels["symphony/activation"])
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, int32(defaultTTL), *job.Spec.TTLSecondsAfterFinished)
+	assert.Equal(t, int64(defaultTimeout.Seconds()), *job.Spec.ActiveDeadlineSeconds)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, []string{"migrate", "up"}, container.Command)
+	assert.Equal(t, "/tmp/outputs.json", container.TerminationMessagePath)
+	assert.Equal(t, []corev1.EnvVar{{Name: "DRY_RUN", Value: "false"}, {Name: "TARGET", Value: "prod"}}, container.Env)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+}
+
+func TestJobFailed(t *testing.T) {
+	provider, client := newTestProvider()
+	finishJob(t, client, "default", 2, "relation users already exists")
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"image": "contoso/migrate:1.0",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.InternalError, v1alpha2.GetErrorState(err))
+	assert.Contains(t, err.Error(), "exit code 2")
+	assert.Equal(t, 2, outputs["exitCode"])
+	assert.Equal(t, "relation users already exists", outputs["message"])
+}
+
+func TestJobSuccessExitCodes(t *testing.T) {
+	provider, client := newTestProvider()
+	finishJob(t, client, "default", 3, "")
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"image":            "contoso/migrate:1.0",
+		"successExitCodes": "[3]",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, outputs["exitCode"])
+}
+
+func TestJobTimeout(t *testing.T) {
+	provider, client := newTestProvider()
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"image":   "contoso/smoke-test:1.0",
+		"timeout": "50ms",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.TimedOut, v1alpha2.GetErrorState(err))
+	// the job is stopped
+	_, err = client.BatchV1().Jobs("default").Get(context.Background(), outputs["jobName"].(string), metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestJobFromManifestWithoutTTL(t *testing.T) {
+	provider, client := newTestProvider()
+	finishJob(t, client, "tests", 0, "smoke tests passed")
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"ttlSecondsAfterFinished": 0,
+		"container":               "tests",
+		"job": `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: smoke-tests
+  namespace: tests
+spec:
+  template:
+    spec:
+      containers:
+      - name: tests
+        image: contoso/smoke-test:1.0
+`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "smoke-tests", outputs["jobName"])
+	assert.Equal(t, "tests", outputs["namespace"])
+	assert.Equal(t, "smoke tests passed", outputs["message"])
+	// a job without a TTL is deleted once its results are read
+	_, err = client.BatchV1().Jobs("tests").Get(context.Background(), "smoke-tests", metav1.GetOptions{})
+	assert.True(t, kerrors.IsNotFound(err))
+}
+
+func TestJobInvalidInputs(t *testing.T) {
+	provider, _ := newTestProvider()
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"image":     "contoso/migrate:1.0",
+		"container": "sidecar",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "doesn't have a container sidecar")
+}
@@ -0,0 +1,450 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+const (
+	loggerName   = "providers.stage.notification"
+	providerName = "P (Notification Stage)"
+	notification = "notification"
+
+	ChannelWebhook = "webhook"
+	ChannelChat    = "chat"
+	ChannelSmtp    = "smtp"
+
+	SignatureHeader = "X-Symphony-Signature"
+	TimestampHeader = "X-Symphony-Timestamp"
+
+	defaultMessage = "Campaign {{.Campaign}} activation {{.Activation}}{{if .Status}} is {{.Status}}{{end}}{{if .Error}}: {{.Error}}{{end}}"
+)
+
+var (
+	msLock                   sync.Mutex
+	log                      = logger.NewLogger(loggerName)
+	once                     sync.Once
+	providerOperationMetrics *metrics.Metrics
+	// sendMail sends mails through an SMTP server, tests replace it
+	sendMail = smtp.SendMail
+)
+
+type NotificationStageProviderConfig struct {
+	// Channel is webhook, chat or smtp
+	Channel string `json:"channel"`
+	// Url of a webhook or of a chat incoming webhook
+	Url     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// SigningKey signs webhook payloads with HMAC-SHA256
+	SigningKey string `json:"signingKey,omitempty"`
+	// TextField is the field of a chat message that holds the text, "text" by default
+	TextField     string   `json:"textField,omitempty"`
+	SmtpHost      string   `json:"smtp.host,omitempty"`
+	SmtpPort      int      `json:"smtp.port,omitempty"`
+	SmtpUser      string   `json:"smtp.user,omitempty"`
+	SmtpPassword  string   `json:"smtp.password,omitempty"`
+	From          string   `json:"from,omitempty"`
+	To            []string `json:"to,omitempty"`
+	RetryCount    int      `json:"retry.count,omitempty"`
+	RetryInterval int      `json:"retry.interval,omitempty"`
+}
+
+// NotificationStageProvider sends a message rendered from the stage inputs to a webhook, a chat incoming webhook
+// or through an SMTP server
+type NotificationStageProvider struct {
+	Config  NotificationStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (s *NotificationStageProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("[Stage] Notification Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	msLock.Lock()
+	defer msLock.Unlock()
+	var notificationConfig NotificationStageProviderConfig
+	notificationConfig, err = toNotificationStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	err = validateConfig(notificationConfig)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Notification Stage): invalid config: %+v", err)
+		return err
+	}
+	s.Config = notificationConfig
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				log.ErrorfCtx(ctx, "  P (Notification Stage): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	return err
+}
+func (s *NotificationStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toNotificationStageProviderConfig(config providers.IProviderConfig) (NotificationStageProviderConfig, error) {
+	ret := NotificationStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *NotificationStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := NotificationStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func NotificationStageProviderConfigFromMap(properties map[string]string) (NotificationStageProviderConfig, error) {
+	ret := NotificationStageProviderConfig{}
+	ret.Channel = properties["channel"]
+	ret.Url = properties["url"]
+	ret.TextField = properties["textField"]
+	ret.SmtpHost = properties["smtp.host"]
+	ret.SmtpUser = properties["smtp.user"]
+	ret.From = properties["from"]
+	if _, ok := properties["signingKey"]; ok {
+		key, err := api_utils.GetString(properties, "signingKey")
+		if err != nil {
+			return ret, err
+		}
+		ret.SigningKey = key
+	}
+	if _, ok := properties["smtp.password"]; ok {
+		password, err := api_utils.GetString(properties, "smtp.password")
+		if err != nil {
+			return ret, err
+		}
+		ret.SmtpPassword = password
+	}
+	if v, ok := properties["to"]; ok {
+		for _, to := range strings.Split(v, ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				ret.To = append(ret.To, to)
+			}
+		}
+	}
+	if v, ok := properties["headers"]; ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &ret.Headers); err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse headers %v", v), v1alpha2.BadConfig)
+		}
+	}
+	for _, key := range []string{"smtp.port", "retry.count", "retry.interval"} {
+		v, ok := properties[key]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to parse %s %v", key, v), v1alpha2.BadConfig)
+		}
+		switch key {
+		case "smtp.port":
+			ret.SmtpPort = n
+		case "retry.count":
+			ret.RetryCount = n
+		case "retry.interval":
+			ret.RetryInterval = n
+		}
+	}
+	return ret, nil
+}
+
+func validateConfig(config NotificationStageProviderConfig) error {
+	switch config.Channel {
+	case ChannelWebhook, ChannelChat:
+		if config.Url == "" {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("url is required by the %s channel", config.Channel), v1alpha2.BadConfig)
+		}
+	case ChannelSmtp:
+		if config.SmtpHost == "" || config.From == "" {
+			return v1alpha2.NewCOAError(nil, "smtp.host and from are required by the smtp channel", v1alpha2.BadConfig)
+		}
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("channel %s is not supported, it should be webhook, chat or smtp", config.Channel), v1alpha2.BadConfig)
+	}
+	if config.RetryCount < 0 || config.RetryInterval < 0 {
+		return v1alpha2.NewCOAError(nil, "retry.count and retry.interval can't be negative", v1alpha2.BadConfig)
+	}
+	return nil
+}
+
+// Process renders the "subject" and "message" inputs, which are Go templates, and sends them to the channel.
+// Failed sends are retried retry.count times. The templates get the campaign, activation, stage, namespace,
+// status and error of the activation, the other inputs as .Inputs and the outputs of the activation as
+// .Outputs when they are provided by a campaign-level notification.
+func (i *NotificationStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Notification Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "  P (Notification Stage): sending notification to %s channel", i.Config.Channel)
+	processTime := time.Now().UTC()
+	functionName := observ_utils.GetFunctionName()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		processTime,
+		notification,
+		metrics.ProcessOperation,
+		metrics.RunOperationType,
+		functionName,
+	)
+
+	data := templateData(inputs)
+	var subject, message string
+	subject, err = render("subject", readInput(inputs, "subject", ""), data)
+	if err == nil {
+		message, err = render("message", readInput(inputs, "message", defaultMessage), data)
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Notification Stage): failed to render notification: %+v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			notification,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.ValidateOperationType,
+			v1alpha2.BadConfig.String(),
+		)
+		return nil, false, err
+	}
+
+	attempts := 0
+	for {
+		attempts++
+		var retriable bool
+		retriable, err = i.send(ctx, subject, message, data)
+		if err == nil || !retriable || attempts > i.Config.RetryCount {
+			break
+		}
+		log.InfofCtx(ctx, "  P (Notification Stage): failed to send notification, retrying (%d/%d): %v", attempts, i.Config.RetryCount, err)
+		select {
+		case <-ctx.Done():
+			err = v1alpha2.NewCOAError(ctx.Err(), "stage is cancelled", v1alpha2.Cancelled)
+			return nil, false, err
+		case <-time.After(time.Duration(i.Config.RetryInterval) * time.Second):
+		}
+	}
+
+	outputs := map[string]interface{}{
+		"channel":  i.Config.Channel,
+		"attempts": attempts,
+		"subject":  subject,
+		"message":  message,
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Notification Stage): failed to send notification after %d attempts: %+v", attempts, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			notification,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.GetErrorState(err).String(),
+		)
+		return outputs, false, err
+	}
+	outputs[v1alpha2.StatusOutput] = v1alpha2.OK
+	log.InfofCtx(ctx, "  P (Notification Stage): notification sent after %d attempts", attempts)
+	return outputs, false, nil
+}
+
+// send sends the notification once. It returns whether a failure is worth retrying.
+func (i *NotificationStageProvider) send(ctx context.Context, subject string, message string, data map[string]interface{}) (bool, error) {
+	switch i.Config.Channel {
+	case ChannelWebhook:
+		payload := map[string]interface{}{
+			"campaign":   data["Campaign"],
+			"activation": data["Activation"],
+			"stage":      data["Stage"],
+			"namespace":  data["Namespace"],
+			"status":     data["Status"],
+			"error":      data["Error"],
+			"subject":    subject,
+			"message":    message,
+			"inputs":     data["Inputs"],
+		}
+		body, _ := json.Marshal(payload)
+		return i.post(ctx, body)
+	case ChannelChat:
+		field := i.Config.TextField
+		if field == "" {
+			field = "text"
+		}
+		text := message
+		if subject != "" {
+			text = subject + "\n" + message
+		}
+		body, _ := json.Marshal(map[string]interface{}{field: text})
+		return i.post(ctx, body)
+	case ChannelSmtp:
+		return i.mail(subject, message)
+	}
+	return false, v1alpha2.NewCOAError(nil, fmt.Sprintf("channel %s is not supported", i.Config.Channel), v1alpha2.BadConfig)
+}
+
+func (i *NotificationStageProvider) post(ctx context.Context, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, i.Config.Url, bytes.NewBuffer(body))
+	if err != nil {
+		return false, v1alpha2.NewCOAError(err, "failed to create request", v1alpha2.BadConfig)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range i.Config.Headers {
+		request.Header.Set(k, v)
+	}
+	if i.Config.SigningKey != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		request.Header.Set(TimestampHeader, timestamp)
+		request.Header.Set(SignatureHeader, "sha256="+Sign(i.Config.SigningKey, timestamp, body))
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return true, v1alpha2.NewCOAError(err, "failed to send request", v1alpha2.InternalError)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	err = v1alpha2.NewCOAError(nil, fmt.Sprintf("notification is rejected with status code %d", response.StatusCode), v1alpha2.InternalError)
+	// Client errors won't go away with a retry, except throttling
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests, err
+}
+
+func (i *NotificationStageProvider) mail(subject string, message string) (bool, error) {
+	if len(i.Config.To) == 0 {
+		return false, v1alpha2.NewCOAError(nil, "to is required by the smtp channel", v1alpha2.BadConfig)
+	}
+	port := i.Config.SmtpPort
+	if port == 0 {
+		port = 25
+	}
+	var auth smtp.Auth
+	if i.Config.SmtpUser != "" {
+		auth = smtp.PlainAuth("", i.Config.SmtpUser, i.Config.SmtpPassword, i.Config.SmtpHost)
+	}
+	var mail strings.Builder
+	fmt.Fprintf(&mail, "From: %s\r\n", i.Config.From)
+	fmt.Fprintf(&mail, "To: %s\r\n", strings.Join(i.Config.To, ", "))
+	fmt.Fprintf(&mail, "Subject: %s\r\n", strings.ReplaceAll(strings.ReplaceAll(subject, "\r", ""), "\n", " "))
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	mail.WriteString(strings.ReplaceAll(message, "\n", "\r\n"))
+	err := sendMail(net.JoinHostPort(i.Config.SmtpHost, strconv.Itoa(port)), auth, i.Config.From, i.Config.To, []byte(mail.String()))
+	if err != nil {
+		return isTransientMailError(err), v1alpha2.NewCOAError(err, "failed to send mail", v1alpha2.InternalError)
+	}
+	return false, nil
+}
+
+// isTransientMailError tells if a mail failure is worth retrying. Only transient (4xx) replies of the server and
+// network errors are; permanent (5xx) replies, such as rejected credentials or recipients, and authentication
+// or configuration errors won't go away with a retry.
+func isTransientMailError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Sign signs a webhook payload. The signature is the hex encoded HMAC-SHA256 of the timestamp, a dot and the
+// payload, so that receivers can reject replayed payloads.
+func Sign(key string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func templateData(inputs map[string]interface{}) map[string]interface{} {
+	userInputs := make(map[string]interface{})
+	for k, v := range inputs {
+		if !strings.HasPrefix(k, "__") && k != "subject" && k != "message" {
+			userInputs[k] = v
+		}
+	}
+	return map[string]interface{}{
+		"Campaign":   readInput(inputs, "__campaign", ""),
+		"Activation": readInput(inputs, "__activation", ""),
+		"Stage":      readInput(inputs, "__stage", ""),
+		"Namespace":  readInput(inputs, "__namespace", ""),
+		"Status":     readInput(inputs, "__status", ""),
+		"Error":      readInput(inputs, "__error", ""),
+		"Inputs":     userInputs,
+		"Outputs":    inputs["__outputs"],
+	}
+}
+
+func render(name string, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("invalid %s template", name), v1alpha2.BadConfig)
+	}
+	var buffer bytes.Buffer
+	if err = tmpl.Execute(&buffer, data); err != nil {
+		return "", v1alpha2.NewCOAError(err, fmt.Sprintf("failed to render %s template", name), v1alpha2.BadConfig)
+	}
+	return buffer.String(), nil
+}
+
+func readInput(inputs map[string]interface{}, key string, defaultValue string) string {
+	if v, ok := inputs[key]; ok && v != nil {
+		if s := api_utils.FormatAsString(v); s != "" {
+			return s
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"net/textproto"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationInitWithMap(t *testing.T) {
+	provider := NotificationStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"channel":        "smtp",
+		"smtp.host":      "smtp.contoso.com",
+		"smtp.port":      "587",
+		"from":           "symphony@contoso.com",
+		"to":             "ops@contoso.com, dev@contoso.com",
+		"retry.count":    "2",
+		"retry.interval": "1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 587, provider.Config.SmtpPort)
+	assert.Equal(t, []string{"ops@contoso.com", "dev@contoso.com"}, provider.Config.To)
+	assert.Equal(t, 2, provider.Config.RetryCount)
+
+	err = provider.InitWithMap(map[string]string{
+		"channel": "webhook",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+
+	err = provider.InitWithMap(map[string]string{
+		"channel": "pager",
+		"url":     "http://localhost",
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "channel pager is not supported")
+}
+
+func TestNotificationSignedWebhook(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := "sha256=" + Sign("secret", r.Header.Get(TimestampHeader), body)
+		if r.Header.Get(SignatureHeader) != signature || r.Header.Get("X-Team") != "ops" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:    ChannelWebhook,
+		Url:        server.URL,
+		SigningKey: "secret",
+		Headers:    map[string]string{"X-Team": "ops"},
+	})
+	assert.Nil(t, err)
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__campaign":   "deploy",
+		"__activation": "deploy-1",
+		"__stage":      "notify",
+		"subject":      "{{.Campaign}} rolled out",
+		"message":      "{{.Inputs.app}} is at revision {{.Inputs.revision}}",
+		"app":          "web",
+		"revision":     3,
+	})
+	assert.Nil(t, err)
+	assert.False(t, pause)
+	assert.Equal(t, v1alpha2.OK, outputs[v1alpha2.StatusOutput])
+	assert.Equal(t, 1, outputs["attempts"])
+	assert.Equal(t, "deploy rolled out", payload["subject"])
+	assert.Equal(t, "web is at revision 3", payload["message"])
+	assert.Equal(t, "deploy-1", payload["activation"])
+	assert.Equal(t, "web", payload["inputs"].(map[string]interface{})["app"])
+}
+
+func TestNotificationChat(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:   ChannelChat,
+		Url:       server.URL,
+		TextField: "content",
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__campaign":   "deploy",
+		"__activation": "deploy-1",
+		"__status":     "Internal Error",
+		"__error":      "stage deploy failed",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Campaign deploy activation deploy-1 is Internal Error: stage deploy failed", payload["content"])
+}
+
+func TestNotificationRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:    ChannelWebhook,
+		Url:        server.URL,
+		RetryCount: 3,
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, outputs["attempts"])
+
+	// retries are used up
+	atomic.StoreInt32(&calls, 0)
+	provider.Config.RetryCount = 1
+	outputs, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, outputs["attempts"])
+}
+
+func TestNotificationNoRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:    ChannelWebhook,
+		Url:        server.URL,
+		RetryCount: 3,
+	})
+	assert.Nil(t, err)
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status code 400")
+	assert.Equal(t, 1, outputs["attempts"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestNotificationSmtp(t *testing.T) {
+	var addr string
+	var to []string
+	var mail string
+	sendMail = func(a string, auth smtp.Auth, from string, recipients []string, msg []byte) error {
+		addr = a
+		to = recipients
+		mail = string(msg)
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:  ChannelSmtp,
+		SmtpHost: "smtp.contoso.com",
+		From:     "symphony@contoso.com",
+		To:       []string{"ops@contoso.com"},
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__campaign": "deploy",
+		"subject":    "{{.Campaign}}\nfailed",
+		"message":    "see {{json .Outputs}}",
+		"__outputs":  map[string]interface{}{"deploy": map[string]interface{}{"status": 500}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "smtp.contoso.com:25", addr)
+	assert.Equal(t, []string{"ops@contoso.com"}, to)
+	assert.Contains(t, mail, "Subject: deploy failed\r\n")
+	assert.Contains(t, mail, "see {\"deploy\":{\"status\":500}}")
+}
+
+func TestNotificationSmtpRetry(t *testing.T) {
+	defer func() { sendMail = smtp.SendMail }()
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel:    ChannelSmtp,
+		SmtpHost:   "smtp.contoso.com",
+		From:       "symphony@contoso.com",
+		To:         []string{"ops@contoso.com"},
+		RetryCount: 2,
+	})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		err      error
+		attempts int
+	}{
+		{&textproto.Error{Code: 451, Msg: "try again later"}, 3},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, 3},
+		{&textproto.Error{Code: 535, Msg: "authentication failed"}, 1},
+		{&textproto.Error{Code: 550, Msg: "mailbox unavailable"}, 1},
+		{&net.DNSError{Err: "no such host", Name: "smtp.contoso.com", IsNotFound: true}, 1},
+		{errors.New("unencrypted connection"), 1},
+	}
+	for _, testCase := range testCases {
+		sendMail = func(a string, auth smtp.Auth, from string, recipients []string, msg []byte) error {
+			return testCase.err
+		}
+		outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+		assert.NotNil(t, err)
+		assert.Equal(t, testCase.attempts, outputs["attempts"], testCase.err.Error())
+	}
+}
+
+func TestNotificationInvalidTemplate(t *testing.T) {
+	provider := NotificationStageProvider{}
+	err := provider.Init(NotificationStageProviderConfig{
+		Channel: ChannelChat,
+		Url:     "http://localhost",
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"message": "{{.Campaign",
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+}
@@ -0,0 +1,385 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	oci_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/oci"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	loggerName   = "providers.stage.oci"
+	providerName = "P (OCI Stage)"
+	oci          = "oci"
+
+	// defaultMaxInlineSize bounds the artifact content copied into a catalog
+	defaultMaxInlineSize = 1 << 20
+
+	referenceAnnotation = "symphony/oci-reference"
+	digestAnnotation    = "symphony/oci-digest"
+)
+
+var (
+	msLock                   sync.Mutex
+	mLog                     = logger.NewLogger(loggerName)
+	once                     sync.Once
+	providerOperationMetrics *metrics.Metrics
+)
+
+type OCIStageProviderConfig struct {
+	User         string `json:"user"`
+	Password     string `json:"password"`
+	CacheDir     string `json:"cacheDir,omitempty"`
+	MaxCacheSize int64  `json:"maxCacheSize,omitempty"`
+	// RequireDigest rejects artifact references that are not pinned by digest, regardless of stage inputs
+	RequireDigest bool `json:"requireDigest,omitempty"`
+}
+
+// OCIStageProvider pulls an artifact from an OCI registry and materializes it into a catalog
+type OCIStageProvider struct {
+	Config    OCIStageProviderConfig
+	Context   *contexts.ManagerContext
+	ApiClient api_utils.ApiClient
+	Fetcher   *oci_utils.Fetcher
+}
+
+func (s *OCIStageProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("[Stage] OCI Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	msLock.Lock()
+	defer msLock.Unlock()
+	var ociConfig OCIStageProviderConfig
+	ociConfig, err = toOCIStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	s.Config = ociConfig
+	s.Fetcher, err = oci_utils.GetFetcher(oci_utils.FetcherConfig{
+		CacheDir:     ociConfig.CacheDir,
+		MaxCacheSize: ociConfig.MaxCacheSize,
+	})
+	if err != nil {
+		return err
+	}
+	s.ApiClient, err = api_utils.GetApiClient()
+	if err != nil {
+		return err
+	}
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				mLog.ErrorfCtx(ctx, "  P (OCI Stage): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	return err
+}
+func (s *OCIStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toOCIStageProviderConfig(config providers.IProviderConfig) (OCIStageProviderConfig, error) {
+	ret := OCIStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *OCIStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := OCIStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func OCIStageProviderConfigFromMap(properties map[string]string) (OCIStageProviderConfig, error) {
+	ret := OCIStageProviderConfig{}
+	if api_utils.ShouldUseUserCreds() {
+		user, err := api_utils.GetString(properties, "user")
+		if err != nil {
+			return ret, err
+		}
+		ret.User = user
+		if ret.User == "" && !api_utils.ShouldUseSATokens() {
+			return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+		}
+		password, err := api_utils.GetString(properties, "password")
+		ret.Password = password
+		if err != nil {
+			return ret, err
+		}
+	}
+	if v, ok := properties["cacheDir"]; ok {
+		ret.CacheDir = v
+	}
+	if v, ok := properties["maxCacheSize"]; ok && v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "maxCacheSize must be an integer", v1alpha2.BadConfig)
+		}
+		ret.MaxCacheSize = size
+	}
+	if v, ok := properties["requireDigest"]; ok && v != "" {
+		requireDigest, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "requireDigest must be a boolean", v1alpha2.BadConfig)
+		}
+		ret.RequireDigest = requireDigest
+	}
+	return ret, nil
+}
+
+// Process pulls the artifact in the reference input and upserts the catalog in the catalog input.
+// When the file input names a layer, the parsed content of that layer becomes the catalog properties;
+// otherwise all titled layers are copied under the files property.
+func (i *OCIStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] OCI provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	mLog.InfofCtx(ctx, "  P (OCI Stage) process started")
+	processTime := time.Now().UTC()
+	functionName := observ_utils.GetFunctionName()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		processTime,
+		oci,
+		metrics.ProcessOperation,
+		metrics.RunOperationType,
+		functionName,
+	)
+
+	outputs := make(map[string]interface{})
+	reference := stage.ReadInputString(inputs, "reference")
+	catalogRef := stage.ReadInputString(inputs, "catalog")
+	if reference == "" || catalogRef == "" {
+		err = v1alpha2.NewCOAError(nil, "reference and catalog inputs are required", v1alpha2.BadRequest)
+		providerOperationMetrics.ProviderOperationErrors(
+			oci,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.ValidateOperationType,
+			v1alpha2.BadRequest.String(),
+		)
+		return outputs, false, err
+	}
+	namespace := stage.GetNamespace(inputs)
+	if namespace == "" {
+		namespace = constants.DefaultScope
+	}
+
+	var options oci_utils.PullOptions
+	options, err = oci_utils.ToPullOptions(inputs)
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, "invalid pull options", v1alpha2.BadRequest)
+		return outputs, false, err
+	}
+	options.RequireDigest = options.RequireDigest || i.Config.RequireDigest
+	options.Namespace = namespace
+	if mgrContext.VencorContext != nil && mgrContext.VencorContext.EvaluationContext != nil {
+		options.SecretProvider = mgrContext.VencorContext.EvaluationContext.SecretProvider
+	}
+
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (OCI Stage): Start to pull artifact %s", reference)
+	var artifact *oci_utils.Artifact
+	artifact, err = i.Fetcher.Pull(ctx, reference, options)
+	if err != nil {
+		mLog.ErrorfCtx(ctx, "  P (OCI Stage) process failed, failed to pull artifact %s: %+v", reference, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			oci,
+			functionName,
+			metrics.PullArtifactOperation,
+			metrics.RunOperationType,
+			v1alpha2.OCIArtifactPullFailed.String(),
+		)
+		return outputs, false, err
+	}
+
+	var properties map[string]interface{}
+	properties, err = getCatalogProperties(artifact, stage.ReadInputString(inputs, "file"), getMaxInlineSize(inputs))
+	if err != nil {
+		mLog.ErrorfCtx(ctx, "  P (OCI Stage) process failed, failed to read artifact %s: %+v", reference, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			oci,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.BadRequest.String(),
+		)
+		return outputs, false, err
+	}
+
+	catalogType := stage.ReadInputString(inputs, "catalogType")
+	if catalogType == "" {
+		catalogType = "config"
+	}
+	catalogName := api_utils.ConvertReferenceToObjectName(catalogRef)
+	if err = i.upsertCatalog(ctx, catalogRef, catalogName, catalogType, namespace, artifact, properties); err != nil {
+		mLog.ErrorfCtx(ctx, "  P (OCI Stage) process failed, failed to upsert catalog %s: %+v", catalogName, err)
+		providerOperationMetrics.ProviderOperationErrors(
+			oci,
+			functionName,
+			metrics.ProcessOperation,
+			metrics.RunOperationType,
+			v1alpha2.CreateCatalogFromCatalogFailed.String(),
+		)
+		return outputs, false, err
+	}
+
+	files := make([]interface{}, 0, len(artifact.Layers))
+	for _, layer := range artifact.Layers {
+		if layer.Title != "" {
+			files = append(files, layer.Title)
+		}
+	}
+	outputs["catalog"] = catalogName
+	outputs["digest"] = artifact.Digest.String()
+	outputs["files"] = files
+	mLog.InfofCtx(ctx, "  P (OCI Stage) materialized %s (%s) into catalog %s", reference, artifact.Digest, catalogName)
+	return outputs, false, nil
+}
+
+// upsertCatalog writes the catalog, creating its catalog container first when needed
+func (i *OCIStageProvider) upsertCatalog(ctx context.Context, catalogRef string, catalogName string, catalogType string, namespace string, artifact *oci_utils.Artifact, properties map[string]interface{}) error {
+	parts := strings.Split(catalogRef, constants.ReferenceSeparator)
+	if len(parts) != 2 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid catalog name %s, expected <name>%s<version>", catalogRef, constants.ReferenceSeparator), v1alpha2.BadRequest)
+	}
+	catalog := model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      catalogName,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				referenceAnnotation: artifact.Reference,
+				digestAnnotation:    artifact.Digest.String(),
+			},
+		},
+		Spec: &model.CatalogSpec{
+			CatalogType:  catalogType,
+			Properties:   properties,
+			RootResource: parts[0],
+			Version:      parts[1],
+		},
+	}
+
+	_, err := i.ApiClient.GetCatalogContainer(ctx, parts[0], namespace, i.Config.User, i.Config.Password)
+	if err != nil && api_utils.IsNotFound(err) {
+		mLog.DebugfCtx(ctx, "Catalog container %s doesn't exist: %s", parts[0], err.Error())
+		catalogContainerState := model.CatalogContainerState{ObjectMeta: model.ObjectMeta{Name: parts[0], Namespace: namespace}}
+		containerObjectData, _ := json.Marshal(catalogContainerState)
+		err = i.ApiClient.CreateCatalogContainer(ctx, parts[0], containerObjectData, namespace, i.Config.User, i.Config.Password)
+		if err != nil {
+			mLog.ErrorfCtx(ctx, "Failed to create catalog container %s: %s", parts[0], err.Error())
+			return err
+		}
+	} else if err != nil {
+		mLog.ErrorfCtx(ctx, "Failed to get catalog container %s: %s", parts[0], err.Error())
+		return err
+	}
+
+	objectData, _ := json.Marshal(catalog)
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (OCI Stage): Start to upsert catalog %s in namespace %s", catalogName, namespace)
+	return i.ApiClient.UpsertCatalog(ctx, catalogName, objectData, i.Config.User, i.Config.Password)
+}
+
+// getCatalogProperties converts the content of an artifact to catalog properties. JSON and YAML layers
+// are parsed, other text layers are copied as strings and binary layers are base64 encoded.
+func getCatalogProperties(artifact *oci_utils.Artifact, file string, maxInlineSize int64) (map[string]interface{}, error) {
+	if file != "" {
+		data, err := artifact.ReadLayer(file)
+		if err != nil {
+			return nil, err
+		}
+		ret := map[string]interface{}{}
+		if err = yaml.Unmarshal(data, &ret); err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("file %s of artifact %s is not a JSON or YAML object", file, artifact.Reference), v1alpha2.BadRequest)
+		}
+		return ret, nil
+	}
+
+	files := map[string]interface{}{}
+	var size int64
+	for _, layer := range artifact.Layers {
+		if layer.Title == "" {
+			continue
+		}
+		size += layer.Size
+		if size > maxInlineSize {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact %s is larger than %d bytes, select a file to materialize", artifact.Reference, maxInlineSize), v1alpha2.BadRequest)
+		}
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			return nil, err
+		}
+		files[layer.Title] = toPropertyValue(layer.Title, data)
+	}
+	return map[string]interface{}{
+		"files": files,
+	}, nil
+}
+
+func toPropertyValue(title string, data []byte) interface{} {
+	switch strings.ToLower(filepath.Ext(title)) {
+	case ".json", ".yaml", ".yml":
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err == nil {
+			return value
+		}
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func getMaxInlineSize(inputs map[string]interface{}) int64 {
+	switch v := inputs["maxInlineSize"].(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case string:
+		if size, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return size
+		}
+	}
+	return defaultMaxInlineSize
+}
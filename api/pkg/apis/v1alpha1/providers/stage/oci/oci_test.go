@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+type AuthResponse struct {
+	AccessToken string   `json:"accessToken"`
+	TokenType   string   `json:"tokenType"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+}
+
+// initializeMockRegistry serves a single artifact with the given layers under the "v1" tag
+func initializeMockRegistry(t *testing.T, layers map[string][]byte) (*httptest.Server, digest.Digest) {
+	blobs := map[digest.Digest][]byte{}
+	config := []byte("{}")
+	blobs[digest.FromBytes(config)] = config
+	manifest := ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.symphony.config",
+		Config:       ocispec.Descriptor{MediaType: ocispec.MediaTypeEmptyJSON, Digest: digest.FromBytes(config), Size: int64(len(config))},
+	}
+	for title, data := range layers {
+		blobs[digest.FromBytes(data)] = data
+		manifest.Layers = append(manifest.Layers, ocispec.Descriptor{
+			MediaType:   "application/octet-stream",
+			Digest:      digest.FromBytes(data),
+			Size:        int64(len(data)),
+			Annotations: map[string]string{ocispec.AnnotationTitle: title},
+		})
+	}
+	manifestData, _ := json.Marshal(manifest)
+	manifestDigest := digest.FromBytes(manifestData)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasSuffix(path, "/manifests/v1") || strings.HasSuffix(path, "/manifests/"+manifestDigest.String()):
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+			w.Header().Set("Content-Length", fmt.Sprint(len(manifestData)))
+			if r.Method == http.MethodGet {
+				w.Write(manifestData)
+			}
+		case strings.Contains(path, "/blobs/"):
+			data, ok := blobs[digest.Digest(path[strings.Index(path, "/blobs/")+len("/blobs/"):])]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, manifestDigest
+}
+
+// initializeMockSymphonyAPI records the catalogs upserted through the Symphony API
+func initializeMockSymphonyAPI(t *testing.T, catalogs map[string]model.CatalogState) *httptest.Server {
+	var lock sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		var response interface{}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/catalogcontainers/"):
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		case strings.HasPrefix(r.URL.Path, "/catalogs/registry/"):
+			body, _ := io.ReadAll(r.Body)
+			var catalog model.CatalogState
+			json.Unmarshal(body, &catalog)
+			catalogs[strings.TrimPrefix(r.URL.Path, "/catalogs/registry/")] = catalog
+		default:
+			response = AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+				Username:    "test-user",
+				Roles:       []string{"role1", "role2"},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestProvider(t *testing.T, catalogs map[string]model.CatalogState) *OCIStageProvider {
+	ts := initializeMockSymphonyAPI(t, catalogs)
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	provider := &OCIStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+		"cacheDir": t.TempDir(),
+	})
+	assert.Nil(t, err)
+	return provider
+}
+
+func TestOCIInitFromVendorMap(t *testing.T) {
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	config, err := OCIStageProviderConfigFromMap(map[string]string{
+		"user":          "admin",
+		"password":      "",
+		"cacheDir":      "/tmp/cache",
+		"maxCacheSize":  "1024",
+		"requireDigest": "true",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", config.User)
+	assert.Equal(t, "/tmp/cache", config.CacheDir)
+	assert.Equal(t, int64(1024), config.MaxCacheSize)
+	assert.True(t, config.RequireDigest)
+
+	_, err = OCIStageProviderConfigFromMap(map[string]string{
+		"user":         "admin",
+		"password":     "",
+		"maxCacheSize": "abc",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestOCIProcessFile(t *testing.T) {
+	registry, manifestDigest := initializeMockRegistry(t, map[string][]byte{
+		"config.yaml": []byte("replicas: 3\nimage: redis:7\n"),
+	})
+	catalogs := map[string]model.CatalogState{}
+	provider := newTestProvider(t, catalogs)
+
+	reference := strings.TrimPrefix(registry.URL, "http://") + "/configs/app@" + manifestDigest.String()
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"reference": reference,
+		"plainHttp": true,
+		"catalog":   "app-config:v1",
+		"file":      "config.yaml",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "app-config-v-v1", outputs["catalog"])
+	assert.Equal(t, manifestDigest.String(), outputs["digest"])
+
+	catalog, ok := catalogs["app-config-v-v1"]
+	assert.True(t, ok)
+	assert.Equal(t, "config", catalog.Spec.CatalogType)
+	assert.Equal(t, "app-config", catalog.Spec.RootResource)
+	assert.Equal(t, "v1", catalog.Spec.Version)
+	assert.Equal(t, float64(3), catalog.Spec.Properties["replicas"])
+	assert.Equal(t, "redis:7", catalog.Spec.Properties["image"])
+	assert.Equal(t, manifestDigest.String(), catalog.ObjectMeta.Annotations[digestAnnotation])
+}
+
+func TestOCIProcessAllFiles(t *testing.T) {
+	registry, _ := initializeMockRegistry(t, map[string][]byte{
+		"settings.json": []byte(`{"level": "debug"}`),
+		"README":        []byte("plain text"),
+	})
+	catalogs := map[string]model.CatalogState{}
+	provider := newTestProvider(t, catalogs)
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"reference":   "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/configs/app:v1",
+		"plainHttp":   true,
+		"catalog":     "app-files:v1",
+		"catalogType": "artifact",
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []interface{}{"settings.json", "README"}, outputs["files"])
+
+	catalog := catalogs["app-files-v-v1"]
+	assert.Equal(t, "artifact", catalog.Spec.CatalogType)
+	files := catalog.Spec.Properties["files"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"level": "debug"}, files["settings.json"])
+	assert.Equal(t, "plain text", files["README"])
+}
+
+func TestOCIProcessRequireDigest(t *testing.T) {
+	registry, _ := initializeMockRegistry(t, map[string][]byte{
+		"config.yaml": []byte("replicas: 3"),
+	})
+	catalogs := map[string]model.CatalogState{}
+	provider := newTestProvider(t, catalogs)
+	provider.Config.RequireDigest = true
+
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"reference": strings.TrimPrefix(registry.URL, "http://") + "/configs/app:v1",
+		"plainHttp": true,
+		"catalog":   "app-config:v1",
+	})
+	assert.NotNil(t, err)
+	assert.Empty(t, catalogs)
+}
+
+func TestOCIProcessMissingInputs(t *testing.T) {
+	provider := newTestProvider(t, map[string]model.CatalogState{})
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"reference": "registry.example.com/configs/app:v1",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestOCIProcessInvalidCatalogName(t *testing.T) {
+	registry, _ := initializeMockRegistry(t, map[string][]byte{
+		"config.yaml": []byte("replicas: 3"),
+	})
+	provider := newTestProvider(t, map[string]model.CatalogState{})
+	_, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"reference": strings.TrimPrefix(registry.URL, "http://") + "/configs/app:v1",
+		"plainHttp": true,
+		"catalog":   "app-config",
+	})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,621 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/plugin"
+	pb "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/proto"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	loggerName   = "providers.target.grpc"
+	providerName = "P (gRPC Target)"
+	grpcProvider = "grpc"
+
+	defaultHealthCheckInterval = 10
+	defaultStartTimeout        = 10
+	defaultMaxRestarts         = 3
+	stopTimeout                = 5 * time.Second
+)
+
+var (
+	sLog                     = logger.NewLogger(loggerName)
+	providerOperationMetrics *metrics.Metrics
+	once                     sync.Once
+)
+
+type GrpcTargetProviderConfig struct {
+	Name string `json:"name"`
+	// Address connects to a running plug-in, either unix:///path/to/socket or host:port
+	Address string `json:"address,omitempty"`
+	// Command launches the plug-in. The host passes a unix socket address in SYMPHONY_PLUGIN_ADDRESS.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Config is passed to the Init call of the plug-in
+	Config map[string]string `json:"config,omitempty"`
+	// HealthCheckInterval is the interval, in seconds, between plug-in health checks
+	HealthCheckInterval int `json:"healthCheckInterval,omitempty"`
+	// StartTimeout is the time, in seconds, a launched plug-in has to become healthy
+	StartTimeout int `json:"startTimeout,omitempty"`
+	// MaxRestarts is the number of times a launched plug-in is restarted after it exits or fails
+	// its health check; 0 never restarts it and a negative value restarts it without limit
+	MaxRestarts *int `json:"maxRestarts,omitempty"`
+}
+
+// GrpcTargetProvider is a target provider implemented out of process by a plug-in speaking the
+// target provider plug-in protocol
+type GrpcTargetProvider struct {
+	Config  GrpcTargetProviderConfig
+	Context *contexts.ManagerContext
+
+	conn      *grpc.ClientConn
+	client    pb.TargetProviderClient
+	health    healthpb.HealthClient
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	socketDir string
+	restarts  int
+	unhealthy bool
+	stop      chan struct{}
+	lock      sync.RWMutex
+}
+
+func GrpcTargetProviderConfigFromMap(properties map[string]string) (GrpcTargetProviderConfig, error) {
+	ret := GrpcTargetProviderConfig{
+		Config: map[string]string{},
+	}
+	ret.Name = properties["name"]
+	ret.Address = properties["address"]
+	ret.Command = properties["command"]
+	if v, ok := properties["args"]; ok {
+		ret.Args = strings.Fields(v)
+	}
+	for k, v := range properties {
+		if key, ok := strings.CutPrefix(k, "config."); ok {
+			ret.Config[key] = v
+		}
+	}
+	var err error
+	if ret.HealthCheckInterval, err = getInt(properties, "healthCheckInterval"); err != nil {
+		return ret, err
+	}
+	if ret.StartTimeout, err = getInt(properties, "startTimeout"); err != nil {
+		return ret, err
+	}
+	if v, ok := properties["maxRestarts"]; ok && v != "" {
+		maxRestarts, err := getInt(properties, "maxRestarts")
+		if err != nil {
+			return ret, err
+		}
+		ret.MaxRestarts = &maxRestarts
+	}
+	return ret, nil
+}
+
+func getInt(properties map[string]string, key string) (int, error) {
+	v, ok := properties[key]
+	if !ok || v == "" {
+		return 0, nil
+	}
+	ret, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("%s: %s must be an integer", providerName, key), v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+func (i *GrpcTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := GrpcTargetProviderConfigFromMap(properties)
+	if err != nil {
+		sLog.Errorf("  P (gRPC Target): expected GrpcTargetProviderConfig: %+v", err)
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *GrpcTargetProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func toGrpcTargetProviderConfig(config providers.IProviderConfig) (GrpcTargetProviderConfig, error) {
+	ret := GrpcTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// Init launches or connects to the plug-in, waits for it to become healthy and initializes it.
+// A background monitor then checks the plug-in health and restarts launched plug-ins that fail.
+func (i *GrpcTargetProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("gRPC Target Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	sLog.InfoCtx(ctx, "  P (gRPC Target): Init()")
+
+	updateConfig, err := toGrpcTargetProviderConfig(config)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): expected GrpcTargetProviderConfig: %+v", err)
+		return err
+	}
+	if (updateConfig.Address == "") == (updateConfig.Command == "") {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: exactly one of address and command is required", providerName), v1alpha2.BadConfig)
+		return err
+	}
+	if updateConfig.HealthCheckInterval <= 0 {
+		updateConfig.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if updateConfig.StartTimeout <= 0 {
+		updateConfig.StartTimeout = defaultStartTimeout
+	}
+	if updateConfig.MaxRestarts == nil {
+		maxRestarts := defaultMaxRestarts
+		updateConfig.MaxRestarts = &maxRestarts
+	}
+	i.Config = updateConfig
+
+	once.Do(func() {
+		if providerOperationMetrics == nil {
+			providerOperationMetrics, err = metrics.New()
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to create metrics: %+v", err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.Config.Command != "" {
+		err = i.start(ctx)
+	} else {
+		err = i.connect(i.Config.Address)
+		if err == nil {
+			err = i.waitForHealthy(ctx)
+		}
+	}
+	if err == nil {
+		err = i.initPlugin(ctx)
+	}
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to initialize plug-in: %+v", err)
+		i.shutdown()
+		return err
+	}
+	i.stop = make(chan struct{})
+	go i.monitor(i.stop)
+	return nil
+}
+
+// Close stops the health monitor and the launched plug-in process
+func (i *GrpcTargetProvider) Close() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.stop != nil {
+		close(i.stop)
+		i.stop = nil
+	}
+	i.shutdown()
+	return nil
+}
+
+func (i *GrpcTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	client := i.getClient()
+	ret := model.ValidationRule{}
+	if client == nil {
+		return ret
+	}
+	resp, err := client.GetValidationRule(ctx, &pb.GetValidationRuleRequest{})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to get validation rule: %+v", plugin.FromStatus(err))
+		return ret
+	}
+	if err = json.Unmarshal(resp.Rule, &ret); err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to deserialize validation rule: %+v", err)
+	}
+	return ret
+}
+
+func (i *GrpcTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("gRPC Target Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	sLog.InfofCtx(ctx, "  P (gRPC Target): getting artifacts: %s - %s", deployment.Instance.Spec.Scope, deployment.Instance.ObjectMeta.Name)
+
+	functionName := observ_utils.GetFunctionName()
+	startTime := time.Now().UTC()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		startTime,
+		grpcProvider,
+		metrics.PluginOperation,
+		metrics.GetOperationType,
+		functionName,
+	)
+
+	client := i.getClient()
+	if client == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: plug-in is not connected", providerName), v1alpha2.TargetPluginUnavailable)
+		return nil, err
+	}
+	req := &pb.GetRequest{}
+	if req.Deployment, err = json.Marshal(deployment); err != nil {
+		return nil, err
+	}
+	if req.References, err = json.Marshal(references); err != nil {
+		return nil, err
+	}
+	var resp *pb.GetResponse
+	resp, err = client.Get(ctx, req)
+	if err != nil {
+		err = plugin.FromStatus(err)
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to get components: %+v", err)
+		providerOperationMetrics.ProviderOperationErrors(
+			grpcProvider,
+			functionName,
+			metrics.PluginOperation,
+			metrics.GetOperationType,
+			getState(err).String(),
+		)
+		return nil, err
+	}
+	var ret []model.ComponentSpec
+	if len(resp.Components) > 0 {
+		if err = json.Unmarshal(resp.Components, &ret); err != nil {
+			err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to deserialize components", providerName), v1alpha2.DeserializeError)
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// Apply streams the deployment step to the plug-in. Progress events are logged as they arrive; the
+// final event carries the results of all components.
+func (i *GrpcTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("gRPC Target Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	sLog.InfofCtx(ctx, "  P (gRPC Target): applying artifacts: %s - %s", deployment.Instance.Spec.Scope, deployment.Instance.ObjectMeta.Name)
+
+	functionName := observ_utils.GetFunctionName()
+	startTime := time.Now().UTC()
+	defer providerOperationMetrics.ProviderOperationLatency(
+		startTime,
+		grpcProvider,
+		metrics.ApplyOperation,
+		metrics.ApplyOperationType,
+		functionName,
+	)
+
+	client := i.getClient()
+	if client == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: plug-in is not connected", providerName), v1alpha2.TargetPluginUnavailable)
+		return nil, err
+	}
+	req := &pb.ApplyRequest{IsDryRun: isDryRun}
+	if req.Deployment, err = json.Marshal(deployment); err != nil {
+		return nil, err
+	}
+	if req.Step, err = json.Marshal(step); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]model.ComponentResultSpec)
+	var stream grpc.ServerStreamingClient[pb.ApplyProgress]
+	stream, err = client.Apply(ctx, req)
+	for err == nil {
+		var progress *pb.ApplyProgress
+		progress, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		if progress.Final {
+			for name, result := range progress.Results {
+				ret[name] = fromResult(result)
+			}
+			continue
+		}
+		result := fromResult(progress.Result)
+		ret[progress.Component] = result
+		sLog.InfofCtx(ctx, "  P (gRPC Target): component %s: %s %s", progress.Component, result.Status.String(), result.Message)
+		observ_utils.EmitUserAuditsLogs(ctx, "  P (gRPC Target): component %s: %s", progress.Component, result.Status.String())
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+		return ret, nil
+	}
+	err = plugin.FromStatus(err)
+	sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to apply components: %+v", err)
+	providerOperationMetrics.ProviderOperationErrors(
+		grpcProvider,
+		functionName,
+		metrics.PluginOperation,
+		metrics.ApplyOperationType,
+		getState(err).String(),
+	)
+	return ret, err
+}
+
+func (i *GrpcTargetProvider) getClient() pb.TargetProviderClient {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return i.client
+}
+
+// start launches the plug-in process on a fresh unix socket and waits for it to become healthy
+func (i *GrpcTargetProvider) start(ctx context.Context) error {
+	dir, err := os.MkdirTemp("", "symphony-plugin-")
+	if err != nil {
+		return err
+	}
+	i.socketDir = dir
+	address := "unix://" + filepath.Join(dir, "plugin.sock")
+
+	cmd := exec.Command(i.Config.Command, i.Config.Args...)
+	cmd.Env = append(os.Environ(), plugin.AddressEnvName+"="+address)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Start(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to launch plug-in %s", providerName, i.Config.Command), v1alpha2.TargetPluginUnavailable)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	i.cmd = cmd
+	i.exited = exited
+	sLog.InfofCtx(ctx, "  P (gRPC Target): launched plug-in %s (pid %d) on %s", i.Config.Command, cmd.Process.Pid, address)
+
+	if err = i.connect(address); err != nil {
+		return err
+	}
+	return i.waitForHealthy(ctx)
+}
+
+func (i *GrpcTargetProvider) connect(address string) error {
+	// a launched plug-in needs a moment to listen, so reconnect quicker than the default one-second backoff
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  100 * time.Millisecond,
+				Multiplier: backoff.DefaultConfig.Multiplier,
+				Jitter:     backoff.DefaultConfig.Jitter,
+				MaxDelay:   5 * time.Second,
+			},
+		}))
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to connect to plug-in at %s", providerName, address), v1alpha2.TargetPluginUnavailable)
+	}
+	i.conn = conn
+	i.client = pb.NewTargetProviderClient(conn)
+	i.health = healthpb.NewHealthClient(conn)
+	return nil
+}
+
+func (i *GrpcTargetProvider) waitForHealthy(ctx context.Context) error {
+	deadline := time.Now().Add(time.Duration(i.Config.StartTimeout) * time.Second)
+	var err error
+	for time.Now().Before(deadline) {
+		if i.exited != nil {
+			select {
+			case <-i.exited:
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: plug-in %s exited during start", providerName, i.Config.Command), v1alpha2.TargetPluginUnavailable)
+			default:
+			}
+		}
+		if err = checkHealth(ctx, i.health, time.Second); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return v1alpha2.NewCOAError(err, fmt.Sprintf("%s: plug-in didn't become healthy in %d seconds", providerName, i.Config.StartTimeout), v1alpha2.TargetPluginUnavailable)
+}
+
+// initPlugin sends the configuration to the plug-in and checks the protocol version it speaks
+func (i *GrpcTargetProvider) initPlugin(ctx context.Context) error {
+	resp, err := i.client.Init(ctx, &pb.InitRequest{
+		ProtocolVersion: plugin.ProtocolVersion,
+		Config:          i.Config.Config,
+	})
+	if err != nil {
+		return plugin.FromStatus(err)
+	}
+	if resp.ProtocolVersion == 0 || resp.ProtocolVersion > plugin.ProtocolVersion {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: plug-in protocol version %d is not supported, the host supports version %d", providerName, resp.ProtocolVersion, plugin.ProtocolVersion), v1alpha2.BadConfig)
+	}
+	return nil
+}
+
+func checkHealth(ctx context.Context, client healthpb.HealthClient, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: plugin.ServiceName})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("plug-in is %s", resp.Status.String())
+	}
+	return nil
+}
+
+// monitor checks the plug-in health until stopped. Launched plug-ins that exit or fail a health check
+// are restarted up to MaxRestarts times; plug-ins the host connects to are initialized again once they
+// are healthy after a failure, as they may have been restarted by their own supervisor.
+func (i *GrpcTargetProvider) monitor(stop chan struct{}) {
+	ctx := context.TODO()
+	ticker := time.NewTicker(time.Duration(i.Config.HealthCheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		i.lock.RLock()
+		exited := i.exited
+		health := i.health
+		i.lock.RUnlock()
+		select {
+		case <-stop:
+			return
+		case <-exited:
+			i.recover(ctx, stop, "plug-in process exited")
+		case <-ticker.C:
+			if health == nil {
+				continue
+			}
+			if err := checkHealth(ctx, health, time.Duration(i.Config.HealthCheckInterval)*time.Second); err != nil {
+				i.recover(ctx, stop, fmt.Sprintf("health check failed: %s", err.Error()))
+			} else {
+				i.markHealthy(ctx, stop)
+			}
+		}
+	}
+}
+
+func (i *GrpcTargetProvider) recover(ctx context.Context, stop chan struct{}, reason string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if isStopped(stop) {
+		return
+	}
+	if i.Config.Command == "" {
+		if !i.unhealthy {
+			sLog.WarnfCtx(ctx, "  P (gRPC Target): plug-in at %s is unhealthy: %s", i.Config.Address, reason)
+		}
+		i.unhealthy = true
+		return
+	}
+	maxRestarts := *i.Config.MaxRestarts
+	if maxRestarts >= 0 && i.restarts >= maxRestarts {
+		if !i.unhealthy {
+			sLog.ErrorfCtx(ctx, "  P (gRPC Target): plug-in %s failed (%s) and reached %d restarts, giving up", i.Config.Command, reason, i.restarts)
+		}
+		i.unhealthy = true
+		i.exited = nil
+		return
+	}
+	i.restarts++
+	sLog.WarnfCtx(ctx, "  P (gRPC Target): restarting plug-in %s (%d/%d): %s", i.Config.Command, i.restarts, maxRestarts, reason)
+	i.shutdown()
+	err := i.start(ctx)
+	if err == nil {
+		err = i.initPlugin(ctx)
+	}
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to restart plug-in %s: %+v", i.Config.Command, err)
+		i.unhealthy = true
+		return
+	}
+	i.unhealthy = false
+}
+
+func (i *GrpcTargetProvider) markHealthy(ctx context.Context, stop chan struct{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if !i.unhealthy || isStopped(stop) {
+		return
+	}
+	if err := i.initPlugin(ctx); err != nil {
+		sLog.ErrorfCtx(ctx, "  P (gRPC Target): failed to initialize recovered plug-in: %+v", err)
+		return
+	}
+	sLog.InfoCtx(ctx, "  P (gRPC Target): plug-in recovered")
+	i.unhealthy = false
+}
+
+// shutdown closes the connection and stops the launched process. It must be called with the lock held.
+func (i *GrpcTargetProvider) shutdown() {
+	if i.conn != nil {
+		i.conn.Close()
+		i.conn = nil
+		i.client = nil
+		i.health = nil
+	}
+	if i.cmd != nil && i.cmd.Process != nil {
+		if err := i.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			i.cmd.Process.Kill()
+		}
+		select {
+		case <-i.exited:
+		case <-time.After(stopTimeout):
+			i.cmd.Process.Kill()
+		}
+		i.cmd = nil
+	}
+	i.exited = nil
+	if i.socketDir != "" {
+		os.RemoveAll(i.socketDir)
+		i.socketDir = ""
+	}
+}
+
+func isStopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func fromResult(result *pb.ComponentResult) model.ComponentResultSpec {
+	if result == nil {
+		return model.ComponentResultSpec{}
+	}
+	return model.ComponentResultSpec{
+		Status:  v1alpha2.State(result.Status),
+		Message: result.Message,
+	}
+}
+
+func getState(err error) v1alpha2.State {
+	var coaErr v1alpha2.COAError
+	if errors.As(err, &coaErr) {
+		return coaErr.State
+	}
+	return v1alpha2.InternalError
+}
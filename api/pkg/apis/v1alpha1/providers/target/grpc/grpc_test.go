@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/plugin"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/reference"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginEnvName = "SYMPHONY_TEST_PLUGIN"
+
+// TestMain lets the test binary act as the reference plug-in when it is launched by the host
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnvName) == "reference" {
+		if err := plugin.Serve(&reference.ReferenceTargetProvider{}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newLaunchedProvider(t *testing.T, config GrpcTargetProviderConfig) *GrpcTargetProvider {
+	t.Setenv(testPluginEnvName, "reference")
+	config.Command = os.Args[0]
+	provider := &GrpcTargetProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func testStep(action model.ComponentAction, names ...string) model.DeploymentStep {
+	step := model.DeploymentStep{}
+	for _, name := range names {
+		step.Components = append(step.Components, model.ComponentStep{
+			Action: action,
+			Component: model.ComponentSpec{
+				Name:       name,
+				Properties: map[string]interface{}{"value": name},
+			},
+		})
+	}
+	return step
+}
+
+func TestGrpcTargetProviderConfigFromMap(t *testing.T) {
+	config, err := GrpcTargetProviderConfigFromMap(map[string]string{
+		"name":                "plugin",
+		"command":             "/usr/bin/plugin",
+		"args":                "--verbose --level 2",
+		"config.region":       "west",
+		"healthCheckInterval": "5",
+		"maxRestarts":         "-1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/usr/bin/plugin", config.Command)
+	assert.Equal(t, []string{"--verbose", "--level", "2"}, config.Args)
+	assert.Equal(t, map[string]string{"region": "west"}, config.Config)
+	assert.Equal(t, 5, config.HealthCheckInterval)
+	assert.Equal(t, -1, *config.MaxRestarts)
+
+	config, err = GrpcTargetProviderConfigFromMap(map[string]string{
+		"command": "/usr/bin/plugin",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, config.MaxRestarts)
+
+	_, err = GrpcTargetProviderConfigFromMap(map[string]string{
+		"address":      "localhost:5000",
+		"startTimeout": "abc",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestInitRequiresAddressOrCommand(t *testing.T) {
+	provider := &GrpcTargetProvider{}
+	err := provider.Init(GrpcTargetProviderConfig{})
+	assert.NotNil(t, err)
+	err = provider.Init(GrpcTargetProviderConfig{Address: "localhost:5000", Command: "plugin"})
+	assert.NotNil(t, err)
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := newLaunchedProvider(t, GrpcTargetProviderConfig{})
+	conformance.ConformanceSuite(t, provider)
+}
+
+func TestApplyAndGet(t *testing.T) {
+	provider := newLaunchedProvider(t, GrpcTargetProviderConfig{})
+	deployment := model.DeploymentSpec{Instance: model.InstanceState{Spec: &model.InstanceSpec{}}}
+
+	results, err := provider.Apply(context.Background(), deployment, testStep(model.ComponentUpdate, "a", "b"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, results["a"].Status)
+	assert.Equal(t, v1alpha2.Updated, results["b"].Status)
+
+	components, err := provider.Get(context.Background(), deployment, testStep(model.ComponentUpdate, "a", "c").Components)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(components))
+	assert.Equal(t, "a", components[0].Name)
+	assert.Equal(t, "a", components[0].Properties["value"])
+
+	results, err = provider.Apply(context.Background(), deployment, testStep(model.ComponentDelete, "a"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Deleted, results["a"].Status)
+	components, err = provider.Get(context.Background(), deployment, testStep(model.ComponentUpdate, "a").Components)
+	assert.Nil(t, err)
+	assert.Empty(t, components)
+}
+
+func TestApplyValidationError(t *testing.T) {
+	provider := newLaunchedProvider(t, GrpcTargetProviderConfig{})
+	step := testStep(model.ComponentUpdate, "a")
+	step.Components[0].Component.Properties = map[string]interface{}{}
+	_, err := provider.Apply(context.Background(), model.DeploymentSpec{Instance: model.InstanceState{Spec: &model.InstanceSpec{}}}, step, false)
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.ValidateFailed, coaErr.State)
+}
+
+func TestRestartAfterExit(t *testing.T) {
+	provider := newLaunchedProvider(t, GrpcTargetProviderConfig{HealthCheckInterval: 1})
+	provider.lock.RLock()
+	provider.cmd.Process.Kill()
+	provider.lock.RUnlock()
+
+	assert.Equal(t, defaultMaxRestarts, *provider.Config.MaxRestarts)
+	assert.Eventually(t, func() bool {
+		provider.lock.RLock()
+		defer provider.lock.RUnlock()
+		return provider.restarts == 1 && !provider.unhealthy
+	}, 10*time.Second, 100*time.Millisecond)
+
+	results, err := provider.Apply(context.Background(), model.DeploymentSpec{Instance: model.InstanceState{Spec: &model.InstanceSpec{}}}, testStep(model.ComponentUpdate, "a"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, results["a"].Status)
+}
+
+func TestNoRestartWithZeroMaxRestarts(t *testing.T) {
+	provider := &GrpcTargetProvider{}
+	t.Setenv(testPluginEnvName, "reference")
+	err := provider.InitWithMap(map[string]string{
+		"command":             os.Args[0],
+		"healthCheckInterval": "1",
+		"maxRestarts":         "0",
+	})
+	assert.Nil(t, err)
+	t.Cleanup(func() { provider.Close() })
+	assert.Equal(t, 0, *provider.Config.MaxRestarts)
+
+	provider.lock.RLock()
+	provider.cmd.Process.Kill()
+	provider.lock.RUnlock()
+
+	assert.Eventually(t, func() bool {
+		provider.lock.RLock()
+		defer provider.lock.RUnlock()
+		return provider.unhealthy
+	}, 10*time.Second, 100*time.Millisecond)
+	provider.lock.RLock()
+	defer provider.lock.RUnlock()
+	assert.Equal(t, 0, provider.restarts)
+}
+
+func TestConnectToAddress(t *testing.T) {
+	address := "unix://" + filepath.Join(t.TempDir(), "plugin.sock")
+	listener, err := plugin.Listen(address)
+	assert.Nil(t, err)
+	server := plugin.NewGRPCServer(&reference.ReferenceTargetProvider{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	provider := &GrpcTargetProvider{}
+	err = provider.Init(GrpcTargetProviderConfig{Address: address})
+	assert.Nil(t, err)
+	t.Cleanup(func() { provider.Close() })
+
+	rule := provider.GetValidationRule(context.Background())
+	assert.Equal(t, []string{"value"}, rule.ComponentValidationRule.RequiredProperties)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+
+	pb "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/proto"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ToStatus converts an error returned by a provider to a gRPC status error. The Symphony state of
+// COA errors is kept in an Error detail so that the host can restore it.
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	state := v1alpha2.InternalError
+	message := err.Error()
+	var coaErr v1alpha2.COAError
+	if errors.As(err, &coaErr) {
+		state = coaErr.State
+		// the host adds the state back when it restores the COA error
+		if coaErr.Message != "" {
+			message = coaErr.Message
+			if coaErr.InnerError != nil {
+				message = fmt.Sprintf("%s (caused by: %s)", coaErr.Message, coaErr.InnerError.Error())
+			}
+		}
+	}
+	st := status.New(toCode(state), err.Error())
+	if detailed, dErr := st.WithDetails(&pb.Error{State: uint32(state), Message: message}); dErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// FromStatus converts a gRPC status error returned by a plug-in back to a COA error
+func FromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return v1alpha2.NewCOAError(err, err.Error(), v1alpha2.InternalError)
+	}
+	for _, detail := range st.Details() {
+		if e, ok := detail.(*pb.Error); ok {
+			return v1alpha2.NewCOAError(nil, e.Message, v1alpha2.State(e.State))
+		}
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.Canceled:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.TargetPluginUnavailable)
+	case codes.DeadlineExceeded:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.TimedOut)
+	case codes.InvalidArgument:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.BadRequest)
+	case codes.NotFound:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.NotFound)
+	case codes.Unimplemented:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.NotImplemented)
+	default:
+		return v1alpha2.NewCOAError(err, st.Message(), v1alpha2.InternalError)
+	}
+}
+
+func toCode(state v1alpha2.State) codes.Code {
+	switch state {
+	case v1alpha2.BadRequest, v1alpha2.BadConfig, v1alpha2.MissingConfig, v1alpha2.InvalidArgument, v1alpha2.ValidateFailed:
+		return codes.InvalidArgument
+	case v1alpha2.NotFound:
+		return codes.NotFound
+	case v1alpha2.Unauthorized, v1alpha2.Forbidden:
+		return codes.PermissionDenied
+	case v1alpha2.Conflict:
+		return codes.AlreadyExists
+	case v1alpha2.NotImplemented:
+		return codes.Unimplemented
+	case v1alpha2.TimedOut:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	err := ToStatus(v1alpha2.NewCOAError(nil, "missing property", v1alpha2.ValidateFailed))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	coaErr, ok := FromStatus(err).(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.ValidateFailed, coaErr.State)
+	assert.Equal(t, "missing property", coaErr.Message)
+}
+
+func TestStatusOfPlainError(t *testing.T) {
+	err := ToStatus(errors.New("boom"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	coaErr := FromStatus(err).(v1alpha2.COAError)
+	assert.Equal(t, v1alpha2.InternalError, coaErr.State)
+}
+
+func TestStatusWithoutDetail(t *testing.T) {
+	coaErr := FromStatus(status.Error(codes.Unavailable, "connection refused")).(v1alpha2.COAError)
+	assert.Equal(t, v1alpha2.TargetPluginUnavailable, coaErr.State)
+	coaErr = FromStatus(status.Error(codes.DeadlineExceeded, "too slow")).(v1alpha2.COAError)
+	assert.Equal(t, v1alpha2.TimedOut, coaErr.State)
+}
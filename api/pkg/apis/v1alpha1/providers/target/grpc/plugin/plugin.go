@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
+	pb "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/proto"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// ProtocolVersion is the version of the target provider plug-in protocol implemented by this package
+	ProtocolVersion uint32 = 1
+	// AddressEnvName is the environment variable a launched plug-in reads its listen address from,
+	// either unix:///path/to/socket or host:port
+	AddressEnvName = "SYMPHONY_PLUGIN_ADDRESS"
+	// ServiceName is the name the plug-in reports its health under
+	ServiceName = "symphony.target.v1.TargetProvider"
+)
+
+var pLog = logger.NewLogger("providers.target.grpc.plugin")
+
+// ProgressFunc reports the result of a component as soon as it is known
+type ProgressFunc func(component string, result model.ComponentResultSpec)
+
+// IProgressTargetProvider is implemented by providers that report component results while applying.
+// Results of providers that only implement ITargetProvider are streamed when Apply returns.
+type IProgressTargetProvider interface {
+	ApplyWithProgress(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool, progress ProgressFunc) (map[string]model.ComponentResultSpec, error)
+}
+
+// IMapInitializer is implemented by providers that are configured from a string map
+type IMapInitializer interface {
+	InitWithMap(properties map[string]string) error
+}
+
+// Server exposes an ITargetProvider through the target provider plug-in protocol
+type Server struct {
+	pb.UnimplementedTargetProviderServer
+	Provider target.ITargetProvider
+	lock     sync.Mutex
+}
+
+// NewServer wraps a target provider
+func NewServer(provider target.ITargetProvider) *Server {
+	return &Server{Provider: provider}
+}
+
+func (s *Server) Init(ctx context.Context, req *pb.InitRequest) (*pb.InitResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if req.ProtocolVersion < ProtocolVersion {
+		return nil, ToStatus(v1alpha2.NewCOAError(nil, fmt.Sprintf("protocol version %d is not supported, the plug-in requires version %d", req.ProtocolVersion, ProtocolVersion), v1alpha2.BadConfig))
+	}
+	config := req.Config
+	if config == nil {
+		config = map[string]string{}
+	}
+	var err error
+	if initializer, ok := s.Provider.(IMapInitializer); ok {
+		err = initializer.InitWithMap(config)
+	} else {
+		err = s.Provider.Init(config)
+	}
+	if err != nil {
+		pLog.ErrorfCtx(ctx, "failed to initialize provider: %+v", err)
+		return nil, ToStatus(err)
+	}
+	return &pb.InitResponse{ProtocolVersion: ProtocolVersion}, nil
+}
+
+func (s *Server) GetValidationRule(ctx context.Context, req *pb.GetValidationRuleRequest) (*pb.GetValidationRuleResponse, error) {
+	data, err := json.Marshal(s.Provider.GetValidationRule(ctx))
+	if err != nil {
+		return nil, ToStatus(v1alpha2.NewCOAError(err, "failed to serialize validation rule", v1alpha2.SerializationError))
+	}
+	return &pb.GetValidationRuleResponse{Rule: data}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	var deployment model.DeploymentSpec
+	var references []model.ComponentStep
+	if err := unmarshalField(req.Deployment, &deployment, "deployment"); err != nil {
+		return nil, ToStatus(err)
+	}
+	if err := unmarshalField(req.References, &references, "references"); err != nil {
+		return nil, ToStatus(err)
+	}
+	components, err := s.Provider.Get(ctx, deployment, references)
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+	data, err := json.Marshal(components)
+	if err != nil {
+		return nil, ToStatus(v1alpha2.NewCOAError(err, "failed to serialize components", v1alpha2.SerializationError))
+	}
+	return &pb.GetResponse{Components: data}, nil
+}
+
+func (s *Server) Apply(req *pb.ApplyRequest, stream grpc.ServerStreamingServer[pb.ApplyProgress]) error {
+	ctx := stream.Context()
+	var deployment model.DeploymentSpec
+	var step model.DeploymentStep
+	if err := unmarshalField(req.Deployment, &deployment, "deployment"); err != nil {
+		return ToStatus(err)
+	}
+	if err := unmarshalField(req.Step, &step, "step"); err != nil {
+		return ToStatus(err)
+	}
+
+	// stream.Send isn't safe for concurrent use, and providers may report from several goroutines
+	var sendLock sync.Mutex
+	progress := func(component string, result model.ComponentResultSpec) {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		if err := stream.Send(&pb.ApplyProgress{Component: component, Result: toResult(result)}); err != nil {
+			pLog.WarnfCtx(ctx, "failed to send progress of component %s: %+v", component, err)
+		}
+	}
+
+	var results map[string]model.ComponentResultSpec
+	var err error
+	if provider, ok := s.Provider.(IProgressTargetProvider); ok {
+		results, err = provider.ApplyWithProgress(ctx, deployment, step, req.IsDryRun, progress)
+	} else {
+		results, err = s.Provider.Apply(ctx, deployment, step, req.IsDryRun)
+		for _, component := range step.Components {
+			if result, ok := results[component.Component.Name]; ok {
+				progress(component.Component.Name, result)
+			}
+		}
+	}
+
+	final := &pb.ApplyProgress{Final: true, Results: map[string]*pb.ComponentResult{}}
+	for name, result := range results {
+		final.Results[name] = toResult(result)
+	}
+	sendLock.Lock()
+	sendErr := stream.Send(final)
+	sendLock.Unlock()
+	if err != nil {
+		return ToStatus(err)
+	}
+	return sendErr
+}
+
+// Serve runs a target provider as a plug-in on the address in the SYMPHONY_PLUGIN_ADDRESS environment
+// variable until the process is interrupted or terminated
+func Serve(provider target.ITargetProvider) error {
+	address := os.Getenv(AddressEnvName)
+	if address == "" {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s is not set", AddressEnvName), v1alpha2.MissingConfig)
+	}
+	listener, err := Listen(address)
+	if err != nil {
+		return err
+	}
+	server := NewGRPCServer(provider)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.GracefulStop()
+	}()
+	pLog.Infof("serving target provider plug-in on %s", address)
+	return server.Serve(listener)
+}
+
+// NewGRPCServer creates a gRPC server exposing a target provider and the standard health service
+func NewGRPCServer(provider target.ITargetProvider) *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterTargetProviderServer(server, NewServer(provider))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	return server
+}
+
+// Listen opens a listener on a plug-in address. Stale unix sockets are removed first.
+func Listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", address)
+}
+
+func unmarshalField(data []byte, v interface{}, field string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to deserialize %s", field), v1alpha2.DeserializeError)
+	}
+	return nil
+}
+
+func toResult(result model.ComponentResultSpec) *pb.ComponentResult {
+	return &pb.ComponentResult{Status: uint32(result.Status), Message: result.Message}
+}
@@ -0,0 +1,25 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// The reference target provider plug-in. Build it and point a providers.target.grpc binding at it:
+//
+//	go build -o reference-plugin ./pkg/apis/v1alpha1/providers/target/grpc/reference/cmd
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/plugin"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/reference"
+)
+
+func main() {
+	if err := plugin.Serve(&reference.ReferenceTargetProvider{}); err != nil {
+		fmt.Fprintf(os.Stderr, "reference plug-in failed: %+v\n", err)
+		os.Exit(1)
+	}
+}
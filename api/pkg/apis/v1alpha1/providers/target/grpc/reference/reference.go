@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+// Package reference is the reference implementation of a target provider plug-in. It keeps components
+// in memory and is meant as a starting point for plug-in authors and as the subject of the protocol
+// conformance run.
+package reference
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/grpc/plugin"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+const providerName = "P (Reference Plug-in)"
+
+type ReferenceTargetProviderConfig struct {
+	Name string `json:"name"`
+}
+
+// ReferenceTargetProvider stores the components it is given. Components require a "value" property.
+type ReferenceTargetProvider struct {
+	Config     ReferenceTargetProviderConfig
+	components map[string]model.ComponentSpec
+	lock       sync.Mutex
+}
+
+func ReferenceTargetProviderConfigFromMap(properties map[string]string) (ReferenceTargetProviderConfig, error) {
+	return ReferenceTargetProviderConfig{Name: properties["name"]}, nil
+}
+
+func (r *ReferenceTargetProvider) InitWithMap(properties map[string]string) error {
+	config, err := ReferenceTargetProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return r.Init(config)
+}
+
+func (r *ReferenceTargetProvider) Init(config providers.IProviderConfig) error {
+	ret := ReferenceTargetProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Config = ret
+	if r.components == nil {
+		r.components = make(map[string]model.ComponentSpec)
+	}
+	return nil
+}
+
+func (r *ReferenceTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		AllowSidecar: false,
+		ComponentValidationRule: model.ComponentValidationRule{
+			RequiredProperties: []string{"value"},
+			OptionalProperties: []string{},
+			RequiredMetadata:   []string{},
+			OptionalMetadata:   []string{},
+		},
+	}
+}
+
+func (r *ReferenceTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ret := make([]model.ComponentSpec, 0)
+	for _, reference := range references {
+		if component, ok := r.components[reference.Component.Name]; ok {
+			ret = append(ret, component)
+		}
+	}
+	return ret, nil
+}
+
+func (r *ReferenceTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	return r.ApplyWithProgress(ctx, deployment, step, isDryRun, nil)
+}
+
+// ApplyWithProgress applies the components one by one and reports each result as soon as it is known
+func (r *ReferenceTargetProvider) ApplyWithProgress(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool, progress plugin.ProgressFunc) (map[string]model.ComponentResultSpec, error) {
+	components := step.GetComponents()
+	if err := r.GetValidationRule(ctx).Validate(components); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("%s: the rule validation failed", providerName), v1alpha2.ValidateFailed)
+	}
+	if isDryRun {
+		return nil, nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ret := step.PrepareResultMap()
+	for _, component := range step.Components {
+		result := model.ComponentResultSpec{}
+		if component.Action == model.ComponentDelete {
+			delete(r.components, component.Component.Name)
+			result.Status = v1alpha2.Deleted
+			result.Message = "component deleted"
+		} else {
+			r.components[component.Component.Name] = component.Component
+			result.Status = v1alpha2.Updated
+			result.Message = "component updated"
+		}
+		ret[component.Component.Name] = result
+		if progress != nil {
+			progress(component.Component.Name, result)
+		}
+	}
+	return ret, nil
+}
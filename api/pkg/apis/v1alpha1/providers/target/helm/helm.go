@@ -23,6 +23,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/metahelper"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -53,12 +54,13 @@ var (
 )
 
 const (
-	defaultNamespace = "default"
-	tempChartDir     = "/tmp/symphony/charts"
-	helmDriver       = "secret"
-	helm             = "helm"
-	providerName     = "P (Helm Target)"
-	loggerName       = "providers.target.helm"
+	defaultNamespace   = "default"
+	defaultWaitTimeout = 5 * time.Minute
+	tempChartDir       = "/tmp/symphony/charts"
+	helmDriver         = "secret"
+	helm               = "helm"
+	providerName       = "P (Helm Target)"
+	loggerName         = "providers.target.helm"
 )
 
 type (
@@ -75,12 +77,15 @@ type (
 		Config        HelmTargetProviderConfig
 		Context       *contexts.ManagerContext
 		MetaPopulator metahelper.MetaPopulator
+		ApiClient     api_utils.ApiClient
 	}
 	// HelmProperty is the property for the Helm chart
 	HelmProperty struct {
 		Chart       HelmChartProperty      `json:"chart"`
 		Values      map[string]interface{} `json:"values,omitempty"`
+		ValuesFrom  []HelmValuesReference  `json:"valuesFrom,omitempty"`
 		ReleaseName string                 `json:"releaseName,omitempty"`
+		Test        *HelmTestProperty      `json:"test,omitempty"`
 	}
 	// HelmChartProperty is the property for the Helm Charts
 	HelmChartProperty struct {
@@ -88,10 +93,16 @@ type (
 		Name     string `json:"name,omitempty"`
 		Version  string `json:"version"`
 		Wait     bool   `json:"wait"`
+		Atomic   bool   `json:"atomic,omitempty"`
 		Timeout  string `json:"timeout,omitempty"`
 		Username string `json:"username,omitempty"`
 		Password string `json:"password,omitempty"`
 	}
+	// HelmTestProperty is the property for running the chart tests after the release is deployed
+	HelmTestProperty struct {
+		Enabled bool   `json:"enabled"`
+		Timeout string `json:"timeout,omitempty"`
+	}
 )
 
 // HelmTargetProviderConfigFromMap converts a map to a HelmTargetProviderConfig
@@ -329,18 +340,28 @@ func (i *HelmTargetProvider) Get(ctx context.Context, deployment model.Deploymen
 					repo = parts[0][9:]
 					name = parts[1][9:]
 				}
-				ret = append(ret, model.ComponentSpec{
-					Name: component.Component.Name,
-					Type: "helm.v3",
-					Properties: map[string]interface{}{
-						"releaseName": res.Name,
-						"chart": map[string]string{
-							"repo":    repo,
-							"name":    name,
-							"version": res.Chart.Metadata.Version,
-						},
-						"values": res.Config,
+				properties := map[string]interface{}{
+					"releaseName": res.Name,
+					"chart": map[string]string{
+						"repo":    repo,
+						"name":    name,
+						"version": res.Chart.Metadata.Version,
 					},
+					"values": res.Config,
+				}
+				if helmProp != nil && len(helmProp.ValuesFrom) > 0 {
+					// the release holds the merged values. When they still match the merge of the catalogs and the
+					// inline values, report the component as is; otherwise the difference triggers an upgrade.
+					merged, mergeErr := i.getMergedValues(ctx, helmProp, deployment.Instance.Spec.Scope)
+					if mergeErr == nil && !propChange(merged, res.Config) {
+						properties["values"] = component.Component.Properties["values"]
+						properties["valuesFrom"] = component.Component.Properties["valuesFrom"]
+					}
+				}
+				ret = append(ret, model.ComponentSpec{
+					Name:       component.Component.Name,
+					Type:       "helm.v3",
+					Properties: properties,
 				})
 			}
 		}
@@ -354,13 +375,14 @@ func (*HelmTargetProvider) GetValidationRule(ctx context.Context) model.Validati
 		AllowSidecar: false,
 		ComponentValidationRule: model.ComponentValidationRule{
 			RequiredProperties:    []string{"chart"},
-			OptionalProperties:    []string{"values"},
+			OptionalProperties:    []string{"values", "valuesFrom", "test"},
 			RequiredComponentType: "",
 			RequiredMetadata:      []string{},
 			OptionalMetadata:      []string{},
 			ChangeDetectionProperties: []model.PropertyDesc{
 				{Name: "chart", IgnoreCase: false, SkipIfMissing: true}, //TODO: deep change detection on interface{}
 				{Name: "values", PropChanged: propChange},
+				{Name: "valuesFrom", PropChanged: propChange},
 			},
 		},
 	}
@@ -451,8 +473,8 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 	}
 
 	if isDryRun {
-		sLog.DebugCtx(ctx, "  P (Helm Target): dryRun is enabled, skipping apply")
-		return nil, nil
+		sLog.DebugCtx(ctx, "  P (Helm Target): dryRun is enabled, previewing changes")
+		return i.previewApply(ctx, deployment, step), nil
 	}
 
 	ret := step.PrepareResultMap()
@@ -535,6 +557,25 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				return ret, err
 			}
 
+			var values map[string]interface{}
+			values, err = i.getMergedValues(ctx, helmProp, deployment.Instance.Spec.Scope)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "  P (Helm Target): failed to get chart values: %+v", err)
+				err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to get chart values", providerName), v1alpha2.GetHelmPropertyFailed)
+				ret[component.Component.Name] = model.ComponentResultSpec{
+					Status:  v1alpha2.UpdateFailed,
+					Message: err.Error(),
+				}
+				providerOperationMetrics.ProviderOperationErrors(
+					helm,
+					functionName,
+					metrics.HelmValuesOperation,
+					metrics.ApplyOperationType,
+					v1alpha2.GetHelmPropertyFailed.String(),
+				)
+				return ret, err
+			}
+
 			chart.Metadata.Tags = "SYM-REPO:" + helmProp.Chart.Repo + ";SYM-NAME:" + helmProp.Chart.Name //this is not used by Helm SDK, we use this to carry repo info
 
 			postRender := &PostRenderer{
@@ -560,13 +601,10 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 			utils.EmitUserAuditsLogs(ctx, "  P (Helm Target): Applying chart, releaseName: %s, defined in component: %s, chart: {repo: %s, name: %s, version: %s}, namespace: %s", releaseName, component.Component.Name, helmProp.Chart.Repo, helmProp.Chart.Name, helmProp.Chart.Version, deployment.Instance.Spec.Scope)
 			if releaseExists {
 				sLog.InfofCtx(ctx, "  P (Helm Target): Chart upgrade started. Details - Release Name: %s, Component Name: %s", releaseName, component.Component.Name)
-				if _, err = upgradeClient.Run(releaseName, chart, helmProp.Values); err != nil {
+				if _, err = upgradeClient.Run(releaseName, chart, values); err != nil {
 					sLog.InfofCtx(ctx, "  P (Helm Target): failed to upgrade: %+v", err)
+					ret[component.Component.Name] = getApplyFailedResult(err, "upgrade")
 					err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to upgrade chart", providerName), v1alpha2.HelmActionFailed)
-					ret[component.Component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.UpdateFailed,
-						Message: err.Error(),
-					}
 					providerOperationMetrics.ProviderOperationErrors(
 						helm,
 						functionName,
@@ -578,13 +616,10 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				}
 			} else {
 				sLog.InfofCtx(ctx, "  P (Helm Target): Chart installation started. Details - Release Name: %s, Component Name: %s", releaseName, component.Component.Name)
-				if _, err := installClient.Run(chart, helmProp.Values); err != nil {
+				if _, err := installClient.Run(chart, values); err != nil {
 					sLog.ErrorfCtx(ctx, "  P (Helm Target): failed to install: %+v", err)
+					ret[component.Component.Name] = getApplyFailedResult(err, "install")
 					err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to install chart", providerName), v1alpha2.HelmActionFailed)
-					ret[component.Component.Name] = model.ComponentResultSpec{
-						Status:  v1alpha2.UpdateFailed,
-						Message: err.Error(),
-					}
 					providerOperationMetrics.ProviderOperationErrors(
 						helm,
 						functionName,
@@ -597,6 +632,25 @@ func (i *HelmTargetProvider) Apply(ctx context.Context, deployment model.Deploym
 				sLog.InfofCtx(ctx, "  P (Helm Target): Chart installation completed successfully. Details - Release Name: %s, Component Name: %s", releaseName, component.Component.Name)
 			}
 
+			if helmProp.Test != nil && helmProp.Test.Enabled {
+				if err = runReleaseTests(ctx, actionConfig, releaseName, helmProp.Test); err != nil {
+					sLog.ErrorfCtx(ctx, "  P (Helm Target): chart tests failed: %+v", err)
+					err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: chart tests of release %s failed", providerName, releaseName), v1alpha2.HelmChartTestFailed)
+					ret[component.Component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.HelmChartTestFailed,
+						Message: err.Error(),
+					}
+					providerOperationMetrics.ProviderOperationErrors(
+						helm,
+						functionName,
+						metrics.HelmTestOperation,
+						metrics.ApplyOperationType,
+						v1alpha2.HelmChartTestFailed.String(),
+					)
+					return ret, err
+				}
+			}
+
 			sLog.InfofCtx(ctx, "  P (Helm Target): apply chart successfully. Details - Release Name: %s, Component Name: %s", releaseName, component.Component.Name)
 			ret[component.Component.Name] = model.ComponentResultSpec{
 				Status:  v1alpha2.Updated,
@@ -775,13 +829,12 @@ func configureInstallClient(ctx context.Context, name string, componentProps *He
 	}
 
 	installClient.Wait = componentProps.Wait
-	if componentProps.Timeout != "" {
-		duration, err := convertTimeout(ctx, componentProps.Timeout)
-		if err != nil {
-			return nil, err
-		}
-		installClient.Timeout = duration
+	installClient.Atomic = componentProps.Atomic
+	duration, err := getWaitTimeout(ctx, componentProps)
+	if err != nil {
+		return nil, err
 	}
+	installClient.Timeout = duration
 
 	installClient.IsUpgrade = true
 	installClient.CreateNamespace = true
@@ -819,13 +872,12 @@ func configureUpgradeClient(ctx context.Context, componentProps *HelmChartProper
 	sLog.InfofCtx(ctx, "  P (Helm Target): start configuring upgrade client in the namespace %s", deployment.Instance.Spec.Scope)
 	upgradeClient := action.NewUpgrade(config)
 	upgradeClient.Wait = componentProps.Wait
-	if componentProps.Timeout != "" {
-		duration, err := convertTimeout(ctx, componentProps.Timeout)
-		if err != nil {
-			return nil, err
-		}
-		upgradeClient.Timeout = duration
+	upgradeClient.Atomic = componentProps.Atomic
+	duration, err := getWaitTimeout(ctx, componentProps)
+	if err != nil {
+		return nil, err
 	}
+	upgradeClient.Timeout = duration
 	if deployment.Instance.Spec.Scope == "" {
 		upgradeClient.Namespace = constants.DefaultScope
 	} else {
@@ -853,6 +905,61 @@ func configureUninstallClient(ctx context.Context, componentProps *HelmChartProp
 	return uninstallClient, nil
 }
 
+// getWaitTimeout returns the timeout of an install or upgrade. Waiting, which atomic implies,
+// falls back to the Helm CLI default when no timeout is given.
+func getWaitTimeout(ctx context.Context, componentProps *HelmChartProperty) (time.Duration, error) {
+	if componentProps.Timeout != "" {
+		return convertTimeout(ctx, componentProps.Timeout)
+	}
+	if componentProps.Wait || componentProps.Atomic {
+		return defaultWaitTimeout, nil
+	}
+	return 0, nil
+}
+
+// isTimeoutError checks whether Helm gave up waiting for the release resources
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return strings.Contains(err.Error(), "timed out waiting for the condition") || strings.Contains(err.Error(), "context deadline exceeded")
+}
+
+// getApplyFailedResult converts an install or upgrade error into a component result. Timeouts are reported
+// as such, along with whether the release has been rolled back because atomic is set.
+func getApplyFailedResult(err error, operation string) model.ComponentResultSpec {
+	if !isTimeoutError(err) {
+		return model.ComponentResultSpec{
+			Status:  v1alpha2.UpdateFailed,
+			Message: v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to %s chart", providerName, operation), v1alpha2.HelmActionFailed).Error(),
+		}
+	}
+	message := fmt.Sprintf("%s: timed out waiting for chart %s", providerName, operation)
+	if strings.Contains(err.Error(), "due to atomic being set") {
+		message = fmt.Sprintf("%s, the release has been rolled back", message)
+	}
+	return model.ComponentResultSpec{
+		Status:  v1alpha2.TimedOut,
+		Message: fmt.Sprintf("%s: %s", message, err.Error()),
+	}
+}
+
+// runReleaseTests runs the test hooks of a release, as helm test does
+func runReleaseTests(ctx context.Context, config *action.Configuration, releaseName string, test *HelmTestProperty) error {
+	sLog.InfofCtx(ctx, "  P (Helm Target): running chart tests of release %s", releaseName)
+	testClient := action.NewReleaseTesting(config)
+	testClient.Timeout = defaultWaitTimeout
+	if test.Timeout != "" {
+		duration, err := convertTimeout(ctx, test.Timeout)
+		if err != nil {
+			return err
+		}
+		testClient.Timeout = duration
+	}
+	_, err := testClient.Run(releaseName)
+	return err
+}
+
 func convertTimeout(ctx context.Context, timeout string) (time.Duration, error) {
 	duration, err := time.ParseDuration(timeout)
 	if err != nil {
@@ -1098,3 +1098,142 @@ func TestHelmTargetProviderPullChartUnauthorizedError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.True(t, isUnauthorized(err))
 }
+
+// TestMergeValues tests that later values take precedence and nested maps are merged
+func TestMergeValues(t *testing.T) {
+	base := map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.0",
+		},
+		"replicas": 1,
+	}
+	override := map[string]interface{}{
+		"image": map[string]interface{}{
+			"tag": "2.0",
+		},
+		"service": "LoadBalancer",
+	}
+	merged := mergeValues(base, override)
+	assert.Equal(t, map[string]interface{}{
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "2.0",
+		},
+		"replicas": 1,
+		"service":  "LoadBalancer",
+	}, merged)
+	// inputs are not modified
+	assert.Equal(t, "1.0", base["image"].(map[string]interface{})["tag"])
+}
+
+// TestGetMergedValuesWithoutValuesFrom tests that inline values are used as is without valuesFrom
+func TestGetMergedValuesWithoutValuesFrom(t *testing.T) {
+	provider := HelmTargetProvider{}
+	values := map[string]interface{}{"replicas": 2}
+	merged, err := provider.getMergedValues(context.Background(), &HelmProperty{Values: values}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, values, merged)
+}
+
+// TestSelectValues tests selecting a nested key of catalog properties
+func TestSelectValues(t *testing.T) {
+	properties := map[string]interface{}{
+		"web": map[string]interface{}{
+			"prod": map[string]interface{}{
+				"replicas": 3,
+			},
+			"name": "web",
+		},
+	}
+	values, err := selectValues(properties, HelmValuesReference{Catalog: "config", Key: "web.prod"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"replicas": 3}, values)
+
+	_, err = selectValues(properties, HelmValuesReference{Catalog: "config", Key: "web.dev"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	_, err = selectValues(properties, HelmValuesReference{Catalog: "config", Key: "web.name"})
+	assert.NotNil(t, err)
+}
+
+// TestDiffManifests tests the per-object manifest diff used by dry runs
+func TestDiffManifests(t *testing.T) {
+	current := `---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: ClusterIP
+---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  mode: dev
+`
+	desired := `---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  mode: prod
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: ClusterIP
+`
+	diff, err := diffManifests(current, desired)
+	assert.Nil(t, err)
+	assert.Contains(t, diff, "ConfigMap//web-config")
+	assert.Contains(t, diff, "-  mode: dev")
+	assert.Contains(t, diff, "+  mode: prod")
+	assert.NotContains(t, diff, "Service//web")
+
+	diff, err = diffManifests(current, current)
+	assert.Nil(t, err)
+	assert.Equal(t, "", diff)
+
+	diff, err = diffManifests("", desired)
+	assert.Nil(t, err)
+	assert.Contains(t, diff, "+kind: Service")
+}
+
+// TestGetApplyFailedResult tests that timeouts are reported with the TimedOut status
+func TestGetApplyFailedResult(t *testing.T) {
+	result := getApplyFailedResult(fmt.Errorf("release web failed, and has been rolled back due to atomic being set: %w", context.DeadlineExceeded), "upgrade")
+	assert.Equal(t, v1alpha2.TimedOut, result.Status)
+	assert.Contains(t, result.Message, "rolled back")
+
+	result = getApplyFailedResult(fmt.Errorf("timed out waiting for the condition"), "install")
+	assert.Equal(t, v1alpha2.TimedOut, result.Status)
+	assert.NotContains(t, result.Message, "rolled back")
+
+	result = getApplyFailedResult(fmt.Errorf("chart requires kubeVersion"), "install")
+	assert.Equal(t, v1alpha2.UpdateFailed, result.Status)
+}
+
+// TestGetWaitTimeout tests the timeout used by install and upgrade
+func TestGetWaitTimeout(t *testing.T) {
+	timeout, err := getWaitTimeout(context.Background(), &HelmChartProperty{})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), timeout)
+
+	timeout, err = getWaitTimeout(context.Background(), &HelmChartProperty{Atomic: true})
+	assert.Nil(t, err)
+	assert.Equal(t, defaultWaitTimeout, timeout)
+
+	timeout, err = getWaitTimeout(context.Background(), &HelmChartProperty{Wait: true, Timeout: "30s"})
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/pmezard/go-difflib/difflib"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
+)
+
+// previewApply renders the charts of a deployment step without installing them and reports, per component,
+// the difference between the rendered manifests and the manifests of the current release.
+// Preview is best effort: failures are reported in the component results and never fail the dry run.
+func (i *HelmTargetProvider) previewApply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep) map[string]model.ComponentResultSpec {
+	ret := step.PrepareResultMap()
+	actionConfig, err := i.createActionConfig(ctx, deployment.Instance.Spec.Scope)
+	if err != nil {
+		sLog.InfofCtx(ctx, "  P (Helm Target): dry run can't create action config, skipping preview: %+v", err)
+		return ret
+	}
+	for _, component := range step.Components {
+		helmProp, err := getHelmPropertyFromComponent(component.Component)
+		releaseName := GetReleaseName(component.Component, helmProp)
+		if component.Action != model.ComponentUpdate {
+			ret[component.Component.Name] = model.ComponentResultSpec{
+				Status:  v1alpha2.Untouched,
+				Message: fmt.Sprintf("Dry run. Release %s would be uninstalled", releaseName),
+			}
+			continue
+		}
+		var message string
+		if err == nil {
+			message, err = i.previewComponent(ctx, actionConfig, &deployment, releaseName, helmProp)
+		}
+		if err != nil {
+			sLog.InfofCtx(ctx, "  P (Helm Target): failed to preview component %s: %+v", component.Component.Name, err)
+			message = fmt.Sprintf("Dry run. Failed to preview release %s: %s", releaseName, err.Error())
+		}
+		ret[component.Component.Name] = model.ComponentResultSpec{
+			Status:  v1alpha2.Untouched,
+			Message: message,
+		}
+	}
+	return ret
+}
+
+// previewComponent renders a chart with a dry run install or upgrade and diffs it against the current release
+func (i *HelmTargetProvider) previewComponent(ctx context.Context, actionConfig *action.Configuration, deployment *model.DeploymentSpec, releaseName string, helmProp *HelmProperty) (string, error) {
+	values, err := i.getMergedValues(ctx, helmProp, deployment.Instance.Spec.Scope)
+	if err != nil {
+		return "", err
+	}
+	fileName, err := i.pullChart(ctx, &helmProp.Chart)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(fileName)
+	chart, err := loader.Load(fileName)
+	if err != nil {
+		return "", err
+	}
+	postRender := &PostRenderer{
+		instance:  deployment.Instance,
+		populator: i.MetaPopulator,
+	}
+
+	current := ""
+	var rendered *release.Release
+	existing, err := action.NewGet(actionConfig).Run(releaseName)
+	switch {
+	case err == nil:
+		current = existing.Manifest
+		var upgradeClient *action.Upgrade
+		upgradeClient, err = configureUpgradeClient(ctx, &helmProp.Chart, deployment, actionConfig, postRender)
+		if err != nil {
+			return "", err
+		}
+		upgradeClient.DryRun = true
+		upgradeClient.DryRunOption = "server"
+		rendered, err = upgradeClient.Run(releaseName, chart, values)
+	case errors.Is(err, driver.ErrReleaseNotFound):
+		var installClient *action.Install
+		installClient, err = configureInstallClient(ctx, releaseName, &helmProp.Chart, deployment, actionConfig, postRender)
+		if err != nil {
+			return "", err
+		}
+		installClient.DryRun = true
+		installClient.DryRunOption = "server"
+		installClient.IsUpgrade = false
+		rendered, err = installClient.Run(chart, values)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := diffManifests(current, rendered.Manifest)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" {
+		return fmt.Sprintf("Dry run. Release %s has no changes", releaseName), nil
+	}
+	return fmt.Sprintf("Dry run. Changes to release %s:\n%s", releaseName, diff), nil
+}
+
+// diffManifests returns a unified diff between two multi-document manifests, object by object.
+// Objects are matched by kind, namespace and name, so reordering documents is not reported as a change.
+func diffManifests(current string, desired string) (string, error) {
+	currentObjects, err := splitManifestObjects(current)
+	if err != nil {
+		return "", err
+	}
+	desiredObjects, err := splitManifestObjects(desired)
+	if err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(currentObjects)+len(desiredObjects))
+	for key := range currentObjects {
+		keys = append(keys, key)
+	}
+	for key := range desiredObjects {
+		if _, ok := currentObjects[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		if currentObjects[key] == desiredObjects[key] {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(currentObjects[key]),
+			B:        difflib.SplitLines(desiredObjects[key]),
+			FromFile: key,
+			ToFile:   key,
+			Context:  3,
+		})
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(diff)
+	}
+	return builder.String(), nil
+}
+
+// splitManifestObjects splits a manifest into objects keyed by kind/namespace/name
+func splitManifestObjects(manifest string) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var head struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil {
+			return nil, err
+		}
+		if head.Kind == "" {
+			continue
+		}
+		ret[fmt.Sprintf("%s/%s/%s", head.Kind, head.Metadata.Namespace, head.Metadata.Name)] = strings.TrimSpace(doc) + "\n"
+	}
+	return ret, nil
+}
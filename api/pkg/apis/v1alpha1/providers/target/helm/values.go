@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// HelmValuesReference points to chart values stored in a catalog
+type HelmValuesReference struct {
+	// Catalog is the name of the catalog holding the values
+	Catalog string `json:"catalog"`
+	// Key optionally selects a nested property of the catalog, with "." separating levels
+	Key string `json:"key,omitempty"`
+	// Optional skips the reference when the catalog or key doesn't exist
+	Optional bool `json:"optional,omitempty"`
+}
+
+// getMergedValues merges the values of a chart. Catalogs referenced in valuesFrom are merged in order,
+// later catalogs taking precedence over earlier ones, and inline values take precedence over all catalogs.
+func (i *HelmTargetProvider) getMergedValues(ctx context.Context, helmProp *HelmProperty, namespace string) (map[string]interface{}, error) {
+	if len(helmProp.ValuesFrom) == 0 {
+		return helmProp.Values, nil
+	}
+	ret := map[string]interface{}{}
+	for _, reference := range helmProp.ValuesFrom {
+		values, err := i.getCatalogValues(ctx, reference, namespace)
+		if err != nil {
+			if reference.Optional {
+				sLog.InfofCtx(ctx, "  P (Helm Target): skipping optional values from catalog %s: %+v", reference.Catalog, err)
+				continue
+			}
+			return nil, err
+		}
+		ret = mergeValues(ret, values)
+	}
+	return mergeValues(ret, helmProp.Values), nil
+}
+
+// getCatalogValues reads the evaluated properties of a catalog, optionally narrowed down to a key
+func (i *HelmTargetProvider) getCatalogValues(ctx context.Context, reference HelmValuesReference, namespace string) (map[string]interface{}, error) {
+	if reference.Catalog == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: valuesFrom requires a catalog name", providerName), v1alpha2.BadConfig)
+	}
+	if i.ApiClient == nil {
+		client, err := api_utils.GetApiClient()
+		if err != nil {
+			sLog.ErrorfCtx(ctx, "  P (Helm Target): failed to create api client: %+v", err)
+			return nil, err
+		}
+		i.ApiClient = client
+	}
+	if namespace == "" {
+		namespace = constants.DefaultScope
+	}
+	user, password := "", ""
+	if i.Context != nil {
+		user = i.Context.SiteInfo.CurrentSite.Username
+		password = i.Context.SiteInfo.CurrentSite.Password
+	}
+	properties, err := i.ApiClient.GetParsedCatalogProperties(ctx, reference.Catalog, namespace, user, password)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Helm Target): failed to get values from catalog %s: %+v", reference.Catalog, err)
+		return nil, err
+	}
+	return selectValues(properties, reference)
+}
+
+// selectValues narrows catalog properties down to the key of a values reference
+func selectValues(properties map[string]interface{}, reference HelmValuesReference) (map[string]interface{}, error) {
+	if reference.Key == "" {
+		return properties, nil
+	}
+	var current interface{} = properties
+	for _, part := range strings.Split(reference.Key, ".") {
+		currentMap, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: key %s of catalog %s is not an object", providerName, reference.Key, reference.Catalog), v1alpha2.BadConfig)
+		}
+		current, ok = currentMap[part]
+		if !ok {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: key %s is not found in catalog %s", providerName, reference.Key, reference.Catalog), v1alpha2.NotFound)
+		}
+	}
+	ret, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: key %s of catalog %s is not an object", providerName, reference.Key, reference.Catalog), v1alpha2.BadConfig)
+	}
+	return ret, nil
+}
+
+// mergeValues deep merges override into base. Nested maps are merged recursively, any other
+// value in override replaces the value in base. Neither input is modified.
+func mergeValues(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		ret[k] = v
+	}
+	for k, v := range override {
+		if overrideMap, ok := v.(map[string]interface{}); ok {
+			if baseMap, ok := ret[k].(map[string]interface{}); ok {
+				ret[k] = mergeValues(baseMap, overrideMap)
+				continue
+			}
+		}
+		ret[k] = v
+	}
+	return ret
+}
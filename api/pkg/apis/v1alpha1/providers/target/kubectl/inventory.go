@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kubectl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	inventoryPrefix       = "symphony-inventory-"
+	inventoryDataKey      = "objects"
+	inventoryComponentKey = "symphony/component"
+	inventoryInstanceKey  = "symphony/instance"
+)
+
+// objectReference identifies an object applied on behalf of a component
+type objectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func toObjectReference(obj *unstructured.Unstructured) objectReference {
+	return objectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// getInventoryName returns the name of the ConfigMap that records the objects applied for a component.
+// A hash is used so that the name is always a valid resource name regardless of instance and component names.
+func getInventoryName(instance string, component string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", instance, component)))
+	return fmt.Sprintf("%s%x", inventoryPrefix, hash[:10])
+}
+
+// getStaleObjects returns the objects in the previous inventory that are not part of the current one
+func getStaleObjects(previous []objectReference, current []objectReference) []objectReference {
+	applied := make(map[objectReference]bool, len(current))
+	for _, ref := range current {
+		applied[ref] = true
+	}
+	ret := make([]objectReference, 0)
+	for _, ref := range previous {
+		if !applied[ref] {
+			ret = append(ret, ref)
+		}
+	}
+	return ret
+}
+
+// readInventory reads the objects recorded for a component. A missing inventory yields an empty list.
+func (i *KubectlTargetProvider) readInventory(ctx context.Context, namespace string, instance string, component string) ([]objectReference, error) {
+	ret := make([]objectReference, 0)
+	cm, err := i.Client.CoreV1().ConfigMaps(namespace).Get(ctx, getInventoryName(instance, component), metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return ret, nil
+		}
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to read inventory of component %s: %+v", component, err)
+		return nil, err
+	}
+	if data, ok := cm.Data[inventoryDataKey]; ok {
+		if err = json.Unmarshal([]byte(data), &ret); err != nil {
+			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to parse inventory of component %s: %+v", component, err)
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// writeInventory records the objects applied for a component
+func (i *KubectlTargetProvider) writeInventory(ctx context.Context, namespace string, instance string, component string, refs []objectReference) error {
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	name := getInventoryName(instance, component)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.ManagerMetaKey: constants.API,
+			},
+			Annotations: map[string]string{
+				inventoryInstanceKey:  instance,
+				inventoryComponentKey: component,
+			},
+		},
+		Data: map[string]string{
+			inventoryDataKey: string(data),
+		},
+	}
+	existing, err := i.Client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		_, err = i.Client.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	cm.SetResourceVersion(existing.GetResourceVersion())
+	_, err = i.Client.CoreV1().ConfigMaps(namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// pruneComponent deletes the objects that were applied for a component previously but are no longer part of it,
+// then records the current objects. When nothing is applied, the inventory itself is removed.
+func (i *KubectlTargetProvider) pruneComponent(ctx context.Context, namespace string, instance string, component string, applied []objectReference) error {
+	if namespace == "" {
+		namespace = constants.DefaultScope
+	}
+	previous, err := i.readInventory(ctx, namespace, instance, component)
+	if err != nil {
+		return err
+	}
+	for _, ref := range getStaleObjects(previous, applied) {
+		if err = i.deleteObjectByReference(ctx, ref); err != nil {
+			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to prune %s %s/%s: %+v", ref.Kind, ref.Namespace, ref.Name, err)
+			return err
+		}
+	}
+	if len(applied) == 0 {
+		err = i.Client.CoreV1().ConfigMaps(namespace).Delete(ctx, getInventoryName(instance, component), metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	return i.writeInventory(ctx, namespace, instance, component, applied)
+}
+
+// deleteObjectByReference deletes an object identified by its reference
+func (i *KubectlTargetProvider) deleteObjectByReference(ctx context.Context, ref objectReference) error {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return err
+	}
+	mapping, err := i.Mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			// the resource type no longer exists, so neither does the object
+			return nil
+		}
+		return err
+	}
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Kubectl Target): Start to prune object - %s", ref.Name)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		err = i.DynamicClient.Resource(mapping.Resource).Namespace(ref.Namespace).Delete(ctx, ref.Name, metav1.DeleteOptions{})
+	} else {
+		err = i.DynamicClient.Resource(mapping.Resource).Delete(ctx, ref.Name, metav1.DeleteOptions{})
+	}
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/metrics"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/metahelper"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -74,6 +75,13 @@ type (
 		ConfigData string `json:"configData,omitempty"`
 		Context    string `json:"context,omitempty"`
 		InCluster  bool   `json:"inCluster"`
+		// ServerSideApply applies objects with server-side apply under FieldManager instead of create/update
+		ServerSideApply bool   `json:"serverSideApply,omitempty"`
+		FieldManager    string `json:"fieldManager,omitempty"`
+		// ForceConflicts takes over fields owned by other field managers instead of reporting a conflict
+		ForceConflicts bool `json:"forceConflicts,omitempty"`
+		// Prune deletes objects that were applied for a component previously but are no longer part of it
+		Prune bool `json:"prune,omitempty"`
 	}
 
 	// KubectlTargetProvider is the kubectl target provider
@@ -86,6 +94,7 @@ type (
 		Mapper          *restmapper.DeferredDiscoveryRESTMapper
 		RESTConfig      *rest.Config
 		MetaPopulator   metahelper.MetaPopulator
+		ApiClient       api_utils.ApiClient
 	}
 
 	// StatusProbe is the expected resource status property
@@ -97,6 +106,10 @@ type (
 		Timeout          string   `json:"timeout,omitempty"`
 		Interval         string   `json:"interval,omitempty"`
 		InitialWait      string   `json:"initialWait,omitempty"`
+		// Kind and Name select the probed objects when a component renders multiple objects.
+		// All rendered objects are probed when they are empty.
+		Kind string `json:"kind,omitempty"`
+		Name string `json:"name,omitempty"`
 	}
 )
 
@@ -125,6 +138,25 @@ func KubectlTargetProviderConfigFromMap(properties map[string]string) (KubectlTa
 			ret.InCluster = bVal
 		}
 	}
+	for _, key := range []string{"serverSideApply", "forceConflicts", "prune"} {
+		if v, ok := properties[key]; ok && v != "" {
+			bVal, err := strconv.ParseBool(v)
+			if err != nil {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid bool value in the '%s' setting of kubectl provider", key), v1alpha2.BadConfig)
+			}
+			switch key {
+			case "serverSideApply":
+				ret.ServerSideApply = bVal
+			case "forceConflicts":
+				ret.ForceConflicts = bVal
+			case "prune":
+				ret.Prune = bVal
+			}
+		}
+	}
+	if v, ok := properties["fieldManager"]; ok {
+		ret.FieldManager = v
+	}
 	return ret, nil
 }
 
@@ -165,6 +197,9 @@ func (i *KubectlTargetProvider) Init(config providers.IProviderConfig) error {
 	}
 
 	i.Config = updateConfig
+	if i.Config.FieldManager == "" {
+		i.Config.FieldManager = defaultFieldManager
+	}
 	var kConfig *rest.Config
 	kConfig, err = i.getKubernetesConfig(ctx)
 	if err != nil {
@@ -282,6 +317,20 @@ func (i *KubectlTargetProvider) Get(ctx context.Context, deployment model.Deploy
 
 	ret := make([]model.ComponentSpec, 0)
 	for _, component := range references {
+		if i.Config.ServerSideApply {
+			var inSync bool
+			inSync, err = i.isComponentInSync(ctx, component.Component, deployment.Instance)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to compare component %s: %+v", component.Component.Name, err)
+				err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to compare component %s", providerName, component.Component.Name), v1alpha2.GetComponentSpecFailed)
+				return nil, err
+			}
+			if inSync {
+				sLog.InfofCtx(ctx, "  P (Kubectl Target): append component: %s", component.Component.Name)
+				ret = append(ret, component.Component)
+			}
+			continue
+		}
 		if v, ok := component.Component.Properties["yaml"].(string); ok {
 			chanMes, chanErr := readYaml(v)
 			stop := false
@@ -346,6 +395,18 @@ func (i *KubectlTargetProvider) Get(ctx context.Context, deployment model.Deploy
 			}
 			sLog.InfofCtx(ctx, "  P (Kubectl Target): append component: %s", component.Component.Name)
 			ret = append(ret, component.Component)
+		} else if component.Component.Properties["kustomize"] != nil {
+			var inSync bool
+			inSync, err = i.isComponentInSync(ctx, component.Component, deployment.Instance)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to read objects of component %s: %+v", component.Component.Name, err)
+				err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to get custom resources rendered from kustomize property", providerName), v1alpha2.GetComponentSpecFailed)
+				return nil, err
+			}
+			if inSync {
+				sLog.InfofCtx(ctx, "  P (Kubectl Target): append component: %s", component.Component.Name)
+				ret = append(ret, component.Component)
+			}
 		} else {
 			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: component doesn't have yaml or resource property", providerName), v1alpha2.GetComponentSpecFailed)
 			sLog.ErrorCtx(ctx, "  P (Kubectl Target): component doesn't have yaml or resource property")
@@ -405,6 +466,7 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 		sLog.InfofCtx(ctx, "  P (Kubectl Target): get updated components: count - %d", len(components))
 		for _, component := range components {
 			if component.Type == "yaml.k8s" {
+				applied := make([]objectReference, 0)
 				if v, ok := component.Properties["yaml"].(string); ok {
 					chanMes, chanErr := readYaml(v)
 					stop := false
@@ -429,14 +491,13 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 							}
 
 							i.ensureNamespace(ctx, deployment.Instance.Spec.Scope)
-							err = i.applyCustomResource(ctx, dataBytes, deployment.Instance.Spec.Scope, deployment.Instance)
+							var obj *unstructured.Unstructured
+							obj, err = i.applyObject(ctx, dataBytes, deployment.Instance.Spec.Scope, deployment.Instance)
 							if err != nil {
 								sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to apply Yaml: %+v", err)
+								applyErr := err
 								err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to apply Yaml", providerName), v1alpha2.ApplyYamlFailed)
-								ret[component.Name] = model.ComponentResultSpec{
-									Status:  v1alpha2.UpdateFailed,
-									Message: err.Error(),
-								}
+								ret[component.Name] = i.getApplyFailedResult(component.Name, applyErr, err)
 								providerOperationMetrics.ProviderOperationErrors(
 									kubectl,
 									functionName,
@@ -448,6 +509,7 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 								return ret, err
 							}
 
+							applied = append(applied, toObjectReference(obj))
 							ret[component.Name] = model.ComponentResultSpec{
 								Status:  v1alpha2.Updated,
 								Message: fmt.Sprintf("No error. %s has been updated", component.Name),
@@ -515,14 +577,13 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 					}
 
 					i.ensureNamespace(ctx, deployment.Instance.Spec.Scope)
-					err = i.applyCustomResource(ctx, dataBytes, deployment.Instance.Spec.Scope, deployment.Instance)
+					var obj *unstructured.Unstructured
+					obj, err = i.applyObject(ctx, dataBytes, deployment.Instance.Spec.Scope, deployment.Instance)
 					if err != nil {
 						sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to apply custom resource: %+v", err)
+						applyErr := err
 						err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to apply custom resource", providerName), v1alpha2.ApplyResourceFailed)
-						ret[component.Name] = model.ComponentResultSpec{
-							Status:  v1alpha2.UpdateFailed,
-							Message: err.Error(),
-						}
+						ret[component.Name] = i.getApplyFailedResult(component.Name, applyErr, err)
 						providerOperationMetrics.ProviderOperationErrors(
 							kubectl,
 							functionName,
@@ -533,6 +594,7 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 
 						return ret, err
 					}
+					applied = append(applied, toObjectReference(obj))
 
 					// check the resource status
 					if component.Properties["statusProbe"] != nil {
@@ -553,6 +615,59 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 						}
 					}
 
+				} else if component.Properties["kustomize"] != nil {
+					var documents [][]byte
+					documents, err = i.renderKustomizeProperty(ctx, component.Properties["kustomize"], deployment.Instance.Spec.Scope)
+					if err != nil {
+						sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to render kustomize property: %+v", err)
+						err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to render kustomize property", providerName), v1alpha2.KustomizeRenderFailed)
+						ret[component.Name] = model.ComponentResultSpec{
+							Status:  v1alpha2.UpdateFailed,
+							Message: err.Error(),
+						}
+						providerOperationMetrics.ProviderOperationErrors(
+							kubectl,
+							functionName,
+							metrics.RenderKustomizeOperation,
+							metrics.ApplyOperationType,
+							v1alpha2.KustomizeRenderFailed.String(),
+						)
+						return ret, err
+					}
+
+					i.ensureNamespace(ctx, deployment.Instance.Spec.Scope)
+					for _, dataBytes := range documents {
+						var obj *unstructured.Unstructured
+						obj, err = i.applyObject(ctx, dataBytes, deployment.Instance.Spec.Scope, deployment.Instance)
+						if err != nil {
+							sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to apply rendered custom resource: %+v", err)
+							applyErr := err
+							err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to apply rendered custom resource", providerName), v1alpha2.ApplyResourceFailed)
+							ret[component.Name] = i.getApplyFailedResult(component.Name, applyErr, err)
+							providerOperationMetrics.ProviderOperationErrors(
+								kubectl,
+								functionName,
+								metrics.ApplyCustomResource,
+								metrics.ApplyOperationType,
+								v1alpha2.ApplyResourceFailed.String(),
+							)
+							return ret, err
+						}
+						applied = append(applied, toObjectReference(obj))
+					}
+
+					ret[component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.Updated,
+						Message: fmt.Sprintf("No error. %s has been updated", component.Name),
+					}
+					if component.Properties["statusProbe"] != nil {
+						statusProbe, err := toStatusProbe(component.Properties["statusProbe"])
+						if err != nil {
+							sLog.ErrorfCtx(ctx, "Status property is not correctly defined: +%v", err)
+						} else {
+							ret[component.Name] = i.checkDocumentsStatus(ctx, documents, deployment.Instance.Spec.Scope, statusProbe, component.Name)
+						}
+					}
 				} else {
 					err := v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: component doesn't have yaml or resource property", providerName), v1alpha2.YamlResourcePropertyNotFound)
 					sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  component doesn't have yaml property or resource property, error: %+v", err)
@@ -570,6 +685,26 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 					)
 					return ret, err
 				}
+
+				if i.Config.Prune {
+					err = i.pruneComponent(ctx, deployment.Instance.Spec.Scope, deployment.Instance.ObjectMeta.Name, component.Name, applied)
+					if err != nil {
+						sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to prune objects of component %s: %+v", component.Name, err)
+						err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to prune objects of component %s", providerName, component.Name), v1alpha2.PruneResourceFailed)
+						ret[component.Name] = model.ComponentResultSpec{
+							Status:  v1alpha2.UpdateFailed,
+							Message: err.Error(),
+						}
+						providerOperationMetrics.ProviderOperationErrors(
+							kubectl,
+							functionName,
+							metrics.PruneResourceOperation,
+							metrics.ApplyOperationType,
+							v1alpha2.PruneResourceFailed.String(),
+						)
+						return ret, err
+					}
+				}
 			}
 		}
 	}
@@ -713,6 +848,50 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 						Message: "",
 					}
 
+				} else if component.Properties["kustomize"] != nil {
+					var documents [][]byte
+					documents, err = i.renderKustomizeProperty(ctx, component.Properties["kustomize"], deployment.Instance.Spec.Scope)
+					if err != nil {
+						sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to render kustomize property: %+v", err)
+						err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to render kustomize property", providerName), v1alpha2.KustomizeRenderFailed)
+						ret[component.Name] = model.ComponentResultSpec{
+							Status:  v1alpha2.DeleteFailed,
+							Message: err.Error(),
+						}
+						providerOperationMetrics.ProviderOperationErrors(
+							kubectl,
+							functionName,
+							metrics.RenderKustomizeOperation,
+							metrics.ApplyOperationType,
+							v1alpha2.KustomizeRenderFailed.String(),
+						)
+						return ret, err
+					}
+
+					for _, dataBytes := range documents {
+						err = i.deleteCustomResource(ctx, dataBytes, deployment.Instance.Spec.Scope)
+						if err != nil && !kerrors.IsNotFound(err) {
+							sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to delete rendered custom resource: %+v", err)
+							err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to delete rendered custom resource", providerName), v1alpha2.DeleteResourceFailed)
+							ret[component.Name] = model.ComponentResultSpec{
+								Status:  v1alpha2.DeleteFailed,
+								Message: err.Error(),
+							}
+							providerOperationMetrics.ProviderOperationErrors(
+								kubectl,
+								functionName,
+								metrics.ApplyCustomResource,
+								metrics.ApplyOperationType,
+								v1alpha2.DeleteResourceFailed.String(),
+							)
+							return ret, err
+						}
+					}
+
+					ret[component.Name] = model.ComponentResultSpec{
+						Status:  v1alpha2.Deleted,
+						Message: "",
+					}
 				} else {
 					err = v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: component doesn't have yaml or resource property", providerName), v1alpha2.DeleteFailed)
 					sLog.ErrorCtx(ctx, "  P (Kubectl Target): component doesn't have yaml property or resource property")
@@ -729,6 +908,26 @@ func (i *KubectlTargetProvider) Apply(ctx context.Context, deployment model.Depl
 					)
 					return ret, err
 				}
+
+				if i.Config.Prune {
+					err = i.pruneComponent(ctx, deployment.Instance.Spec.Scope, deployment.Instance.ObjectMeta.Name, component.Name, nil)
+					if err != nil {
+						sLog.ErrorfCtx(ctx, "  P (Kubectl Target):  failed to prune objects of component %s: %+v", component.Name, err)
+						err = v1alpha2.NewCOAError(err, fmt.Sprintf("%s: failed to prune objects of component %s", providerName, component.Name), v1alpha2.PruneResourceFailed)
+						ret[component.Name] = model.ComponentResultSpec{
+							Status:  v1alpha2.DeleteFailed,
+							Message: err.Error(),
+						}
+						providerOperationMetrics.ProviderOperationErrors(
+							kubectl,
+							functionName,
+							metrics.PruneResourceOperation,
+							metrics.ApplyOperationType,
+							v1alpha2.PruneResourceFailed.String(),
+						)
+						return ret, err
+					}
+				}
 			}
 		}
 	}
@@ -850,6 +1049,43 @@ func (k *KubectlTargetProvider) checkResourceStatus(ctx context.Context, dataByt
 	}
 }
 
+// checkDocumentsStatus checks the status of the objects selected by the status probe among the given manifests
+func (k *KubectlTargetProvider) checkDocumentsStatus(ctx context.Context, documents [][]byte, namespace string, status *StatusProbe, componentName string) model.ComponentResultSpec {
+	result := model.ComponentResultSpec{
+		Status:  v1alpha2.Updated,
+		Message: fmt.Sprintf("No error. %s has been updated", componentName),
+	}
+	for _, dataBytes := range documents {
+		if !isProbedDocument(dataBytes, status) {
+			continue
+		}
+		probe := *status
+		resourceStatus, err := k.checkResourceStatus(ctx, dataBytes, namespace, &probe, componentName)
+		if err != nil {
+			sLog.ErrorfCtx(ctx, "Failed to check resource status: +%v", err)
+		}
+		if resourceStatus.Status != v1alpha2.Updated {
+			return resourceStatus
+		}
+	}
+	return result
+}
+
+// isProbedDocument checks whether a manifest is selected by the kind and name of a status probe
+func isProbedDocument(dataBytes []byte, status *StatusProbe) bool {
+	obj := &unstructured.Unstructured{}
+	if _, _, err := decUnstructured.Decode(dataBytes, nil, obj); err != nil {
+		return false
+	}
+	if status.Kind != "" && status.Kind != obj.GetKind() {
+		return false
+	}
+	if status.Name != "" && status.Name != obj.GetName() {
+		return false
+	}
+	return true
+}
+
 // ensureNamespace ensures that the namespace exists
 func (k *KubectlTargetProvider) ensureNamespace(ctx context.Context, namespace string) error {
 	ctx, span := observability.StartSpan(
@@ -899,13 +1135,14 @@ func (*KubectlTargetProvider) GetValidationRule(ctx context.Context) model.Valid
 		AllowSidecar: false,
 		ComponentValidationRule: model.ComponentValidationRule{
 			RequiredProperties:    []string{},
-			OptionalProperties:    []string{"yaml", "resource"},
+			OptionalProperties:    []string{"yaml", "resource", "kustomize"},
 			RequiredComponentType: "",
 			RequiredMetadata:      []string{},
 			OptionalMetadata:      []string{},
 			ChangeDetectionProperties: []model.PropertyDesc{
 				{Name: "yaml", IgnoreCase: false, SkipIfMissing: true},
 				{Name: "resource", IgnoreCase: false, SkipIfMissing: true},
+				{Name: "kustomize", IgnoreCase: false, SkipIfMissing: true},
 			},
 		},
 	}
@@ -1017,12 +1254,12 @@ func (i *KubectlTargetProvider) deleteCustomResource(ctx context.Context, dataBy
 }
 
 // applyCustomResource applies a custom resource from a byte array
-func (i *KubectlTargetProvider) applyCustomResource(ctx context.Context, dataBytes []byte, namespace string, instance model.InstanceState) error {
+func (i *KubectlTargetProvider) applyCustomResource(ctx context.Context, dataBytes []byte, namespace string, instance model.InstanceState) (*unstructured.Unstructured, error) {
 	sLog.ErrorfCtx(ctx, "  P (Kubectl Target): apply custom resource in the namespace: %s", namespace)
 	obj, dr, err := i.buildDynamicResourceClient(dataBytes, namespace)
 	if err != nil {
 		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to build a new dynamic client: %+v", err)
-		return err
+		return nil, err
 	}
 
 	// Check if the object exists
@@ -1030,14 +1267,14 @@ func (i *KubectlTargetProvider) applyCustomResource(ctx context.Context, dataByt
 	if err != nil {
 		if !kerrors.IsNotFound(err) {
 			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to read object: %+v", err)
-			return err
+			return nil, err
 		} else {
 			sLog.InfofCtx(ctx, "  P (Kubectl Target): object %s not found: %+v", obj.GetName(), err)
 		}
 
 		if err = i.MetaPopulator.PopulateMeta(obj, instance); err != nil {
 			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to populate meta: +%v", err)
-			return err
+			return nil, err
 		}
 
 		// Create the object
@@ -1045,24 +1282,24 @@ func (i *KubectlTargetProvider) applyCustomResource(ctx context.Context, dataByt
 		_, err = dr.Create(ctx, obj, metav1.CreateOptions{})
 		if err != nil {
 			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to create Yaml: %+v", err)
-			return err
+			return nil, err
 		}
-		return nil
+		return obj, nil
 	}
 
 	if err = i.MetaPopulator.PopulateMeta(obj, instance); err != nil {
 		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to populate meta: +%v", err)
-		return err
+		return nil, err
 	}
 	// Update the object
 	obj.SetResourceVersion(existing.GetResourceVersion())
 	_, err = dr.Update(ctx, obj, metav1.UpdateOptions{})
 	if err != nil {
 		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to apply Yaml: %+v", err)
-		return err
+		return nil, err
 	}
 
-	return nil
+	return obj, nil
 }
 
 // toStatusProbe converts a component status property to a status probe property
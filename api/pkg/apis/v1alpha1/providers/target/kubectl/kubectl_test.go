@@ -15,7 +15,11 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	dfake "k8s.io/client-go/dynamic/fake"
 	kfake "k8s.io/client-go/kubernetes/fake"
@@ -933,3 +937,293 @@ func TestKubectlTargetProviderGet(t *testing.T) {
 	_, err = provider.Get(context.Background(), deployment, reference)
 	assert.Nil(t, err)
 }
+
+func TestKubectlTargetProviderConfigFromMapServerSideApply(t *testing.T) {
+	config, err := KubectlTargetProviderConfigFromMap(map[string]string{
+		"serverSideApply": "true",
+		"fieldManager":    "my-manager",
+		"forceConflicts":  "true",
+		"prune":           "true",
+	})
+	assert.Nil(t, err)
+	assert.True(t, config.ServerSideApply)
+	assert.Equal(t, "my-manager", config.FieldManager)
+	assert.True(t, config.ForceConflicts)
+	assert.True(t, config.Prune)
+
+	_, err = KubectlTargetProviderConfigFromMap(map[string]string{
+		"prune": "bad",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestGetStaleObjects(t *testing.T) {
+	previous := []objectReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "b"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "a"},
+	}
+	current := []objectReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "a"},
+	}
+	stale := getStaleObjects(previous, current)
+	assert.Equal(t, 1, len(stale))
+	assert.Equal(t, "b", stale[0].Name)
+	assert.Equal(t, 0, len(getStaleObjects(nil, current)))
+}
+
+func TestGetInventoryName(t *testing.T) {
+	name := getInventoryName("instance", "component")
+	assert.Equal(t, name, getInventoryName("instance", "component"))
+	assert.NotEqual(t, name, getInventoryName("instance", "other"))
+	assert.True(t, len(name) < 64)
+}
+
+func TestPruneComponentInventory(t *testing.T) {
+	provider := KubectlTargetProvider{
+		Client: kfake.NewSimpleClientset(),
+	}
+	ctx := context.Background()
+	refs := []objectReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "a"},
+	}
+	err := provider.pruneComponent(ctx, "", "instance", "component", refs)
+	assert.Nil(t, err)
+	inventory, err := provider.readInventory(ctx, "default", "instance", "component")
+	assert.Nil(t, err)
+	assert.Equal(t, refs, inventory)
+
+	// the inventory is updated when the same objects are applied again
+	err = provider.pruneComponent(ctx, "default", "instance", "component", refs)
+	assert.Nil(t, err)
+
+	inventory, err = provider.readInventory(ctx, "default", "instance", "other")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(inventory))
+}
+
+func TestIsOwnedFieldsDrifted(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": "test",
+		},
+		"data": map[string]interface{}{
+			"owned": "value",
+		},
+	}}
+	live := desired.DeepCopy()
+	live.Object["data"] = map[string]interface{}{
+		"owned":   "value",
+		"foreign": "other",
+	}
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "symphony",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:owned":{}}}`)},
+		},
+		{
+			Manager:    "someone-else",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:foreign":{}}}`)},
+		},
+	})
+
+	// fields owned by other managers are ignored
+	drifted, err := isOwnedFieldsDrifted(desired, live, "symphony")
+	assert.Nil(t, err)
+	assert.False(t, drifted)
+
+	// an owned field changed by someone else is a drift
+	unstructured.SetNestedField(live.Object, "changed", "data", "owned")
+	drifted, err = isOwnedFieldsDrifted(desired, live, "symphony")
+	assert.Nil(t, err)
+	assert.True(t, drifted)
+
+	// objects without an apply entry for the manager are considered in sync
+	drifted, err = isOwnedFieldsDrifted(desired, live, "unknown")
+	assert.Nil(t, err)
+	assert.False(t, drifted)
+}
+
+func TestIsOwnedFieldsDriftedWithLists(t *testing.T) {
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name": "web",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "app",
+							"image": "nginx:1",
+							"ports": []interface{}{
+								map[string]interface{}{"containerPort": int64(80), "protocol": "TCP"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+	live := desired.DeepCopy()
+	// the server defaults fields of the owned container and another manager adds a sidecar
+	unstructured.SetNestedSlice(live.Object, []interface{}{
+		map[string]interface{}{
+			"name":                     "app",
+			"image":                    "nginx:1",
+			"imagePullPolicy":          "IfNotPresent",
+			"terminationMessagePolicy": "File",
+			"ports": []interface{}{
+				map[string]interface{}{"containerPort": float64(80), "protocol": "TCP"},
+			},
+		},
+		map[string]interface{}{
+			"name":  "proxy",
+			"image": "envoy:1",
+		},
+	}, "spec", "template", "spec", "containers")
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "symphony",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{"f:spec":{"f:containers":{` +
+				`"k:{\"name\":\"app\"}":{".":{},"f:name":{},"f:image":{},"f:ports":{` +
+				`"k:{\"containerPort\":80,\"protocol\":\"TCP\"}":{".":{},"f:containerPort":{},"f:protocol":{}}}}}}}}}`)},
+		},
+		{
+			Manager:    "injector",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"proxy\"}":{".":{},"f:name":{},"f:image":{}}}}}}}`)},
+		},
+	})
+
+	// defaulted fields and items of other managers are ignored
+	drifted, err := isOwnedFieldsDrifted(desired, live, "symphony")
+	assert.Nil(t, err)
+	assert.False(t, drifted)
+
+	// an owned field of a list item changed by someone else is a drift
+	changed := live.DeepCopy()
+	containers, _, _ := unstructured.NestedSlice(changed.Object, "spec", "template", "spec", "containers")
+	containers[0].(map[string]interface{})["image"] = "nginx:2"
+	unstructured.SetNestedSlice(changed.Object, containers, "spec", "template", "spec", "containers")
+	drifted, err = isOwnedFieldsDrifted(desired, changed, "symphony")
+	assert.Nil(t, err)
+	assert.True(t, drifted)
+
+	// an owned list item that was removed is a drift
+	removed := live.DeepCopy()
+	unstructured.SetNestedSlice(removed.Object, containers[1:], "spec", "template", "spec", "containers")
+	drifted, err = isOwnedFieldsDrifted(desired, removed, "symphony")
+	assert.Nil(t, err)
+	assert.True(t, drifted)
+}
+
+func TestGetApplyFailedResult(t *testing.T) {
+	conflict := kerrors.NewApplyConflict([]metav1.StatusCause{
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kube-controller-manager"`,
+			Field:   ".spec.replicas",
+		},
+	}, "Apply failed with 1 conflict")
+
+	provider := KubectlTargetProvider{
+		Config: KubectlTargetProviderConfig{ServerSideApply: true},
+	}
+	result := provider.getApplyFailedResult("comp", conflict, conflict)
+	assert.Equal(t, v1alpha2.ApplyConflict, result.Status)
+	assert.Contains(t, result.Message, ".spec.replicas")
+
+	provider.Config.ServerSideApply = false
+	result = provider.getApplyFailedResult("comp", conflict, conflict)
+	assert.Equal(t, v1alpha2.UpdateFailed, result.Status)
+}
+
+func TestRenderKustomizationInlineBase(t *testing.T) {
+	spec, err := toKustomizeSpec(map[string]interface{}{
+		"base": map[string]interface{}{
+			"files": map[string]interface{}{
+				"app/kustomization.yaml": "resources:\n- deployment.yaml\n",
+				"app/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.0
+`,
+			},
+			"path": "app",
+		},
+		"namePrefix": "prod-",
+		"images": []interface{}{
+			map[string]interface{}{"name": "nginx", "newTag": "2.0"},
+		},
+		"configMapGenerator": []interface{}{
+			map[string]interface{}{"name": "settings", "literals": []interface{}{"mode=prod"}},
+		},
+		"patches": []interface{}{
+			map[string]interface{}{
+				"target": map[string]interface{}{"kind": "Deployment", "name": "web"},
+				"patch":  "- op: add\n  path: /spec/replicas\n  value: 3\n",
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	documents, err := renderKustomization(context.Background(), spec)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(documents))
+
+	objects := map[string]*unstructured.Unstructured{}
+	for _, document := range documents {
+		obj := &unstructured.Unstructured{}
+		_, _, err = decUnstructured.Decode(document, nil, obj)
+		assert.Nil(t, err)
+		objects[obj.GetKind()] = obj
+	}
+	assert.Equal(t, "prod-web", objects["Deployment"].GetName())
+	replicas, _, _ := unstructured.NestedInt64(objects["Deployment"].Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+	containers, _, _ := unstructured.NestedSlice(objects["Deployment"].Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "nginx:2.0", containers[0].(map[string]interface{})["image"])
+	assert.Contains(t, objects["ConfigMap"].GetName(), "prod-settings-")
+
+	probe := &StatusProbe{Kind: "Deployment"}
+	assert.True(t, isProbedDocument(documents[1], probe) || isProbedDocument(documents[0], probe))
+	probe.Name = "other"
+	assert.False(t, isProbedDocument(documents[0], probe) || isProbedDocument(documents[1], probe))
+}
+
+func TestRenderKustomizationBadBase(t *testing.T) {
+	_, err := renderKustomization(context.Background(), KustomizeSpec{})
+	assert.NotNil(t, err)
+
+	_, err = renderKustomization(context.Background(), KustomizeSpec{
+		Base: KustomizeBase{
+			Files: map[string]string{"../kustomization.yaml": "resources: []"},
+		},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestGetGitBaseRef(t *testing.T) {
+	assert.Equal(t, "https://github.com/org/repo//overlays/prod?ref=v1", getGitBaseRef(GitReference{Repo: "https://github.com/org/repo", Ref: "v1"}, "overlays/prod"))
+	assert.Equal(t, "https://github.com/org/repo", getGitBaseRef(GitReference{Repo: "https://github.com/org/repo"}, ""))
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kubectl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	oci_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/oci"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	kustomizationFileName = "kustomization.yaml"
+)
+
+type (
+	// KustomizeSpec is the kustomize property of a component. The base is rendered with the
+	// overlay settings below, which are evaluated by Symphony like any other component property.
+	KustomizeSpec struct {
+		Base               KustomizeBase         `json:"base"`
+		Namespace          string                `json:"namespace,omitempty"`
+		NamePrefix         string                `json:"namePrefix,omitempty"`
+		NameSuffix         string                `json:"nameSuffix,omitempty"`
+		CommonLabels       map[string]string     `json:"commonLabels,omitempty"`
+		CommonAnnotations  map[string]string     `json:"commonAnnotations,omitempty"`
+		Patches            []types.Patch         `json:"patches,omitempty"`
+		Images             []types.Image         `json:"images,omitempty"`
+		ConfigMapGenerator []types.ConfigMapArgs `json:"configMapGenerator,omitempty"`
+	}
+
+	// KustomizeBase locates the kustomize base. Exactly one of Files, OCI, Git or Catalog is expected.
+	// A catalog holds any of the other three in its properties.
+	KustomizeBase struct {
+		Files    map[string]string `json:"files,omitempty"`
+		OCI      string            `json:"oci,omitempty"`
+		Username string            `json:"username,omitempty"`
+		Password string            `json:"password,omitempty"`
+		// RequireDigest rejects OCI bases that are not pinned by digest
+		RequireDigest bool `json:"requireDigest,omitempty"`
+		// Verifier is the name of the signature verifier the OCI base must pass
+		Verifier string        `json:"verifier,omitempty"`
+		Git      *GitReference `json:"git,omitempty"`
+		Catalog  string        `json:"catalog,omitempty"`
+		// Path is the directory inside the base that holds the kustomization
+		Path string `json:"path,omitempty"`
+	}
+
+	// GitReference is a kustomize remote base in a git repository
+	GitReference struct {
+		Repo string `json:"repo"`
+		Ref  string `json:"ref,omitempty"`
+	}
+)
+
+// toKustomizeSpec converts a component kustomize property to a KustomizeSpec
+func toKustomizeSpec(property interface{}) (KustomizeSpec, error) {
+	ret := KustomizeSpec{}
+	data, err := json.Marshal(property)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// renderKustomizeProperty renders the kustomize property of a component into Kubernetes manifests
+func (i *KubectlTargetProvider) renderKustomizeProperty(ctx context.Context, property interface{}, namespace string) ([][]byte, error) {
+	spec, err := toKustomizeSpec(property)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): kustomize property is not correctly defined: %+v", err)
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("%s: kustomize property is not correctly defined", providerName), v1alpha2.BadConfig)
+	}
+	if spec.Base.Catalog != "" {
+		spec.Base, err = i.getKustomizeBaseFromCatalog(ctx, spec.Base, namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renderKustomization(ctx, spec)
+}
+
+// getKustomizeBaseFromCatalog reads a kustomize base stored in the properties of a catalog
+func (i *KubectlTargetProvider) getKustomizeBaseFromCatalog(ctx context.Context, base KustomizeBase, namespace string) (KustomizeBase, error) {
+	ret := KustomizeBase{}
+	if i.ApiClient == nil {
+		client, err := api_utils.GetApiClient()
+		if err != nil {
+			sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to create api client: %+v", err)
+			return ret, err
+		}
+		i.ApiClient = client
+	}
+	if namespace == "" {
+		namespace = constants.DefaultScope
+	}
+	user, password := "", ""
+	if i.Context != nil && i.Context.SiteInfo.CurrentSite.Username != "" {
+		user = i.Context.SiteInfo.CurrentSite.Username
+		password = i.Context.SiteInfo.CurrentSite.Password
+	}
+	catalog, err := i.ApiClient.GetCatalog(ctx, base.Catalog, namespace, user, password)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to get kustomize base catalog %s: %+v", base.Catalog, err)
+		return ret, err
+	}
+	if catalog.Spec == nil {
+		return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: catalog %s has no spec", providerName, base.Catalog), v1alpha2.BadConfig)
+	}
+	data, err := json.Marshal(catalog.Spec.Properties)
+	if err != nil {
+		return ret, err
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("%s: catalog %s doesn't contain a valid kustomize base", providerName, base.Catalog), v1alpha2.BadConfig)
+	}
+	if ret.Catalog != "" {
+		return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: catalog %s can't reference another catalog", providerName, base.Catalog), v1alpha2.BadConfig)
+	}
+	// the component may narrow down the directory inside the base
+	if base.Path != "" {
+		ret.Path = base.Path
+	}
+	return ret, nil
+}
+
+// renderKustomization materializes the base into a temporary directory, layers an overlay carrying
+// the component settings on top of it and runs kustomize
+func renderKustomization(ctx context.Context, spec KustomizeSpec) ([][]byte, error) {
+	dir, err := os.MkdirTemp("", "symphony-kustomize-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	baseRef, err := materializeKustomizeBase(ctx, spec.Base, filepath.Join(dir, "base"))
+	if err != nil {
+		return nil, err
+	}
+
+	overlay := types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+		Resources:          []string{baseRef},
+		Namespace:          spec.Namespace,
+		NamePrefix:         spec.NamePrefix,
+		NameSuffix:         spec.NameSuffix,
+		CommonLabels:       spec.CommonLabels,
+		CommonAnnotations:  spec.CommonAnnotations,
+		Patches:            spec.Patches,
+		Images:             spec.Images,
+		ConfigMapGenerator: spec.ConfigMapGenerator,
+	}
+	data, err := syaml.Marshal(overlay)
+	if err != nil {
+		return nil, err
+	}
+	overlayDir := filepath.Join(dir, "overlay")
+	if err = os.MkdirAll(overlayDir, 0755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(filepath.Join(overlayDir, kustomizationFileName), data, 0644); err != nil {
+		return nil, err
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), overlayDir)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to run kustomize: %+v", err)
+		return nil, err
+	}
+	rendered, err := resMap.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	return splitYamlDocuments(rendered)
+}
+
+// materializeKustomizeBase makes the base available to kustomize and returns the reference
+// the overlay uses to include it
+func materializeKustomizeBase(ctx context.Context, base KustomizeBase, dir string) (string, error) {
+	if base.Path != "" && !filepath.IsLocal(base.Path) {
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: kustomize base path %s must be relative", providerName, base.Path), v1alpha2.BadConfig)
+	}
+	localRef := path.Join("..", "base", filepath.ToSlash(base.Path))
+	switch {
+	case len(base.Files) > 0:
+		return localRef, writeKustomizeFiles(base.Files, dir)
+	case base.OCI != "":
+		return localRef, pullKustomizeBase(ctx, base, dir)
+	case base.Git != nil && base.Git.Repo != "":
+		return getGitBaseRef(*base.Git, base.Path), nil
+	default:
+		return "", v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: kustomize base requires files, oci, git or catalog", providerName), v1alpha2.BadConfig)
+	}
+}
+
+// writeKustomizeFiles writes an inline filesystem to a directory
+func writeKustomizeFiles(files map[string]string, dir string) error {
+	for name, content := range files {
+		if !filepath.IsLocal(name) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: kustomize file name %s must be relative", providerName, name), v1alpha2.BadConfig)
+		}
+		fileName := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pullKustomizeBase pulls an OCI artifact into a directory through the shared OCI fetcher. Layers are
+// written by their title annotation; directories pushed as tarballs are unpacked.
+func pullKustomizeBase(ctx context.Context, base KustomizeBase, dir string) error {
+	fetcher, err := oci_utils.GetFetcher(oci_utils.FetcherConfig{})
+	if err != nil {
+		return err
+	}
+	artifact, err := fetcher.Pull(ctx, base.OCI, oci_utils.PullOptions{
+		Username:      base.Username,
+		Password:      base.Password,
+		RequireDigest: base.RequireDigest,
+		Verifier:      base.Verifier,
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to pull kustomize base %s: %+v", base.OCI, err)
+		return err
+	}
+	return artifact.Extract(dir)
+}
+
+// getGitBaseRef returns a kustomize remote base reference, for example https://github.com/org/repo//path?ref=v1
+func getGitBaseRef(git GitReference, dir string) string {
+	ret := git.Repo
+	if dir != "" {
+		ret = fmt.Sprintf("%s//%s", ret, filepath.ToSlash(dir))
+	}
+	if git.Ref != "" {
+		ret = fmt.Sprintf("%s?ref=%s", ret, git.Ref)
+	}
+	return ret
+}
+
+// splitYamlDocuments splits a multi-document yaml into its non-empty documents
+func splitYamlDocuments(data []byte) ([][]byte, error) {
+	ret := make([][]byte, 0)
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return ret, nil
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) > 0 {
+			ret = append(ret, doc)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+const (
+	defaultFieldManager = "symphony"
+)
+
+// serverSideApplyCustomResource applies a custom resource from a byte array using server-side apply.
+// Field ownership is recorded under the configured field manager so that fields owned by other
+// controllers are left untouched.
+func (i *KubectlTargetProvider) serverSideApplyCustomResource(ctx context.Context, dataBytes []byte, namespace string, instance model.InstanceState) (*unstructured.Unstructured, error) {
+	sLog.InfofCtx(ctx, "  P (Kubectl Target): server-side apply custom resource in the namespace: %s", namespace)
+	obj, dr, err := i.buildDynamicResourceClient(dataBytes, namespace)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to build a new dynamic client: %+v", err)
+		return nil, err
+	}
+
+	if err = i.MetaPopulator.PopulateMeta(obj, instance); err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to populate meta: +%v", err)
+		return nil, err
+	}
+
+	// server-side apply rejects objects carrying server-populated fields
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Kubectl Target): Start to apply object - %s", obj.GetName())
+	_, err = dr.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{
+		FieldManager: i.Config.FieldManager,
+		Force:        i.Config.ForceConflicts,
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Kubectl Target): failed to apply object %s: %+v", obj.GetName(), err)
+		return obj, err
+	}
+	return obj, nil
+}
+
+// applyObject applies an object with server-side apply when enabled, or with create/update otherwise
+func (i *KubectlTargetProvider) applyObject(ctx context.Context, dataBytes []byte, namespace string, instance model.InstanceState) (*unstructured.Unstructured, error) {
+	if i.Config.ServerSideApply {
+		return i.serverSideApplyCustomResource(ctx, dataBytes, namespace, instance)
+	}
+	return i.applyCustomResource(ctx, dataBytes, namespace, instance)
+}
+
+// getApplyFailedResult builds the component result of a failed apply. Field ownership conflicts
+// reported by server-side apply are surfaced with the conflicting fields.
+func (i *KubectlTargetProvider) getApplyFailedResult(componentName string, applyErr error, err error) model.ComponentResultSpec {
+	if i.Config.ServerSideApply && kerrors.IsConflict(applyErr) {
+		return model.ComponentResultSpec{
+			Status:  v1alpha2.ApplyConflict,
+			Message: getConflictMessage(componentName, applyErr),
+		}
+	}
+	return model.ComponentResultSpec{
+		Status:  v1alpha2.UpdateFailed,
+		Message: err.Error(),
+	}
+}
+
+// isComponentInSync checks whether all objects of a component exist and the fields owned by
+// Symphony still hold the desired values
+func (i *KubectlTargetProvider) isComponentInSync(ctx context.Context, component model.ComponentSpec, instance model.InstanceState) (bool, error) {
+	documents, err := i.getComponentDocuments(ctx, component, instance.Spec.Scope)
+	if err != nil {
+		return false, err
+	}
+	for _, dataBytes := range documents {
+		desired, dr, err := i.buildDynamicResourceClient(dataBytes, instance.Spec.Scope)
+		if err != nil {
+			return false, err
+		}
+		live, err := dr.Get(ctx, desired.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				sLog.InfofCtx(ctx, "  P (Kubectl Target): object %s of component %s not found", desired.GetName(), component.Name)
+				return false, nil
+			}
+			return false, err
+		}
+		if err = i.MetaPopulator.PopulateMeta(desired, instance); err != nil {
+			return false, err
+		}
+		drifted, err := isOwnedFieldsDrifted(desired, live, i.Config.FieldManager)
+		if err != nil {
+			return false, err
+		}
+		if drifted {
+			sLog.InfofCtx(ctx, "  P (Kubectl Target): fields owned by %s drifted on object %s of component %s", i.Config.FieldManager, desired.GetName(), component.Name)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getComponentDocuments returns the manifests of a component from its yaml, resource or kustomize property
+func (i *KubectlTargetProvider) getComponentDocuments(ctx context.Context, component model.ComponentSpec, namespace string) ([][]byte, error) {
+	if v, ok := component.Properties["yaml"].(string); ok {
+		return readAllYaml(v)
+	}
+	if component.Properties["resource"] != nil {
+		data, err := json.Marshal(component.Properties["resource"])
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{data}, nil
+	}
+	if component.Properties["kustomize"] != nil {
+		return i.renderKustomizeProperty(ctx, component.Properties["kustomize"], namespace)
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s: component doesn't have yaml, resource or kustomize property", providerName), v1alpha2.YamlResourcePropertyNotFound)
+}
+
+// readAllYaml reads all non-empty documents of a multi-document yaml from url
+func readAllYaml(yaml string) ([][]byte, error) {
+	chanMes, chanErr := readYaml(yaml)
+	ret := make([][]byte, 0)
+	for {
+		select {
+		case dataBytes := <-chanMes:
+			if len(bytes.TrimSpace(dataBytes)) > 0 {
+				ret = append(ret, dataBytes)
+			}
+		case err := <-chanErr:
+			if err == io.EOF {
+				return ret, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// isOwnedFieldsDrifted checks whether the fields owned by the given field manager on the live object
+// differ from the desired object. Fields owned by other managers are ignored. When the live object
+// carries no apply entry for the manager, the object is considered in sync.
+func isOwnedFieldsDrifted(desired *unstructured.Unstructured, live *unstructured.Unstructured, fieldManager string) (bool, error) {
+	fieldSet, ok, err := getOwnedFieldSet(live, fieldManager)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	drifted := false
+	fieldSet.Leaves().Iterate(func(path fieldpath.Path) {
+		if drifted {
+			return
+		}
+		desiredValue, desiredFound := lookupFieldPath(desired.Object, path)
+		liveValue, liveFound := lookupFieldPath(live.Object, path)
+		drifted = desiredFound != liveFound || (desiredFound && !jsonEqual(desiredValue, liveValue))
+	})
+	return drifted, nil
+}
+
+// getOwnedFieldSet returns the set of fields the field manager owns through apply operations
+func getOwnedFieldSet(obj *unstructured.Unstructured, fieldManager string) (*fieldpath.Set, bool, error) {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.Subresource != "" {
+			continue
+		}
+		if entry.FieldsV1 == nil {
+			return nil, false, nil
+		}
+		fieldSet := &fieldpath.Set{}
+		if err := fieldSet.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, false, err
+		}
+		return fieldSet, true, nil
+	}
+	return nil, false, nil
+}
+
+// lookupFieldPath resolves a managed field path on an object. List items are matched by their
+// key fields, their value or their index, the way the path identifies them, so that fields the
+// server defaulted on an owned list item aren't compared.
+func lookupFieldPath(obj interface{}, path fieldpath.Path) (interface{}, bool) {
+	current := obj
+	for _, element := range path {
+		switch {
+		case element.FieldName != nil:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if current, ok = m[*element.FieldName]; !ok {
+				return nil, false
+			}
+		case element.Key != nil:
+			item, ok := findListItem(current, func(item interface{}) bool {
+				m, ok := item.(map[string]interface{})
+				if !ok {
+					return false
+				}
+				for _, field := range *element.Key {
+					if v, ok := m[field.Name]; !ok || !jsonEqual(v, field.Value.Unstructured()) {
+						return false
+					}
+				}
+				return true
+			})
+			if !ok {
+				return nil, false
+			}
+			current = item
+		case element.Value != nil:
+			item, ok := findListItem(current, func(item interface{}) bool {
+				return jsonEqual(item, (*element.Value).Unstructured())
+			})
+			if !ok {
+				return nil, false
+			}
+			current = item
+		case element.Index != nil:
+			l, ok := current.([]interface{})
+			if !ok || *element.Index < 0 || *element.Index >= len(l) {
+				return nil, false
+			}
+			current = l[*element.Index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func findListItem(list interface{}, match func(item interface{}) bool) (interface{}, bool) {
+	l, ok := list.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	for _, item := range l {
+		if match(item) {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// jsonEqual compares two values through a JSON round trip so that numbers compare equally
+// regardless of their origin
+func jsonEqual(a interface{}, b interface{}) bool {
+	var left, right interface{}
+	aData, err := json.Marshal(a)
+	if err != nil || json.Unmarshal(aData, &left) != nil {
+		return false
+	}
+	bData, err := json.Marshal(b)
+	if err != nil || json.Unmarshal(bData, &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// getConflictMessage renders the conflicting fields reported by a server-side apply conflict
+func getConflictMessage(name string, err error) string {
+	var statusErr *kerrors.StatusError
+	fields := make([]string, 0)
+	if errors.As(err, &statusErr) && statusErr.ErrStatus.Details != nil {
+		for _, cause := range statusErr.ErrStatus.Details.Causes {
+			if cause.Type == metav1.CauseTypeFieldManagerConflict {
+				fields = append(fields, fmt.Sprintf("%s (%s)", cause.Field, cause.Message))
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Sprintf("%s: field ownership conflict on %s: %s", providerName, name, err.Error())
+	}
+	return fmt.Sprintf("%s: field ownership conflict on %s: %s", providerName, name, strings.Join(fields, "; "))
+}
@@ -18,6 +18,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +39,14 @@ type (
 		tokenProvider TokenProvider
 		client        *http.Client
 		caCertPath    string
+		clientCert    *clientCertificate
+	}
+
+	// clientCertificate is a client certificate kept in files. They're read on each TLS handshake, so a rotated
+	// certificate is picked up without recreating the client.
+	clientCertificate struct {
+		certFile string
+		keyFile  string
 	}
 
 	ApiClientOption func(*apiClient)
@@ -73,8 +82,13 @@ type (
 		CreateTarget(ctx context.Context, target string, payload []byte, namespace string, user string, password string) error
 		Reconcile(ctx context.Context, deployment model.DeploymentSpec, isDelete bool, namespace string, user string, password string) (model.SummarySpec, error)
 		CatalogHook(ctx context.Context, payload []byte, user string, password string) error
+		CatalogDeleteHook(ctx context.Context, catalog string, namespace string, user string, password string) error
 		PublishActivationEvent(ctx context.Context, event v1alpha2.ActivationData, user string, password string) error
 		GetActivation(ctx context.Context, activation string, namespace string, user string, password string) (model.ActivationState, error)
+		ControlActivation(ctx context.Context, activation string, namespace string, state string, user string, password string) error
+		CreateActivation(ctx context.Context, activation string, payload []byte, namespace string, user string, password string) error
+		DeleteActivation(ctx context.Context, activation string, namespace string, user string, password string) error
+		GetCampaign(ctx context.Context, campaign string, namespace string, user string, password string) (model.CampaignState, error)
 		GetCatalog(ctx context.Context, catalog string, namespace string, user string, password string) (model.CatalogState, error)
 		UpsertCatalog(ctx context.Context, catalog string, payload []byte, user string, password string) error
 		DeleteCatalog(ctx context.Context, catalog string, user string, password string) error
@@ -84,7 +98,12 @@ type (
 		GetCatalogsWithFilter(ctx context.Context, namespace string, filterType string, filterValue string, user string, password string) ([]model.CatalogState, error)
 		UpdateSite(ctx context.Context, site string, payload []byte, user string, password string) error
 		GetABatchForSite(ctx context.Context, site string, user string, password string) (model.SyncPackage, error)
+		GetSyncPackageForSite(ctx context.Context, site string, watermark model.SyncWatermark, user string, password string) (model.SyncPackage, error)
 		SyncStageStatus(ctx context.Context, status model.StageStatus, user string, password string) error
+		OpenSyncChannel(ctx context.Context, site string, watermark model.SyncWatermark, user string, password string) (io.ReadCloser, error)
+		SendChannelMessages(ctx context.Context, site string, messages []model.ChannelMessage, user string, password string) error
+		EnrollSite(ctx context.Context, request model.SiteEnrollmentRequest) (model.SiteEnrollment, error)
+		RotateSiteCertificate(ctx context.Context, request model.SiteEnrollmentRequest, user string, password string) (model.SiteEnrollment, error)
 		SendVisualizationPacket(ctx context.Context, payload []byte, user string, password string) error
 		ReportCatalogs(ctx context.Context, instance string, components []model.ComponentSpec, user string, password string) error
 		CreateSolutionContainer(ctx context.Context, instanceContainer string, payload []byte, namespace string, user string, password string) error
@@ -181,6 +200,17 @@ func WithCertAuth(caCertPath string) ApiClientOption {
 	}
 }
 
+// WithClientCert authenticates with a client certificate once its files exist, instead of a token. Until then,
+// requests keep using the token provider.
+func WithClientCert(certFile string, keyFile string) ApiClientOption {
+	return func(a *apiClient) {
+		if certFile == "" || keyFile == "" {
+			return
+		}
+		a.clientCert = &clientCertificate{certFile: certFile, keyFile: keyFile}
+	}
+}
+
 func NewApiClient(ctx context.Context, baseUrl string, opts ...ApiClientOption) (*apiClient, error) {
 	rUrl, err := url.Parse(baseUrl)
 	if err != nil {
@@ -189,24 +219,50 @@ func NewApiClient(ctx context.Context, baseUrl string, opts ...ApiClientOption)
 
 	isSecure := rUrl.Scheme == "https"
 
-	client, err := newHttpClient(ctx, isSecure)
-	if err != nil {
-		return nil, err
-	}
-
 	a := &apiClient{
 		baseUrl:       baseUrl,
 		tokenProvider: noTokenProvider,
-		client:        client,
 	}
 
 	for _, opt := range opts {
 		opt(a)
 	}
 
+	a.client, err = newHttpClient(ctx, isSecure, a.clientCert)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.clientCert != nil {
+		tokenProvider := a.tokenProvider
+		a.tokenProvider = func(ctx context.Context, baseUrl string, client *http.Client, user string, password string) (string, error) {
+			if a.clientCert.available() {
+				return "", nil
+			}
+			return tokenProvider(ctx, baseUrl, client, user, password)
+		}
+	}
+
 	return a, nil
 }
 
+func (c *clientCertificate) available() bool {
+	if _, err := os.Stat(c.certFile); err != nil {
+		return false
+	}
+	_, err := os.Stat(c.keyFile)
+	return err == nil
+}
+
+func (c *clientCertificate) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		// no certificate is sent, the parent then rejects the request if it needs one
+		return &tls.Certificate{}, nil
+	}
+	return &cert, nil
+}
+
 func (a *apiClient) GetInstances(ctx context.Context, namespace string, user string, password string) ([]model.InstanceState, error) {
 	ret := make([]model.InstanceState, 0)
 	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
@@ -603,6 +659,25 @@ func (a *apiClient) CatalogHook(ctx context.Context, payload []byte, user string
 	return nil
 }
 
+func (a *apiClient) CatalogDeleteHook(ctx context.Context, catalog string, namespace string, user string, password string) error {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      catalog,
+			Namespace: namespace,
+		},
+	})
+	path := "federation/k8shook?objectType=catalog&action=delete"
+	_, err = a.callRestAPI(ctx, path, "POST", payload, token)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (a *apiClient) PublishActivationEvent(ctx context.Context, event v1alpha2.ActivationData, user string, password string) error {
 	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
 
@@ -640,6 +715,81 @@ func (a *apiClient) GetActivation(ctx context.Context, activation string, namesp
 	return ret, nil
 }
 
+// ControlActivation pauses, resumes or cancels an activation according to the desired state
+func (a *apiClient) ControlActivation(ctx context.Context, activation string, namespace string, state string, user string, password string) error {
+	var operation string
+	switch state {
+	case model.ActivationStatePaused:
+		operation = "pause"
+	case "", model.ActivationStateRunning:
+		operation = "resume"
+	case model.ActivationStateCancelled:
+		operation = "cancel"
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid activation state %s", state), v1alpha2.BadRequest)
+	}
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.callRestAPI(ctx, "activations/"+operation+"/"+url.QueryEscape(activation)+"?namespace="+url.QueryEscape(namespace), "POST", nil, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *apiClient) CreateActivation(ctx context.Context, activation string, payload []byte, namespace string, user string, password string) error {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.callRestAPI(ctx, "activations/registry/"+url.QueryEscape(activation)+"?namespace="+url.QueryEscape(namespace), "POST", payload, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *apiClient) DeleteActivation(ctx context.Context, activation string, namespace string, user string, password string) error {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.callRestAPI(ctx, "activations/registry/"+url.QueryEscape(activation)+"?namespace="+url.QueryEscape(namespace), "DELETE", nil, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *apiClient) GetCampaign(ctx context.Context, campaign string, namespace string, user string, password string) (model.CampaignState, error) {
+	ret := model.CampaignState{}
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+
+	if err != nil {
+		return ret, err
+	}
+
+	response, err := a.callRestAPI(ctx, "campaigns/"+url.QueryEscape(campaign)+"?namespace="+url.QueryEscape(namespace), "GET", nil, token)
+	if err != nil {
+		return ret, err
+	}
+
+	err = json.Unmarshal(response, &ret)
+	if err != nil {
+		return ret, err
+	}
+
+	return ret, nil
+}
+
 func (a *apiClient) GetCatalog(ctx context.Context, catalog string, namespace string, user string, password string) (model.CatalogState, error) {
 	ret := model.CatalogState{}
 	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
@@ -806,6 +956,118 @@ func (a *apiClient) GetABatchForSite(ctx context.Context, site string, user stri
 	return ret, nil
 }
 
+func (a *apiClient) GetSyncPackageForSite(ctx context.Context, site string, watermark model.SyncWatermark, user string, password string) (model.SyncPackage, error) {
+	ret := model.SyncPackage{}
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+
+	if err != nil {
+		return ret, err
+	}
+
+	path := "federation/sync/" + url.QueryEscape(site) + "?count=10&since=" + strconv.FormatInt(watermark.Revision, 10)
+	if watermark.Epoch != "" {
+		path += "&epoch=" + url.QueryEscape(watermark.Epoch)
+	}
+	if watermark.Resync {
+		path += "&resync=true"
+	}
+	response, err := a.callRestAPI(ctx, path, "GET", nil, token)
+	if err != nil {
+		return ret, err
+	}
+
+	err = json.Unmarshal(response, &ret)
+	if err != nil {
+		return ret, err
+	}
+	return ret, nil
+}
+
+// OpenSyncChannel opens the push channel of a site. The returned stream has the newline-delimited JSON frames
+// the parent writes. It isn't retried, as the caller reconnects when the channel drops.
+func (a *apiClient) OpenSyncChannel(ctx context.Context, site string, watermark model.SyncWatermark, user string, password string) (io.ReadCloser, error) {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	route := "federation/channel/" + url.QueryEscape(site) + "?since=" + strconv.FormatInt(watermark.Revision, 10)
+	if watermark.Epoch != "" {
+		route += "&epoch=" + url.QueryEscape(watermark.Epoch)
+	}
+	if watermark.Resync {
+		route += "&resync=true"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseUrl+route, nil)
+	if err != nil {
+		return nil, err
+	}
+	observ_utils.PropagateSpanContextToHttpRequestHeader(req)
+	coacontexts.PropagateActivityLogContextToHttpRequestHeader(req)
+	coacontexts.PropagateDiagnosticLogContextToHttpRequestHeader(req)
+	req.Header.Set("Accept", "application/x-ndjson")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, NewAPIError(v1alpha2.GetHttpStatus(resp.StatusCode), fmt.Sprintf("Symphony API: %s", string(bodyBytes)))
+	}
+	return resp.Body, nil
+}
+
+// SendChannelMessages sends status reports and heartbeats of a site over its push channel
+func (a *apiClient) SendChannelMessages(ctx context.Context, site string, messages []model.ChannelMessage, user string, password string) error {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	encoder := json.NewEncoder(&payload)
+	for _, message := range messages {
+		if err = encoder.Encode(message); err != nil {
+			return err
+		}
+	}
+	_, err = a.callRestAPI(ctx, "federation/channel/"+url.QueryEscape(site), "POST", payload.Bytes(), token)
+	return err
+}
+
+// EnrollSite sends the certificate request of a site with its join token. It's sent without credentials, the
+// join token is what authenticates the site.
+func (a *apiClient) EnrollSite(ctx context.Context, request model.SiteEnrollmentRequest) (model.SiteEnrollment, error) {
+	ret := model.SiteEnrollment{}
+	jData, _ := json.Marshal(request)
+	response, err := a.callRestAPI(ctx, "federation/enroll", "POST", jData, "")
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(response, &ret)
+	return ret, err
+}
+
+// RotateSiteCertificate sends a new certificate request of a site, authenticated with its current certificate
+func (a *apiClient) RotateSiteCertificate(ctx context.Context, request model.SiteEnrollmentRequest, user string, password string) (model.SiteEnrollment, error) {
+	ret := model.SiteEnrollment{}
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return ret, err
+	}
+	jData, _ := json.Marshal(request)
+	response, err := a.callRestAPI(ctx, "federation/certificates/"+url.QueryEscape(request.Site), "POST", jData, token)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(response, &ret)
+	return ret, err
+}
+
 func (a *apiClient) SyncStageStatus(ctx context.Context, status model.StageStatus, user string, password string) error {
 	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
 
@@ -1073,7 +1335,7 @@ func (a *apiClient) GetCampaignContainer(ctx context.Context, campaignContainer
 	return ret, nil
 }
 
-func newHttpClient(ctx context.Context, secure bool) (*http.Client, error) {
+func newHttpClient(ctx context.Context, secure bool, clientCert *clientCertificate) (*http.Client, error) {
 	client := &http.Client{}
 	if !secure {
 		return client, nil
@@ -1087,11 +1349,15 @@ func newHttpClient(ctx context.Context, secure bool) (*http.Client, error) {
 	updateTransport := func(certBytes []byte) {
 		caCertPool := x509.NewCertPool()
 		caCertPool.AppendCertsFromPEM(certBytes)
+		tlsConfig := &tls.Config{
+			RootCAs:            caCertPool,
+			InsecureSkipVerify: false,
+		}
+		if clientCert != nil {
+			tlsConfig.GetClientCertificate = clientCert.get
+		}
 		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:            caCertPool,
-				InsecureSkipVerify: false,
-			},
+			TLSClientConfig: tlsConfig,
 		}
 	}
 
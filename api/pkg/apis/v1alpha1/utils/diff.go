@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+)
+
+// DiffProperties gets the changes from one set of properties to another. Objects are compared key by key, and
+// other values, including arrays, as a whole.
+func DiffProperties(old interface{}, new interface{}) []model.PropertyChange {
+	changes := make([]model.PropertyChange, 0)
+	diffValues("", toJsonValue(old), toJsonValue(new), &changes)
+	return changes
+}
+
+// toJsonValue converts a value to what it reads as from JSON, so that values of different types compare
+// by their content. A nil value is an empty object.
+func toJsonValue(value interface{}) interface{} {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return map[string]interface{}{}
+	}
+	jData, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var ret interface{}
+	if err = json.Unmarshal(jData, &ret); err != nil {
+		return value
+	}
+	return ret
+}
+
+func diffValues(path string, old interface{}, new interface{}, changes *[]model.PropertyChange) {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if !reflect.DeepEqual(old, new) {
+			*changes = append(*changes, model.PropertyChange{Path: path, Op: "replace", Old: old, New: new})
+		}
+		return
+	}
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		childPath := path + "/" + escapePointerToken(k)
+		oldValue, inOld := oldMap[k]
+		newValue, inNew := newMap[k]
+		switch {
+		case !inOld:
+			*changes = append(*changes, model.PropertyChange{Path: childPath, Op: "add", New: newValue})
+		case !inNew:
+			*changes = append(*changes, model.PropertyChange{Path: childPath, Op: "remove", Old: oldValue})
+		default:
+			diffValues(childPath, oldValue, newValue, changes)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// PropertySchema checks properties against the spec of a schema catalog
+type PropertySchema interface {
+	CheckProperties(ctx context.Context, properties map[string]interface{}, evaluationContext *coa_utils.EvaluationContext) (SchemaResult, error)
+}
+
+// JsonSchema is a standard JSON Schema document. Its errors are keyed by the JSON pointer of the invalid value.
+type JsonSchema struct {
+	document map[string]interface{}
+	schema   *gojsonschema.Schema
+}
+
+// ParseSchema reads the spec of a schema catalog. A spec with only rules is a Schema of rules on top-level
+// properties, and anything else is a JSON Schema.
+func ParseSchema(spec interface{}) (PropertySchema, error) {
+	jData, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err = json.Unmarshal(jData, &document); err != nil {
+		return nil, fmt.Errorf("schema is not an object: %v", err)
+	}
+	if _, ok := document["rules"]; ok && len(document) == 1 {
+		var schema Schema
+		if err = json.Unmarshal(jData, &schema); err != nil {
+			return nil, err
+		}
+		return &schema, nil
+	}
+	return NewJsonSchema(document)
+}
+
+// NewJsonSchema compiles a JSON Schema document
+func NewJsonSchema(document map[string]interface{}) (*JsonSchema, error) {
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	return &JsonSchema{document: document, schema: schema}, nil
+}
+
+// CheckProperties fills in the defaults of missing properties, then validates them. Expressions aren't part of
+// JSON Schema, so the evaluation context isn't used.
+func (s *JsonSchema) CheckProperties(ctx context.Context, properties map[string]interface{}, evaluationContext *coa_utils.EvaluationContext) (SchemaResult, error) {
+	s.ApplyDefaults(properties)
+	result, err := s.schema.Validate(gojsonschema.NewGoLoader(properties))
+	if err != nil {
+		return SchemaResult{}, err
+	}
+	ret := SchemaResult{Valid: result.Valid(), Errors: make(map[string]RuleResult)}
+	for _, e := range result.Errors() {
+		pointer := jsonPointer(e.Context())
+		if e.Type() == "required" {
+			pointer += "/" + escapePointerToken(FormatAsString(e.Details()["property"]))
+		}
+		message := e.Description()
+		if existing, ok := ret.Errors[pointer]; ok {
+			message = existing.Error + "; " + message
+		}
+		ret.Errors[pointer] = RuleResult{Valid: false, Error: message}
+	}
+	return ret, nil
+}
+
+// ApplyDefaults sets the defaults of the schema on the missing properties of a value, following properties,
+// items and allOf. Defaults under oneOf and anyOf are ambiguous, so they're not applied.
+func (s *JsonSchema) ApplyDefaults(value interface{}) {
+	applyDefaults(s.document, value)
+}
+
+func applyDefaults(schema map[string]interface{}, value interface{}) {
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				applyDefaults(subSchema, value)
+			}
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name, p := range properties {
+			propertySchema, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, exists := v[name]; !exists {
+				if d, ok := propertySchema["default"]; ok {
+					v[name] = copyJson(d)
+				}
+			}
+			if child, exists := v[name]; exists {
+				applyDefaults(propertySchema, child)
+			}
+		}
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range v {
+			applyDefaults(items, item)
+		}
+	}
+}
+
+// copyJson copies a default, so that values it's set on don't share maps and slices
+func copyJson(value interface{}) interface{} {
+	jData, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var ret interface{}
+	if err = json.Unmarshal(jData, &ret); err != nil {
+		return value
+	}
+	return ret
+}
+
+// jsonPointer converts the location of a validation error to a JSON pointer (RFC 6901)
+func jsonPointer(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+	tokens := strings.Split(context.String("\x00"), "\x00")
+	var sb strings.Builder
+	for _, token := range tokens[1:] {
+		sb.WriteString("/")
+		sb.WriteString(escapePointerToken(token))
+	}
+	return sb.String()
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
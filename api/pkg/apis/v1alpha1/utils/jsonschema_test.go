@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const deviceSchema = `{
+	"type": "object",
+	"required": ["name", "network"],
+	"properties": {
+		"name": {"type": "string"},
+		"mode": {"enum": ["edge", "cloud"], "default": "edge"},
+		"network": {
+			"type": "object",
+			"required": ["port"],
+			"properties": {
+				"port": {"type": "integer", "maximum": 65535},
+				"protocol": {"type": "string", "default": "tcp"}
+			}
+		},
+		"sensors": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"unit": {"type": "string", "default": "celsius"},
+					"a/b": {"type": "string"}
+				}
+			}
+		},
+		"endpoint": {
+			"oneOf": [
+				{"type": "string", "format": "uri"},
+				{"type": "object", "required": ["host"]}
+			]
+		}
+	}
+}`
+
+func parseJson(t *testing.T, text string) map[string]interface{} {
+	var ret map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(text), &ret))
+	return ret
+}
+
+func TestParseSchemaRules(t *testing.T) {
+	schema, err := ParseSchema(map[string]interface{}{
+		"rules": map[string]interface{}{
+			"email": map[string]interface{}{"pattern": "<email>"},
+		},
+	})
+	assert.Nil(t, err)
+	rules, ok := schema.(*Schema)
+	assert.True(t, ok)
+	assert.Equal(t, "<email>", rules.Rules["email"].Pattern)
+}
+
+func TestParseSchemaInvalid(t *testing.T) {
+	_, err := ParseSchema(map[string]interface{}{"type": 12})
+	assert.NotNil(t, err)
+	_, err = ParseSchema("not an object")
+	assert.NotNil(t, err)
+}
+
+func TestJsonSchemaValid(t *testing.T) {
+	schema, err := ParseSchema(parseJson(t, deviceSchema))
+	assert.Nil(t, err)
+	properties := parseJson(t, `{
+		"name": "camera",
+		"network": {"port": 8080},
+		"sensors": [{"unit": "kelvin"}, {}],
+		"endpoint": {"host": "camera.local"}
+	}`)
+	result, err := schema.CheckProperties(ctx, properties, nil)
+	assert.Nil(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, "edge", properties["mode"])
+	assert.Equal(t, "tcp", properties["network"].(map[string]interface{})["protocol"])
+	sensors := properties["sensors"].([]interface{})
+	assert.Equal(t, "kelvin", sensors[0].(map[string]interface{})["unit"])
+	assert.Equal(t, "celsius", sensors[1].(map[string]interface{})["unit"])
+}
+
+func TestJsonSchemaErrorPointers(t *testing.T) {
+	schema, err := ParseSchema(parseJson(t, deviceSchema))
+	assert.Nil(t, err)
+	properties := parseJson(t, `{
+		"mode": "fog",
+		"network": {"port": 70000},
+		"sensors": [{"a/b": 1}],
+		"endpoint": 12
+	}`)
+	result, err := schema.CheckProperties(ctx, properties, nil)
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors, "/name")
+	assert.Contains(t, result.Errors, "/mode")
+	assert.Contains(t, result.Errors, "/network/port")
+	assert.Contains(t, result.Errors, "/sensors/0/a~1b")
+	assert.Contains(t, result.Errors, "/endpoint")
+	assert.Equal(t, 5, len(result.Errors))
+	assert.Contains(t, result.ToErrorMessages(), "/network/port: ")
+}
+
+func TestJsonSchemaRootError(t *testing.T) {
+	schema, err := ParseSchema(map[string]interface{}{"minProperties": 1})
+	assert.Nil(t, err)
+	result, err := schema.CheckProperties(ctx, map[string]interface{}{}, nil)
+	assert.Nil(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors, "")
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// Cache is a local content-addressed store for artifact blobs. Blobs are stored under
+// <dir>/blobs/<algorithm>/<encoded digest>, so the same layer pulled by different artifacts
+// or providers is only downloaded once. When the cache grows beyond its maximum size, the
+// least recently used blobs are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64
+	lock    sync.Mutex
+}
+
+// NewCache creates a cache in a directory. A maxSize of 0 or less disables eviction.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "blobs"), 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// Path returns the location of a blob in the cache
+func (c *Cache) Path(dgst digest.Digest) string {
+	return filepath.Join(c.dir, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+// Exists checks whether a blob is in the cache and marks it as recently used
+func (c *Cache) Exists(dgst digest.Digest) bool {
+	if dgst.Validate() != nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	path := c.Path(dgst)
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return true
+}
+
+// Read returns the content of a blob in the cache
+func (c *Cache) Read(dgst digest.Digest) ([]byte, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return os.ReadFile(c.Path(dgst))
+}
+
+// Write stores a blob read from a reader. The content is verified against the digest before it
+// becomes visible in the cache, so a partial or tampered download never poisons the cache.
+func (c *Cache) Write(dgst digest.Digest, reader io.Reader) error {
+	if err := dgst.Validate(); err != nil {
+		return err
+	}
+	path := c.Path(dgst)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	verifier := dgst.Verifier()
+	_, err = io.Copy(io.MultiWriter(file, verifier), reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("content doesn't match digest %s", dgst)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err = os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	return c.evict(path)
+}
+
+// Size returns the total size of the blobs in the cache
+func (c *Cache) Size() (int64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.size
+	}
+	return size, nil
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) entries() ([]cacheEntry, error) {
+	ret := make([]cacheEntry, 0)
+	err := filepath.WalkDir(filepath.Join(c.dir, "blobs"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Base(path)[0] == '.' {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		ret = append(ret, cacheEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return ret, err
+}
+
+// evict removes the least recently used blobs until the cache fits in its maximum size.
+// The blob that was just written is kept even if it alone exceeds the maximum size.
+func (c *Cache) evict(keep string) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.size
+	}
+	if size <= c.maxSize {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, entry := range entries {
+		if size <= c.maxSize {
+			break
+		}
+		if entry.path == keep {
+			continue
+		}
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		size -= entry.size
+	}
+	return nil
+}
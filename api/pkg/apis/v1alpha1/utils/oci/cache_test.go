@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheWriteAndRead(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 0)
+	assert.Nil(t, err)
+	data := []byte("payload")
+	dgst := digest.FromBytes(data)
+	assert.False(t, cache.Exists(dgst))
+	assert.Nil(t, cache.Write(dgst, bytes.NewReader(data)))
+	assert.True(t, cache.Exists(dgst))
+	read, err := cache.Read(dgst)
+	assert.Nil(t, err)
+	assert.Equal(t, data, read)
+}
+
+func TestCacheRejectsTamperedContent(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 0)
+	assert.Nil(t, err)
+	dgst := digest.FromBytes([]byte("payload"))
+	assert.NotNil(t, cache.Write(dgst, bytes.NewReader([]byte("tampered"))))
+	assert.False(t, cache.Exists(dgst))
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 250)
+	assert.Nil(t, err)
+	blobs := [][]byte{
+		bytes.Repeat([]byte("a"), 100),
+		bytes.Repeat([]byte("b"), 100),
+		bytes.Repeat([]byte("c"), 100),
+	}
+	past := time.Now().Add(-time.Hour)
+	for i, blob := range blobs[:2] {
+		dgst := digest.FromBytes(blob)
+		assert.Nil(t, cache.Write(dgst, bytes.NewReader(blob)))
+		// make the access order deterministic: the first blob is the least recently used
+		os.Chtimes(cache.Path(dgst), past.Add(time.Duration(i)*time.Minute), past.Add(time.Duration(i)*time.Minute))
+	}
+	assert.Nil(t, cache.Write(digest.FromBytes(blobs[2]), bytes.NewReader(blobs[2])))
+
+	assert.False(t, cache.Exists(digest.FromBytes(blobs[0])))
+	assert.True(t, cache.Exists(digest.FromBytes(blobs[1])))
+	assert.True(t, cache.Exists(digest.FromBytes(blobs[2])))
+	size, err := cache.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), size)
+}
@@ -0,0 +1,386 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	// DefaultCacheDir is where artifacts are cached unless configured otherwise
+	DefaultCacheDir = "/tmp/symphony/oci"
+	// DefaultMaxCacheSize is the cache size beyond which the least recently used blobs are evicted
+	DefaultMaxCacheSize int64 = 2 << 30
+	// unpackAnnotation marks a layer holding a gzipped tarball of a directory, as pushed by oras
+	unpackAnnotation = "io.deis.oras.content.unpack"
+	// maxManifestSize bounds the manifests read into memory
+	maxManifestSize int64 = 4 << 20
+)
+
+var (
+	log          = logger.NewLogger("coa.runtime")
+	fetchers     = map[string]*Fetcher{}
+	fetchersLock sync.Mutex
+)
+
+type (
+	// FetcherConfig configures the local cache of a fetcher
+	FetcherConfig struct {
+		CacheDir     string `json:"cacheDir,omitempty"`
+		MaxCacheSize int64  `json:"maxCacheSize,omitempty"`
+	}
+
+	// SecretReference locates registry credentials in the secret provider
+	SecretReference struct {
+		Name          string `json:"name"`
+		UsernameField string `json:"usernameField,omitempty"`
+		PasswordField string `json:"passwordField,omitempty"`
+	}
+
+	// PullOptions controls how an artifact is pulled
+	PullOptions struct {
+		Username string           `json:"username,omitempty"`
+		Password string           `json:"password,omitempty"`
+		Secret   *SecretReference `json:"secret,omitempty"`
+		// RequireDigest rejects references that are not pinned by digest
+		RequireDigest bool `json:"requireDigest,omitempty"`
+		// Verifier is the name of a registered signature verifier
+		Verifier string `json:"verifier,omitempty"`
+		// PlainHTTP talks to the registry without TLS
+		PlainHTTP bool `json:"plainHttp,omitempty"`
+
+		// SecretProvider resolves Secret, usually the one of the vendor evaluation context
+		SecretProvider secret.IExtSecretProvider `json:"-"`
+		// Namespace is the namespace the secret is read from
+		Namespace string `json:"-"`
+	}
+
+	// Artifact is a pulled artifact whose blobs are in the cache
+	Artifact struct {
+		Reference    string            `json:"reference"`
+		Digest       digest.Digest     `json:"digest"`
+		ArtifactType string            `json:"artifactType,omitempty"`
+		Annotations  map[string]string `json:"annotations,omitempty"`
+		Layers       []Layer           `json:"layers"`
+	}
+
+	// Layer is a blob of a pulled artifact
+	Layer struct {
+		Digest      digest.Digest     `json:"digest"`
+		MediaType   string            `json:"mediaType"`
+		Size        int64             `json:"size"`
+		Title       string            `json:"title,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		// Path is the location of the blob in the cache
+		Path string `json:"-"`
+	}
+
+	// Fetcher pulls artifacts from OCI registries into a local content-addressed cache
+	Fetcher struct {
+		Cache *Cache
+	}
+)
+
+// GetFetcher returns the fetcher of a cache directory. Fetchers are shared per directory so that
+// all providers using the same cache see the same content and the same eviction.
+func GetFetcher(config FetcherConfig) (*Fetcher, error) {
+	if config.CacheDir == "" {
+		config.CacheDir = DefaultCacheDir
+	}
+	if config.MaxCacheSize == 0 {
+		config.MaxCacheSize = DefaultMaxCacheSize
+	}
+	fetchersLock.Lock()
+	defer fetchersLock.Unlock()
+	if fetcher, ok := fetchers[config.CacheDir]; ok {
+		return fetcher, nil
+	}
+	cache, err := NewCache(config.CacheDir, config.MaxCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := &Fetcher{Cache: cache}
+	fetchers[config.CacheDir] = fetcher
+	return fetcher, nil
+}
+
+// ToPullOptions converts a component property or stage input to PullOptions
+func ToPullOptions(value interface{}) (PullOptions, error) {
+	ret := PullOptions{}
+	if value == nil {
+		return ret, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// Pull resolves a reference, verifies its signature when a verifier is set and makes the manifest
+// and all layers available in the cache. Blobs already in the cache are not downloaded again.
+func (f *Fetcher) Pull(ctx context.Context, reference string, options PullOptions) (*Artifact, error) {
+	reference = strings.TrimPrefix(reference, "oci://")
+	ref, err := registry.ParseReference(reference)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid artifact reference %s", reference), v1alpha2.BadConfig)
+	}
+	if ref.Reference == "" {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact reference %s requires a tag or digest", reference), v1alpha2.BadConfig)
+	}
+	pinned, pinnedErr := ref.Digest()
+	if options.RequireDigest && pinnedErr != nil {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact reference %s must be pinned by digest", reference), v1alpha2.BadConfig)
+	}
+
+	repo, err := remote.NewRepository(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid artifact reference %s", reference), v1alpha2.BadConfig)
+	}
+	repo.PlainHTTP = options.PlainHTTP
+	client := &auth.Client{
+		Client: retry.DefaultClient,
+		Cache:  auth.NewCache(),
+	}
+	credential, err := options.getCredential(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credential != auth.EmptyCredential {
+		client.Credential = auth.StaticCredential(ref.Registry, credential)
+	}
+	repo.Client = client
+
+	manifestDesc, err := repo.Resolve(ctx, ref.Reference)
+	if err != nil {
+		log.ErrorfCtx(ctx, "  OCI: failed to resolve %s: %+v", reference, err)
+		return nil, toCOAError(err, fmt.Sprintf("failed to resolve artifact %s", reference))
+	}
+	if pinnedErr == nil && manifestDesc.Digest != pinned {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact %s resolved to unexpected digest %s", reference, manifestDesc.Digest), v1alpha2.BadRequest)
+	}
+	if manifestDesc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact %s has unsupported manifest type %s", reference, manifestDesc.MediaType), v1alpha2.BadRequest)
+	}
+	if manifestDesc.Size > maxManifestSize {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("manifest of artifact %s is too large", reference), v1alpha2.BadRequest)
+	}
+
+	if options.Verifier != "" {
+		verifier, ok := GetVerifier(options.Verifier)
+		if !ok {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("signature verifier %s is not registered", options.Verifier), v1alpha2.BadConfig)
+		}
+		if err = verifier.Verify(ctx, repo, reference, manifestDesc); err != nil {
+			log.ErrorfCtx(ctx, "  OCI: failed to verify signature of %s: %+v", reference, err)
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to verify signature of artifact %s", reference), v1alpha2.Unauthorized)
+		}
+	}
+
+	if err = f.ensureBlob(ctx, repo, manifestDesc); err != nil {
+		return nil, toCOAError(err, fmt.Sprintf("failed to pull manifest of artifact %s", reference))
+	}
+	data, err := f.Cache.Read(manifestDesc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("artifact %s has an invalid manifest", reference), v1alpha2.BadRequest)
+	}
+
+	artifact := &Artifact{
+		Reference:    reference,
+		Digest:       manifestDesc.Digest,
+		ArtifactType: manifest.ArtifactType,
+		Annotations:  manifest.Annotations,
+		Layers:       make([]Layer, 0, len(manifest.Layers)),
+	}
+	if artifact.ArtifactType == "" {
+		artifact.ArtifactType = manifest.Config.MediaType
+	}
+	for _, layerDesc := range manifest.Layers {
+		if err = f.ensureBlob(ctx, repo, layerDesc); err != nil {
+			return nil, toCOAError(err, fmt.Sprintf("failed to pull layer %s of artifact %s", layerDesc.Digest, reference))
+		}
+		artifact.Layers = append(artifact.Layers, Layer{
+			Digest:      layerDesc.Digest,
+			MediaType:   layerDesc.MediaType,
+			Size:        layerDesc.Size,
+			Title:       layerDesc.Annotations[ocispec.AnnotationTitle],
+			Annotations: layerDesc.Annotations,
+			Path:        f.Cache.Path(layerDesc.Digest),
+		})
+	}
+	log.InfofCtx(ctx, "  OCI: pulled %s (%s) with %d layers", reference, manifestDesc.Digest, len(artifact.Layers))
+	return artifact, nil
+}
+
+// ensureBlob downloads a blob into the cache unless it is already there
+func (f *Fetcher) ensureBlob(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor) error {
+	if f.Cache.Exists(desc.Digest) {
+		return nil
+	}
+	reader, err := repo.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return f.Cache.Write(desc.Digest, io.LimitReader(reader, desc.Size+1))
+}
+
+// getCredential returns the registry credential, reading it from the secret provider when a secret is referenced
+func (o PullOptions) getCredential(ctx context.Context) (auth.Credential, error) {
+	if o.Secret == nil {
+		return auth.Credential{Username: o.Username, Password: o.Password}, nil
+	}
+	if o.SecretProvider == nil {
+		return auth.EmptyCredential, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret provider is not available to read registry secret %s", o.Secret.Name), v1alpha2.MissingConfig)
+	}
+	usernameField := o.Secret.UsernameField
+	if usernameField == "" {
+		usernameField = "username"
+	}
+	passwordField := o.Secret.PasswordField
+	if passwordField == "" {
+		passwordField = "password"
+	}
+	evaluationContext := coa_utils.EvaluationContext{
+		Namespace: o.Namespace,
+		Context:   ctx,
+	}
+	username, err := o.SecretProvider.Get(ctx, o.Secret.Name, usernameField, evaluationContext)
+	if err != nil {
+		return auth.EmptyCredential, err
+	}
+	password, err := o.SecretProvider.Get(ctx, o.Secret.Name, passwordField, evaluationContext)
+	if err != nil {
+		return auth.EmptyCredential, err
+	}
+	return auth.Credential{Username: username, Password: password}, nil
+}
+
+// ReadLayer returns the content of the layer with a given title
+func (a *Artifact) ReadLayer(title string) ([]byte, error) {
+	for _, layer := range a.Layers {
+		if layer.Title == title {
+			return os.ReadFile(layer.Path)
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact %s has no layer titled %s", a.Reference, title), v1alpha2.NotFound)
+}
+
+// Extract writes the titled layers of an artifact to a directory. Layers pushed as directories
+// are unpacked; layers without a title are skipped.
+func (a *Artifact) Extract(dir string) error {
+	for _, layer := range a.Layers {
+		if layer.Title == "" {
+			continue
+		}
+		if !filepath.IsLocal(layer.Title) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("layer title %s must be a relative path", layer.Title), v1alpha2.BadRequest)
+		}
+		target := filepath.Join(dir, layer.Title)
+		data, err := os.ReadFile(layer.Path)
+		if err != nil {
+			return err
+		}
+		if layer.Annotations[unpackAnnotation] == "true" {
+			if err = untar(data, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untar unpacks a gzipped tarball of a directory. The tarball may contain the directory itself as its top entry.
+func untar(data []byte, dir string) error {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+	base := filepath.Base(dir)
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		name := filepath.Clean(header.Name)
+		if name == base {
+			continue
+		}
+		name = strings.TrimPrefix(name, base+string(filepath.Separator))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("tarball entry %s escapes the target directory", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toCOAError maps registry errors to COA errors so that callers can tell missing artifacts from other failures
+func toCOAError(err error, message string) error {
+	if errors.Is(err, errdef.ErrNotFound) {
+		return v1alpha2.NewCOAError(err, message, v1alpha2.NotFound)
+	}
+	return v1alpha2.NewCOAError(err, message, v1alpha2.InternalError)
+}
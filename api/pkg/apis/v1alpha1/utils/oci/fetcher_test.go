@@ -0,0 +1,326 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRegistry is a minimal in-memory OCI distribution server
+type testRegistry struct {
+	server       *httptest.Server
+	manifests    map[string][]byte
+	blobs        map[digest.Digest][]byte
+	referrers    map[digest.Digest][]ocispec.Descriptor
+	blobRequests int
+	username     string
+	password     string
+	lock         sync.Mutex
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	r := &testRegistry{
+		manifests: map[string][]byte{},
+		blobs:     map[digest.Digest][]byte{},
+		referrers: map[digest.Digest][]ocispec.Descriptor{},
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *testRegistry) host() string {
+	return strings.TrimPrefix(r.server.URL, "http://")
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.username != "" {
+		user, password, ok := req.BasicAuth()
+		if !ok || user != r.username || password != r.password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case req.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case strings.Contains(path, "/manifests/"):
+		reference := path[strings.Index(path, "/manifests/")+len("/manifests/"):]
+		data, ok := r.manifests[reference]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case strings.Contains(path, "/blobs/"):
+		r.blobRequests++
+		data, ok := r.blobs[digest.Digest(path[strings.Index(path, "/blobs/")+len("/blobs/"):])]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Write(data)
+	case strings.Contains(path, "/referrers/"):
+		subject := digest.Digest(path[strings.Index(path, "/referrers/")+len("/referrers/"):])
+		index := ocispec.Index{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispec.MediaTypeImageIndex,
+			Manifests: []ocispec.Descriptor{},
+		}
+		for _, referrer := range r.referrers[subject] {
+			if artifactType := req.URL.Query().Get("artifactType"); artifactType == "" || artifactType == referrer.ArtifactType {
+				index.Manifests = append(index.Manifests, referrer)
+			}
+		}
+		data, _ := json.Marshal(index)
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// push stores an artifact with the given layers and returns its manifest digest
+func (r *testRegistry) push(tag string, layers map[string][]byte, annotations map[string]map[string]string) digest.Digest {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	config := []byte("{}")
+	r.blobs[digest.FromBytes(config)] = config
+	manifest := ocispec.Manifest{
+		Versioned:    specs.Versioned{SchemaVersion: 2},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: "application/vnd.symphony.payload",
+		Config:       ocispec.Descriptor{MediaType: ocispec.MediaTypeEmptyJSON, Digest: digest.FromBytes(config), Size: int64(len(config))},
+	}
+	for title, data := range layers {
+		dgst := digest.FromBytes(data)
+		r.blobs[dgst] = data
+		layerAnnotations := map[string]string{ocispec.AnnotationTitle: title}
+		for k, v := range annotations[title] {
+			layerAnnotations[k] = v
+		}
+		manifest.Layers = append(manifest.Layers, ocispec.Descriptor{
+			MediaType:   "application/octet-stream",
+			Digest:      dgst,
+			Size:        int64(len(data)),
+			Annotations: layerAnnotations,
+		})
+	}
+	data, _ := json.Marshal(manifest)
+	dgst := digest.FromBytes(data)
+	r.manifests[tag] = data
+	r.manifests[dgst.String()] = data
+	return dgst
+}
+
+func newTestFetcher(t *testing.T, maxSize int64) *Fetcher {
+	cache, err := NewCache(t.TempDir(), maxSize)
+	assert.Nil(t, err)
+	return &Fetcher{Cache: cache}
+}
+
+func TestPullByDigest(t *testing.T) {
+	registry := newTestRegistry(t)
+	dgst := registry.push("v1", map[string][]byte{"config.json": []byte(`{"a":1}`)}, nil)
+	fetcher := newTestFetcher(t, 0)
+
+	artifact, err := fetcher.Pull(context.Background(), fmt.Sprintf("oci://%s/payloads/app@%s", registry.host(), dgst), PullOptions{PlainHTTP: true, RequireDigest: true})
+	assert.Nil(t, err)
+	assert.Equal(t, dgst, artifact.Digest)
+	assert.Equal(t, "application/vnd.symphony.payload", artifact.ArtifactType)
+	assert.Equal(t, 1, len(artifact.Layers))
+	data, err := artifact.ReadLayer("config.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":1}`, string(data))
+
+	_, err = artifact.ReadLayer("missing.json")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestPullRequireDigest(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.push("v1", map[string][]byte{"config.json": []byte(`{}`)}, nil)
+	fetcher := newTestFetcher(t, 0)
+
+	_, err := fetcher.Pull(context.Background(), fmt.Sprintf("%s/payloads/app:v1", registry.host()), PullOptions{PlainHTTP: true, RequireDigest: true})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadConfig, coaErr.State)
+}
+
+func TestPullUsesCache(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.push("v1", map[string][]byte{"model.bin": bytes.Repeat([]byte("x"), 1024)}, nil)
+	fetcher := newTestFetcher(t, 0)
+	reference := fmt.Sprintf("%s/payloads/app:v1", registry.host())
+
+	_, err := fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true})
+	assert.Nil(t, err)
+	requests := registry.blobRequests
+	assert.Equal(t, 1, requests)
+
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true})
+	assert.Nil(t, err)
+	assert.Equal(t, requests, registry.blobRequests)
+}
+
+func TestPullNotFound(t *testing.T) {
+	registry := newTestRegistry(t)
+	fetcher := newTestFetcher(t, 0)
+	_, err := fetcher.Pull(context.Background(), fmt.Sprintf("%s/payloads/app:v1", registry.host()), PullOptions{PlainHTTP: true})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+type testSecretProvider struct {
+	secrets map[string]map[string]string
+}
+
+func (s *testSecretProvider) Get(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	if value, ok := s.secrets[name][field]; ok {
+		return value, nil
+	}
+	return "", v1alpha2.NewCOAError(nil, "secret not found", v1alpha2.NotFound)
+}
+
+func TestPullWithSecret(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.push("v1", map[string][]byte{"config.json": []byte(`{}`)}, nil)
+	registry.username = "reader"
+	registry.password = "s3cret"
+	fetcher := newTestFetcher(t, 0)
+	reference := fmt.Sprintf("%s/payloads/app:v1", registry.host())
+
+	_, err := fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true})
+	assert.NotNil(t, err)
+
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{
+		PlainHTTP: true,
+		Secret:    &SecretReference{Name: "registry", UsernameField: "user"},
+	})
+	assert.NotNil(t, err) // no secret provider
+
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{
+		PlainHTTP: true,
+		Secret:    &SecretReference{Name: "registry", UsernameField: "user"},
+		SecretProvider: &testSecretProvider{secrets: map[string]map[string]string{
+			"registry": {"user": "reader", "password": "s3cret"},
+		}},
+	})
+	assert.Nil(t, err)
+}
+
+func TestPullWithVerifier(t *testing.T) {
+	registry := newTestRegistry(t)
+	dgst := registry.push("v1", map[string][]byte{"config.json": []byte(`{}`)}, nil)
+	fetcher := newTestFetcher(t, 0)
+	reference := fmt.Sprintf("%s/payloads/app:v1", registry.host())
+
+	_, err := fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true, Verifier: "unknown"})
+	assert.NotNil(t, err)
+
+	// no verifiers are registered by default
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true, Verifier: "notation"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	_, ok := GetVerifier(ReferrerPresentVerifierName)
+	assert.False(t, ok)
+
+	RegisterVerifier(ReferrerPresentVerifierName, ReferrerPresentVerifier{ArtifactType: NotationSignatureArtifactType})
+	defer func() {
+		verifiersLock.Lock()
+		delete(verifiers, ReferrerPresentVerifierName)
+		verifiersLock.Unlock()
+	}()
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true, Verifier: ReferrerPresentVerifierName})
+	assert.NotNil(t, err)
+	coaErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Unauthorized, coaErr.State)
+
+	registry.referrers[dgst] = []ocispec.Descriptor{{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: NotationSignatureArtifactType,
+		Digest:       digest.FromString("signature"),
+		Size:         9,
+	}}
+	_, err = fetcher.Pull(context.Background(), reference, PullOptions{PlainHTTP: true, Verifier: ReferrerPresentVerifierName})
+	assert.Nil(t, err)
+}
+
+func TestExtract(t *testing.T) {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter.WriteHeader(&tar.Header{Name: "base/", Typeflag: tar.TypeDir, Mode: 0755})
+	content := []byte("resources: []\n")
+	tarWriter.WriteHeader(&tar.Header{Name: "base/kustomization.yaml", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+	tarWriter.Write(content)
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	registry := newTestRegistry(t)
+	registry.push("v1", map[string][]byte{
+		"base":        buffer.Bytes(),
+		"values.yaml": []byte("replicas: 1\n"),
+	}, map[string]map[string]string{
+		"base": {unpackAnnotation: "true"},
+	})
+	fetcher := newTestFetcher(t, 0)
+	artifact, err := fetcher.Pull(context.Background(), fmt.Sprintf("%s/payloads/app:v1", registry.host()), PullOptions{PlainHTTP: true})
+	assert.Nil(t, err)
+
+	dir := t.TempDir()
+	assert.Nil(t, artifact.Extract(dir))
+	data, err := os.ReadFile(filepath.Join(dir, "base", "kustomization.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, content, data)
+	data, err = os.ReadFile(filepath.Join(dir, "values.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "replicas: 1\n", string(data))
+}
+
+func TestToPullOptions(t *testing.T) {
+	options, err := ToPullOptions(map[string]interface{}{
+		"requireDigest": true,
+		"verifier":      "notation",
+		"secret": map[string]interface{}{
+			"name": "registry",
+		},
+	})
+	assert.Nil(t, err)
+	assert.True(t, options.RequireDigest)
+	assert.Equal(t, "notation", options.Verifier)
+	assert.Equal(t, "registry", options.Secret.Name)
+}
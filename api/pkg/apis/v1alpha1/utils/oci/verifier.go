@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// NotationSignatureArtifactType is the artifact type of Notary Project signatures
+	NotationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+	// SigstoreBundleArtifactType is the artifact type of Sigstore bundles attached as referrers
+	SigstoreBundleArtifactType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+)
+
+// SignatureSource gives verifiers access to the signatures stored next to an artifact
+type SignatureSource interface {
+	// Referrers lists the artifacts referring to a manifest, optionally filtered by artifact type
+	Referrers(ctx context.Context, desc ocispec.Descriptor, artifactType string, fn func(referrers []ocispec.Descriptor) error) error
+	// Fetch reads the content of a manifest or blob
+	Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error)
+}
+
+// SignatureVerifier is a hook that verifies the signature of an artifact before its content is used.
+// The manifest descriptor is resolved and digest-checked by the fetcher.
+type SignatureVerifier interface {
+	Verify(ctx context.Context, source SignatureSource, reference string, manifest ocispec.Descriptor) error
+}
+
+// ReferrerPresentVerifierName is the name to register a ReferrerPresentVerifier under
+const ReferrerPresentVerifierName = "referrer-present"
+
+// ReferrerPresentVerifier requires a referrer of a given artifact type, such as a signature, to be
+// attached to the artifact. It only checks that the referrer exists, not what it contains, so anyone
+// who can push to the registry can pass it. It's only meant for registries that verify signatures
+// when they're pushed, and isn't registered unless a deployment registers it.
+type ReferrerPresentVerifier struct {
+	ArtifactType string
+}
+
+// Verify checks that at least one referrer of the configured artifact type exists
+func (v ReferrerPresentVerifier) Verify(ctx context.Context, source SignatureSource, reference string, manifest ocispec.Descriptor) error {
+	found := false
+	err := source.Referrers(ctx, manifest, v.ArtifactType, func(referrers []ocispec.Descriptor) error {
+		if len(referrers) > 0 {
+			found = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no signature of type %s is attached to %s@%s", v.ArtifactType, reference, manifest.Digest)
+	}
+	return nil
+}
+
+var (
+	verifiers     = map[string]SignatureVerifier{}
+	verifiersLock sync.RWMutex
+)
+
+// RegisterVerifier makes a signature verifier available by name. No verifiers are registered by
+// default. Registering a name again replaces the previous verifier.
+func RegisterVerifier(name string, verifier SignatureVerifier) {
+	verifiersLock.Lock()
+	defer verifiersLock.Unlock()
+	verifiers[name] = verifier
+}
+
+// GetVerifier returns a registered signature verifier
+func GetVerifier(name string) (SignatureVerifier, bool) {
+	verifiersLock.RLock()
+	defer verifiersLock.RUnlock()
+	verifier, ok := verifiers[name]
+	return verifier, ok
+}
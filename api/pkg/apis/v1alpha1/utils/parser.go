@@ -399,6 +399,8 @@ func (n *BinaryNode) Eval(context utils.EvaluationContext) (interface{}, error)
 type FunctionNode struct {
 	Name string
 	Args []Node
+	// Column is the position of the function in the expression, which is reported in errors
+	Column int
 }
 
 func readProperty(properties map[string]string, key string) (string, error) {
@@ -864,6 +866,12 @@ func (n *FunctionNode) Eval(context utils.EvaluationContext) (interface{}, error
 		}
 		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("$str() expects 1 argument, found %d", len(n.Args)), v1alpha2.BadConfig)
 	}
+	if f, ok := libraryFunctions[n.Name]; ok {
+		if !f.arity.accepts(len(n.Args)) {
+			return nil, n.errorf("expects %s, found %d", f.arity, len(n.Args))
+		}
+		return f.eval(n, context)
+	}
 	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid function name: '%s'", n.Name), v1alpha2.BadConfig)
 }
 
@@ -932,6 +940,73 @@ func (p *Parser) Eval(context utils.EvaluationContext) (interface{}, error) {
 	return ret, nil
 }
 
+// Parse parses the expressions in the text without evaluating them. It returns the syntax trees of the
+// expressions, so that they can be checked before they are evaluated.
+func (p *Parser) Parse() ([]Node, error) {
+	nodes := make([]Node, 0)
+	for _, s := range p.Segments {
+		if strings.HasPrefix(s, "${{") && strings.HasSuffix(s, "}}") {
+			parser := newExpressionParser(s[3 : len(s)-2])
+			for {
+				n, err := parser.expr(false)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := n.(*NullNode); ok {
+					break
+				}
+				nodes = append(nodes, n)
+				parser.next()
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// IsFunction checks if an expression function with the given name exists
+func IsFunction(name string) bool {
+	_, ok := functionArity(name)
+	return ok
+}
+
+func functionArity(name string) (arity, bool) {
+	if a, ok := functionNames[name]; ok {
+		return a, true
+	}
+	if f, ok := libraryFunctions[name]; ok {
+		return f.arity, true
+	}
+	return arity{}, false
+}
+
+// functionNames are the functions evaluated by FunctionNode.Eval itself, with the number of arguments they
+// take. The rest of the functions are in libraryFunctions.
+var functionNames = map[string]arity{
+	"param":    {1, 1},
+	"property": {1, 1},
+	"input":    {1, 1},
+	"output":   {2, 2},
+	"trigger":  {2, 2},
+	"equal":    {2, 2},
+	"and":      {2, 2},
+	"or":       {2, 2},
+	"not":      {1, 1},
+	"gt":       {2, 2},
+	"ge":       {2, 2},
+	"if":       {3, 3},
+	"in":       {2, -1},
+	"lt":       {2, 2},
+	"between":  {3, 3},
+	"le":       {2, 2},
+	"config":   {2, -1},
+	"secret":   {2, 2},
+	"instance": {0, 0},
+	"val":      {0, 1},
+	"context":  {0, 1},
+	"json":     {1, 1},
+	"str":      {1, 1},
+}
+
 func newExpressionParser(text string) *ExpressionParser {
 	var s scanner.Scanner // TODO: this is mostly used to scan go code, we should use a custom scanner
 	s.Init(strings.NewReader(strings.TrimSpace(text)))
@@ -1069,11 +1144,31 @@ func (p *ExpressionParser) match(t Token) error {
 	if p.token == t {
 		p.next()
 	} else {
-		return v1alpha2.NewCOAError(nil, fmt.Sprintf("expected %T, got %s", t, p.text), v1alpha2.BadConfig)
+		got := fmt.Sprintf("'%s'", p.text)
+		if p.token == EOF {
+			got = "end of expression"
+		}
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("expected %s at column %d, got %s", tokenNames[t], p.s.Position.Column, got), v1alpha2.BadConfig)
 	}
 	return nil
 }
 
+// tokenNames are the names of the tokens the parser expects, for error messages
+var tokenNames = map[Token]string{
+	EOF:      "end of expression",
+	NUMBER:   "number",
+	INT:      "integer",
+	DOLLAR:   "'$'",
+	IDENT:    "identifier",
+	OPAREN:   "'('",
+	CPAREN:   "')'",
+	OBRACKET: "'['",
+	CBRACKET: "']'",
+	OCURLY:   "'{'",
+	CCURLY:   "'}'",
+	STRING:   "string",
+}
+
 func (p *ExpressionParser) primary() (Node, error) {
 	switch p.token {
 	case INT:
@@ -1267,6 +1362,7 @@ func (p *ExpressionParser) expr(inFunc bool) (Node, error) {
 }
 
 func (p *ExpressionParser) function() (Node, error) {
+	column := p.s.Position.Column
 	err := p.match(DOLLAR)
 	if err != nil {
 		return nil, err
@@ -1287,7 +1383,7 @@ func (p *ExpressionParser) function() (Node, error) {
 			return nil, err
 		}
 		if _, ok := node.(*NullNode); ok {
-			return nil, v1alpha2.NewCOAError(nil, "invalid argument", v1alpha2.BadConfig)
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid argument of $%s() at column %d", name, column), v1alpha2.BadConfig)
 		}
 		args = append(args, node)
 		if p.token == COMMA {
@@ -1298,7 +1394,11 @@ func (p *ExpressionParser) function() (Node, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &FunctionNode{name, args}, nil
+	// Functions that aren't known are reported when they are evaluated
+	if a, ok := functionArity(name); ok && !a.accepts(len(args)) {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("$%s() at column %d expects %s, found %d", name, column, a, len(args)), v1alpha2.BadConfig)
+	}
+	return &FunctionNode{Name: name, Args: args, Column: column}, nil
 }
 
 func EvaluateDeployment(context utils.EvaluationContext) (model.DeploymentSpec, error) {
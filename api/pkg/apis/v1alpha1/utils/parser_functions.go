@@ -0,0 +1,488 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+)
+
+// arity is the number of arguments a function takes. max is -1 for functions that take any number of
+// arguments from min on.
+type arity struct {
+	min int
+	max int
+}
+
+func (a arity) accepts(n int) bool {
+	return n >= a.min && (a.max < 0 || n <= a.max)
+}
+
+func (a arity) String() string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case a.max < 0:
+		return "at least " + plural(a.min)
+	case a.min == a.max:
+		return plural(a.min)
+	case a.max == a.min+1:
+		return fmt.Sprintf("%d or %s", a.min, plural(a.max))
+	default:
+		return fmt.Sprintf("%d to %s", a.min, plural(a.max))
+	}
+}
+
+// libraryFunction is a function of the expression library. Arguments are passed unevaluated, so that
+// functions like $filter() can evaluate them once per item.
+type libraryFunction struct {
+	arity arity
+	eval  func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error)
+}
+
+// timeNow is replaced in tests
+var timeNow = time.Now
+
+var timeLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+var libraryFunctions map[string]libraryFunction
+
+func init() {
+	libraryFunctions = map[string]libraryFunction{
+		// strings
+		"split": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			ret := []interface{}{}
+			for _, s := range strings.Split(FormatAsString(args[0]), FormatAsString(args[1])) {
+				ret = append(ret, s)
+			}
+			return ret, nil
+		}},
+		"join": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			list, err := n.toList(args[0])
+			if err != nil {
+				return nil, err
+			}
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				items = append(items, FormatAsString(item))
+			}
+			return strings.Join(items, FormatAsString(args[1])), nil
+		}},
+		"replace": {arity{3, 3}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			return strings.ReplaceAll(FormatAsString(args[0]), FormatAsString(args[1]), FormatAsString(args[2])), nil
+		}},
+		"regex": {arity{2, 3}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			re, err := regexp.Compile(FormatAsString(args[1]))
+			if err != nil {
+				return nil, n.errorf("invalid pattern: %s", err.Error())
+			}
+			if len(args) == 3 {
+				return re.ReplaceAllString(FormatAsString(args[0]), FormatAsString(args[2])), nil
+			}
+			return re.MatchString(FormatAsString(args[0])), nil
+		}},
+		// lists
+		"len": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			switch v := args[0].(type) {
+			case []interface{}:
+				return int64(len(v)), nil
+			case []string:
+				return int64(len(v)), nil
+			case map[string]interface{}:
+				return int64(len(v)), nil
+			case map[string]string:
+				return int64(len(v)), nil
+			case nil:
+				return int64(0), nil
+			}
+			s := FormatAsString(args[0])
+			if list, err := n.toList(s); err == nil && strings.HasPrefix(strings.TrimSpace(s), "[") {
+				return int64(len(list)), nil
+			}
+			if m, err := n.toMap(s); err == nil && strings.HasPrefix(strings.TrimSpace(s), "{") {
+				return int64(len(m)), nil
+			}
+			return int64(utf8.RuneCountInString(s)), nil
+		}},
+		"first": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			return n.listItem(context, true)
+		}},
+		"last": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			return n.listItem(context, false)
+		}},
+		"filter": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			val, err := n.Args[0].Eval(context)
+			if err != nil {
+				return nil, err
+			}
+			list, err := n.toList(val)
+			if err != nil {
+				return nil, err
+			}
+			ret := []interface{}{}
+			for _, item := range list {
+				itemContext := context
+				itemContext.Value = item
+				keep, err := n.Args[1].Eval(itemContext)
+				if err != nil {
+					return nil, err
+				}
+				b, ok := toBool(keep)
+				if !ok {
+					return nil, n.errorf("condition evaluates to %v, which is not a boolean value", keep)
+				}
+				if b {
+					ret = append(ret, item)
+				}
+			}
+			return ret, nil
+		}},
+		"map": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			val, err := n.Args[0].Eval(context)
+			if err != nil {
+				return nil, err
+			}
+			list, err := n.toList(val)
+			if err != nil {
+				return nil, err
+			}
+			ret := make([]interface{}, 0, len(list))
+			for _, item := range list {
+				itemContext := context
+				itemContext.Value = item
+				v, err := n.Args[1].Eval(itemContext)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, v)
+			}
+			return ret, nil
+		}},
+		// maps
+		"keys": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			m, err := n.mapArg(context)
+			if err != nil {
+				return nil, err
+			}
+			ret := []interface{}{}
+			for _, k := range sortedKeys(m) {
+				ret = append(ret, k)
+			}
+			return ret, nil
+		}},
+		"values": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			m, err := n.mapArg(context)
+			if err != nil {
+				return nil, err
+			}
+			ret := []interface{}{}
+			for _, k := range sortedKeys(m) {
+				ret = append(ret, m[k])
+			}
+			return ret, nil
+		}},
+		"merge": {arity{1, -1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			ret := map[string]interface{}{}
+			for _, arg := range args {
+				m, err := n.toMap(arg)
+				if err != nil {
+					return nil, err
+				}
+				for k, v := range m {
+					ret[k] = v
+				}
+			}
+			return ret, nil
+		}},
+		// time
+		"now": {arity{0, 0}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			return timeNow().UTC().Format(time.RFC3339), nil
+		}},
+		"addDuration": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			t, err := n.toTime(args[0])
+			if err != nil {
+				return nil, err
+			}
+			d, err := time.ParseDuration(FormatAsString(args[1]))
+			if err != nil {
+				return nil, n.errorf("%s is not a valid duration, such as '1h30m'", FormatAsString(args[1]))
+			}
+			return t.Add(d).Format(time.RFC3339), nil
+		}},
+		"formatTime": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			t, err := n.toTime(args[0])
+			if err != nil {
+				return nil, err
+			}
+			layout := FormatAsString(args[1])
+			if layout == "unix" {
+				return t.Unix(), nil
+			}
+			if l, ok := timeLayouts[layout]; ok {
+				layout = l
+			}
+			return t.Format(layout), nil
+		}},
+		// encoding
+		"base64": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.EncodeToString(toBytes(args[0])), nil
+		}},
+		"base64decode": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			data, err := base64.StdEncoding.DecodeString(FormatAsString(args[0]))
+			if err != nil {
+				return nil, n.errorf("value is not base64 encoded: %s", err.Error())
+			}
+			return string(data), nil
+		}},
+		"sha256": {arity{1, 1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			args, err := n.evalArgs(context)
+			if err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(toBytes(args[0]))
+			return hex.EncodeToString(sum[:]), nil
+		}},
+		// defaults
+		"default": {arity{2, 2}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			if v, err := n.Args[0].Eval(context); err == nil && !isEmpty(v) {
+				return v, nil
+			}
+			return n.Args[1].Eval(context)
+		}},
+		"coalesce": {arity{1, -1}, func(n *FunctionNode, context utils.EvaluationContext) (interface{}, error) {
+			for _, arg := range n.Args {
+				if v, err := arg.Eval(context); err == nil && !isEmpty(v) {
+					return v, nil
+				}
+			}
+			return "", nil
+		}},
+	}
+}
+
+// errorf creates an error that tells which function failed and where it is in the expression
+func (n *FunctionNode) errorf(format string, args ...interface{}) error {
+	return v1alpha2.NewCOAError(nil, fmt.Sprintf("$%s() at column %d: %s", n.Name, n.Column, fmt.Sprintf(format, args...)), v1alpha2.BadConfig)
+}
+
+func (n *FunctionNode) evalArgs(context utils.EvaluationContext) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(n.Args))
+	for _, arg := range n.Args {
+		v, err := arg.Eval(context)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+// toList converts a value to a list. JSON arrays in strings are parsed.
+func (n *FunctionNode) toList(val interface{}) ([]interface{}, error) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, nil
+	case []string:
+		ret := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			ret = append(ret, s)
+		}
+		return ret, nil
+	case []map[string]interface{}:
+		ret := make([]interface{}, 0, len(v))
+		for _, m := range v {
+			ret = append(ret, m)
+		}
+		return ret, nil
+	case nil:
+		return []interface{}{}, nil
+	case string:
+		if v == "" {
+			return []interface{}{}, nil
+		}
+		var ret []interface{}
+		if err := json.Unmarshal([]byte(v), &ret); err == nil {
+			return ret, nil
+		}
+	}
+	return nil, n.errorf("%v is not a list", val)
+}
+
+// toMap converts a value to a map. JSON objects in strings are parsed.
+func (n *FunctionNode) toMap(val interface{}) (map[string]interface{}, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case map[string]string:
+		ret := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			ret[k] = s
+		}
+		return ret, nil
+	case nil:
+		return map[string]interface{}{}, nil
+	case string:
+		if v == "" {
+			return map[string]interface{}{}, nil
+		}
+		var ret map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &ret); err == nil && ret != nil {
+			return ret, nil
+		}
+	}
+	return nil, n.errorf("%v is not a map", val)
+}
+
+func (n *FunctionNode) mapArg(context utils.EvaluationContext) (map[string]interface{}, error) {
+	val, err := n.Args[0].Eval(context)
+	if err != nil {
+		return nil, err
+	}
+	return n.toMap(val)
+}
+
+// listItem gets the first or the last item of a list, or an empty string when the list is empty
+func (n *FunctionNode) listItem(context utils.EvaluationContext, first bool) (interface{}, error) {
+	val, err := n.Args[0].Eval(context)
+	if err != nil {
+		return nil, err
+	}
+	list, err := n.toList(val)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return "", nil
+	}
+	if first {
+		return list[0], nil
+	}
+	return list[len(list)-1], nil
+}
+
+// toTime parses an RFC 3339 time, or Unix time in seconds
+func (n *FunctionNode) toTime(val interface{}) (time.Time, error) {
+	switch v := val.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	}
+	s := FormatAsString(val)
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, n.errorf("%s is not an RFC 3339 time, such as '2024-01-02T15:04:05Z'", s)
+}
+
+// toBytes gets the bytes of a string, or the JSON encoding of other values
+func toBytes(val interface{}) []byte {
+	switch v := val.(type) {
+	case string:
+		return []byte(v)
+	case []interface{}, []string, map[string]interface{}, map[string]string:
+		data, _ := json.Marshal(v)
+		return data
+	}
+	return []byte(FormatAsString(val))
+}
+
+// isEmpty tells if a value is missing: nil, an empty string, or an empty list or map
+func isEmpty(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case map[string]string:
+		return len(v) == 0
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,326 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	parser := NewParser("${{$split('a,b,c', ',')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, val)
+}
+func TestJoin(t *testing.T) {
+	parser := NewParser("${{$join($split('a,b,c', ','), '-')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "a-b-c", val)
+}
+func TestJoinJsonList(t *testing.T) {
+	parser := NewParser("${{$join($val(), ';')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   `["x", "y"]`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "x;y", val)
+}
+func TestReplace(t *testing.T) {
+	parser := NewParser("${{$replace('v1.2.3', '.', '-')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "v1-2-3", val)
+}
+func TestRegexMatch(t *testing.T) {
+	parser := NewParser("${{$regex('edge-01', '^edge-[0-9]+$')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, true, val)
+}
+func TestRegexReplace(t *testing.T) {
+	parser := NewParser("${{$regex('edge-01', '^edge-([0-9]+)$', 'site$1')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "site01", val)
+}
+func TestRegexInvalidPattern(t *testing.T) {
+	parser := NewParser("${{$regex('a', '(')}}")
+	_, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$regex() at column 1: invalid pattern")
+}
+func TestLen(t *testing.T) {
+	parser := NewParser("${{$len($split('a,b,c', ','))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), val)
+}
+func TestLenString(t *testing.T) {
+	parser := NewParser("${{$len('héllo')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), val)
+}
+func TestLenMap(t *testing.T) {
+	parser := NewParser("${{$len($val())}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   map[string]interface{}{"a": 1, "b": 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), val)
+}
+func TestFirstAndLast(t *testing.T) {
+	parser := NewParser("${{$first($split('a,b,c', ','))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", val)
+
+	parser = NewParser("${{$last($split('a,b,c', ','))}}")
+	val, err = parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "c", val)
+}
+func TestFirstEmptyList(t *testing.T) {
+	parser := NewParser("${{$first($val())}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   []interface{}{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+}
+func TestFilter(t *testing.T) {
+	parser := NewParser("${{$filter($val(), $gt($val(), 2))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   []interface{}{int64(1), int64(2), int64(3), int64(4)},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(3), int64(4)}, val)
+}
+func TestFilterObjects(t *testing.T) {
+	parser := NewParser("${{$filter($val(), $equal($val(region), 'west'))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value: []interface{}{
+			map[string]interface{}{"name": "a", "region": "west"},
+			map[string]interface{}{"name": "b", "region": "east"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "a", "region": "west"}}, val)
+}
+func TestFilterNotBoolean(t *testing.T) {
+	parser := NewParser("${{$filter($val(), 'abc')}}")
+	_, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   []interface{}{"a"},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$filter() at column 1")
+}
+func TestMap(t *testing.T) {
+	parser := NewParser("${{$map($val(), $val(name))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value: []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, val)
+}
+func TestKeysAndValues(t *testing.T) {
+	value := map[string]interface{}{"b": "2", "a": "1"}
+	parser := NewParser("${{$keys($val())}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   value,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, val)
+
+	parser = NewParser("${{$values($val())}}")
+	val, err = parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value:   value,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"1", "2"}, val)
+}
+func TestKeysNotMap(t *testing.T) {
+	parser := NewParser("${{$keys('abc')}}")
+	_, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$keys() at column 1: abc is not a map")
+}
+func TestMerge(t *testing.T) {
+	parser := NewParser("${{$merge($val(a), $val(b))}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+		Value: map[string]interface{}{
+			"a": map[string]interface{}{"x": "1", "y": "1"},
+			"b": map[string]interface{}{"y": "2"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"x": "1", "y": "2"}, val)
+}
+func TestNow(t *testing.T) {
+	timeNow = func() time.Time {
+		return time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	}
+	defer func() { timeNow = time.Now }()
+	parser := NewParser("${{$now()}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T15:04:05Z", val)
+}
+func TestAddDuration(t *testing.T) {
+	parser := NewParser("${{$addDuration('2024-01-02T15:04:05Z', '1h30m')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02T16:34:05Z", val)
+}
+func TestAddDurationInvalid(t *testing.T) {
+	parser := NewParser("${{$addDuration('2024-01-02T15:04:05Z', 'soon')}}")
+	_, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "soon is not a valid duration")
+}
+func TestFormatTime(t *testing.T) {
+	parser := NewParser("${{$formatTime('2024-01-02T15:04:05Z', 'DateOnly')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-02", val)
+
+	parser = NewParser("${{$formatTime('2024-01-02T15:04:05Z', 'unix')}}")
+	val, err = parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1704207845), val)
+}
+func TestBase64(t *testing.T) {
+	parser := NewParser("${{$base64('hello')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "aGVsbG8=", val)
+
+	parser = NewParser("${{$base64decode($base64('hello'))}}")
+	val, err = parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", val)
+}
+func TestSha256(t *testing.T) {
+	parser := NewParser("${{$sha256('hello')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context: ctx,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", val)
+}
+func TestDefault(t *testing.T) {
+	parser := NewParser("${{$default($property(missing), 'fallback')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context:    ctx,
+		Properties: map[string]string{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "fallback", val)
+
+	parser = NewParser("${{$default($property(foo), 'fallback')}}")
+	val, err = parser.Eval(utils.EvaluationContext{
+		Context:    ctx,
+		Properties: map[string]string{"foo": "bar"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", val)
+}
+func TestCoalesce(t *testing.T) {
+	parser := NewParser("${{$coalesce($property(a), $property(b), 'c')}}")
+	val, err := parser.Eval(utils.EvaluationContext{
+		Context:    ctx,
+		Properties: map[string]string{"a": "", "b": "B"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "B", val)
+}
+func TestFunctionArityCheckedWhenParsed(t *testing.T) {
+	_, err := NewParser("${{$split('a,b')}}").Parse()
+	assert.NotNil(t, err)
+	assert.Equal(t, "Bad Config: $split() at column 1 expects 2 arguments, found 1", err.Error())
+
+	_, err = NewParser("${{$if($equal(1, 1), 'a')}}").Parse()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$if() at column 1 expects 3 arguments, found 2")
+
+	_, err = NewParser("${{$str('a') + $regex('a')}}").Parse()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$regex() at column 13 expects 2 or 3 arguments, found 1")
+
+	_, err = NewParser("${{$merge()}}").Parse()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "$merge() at column 1 expects at least 1 argument, found 0")
+}
+func TestParseErrorPosition(t *testing.T) {
+	_, err := NewParser("${{$split('a', ','}}").Parse()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid argument of $split() at column 1")
+
+	_, err = NewParser("${{1 + $str}}").Parse()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "expected '(' at column")
+}
+func TestLibraryFunctionsAreFunctions(t *testing.T) {
+	for _, name := range []string{"split", "join", "replace", "regex", "len", "first", "last", "filter", "map", "keys",
+		"values", "merge", "now", "addDuration", "formatTime", "base64", "base64decode", "sha256", "default", "coalesce"} {
+		assert.True(t, IsFunction(name), name)
+	}
+}
@@ -2988,3 +2988,22 @@ func TestRecursiveUnsupported(t *testing.T) {
 	})
 	assert.NotNil(t, err)
 }
+func TestParseWithoutEvaluation(t *testing.T) {
+	parser := NewParser("stage-${{$if($lt($output(counter,val), 20), counter, '')}}")
+	nodes, err := parser.Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(nodes))
+	function, ok := nodes[0].(*FunctionNode)
+	assert.True(t, ok)
+	assert.Equal(t, "if", function.Name)
+	assert.Equal(t, 3, len(function.Args))
+}
+func TestParseInvalidArgument(t *testing.T) {
+	parser := NewParser("${{$output(counter, val}}")
+	_, err := parser.Parse()
+	assert.NotNil(t, err)
+}
+func TestIsFunction(t *testing.T) {
+	assert.True(t, IsFunction("output"))
+	assert.False(t, IsFunction("outputs"))
+}
@@ -93,7 +93,7 @@ func getApiClient() (*apiClient, error) {
 	return client, nil
 }
 
-func GetParentApiClient(baseUrl string) (*apiClient, error) {
+func GetParentApiClient(parent v1alpha2.SiteConnection) (*apiClient, error) {
 	clientOptions := make([]ApiClientOption, 0)
 
 	if caCert, ok := os.LookupEnv(constants.ApiCertEnvName); ok {
@@ -101,7 +101,8 @@ func GetParentApiClient(baseUrl string) (*apiClient, error) {
 	}
 
 	clientOptions = append(clientOptions, WithUserPassword(context.TODO()))
-	client, err := NewApiClient(context.Background(), baseUrl, clientOptions...)
+	clientOptions = append(clientOptions, WithClientCert(parent.CertFile, parent.KeyFile))
+	client, err := NewApiClient(context.Background(), parent.BaseUrl, clientOptions...)
 	if err != nil {
 		return nil, err
 	}
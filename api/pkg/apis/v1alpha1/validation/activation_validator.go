@@ -34,7 +34,8 @@ func NewActivationValidator(campaignLookupFunc ObjectLookupFunc) ActivationValid
 // Validate Activation creation or update
 // 1. Campaign exists
 // 2. If initial stage is provided in the activation spec, validate it is a valid stage in the campaign
-// 3. Spec is immutable for update
+// 3. Spec is immutable for update, except for the state, which can't leave cancelled
+// 4. State is one of running, paused or cancelled
 func (a *ActivationValidator) ValidateCreateOrUpdate(ctx context.Context, newRef interface{}, oldRef interface{}) []ErrorField {
 	new := a.ConvertInterfaceToActivation(newRef)
 	old := a.ConvertInterfaceToActivation(oldRef)
@@ -58,6 +59,20 @@ func (a *ActivationValidator) ValidateCreateOrUpdate(ctx context.Context, newRef
 				DetailedMessage: "spec is immutable: " + err.Error(),
 			})
 		}
+		if old.Spec.State == model.ActivationStateCancelled && new.Spec.State != model.ActivationStateCancelled {
+			errorFields = append(errorFields, ErrorField{
+				FieldPath:       "spec.state",
+				Value:           new.Spec.State,
+				DetailedMessage: "a cancelled activation can't be paused or resumed",
+			})
+		}
+	}
+	if !model.IsValidActivationState(new.Spec.State) {
+		errorFields = append(errorFields, ErrorField{
+			FieldPath:       "spec.state",
+			Value:           new.Spec.State,
+			DetailedMessage: "spec.state must be one of running, paused or cancelled",
+		})
 	}
 
 	return errorFields
@@ -9,9 +9,12 @@ package validation
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 )
 
 var (
@@ -37,8 +40,12 @@ func NewCampaignValidator(campaignContainerLookupFunc ObjectLookupFunc, campaign
 // Validate Campaign creation or update
 // 1. First stage is valid
 // 2. Stages in the list are
-// 3. campaign name and rootResource is valid. And rootResource is immutable
-// 4. Update is not allow when there are running activations
+// 3. Expressions of stages are valid and refer to stages in the list
+// 4. Stages of self-driving campaigns are reachable and loops have an exit
+// 5. Notifications listen to known events
+// 6. Hooks refer to stages in the list
+// 7. campaign name and rootResource is valid. And rootResource is immutable
+// 8. Update is not allow when there are running activations
 func (c *CampaignValidator) ValidateCreateOrUpdate(ctx context.Context, newRef interface{}, oldRef interface{}) []ErrorField {
 	new := c.ConvertInterfaceToCampaign(newRef)
 	old := c.ConvertInterfaceToCampaign(oldRef)
@@ -54,6 +61,10 @@ func (c *CampaignValidator) ValidateCreateOrUpdate(ctx context.Context, newRef i
 	if err := c.ValidateStages(new); err != nil {
 		errorFields = append(errorFields, *err)
 	}
+	errorFields = append(errorFields, c.ValidateExpressions(new)...)
+	errorFields = append(errorFields, c.ValidateStageGraph(new)...)
+	errorFields = append(errorFields, c.ValidateNotifications(new)...)
+	errorFields = append(errorFields, c.ValidateHooks(new)...)
 	if oldRef == nil {
 		// validate create specific fields
 		if err := ValidateObjectName(new.ObjectMeta.Name, new.Spec.RootResource, campaignMinNameLength, campaignMaxNameLength); err != nil {
@@ -117,7 +128,8 @@ func (c *CampaignValidator) ValidateFirstStage(campaign model.CampaignState) *Er
 	}
 }
 
-// Validate stageSelector of stages should always be one of the stages in the stages list
+// Validate stageSelector, nextStages and join predecessors of stages should always be stages in the stages list,
+// and stages with forEach have a provider
 func (c *CampaignValidator) ValidateStages(campaign model.CampaignState) *ErrorField {
 	stages := make(map[string]struct{}, 0)
 	for _, stage := range campaign.Spec.Stages {
@@ -133,10 +145,290 @@ func (c *CampaignValidator) ValidateStages(campaign model.CampaignState) *ErrorF
 				}
 			}
 		}
+		for _, next := range stage.NextStages {
+			if _, ok := stages[next]; !ok {
+				return &ErrorField{
+					FieldPath:       fmt.Sprintf("spec.stages.%s.nextStages", stage.Name),
+					Value:           next,
+					DetailedMessage: "nextStages must be stages in the stages list",
+				}
+			}
+		}
+		if stage.Join != nil {
+			for _, predecessor := range stage.Join.Predecessors {
+				if _, ok := stages[predecessor]; !ok {
+					return &ErrorField{
+						FieldPath:       fmt.Sprintf("spec.stages.%s.join.predecessors", stage.Name),
+						Value:           predecessor,
+						DetailedMessage: "predecessors must be stages in the stages list",
+					}
+				}
+			}
+			if len(stage.Join.Predecessors) == 0 || stage.Join.Count < 0 || stage.Join.Count > len(stage.Join.Predecessors) {
+				return &ErrorField{
+					FieldPath:       fmt.Sprintf("spec.stages.%s.join.count", stage.Name),
+					Value:           stage.Join.Count,
+					DetailedMessage: "join count must be between 0 and the number of predecessors, which can't be empty",
+				}
+			}
+		}
+		if stage.ForEach != "" && stage.Provider == "" {
+			return &ErrorField{
+				FieldPath:       fmt.Sprintf("spec.stages.%s.forEach", stage.Name),
+				Value:           stage.ForEach,
+				DetailedMessage: "forEach requires a provider on the stage",
+			}
+		}
 	}
 	return nil
 }
 
+// Validate expressions of stages
+// 1. Expressions in stageSelector, contexts, forEach and inputs of stages and tasks can be parsed and only call known functions
+// 2. $output() refers to stages in the stages list
+// 3. Stages a stageSelector expression can select are stages in the stages list
+func (c *CampaignValidator) ValidateExpressions(campaign model.CampaignState) []ErrorField {
+	errorFields := []ErrorField{}
+	stages := make(map[string]struct{}, 0)
+	for name, stage := range campaign.Spec.Stages {
+		stages[name] = struct{}{}
+		if stage.Name != "" {
+			stages[stage.Name] = struct{}{}
+		}
+	}
+	for _, name := range sortedStageNames(campaign) {
+		stage := campaign.Spec.Stages[name]
+		path := fmt.Sprintf("spec.stages.%s", name)
+		errorFields = append(errorFields, validateExpressionValue(stage.StageSelector, path+".stageSelector", stages)...)
+		errorFields = append(errorFields, validateExpressionValue(stage.Contexts, path+".contexts", stages)...)
+		errorFields = append(errorFields, validateExpressionValue(stage.ForEach, path+".forEach", stages)...)
+		errorFields = append(errorFields, validateExpressionValue(stage.Inputs, path+".inputs", stages)...)
+		for i, task := range stage.Tasks {
+			errorFields = append(errorFields, validateExpressionValue(task.Inputs, fmt.Sprintf("%s.tasks[%d].inputs", path, i), stages)...)
+		}
+		if !strings.Contains(stage.StageSelector, "${{") {
+			// Plain stage names are checked by ValidateStages
+			continue
+		}
+		targets, err := getSelectorTargets(stage.StageSelector)
+		if err != nil {
+			continue
+		}
+		for _, target := range targets.stages {
+			if _, ok := stages[target]; !ok {
+				errorFields = append(errorFields, ErrorField{
+					FieldPath:       path + ".stageSelector",
+					Value:           target,
+					DetailedMessage: "stageSelector can select a stage that is not in the stages list",
+				})
+			}
+		}
+	}
+	return errorFields
+}
+
+// Validate the stages of a self-driving campaign
+// 1. All stages but hook stages can be reached from the first stage, unless a stageSelector can't be evaluated statically
+// 2. Stages that are reached can lead to the end of the campaign, so loops always have an exit
+func (c *CampaignValidator) ValidateStageGraph(campaign model.CampaignState) []ErrorField {
+	errorFields := []ErrorField{}
+	if !campaign.Spec.SelfDriving {
+		return errorFields
+	}
+	if _, ok := campaign.Spec.Stages[campaign.Spec.FirstStage]; !ok {
+		return errorFields
+	}
+
+	// Build the stage graph. Stages that can end the campaign and stages whose next stage can't be
+	// determined statically are exits.
+	next := make(map[string][]string)
+	exits := make(map[string]bool)
+	dynamic := false
+	for name, stage := range campaign.Spec.Stages {
+		if len(stage.NextStages) > 0 {
+			next[name] = stage.NextStages
+			continue
+		}
+		targets, err := getSelectorTargets(stage.StageSelector)
+		if err != nil || targets.dynamic {
+			dynamic = true
+			exits[name] = true
+			continue
+		}
+		for _, target := range targets.stages {
+			if _, ok := campaign.Spec.Stages[target]; ok {
+				next[name] = append(next[name], target)
+			} else {
+				// Selecting a missing stage fails the activation
+				exits[name] = true
+			}
+		}
+		if targets.canExit {
+			exits[name] = true
+		}
+	}
+
+	reachable := map[string]bool{campaign.Spec.FirstStage: true}
+	queue := []string{campaign.Spec.FirstStage}
+	for len(queue) > 0 {
+		stage := queue[0]
+		queue = queue[1:]
+		for _, n := range next[stage] {
+			if !reachable[n] {
+				reachable[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	if !dynamic {
+		for _, name := range sortedStageNames(campaign) {
+			// Hook stages run once an activation is finished, they don't need to be reached
+			if !reachable[name] && !isHookStage(campaign, name) {
+				errorFields = append(errorFields, ErrorField{
+					FieldPath:       fmt.Sprintf("spec.stages.%s", name),
+					Value:           name,
+					DetailedMessage: "stage can't be reached from firstStage",
+				})
+			}
+		}
+	}
+
+	// Find the stages that can reach an exit by walking the graph backwards from the exits
+	finishing := make(map[string]bool)
+	changed := true
+	for changed {
+		changed = false
+		for name := range campaign.Spec.Stages {
+			if finishing[name] {
+				continue
+			}
+			if exits[name] {
+				finishing[name] = true
+				changed = true
+				continue
+			}
+			for _, n := range next[name] {
+				if finishing[n] {
+					finishing[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	loop := []string{}
+	for _, name := range sortedStageNames(campaign) {
+		if reachable[name] && !finishing[name] {
+			loop = append(loop, name)
+		}
+	}
+	if len(loop) > 0 {
+		errorFields = append(errorFields, ErrorField{
+			FieldPath:       "spec.stages",
+			Value:           loop,
+			DetailedMessage: "stages loop without an exit: none of them can select a stage that ends the campaign",
+		})
+	}
+	return errorFields
+}
+
+// Validate the stages and expressions of a campaign, regardless of the object it is stored in
+func (c *CampaignValidator) ValidateSpec(campaign model.CampaignState) []ErrorField {
+	errorFields := []ErrorField{}
+	if err := c.ValidateFirstStage(campaign); err != nil {
+		errorFields = append(errorFields, *err)
+	}
+	if err := c.ValidateStages(campaign); err != nil {
+		errorFields = append(errorFields, *err)
+	}
+	errorFields = append(errorFields, c.ValidateExpressions(campaign)...)
+	errorFields = append(errorFields, c.ValidateStageGraph(campaign)...)
+	errorFields = append(errorFields, c.ValidateNotifications(campaign)...)
+	errorFields = append(errorFields, c.ValidateHooks(campaign)...)
+	return errorFields
+}
+
+// Validate campaign-level notifications
+// 1. Notifications listen to at least one of the success, failure, cancelled and finished events
+// 2. Expressions in inputs can be parsed, only call known functions and refer to stages in the stages list
+func (c *CampaignValidator) ValidateNotifications(campaign model.CampaignState) []ErrorField {
+	errorFields := []ErrorField{}
+	stages := make(map[string]struct{}, 0)
+	for name, stage := range campaign.Spec.Stages {
+		stages[name] = struct{}{}
+		if stage.Name != "" {
+			stages[stage.Name] = struct{}{}
+		}
+	}
+	for i, notification := range campaign.Spec.Notifications {
+		path := fmt.Sprintf("spec.notifications[%d]", i)
+		if len(notification.On) == 0 {
+			errorFields = append(errorFields, ErrorField{
+				FieldPath:       path + ".on",
+				Value:           notification.On,
+				DetailedMessage: "notification must listen to at least one event",
+			})
+		}
+		for _, event := range notification.On {
+			switch event {
+			case model.NotificationEventSuccess, model.NotificationEventFailure, model.NotificationEventCancelled, model.NotificationEventFinished:
+			default:
+				errorFields = append(errorFields, ErrorField{
+					FieldPath:       path + ".on",
+					Value:           event,
+					DetailedMessage: "event must be one of success, failure, cancelled and finished",
+				})
+			}
+		}
+		errorFields = append(errorFields, validateExpressionValue(notification.Inputs, path+".inputs", stages)...)
+	}
+	return errorFields
+}
+
+// Validate lifecycle hooks
+// 1. Hook stages are in the stages list
+func (c *CampaignValidator) ValidateHooks(campaign model.CampaignState) []ErrorField {
+	errorFields := []ErrorField{}
+	if campaign.Spec.Hooks == nil {
+		return errorFields
+	}
+	hooks := []struct {
+		name   string
+		stages []string
+	}{
+		{model.HookOnSuccess, campaign.Spec.Hooks.OnSuccess},
+		{model.HookOnFailure, campaign.Spec.Hooks.OnFailure},
+		{model.HookFinally, campaign.Spec.Hooks.Finally},
+	}
+	for _, hook := range hooks {
+		for i, stage := range hook.stages {
+			if _, ok := campaign.Spec.Stages[stage]; !ok {
+				errorFields = append(errorFields, ErrorField{
+					FieldPath:       fmt.Sprintf("spec.hooks.%s[%d]", hook.name, i),
+					Value:           stage,
+					DetailedMessage: "hook stage must be one of the stages in the stages list",
+				})
+			}
+		}
+	}
+	return errorFields
+}
+
+// isHookStage checks if a stage runs as a lifecycle hook of the campaign
+func isHookStage(campaign model.CampaignState, name string) bool {
+	if campaign.Spec.Hooks == nil {
+		return false
+	}
+	for _, stages := range [][]string{campaign.Spec.Hooks.OnSuccess, campaign.Spec.Hooks.OnFailure, campaign.Spec.Hooks.Finally} {
+		for _, stage := range stages {
+			if stage == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Validate NO running activations
 // CampaignActivationsLookupFunc will look up activations with label {"campaign" : c.ObjectMeta.Name}
 func (c *CampaignValidator) ValidateRunningActivation(ctx context.Context, campaign model.CampaignState) *ErrorField {
@@ -169,3 +461,163 @@ func (c *CampaignValidator) ConvertInterfaceToCampaign(ref interface{}) model.Ca
 		}
 	}
 }
+
+func sortedStageNames(campaign model.CampaignState) []string {
+	names := make([]string, 0, len(campaign.Spec.Stages))
+	for name := range campaign.Spec.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateExpressionValue parses the expressions in a value, which can be a string or a collection of values
+func validateExpressionValue(value interface{}, path string, stages map[string]struct{}) []ErrorField {
+	errorFields := []ErrorField{}
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "${{") {
+			return errorFields
+		}
+		nodes, err := api_utils.NewParser(v).Parse()
+		if err != nil {
+			return append(errorFields, ErrorField{
+				FieldPath:       path,
+				Value:           v,
+				DetailedMessage: fmt.Sprintf("invalid expression: %s", err.Error()),
+			})
+		}
+		for _, node := range nodes {
+			walkExpression(node, func(n api_utils.Node) {
+				function, ok := n.(*api_utils.FunctionNode)
+				if !ok {
+					return
+				}
+				if !api_utils.IsFunction(function.Name) {
+					errorFields = append(errorFields, ErrorField{
+						FieldPath:       path,
+						Value:           v,
+						DetailedMessage: fmt.Sprintf("unknown function $%s()", function.Name),
+					})
+					return
+				}
+				if function.Name == "output" && len(function.Args) > 0 {
+					if stage, ok := evalConstant(function.Args[0]); ok {
+						if _, ok := stages[stage]; !ok {
+							errorFields = append(errorFields, ErrorField{
+								FieldPath:       path,
+								Value:           v,
+								DetailedMessage: fmt.Sprintf("$output() refers to stage %s, which is not in the stages list", stage),
+							})
+						}
+					}
+				}
+			})
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			errorFields = append(errorFields, validateExpressionValue(v[k], path+"."+k, stages)...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			errorFields = append(errorFields, validateExpressionValue(item, fmt.Sprintf("%s[%d]", path, i), stages)...)
+		}
+	}
+	return errorFields
+}
+
+func walkExpression(node api_utils.Node, visit func(api_utils.Node)) {
+	if node == nil {
+		return
+	}
+	visit(node)
+	switch n := node.(type) {
+	case *api_utils.UnaryNode:
+		walkExpression(n.Expr, visit)
+	case *api_utils.BinaryNode:
+		walkExpression(n.Left, visit)
+		walkExpression(n.Right, visit)
+	case *api_utils.FunctionNode:
+		for _, arg := range n.Args {
+			walkExpression(arg, visit)
+		}
+	}
+}
+
+// evalConstant evaluates an expression that doesn't depend on the evaluation context, such as a stage name
+func evalConstant(node api_utils.Node) (string, bool) {
+	if !isConstant(node) {
+		return "", false
+	}
+	v, err := node.Eval(utils.EvaluationContext{})
+	if err != nil {
+		return "", false
+	}
+	return api_utils.FormatAsString(v), true
+}
+
+func isConstant(node api_utils.Node) bool {
+	switch n := node.(type) {
+	case *api_utils.IdentifierNode, *api_utils.IntNode, *api_utils.NumberNode:
+		return true
+	case *api_utils.UnaryNode:
+		return (n.Op == api_utils.PLUS || n.Op == api_utils.MINUS) && isConstant(n.Expr)
+	case *api_utils.BinaryNode:
+		return isConstant(n.Left) && isConstant(n.Right)
+	}
+	return false
+}
+
+// selectorTargets are the stages a stageSelector can select. canExit is set when the selector can end the
+// campaign, and dynamic is set when the selected stage depends on the evaluation context.
+type selectorTargets struct {
+	stages  []string
+	canExit bool
+	dynamic bool
+}
+
+func getSelectorTargets(selector string) (selectorTargets, error) {
+	targets := selectorTargets{}
+	if selector == "" {
+		targets.canExit = true
+		return targets, nil
+	}
+	if !strings.Contains(selector, "${{") {
+		targets.stages = []string{selector}
+		return targets, nil
+	}
+	parser := api_utils.NewParser(selector)
+	nodes, err := parser.Parse()
+	if err != nil {
+		return targets, err
+	}
+	if len(parser.Segments) != 1 || len(nodes) != 1 {
+		targets.dynamic = true
+		return targets, nil
+	}
+	collectSelectorTargets(nodes[0], &targets)
+	return targets, nil
+}
+
+func collectSelectorTargets(node api_utils.Node, targets *selectorTargets) {
+	if function, ok := node.(*api_utils.FunctionNode); ok && function.Name == "if" && len(function.Args) == 3 {
+		collectSelectorTargets(function.Args[1], targets)
+		collectSelectorTargets(function.Args[2], targets)
+		return
+	}
+	stage, ok := evalConstant(node)
+	if !ok {
+		targets.dynamic = true
+		return
+	}
+	if stage == "" {
+		targets.canExit = true
+		return
+	}
+	targets.stages = append(targets.stages, stage)
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func campaignWithStages(firstStage string, stages map[string]model.StageSpec) model.CampaignState {
+	for name, stage := range stages {
+		stage.Name = name
+		stages[name] = stage
+	}
+	return model.CampaignState{
+		Spec: &model.CampaignSpec{
+			FirstStage:  firstStage,
+			SelfDriving: true,
+			Stages:      stages,
+		},
+	}
+}
+
+func TestValidateExpressions(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("counter", map[string]model.StageSpec{
+		"counter": {
+			StageSelector: "${{$if($lt($output(counter,val), 20), counter, '')}}",
+			Inputs: map[string]interface{}{
+				"val":    "${{$output(counter,val)}}",
+				"nested": map[string]interface{}{"list": []interface{}{"${{$input(val)}}"}},
+			},
+		},
+	})
+	assert.Empty(t, validator.ValidateExpressions(campaign))
+
+	campaign = campaignWithStages("counter", map[string]model.StageSpec{
+		"counter": {
+			StageSelector: "${{$if($lt($output(count,val), 20), countr, '')}}",
+			Contexts:      "${{$sites()}}",
+			Inputs: map[string]interface{}{
+				"nested": map[string]interface{}{"list": []interface{}{"${{$output(counter, val}}"}},
+			},
+		},
+	})
+	errors := validator.ValidateExpressions(campaign)
+	assert.Equal(t, 4, len(errors))
+	assert.Equal(t, "spec.stages.counter.stageSelector", errors[0].FieldPath)
+	assert.Contains(t, errors[0].DetailedMessage, "$output() refers to stage count")
+	assert.Equal(t, "spec.stages.counter.contexts", errors[1].FieldPath)
+	assert.Contains(t, errors[1].DetailedMessage, "unknown function $sites()")
+	assert.Equal(t, "spec.stages.counter.inputs.nested.list[0]", errors[2].FieldPath)
+	assert.Contains(t, errors[2].DetailedMessage, "invalid expression")
+	assert.Equal(t, "spec.stages.counter.stageSelector", errors[3].FieldPath)
+	assert.Equal(t, "countr", errors[3].Value)
+}
+
+func TestValidateExpressionsWithHyphenatedStageNames(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("weight-counter", map[string]model.StageSpec{
+		"weight-counter": {
+			StageSelector: "${{$if($le($output(weight-counter,weight),0), roll-back, $if($ge($output(weight-counter, weight), 100), '', weight-counter))}}",
+		},
+		"roll-back": {},
+	})
+	assert.Empty(t, validator.ValidateExpressions(campaign))
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+}
+
+func TestValidateStageGraphUnreachableStage(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy":  {StageSelector: "verify"},
+		"verify":  {},
+		"cleanup": {},
+	})
+	errors := validator.ValidateStageGraph(campaign)
+	assert.Equal(t, 1, len(errors))
+	assert.Equal(t, "spec.stages.cleanup", errors[0].FieldPath)
+	assert.Contains(t, errors[0].DetailedMessage, "can't be reached")
+
+	// A selector that depends on outputs can select any stage
+	campaign.Spec.Stages["verify"] = model.StageSpec{Name: "verify", StageSelector: "${{$output(deploy,next)}}"}
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+
+	// Stages of campaigns that aren't self-driving are triggered one by one
+	campaign.Spec.Stages["verify"] = model.StageSpec{Name: "verify"}
+	campaign.Spec.SelfDriving = false
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+}
+
+func TestValidateStageGraphLoopWithoutExit(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy": {StageSelector: "verify"},
+		"verify": {StageSelector: "${{$if($equal($output(verify,status), 200), deploy, retry)}}"},
+		"retry":  {StageSelector: "verify"},
+	})
+	errors := validator.ValidateStageGraph(campaign)
+	assert.Equal(t, 1, len(errors))
+	assert.Equal(t, "spec.stages", errors[0].FieldPath)
+	assert.Equal(t, []string{"deploy", "retry", "verify"}, errors[0].Value)
+
+	campaign.Spec.Stages["verify"] = model.StageSpec{Name: "verify", StageSelector: "${{$if($equal($output(verify,status), 200), '', retry)}}"}
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+}
+
+func TestValidateStageGraphWithBranches(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("start", map[string]model.StageSpec{
+		"start": {NextStages: []string{"a", "b"}},
+		"a":     {StageSelector: "join"},
+		"b":     {StageSelector: "join"},
+		"join":  {Join: &model.JoinSpec{Predecessors: []string{"a", "b"}}},
+	})
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+}
+
+func TestValidateForEach(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy": {
+			Provider: "providers.stage.mock",
+			ForEach:  "${{$trigger(regions, '')}}",
+		},
+	})
+	assert.Nil(t, validator.ValidateStages(campaign))
+	assert.Empty(t, validator.ValidateExpressions(campaign))
+
+	campaign = campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy": {
+			ForEach: "${{$regions()}}",
+		},
+	})
+	err := validator.ValidateStages(campaign)
+	assert.NotNil(t, err)
+	assert.Equal(t, "spec.stages.deploy.forEach", err.FieldPath)
+	errors := validator.ValidateExpressions(campaign)
+	assert.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].DetailedMessage, "unknown function $regions()")
+}
+
+func TestValidateNotifications(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy": {},
+	})
+	campaign.Spec.Notifications = []model.NotificationSpec{
+		{
+			On: []string{model.NotificationEventFailure, model.NotificationEventCancelled},
+			Inputs: map[string]interface{}{
+				"revision": "${{$output(deploy,revision)}}",
+			},
+		},
+	}
+	assert.Empty(t, validator.ValidateNotifications(campaign))
+
+	campaign.Spec.Notifications = []model.NotificationSpec{
+		{},
+		{
+			On: []string{"failed"},
+			Inputs: map[string]interface{}{
+				"revision": "${{$output(build,revision)}}",
+			},
+		},
+	}
+	errors := validator.ValidateNotifications(campaign)
+	assert.Equal(t, 3, len(errors))
+	assert.Equal(t, "spec.notifications[0].on", errors[0].FieldPath)
+	assert.Equal(t, "spec.notifications[1].on", errors[1].FieldPath)
+	assert.Equal(t, "failed", errors[1].Value)
+	assert.Equal(t, "spec.notifications[1].inputs.revision", errors[2].FieldPath)
+}
+
+func TestValidateHooks(t *testing.T) {
+	validator := NewCampaignValidator(nil, nil)
+	campaign := campaignWithStages("deploy", map[string]model.StageSpec{
+		"deploy":   {},
+		"rollback": {},
+	})
+	campaign.Spec.Hooks = &model.CampaignHooks{
+		OnFailure: []string{"rollback"},
+	}
+	assert.Empty(t, validator.ValidateHooks(campaign))
+	// hook stages don't need to be reached from the first stage
+	assert.Empty(t, validator.ValidateStageGraph(campaign))
+
+	campaign.Spec.Hooks.Finally = []string{"rollback", "cleanup"}
+	errors := validator.ValidateHooks(campaign)
+	assert.Equal(t, 1, len(errors))
+	assert.Equal(t, "spec.hooks.finally[1]", errors[0].FieldPath)
+	assert.Equal(t, "cleanup", errors[0].Value)
+}
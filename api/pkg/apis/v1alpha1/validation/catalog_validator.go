@@ -90,62 +90,27 @@ func (c *CatalogValidator) ValidateDelete(ctx context.Context, catalog interface
 
 // Validate Schema is valid
 func (c *CatalogValidator) ValidateSchema(ctx context.Context, new model.CatalogState) *ErrorField {
-	if c.CatalogLookupFunc == nil {
-		return nil
-	}
-	if schemaName, ok := new.Spec.Metadata["schema"]; ok {
-		// 1). Lookup catalog object with schema name
-		schemaName = ConvertReferenceToObjectName(schemaName)
-		lookupRes, err := c.CatalogLookupFunc(ctx, schemaName, new.ObjectMeta.Namespace)
-
-		if err != nil {
-			return &ErrorField{
-				FieldPath:       "spec.metadata.schema",
-				Value:           schemaName,
-				DetailedMessage: "could not find the required schema",
-			}
-		}
-		marshalResult, _ := json.Marshal(lookupRes)
-		var catalog model.CatalogState
-		err = json.Unmarshal(marshalResult, &catalog)
-		if err != nil {
-			return &ErrorField{
-				FieldPath:       "spec.metadata.schema",
-				Value:           schemaName,
-				DetailedMessage: "schema is not a valid catalog object",
-			}
-		}
-		if spec, ok := catalog.Spec.Properties["spec"]; ok {
-			// 2). Extract Schema object from the catalog object
-			var schemaObj utils.Schema
-			jData, _ := json.Marshal(spec)
-			err := json.Unmarshal(jData, &schemaObj)
-			if err != nil {
+	if new.Spec.CatalogType == "schema" {
+		// a schema catalog needs a schema that compiles
+		if spec, ok := new.Spec.Properties["spec"]; ok {
+			if _, err := utils.ParseSchema(spec); err != nil {
 				return &ErrorField{
-					FieldPath:       "spec.metadata.schema",
-					Value:           schemaName,
-					DetailedMessage: "invalid schema",
-				}
-			}
-
-			// 3). Validate the schema on the catalog which is being created/updated
-			result, err := schemaObj.CheckProperties(ctx, new.Spec.Properties, nil)
-			if err != nil {
-				return &ErrorField{
-					FieldPath:       "spec.metadata.schema",
-					Value:           schemaName,
-					DetailedMessage: "unable to determine the validity of the schema",
-				}
-			}
-			if !result.Valid {
-				return &ErrorField{
-					FieldPath:       "spec.Properties",
+					FieldPath:       "spec.properties.spec",
 					Value:           "(hidden)",
-					DetailedMessage: "invalid schema result: " + result.ToErrorMessages(),
+					DetailedMessage: "invalid schema: " + err.Error(),
 				}
 			}
 		}
 	}
+	if c.CatalogLookupFunc == nil {
+		return nil
+	}
+	if schemaName, ok := new.Spec.Metadata["schema"]; ok {
+		if new.Spec.Properties == nil {
+			new.Spec.Properties = make(map[string]interface{})
+		}
+		return ValidatePropertiesWithSchema(ctx, c.CatalogLookupFunc, schemaName, new.ObjectMeta.Namespace, new.Spec.Properties, "spec.metadata.schema", "spec.Properties")
+	}
 	return nil
 }
 
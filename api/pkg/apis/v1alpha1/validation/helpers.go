@@ -8,6 +8,7 @@ package validation
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -214,6 +215,62 @@ func ValidateRootResource(ctx context.Context, o model.ObjectMeta, rootResource
 	return nil
 }
 
+// ValidatePropertiesWithSchema validates properties with the schema of a schema catalog, which is looked up by
+// its reference. Missing properties get the defaults of a JSON schema.
+func ValidatePropertiesWithSchema(ctx context.Context, lookupFunc ObjectLookupFunc, schemaName string, namespace string, properties map[string]interface{}, schemaPath string, propertiesPath string) *ErrorField {
+	// 1). Lookup catalog object with schema name
+	schemaName = ConvertReferenceToObjectName(schemaName)
+	lookupRes, err := lookupFunc(ctx, schemaName, namespace)
+	if err != nil {
+		return &ErrorField{
+			FieldPath:       schemaPath,
+			Value:           schemaName,
+			DetailedMessage: "could not find the required schema",
+		}
+	}
+	marshalResult, _ := json.Marshal(lookupRes)
+	var catalog model.CatalogState
+	err = json.Unmarshal(marshalResult, &catalog)
+	if err != nil || catalog.Spec == nil {
+		return &ErrorField{
+			FieldPath:       schemaPath,
+			Value:           schemaName,
+			DetailedMessage: "schema is not a valid catalog object",
+		}
+	}
+	spec, ok := catalog.Spec.Properties["spec"]
+	if !ok {
+		return nil
+	}
+	// 2). Extract Schema object from the catalog object
+	schemaObj, err := api_utils.ParseSchema(spec)
+	if err != nil {
+		return &ErrorField{
+			FieldPath:       schemaPath,
+			Value:           schemaName,
+			DetailedMessage: "invalid schema",
+		}
+	}
+
+	// 3). Validate the properties with the schema
+	result, err := schemaObj.CheckProperties(ctx, properties, nil)
+	if err != nil {
+		return &ErrorField{
+			FieldPath:       schemaPath,
+			Value:           schemaName,
+			DetailedMessage: "unable to determine the validity of the schema",
+		}
+	}
+	if !result.Valid {
+		return &ErrorField{
+			FieldPath:       propertiesPath,
+			Value:           "(hidden)",
+			DetailedMessage: "invalid schema result: " + result.ToErrorMessages(),
+		}
+	}
+	return nil
+}
+
 // Validate the name of versioned objects
 func ValidateObjectName(name string, rootResource string, minLength int, maxLength int) *ErrorField {
 	if rootResource == "" {
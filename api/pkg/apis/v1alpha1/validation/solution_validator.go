@@ -8,6 +8,7 @@ package validation
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -23,24 +24,29 @@ type SolutionValidator struct {
 	SolutionInstanceLookupFunc   LinkedObjectLookupFunc
 	SolutionContainerLookupFunc  ObjectLookupFunc
 	UniqueNameSolutionLookupFunc ObjectLookupFunc
+	// Check component properties with the schema catalogs they refer to
+	CatalogLookupFunc ObjectLookupFunc
 }
 
-func NewSolutionValidator(solutionInstanceLookupFunc LinkedObjectLookupFunc, solutionContainerLookupFunc ObjectLookupFunc, uniqueNameSolutionLookupFunc ObjectLookupFunc) SolutionValidator {
+func NewSolutionValidator(solutionInstanceLookupFunc LinkedObjectLookupFunc, solutionContainerLookupFunc ObjectLookupFunc, uniqueNameSolutionLookupFunc ObjectLookupFunc, catalogLookupFunc ObjectLookupFunc) SolutionValidator {
 	return SolutionValidator{
 		SolutionInstanceLookupFunc:   solutionInstanceLookupFunc,
 		SolutionContainerLookupFunc:  solutionContainerLookupFunc,
 		UniqueNameSolutionLookupFunc: uniqueNameSolutionLookupFunc,
+		CatalogLookupFunc:            catalogLookupFunc,
 	}
 }
 
 // Validate Solution creation or update
 // 1. DisplayName is unique
 // 2. name and rootResource is valid. And rootResource is immutable for update
+// 3. Component properties are valid with the schemas of the components
 func (s *SolutionValidator) ValidateCreateOrUpdate(ctx context.Context, newRef interface{}, oldRef interface{}) []ErrorField {
 	new := s.ConvertInterfaceToSolution(newRef)
 	old := s.ConvertInterfaceToSolution(oldRef)
 
 	errorFields := []ErrorField{}
+	errorFields = append(errorFields, s.ValidateComponentSchemas(ctx, new)...)
 	if oldRef == nil || new.Spec.DisplayName != old.Spec.DisplayName {
 		if err := s.ValidateSolutionUniqueName(ctx, new); err != nil {
 			errorFields = append(errorFields, *err)
@@ -97,6 +103,29 @@ func (s *SolutionValidator) ValidateSolutionUniqueName(ctx context.Context, solu
 	return nil
 }
 
+// Validate the properties of components that have a schema in their metadata with that schema
+func (s *SolutionValidator) ValidateComponentSchemas(ctx context.Context, solution model.SolutionState) []ErrorField {
+	errorFields := []ErrorField{}
+	if s.CatalogLookupFunc == nil {
+		return errorFields
+	}
+	for i := range solution.Spec.Components {
+		component := &solution.Spec.Components[i]
+		schemaName, ok := component.Metadata["schema"]
+		if !ok {
+			continue
+		}
+		if component.Properties == nil {
+			component.Properties = make(map[string]interface{})
+		}
+		path := fmt.Sprintf("spec.components[%d]", i)
+		if err := ValidatePropertiesWithSchema(ctx, s.CatalogLookupFunc, schemaName, solution.ObjectMeta.Namespace, component.Properties, path+".metadata.schema", path+".properties"); err != nil {
+			errorFields = append(errorFields, *err)
+		}
+	}
+	return errorFields
+}
+
 // Validate no instance associated with the solution
 // SolutionInstanceLookupFunc will lookup instances with labels {"solution": s.ObjectMeta.Name}
 func (s *SolutionValidator) ValidateNoInstanceForSolution(ctx context.Context, solution model.SolutionState) *ErrorField {
@@ -74,7 +74,98 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onStatus,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/pause",
+			Version:    o.Version,
+			Handler:    o.onPause,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/resume",
+			Version:    o.Version,
+			Handler:    o.onResume,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/cancel",
+			Version:    o.Version,
+			Handler:    o.onCancel,
+			Parameters: []string{"name"},
+		},
+	}
+}
+
+func (c *ActivationsVendor) onPause(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, "onPause", model.ActivationStatePaused)
+}
+
+func (c *ActivationsVendor) onResume(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, "onResume", model.ActivationStateRunning)
+}
+
+func (c *ActivationsVendor) onCancel(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, "onCancel", model.ActivationStateCancelled)
+}
+
+// onControl sets the desired state of an activation and asks the stage runtime to apply it
+func (c *ActivationsVendor) onControl(request v1alpha2.COARequest, method string, state string) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": method,
+	})
+	defer span.End()
+
+	vLog.InfofCtx(pCtx, "V (Activations Vendor): %s, method: %s", method, string(request.Method))
+
+	namespace, namespaceSupplied := request.Parameters["namespace"]
+	if !namespaceSupplied {
+		namespace = "default"
 	}
+
+	switch request.Method {
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan(method+"-POST", pCtx, nil)
+		id := request.Parameters["__name"]
+		activation, err := c.ActivationsManager.ControlActivation(ctx, id, namespace, state)
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): %s failed - %s", method, err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		err = c.Context.Publish("activation-control", v1alpha2.Event{
+			Body: v1alpha2.ActivationControlData{
+				Activation: id,
+				Namespace:  namespace,
+				State:      state,
+			},
+			Context: ctx,
+		})
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): %s failed - %s", method, err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(activation, false, request.Parameters["path"], request.Parameters["doc-type"])
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	vLog.InfofCtx(pCtx, "V (Activations Vendor): %s failed - 405 method not allowed", method)
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
 }
 
 func (c *ActivationsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
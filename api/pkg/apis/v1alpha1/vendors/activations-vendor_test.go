@@ -39,7 +39,7 @@ func TestActivationsEndpoints(t *testing.T) {
 	vendor := createActivationsVendor()
 	vendor.Route = "activations"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 2, len(endpoints))
+	assert.Equal(t, 5, len(endpoints))
 }
 func TestActivationsInfo(t *testing.T) {
 	vendor := createActivationsVendor()
@@ -175,6 +175,67 @@ func TestActivationsOnActivations(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.OK, resp.State)
 }
+func TestActivationsPauseResumeCancel(t *testing.T) {
+	vendor := createActivationsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	controls := make(chan v1alpha2.ActivationControlData, 3)
+	vendor.Context.Subscribe("activation-control", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var control v1alpha2.ActivationControlData
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &control)
+			assert.Nil(t, err)
+			controls <- control
+			return nil
+		},
+	})
+	err := vendor.ActivationsManager.UpsertState(context.Background(), "activation1", model.ActivationState{
+		Spec: &model.ActivationSpec{Campaign: "campaign1"},
+	})
+	assert.Nil(t, err)
+
+	request := v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name": "activation1",
+		},
+		Context: context.Background(),
+	}
+	resp := vendor.onPause(request)
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var activation model.ActivationState
+	err = json.Unmarshal(resp.Body, &activation)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ActivationStatePaused, activation.Spec.State)
+	control := <-controls
+	assert.Equal(t, "activation1", control.Activation)
+	assert.Equal(t, "default", control.Namespace)
+	assert.Equal(t, model.ActivationStatePaused, control.State)
+
+	resp = vendor.onResume(request)
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	control = <-controls
+	assert.Equal(t, model.ActivationStateRunning, control.State)
+
+	resp = vendor.onCancel(request)
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	control = <-controls
+	assert.Equal(t, model.ActivationStateCancelled, control.State)
+
+	resp = vendor.onResume(request)
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	request.Parameters["__name"] = "activation2"
+	resp = vendor.onPause(request)
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+
+	request.Method = fasthttp.MethodGet
+	resp = vendor.onCancel(request)
+	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
+}
 func TestActivationsWrongMethod(t *testing.T) {
 	vendor := createActivationsVendor()
 	resp := vendor.onActivations(v1alpha2.COARequest{
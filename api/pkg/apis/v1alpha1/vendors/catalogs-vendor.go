@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -65,17 +66,11 @@ func (e *CatalogsVendor) Init(config vendors.VendorConfig, factories []managers.
 				err = utils2.UnmarshalJson(jData, &catalog)
 				origin := event.Metadata["origin"]
 				if err == nil {
-					name := fmt.Sprintf("%s-%s", origin, catalog.ObjectMeta.Name)
-					catalog.ObjectMeta.Name = name
-					catalog.Spec.RootResource = validation.GetRootResourceFromName(name)
-					if catalog.Spec.ParentName != "" {
-						catalog.Spec.ParentName = fmt.Sprintf("%s-%s", origin, catalog.Spec.ParentName)
-					}
 					ctx := context.TODO()
 					if event.Context != nil {
 						ctx = event.Context
 					}
-					err := e.CatalogsManager.UpsertState(ctx, name, catalog)
+					err := e.CatalogsManager.UpsertSyncedState(ctx, origin, catalog)
 					if err != nil {
 						return err
 					}
@@ -117,6 +112,27 @@ func (e *CatalogsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Version: e.Version,
 			Handler: e.onCheck,
 		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/revisions",
+			Version:    e.Version,
+			Handler:    e.onRevisions,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/diff",
+			Version:    e.Version,
+			Handler:    e.onDiff,
+			Parameters: []string{"name"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/restore",
+			Version:    e.Version,
+			Handler:    e.onRestore,
+			Parameters: []string{"name"},
+		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
 			Route:      route + "/status",
@@ -126,6 +142,152 @@ func (e *CatalogsVendor) GetEndpoints() []v1alpha2.Endpoint {
 		},
 	}
 }
+func (e *CatalogsVendor) onRevisions(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	rCtx, span := observability.StartSpan("Catalogs Vendor", request.Context, &map[string]string{
+		"method": "onRevisions",
+	})
+	defer span.End()
+
+	lLog.InfofCtx(rCtx, "V (Catalogs Vendor): onRevisions, method: %s", string(request.Method))
+
+	id := request.Parameters["__name"]
+	namespace := request.Parameters["namespace"]
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var state interface{}
+		isArray := false
+		revision, err := parseRevision(request.Parameters, "revision")
+		if err == nil {
+			if _, ok := request.Parameters["revision"]; ok {
+				state, err = e.CatalogsManager.GetRevision(rCtx, id, namespace, revision)
+			} else {
+				state, err = e.CatalogsManager.ListRevisions(rCtx, id, namespace)
+				isArray = true
+			}
+		}
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
+		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+		if request.Parameters["doc-type"] == "yaml" {
+			resp.ContentType = "text/plain"
+		}
+		return resp
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+func (e *CatalogsVendor) onDiff(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	rCtx, span := observability.StartSpan("Catalogs Vendor", request.Context, &map[string]string{
+		"method": "onDiff",
+	})
+	defer span.End()
+
+	lLog.InfofCtx(rCtx, "V (Catalogs Vendor): onDiff, method: %s", string(request.Method))
+
+	id := request.Parameters["__name"]
+	namespace := request.Parameters["namespace"]
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var changes []model.CatalogRevisionChange
+		from, err := parseRevision(request.Parameters, "from")
+		if err == nil {
+			var to int64
+			to, err = parseRevision(request.Parameters, "to")
+			if err == nil {
+				changes, err = e.CatalogsManager.DiffRevisions(rCtx, id, namespace, from, to)
+			}
+		}
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(changes, true, "", "")
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+func (e *CatalogsVendor) onRestore(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	rCtx, span := observability.StartSpan("Catalogs Vendor", request.Context, &map[string]string{
+		"method": "onRestore",
+	})
+	defer span.End()
+
+	lLog.InfofCtx(rCtx, "V (Catalogs Vendor): onRestore, method: %s", string(request.Method))
+
+	id := request.Parameters["__name"]
+	namespace := request.Parameters["namespace"]
+
+	switch request.Method {
+	case fasthttp.MethodPost:
+		if _, ok := request.Parameters["revision"]; !ok {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("missing revision"),
+			})
+		}
+		revision, err := parseRevision(request.Parameters, "revision")
+		if err == nil {
+			err = e.CatalogsManager.RestoreRevision(rCtx, id, namespace, revision)
+		}
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// parseRevision reads a revision number from the request parameters. A missing revision is 0, the last one.
+func parseRevision(parameters map[string]string, key string) (int64, error) {
+	value, ok := parameters[key]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("%s is not a valid revision", key), v1alpha2.BadRequest)
+	}
+	return revision, nil
+}
 func (e *CatalogsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	rCtx, span := observability.StartSpan("Catalogs Vendor", request.Context, &map[string]string{
 		"method": "onStatus",
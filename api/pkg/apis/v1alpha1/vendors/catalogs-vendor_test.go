@@ -536,3 +536,90 @@ func TestCatalogSubscribe(t *testing.T) {
 	}
 	assert.Equal(t, v1alpha2.OK, response.State)
 }
+
+func TestCatalogOnRevisions(t *testing.T) {
+	vendor := CatalogVendorInit()
+	ctx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "admin")
+	catalog := catalogState
+	for _, value := range []string{"value1", "value2"} {
+		catalog.Spec = &model.CatalogSpec{
+			CatalogType: "catalog",
+			Properties: map[string]interface{}{
+				"property1": value,
+			},
+			RootResource: "name1",
+		}
+		b, err := json.Marshal(catalog)
+		assert.Nil(t, err)
+		response := vendor.onCatalogs(v1alpha2.COARequest{
+			Method:     fasthttp.MethodPost,
+			Context:    ctx,
+			Body:       b,
+			Parameters: map[string]string{"__name": catalog.ObjectMeta.Name},
+		})
+		assert.Equal(t, v1alpha2.OK, response.State)
+	}
+
+	response := vendor.onRevisions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var revisions []model.CatalogRevision
+	err := json.Unmarshal(response.Body, &revisions)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, "admin", revisions[0].Author)
+
+	response = vendor.onRevisions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name, "revision": "1"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var revision model.CatalogRevision
+	err = json.Unmarshal(response.Body, &revision)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", revision.Catalog.Spec.Properties["property1"])
+
+	response = vendor.onDiff(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name, "from": "1", "to": "2"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var changes []model.CatalogRevisionChange
+	err = json.Unmarshal(response.Body, &changes)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.CatalogRevisionChange{{Path: "/properties/property1", Op: "replace", Old: "value1", New: "value2"}}, changes)
+
+	response = vendor.onDiff(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name, "from": "first"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+
+	response = vendor.onRestore(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+	response = vendor.onRestore(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": catalog.ObjectMeta.Name, "revision": "1"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	state, err := vendor.CatalogsManager.GetState(ctx, catalog.ObjectMeta.Name, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", state.Spec.Properties["property1"])
+
+	response = vendor.onRestore(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: ctx,
+	})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, response.State)
+}
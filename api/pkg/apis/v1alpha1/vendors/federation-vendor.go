@@ -7,15 +7,33 @@
 package vendors
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaigncontainers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaigns"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogcontainers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instances"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/sites"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/solutioncontainers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/solutions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/staging"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/sync"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/targets"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/trails"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -24,6 +42,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -33,6 +52,13 @@ import (
 
 var fLog = logger.NewLogger("coa.runtime")
 
+const (
+	defaultChannelPingInterval = 30 * time.Second
+	defaultChannelMaxDuration  = 10 * time.Minute
+	// channelBatchSize is how many jobs and catalog changes a sync frame of the push channel carries
+	channelBatchSize = 10
+)
+
 type FederationVendor struct {
 	vendors.Vendor
 	SitesManager    *sites.SitesManager
@@ -40,7 +66,24 @@ type FederationVendor struct {
 	StagingManager  *staging.StagingManager
 	SyncManager     *sync.SyncManager
 	TrailsManager   *trails.TrailsManager
-	apiClient       utils.ApiClient
+	// The managers below are optional. Offline bundles carry the objects of the ones that are supplied.
+	SolutionsManager          *solutions.SolutionsManager
+	SolutionContainersManager *solutioncontainers.SolutionContainersManager
+	CatalogContainersManager  *catalogcontainers.CatalogContainersManager
+	CampaignsManager          *campaigns.CampaignsManager
+	CampaignContainersManager *campaigncontainers.CampaignContainersManager
+	apiClient                 utils.ApiClient
+	// channelPingInterval is how often push channels are pinged when there is nothing to send
+	channelPingInterval time.Duration
+	// channelMaxDuration is how long a push channel stays open before the child site reconnects
+	channelMaxDuration time.Duration
+	// bundleOffline keeps the reports of a site for its parent until they're exported in a bundle
+	bundleOffline bool
+	// bundleArtifactDir is where artifacts are read from when they're exported, and written to when they're imported
+	bundleArtifactDir string
+	// bundleLock makes bundles import one at a time, and lastBundles keeps bundles from being imported twice
+	bundleLock  *gosync.Mutex
+	lastBundles map[string]time.Time
 }
 
 func (f *FederationVendor) GetInfo() vendors.VendorInfo {
@@ -55,6 +98,8 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 	if err != nil {
 		return err
 	}
+	var instancesManager *instances.InstancesManager
+	var targetsManager *targets.TargetsManager
 	for _, m := range f.Managers {
 		if c, ok := m.(*sites.SitesManager); ok {
 			f.SitesManager = c
@@ -71,6 +116,27 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 		if c, ok := m.(*trails.TrailsManager); ok {
 			f.TrailsManager = c
 		}
+		if c, ok := m.(*solutions.SolutionsManager); ok {
+			f.SolutionsManager = c
+		}
+		if c, ok := m.(*solutioncontainers.SolutionContainersManager); ok {
+			f.SolutionContainersManager = c
+		}
+		if c, ok := m.(*catalogcontainers.CatalogContainersManager); ok {
+			f.CatalogContainersManager = c
+		}
+		if c, ok := m.(*campaigns.CampaignsManager); ok {
+			f.CampaignsManager = c
+		}
+		if c, ok := m.(*campaigncontainers.CampaignContainersManager); ok {
+			f.CampaignContainersManager = c
+		}
+		if c, ok := m.(*instances.InstancesManager); ok {
+			instancesManager = c
+		}
+		if c, ok := m.(*targets.TargetsManager); ok {
+			targetsManager = c
+		}
 	}
 	if f.StagingManager == nil {
 		return v1alpha2.NewCOAError(nil, "staging manager is not supplied", v1alpha2.MissingConfig)
@@ -81,12 +147,46 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 	if f.CatalogsManager == nil {
 		return v1alpha2.NewCOAError(nil, "catalogs manager is not supplied", v1alpha2.MissingConfig)
 	}
-	f.apiClient, err = utils.GetParentApiClient(f.Vendor.Context.SiteInfo.ParentSite.BaseUrl)
+	f.apiClient, err = utils.GetParentApiClient(f.Vendor.Context.SiteInfo.ParentSite)
+	if err != nil {
+		return err
+	}
+	f.channelPingInterval, err = parseChannelDuration(config.Properties, "channel.pingInterval", defaultChannelPingInterval)
 	if err != nil {
 		return err
 	}
+	f.channelMaxDuration, err = parseChannelDuration(config.Properties, "channel.maxDuration", defaultChannelMaxDuration)
+	if err != nil {
+		return err
+	}
+	f.bundleOffline = config.Properties["bundle.offline"] == "true"
+	f.bundleArtifactDir = config.Properties["bundle.artifactDir"]
+	f.bundleLock = &gosync.Mutex{}
+	f.lastBundles = make(map[string]time.Time)
+	// The sites manager reports the statuses of the deployments of the site to its parent
+	if instancesManager != nil {
+		f.SitesManager.Instances = instancesManager
+	}
+	if targetsManager != nil {
+		f.SitesManager.Targets = targetsManager
+	}
+	if f.SyncManager != nil {
+		f.SyncManager.CatalogsManager = f.CatalogsManager
+		f.SitesManager.Channel = f.SyncManager
+	}
 	f.Vendor.Context.Subscribe("catalog", v1alpha2.EventHandler{
 		Handler: func(topic string, event v1alpha2.Event) error {
+			var job v1alpha2.JobData
+			jData, _ := json.Marshal(event.Body)
+			if err := json.Unmarshal(jData, &job); err == nil {
+				ctx := context.TODO()
+				if event.Context != nil {
+					ctx = event.Context
+				}
+				if _, err := f.StagingManager.RecordCatalogChange(ctx, job); err != nil {
+					fLog.ErrorfCtx(ctx, "V (Federation): failed to record catalog change: %v", err)
+				}
+			}
 			sites, err := f.SitesManager.ListState(context.TODO())
 			if err != nil {
 				return err
@@ -133,6 +233,22 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 				if event.Context != nil {
 					ctx = event.Context
 				}
+				if f.bundleOffline {
+					return f.StagingManager.QueueReport(model.ChannelMessage{
+						Type:   model.ChannelStatus,
+						Status: &status,
+					})
+				}
+				if f.SyncManager != nil && f.SyncManager.Connected() {
+					err := f.SyncManager.Report(ctx, model.ChannelMessage{
+						Type:   model.ChannelStatus,
+						Status: &status,
+					})
+					if err == nil {
+						return nil
+					}
+					fLog.WarnfCtx(ctx, "V (Federation): failed to report activation status over the push channel, falling back to a request: %v", err)
+				}
 				err := f.apiClient.SyncStageStatus(ctx, status,
 					f.Vendor.Context.SiteInfo.ParentSite.Username,
 					f.Vendor.Context.SiteInfo.ParentSite.Password)
@@ -150,14 +266,22 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 			if event.Context != nil {
 				ctx = event.Context
 			}
-			if f.TrailsManager != nil {
-				jData, _ := json.Marshal(event.Body)
-				var trails []v1alpha2.Trail
-				err := utils2.UnmarshalJson(jData, &trails)
-				if err == nil {
-					return f.TrailsManager.Append(ctx, trails)
+			jData, _ := json.Marshal(event.Body)
+			var trails []v1alpha2.Trail
+			if err := utils2.UnmarshalJson(jData, &trails); err != nil {
+				return nil
+			}
+			if f.bundleOffline {
+				if err := f.StagingManager.QueueReport(model.ChannelMessage{
+					Type:   model.ChannelTrail,
+					Trails: trails,
+				}); err != nil {
+					fLog.ErrorfCtx(ctx, "V (Federation): failed to queue trails for the parent: %v", err)
 				}
 			}
+			if f.TrailsManager != nil {
+				return f.TrailsManager.Append(ctx, trails)
+			}
 			return nil
 		},
 	})
@@ -192,37 +316,136 @@ func (f *FederationVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Methods:    []string{fasthttp.MethodPost, fasthttp.MethodGet},
 			Route:      route + "/sync",
 			Version:    f.Version,
-			Handler:    f.onSync,
+			Handler:    f.withSiteIdentity(f.onSync, "__site", fasthttp.MethodPost, fasthttp.MethodGet),
 			Parameters: []string{"site?"},
 		},
+		{
+			Methods:    []string{fasthttp.MethodPost, fasthttp.MethodGet},
+			Route:      route + "/channel",
+			Version:    f.Version,
+			Handler:    f.withSiteIdentity(f.onChannel, "__site", fasthttp.MethodPost, fasthttp.MethodGet),
+			Parameters: []string{"site"},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/resync",
+			Version: f.Version,
+			Handler: f.withSiteIdentity(f.onResync, ""),
+		},
 		{
 			Methods:    []string{fasthttp.MethodPost, fasthttp.MethodGet},
 			Route:      route + "/registry",
 			Version:    f.Version,
-			Handler:    f.onRegistry,
+			Handler:    f.withSiteIdentity(f.onRegistry, ""),
 			Parameters: []string{"name?"},
 		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
 			Route:      route + "/status",
 			Version:    f.Version,
-			Handler:    f.onStatus,
+			Handler:    f.withSiteIdentity(f.onStatus, "__name", fasthttp.MethodPost),
 			Parameters: []string{"name"},
 		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/trail",
 			Version: f.Version,
-			Handler: f.onTrail,
+			Handler: f.withSiteIdentity(f.onTrail, "", fasthttp.MethodPost),
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/jointokens",
+			Version: f.Version,
+			Handler: f.withSiteIdentity(f.onJoinTokens, ""),
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/enroll",
+			Version: f.Version,
+			Handler: f.withSiteIdentity(f.onEnroll, ""),
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost, fasthttp.MethodGet, fasthttp.MethodDelete},
+			Route:      route + "/certificates",
+			Version:    f.Version,
+			Handler:    f.withSiteIdentity(f.onCertificates, "__site", fasthttp.MethodPost),
+			Parameters: []string{"site"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/export",
+			Version:    f.Version,
+			Handler:    f.withSiteIdentity(f.onExport, ""),
+			Parameters: []string{"site?"},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/import",
+			Version: f.Version,
+			Handler: f.withSiteIdentity(f.onImport, ""),
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet},
+			Route:      route + "/fleet",
+			Version:    f.Version,
+			Handler:    f.withSiteIdentity(f.onFleet, ""),
+			Parameters: []string{"view?"},
 		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/k8shook",
 			Version: f.Version,
-			Handler: f.onK8sHook,
+			Handler: f.withSiteIdentity(f.onK8sHook, ""),
 		},
 	}
 }
+
+// siteIdentityKey is the context key of the site a request was authenticated as with its client certificate
+type siteIdentityKey struct{}
+
+// withSiteIdentity authenticates requests that come with a client certificate. The certificate has to be issued
+// to a registered site, which has to match the siteParam route parameter when it's given, and sites can only use
+// the listed methods. Requests without a certificate are left to token authentication.
+func (f *FederationVendor) withSiteIdentity(handler v1alpha2.COAHandler, siteParam string, methods ...string) v1alpha2.COAHandler {
+	return func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		if request.Context == nil {
+			return handler(request)
+		}
+		chain, _ := request.Context.Value(v1alpha2.COAPeerCertificatesKey).([]*x509.Certificate)
+		if len(chain) == 0 {
+			return handler(request)
+		}
+		site, err := f.SitesManager.VerifySiteCertificate(request.Context, chain)
+		if err != nil {
+			fLog.WarnfCtx(request.Context, "V (Federation): rejected client certificate: %s", err.Error())
+			return v1alpha2.COAResponse{
+				State: v1alpha2.Unauthorized,
+				Body:  []byte(err.Error()),
+			}
+		}
+		allowed := false
+		for _, method := range methods {
+			if method == request.Method {
+				allowed = true
+			}
+		}
+		if !allowed || (siteParam != "" && request.Parameters[siteParam] != "" && request.Parameters[siteParam] != site) {
+			return v1alpha2.COAResponse{
+				State: v1alpha2.Forbidden,
+				Body:  []byte(fmt.Sprintf("site %s is not allowed to call %s %s", site, request.Method, request.Route)),
+			}
+		}
+		request.Context = context.WithValue(request.Context, siteIdentityKey{}, site)
+		return handler(request)
+	}
+}
+
+// siteIdentity gets the site a request was authenticated as with its client certificate
+func siteIdentity(ctx context.Context) (string, bool) {
+	site, ok := ctx.Value(siteIdentityKey{}).(string)
+	return site, ok
+}
+
 func (c *FederationVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
 		"method": "onStatus",
@@ -234,6 +457,12 @@ func (c *FederationVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COARes
 	case fasthttp.MethodPost:
 		var state model.SiteState
 		utils2.UnmarshalJson(request.Body, &state)
+		if site, ok := siteIdentity(request.Context); ok && state.Id != site {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.Forbidden,
+				Body:  []byte(fmt.Sprintf("site %s can't report the status of site %s", site, state.Id)),
+			})
+		}
 
 		err := c.SitesManager.ReportState(pCtx, state)
 
@@ -396,6 +625,34 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 				Body:  []byte(err.Error()),
 			})
 		}
+		if _, ok := request.Parameters["since"]; ok {
+			// The child syncs catalogs from the change journal
+			watermark, err := parseSyncWatermark(request.Parameters)
+			if err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+			var pack model.SyncPackage
+			pack, err = f.getSyncPackage(ctx, id, namespace, watermark, intCount)
+			if err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.GetErrorState(err),
+					Body:  []byte(err.Error()),
+				})
+			}
+			jData, _ := utils.FormatObject(pack, false, request.Parameters["path"], request.Parameters["doc-type"])
+			resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State:       v1alpha2.OK,
+				Body:        jData,
+				ContentType: "application/json",
+			})
+			if request.Parameters["doc-type"] == "yaml" {
+				resp.ContentType = "text/plain"
+			}
+			return resp
+		}
 		batch, err := f.StagingManager.GetABatchForSite(id, intCount)
 
 		pack := model.SyncPackage{
@@ -413,6 +670,9 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 		for _, c := range batch {
 			if c.Action == v1alpha2.JobRun { //TODO: I don't really like this
 				jobs = append(jobs, c)
+			} else if c.Action == v1alpha2.JobDelete {
+				// Deletions are only sent to children syncing from the change journal
+				continue
 			} else {
 				catalog, err := f.CatalogsManager.GetState(ctx, c.Id, namespace)
 				if err != nil {
@@ -445,6 +705,286 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// getSyncPackage gets the jobs queued for a child site, and the catalog changes after its watermark. When the
+// changes can't be computed from the watermark, all catalogs are sent as a full sync.
+func (f *FederationVendor) getSyncPackage(ctx context.Context, site string, namespace string, watermark model.SyncWatermark, count int) (model.SyncPackage, error) {
+	pack := model.SyncPackage{
+		Origin:    f.Context.SiteInfo.SiteId,
+		Catalogs:  make([]model.CatalogState, 0),
+		Jobs:      make([]v1alpha2.JobData, 0),
+		Deletions: make([]model.CatalogTombstone, 0),
+	}
+	batch, err := f.StagingManager.GetABatchForSite(site, count)
+	if err != nil {
+		return pack, err
+	}
+	for _, job := range batch {
+		if job.Action == v1alpha2.JobRun {
+			pack.Jobs = append(pack.Jobs, job)
+		}
+	}
+
+	changes, err := f.StagingManager.GetCatalogChanges(ctx, site, watermark, namespace, count)
+	if err != nil {
+		return pack, err
+	}
+	pack.Epoch = changes.Epoch
+	pack.Watermark = changes.Watermark
+	pack.FullSync = changes.FullSync
+	pack.More = changes.More
+	if changes.FullSync {
+		fLog.InfofCtx(ctx, "V (Federation): sending all catalogs to site %s at revision %d", site, changes.Watermark)
+		pack.Catalogs, err = f.CatalogsManager.ListState(ctx, namespace, "", "")
+		return pack, err
+	}
+	for _, change := range changes.Changes {
+		if !change.Deleted {
+			var catalog model.CatalogState
+			catalog, err = f.CatalogsManager.GetState(ctx, change.Name, change.Namespace)
+			if err == nil {
+				pack.Catalogs = append(pack.Catalogs, catalog)
+				continue
+			}
+			if !utils.IsNotFound(err) {
+				return pack, err
+			}
+			// The catalog was deleted after the change, and the deletion is on its way to the journal
+		}
+		pack.Deletions = append(pack.Deletions, model.CatalogTombstone{
+			Name:      change.Name,
+			Namespace: change.Namespace,
+			Revision:  change.Revision,
+		})
+	}
+	return pack, nil
+}
+
+// parseSyncWatermark reads the watermark a child site sends as the since, epoch and resync parameters
+func parseSyncWatermark(parameters map[string]string) (model.SyncWatermark, error) {
+	watermark := model.SyncWatermark{
+		Epoch:  parameters["epoch"],
+		Resync: parameters["resync"] == "true",
+	}
+	if since, ok := parameters["since"]; ok && since != "" {
+		revision, err := strconv.ParseInt(since, 10, 64)
+		if err != nil {
+			return watermark, v1alpha2.NewCOAError(err, "since is not a valid revision", v1alpha2.BadRequest)
+		}
+		watermark.Revision = revision
+	}
+	return watermark, nil
+}
+
+func parseChannelDuration(properties map[string]string, key string, defaultValue time.Duration) (time.Duration, error) {
+	v, ok := properties[key]
+	if !ok || v == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("%s is not a valid duration", key), v1alpha2.BadConfig)
+	}
+	return d, nil
+}
+
+// onChannel serves the push channel of a child site. A GET opens a stream of sync frames the parent writes as
+// soon as there are jobs or catalog changes for the site, starting from the watermark the child sends. A POST
+// takes frames from the child: stage status reports and heartbeats.
+func (f *FederationVendor) onChannel(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onChannel",
+	})
+	defer span.End()
+
+	site := request.Parameters["__site"]
+	tLog.InfofCtx(pCtx, "V (Federation): onChannel %s, site: %s", request.Method, site)
+	if site == "" {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("site is required"),
+		})
+	}
+	switch request.Method {
+	case fasthttp.MethodGet:
+		namespace, exist := request.Parameters["namespace"]
+		if !exist {
+			namespace = "default"
+		}
+		watermark, err := parseSyncWatermark(request.Parameters)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		// The first package is built before the stream starts, so errors are returned to the child
+		pack, err := f.getSyncPackage(pCtx, site, namespace, watermark, channelBatchSize)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			ContentType: "application/x-ndjson",
+			BodyStream: func(w *bufio.Writer) {
+				f.streamChannel(pCtx, site, namespace, pack, w)
+			},
+		})
+	case fasthttp.MethodPost:
+		decoder := json.NewDecoder(bytes.NewReader(request.Body))
+		for {
+			var message model.ChannelMessage
+			err := decoder.Decode(&message)
+			if err == io.EOF {
+				break
+			}
+			if err == nil {
+				err = f.handleChannelMessage(pCtx, site, message)
+			} else {
+				err = v1alpha2.NewCOAError(err, "invalid channel message", v1alpha2.BadRequest)
+			}
+			if err != nil {
+				tLog.ErrorfCtx(pCtx, "V (Federation): failed to handle channel message of site %s: %v", site, err)
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.GetErrorState(err),
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// streamChannel writes sync frames to a child site until the connection drops, the manager shuts down, or the
+// channel reaches its maximum duration. The watermark of the channel moves with each frame written, so the
+// child reconnects with its own watermark when it fails to apply one.
+func (f *FederationVendor) streamChannel(ctx context.Context, site string, namespace string, pack model.SyncPackage, w *bufio.Writer) {
+	notify, stop := f.StagingManager.WatchSite(site)
+	defer stop()
+	ping := time.NewTicker(f.channelPingInterval)
+	defer ping.Stop()
+	deadline := time.NewTimer(f.channelMaxDuration)
+	defer deadline.Stop()
+
+	fLog.InfofCtx(ctx, "V (Federation): push channel of site %s is open", site)
+	defer fLog.InfofCtx(ctx, "V (Federation): push channel of site %s is closed", site)
+	var watermark model.SyncWatermark
+	next := &pack
+	for {
+		for next != nil {
+			if err := writeChannelMessage(w, model.ChannelMessage{Type: model.ChannelSync, Package: next}); err != nil {
+				fLog.InfofCtx(ctx, "V (Federation): failed to write to the push channel of site %s: %v", site, err)
+				return
+			}
+			watermark = model.SyncWatermark{Epoch: next.Epoch, Revision: next.Watermark}
+			if !next.More {
+				next = nil
+			} else if !f.nextChannelPackage(ctx, site, namespace, watermark, &next) {
+				return
+			}
+		}
+		select {
+		case _, ok := <-notify:
+			if !ok {
+				return
+			}
+			if !f.nextChannelPackage(ctx, site, namespace, watermark, &next) {
+				return
+			}
+		case <-ping.C:
+			if err := writeChannelMessage(w, model.ChannelMessage{Type: model.ChannelPing}); err != nil {
+				fLog.InfofCtx(ctx, "V (Federation): failed to ping the push channel of site %s: %v", site, err)
+				return
+			}
+		case <-deadline.C:
+			return
+		}
+	}
+}
+
+// nextChannelPackage gets the next package of a push channel. next is left nil when there is nothing to send.
+// It returns false when the package can't be built, which closes the channel.
+func (f *FederationVendor) nextChannelPackage(ctx context.Context, site string, namespace string, watermark model.SyncWatermark, next **model.SyncPackage) bool {
+	pack, err := f.getSyncPackage(ctx, site, namespace, watermark, channelBatchSize)
+	if err != nil {
+		fLog.ErrorfCtx(ctx, "V (Federation): failed to get the sync package of site %s: %v", site, err)
+		return false
+	}
+	*next = nil
+	if len(pack.Jobs) > 0 || len(pack.Catalogs) > 0 || len(pack.Deletions) > 0 || pack.FullSync ||
+		pack.Epoch != watermark.Epoch || pack.Watermark != watermark.Revision {
+		*next = &pack
+	}
+	return true
+}
+
+func writeChannelMessage(w *bufio.Writer, message model.ChannelMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.WriteByte('\n'); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (f *FederationVendor) handleChannelMessage(ctx context.Context, site string, message model.ChannelMessage) error {
+	switch message.Type {
+	case model.ChannelStatus:
+		if message.Status == nil {
+			return v1alpha2.NewCOAError(nil, "status message has no status", v1alpha2.BadRequest)
+		}
+		return f.Vendor.Context.Publish("job-report", v1alpha2.Event{
+			Body:    *message.Status,
+			Context: ctx,
+		})
+	case model.ChannelHeartbeat:
+		if message.Site == nil || message.Site.Id != site {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("heartbeat message isn't from site %s", site), v1alpha2.BadRequest)
+		}
+		return f.SitesManager.ReportState(ctx, *message.Site)
+	case model.ChannelTrail:
+		if f.TrailsManager == nil || len(message.Trails) == 0 {
+			return nil
+		}
+		return f.TrailsManager.Append(ctx, message.Trails)
+	}
+	return v1alpha2.NewCOAError(nil, fmt.Sprintf("channel message type '%s' is not supported", message.Type), v1alpha2.BadRequest)
+}
+func (f *FederationVendor) onResync(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onResync",
+	})
+	defer span.End()
+
+	tLog.InfoCtx(pCtx, "V (Federation): onResync")
+	if f.SyncManager == nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("sync manager is not supplied"),
+		})
+	}
+	f.SyncManager.RequestResync()
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State: v1alpha2.OK,
+	})
+}
 func (f *FederationVendor) onTrail(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	_, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
 		"method": "onTrail",
@@ -477,13 +1017,20 @@ func (f *FederationVendor) onK8sHook(request v1alpha2.COARequest) v1alpha2.COARe
 					Body:  []byte(err.Error()),
 				})
 			}
+			action := v1alpha2.JobUpdate
+			catalogType := ""
+			if request.Parameters["action"] == "delete" {
+				action = v1alpha2.JobDelete
+			} else if catalog.Spec != nil {
+				catalogType = catalog.Spec.CatalogType
+			}
 			err = f.Vendor.Context.Publish("catalog", v1alpha2.Event{
 				Metadata: map[string]string{
-					"objectType": catalog.Spec.CatalogType,
+					"objectType": catalogType,
 				},
 				Body: v1alpha2.JobData{
 					Id:     catalog.ObjectMeta.Name,
-					Action: v1alpha2.JobUpdate, //TODO: handle deletion, this probably requires BetBachForSites return flags
+					Action: action,
 					Body:   catalog,
 				},
 				Context: ctx,
@@ -508,3 +1055,684 @@ func (f *FederationVendor) onK8sHook(request v1alpha2.COARequest) v1alpha2.COARe
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+func (f *FederationVendor) onJoinTokens(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onJoinTokens",
+	})
+	defer span.End()
+
+	tLog.InfoCtx(pCtx, "V (Federation): onJoinTokens")
+	switch request.Method {
+	case fasthttp.MethodPost:
+		var tokenRequest model.SiteJoinTokenRequest
+		err := utils2.UnmarshalJson(request.Body, &tokenRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		var ttl time.Duration
+		if tokenRequest.TTL != "" {
+			ttl, err = time.ParseDuration(tokenRequest.TTL)
+			if err != nil || ttl <= 0 {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(fmt.Sprintf("ttl '%s' is not a valid duration", tokenRequest.TTL)),
+				})
+			}
+		}
+		grant, err := f.SitesManager.CreateJoinToken(pCtx, tokenRequest.Site, ttl)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(grant)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (f *FederationVendor) onEnroll(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onEnroll",
+	})
+	defer span.End()
+
+	tLog.InfoCtx(pCtx, "V (Federation): onEnroll")
+	switch request.Method {
+	case fasthttp.MethodPost:
+		var enrollmentRequest model.SiteEnrollmentRequest
+		err := utils2.UnmarshalJson(request.Body, &enrollmentRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		enrollment, err := f.SitesManager.Enroll(pCtx, enrollmentRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(enrollment)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (f *FederationVendor) onCertificates(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onCertificates",
+	})
+	defer span.End()
+
+	site := request.Parameters["__site"]
+	tLog.InfofCtx(pCtx, "V (Federation): onCertificates %s, site: %s", request.Method, site)
+	switch request.Method {
+	case fasthttp.MethodGet:
+		state, err := f.SitesManager.GetState(pCtx, site)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		certificates := state.Spec.Certificates
+		if certificates == nil {
+			certificates = make([]model.SiteCertificate, 0)
+		}
+		jData, _ := json.Marshal(certificates)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		if _, ok := siteIdentity(request.Context); !ok {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.Unauthorized,
+				Body:  []byte("a certificate is rotated with the current client certificate of the site"),
+			})
+		}
+		var enrollmentRequest model.SiteEnrollmentRequest
+		err := utils2.UnmarshalJson(request.Body, &enrollmentRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		enrollmentRequest.Site = site
+		enrollment, err := f.SitesManager.RotateCertificate(pCtx, enrollmentRequest)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(enrollment)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		err := f.SitesManager.RevokeCertificates(pCtx, site, request.Parameters["serial"])
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// onFleet queries the status of the sites this site sees, including the sites below its children. The view is
+// sites (the default), instances or targets.
+func (f *FederationVendor) onFleet(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onFleet",
+	})
+	defer span.End()
+
+	view := request.Parameters["__view"]
+	tLog.InfofCtx(pCtx, "V (Federation): onFleet, view: %s", view)
+	switch request.Method {
+	case fasthttp.MethodGet:
+		filter, err := parseFleetFilter(request.Parameters)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		fleet, err := f.SitesManager.Fleet(pCtx)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		var result interface{}
+		switch view {
+		case "", "sites":
+			result = sites.FilterSites(fleet, filter)
+		case "instances":
+			result = sites.FilterInstances(fleet, filter)
+		case "targets":
+			result = sites.FilterTargets(fleet, filter)
+		default:
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(fmt.Sprintf("fleet view '%s' is not supported", view)),
+			})
+		}
+		jData, _ := utils.FormatObject(result, true, request.Parameters["path"], request.Parameters["doc-type"])
+		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+		if request.Parameters["doc-type"] == "yaml" {
+			resp.ContentType = "text/plain"
+		}
+		return resp
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// parseFleetFilter reads a fleet filter from the property (as comma-separated key=value pairs), online, staleFor,
+// state and solution parameters
+func parseFleetFilter(parameters map[string]string) (sites.FleetFilter, error) {
+	filter := sites.FleetFilter{
+		State:    parameters["state"],
+		Solution: parameters["solution"],
+	}
+	if v := parameters["property"]; v != "" {
+		filter.Properties = make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return filter, fmt.Errorf("property filter '%s' is not a key=value pair", pair)
+			}
+			filter.Properties[kv[0]] = kv[1]
+		}
+	}
+	if v := parameters["online"]; v != "" {
+		online, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("online '%s' is not a boolean", v)
+		}
+		filter.Online = &online
+	}
+	if v := parameters["staleFor"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return filter, fmt.Errorf("staleFor '%s' is not a valid duration", v)
+		}
+		filter.StaleFor = d
+	}
+	return filter, nil
+}
+
+// onExport exports an offline bundle. With a site, the bundle carries what the child site needs from this site:
+// its pending jobs, catalogs, and the solutions and campaigns they refer to. Without a site, it carries the
+// reports this site queued for its parent while offline.
+func (f *FederationVendor) onExport(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onExport",
+	})
+	defer span.End()
+
+	site := request.Parameters["__site"]
+	tLog.InfofCtx(pCtx, "V (Federation): onExport, site: %s", site)
+	switch request.Method {
+	case fasthttp.MethodPost:
+		// Jobs and reports are taken from their queues as they're exported, so the signer is checked first
+		certPEM, sign, err := f.bundleSigner(site)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		var exportRequest model.BundleExportRequest
+		if len(request.Body) > 0 {
+			if err := utils2.UnmarshalJson(request.Body, &exportRequest); err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		var pack model.SyncPackage
+		if site == "" {
+			pack, err = f.exportReports(pCtx)
+		} else {
+			pack, err = f.exportSite(pCtx, site, request.Parameters, exportRequest)
+		}
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		bundle, err := sync.SignBundle(pack, certPEM, sign)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(bundle)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// onImport imports an offline bundle. A bundle for this site is applied like a sync package from its parent,
+// and a bundle from a child site has its reports handled like frames of its push channel.
+func (f *FederationVendor) onImport(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Federation Vendor", request.Context, &map[string]string{
+		"method": "onImport",
+	})
+	defer span.End()
+
+	tLog.InfoCtx(pCtx, "V (Federation): onImport")
+	switch request.Method {
+	case fasthttp.MethodPost:
+		var bundle model.SyncBundle
+		err := utils2.UnmarshalJson(request.Body, &bundle)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		err = f.importBundle(pCtx, bundle)
+		if err != nil {
+			tLog.ErrorfCtx(pCtx, "V (Federation): failed to import bundle: %v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// bundleSigner gets the certificate bundles are signed with and the function that signs with its key. A bundle
+// for a child site is signed by the CA of the certs provider, which issued the certificate of the child. A bundle
+// for the parent is signed with the client certificate this site enrolled with.
+func (f *FederationVendor) bundleSigner(site string) ([]byte, func(data []byte) ([]byte, error), error) {
+	if site != "" {
+		if f.SitesManager.CertSigner == nil {
+			return nil, nil, v1alpha2.NewCOAError(nil, "bundles for child sites need a certs provider that signs certificates", v1alpha2.BadConfig)
+		}
+		caPEM, err := f.SitesManager.CertSigner.GetCACert()
+		if err != nil {
+			return nil, nil, err
+		}
+		return caPEM, f.SitesManager.CertSigner.SignData, nil
+	}
+	parent := f.Context.SiteInfo.ParentSite
+	if parent.CertFile == "" || parent.KeyFile == "" {
+		return nil, nil, v1alpha2.NewCOAError(nil, "bundles for the parent need the client certificate of the site", v1alpha2.BadConfig)
+	}
+	certPEM, err := os.ReadFile(parent.CertFile)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to read the client certificate of the site", v1alpha2.BadConfig)
+	}
+	keyPEM, err := os.ReadFile(parent.KeyFile)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to read the client key of the site", v1alpha2.BadConfig)
+	}
+	key, err := certs.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, func(data []byte) ([]byte, error) {
+		return certs.SignData(key, data)
+	}, nil
+}
+
+// exportSite builds the bundle of a child site. Without a since parameter, the bundle has all the catalogs.
+func (f *FederationVendor) exportSite(ctx context.Context, site string, parameters map[string]string, exportRequest model.BundleExportRequest) (model.SyncPackage, error) {
+	if _, err := f.SitesManager.GetState(ctx, site); err != nil {
+		return model.SyncPackage{}, err
+	}
+	namespace, exist := parameters["namespace"]
+	if !exist {
+		namespace = "default"
+	}
+	watermark, err := parseSyncWatermark(parameters)
+	if err != nil {
+		return model.SyncPackage{}, err
+	}
+	if _, ok := parameters["since"]; !ok {
+		watermark.Resync = true
+	}
+	artifacts, err := f.readArtifacts(exportRequest.Artifacts)
+	if err != nil {
+		return model.SyncPackage{}, err
+	}
+	pack, err := f.getSyncPackage(ctx, site, namespace, watermark, 0)
+	if err != nil {
+		return pack, err
+	}
+	pack.Site = site
+	pack.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	pack.Artifacts = artifacts
+	if f.SolutionContainersManager != nil {
+		if pack.SolutionContainers, err = f.SolutionContainersManager.ListState(ctx, namespace); err != nil {
+			return pack, err
+		}
+	}
+	if f.SolutionsManager != nil {
+		if pack.Solutions, err = f.SolutionsManager.ListState(ctx, namespace); err != nil {
+			return pack, err
+		}
+	}
+	if f.CatalogContainersManager != nil {
+		if pack.CatalogContainers, err = f.CatalogContainersManager.ListState(ctx, namespace); err != nil {
+			return pack, err
+		}
+	}
+	if f.CampaignContainersManager != nil {
+		if pack.CampaignContainers, err = f.CampaignContainersManager.ListState(ctx, namespace); err != nil {
+			return pack, err
+		}
+	}
+	if f.CampaignsManager != nil {
+		if pack.Campaigns, err = f.CampaignsManager.ListState(ctx, namespace); err != nil {
+			return pack, err
+		}
+	}
+	fLog.InfofCtx(ctx, "V (Federation): exported a bundle for site %s with %d jobs and %d catalogs", site, len(pack.Jobs), len(pack.Catalogs))
+	return pack, nil
+}
+
+// exportReports builds the bundle of this site for its parent, with the reports it queued and its own state
+func (f *FederationVendor) exportReports(ctx context.Context) (model.SyncPackage, error) {
+	pack := model.SyncPackage{
+		Origin:    f.Context.SiteInfo.SiteId,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	self, err := f.SitesManager.GetState(ctx, f.Context.SiteInfo.SiteId)
+	if err != nil {
+		return pack, err
+	}
+	self.Id = f.Context.SiteInfo.SiteId
+	if status, err := f.SitesManager.RollUp(ctx); err == nil {
+		self.Status = &status
+	} else {
+		fLog.WarnfCtx(ctx, "V (Federation): failed to get the status to report to the parent: %v", err)
+	}
+	pack.Reports, err = f.StagingManager.DrainReports()
+	if err != nil {
+		return pack, err
+	}
+	pack.Reports = append(pack.Reports, model.ChannelMessage{
+		Type: model.ChannelHeartbeat,
+		Site: &self,
+	})
+	fLog.InfofCtx(ctx, "V (Federation): exported a bundle for the parent with %d reports", len(pack.Reports))
+	return pack, nil
+}
+
+// importBundle verifies a bundle and applies it. A bundle for this site has to be signed by the CA of the parent,
+// and a bundle from a child site with a certificate the certs provider issued to that site. Bundles that aren't
+// newer than the last one imported from the same origin are rejected, so their jobs and reports aren't handled
+// twice.
+func (f *FederationVendor) importBundle(ctx context.Context, bundle model.SyncBundle) error {
+	pack, cert, err := sync.OpenBundle(bundle)
+	if err != nil {
+		return err
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, pack.CreatedAt)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "bundle creation time is not valid", v1alpha2.BadRequest)
+	}
+	self := f.Context.SiteInfo.SiteId
+	switch {
+	case pack.Site == self:
+		if f.SyncManager == nil {
+			return v1alpha2.NewCOAError(nil, "sync manager is not supplied", v1alpha2.BadConfig)
+		}
+		caFile := f.Context.SiteInfo.ParentSite.CAFile
+		if caFile == "" {
+			return v1alpha2.NewCOAError(nil, "CA certificate of the parent site is not configured", v1alpha2.BadConfig)
+		}
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to read the CA certificate of the parent site", v1alpha2.BadConfig)
+		}
+		if err = sync.VerifyBundleCA(cert, caPEM); err != nil {
+			return err
+		}
+	case pack.Site == "":
+		// Only registered child sites can send their reports
+		if pack.Origin == self {
+			return v1alpha2.NewCOAError(nil, "bundle was exported by this site", v1alpha2.BadRequest)
+		}
+		if _, err := f.SitesManager.GetState(ctx, pack.Origin); err != nil {
+			if utils.IsNotFound(err) {
+				return v1alpha2.NewCOAError(err, fmt.Sprintf("bundle is from site %s, which is not registered", pack.Origin), v1alpha2.Forbidden)
+			}
+			return err
+		}
+		signer, err := f.SitesManager.VerifySiteCertificate(ctx, []*x509.Certificate{cert})
+		if err != nil {
+			return err
+		}
+		if signer != pack.Origin {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("bundle from site %s is signed by site %s", pack.Origin, signer), v1alpha2.Forbidden)
+		}
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("bundle is for site %s", pack.Site), v1alpha2.Forbidden)
+	}
+
+	f.bundleLock.Lock()
+	defer f.bundleLock.Unlock()
+	if last, ok := f.lastBundles[pack.Origin]; ok && !createdAt.After(last) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("a bundle from %s created at %s or later was already imported", pack.Origin, last.Format(time.RFC3339)), v1alpha2.BadRequest)
+	}
+	if pack.Site == "" {
+		err = f.importReports(ctx, pack)
+	} else {
+		err = f.importSite(ctx, pack)
+	}
+	if err == nil {
+		f.lastBundles[pack.Origin] = createdAt
+	}
+	return err
+}
+
+// importSite writes the artifacts and objects of a bundle from the parent before its jobs run. Objects keep the
+// names they have on the parent, as jobs refer to them by those names.
+func (f *FederationVendor) importSite(ctx context.Context, pack model.SyncPackage) error {
+	if err := f.writeArtifacts(pack.Artifacts); err != nil {
+		return err
+	}
+	if f.SolutionContainersManager != nil {
+		for _, container := range pack.SolutionContainers {
+			container.ObjectMeta.ETag = ""
+			if err := f.SolutionContainersManager.UpsertState(ctx, container.ObjectMeta.Name, container); err != nil {
+				return err
+			}
+		}
+	}
+	if f.SolutionsManager != nil {
+		for _, solution := range pack.Solutions {
+			solution.ObjectMeta.ETag = ""
+			if err := f.SolutionsManager.UpsertState(ctx, solution.ObjectMeta.Name, solution); err != nil {
+				return err
+			}
+		}
+	}
+	if f.CatalogContainersManager != nil {
+		for _, container := range pack.CatalogContainers {
+			container.ObjectMeta.ETag = ""
+			if err := f.CatalogContainersManager.UpsertState(ctx, container.ObjectMeta.Name, container); err != nil {
+				return err
+			}
+		}
+	}
+	if f.CampaignContainersManager != nil {
+		for _, container := range pack.CampaignContainers {
+			container.ObjectMeta.ETag = ""
+			if err := f.CampaignContainersManager.UpsertState(ctx, container.ObjectMeta.Name, container); err != nil {
+				return err
+			}
+		}
+	}
+	if f.CampaignsManager != nil {
+		for _, campaign := range pack.Campaigns {
+			campaign.ObjectMeta.ETag = ""
+			if err := f.CampaignsManager.UpsertState(ctx, campaign.ObjectMeta.Name, campaign); err != nil {
+				return err
+			}
+		}
+	}
+	fLog.InfofCtx(ctx, "V (Federation): importing a bundle from %s with %d jobs and %d catalogs", pack.Origin, len(pack.Jobs), len(pack.Catalogs))
+	return f.SyncManager.ImportPackage(ctx, pack)
+}
+
+// importReports handles the reports of a bundle from a child site
+func (f *FederationVendor) importReports(ctx context.Context, pack model.SyncPackage) error {
+	fLog.InfofCtx(ctx, "V (Federation): importing a bundle from site %s with %d reports", pack.Origin, len(pack.Reports))
+	for _, message := range pack.Reports {
+		if err := f.handleChannelMessage(ctx, pack.Origin, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readArtifacts computes the digests of the artifacts to export, reading the ones without data from the
+// artifact directory
+func (f *FederationVendor) readArtifacts(artifacts []model.BundleArtifact) ([]model.BundleArtifact, error) {
+	ret := make([]model.BundleArtifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		if err := validateArtifactName(artifact.Name); err != nil {
+			return nil, err
+		}
+		if len(artifact.Data) == 0 {
+			if f.bundleArtifactDir == "" {
+				return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact %s has no data, and bundle.artifactDir is not configured", artifact.Name), v1alpha2.BadRequest)
+			}
+			data, err := os.ReadFile(filepath.Join(f.bundleArtifactDir, artifact.Name))
+			if err != nil {
+				return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read artifact %s", artifact.Name), v1alpha2.NotFound)
+			}
+			artifact.Data = data
+		}
+		artifact.Digest = artifactDigest(artifact.Data)
+		ret = append(ret, artifact)
+	}
+	return ret, nil
+}
+
+// writeArtifacts verifies the digests of imported artifacts and writes them to the artifact directory
+func (f *FederationVendor) writeArtifacts(artifacts []model.BundleArtifact) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	if f.bundleArtifactDir == "" {
+		return v1alpha2.NewCOAError(nil, "bundle has artifacts, and bundle.artifactDir is not configured", v1alpha2.BadConfig)
+	}
+	for _, artifact := range artifacts {
+		if err := validateArtifactName(artifact.Name); err != nil {
+			return err
+		}
+		if artifactDigest(artifact.Data) != artifact.Digest {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("digest of artifact %s doesn't match", artifact.Name), v1alpha2.BadRequest)
+		}
+	}
+	if err := os.MkdirAll(f.bundleArtifactDir, 0755); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to create the artifact directory", v1alpha2.InternalError)
+	}
+	for _, artifact := range artifacts {
+		path := filepath.Join(f.bundleArtifactDir, artifact.Name)
+		if err := os.WriteFile(path+".tmp", artifact.Data, 0644); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to write artifact %s", artifact.Name), v1alpha2.InternalError)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to write artifact %s", artifact.Name), v1alpha2.InternalError)
+		}
+	}
+	return nil
+}
+
+// validateArtifactName keeps artifacts in the artifact directory
+func validateArtifactName(name string) error {
+	if name == "" || name == "." || name == ".." || name != filepath.Base(name) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("artifact name '%s' is not a valid file name", name), v1alpha2.BadRequest)
+	}
+	return nil
+}
+
+func artifactDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
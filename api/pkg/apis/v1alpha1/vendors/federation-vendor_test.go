@@ -1,18 +1,29 @@
 package vendors
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/sync"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	memorygraph "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph/memory"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
@@ -48,8 +59,11 @@ func federationVendorInit() FederationVendor {
 	stagingProviders["StateProvider"] = stateProvider
 	stagingProviders["QueueProvider"] = queueProvider
 
+	certProvider := &autogen.AutoGenCertProvider{}
+	certProvider.Init(autogen.AutoGenCertProviderConfig{Name: "CertProvider"})
 	siteProviders := make(map[string]providers.IProvider)
 	siteProviders["StateProvider"] = stateProvider
+	siteProviders["CertProvider"] = certProvider
 
 	mProvider := &mockledger.MockLedgerProvider{}
 	mProvider.Init(mockledger.MockLedgerProviderConfig{})
@@ -511,3 +525,606 @@ func TestFederationOnK8SHook(t *testing.T) {
 	response = vendor.onK8sHook(*requestPatch)
 	assert.Equal(t, v1alpha2.MethodNotAllowed, response.State)
 }
+
+func TestFederationOnSyncGetDelta(t *testing.T) {
+	vendor := federationVendorInit()
+	vendor.CatalogsManager.CatalogValidator = validation.NewCatalogValidator(vendor.CatalogsManager.CatalogLookup, nil, vendor.CatalogsManager.ChildCatalogLookup)
+	getPackage := func(watermark model.SyncWatermark) model.SyncPackage {
+		parameters := map[string]string{
+			"__site": "child2",
+			"count":  "10",
+			"since":  fmt.Sprintf("%d", watermark.Revision),
+			"epoch":  watermark.Epoch,
+		}
+		response := vendor.onSync(v1alpha2.COARequest{
+			Method:     fasthttp.MethodGet,
+			Context:    context.Background(),
+			Parameters: parameters,
+		})
+		assert.Equal(t, v1alpha2.OK, response.State)
+		var pack model.SyncPackage
+		err := json.Unmarshal(response.Body, &pack)
+		assert.Nil(t, err)
+		return pack
+	}
+
+	catalogState := model.CatalogState{
+		ObjectMeta: model.ObjectMeta{
+			Name: "catalog1-v-version1",
+		},
+		Spec: &model.CatalogSpec{
+			CatalogType:  "catalog",
+			RootResource: "catalog1",
+		},
+	}
+	err := vendor.CatalogsManager.UpsertState(context.Background(), catalogState.ObjectMeta.Name, catalogState)
+	assert.Nil(t, err)
+
+	// A new child gets all catalogs
+	pack := getPackage(model.SyncWatermark{})
+	assert.True(t, pack.FullSync)
+	assert.NotEmpty(t, pack.Epoch)
+	assert.Contains(t, syncedCatalogNames(pack), catalogState.ObjectMeta.Name)
+	watermark := model.SyncWatermark{Epoch: pack.Epoch, Revision: pack.Watermark}
+
+	err = vendor.CatalogsManager.DeleteState(context.Background(), catalogState.ObjectMeta.Name, "default")
+	assert.Nil(t, err)
+	for i := 0; i < 30; i++ {
+		pack = getPackage(watermark)
+		assert.False(t, pack.FullSync)
+		if len(pack.Deletions) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, 0, len(pack.Catalogs))
+	assert.Equal(t, 1, len(pack.Deletions))
+	assert.Equal(t, catalogState.ObjectMeta.Name, pack.Deletions[0].Name)
+	assert.Greater(t, pack.Watermark, watermark.Revision)
+
+	// A child with a watermark of another epoch gets all catalogs again
+	pack = getPackage(model.SyncWatermark{Epoch: "other", Revision: pack.Watermark})
+	assert.True(t, pack.FullSync)
+	assert.NotContains(t, syncedCatalogNames(pack), catalogState.ObjectMeta.Name)
+}
+
+func syncedCatalogNames(pack model.SyncPackage) []string {
+	names := make([]string, 0, len(pack.Catalogs))
+	for _, catalog := range pack.Catalogs {
+		names = append(names, catalog.ObjectMeta.Name)
+	}
+	return names
+}
+
+func TestFederationOnResync(t *testing.T) {
+	vendor := federationVendorInit()
+	response := vendor.onResync(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.True(t, vendor.SyncManager.Watermark().Resync)
+}
+
+func TestFederationOnChannelPost(t *testing.T) {
+	vendor := federationVendorInit()
+
+	reports := make(chan model.StageStatus, 1)
+	vendor.Context.Subscribe("job-report", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			jData, _ := json.Marshal(event.Body)
+			var status model.StageStatus
+			json.Unmarshal(jData, &status)
+			reports <- status
+			return nil
+		},
+	})
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.Encode(model.ChannelMessage{
+		Type:   model.ChannelStatus,
+		Status: &model.StageStatus{Stage: "stage1"},
+	})
+	encoder.Encode(model.ChannelMessage{
+		Type: model.ChannelHeartbeat,
+		Site: &model.SiteState{
+			Id:     "channel-site",
+			Spec:   &model.SiteSpec{Name: "channel-site"},
+			Status: &model.SiteStatus{IsOnline: true},
+		},
+	})
+	response := vendor.onChannel(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "channel-site"},
+		Body:       body.Bytes(),
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	select {
+	case status := <-reports:
+		assert.Equal(t, "stage1", status.Stage)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stage status isn't published")
+	}
+	site, err := vendor.SitesManager.GetState(context.Background(), "channel-site")
+	assert.Nil(t, err)
+	assert.Equal(t, "channel-site", site.Spec.Name)
+
+	// A site can't send heartbeats of another site
+	body.Reset()
+	encoder.Encode(model.ChannelMessage{
+		Type: model.ChannelHeartbeat,
+		Site: &model.SiteState{Id: "other-site", Spec: &model.SiteSpec{Name: "other-site"}},
+	})
+	response = vendor.onChannel(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "channel-site"},
+		Body:       body.Bytes(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+
+	response = vendor.onChannel(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "channel-site"},
+		Body:       []byte(`{"type":"unknown"}`),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+}
+
+func TestFederationOnChannelStream(t *testing.T) {
+	vendor := federationVendorInit()
+	vendor.channelPingInterval = 50 * time.Millisecond
+
+	response := vendor.onChannel(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "channel-site", "since": "0"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.NotNil(t, response.BodyStream)
+
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		response.BodyStream(bufio.NewWriter(writer))
+		writer.Close()
+		close(done)
+	}()
+	decoder := json.NewDecoder(reader)
+
+	// The first frame brings the site up to date
+	var message model.ChannelMessage
+	err := decoder.Decode(&message)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ChannelSync, message.Type)
+	assert.True(t, message.Package.FullSync)
+	assert.NotEmpty(t, message.Package.Epoch)
+
+	// Jobs are pushed as soon as they're queued
+	err = vendor.StagingManager.HandleJobEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{"site": "channel-site"},
+		Body: v1alpha2.JobData{
+			Id:     "job1",
+			Action: v1alpha2.JobRun,
+		},
+	})
+	assert.Nil(t, err)
+	assert.True(t, vendor.StagingManager.IsSiteConnected("channel-site"))
+	var jobs []v1alpha2.JobData
+	for i := 0; i < 100 && len(jobs) == 0; i++ {
+		message = model.ChannelMessage{}
+		err = decoder.Decode(&message)
+		assert.Nil(t, err)
+		if message.Type == model.ChannelSync {
+			jobs = message.Package.Jobs
+		}
+	}
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "job1", jobs[0].Id)
+
+	// The stream ends when the staging manager shuts down
+	go io.Copy(io.Discard, reader)
+	vendor.StagingManager.Shutdown(context.Background())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stream doesn't end on shutdown")
+	}
+	assert.False(t, vendor.StagingManager.IsSiteConnected("channel-site"))
+}
+
+func federationEndpoint(vendor *FederationVendor, route string) v1alpha2.COAHandler {
+	for _, endpoint := range vendor.GetEndpoints() {
+		if endpoint.Route == route {
+			return endpoint.Handler
+		}
+	}
+	return nil
+}
+
+func withPeerCertificate(t *testing.T, certPEM string) context.Context {
+	block, _ := pem.Decode([]byte(certPEM))
+	assert.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	return context.WithValue(context.Background(), v1alpha2.COAPeerCertificatesKey, []*x509.Certificate{cert})
+}
+
+func TestFederationEnrollment(t *testing.T) {
+	vendor := federationVendorInit()
+
+	b, _ := json.Marshal(model.SiteJoinTokenRequest{Site: "child1", TTL: "10m"})
+	response := federationEndpoint(&vendor, "federation/jointokens")(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    b,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var grant model.SiteJoinTokenGrant
+	assert.Nil(t, json.Unmarshal(response.Body, &grant))
+
+	csr, _, err := certs.GenerateCSR("child1")
+	assert.Nil(t, err)
+	b, _ = json.Marshal(model.SiteEnrollmentRequest{Site: "child1", Token: grant.Token, CSR: string(csr)})
+	response = federationEndpoint(&vendor, "federation/enroll")(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Body:    b,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var enrollment model.SiteEnrollment
+	assert.Nil(t, json.Unmarshal(response.Body, &enrollment))
+	ctx := withPeerCertificate(t, enrollment.Certificate)
+
+	// The site can report its own status with its certificate, but not the status of other sites
+	status := func(name string, id string) v1alpha2.COAResponse {
+		b, _ := json.Marshal(model.SiteState{Id: id, Spec: &model.SiteSpec{}, Status: &model.SiteStatus{IsOnline: true}})
+		return federationEndpoint(&vendor, "federation/status")(v1alpha2.COARequest{
+			Method:     fasthttp.MethodPost,
+			Context:    ctx,
+			Parameters: map[string]string{"__name": name},
+			Body:       b,
+		})
+	}
+	assert.Equal(t, v1alpha2.OK, status("child1", "child1").State)
+	assert.Equal(t, v1alpha2.Forbidden, status("child2", "child2").State)
+	assert.Equal(t, v1alpha2.Forbidden, status("child1", "child2").State)
+
+	// Administrative endpoints can't be called with a site certificate
+	response = federationEndpoint(&vendor, "federation/registry")(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__name": "child1"},
+		Body:       []byte("{}"),
+	})
+	assert.Equal(t, v1alpha2.Forbidden, response.State)
+
+	certificates := federationEndpoint(&vendor, "federation/certificates")
+	csr, _, err = certs.GenerateCSR("child1")
+	assert.Nil(t, err)
+	b, _ = json.Marshal(model.SiteEnrollmentRequest{CSR: string(csr)})
+	response = certificates(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "child1"},
+		Body:       b,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+	response = certificates(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__site": "child1"},
+		Body:       b,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var rotated model.SiteEnrollment
+	assert.Nil(t, json.Unmarshal(response.Body, &rotated))
+	assert.NotEqual(t, enrollment.Serial, rotated.Serial)
+
+	response = certificates(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "child1"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var active []model.SiteCertificate
+	assert.Nil(t, json.Unmarshal(response.Body, &active))
+	assert.Equal(t, 2, len(active))
+
+	response = certificates(v1alpha2.COARequest{
+		Method:     fasthttp.MethodDelete,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "child1"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, v1alpha2.Unauthorized, status("child1", "child1").State)
+}
+
+// enrollBundleSite enrolls a child site with the parent and writes its client certificate and the CA of the parent
+// to the files the child signs and verifies bundles with
+func enrollBundleSite(t *testing.T, parent *FederationVendor, site string) v1alpha2.SiteConnection {
+	ctx := context.Background()
+	grant, err := parent.SitesManager.CreateJoinToken(ctx, site, 0)
+	assert.Nil(t, err)
+	csr, key, err := certs.GenerateCSR(site)
+	assert.Nil(t, err)
+	enrollment, err := parent.SitesManager.Enroll(ctx, model.SiteEnrollmentRequest{Site: site, Token: grant.Token, CSR: string(csr)})
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	connection := v1alpha2.SiteConnection{
+		CertFile: filepath.Join(dir, "site.crt"),
+		KeyFile:  filepath.Join(dir, "site.key"),
+		CAFile:   filepath.Join(dir, "parent-ca.crt"),
+	}
+	assert.Nil(t, os.WriteFile(connection.CertFile, []byte(enrollment.Certificate), 0644))
+	assert.Nil(t, os.WriteFile(connection.KeyFile, key, 0600))
+	assert.Nil(t, os.WriteFile(connection.CAFile, []byte(enrollment.CACertificate), 0644))
+	return connection
+}
+
+func TestFederationBundleRoundTrip(t *testing.T) {
+	parent := federationVendorInit()
+	parent.bundleArtifactDir = t.TempDir()
+	child := federationVendorInit()
+	child.Context.SiteInfo.SiteId = "child1"
+	child.Context.SiteInfo.ParentSite = enrollBundleSite(t, &parent, "child1")
+	child.bundleArtifactDir = t.TempDir()
+	ctx := context.Background()
+	err := child.SitesManager.UpsertState(ctx, "child1", model.SiteState{Spec: &model.SiteSpec{Name: "child1", IsSelf: true}})
+	assert.Nil(t, err)
+	err = parent.CatalogsManager.UpsertState(ctx, "catalog1-v-v1", model.CatalogState{
+		ObjectMeta: model.ObjectMeta{Name: "catalog1-v-v1"},
+		Spec:       &model.CatalogSpec{CatalogType: "config", RootResource: "catalog1"},
+	})
+	assert.Nil(t, err)
+	err = parent.StagingManager.HandleJobEvent(ctx, v1alpha2.Event{
+		Metadata: map[string]string{"site": "child1"},
+		Body:     v1alpha2.JobData{Id: "instance1", Action: v1alpha2.JobRun},
+	})
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(parent.bundleArtifactDir, "chart.tgz"), []byte("chart"), 0644)
+	assert.Nil(t, err)
+
+	export := federationEndpoint(&parent, "federation/export")
+	b, _ := json.Marshal(model.BundleExportRequest{Artifacts: []model.BundleArtifact{
+		{Name: "chart.tgz"},
+		{Name: "values.yaml", Data: []byte("replicas: 1")},
+	}})
+	response := export(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__site": "child1"},
+		Body:       b,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var bundle model.SyncBundle
+	err = json.Unmarshal(response.Body, &bundle)
+	assert.Nil(t, err)
+	var pack model.SyncPackage
+	err = json.Unmarshal(bundle.Payload, &pack)
+	assert.Nil(t, err)
+	assert.Equal(t, "child1", pack.Site)
+	assert.True(t, pack.FullSync)
+	assert.Equal(t, 1, len(pack.Jobs))
+	assert.Contains(t, syncedCatalogNames(pack), "catalog1-v-v1")
+	assert.Equal(t, 2, len(pack.Artifacts))
+	assert.Equal(t, "chart", string(pack.Artifacts[0].Data))
+	assert.NotEmpty(t, pack.Artifacts[0].Digest)
+	// The test vendor keeps sites in the same state provider as catalogs, so they're listed as catalogs too
+	catalogs := make([]model.CatalogState, 0)
+	for _, catalog := range pack.Catalogs {
+		if catalog.ObjectMeta.Name == "catalog1-v-v1" {
+			catalogs = append(catalogs, catalog)
+		}
+	}
+	pack.Catalogs = catalogs
+	bundle, err = sync.SignBundle(pack, []byte(bundle.Certificate), parent.SitesManager.CertSigner.SignData)
+	assert.Nil(t, err)
+
+	// Sites that aren't registered get no bundle
+	response = export(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    ctx,
+		Parameters: map[string]string{"__site": "unknown"},
+	})
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+
+	importBundle := func(vendor *FederationVendor, bundle model.SyncBundle) v1alpha2.COAResponse {
+		b, _ := json.Marshal(bundle)
+		return federationEndpoint(vendor, "federation/import")(v1alpha2.COARequest{
+			Method:  fasthttp.MethodPost,
+			Context: ctx,
+			Body:    b,
+		})
+	}
+
+	// A tampered bundle is rejected
+	tampered := model.SyncBundle{
+		Payload:     bytes.Replace(bundle.Payload, []byte("instance1"), []byte("instance2"), 1),
+		Certificate: bundle.Certificate,
+		Signature:   bundle.Signature,
+	}
+	assert.Equal(t, v1alpha2.Unauthorized, importBundle(&child, tampered).State)
+	// A bundle signed by another CA is rejected
+	impostor := federationVendorInit()
+	impostorCA, err := impostor.SitesManager.CertSigner.GetCACert()
+	assert.Nil(t, err)
+	forged, err := sync.SignBundle(pack, impostorCA, impostor.SitesManager.CertSigner.SignData)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, importBundle(&child, forged).State)
+	// A bundle for another site is rejected
+	other := federationVendorInit()
+	other.Context.SiteInfo.SiteId = "child2"
+	assert.Equal(t, v1alpha2.Forbidden, importBundle(&other, bundle).State)
+
+	jobs := make(chan v1alpha2.JobData, 1)
+	child.Context.Subscribe("remote-job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			jobs <- event.Body.(v1alpha2.JobData)
+			return nil
+		},
+	})
+	response = importBundle(&child, bundle)
+	assert.Equal(t, v1alpha2.OK, response.State)
+	catalog, err := child.CatalogsManager.GetState(ctx, "exampleSiteId-catalog1-v-v1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "config", catalog.Spec.CatalogType)
+	select {
+	case job := <-jobs:
+		assert.Equal(t, "instance1", job.Id)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "job of the bundle isn't published")
+	}
+	data, err := os.ReadFile(filepath.Join(child.bundleArtifactDir, "chart.tgz"))
+	assert.Nil(t, err)
+	assert.Equal(t, "chart", string(data))
+
+	// A bundle can't be imported twice
+	assert.Equal(t, v1alpha2.BadRequest, importBundle(&child, bundle).State)
+
+	// The child exports its reports for the parent
+	err = child.StagingManager.QueueReport(model.ChannelMessage{
+		Type:   model.ChannelStatus,
+		Status: &model.StageStatus{Stage: "deploy"},
+	})
+	assert.Nil(t, err)
+	reports := make(chan model.StageStatus, 1)
+	parent.Context.Subscribe("job-report", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			jData, _ := json.Marshal(event.Body)
+			var status model.StageStatus
+			json.Unmarshal(jData, &status)
+			reports <- status
+			return nil
+		},
+	})
+	response = federationEndpoint(&child, "federation/export")(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: ctx,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var upward model.SyncBundle
+	err = json.Unmarshal(response.Body, &upward)
+	assert.Nil(t, err)
+	remaining, err := child.StagingManager.DrainReports()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(remaining))
+
+	// A child site can't send reports in the name of another site
+	enrollBundleSite(t, &parent, "child2")
+	var upwardPack model.SyncPackage
+	assert.Nil(t, json.Unmarshal(upward.Payload, &upwardPack))
+	upwardPack.Origin = "child2"
+	keyPEM, err := os.ReadFile(child.Context.SiteInfo.ParentSite.KeyFile)
+	assert.Nil(t, err)
+	childKey, err := certs.ParsePrivateKey(keyPEM)
+	assert.Nil(t, err)
+	impersonating, err := sync.SignBundle(upwardPack, []byte(upward.Certificate), func(data []byte) ([]byte, error) {
+		return certs.SignData(childKey, data)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Forbidden, importBundle(&parent, impersonating).State)
+
+	response = importBundle(&parent, upward)
+	assert.Equal(t, v1alpha2.OK, response.State)
+	select {
+	case status := <-reports:
+		assert.Equal(t, "deploy", status.Stage)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stage status of the bundle isn't published")
+	}
+	site, err := parent.SitesManager.GetState(ctx, "child1")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, site.Status.LastReported)
+}
+
+func TestFederationBundleWithoutSigner(t *testing.T) {
+	vendor := federationVendorInit()
+	vendor.SitesManager.CertSigner = nil
+	err := vendor.StagingManager.HandleJobEvent(context.Background(), v1alpha2.Event{
+		Metadata: map[string]string{"site": "child1"},
+		Body:     v1alpha2.JobData{Id: "instance1", Action: v1alpha2.JobRun},
+	})
+	assert.Nil(t, err)
+	response := federationEndpoint(&vendor, "federation/export")(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__site": "child1"},
+	})
+	assert.Equal(t, v1alpha2.BadConfig, response.State)
+	// The jobs of the site stay queued
+	jobs, err := vendor.StagingManager.GetABatchForSite("child1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+
+	// A site without a client certificate can't export its reports
+	response = federationEndpoint(&vendor, "federation/export")(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadConfig, response.State)
+}
+
+func TestFederationFleet(t *testing.T) {
+	vendor := federationVendorInit()
+	err := vendor.SitesManager.ReportState(context.Background(), model.SiteState{
+		Id:   "child1",
+		Spec: &model.SiteSpec{Properties: map[string]string{"region": "east"}},
+		Status: &model.SiteStatus{
+			IsOnline: true,
+			InstanceStatuses: map[string]model.SiteInstanceStatus{
+				"instance1": {State: v1alpha2.UpdateFailed, Reason: "image pull failed", Solution: "app:v1"},
+			},
+			Descendants: []model.SiteSummary{
+				{Name: "grandchild1", Parent: "child1", IsOnline: true},
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	fleet := federationEndpoint(&vendor, "federation/fleet")
+	response := fleet(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var sites []model.SiteSummary
+	assert.Nil(t, json.Unmarshal(response.Body, &sites))
+	names := make([]string, 0, len(sites))
+	for _, site := range sites {
+		names = append(names, site.Name)
+	}
+	assert.Equal(t, []string{"exampleSiteId", "child1", "grandchild1"}, names)
+
+	response = fleet(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__view": "instances", "state": "failed", "property": "region=east"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var instances []model.FleetInstance
+	assert.Nil(t, json.Unmarshal(response.Body, &instances))
+	assert.Equal(t, []model.FleetInstance{{
+		Site:     "child1",
+		Name:     "instance1",
+		Solution: "app:v1",
+		State:    v1alpha2.UpdateFailed,
+		Reason:   "image pull failed",
+	}}, instances)
+
+	response = fleet(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"staleFor": "soon"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+	response = fleet(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__view": "devices"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+}
@@ -96,6 +96,15 @@ func (e *JobVendor) Init(config vendors.VendorConfig, factories []managers.IMana
 			return e.JobsManager.HandleScheduleEvent(ctx, event)
 		},
 	})
+	e.Vendor.Context.Subscribe("secret", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			return e.JobsManager.HandleSecretEvent(ctx, event)
+		},
+	})
 
 	if err != nil {
 		return err
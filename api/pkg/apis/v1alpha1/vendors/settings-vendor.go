@@ -7,9 +7,15 @@
 package vendors
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/secrets"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
@@ -71,6 +77,26 @@ func (o *SettingsVendor) GetEndpoints() []v1alpha2.Endpoint {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   route + "/environments",
+			Version: o.Version,
+			Handler: o.onEnvironments,
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost},
+			Route:      route + "/promotions",
+			Version:    o.Version,
+			Handler:    o.onPromotions,
+			Parameters: []string{"id?"},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route + "/secrets",
+			Version:    o.Version,
+			Handler:    o.onSecrets,
+			Parameters: []string{"name?"},
+		},
 		{
 			Methods:    []string{fasthttp.MethodGet},
 			Route:      route + "/config",
@@ -101,6 +127,30 @@ func (c *SettingsVendor) onConfig(request v1alpha2.COARequest) v1alpha2.COARespo
 		if overrides != "" {
 			parts = strings.Split(overrides, ",")
 		}
+		if environment := request.Parameters["environment"]; environment != "" {
+			val, err := c.getEnvironmentConfig(ctx, id, field, environment, EvaluationContext)
+			if err != nil {
+				log.ErrorfCtx(ctx, "V (Settings): onConfig failed to get config %s in environment %s, error: %v", id, environment, err)
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.GetErrorState(err),
+					Body:  []byte(err.Error()),
+				})
+			}
+			if field != "" {
+				data, _ := json.Marshal(val)
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State:       v1alpha2.OK,
+					Body:        data,
+					ContentType: "text/plain",
+				})
+			}
+			jData, _ := api_utils.FormatObject(val, false, "", "")
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State:       v1alpha2.OK,
+				Body:        jData,
+				ContentType: "application/json",
+			})
+		}
 		if field != "" {
 			val, err := c.EvaluationContext.ConfigProvider.Get(ctx, id, field, parts, EvaluationContext)
 			if err != nil {
@@ -151,3 +201,281 @@ func (c *SettingsVendor) onConfig(request v1alpha2.COARequest) v1alpha2.COARespo
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// getEnvironmentConfig gets the effective config of an object in an environment, or a field of it
+func (c *SettingsVendor) getEnvironmentConfig(ctx context.Context, id string, field string, environment string, evaluationContext utils.EvaluationContext) (interface{}, error) {
+	manager, err := c.configsManager()
+	if err != nil {
+		return nil, err
+	}
+	val, err := manager.GetEnvironmentObject(ctx, id, environment, evaluationContext)
+	if err != nil {
+		return nil, err
+	}
+	if field == "" {
+		return val, nil
+	}
+	v, ok := val[field]
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("field '%s' is not found in configuration '%s' in environment '%s'", field, id, environment), v1alpha2.NotFound)
+	}
+	return v, nil
+}
+
+func (c *SettingsVendor) onEnvironments(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Settings Vendor", request.Context, &map[string]string{
+		"method": "onEnvironments",
+	})
+	defer span.End()
+	csLog.InfofCtx(ctx, "V (Settings): onEnvironments method: %s", request.Method)
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		manager, err := c.configsManager()
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		environments := manager.Environments()
+		if environments == nil {
+			environments = []string{}
+		}
+		jData, _ := json.Marshal(environments)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *SettingsVendor) onPromotions(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Settings Vendor", request.Context, &map[string]string{
+		"method": "onPromotions",
+	})
+	defer span.End()
+	csLog.InfofCtx(ctx, "V (Settings): onPromotions method: %s", request.Method)
+
+	manager, err := c.configsManager()
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	id := request.Parameters["__id"]
+	evaluationContext := utils.EvaluationContext{
+		Namespace: request.Parameters["namespace"],
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var state interface{}
+		isArray := false
+		if id == "" {
+			state, err = manager.ListPromotions(ctx, request.Parameters["object"])
+			isArray = true
+		} else {
+			state, err = manager.GetPromotion(ctx, id)
+		}
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := api_utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		var promotion model.ConfigPromotion
+		if id != "" {
+			switch request.Parameters["action"] {
+			case "approve":
+				promotion, err = manager.ReviewPromotion(ctx, id, true, request.Parameters["comment"])
+			case "reject":
+				promotion, err = manager.ReviewPromotion(ctx, id, false, request.Parameters["comment"])
+			default:
+				err = v1alpha2.NewCOAError(nil, "action needs to be approve or reject", v1alpha2.BadRequest)
+			}
+		} else {
+			var promotionRequest model.ConfigPromotionRequest
+			err = utils.UnmarshalJson(request.Body, &promotionRequest)
+			if err != nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+			if request.Parameters["preview"] == "true" {
+				promotion, err = manager.PreviewPromotion(ctx, promotionRequest, evaluationContext)
+			} else {
+				promotion, err = manager.Promote(ctx, promotionRequest, evaluationContext)
+			}
+		}
+		if err != nil && promotion.Id == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		// a promotion that failed to be written is returned with its record
+		state := v1alpha2.OK
+		if err != nil {
+			state = v1alpha2.GetErrorState(err)
+		}
+		jData, _ := json.Marshal(promotion)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       state,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// onSecrets writes, deletes and lists secrets. Secret values are never returned.
+func (c *SettingsVendor) onSecrets(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Settings Vendor", request.Context, &map[string]string{
+		"method": "onSecrets",
+	})
+	defer span.End()
+	csLog.InfofCtx(ctx, "V (Settings): onSecrets method: %s", request.Method)
+
+	// secrets are only changed on behalf of an authenticated user, whatever the middleware lets through
+	if user, _ := ctx.Value(v1alpha2.COAUserKey).(string); user == "" {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Unauthorized,
+			Body:  []byte("secrets need an authenticated user"),
+		})
+	}
+	manager, err := c.secretsManager()
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	name := request.Parameters["__name"]
+	namespace, exist := request.Parameters["namespace"]
+	if !exist {
+		namespace = "default"
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		if name != "" {
+			break
+		}
+		var list []secret.SecretInfo
+		list, err = manager.List(ctx, request.Parameters["provider"], namespace)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(list)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		if name == "" {
+			break
+		}
+		var fields map[string]string
+		err = utils.UnmarshalJson(request.Body, &fields)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		var gracePeriod time.Duration
+		if v := request.Parameters["gracePeriod"]; v != "" {
+			gracePeriod, err = time.ParseDuration(v)
+			if err != nil || gracePeriod < 0 {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(fmt.Sprintf("invalid grace period '%s'", v)),
+				})
+			}
+		}
+		var version secret.SecretVersion
+		version, err = manager.Write(ctx, name, fields, gracePeriod, namespace)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := json.Marshal(version)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		if name == "" {
+			break
+		}
+		err = manager.Delete(ctx, name, namespace)
+		if err != nil {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *SettingsVendor) secretsManager() (*secrets.SecretsManager, error) {
+	if c.EvaluationContext != nil {
+		if manager, ok := c.EvaluationContext.SecretProvider.(*secrets.SecretsManager); ok {
+			return manager, nil
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, "secrets manager is not found", v1alpha2.BadConfig)
+}
+
+func (c *SettingsVendor) configsManager() (*configs.ConfigsManager, error) {
+	if c.EvaluationContext != nil {
+		if manager, ok := c.EvaluationContext.ConfigProvider.(*configs.ConfigsManager); ok {
+			return manager, nil
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, "configs manager is not found", v1alpha2.BadConfig)
+}
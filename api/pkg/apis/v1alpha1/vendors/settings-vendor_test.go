@@ -8,15 +8,21 @@ package vendors
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/secrets"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
 	memory "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/memoryconfig"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/encryptedsecret"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -150,3 +156,240 @@ func TestConfigGetField(t *testing.T) {
 	res = vendor.onConfig(*request)
 	assert.Equal(t, v1alpha2.NotFound, res.State)
 }
+
+func createPromotionSettingsVendor(t *testing.T) (SettingsVendor, *memory.MemoryConfigProvider) {
+	provider := &memory.MemoryConfigProvider{}
+	provider.Init(memory.MemoryConfigProviderConfig{})
+	manager := &configs.ConfigsManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"environments":          "dev,prod",
+			"environments.approval": "prod",
+		},
+	}, map[string]providers.IProvider{
+		"memory": provider,
+	})
+	assert.Nil(t, err)
+	provider.SetObject(ctx, "app", map[string]interface{}{"image": "app:v1"})
+	provider.SetObject(ctx, "app-dev", map[string]interface{}{"image": "app:v2"})
+	provider.SetObject(ctx, "app-prod", map[string]interface{}{"replicas": "3"})
+	vendor := SettingsVendor{
+		EvaluationContext: &coa_utils.EvaluationContext{
+			ConfigProvider: manager,
+		},
+	}
+	return vendor, provider
+}
+
+func TestConfigGetEnvironment(t *testing.T) {
+	vendor, _ := createPromotionSettingsVendor(t)
+	request := &v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: context.Background(),
+		Parameters: map[string]string{
+			"__name":      "app",
+			"environment": "dev",
+		},
+	}
+	res := vendor.onConfig(*request)
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var config map[string]interface{}
+	err := json.Unmarshal(res.Body, &config)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"image": "app:v2", "replicas": "3"}, config)
+
+	request.Parameters["environment"] = "prod"
+	request.Parameters["field"] = "image"
+	res = vendor.onConfig(*request)
+	assert.Equal(t, v1alpha2.OK, res.State)
+	assert.Equal(t, "\"app:v1\"", string(res.Body))
+
+	request.Parameters["field"] = "tag"
+	res = vendor.onConfig(*request)
+	assert.Equal(t, v1alpha2.NotFound, res.State)
+
+	res = vendor.onEnvironments(v1alpha2.COARequest{Method: fasthttp.MethodGet, Context: context.Background()})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	assert.Equal(t, "[\"dev\",\"prod\"]", string(res.Body))
+}
+
+func TestPromotions(t *testing.T) {
+	vendor, provider := createPromotionSettingsVendor(t)
+	requesterCtx := context.WithValue(context.Background(), v1alpha2.COAUserKey, "developer")
+	body, _ := json.Marshal(model.ConfigPromotionRequest{Object: "app", Source: "dev", Target: "prod"})
+
+	res := vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    requesterCtx,
+		Body:       body,
+		Parameters: map[string]string{"preview": "true"},
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var promotion model.ConfigPromotion
+	err := json.Unmarshal(res.Body, &promotion)
+	assert.Nil(t, err)
+	assert.Equal(t, "", promotion.Id)
+	assert.Equal(t, []model.PropertyChange{{Path: "/image", Op: "replace", Old: "app:v1", New: "app:v2"}}, promotion.Changes)
+
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: requesterCtx,
+		Body:    body,
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	err = json.Unmarshal(res.Body, &promotion)
+	assert.Nil(t, err)
+	assert.Equal(t, model.PromotionPending, promotion.State)
+
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    requesterCtx,
+		Parameters: map[string]string{"__id": promotion.Id, "action": "approve"},
+	})
+	assert.Equal(t, v1alpha2.Forbidden, res.State)
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.WithValue(context.Background(), v1alpha2.COAUserKey, "admin"),
+		Parameters: map[string]string{"__id": promotion.Id, "action": "merge"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, res.State)
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Context:    context.WithValue(context.Background(), v1alpha2.COAUserKey, "admin"),
+		Parameters: map[string]string{"__id": promotion.Id, "action": "approve"},
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	assert.Equal(t, "app:v2", provider.ConfigData["app-prod"]["image"])
+
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"object": "app"},
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var promotions []model.ConfigPromotion
+	err = json.Unmarshal(res.Body, &promotions)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(promotions))
+	assert.Equal(t, model.PromotionPromoted, promotions[0].State)
+	assert.Equal(t, "developer", promotions[0].RequestedBy)
+	assert.Equal(t, "admin", promotions[0].ReviewedBy)
+
+	res = vendor.onPromotions(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__id": "missing"},
+	})
+	assert.Equal(t, v1alpha2.NotFound, res.State)
+	res = vendor.onPromotions(v1alpha2.COARequest{Method: fasthttp.MethodDelete, Context: context.Background()})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, res.State)
+}
+
+func createSecretsSettingsVendor(t *testing.T) SettingsVendor {
+	provider := encryptedsecret.EncryptedSecretProvider{}
+	err := provider.Init(encryptedsecret.EncryptedSecretProviderConfig{
+		Key: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")),
+	})
+	assert.Nil(t, err)
+	vendor := createSettingsVendor()
+	vendor.EvaluationContext.SecretProvider = &secrets.SecretsManager{
+		SecretProviders: map[string]secret.ISecretProvider{
+			"encrypted": &provider,
+		},
+	}
+	return vendor
+}
+
+func TestSecrets(t *testing.T) {
+	vendor := createSecretsSettingsVendor(t)
+	userCtx := context.WithValue(ctx, v1alpha2.COAUserKey, "admin")
+
+	resp := vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       []byte(`{"password": "p@ss"}`),
+		Parameters: map[string]string{"__name": "db"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var version secret.SecretVersion
+	assert.Nil(t, json.Unmarshal(resp.Body, &version))
+	assert.Equal(t, int64(1), version.Version)
+
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       []byte(`{"password": "n3w"}`),
+		Parameters: map[string]string{"__name": "db", "gracePeriod": "1h"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	val, err := vendor.EvaluationContext.SecretProvider.Get(ctx, "db:previous", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", val)
+
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: userCtx,
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.NotContains(t, string(resp.Body), "n3w")
+	var list []secret.SecretInfo
+	assert.Nil(t, json.Unmarshal(resp.Body, &list))
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, "db", list[0].Name)
+	assert.Equal(t, "encrypted", list[0].Provider)
+	assert.Equal(t, 2, len(list[0].Versions))
+
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodDelete,
+		Parameters: map[string]string{"__name": "db"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodDelete,
+		Parameters: map[string]string{"__name": "db"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+}
+
+func TestSecretsBadRequests(t *testing.T) {
+	vendor := createSecretsSettingsVendor(t)
+	userCtx := context.WithValue(ctx, v1alpha2.COAUserKey, "admin")
+
+	resp := vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       []byte(`{"password": "p@ss"}`),
+		Parameters: map[string]string{"__name": "db"},
+		Context:    ctx,
+	})
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       []byte(`{"password": "p@ss"}`),
+		Parameters: map[string]string{"__name": "db", "gracePeriod": "a day"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       []byte(`["p@ss"]`),
+		Parameters: map[string]string{"__name": "db"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	// secret values can't be read through the API
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{"__name": "db"},
+		Context:    userCtx,
+	})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
+
+	vendor = createSettingsVendor()
+	resp = vendor.onSecrets(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: userCtx,
+	})
+	assert.Equal(t, v1alpha2.BadConfig, resp.State)
+}
@@ -69,7 +69,7 @@ func createSolutionsVendor() SolutionsVendor {
 			"mem-state": &stateProvider,
 		},
 	}, nil)
-	vendor.SolutionsManager.SolutionValidator = validation.NewSolutionValidator(nil, nil, nil)
+	vendor.SolutionsManager.SolutionValidator = validation.NewSolutionValidator(nil, nil, nil, nil)
 	return vendor
 }
 func TestSolutionsOnSolutions(t *testing.T) {
@@ -22,11 +22,14 @@ import (
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/valyala/fasthttp"
 )
 
 var sLog = logger.NewLogger("coa.runtime")
@@ -47,7 +50,95 @@ func (s *StageVendor) GetInfo() vendors.VendorInfo {
 }
 
 func (o *StageVendor) GetEndpoints() []v1alpha2.Endpoint {
-	return []v1alpha2.Endpoint{}
+	route := "stage"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodPost},
+			Route:      route + "/simulate",
+			Version:    o.Version,
+			Handler:    o.onSimulate,
+			Parameters: []string{"name?"},
+		},
+	}
+}
+
+// onSimulate runs a dry run of the named campaign, or of the campaign in the simulation spec, with scripted stage results
+func (s *StageVendor) onSimulate(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Stage Vendor", request.Context, &map[string]string{
+		"method": "onSimulate",
+	})
+	defer span.End()
+	sLog.InfofCtx(pCtx, "V (Stage): onSimulate, method: %s", string(request.Method))
+
+	namespace, namespaceSupplied := request.Parameters["namespace"]
+	if !namespaceSupplied {
+		namespace = "default"
+	}
+
+	switch request.Method {
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onSimulate-POST", pCtx, nil)
+		var spec model.CampaignSimulationSpec
+		if len(request.Body) > 0 {
+			err := utils2.UnmarshalJson(request.Body, &spec)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): onSimulate failed - %s", err.Error())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.BadRequest,
+					Body:  []byte(err.Error()),
+				})
+			}
+		}
+		name := request.Parameters["__name"]
+		var campaign model.CampaignSpec
+		if name != "" {
+			state, err := s.CampaignsManager.GetState(ctx, name, namespace)
+			if err != nil || state.Spec == nil {
+				if err == nil {
+					err = v1alpha2.NewCOAError(nil, fmt.Sprintf("campaign %s has no spec", name), v1alpha2.BadRequest)
+				}
+				sLog.ErrorfCtx(ctx, "V (Stage): onSimulate failed - %s", err.Error())
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.GetErrorState(err),
+					Body:  []byte(err.Error()),
+				})
+			}
+			campaign = *state.Spec
+		} else if spec.Campaign != nil {
+			campaign = *spec.Campaign
+		} else {
+			sLog.ErrorCtx(ctx, "V (Stage): onSimulate failed - campaign is not supplied")
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("a campaign name or a campaign in the request body is required"),
+			})
+		}
+		result, err := s.StageManager.SimulateCampaign(ctx, name, namespace, campaign, spec)
+		if err != nil {
+			sLog.ErrorfCtx(ctx, "V (Stage): onSimulate failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(result, false, request.Parameters["path"], request.Parameters["doc-type"])
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+	}
+	sLog.InfoCtx(pCtx, "V (Stage): onSimulate failed - 405 method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
 }
 
 func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
@@ -157,11 +248,18 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				triggerData.Activation, triggerData.Stage, triggerData.Namespace)
 
 			status.Outputs["__namespace"] = triggerData.Namespace
-			_, err = s.ActivationsManager.GetState(ctx, triggerData.Activation, triggerData.Namespace)
+			activationState, err := s.ActivationsManager.GetState(ctx, triggerData.Activation, triggerData.Namespace)
 			if err != nil {
 				sLog.ErrorfCtx(ctx, "V (Stage): unable to find activation: %+v", err)
 				return nil
 			}
+			// The activation may have been paused or cancelled through its spec while this process wasn't listening
+			if activationState.Spec.State == model.ActivationStatePaused || activationState.Spec.State == model.ActivationStateCancelled {
+				_, err = s.StageManager.ControlActivation(ctx, triggerData.Activation, triggerData.Namespace, activationState.Spec.State)
+				if err != nil {
+					sLog.ErrorfCtx(ctx, "V (Stage): failed to apply activation state %s: %v", activationState.Spec.State, err)
+				}
+			}
 			campaignName := api_utils.ConvertReferenceToObjectName(triggerData.Campaign)
 			campaign, err := s.CampaignsManager.GetState(ctx, campaignName, triggerData.Namespace)
 			if err != nil {
@@ -172,26 +270,33 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				return err
 			}
 			status.Stage = triggerData.Stage
+			status.Branch = triggerData.Branch
 			status.ErrorMessage = ""
 			status.Status = v1alpha2.Running
 			status.StatusMessage = v1alpha2.Running.String()
-			if triggerData.NeedsReport {
-				sLog.DebugfCtx(ctx, "V (Stage): activation %s, stage %s in namespace %s reporting status: %v", triggerData.Activation, triggerData.Stage, triggerData.Namespace, status)
-				s.Vendor.Context.Publish("report", v1alpha2.Event{
-					Body:    status,
-					Context: ctx,
-				})
-			} else {
-				err = s.ActivationsManager.ReportStageStatus(ctx, triggerData.Activation, triggerData.Namespace, status)
-				if err != nil {
-					sLog.Errorf("V (Stage): failed to report accepted status: %v (%v)", status.ErrorMessage, err)
-					return err
+			// A join stage reports once it knows whether its predecessors have all arrived
+			if stage, ok := campaign.Spec.Stages[triggerData.Stage]; !ok || stage.Join == nil {
+				if triggerData.NeedsReport {
+					sLog.DebugfCtx(ctx, "V (Stage): activation %s, stage %s in namespace %s reporting status: %v", triggerData.Activation, triggerData.Stage, triggerData.Namespace, status)
+					s.Vendor.Context.Publish("report", v1alpha2.Event{
+						Body:    status,
+						Context: ctx,
+					})
+				} else {
+					err = s.ActivationsManager.ReportStageStatus(ctx, triggerData.Activation, triggerData.Namespace, status)
+					if err != nil {
+						sLog.Errorf("V (Stage): failed to report accepted status: %v (%v)", status.ErrorMessage, err)
+						return err
+					}
 				}
 			}
 
-			status, activation := s.StageManager.HandleTriggerEvent(ctx, *campaign.Spec, triggerData)
+			status, activations := s.StageManager.HandleTriggerEventWithBranches(ctx, *campaign.Spec, triggerData)
 
-			if triggerData.NeedsReport {
+			if status.Status == v1alpha2.Untouched {
+				// A predecessor arrived after its join stage was triggered, there is nothing to report
+				log.InfofCtx(ctx, "V (Stage): stage %s is already triggered", triggerData.Stage)
+			} else if triggerData.NeedsReport {
 				sLog.DebugfCtx(ctx, "V (Stage): reporting status: %v", status)
 				s.Vendor.Context.Publish("report", v1alpha2.Event{
 					Body:    status,
@@ -204,17 +309,87 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 					sLog.ErrorfCtx(ctx, "V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
 					return err
 				}
-				if activation != nil && status.NextStage != "" && status.Status != v1alpha2.Paused {
-					s.Vendor.Context.Publish("trigger", v1alpha2.Event{
-						Body:    *activation,
-						Context: ctx,
-					})
+				if status.Status != v1alpha2.Paused {
+					for _, activation := range activations {
+						s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+							Body:    activation,
+							Context: ctx,
+						})
+					}
 				}
 			}
 			log.InfoCtx(ctx, "V (Stage): Finished handling trigger event")
 			return nil
 		},
 	})
+	s.Vendor.Context.Subscribe("activation-control", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var controlData v1alpha2.ActivationControlData
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &controlData)
+			if err != nil {
+				sLog.ErrorCtx(ctx, "V (Stage): event body of activation-control event is not ActivationControlData")
+				return v1alpha2.NewCOAError(nil, "event body is not an activation control request", v1alpha2.BadRequest)
+			}
+			sLog.InfofCtx(ctx, "V (Stage): handling activation-control event for activation %s in namespace %s: %s", controlData.Activation, controlData.Namespace, controlData.State)
+			activation, err := s.StageManager.ControlActivation(ctx, controlData.Activation, controlData.Namespace, controlData.State)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to apply activation state: %v", err)
+				return err
+			}
+			if activation != nil {
+				sLog.InfofCtx(ctx, "V (Stage): resuming stage %s of activation %s", activation.Stage, activation.Activation)
+				s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+					Body:    *activation,
+					Context: ctx,
+				})
+			}
+			return nil
+		},
+	})
+	s.Vendor.Context.Subscribe(activations.ActivationFinishedTopic, v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var activation model.ActivationState
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &activation)
+			if err != nil || activation.Spec == nil {
+				sLog.ErrorCtx(ctx, "V (Stage): event body of activation-finished event is not ActivationState")
+				return v1alpha2.NewCOAError(nil, "event body is not an activation", v1alpha2.BadRequest)
+			}
+			campaignName := api_utils.ConvertReferenceToObjectName(activation.Spec.Campaign)
+			campaign, err := s.CampaignsManager.GetState(ctx, campaignName, activation.ObjectMeta.Namespace)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to get campaign spec '%s': %v", campaignName, err)
+				return nil
+			}
+			// Hook stages run before notifications are sent, so that notifications get their outputs
+			hookStatuses, err := s.StageManager.RunHooks(ctx, *campaign.Spec, activation)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to run hook stages for activation %s: %v", activation.ObjectMeta.Name, err)
+			}
+			for _, status := range hookStatuses {
+				err = s.ActivationsManager.ReportStageStatus(ctx, activation.ObjectMeta.Name, activation.ObjectMeta.Namespace, status)
+				if err != nil {
+					sLog.ErrorfCtx(ctx, "V (Stage): failed to report status of hook stage %s: %v", status.Stage, err)
+				}
+				activation.Status.StageHistory = append(activation.Status.StageHistory, status)
+			}
+			// Notifications are sent at most once, a failed notification is retried by its provider
+			err = s.StageManager.SendNotifications(ctx, *campaign.Spec, activation)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to send notifications for activation %s: %v", activation.ObjectMeta.Name, err)
+			}
+			return nil
+		},
+	})
 	s.Vendor.Context.Subscribe("job-report", v1alpha2.EventHandler{
 		Handler: func(topic string, event v1alpha2.Event) error {
 			ctx := context.TODO()
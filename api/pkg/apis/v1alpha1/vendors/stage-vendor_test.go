@@ -7,22 +7,87 @@
 package vendors
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/eclipse-symphony/symphony/api/constants"
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func TestStageEndpoints(t *testing.T) {
 	vendor := createStageVendor()
 	vendor.Route = "stage"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 0, len(endpoints))
+	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, "stage/simulate", endpoints[0].Route)
+}
+
+func TestStageSimulate(t *testing.T) {
+	t.Setenv(constants.SymphonyAPIUrlEnvName, "http://localhost:8082/v1alpha2/")
+	t.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	vendor := createStageVendor()
+	vendor.Context.EvaluationContext = &coa_utils.EvaluationContext{}
+	spec := model.CampaignSimulationSpec{
+		Campaign: &model.CampaignSpec{
+			SelfDriving: true,
+			FirstStage:  "approval",
+			Stages: map[string]model.StageSpec{
+				"approval": {
+					Name:          "approval",
+					Provider:      "providers.stage.http",
+					StageSelector: "${{$if($equal($output(approval,status), 200), deploy, '')}}",
+				},
+				"deploy": {
+					Name:     "deploy",
+					Provider: "providers.stage.create",
+				},
+			},
+		},
+		Stages: map[string][]model.SimulatedStageResult{
+			"approval": {
+				{Outputs: map[string]interface{}{"status": 200}},
+			},
+		},
+	}
+	data, _ := json.Marshal(spec)
+	resp := vendor.onSimulate(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var result model.CampaignSimulationResult
+	err := json.Unmarshal(resp.Body, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, result.Status)
+	assert.Equal(t, 2, len(result.StageHistory))
+	assert.Equal(t, "deploy", result.StageHistory[1].Stage)
+
+	// a campaign is required
+	resp = vendor.onSimulate(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	// stored campaigns must exist
+	resp = vendor.onSimulate(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "missing"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
 }
 
 func TestStageInfo(t *testing.T) {
@@ -48,6 +113,7 @@ func createStageVendor() StageVendor {
 				Type: "managers.symphony.stage",
 				Properties: map[string]string{
 					"providers.persistentstate": "mem-state",
+					"providers.volatilestate":   "mem-state",
 				},
 				Providers: map[string]managers.ProviderConfig{
 					"mem-state": {
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleFile          string
+	bundleSite          string
+	bundleNamespace     string
+	bundleSince         string
+	bundleEpoch         string
+	bundleArtifacts     []string
+	bundleArtifactRefs  []string
+	bundleConfigFile    string
+	bundleConfigContext string
+)
+
+var BundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Carry Symphony state to and from sites without a network path to their parent",
+}
+
+var BundleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a signed bundle for a child site, or for the parent of the site when no site is given",
+	Run: func(cmd *cobra.Command, args []string) {
+		if bundleFile == "" {
+			fmt.Printf("\n%s  Please specify the bundle file with --file%s\n\n", utils.ColorRed(), utils.ColorReset())
+			return
+		}
+		artifacts := make([]model.BundleArtifact, 0, len(bundleArtifacts))
+		for _, path := range bundleArtifacts {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("\n%s  Failed to read artifact: %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+				return
+			}
+			artifacts = append(artifacts, model.BundleArtifact{Name: filepath.Base(path), Data: data})
+		}
+		for _, name := range bundleArtifactRefs {
+			artifacts = append(artifacts, model.BundleArtifact{Name: name})
+		}
+		parameters := map[string]string{}
+		if bundleNamespace != "" {
+			parameters["namespace"] = bundleNamespace
+		}
+		if bundleSince != "" {
+			parameters["since"] = bundleSince
+			parameters["epoch"] = bundleEpoch
+		}
+		c := config.GetMaestroConfig(bundleConfigFile)
+		ctx := bundleContext(c.DefaultContext)
+		bundle, err := utils.ExportBundle(
+			c.Contexts[ctx].Url,
+			c.Contexts[ctx].User,
+			c.Contexts[ctx].Secret,
+			bundleSite,
+			parameters,
+			artifacts)
+		if err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		if err = os.WriteFile(bundleFile, bundle, 0600); err != nil {
+			fmt.Printf("\n%s  Failed to write bundle: %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		fmt.Printf("\n%s  Bundle is written to %s%s\n\n", utils.ColorGreen(), bundleFile, utils.ColorReset())
+	},
+}
+
+var BundleImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a signed bundle exported by the parent or by a child site",
+	Run: func(cmd *cobra.Command, args []string) {
+		if bundleFile == "" {
+			fmt.Printf("\n%s  Please specify the bundle file with --file%s\n\n", utils.ColorRed(), utils.ColorReset())
+			return
+		}
+		bundle, err := os.ReadFile(bundleFile)
+		if err != nil {
+			fmt.Printf("\n%s  Failed to read bundle: %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		c := config.GetMaestroConfig(bundleConfigFile)
+		ctx := bundleContext(c.DefaultContext)
+		err = utils.ImportBundle(
+			c.Contexts[ctx].Url,
+			c.Contexts[ctx].User,
+			c.Contexts[ctx].Secret,
+			bundle)
+		if err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		fmt.Printf("\n%s  Bundle %s is imported%s\n\n", utils.ColorGreen(), bundleFile, utils.ColorReset())
+	},
+}
+
+func bundleContext(defaultContext string) string {
+	if bundleConfigContext != "" {
+		return bundleConfigContext
+	}
+	if defaultContext != "" {
+		return defaultContext
+	}
+	return "default"
+}
+
+func init() {
+	BundleExportCmd.Flags().StringVarP(&bundleFile, "file", "f", "", "File to write the bundle to")
+	BundleExportCmd.Flags().StringVarP(&bundleSite, "site", "", "", "Child site to export the bundle for")
+	BundleExportCmd.Flags().StringVarP(&bundleNamespace, "namespace", "", "", "Namespace of the objects to export")
+	BundleExportCmd.Flags().StringVarP(&bundleSince, "since", "", "", "Catalog revision the child site has applied; all catalogs are exported when it's not given")
+	BundleExportCmd.Flags().StringVarP(&bundleEpoch, "epoch", "", "", "Epoch of the catalog revision the child site has applied")
+	BundleExportCmd.Flags().StringArrayVarP(&bundleArtifacts, "artifact", "a", nil, "File to add to the bundle as an artifact")
+	BundleExportCmd.Flags().StringArrayVarP(&bundleArtifactRefs, "artifact-ref", "", nil, "Artifact to add from the artifact directory of the site")
+	BundleExportCmd.Flags().StringVarP(&bundleConfigFile, "config", "c", "", "Maestro CLI config file")
+	BundleExportCmd.Flags().StringVarP(&bundleConfigContext, "context", "", "", "Maestro CLI configuration context")
+	BundleImportCmd.Flags().StringVarP(&bundleFile, "file", "f", "", "Bundle file to import")
+	BundleImportCmd.Flags().StringVarP(&bundleConfigFile, "config", "c", "", "Maestro CLI config file")
+	BundleImportCmd.Flags().StringVarP(&bundleConfigContext, "context", "", "", "Maestro CLI configuration context")
+	BundleCmd.AddCommand(BundleExportCmd)
+	BundleCmd.AddCommand(BundleImportCmd)
+	RootCmd.AddCommand(BundleCmd)
+}
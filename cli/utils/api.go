@@ -14,6 +14,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"sigs.k8s.io/yaml"
 )
 
@@ -151,6 +152,38 @@ func Get(url string, username string, password string, objType string, path stri
 	return ret, nil
 }
 
+// ExportBundle exports the offline bundle of a child site. Without a site, it exports the bundle the site the url
+// points to keeps for its parent.
+func ExportBundle(url string, username string, password string, site string, parameters map[string]string, artifacts []model.BundleArtifact) ([]byte, error) {
+	token, err := Login(url, username, password)
+	if err != nil {
+		return nil, err
+	}
+	route := "/federation/export"
+	if site != "" {
+		route += "/" + site
+	}
+	payload, _ := json.Marshal(model.BundleExportRequest{Artifacts: artifacts})
+	ret, err := callRestAPI(url, route, "POST", payload, token, parameters)
+	if err != nil {
+		return nil, err
+	}
+	if ret == nil {
+		return nil, fmt.Errorf("site '%s' is not found", site)
+	}
+	return ret, nil
+}
+
+// ImportBundle imports an offline bundle exported by another site
+func ImportBundle(url string, username string, password string, bundle []byte) error {
+	token, err := Login(url, username, password)
+	if err != nil {
+		return err
+	}
+	_, err = callRestAPI(url, "/federation/import", "POST", bundle, token, nil)
+	return err
+}
+
 func Login(url string, username string, password string) (string, error) {
 	data, _ := json.Marshal(authRequest{
 		UserName: username,
@@ -8,6 +8,8 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -42,6 +44,9 @@ type HttpBindingConfig struct {
 	Pipeline     []MiddlewareConfig `json:"pipeline"`
 	TLS          bool               `json:"tls"`
 	CertProvider CertProviderConfig `json:"certProvider"`
+	// ClientCerts asks TLS clients for certificates. They aren't verified by the binding, but passed on to
+	// handlers, which verify them.
+	ClientCerts bool `json:"clientCerts,omitempty"`
 }
 
 // HttpBinding provides service endpoints as a fasthttp web server
@@ -83,6 +88,11 @@ func (h *HttpBinding) Launch(config HttpBindingConfig, endpoints []v1alpha2.Endp
 	h.server = &fasthttp.Server{
 		Handler: h.pipeline.Apply(handler),
 	}
+	if config.TLS && config.ClientCerts {
+		h.server.TLSConfig = &tls.Config{
+			ClientAuth: tls.RequestClientCert,
+		}
+	}
 
 	go func() {
 		var serverErr error
@@ -151,9 +161,29 @@ func composeCOARequestContext(reqCtx *fasthttp.RequestCtx, actCtx *contexts.Acti
 	if diagCtx != nil {
 		retCtx = context.WithValue(retCtx, contexts.DiagnosticLogContextKey, diagCtx)
 	}
+	if certs := peerCertificates(reqCtx); len(certs) > 0 {
+		retCtx = context.WithValue(retCtx, v1alpha2.COAPeerCertificatesKey, certs)
+	}
+	if reqCtx != nil {
+		if user, ok := reqCtx.UserValue(string(v1alpha2.COAUserKey)).(string); ok {
+			retCtx = context.WithValue(retCtx, v1alpha2.COAUserKey, user)
+		}
+	}
 	return retCtx
 }
 
+// peerCertificates gets the certificates a TLS client presented, without verifying them
+func peerCertificates(reqCtx *fasthttp.RequestCtx) []*x509.Certificate {
+	if reqCtx == nil || !reqCtx.IsTLS() {
+		return nil
+	}
+	state := reqCtx.TLSConnectionState()
+	if state == nil {
+		return nil
+	}
+	return state.PeerCertificates
+}
+
 func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler) fasthttp.RequestHandler {
 	return func(reqCtx *fasthttp.RequestCtx) {
 		actCtx := contexts.ParseActivityLogContextFromHttpRequestHeader(reqCtx)
@@ -203,7 +233,11 @@ func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler)
 				reqCtx.Response.Header.Set(v1alpha2.COAMetaHeader, string(data))
 			}
 			reqCtx.SetContentType(resp.ContentType)
-			reqCtx.SetBody(resp.Body)
+			if resp.BodyStream != nil {
+				reqCtx.SetBodyStreamWriter(resp.BodyStream)
+			} else {
+				reqCtx.SetBody(resp.Body)
+			}
 			reqCtx.SetStatusCode(toHttpState(resp.State))
 		}
 	}
@@ -7,9 +7,11 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -20,6 +22,7 @@ import (
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	autogen "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	jwt "github.com/golang-jwt/jwt/v4"
@@ -137,6 +140,22 @@ func TestHTTPEcho(t *testing.T) {
 				}
 			},
 		},
+		{
+			Methods: []string{"GET"},
+			Route:   "greetingsStream",
+			Version: "v1",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					BodyStream: func(w *bufio.Writer) {
+						w.WriteString("Hi\n")
+						w.Flush()
+						w.WriteString("there\n")
+						w.Flush()
+					},
+					State: v1alpha2.OK,
+				}
+			},
+		},
 	}
 	err := binding.Launch(config, endpoints, nil)
 	assert.Nil(t, err)
@@ -146,6 +165,9 @@ func TestHTTPEcho(t *testing.T) {
 
 	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetings", nil, 200, "Hi there!!")
 
+	// streamed body
+	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetingsStream", nil, 200, "Hi\nthere\n")
+
 	// query args
 	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetings2?name=John", nil, 200, "Hi John!!")
 
@@ -220,6 +242,79 @@ func TestHTTPEchoWithTLS(t *testing.T) {
 	assert.Equal(t, string(bodyBytes), "Hi there!!")
 }
 
+func TestHTTPClientCertsWithTLS(t *testing.T) {
+	config := HttpBindingConfig{
+		Port:        8889,
+		TLS:         true,
+		ClientCerts: true,
+		CertProvider: CertProviderConfig{
+			Type: "certs.autogen",
+			Config: autogen.AutoGenCertProviderConfig{
+				Name: "test",
+			},
+		},
+		Pipeline: []MiddlewareConfig{
+			{
+				Type: "middleware.http.jwt",
+				Properties: map[string]interface{}{
+					"verifyKey":       "SymphonyKey",
+					"clientCertPaths": []string{"/v1/federation"},
+				},
+			},
+		},
+	}
+	binding := HttpBinding{}
+	endpoints := []v1alpha2.Endpoint{
+		{
+			Methods: []string{"GET"},
+			Route:   "federation",
+			Version: "v1",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				certs, _ := c.Context.Value(v1alpha2.COAPeerCertificatesKey).([]*x509.Certificate)
+				if len(certs) == 0 {
+					return v1alpha2.COAResponse{State: v1alpha2.Unauthorized}
+				}
+				return v1alpha2.COAResponse{
+					Body:  []byte(certs[0].Subject.CommonName),
+					State: v1alpha2.OK,
+				}
+			},
+		},
+	}
+	err := binding.Launch(config, endpoints, nil)
+	assert.Nil(t, err)
+	defer binding.Shutdown(context.Background())
+
+	certPEM, keyPEM, err := certs.GenerateCA("site1", time.Hour)
+	assert.Nil(t, err)
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.Nil(t, err)
+
+	// A client certificate is passed to the handler without a token
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			Certificates:       []tls.Certificate{clientCert},
+		},
+	}}
+	resp, err := client.Get("https://localhost:8889/v1/federation")
+	assert.Nil(t, err)
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "site1", string(bodyBytes))
+
+	// Without a certificate, a token is still needed
+	client = &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err = client.Get("https://localhost:8889/v1/federation")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
 func TestHTTPEchoWithPipeline(t *testing.T) {
 	pubsub := &memory.InMemoryPubSubProvider{}
 	err := pubsub.Init(memory.InMemoryPubSubConfig{})
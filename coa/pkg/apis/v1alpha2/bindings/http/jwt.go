@@ -34,6 +34,12 @@ type JWT struct {
 	EnableRBAC       bool              `json:"enableRBAC,omitempty"`
 	Policy           map[string]Policy `json:"policy,omitempty"`
 	DisableUserCreds bool              `json:"disableUserCreds,omitempty"`
+	// ClientCertPaths are path prefixes where requests with a TLS client certificate don't need a token. The
+	// handlers of these paths verify the certificate.
+	ClientCertPaths []string `json:"clientCertPaths,omitempty"`
+	// StrictPaths are path prefixes that are only allowed by a policy item of the path itself or a path under
+	// it, not by "*" or a parent path. They need RBAC and a Symphony token.
+	StrictPaths []string `json:"strictPaths,omitempty"`
 }
 
 // enum string for AuthServer
@@ -89,6 +95,16 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 			next(ctx)
 			return
 		}
+		strictPath := j.strictPath(string(ctx.Path()))
+		if len(j.ClientCertPaths) > 0 && len(peerCertificates(ctx)) > 0 && strictPath == "" {
+			path := string(ctx.Path())
+			for _, p := range j.ClientCertPaths {
+				if strings.HasPrefix(path, p) {
+					next(ctx)
+					return
+				}
+			}
+		}
 		tokenStr := j.readAuthHeader(ctx)
 		if tokenStr == "" {
 			log.Errorf("JWT: Token is empty.\n")
@@ -107,18 +123,24 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 					return
 				}
 				log.Debugf("JWT: Validating token with username plus pwd.")
-				_, roles, err := j.validateToken(tokenStr)
+				claims, roles, err := j.validateToken(tokenStr)
 				if err != nil {
 					log.Error("JWT: Validate token with user creds failed. %s\n", err.Error())
 					ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 					return
 				} else {
+					if user, ok := claims["user"].(string); ok && user != "" {
+						ctx.SetUserValue(string(v1alpha2.COAUserKey), user)
+					}
 					if j.EnableRBAC {
 						path := string(ctx.Path())
 						method := string(ctx.Method())
 						for _, role := range roles {
 							if v, ok := j.Policy[role]; ok {
 								for key, val := range v.Items {
+									if strictPath != "" && !strings.HasPrefix(key, strictPath) {
+										continue
+									}
 									if key == "*" || strings.HasPrefix(path, key) {
 										if val == "*" || strings.Contains(val, method) {
 											next(ctx)
@@ -131,9 +153,19 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 						ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
 						return
 					}
+					if strictPath != "" {
+						log.Infof("JWT: Path %s needs RBAC.", strictPath)
+						ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
+						return
+					}
 					next(ctx)
 				}
 			} else {
+				if strictPath != "" {
+					log.Infof("JWT: Path %s needs a Symphony token.", strictPath)
+					ctx.Response.SetStatusCode(fasthttp.StatusForbidden)
+					return
+				}
 				if j.AuthServer == AuthServerKuberenetes {
 					log.Debugf("JWT: Validating token with k8s.")
 					err := j.validateServiceAccountToken(ctx, tokenStr)
@@ -152,6 +184,16 @@ func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		}
 	}
 }
+
+// strictPath returns the strict path prefix of a path, or an empty string if the path isn't strict
+func (j JWT) strictPath(path string) string {
+	for _, p := range j.StrictPaths {
+		if strings.HasPrefix(path, p) {
+			return p
+		}
+	}
+	return ""
+}
 func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 	v := ctx.Request.Header.Peek(j.AuthHeader)
 	if v != nil {
@@ -286,6 +328,7 @@ func (j *JWT) validateServiceAccountToken(ctx *fasthttp.RequestCtx, tokenStr str
 			log.Errorf("JWT: Validate token with k8s failed. K8s returned invalid username, %s\n", result.Status.User.Username)
 			return v1alpha2.NewCOAError(nil, "Authentication failed.", v1alpha2.Unauthorized)
 		}
+		ctx.SetUserValue(string(v1alpha2.COAUserKey), result.Status.User.Username)
 	}
 	return nil
 
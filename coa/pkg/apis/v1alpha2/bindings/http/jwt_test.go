@@ -14,8 +14,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func generateJWTToken(signingKey interface{}, method jwt.SigningMethod, userName string, expiresAt time.Time, issuedAt time.Time, notAfter time.Time, issuer string, subject string, audiences []string) (string, error) {
@@ -142,3 +144,67 @@ func TestValidateWithTokenWithCert(t *testing.T) {
 	_, _, err = j.validateToken(token)
 	assert.Nil(t, err)
 }
+
+func TestJWTSetsUser(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "test",
+	}
+	token, err := generateJWTToken([]byte("test"), jwt.SigningMethodHS256, "admin", time.Now().Add(time.Hour), time.Now(), time.Now(), SymphonyIssuer, "test", []string{"test"})
+	assert.Nil(t, err)
+
+	reqCtx := &fasthttp.RequestCtx{}
+	reqCtx.Request.Header.Set("Authorization", "Bearer "+token)
+	called := false
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})(reqCtx)
+	assert.True(t, called)
+	ctx := composeCOARequestContext(reqCtx, nil, nil)
+	assert.Equal(t, "admin", ctx.Value(v1alpha2.COAUserKey))
+}
+
+func TestJWTStrictPaths(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "test",
+		EnableRBAC: true,
+		Roles: []ClaimRoleMap{
+			{Role: "administrator", Claim: "user", Value: "admin"},
+			{Role: "secret-admin", Claim: "user", Value: "secret-admin"},
+			{Role: "reader", Claim: "user", Value: "*"},
+		},
+		Policy: map[string]Policy{
+			"administrator": {Items: map[string]string{"*": "*"}},
+			"secret-admin":  {Items: map[string]string{"/v1alpha2/settings/secrets": "*"}},
+			"reader":        {Items: map[string]string{"*": "GET", "/v1alpha2/settings": "GET"}},
+		},
+		StrictPaths: []string{"/v1alpha2/settings/secrets"},
+	}
+	call := func(j JWT, user string, method string, path string) bool {
+		token, err := generateJWTToken([]byte("test"), jwt.SigningMethodHS256, user, time.Now().Add(time.Hour), time.Now(), time.Now(), SymphonyIssuer, "test", []string{"test"})
+		assert.Nil(t, err)
+		reqCtx := &fasthttp.RequestCtx{}
+		reqCtx.Request.Header.SetMethod(method)
+		reqCtx.Request.SetRequestURI(path)
+		reqCtx.Request.Header.Set("Authorization", "Bearer "+token)
+		called := false
+		j.JWT(func(ctx *fasthttp.RequestCtx) {
+			called = true
+		})(reqCtx)
+		return called
+	}
+
+	assert.True(t, call(j, "secret-admin", fasthttp.MethodPost, "/v1alpha2/settings/secrets/db"))
+	assert.True(t, call(j, "secret-admin", fasthttp.MethodGet, "/v1alpha2/settings/secrets"))
+	// "*" and parent paths don't allow strict paths
+	assert.False(t, call(j, "admin", fasthttp.MethodPost, "/v1alpha2/settings/secrets/db"))
+	assert.False(t, call(j, "someone", fasthttp.MethodGet, "/v1alpha2/settings/secrets"))
+	assert.True(t, call(j, "admin", fasthttp.MethodGet, "/v1alpha2/settings/config"))
+	assert.True(t, call(j, "someone", fasthttp.MethodGet, "/v1alpha2/settings/config"))
+
+	// strict paths need RBAC
+	j.EnableRBAC = false
+	assert.False(t, call(j, "secret-admin", fasthttp.MethodGet, "/v1alpha2/settings/secrets"))
+	assert.True(t, call(j, "secret-admin", fasthttp.MethodGet, "/v1alpha2/settings/config"))
+}
@@ -230,6 +230,15 @@ type ActivationData struct {
 	TriggeringStage      string                            `json:"triggeringStage,omitempty"`
 	Schedule             string                            `json:"schedule,omitempty"`
 	NeedsReport          bool                              `json:"needsReport,omitempty"`
+	Branch               string                            `json:"branch,omitempty"`
+}
+
+// ActivationControlData is the body of an activation-control event, which asks the stage
+// runtime to pause, resume or cancel an activation. State is the desired spec state of the activation.
+type ActivationControlData struct {
+	Activation string `json:"activation"`
+	Namespace  string `json:"namespace,omitempty"`
+	State      string `json:"state"`
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for ActivationData
@@ -128,6 +128,10 @@ func (h *APIHost) Launch(config HostConfig,
 	config.SiteInfo.ParentSite.BaseUrl = overrideWithEnvVariable(config.SiteInfo.ParentSite.BaseUrl, "PARENT_SYMPHONY_API_BASE_URL")
 	config.SiteInfo.ParentSite.Username = overrideWithEnvVariable(config.SiteInfo.ParentSite.Username, "PARENT_SYMPHONY_API_USER")
 	config.SiteInfo.ParentSite.Password = overrideWithEnvVariable(config.SiteInfo.ParentSite.Password, "PARENT_SYMPHONY_API_PASSWORD")
+	config.SiteInfo.ParentSite.CertFile = overrideWithEnvVariable(config.SiteInfo.ParentSite.CertFile, "PARENT_SYMPHONY_API_CERT_FILE")
+	config.SiteInfo.ParentSite.KeyFile = overrideWithEnvVariable(config.SiteInfo.ParentSite.KeyFile, "PARENT_SYMPHONY_API_KEY_FILE")
+	config.SiteInfo.ParentSite.CAFile = overrideWithEnvVariable(config.SiteInfo.ParentSite.CAFile, "PARENT_SYMPHONY_API_CA_FILE")
+	config.SiteInfo.ParentSite.JoinToken = overrideWithEnvVariable(config.SiteInfo.ParentSite.JoinToken, "PARENT_SYMPHONY_API_JOIN_TOKEN")
 
 	var pubsubProvider pv.IProvider
 	for _, v := range config.API.Vendors {
@@ -8,6 +8,7 @@ package managers
 
 import (
 	"context"
+	"fmt"
 
 	k8sstate "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/states/k8s"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -71,6 +72,13 @@ func (m *Manager) Init(context *contexts.VendorContext, config ManagerConfig, pr
 		if c, ok := p.(contexts.IWithManagerContext); ok {
 			c.SetContext(m.Context)
 		}
+		if c, ok := p.(states.IWithStateProvider); ok && c.StateProviderName() != "" {
+			stateProvider, ok := providers[c.StateProviderName()].(states.IStateProvider)
+			if !ok {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("state provider '%s' is not supplied", c.StateProviderName()), v1alpha2.BadConfig)
+			}
+			c.SetStateProvider(stateProvider)
+		}
 	}
 	m.Context.Logger.Debugf(" M (%s): initalize manager type '%s'", config.Name, config.Type)
 	return err
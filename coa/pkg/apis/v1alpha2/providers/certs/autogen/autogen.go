@@ -7,23 +7,36 @@
 package autogen
 
 import (
+	"crypto"
+	"crypto/x509"
 	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	fasthttp "github.com/valyala/fasthttp"
 )
 
 var log = logger.NewLogger("coa.runtime")
 
+const caValidity = 10 * 365 * 24 * time.Hour
+
 type AutoGenCertProviderConfig struct {
 	Name string `json:"name"`
 }
 
+// AutoGenCertProvider generates certificates in memory. As a signer, it generates a CA when it first signs, which
+// is lost when the process restarts.
 type AutoGenCertProvider struct {
 	Config AutoGenCertProviderConfig
+	lock   sync.Mutex
+	caPEM  []byte
+	caCert *x509.Certificate
+	caKey  crypto.Signer
 }
 
 func (w *AutoGenCertProvider) ID() string {
@@ -55,3 +68,43 @@ func toAutoGenCertProviderConfig(config providers.IProviderConfig) (AutoGenCertP
 func (w *AutoGenCertProvider) GetCert(host string) ([]byte, []byte, error) {
 	return fasthttp.GenerateTestCertificate(host)
 }
+
+func (w *AutoGenCertProvider) GetCACert() ([]byte, error) {
+	if err := w.ensureCA(); err != nil {
+		return nil, err
+	}
+	return w.caPEM, nil
+}
+
+func (w *AutoGenCertProvider) SignCSR(csr []byte, commonName string, validity time.Duration) ([]byte, error) {
+	if err := w.ensureCA(); err != nil {
+		return nil, err
+	}
+	return certs.SignCSR(w.caCert, w.caKey, csr, commonName, validity)
+}
+
+func (w *AutoGenCertProvider) SignData(data []byte) ([]byte, error) {
+	if err := w.ensureCA(); err != nil {
+		return nil, err
+	}
+	return certs.SignData(w.caKey, data)
+}
+
+func (w *AutoGenCertProvider) ensureCA() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.caCert != nil {
+		return nil
+	}
+	certPEM, keyPEM, err := certs.GenerateCA("symphony-autogen-ca", caValidity)
+	if err != nil {
+		return err
+	}
+	w.caCert, w.caKey, err = certs.ParseCA(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	w.caPEM = certPEM
+	log.Info("  P (Autogen): generated an in-memory CA")
+	return nil
+}
@@ -7,9 +7,16 @@
 package autogen
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +45,49 @@ func TestID(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "test", provider.ID())
 }
+
+func TestSignCSR(t *testing.T) {
+	provider := AutoGenCertProvider{}
+	err := provider.Init(AutoGenCertProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	assert.Nil(t, err)
+	certPEM, err := provider.SignCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), "site1", time.Hour)
+	assert.Nil(t, err)
+
+	caPEM, err := provider.GetCACert()
+	assert.Nil(t, err)
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(caPEM))
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.Nil(t, err)
+
+	// The CA is kept
+	caPEM2, err := provider.GetCACert()
+	assert.Nil(t, err)
+	assert.Equal(t, caPEM, caPEM2)
+}
+
+func TestSignData(t *testing.T) {
+	provider := AutoGenCertProvider{}
+	err := provider.Init(AutoGenCertProviderConfig{Name: "test"})
+	assert.Nil(t, err)
+
+	signature, err := provider.SignData([]byte("payload"))
+	assert.Nil(t, err)
+	caPEM, err := provider.GetCACert()
+	assert.Nil(t, err)
+	block, _ := pem.Decode(caPEM)
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	assert.Nil(t, certs.VerifyData(caCert, []byte("payload"), signature))
+}
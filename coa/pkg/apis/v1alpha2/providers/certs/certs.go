@@ -7,6 +7,8 @@
 package certs
 
 import (
+	"time"
+
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 )
 
@@ -15,3 +17,15 @@ type ICertProvider interface {
 	Init(config providers.IProviderConfig) error
 	GetCert(host string) ([]byte, []byte, error)
 }
+
+// ICertSigner is a cert provider that acts as a certificate authority for clients
+type ICertSigner interface {
+	ICertProvider
+	// GetCACert gets the PEM certificate signed certificates chain to
+	GetCACert() ([]byte, error)
+	// SignCSR signs a PEM certificate request for client authentication. The certificate gets commonName as its
+	// subject, whatever the request asks for, and is valid for the given duration.
+	SignCSR(csr []byte, commonName string, validity time.Duration) ([]byte, error)
+	// SignData signs data with the key of the CA, so that anyone trusting the CA can verify it
+	SignData(data []byte) ([]byte, error)
+}
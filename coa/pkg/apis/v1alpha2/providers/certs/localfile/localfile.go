@@ -10,10 +10,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
@@ -79,3 +81,36 @@ func (w *LocalCertFileProvider) GetCert(host string) ([]byte, []byte, error) {
 	}
 	return certData, keyData, nil
 }
+
+// GetCACert gets the certificate file, which is the CA when the provider signs certificates
+func (w *LocalCertFileProvider) GetCACert() ([]byte, error) {
+	certData, _, err := w.GetCert("")
+	return certData, err
+}
+
+// SignCSR signs a certificate request with the certificate and key files. The files are read for each request,
+// so a replaced CA is used right away.
+func (w *LocalCertFileProvider) SignCSR(csr []byte, commonName string, validity time.Duration) ([]byte, error) {
+	certData, keyData, err := w.GetCert("")
+	if err != nil {
+		return nil, err
+	}
+	caCert, caKey, err := certs.ParseCA(certData, keyData)
+	if err != nil {
+		return nil, err
+	}
+	return certs.SignCSR(caCert, caKey, csr, commonName, validity)
+}
+
+// SignData signs data with the key file
+func (w *LocalCertFileProvider) SignData(data []byte) ([]byte, error) {
+	_, keyData, err := w.GetCert("")
+	if err != nil {
+		return nil, err
+	}
+	key, err := certs.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, err
+	}
+	return certs.SignData(key, data)
+}
@@ -7,8 +7,17 @@
 package localfile
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,3 +56,54 @@ func TestCertGetTestFile(t *testing.T) {
 	_, _, err = provider.GetCert("localhost")
 	assert.Nil(t, err)
 }
+
+func TestSignCSR(t *testing.T) {
+	caPEM, keyPEM, err := certs.GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ca.crt"), caPEM, 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ca.key"), keyPEM, 0600))
+	provider := LocalCertFileProvider{}
+	err = provider.Init(LocalCertFileProviderConfig{
+		Name:     "test",
+		CertFile: filepath.Join(dir, "ca.crt"),
+		KeyFile:  filepath.Join(dir, "ca.key"),
+	})
+	assert.Nil(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	assert.Nil(t, err)
+	certPEM, err := provider.SignCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), "site1", time.Hour)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	assert.Equal(t, "site1", cert.Subject.CommonName)
+
+	ca, err := provider.GetCACert()
+	assert.Nil(t, err)
+	assert.Equal(t, caPEM, ca)
+}
+
+func TestSignData(t *testing.T) {
+	caPEM, keyPEM, err := certs.GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ca.crt"), caPEM, 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "ca.key"), keyPEM, 0600))
+	provider := LocalCertFileProvider{}
+	err = provider.Init(LocalCertFileProviderConfig{
+		Name:     "test",
+		CertFile: filepath.Join(dir, "ca.crt"),
+		KeyFile:  filepath.Join(dir, "ca.key"),
+	})
+	assert.Nil(t, err)
+
+	signature, err := provider.SignData([]byte("payload"))
+	assert.Nil(t, err)
+	caCert, _, err := certs.ParseCA(caPEM, keyPEM)
+	assert.Nil(t, err)
+	assert.Nil(t, certs.VerifyData(caCert, []byte("payload"), signature))
+}
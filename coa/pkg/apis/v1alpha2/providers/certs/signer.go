@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// GenerateCA creates a self-signed CA certificate and its key, in PEM
+func GenerateCA(commonName string, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to generate CA key", v1alpha2.InternalError)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to create CA certificate", v1alpha2.InternalError)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to encode CA key", v1alpha2.InternalError)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+// ParseCA parses a PEM CA certificate and its key
+func ParseCA(certPEM []byte, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, v1alpha2.NewCOAError(nil, "CA certificate is not PEM encoded", v1alpha2.BadConfig)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to parse CA certificate", v1alpha2.BadConfig)
+	}
+	signer, err := ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, signer, nil
+}
+
+// ParsePrivateKey parses a PEM private key that can sign
+func ParsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, v1alpha2.NewCOAError(nil, "key is not PEM encoded", v1alpha2.BadConfig)
+	}
+	var key interface{}
+	var err error
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	}
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to parse key", v1alpha2.BadConfig)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "key can't sign", v1alpha2.BadConfig)
+	}
+	return signer, nil
+}
+
+// SignData signs the SHA-256 digest of data with a key
+func SignData(key crypto.Signer, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to sign data", v1alpha2.InternalError)
+	}
+	return signature, nil
+}
+
+// VerifyData checks a signature made by SignData with the key of a certificate
+func VerifyData(cert *x509.Certificate, data []byte, signature []byte) error {
+	var algorithm x509.SignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	default:
+		return v1alpha2.NewCOAError(nil, "certificate key type is not supported", v1alpha2.BadRequest)
+	}
+	if err := cert.CheckSignature(algorithm, data, signature); err != nil {
+		return v1alpha2.NewCOAError(err, "signature is not valid", v1alpha2.Unauthorized)
+	}
+	return nil
+}
+
+// SignCSR signs a PEM certificate request with a CA, for client authentication. The subject of the request is
+// replaced with commonName, and other extensions it asks for are ignored.
+func SignCSR(caCert *x509.Certificate, caKey crypto.Signer, csrPEM []byte, commonName string, validity time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, v1alpha2.NewCOAError(nil, "certificate request is not PEM encoded", v1alpha2.BadRequest)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to parse certificate request", v1alpha2.BadRequest)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "certificate request signature is invalid", v1alpha2.BadRequest)
+	}
+	switch key := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return nil, v1alpha2.NewCOAError(nil, "RSA keys need at least 2048 bits", v1alpha2.BadRequest)
+		}
+	case *ecdsa.PublicKey:
+	default:
+		return nil, v1alpha2.NewCOAError(nil, "certificate request key type is not supported", v1alpha2.BadRequest)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	notAfter := now.Add(validity)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, csr.PublicKey, caKey)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to sign certificate", v1alpha2.InternalError)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// GenerateCSR creates an ECDSA key and a certificate request for it, in PEM
+func GenerateCSR(commonName string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to generate key", v1alpha2.InternalError)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, key)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to create certificate request", v1alpha2.InternalError)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, v1alpha2.NewCOAError(err, "failed to encode key", v1alpha2.InternalError)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to generate certificate serial number", v1alpha2.InternalError)
+	}
+	return serial, nil
+}
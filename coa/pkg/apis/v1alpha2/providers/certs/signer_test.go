@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCSR(t *testing.T, commonName string) []byte {
+	csrPEM, _, err := GenerateCSR(commonName)
+	assert.Nil(t, err)
+	return csrPEM
+}
+
+func TestGenerateCSR(t *testing.T) {
+	caPEM, caKeyPEM, err := GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	caCert, caKey, err := ParseCA(caPEM, caKeyPEM)
+	assert.Nil(t, err)
+
+	csrPEM, keyPEM, err := GenerateCSR("site1")
+	assert.Nil(t, err)
+	certPEM, err := SignCSR(caCert, caKey, csrPEM, "site1", time.Hour)
+	assert.Nil(t, err)
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	assert.Nil(t, err)
+}
+
+func TestSignCSR(t *testing.T) {
+	caPEM, keyPEM, err := GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	caCert, caKey, err := ParseCA(caPEM, keyPEM)
+	assert.Nil(t, err)
+	assert.True(t, caCert.IsCA)
+
+	// The subject is set by the signer, not by the request
+	certPEM, err := SignCSR(caCert, caKey, newTestCSR(t, "someone-else"), "site1", 30*time.Minute)
+	assert.Nil(t, err)
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	assert.Equal(t, "site1", cert.Subject.CommonName)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), cert.NotAfter, time.Minute)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.Nil(t, err)
+
+	// Certificates don't outlive the CA
+	certPEM, err = SignCSR(caCert, caKey, newTestCSR(t, "site1"), "site1", 24*time.Hour)
+	assert.Nil(t, err)
+	block, _ = pem.Decode(certPEM)
+	cert, err = x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	assert.False(t, cert.NotAfter.After(caCert.NotAfter))
+}
+
+func TestSignCSRInvalid(t *testing.T) {
+	caPEM, keyPEM, err := GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	caCert, caKey, err := ParseCA(caPEM, keyPEM)
+	assert.Nil(t, err)
+
+	_, err = SignCSR(caCert, caKey, []byte("not a csr"), "site1", time.Hour)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	// A request with a signature that doesn't match its key
+	csrPEM := newTestCSR(t, "site1")
+	block, _ := pem.Decode(csrPEM)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	_, err = SignCSR(caCert, caKey, pem.EncodeToMemory(block), "site1", time.Hour)
+	assert.NotNil(t, err)
+}
+
+func TestParseCAInvalid(t *testing.T) {
+	caPEM, _, err := GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	_, _, err = ParseCA(caPEM, []byte("not a key"))
+	assert.NotNil(t, err)
+	_, _, err = ParseCA([]byte("not a cert"), nil)
+	assert.NotNil(t, err)
+}
+
+func TestSignData(t *testing.T) {
+	caPEM, keyPEM, err := GenerateCA("test-ca", time.Hour)
+	assert.Nil(t, err)
+	caCert, caKey, err := ParseCA(caPEM, keyPEM)
+	assert.Nil(t, err)
+
+	signature, err := SignData(caKey, []byte("payload"))
+	assert.Nil(t, err)
+	assert.Nil(t, VerifyData(caCert, []byte("payload"), signature))
+
+	err = VerifyData(caCert, []byte("tampered"), signature)
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	// A signature by another key isn't accepted
+	_, otherKeyPEM, err := GenerateCSR("site1")
+	assert.Nil(t, err)
+	otherKey, err := ParsePrivateKey(otherKeyPEM)
+	assert.Nil(t, err)
+	signature, err = SignData(otherKey, []byte("payload"))
+	assert.Nil(t, err)
+	assert.NotNil(t, VerifyData(caCert, []byte("payload"), signature))
+}
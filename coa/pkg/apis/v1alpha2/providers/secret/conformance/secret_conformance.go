@@ -8,8 +8,11 @@ package conformance
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,8 +24,72 @@ func GetSecretNotFound[P secret.ISecretProvider](t *testing.T, p P) {
 	_, err := p.Read(context.TODO(), "fake_object", "fake_key", nil)
 	assert.Nil(t, err)
 }
+func WriteAndReadSecret(t *testing.T, p secret.ISecretProvider, w secret.ISecretWriter) {
+	version, err := w.Write(context.TODO(), "conformance_object", map[string]string{"key": "value"}, 0, nil)
+	assert.Nil(t, err)
+	val, err := p.Read(context.TODO(), "conformance_object", "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "value", val)
+	_, err = p.Read(context.TODO(), "conformance_object", "fake_key", nil)
+	assert.NotNil(t, err)
+
+	secrets, err := w.List(context.TODO(), nil)
+	assert.Nil(t, err)
+	found := false
+	for _, s := range secrets {
+		if s.Name == "conformance_object" {
+			found = true
+			assert.Equal(t, version.Version, s.Versions[len(s.Versions)-1].Version)
+		}
+	}
+	assert.True(t, found)
+
+	err = w.Delete(context.TODO(), "conformance_object", nil)
+	assert.Nil(t, err)
+	val, err = p.Read(context.TODO(), "conformance_object", "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	err = w.Delete(context.TODO(), "conformance_object", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+func RotateSecret(t *testing.T, p secret.ISecretProvider, w secret.ISecretWriter) {
+	first, err := w.Write(context.TODO(), "conformance_rotated", map[string]string{"key": "old"}, 0, nil)
+	assert.Nil(t, err)
+	second, err := w.Write(context.TODO(), "conformance_rotated", map[string]string{"key": "new"}, time.Hour, nil)
+	assert.Nil(t, err)
+	assert.Greater(t, second.Version, first.Version)
+
+	// Both versions are valid during the grace period
+	val, err := p.Read(context.TODO(), "conformance_rotated", "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", val)
+	val, err = p.Read(context.TODO(), "conformance_rotated:"+secret.PreviousVersion, "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", val)
+	val, err = p.Read(context.TODO(), fmt.Sprintf("conformance_rotated:%d", first.Version), "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", val)
+
+	// The previous version is dropped when the secret is rotated again
+	_, err = w.Write(context.TODO(), "conformance_rotated", map[string]string{"key": "newer"}, time.Hour, nil)
+	assert.Nil(t, err)
+	_, err = p.Read(context.TODO(), fmt.Sprintf("conformance_rotated:%d", first.Version), "key", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	val, err = p.Read(context.TODO(), "conformance_rotated:"+secret.PreviousVersion, "key", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", val)
+
+	assert.Nil(t, w.Delete(context.TODO(), "conformance_rotated", nil))
+}
 func ConformanceSuite[P secret.ISecretProvider](t *testing.T, p P) {
 	t.Run("Level=Default", func(t *testing.T) {
 		GetSecretNotFound(t, p)
 	})
+	// Providers that secrets can be written to need to pass these as well
+	if w, ok := any(p).(secret.ISecretWriter); ok {
+		t.Run("Level=Write", func(t *testing.T) {
+			WriteAndReadSecret(t, p, w)
+			RotateSecret(t, p, w)
+		})
+	}
 }
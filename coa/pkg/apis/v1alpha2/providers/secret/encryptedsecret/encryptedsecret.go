@@ -0,0 +1,541 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package encryptedsecret
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	keySize            = 32
+	defaultGracePeriod = 24 * time.Hour
+)
+
+type EncryptedSecretProviderConfig struct {
+	Name string `json:"name"`
+	// Key is the base64 encoded 256-bit key that seals the secrets
+	Key string `json:"key,omitempty"`
+	// KeyFile is a file with the base64 encoded key, read when Key isn't set
+	KeyFile string `json:"keyFile,omitempty"`
+	// StateProvider is the state provider of the manager that keeps the sealed secrets. The secrets are kept
+	// in memory without it.
+	StateProvider string `json:"stateProvider,omitempty"`
+	// GracePeriod is how long a replaced version of a secret stays valid by default, such as 1h. It's 24h
+	// when it isn't set.
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+func EncryptedSecretProviderConfigFromMap(properties map[string]string) (EncryptedSecretProviderConfig, error) {
+	ret := EncryptedSecretProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["key"]; ok {
+		ret.Key = utils.ParseProperty(v)
+	}
+	if v, ok := properties["keyFile"]; ok {
+		ret.KeyFile = utils.ParseProperty(v)
+	}
+	if v, ok := properties["stateProvider"]; ok {
+		ret.StateProvider = utils.ParseProperty(v)
+	}
+	if v, ok := properties["gracePeriod"]; ok {
+		ret.GracePeriod = utils.ParseProperty(v)
+	}
+	return ret, nil
+}
+
+// sealedSecret is a version of a secret as it's stored. The fields are sealed with a data key of their own,
+// which is sealed with the key of the provider. Both are bound to the namespace, name and version of the
+// secret, so a sealed secret can't be passed off as another.
+type sealedSecret struct {
+	secret.SecretVersion
+	// KeyID identifies the key that sealed the data key
+	KeyID      string `json:"keyId"`
+	WrappedKey string `json:"wrappedKey"`
+	Data       string `json:"data"`
+}
+
+// secretEntry keeps the current version of a secret last, after the previous version if it's still kept
+type secretEntry struct {
+	Versions []sealedSecret `json:"versions"`
+}
+
+// EncryptedSecretProvider keeps secrets encrypted at rest in a state provider
+type EncryptedSecretProvider struct {
+	Config        EncryptedSecretProviderConfig
+	Context       *contexts.ManagerContext
+	StateProvider states.IStateProvider
+	key           []byte
+	keyID         string
+	gracePeriod   time.Duration
+	lock          sync.Mutex
+}
+
+func (i *EncryptedSecretProvider) InitWithMap(properties map[string]string) error {
+	config, err := EncryptedSecretProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (m *EncryptedSecretProvider) ID() string {
+	return m.Config.Name
+}
+
+func (a *EncryptedSecretProvider) SetContext(context *contexts.ManagerContext) {
+	a.Context = context
+}
+
+func (m *EncryptedSecretProvider) StateProviderName() string {
+	return m.Config.StateProvider
+}
+
+func (m *EncryptedSecretProvider) SetStateProvider(provider states.IStateProvider) {
+	m.StateProvider = provider
+}
+
+func (m *EncryptedSecretProvider) Init(config providers.IProviderConfig) error {
+	aConfig, err := toEncryptedSecretProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid encrypted secret provider config", v1alpha2.BadConfig)
+	}
+	m.Config = aConfig
+	encodedKey := m.Config.Key
+	if encodedKey == "" {
+		if m.Config.KeyFile == "" {
+			return v1alpha2.NewCOAError(nil, "encrypted secret provider key is not set", v1alpha2.BadConfig)
+		}
+		data, err := os.ReadFile(m.Config.KeyFile)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read key file '%s'", m.Config.KeyFile), v1alpha2.BadConfig)
+		}
+		encodedKey = string(data)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != keySize {
+		return v1alpha2.NewCOAError(err, "encrypted secret provider key must be a base64 encoded 256-bit key", v1alpha2.BadConfig)
+	}
+	m.key = key
+	hash := sha256.Sum256(key)
+	m.keyID = hex.EncodeToString(hash[:8])
+	m.gracePeriod = defaultGracePeriod
+	if m.Config.GracePeriod != "" {
+		m.gracePeriod, err = time.ParseDuration(m.Config.GracePeriod)
+		if err != nil || m.gracePeriod < 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid grace period '%s'", m.Config.GracePeriod), v1alpha2.BadConfig)
+		}
+	}
+	if m.Config.StateProvider == "" {
+		stateProvider := &memorystate.MemoryStateProvider{}
+		if err = stateProvider.Init(memorystate.MemoryStateProviderConfig{}); err != nil {
+			return err
+		}
+		m.StateProvider = stateProvider
+	}
+	return nil
+}
+
+func toEncryptedSecretProviderConfig(config providers.IProviderConfig) (EncryptedSecretProviderConfig, error) {
+	ret := EncryptedSecretProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	ret.Name = utils.ParseProperty(ret.Name)
+	ret.Key = utils.ParseProperty(ret.Key)
+	ret.KeyFile = utils.ParseProperty(ret.KeyFile)
+	ret.StateProvider = utils.ParseProperty(ret.StateProvider)
+	ret.GracePeriod = utils.ParseProperty(ret.GracePeriod)
+	return ret, err
+}
+
+// Read reads a field of a secret. The name can be in the <name>:<version> form to read a version other
+// than the current one, as long as it's still valid. A secret that doesn't exist reads as an empty value, as
+// the conformance suite expects, while a version that doesn't exist is NotFound.
+func (m *EncryptedSecretProvider) Read(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	ctx, span := observability.StartSpan("Encrypted Secret Provider", ctx, &map[string]string{
+		"method": "Read",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	name, version := secret.ParseVersion(name)
+	namespace := namespaceFromContext(localContext)
+	entry, err := m.get(ctx, name, namespace)
+	if err != nil {
+		if v1alpha2.IsNotFound(err) && version == secret.CurrentVersion {
+			sLog.InfofCtx(ctx, "  P (Encrypted Secret): secret %s is not found", name)
+			err = nil
+			return "", nil
+		}
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to read secret %s: %+v", name, err)
+		return "", err
+	}
+	sealed, err := findVersion(entry, name, version)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): %+v", err)
+		return "", err
+	}
+	fields, err := m.open(name, namespace, sealed)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to read secret %s: %+v", name, err)
+		return "", err
+	}
+	value, ok := fields[field]
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("field %s not found in secret %s", field, name), v1alpha2.MissingConfig)
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): %+v", err)
+		return "", err
+	}
+	return value, nil
+}
+
+func (m *EncryptedSecretProvider) Get(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	return m.Read(ctx, name, field, localContext)
+}
+
+// Write seals the fields of a secret as its new current version. The version it replaces stays valid for
+// the grace period, and older versions are dropped.
+func (m *EncryptedSecretProvider) Write(ctx context.Context, name string, fields map[string]string, gracePeriod time.Duration, localContext interface{}) (secret.SecretVersion, error) {
+	ctx, span := observability.StartSpan("Encrypted Secret Provider", ctx, &map[string]string{
+		"method": "Write",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if name == "" || strings.Contains(name, ":") {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid secret name '%s'", name), v1alpha2.BadRequest)
+		return secret.SecretVersion{}, err
+	}
+	if len(fields) == 0 {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("secret %s has no fields", name), v1alpha2.BadRequest)
+		return secret.SecretVersion{}, err
+	}
+	if gracePeriod <= 0 {
+		gracePeriod = m.gracePeriod
+	}
+	namespace := namespaceFromContext(localContext)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	entry, err := m.get(ctx, name, namespace)
+	if err != nil && !v1alpha2.IsNotFound(err) {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to read secret %s: %+v", name, err)
+		return secret.SecretVersion{}, err
+	}
+	now := time.Now().UTC()
+	version := secret.SecretVersion{
+		Version:   1,
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	versions := make([]sealedSecret, 0, 2)
+	if n := len(entry.Versions); n > 0 {
+		current := entry.Versions[n-1]
+		current.ExpiresAt = now.Add(gracePeriod).Format(time.RFC3339Nano)
+		versions = append(versions, current)
+		version.Version = current.Version + 1
+	}
+	sealed, err := m.seal(name, namespace, version, fields)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to seal secret %s: %+v", name, err)
+		return secret.SecretVersion{}, err
+	}
+	entry.Versions = append(versions, sealed)
+	_, err = m.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   entryID(name),
+			Body: entry,
+		},
+		Metadata: metadata(namespace),
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to store secret %s: %+v", name, err)
+		return secret.SecretVersion{}, err
+	}
+	return version, nil
+}
+
+func (m *EncryptedSecretProvider) Delete(ctx context.Context, name string, localContext interface{}) error {
+	ctx, span := observability.StartSpan("Encrypted Secret Provider", ctx, &map[string]string{
+		"method": "Delete",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	namespace := namespaceFromContext(localContext)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, err = m.get(ctx, name, namespace); err != nil {
+		return err
+	}
+	err = m.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID:       entryID(name),
+		Metadata: metadata(namespace),
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to delete secret %s: %+v", name, err)
+	}
+	return err
+}
+
+func (m *EncryptedSecretProvider) List(ctx context.Context, localContext interface{}) ([]secret.SecretInfo, error) {
+	ctx, span := observability.StartSpan("Encrypted Secret Provider", ctx, &map[string]string{
+		"method": "List",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	if m.StateProvider == nil {
+		err = v1alpha2.NewCOAError(nil, "encrypted secret provider has no state provider", v1alpha2.BadConfig)
+		return nil, err
+	}
+	namespace := namespaceFromContext(localContext)
+	entries, _, err := m.StateProvider.List(ctx, states.ListRequest{
+		Metadata: metadata(namespace),
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Encrypted Secret): failed to list secrets: %+v", err)
+		return nil, err
+	}
+	ret := make([]secret.SecretInfo, 0)
+	for _, e := range entries {
+		if !strings.HasPrefix(e.ID, entryID("")) {
+			continue
+		}
+		entry, err := toSecretEntry(e.Body)
+		if err != nil || len(entry.Versions) == 0 {
+			continue
+		}
+		info := secret.SecretInfo{
+			Name:      strings.TrimPrefix(e.ID, entryID("")),
+			Namespace: namespace,
+			Versions:  make([]secret.SecretVersion, 0, len(entry.Versions)),
+		}
+		for _, v := range validVersions(entry) {
+			info.Versions = append(info.Versions, v.SecretVersion)
+		}
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+func (m *EncryptedSecretProvider) get(ctx context.Context, name string, namespace string) (secretEntry, error) {
+	if m.StateProvider == nil {
+		return secretEntry{}, v1alpha2.NewCOAError(nil, "encrypted secret provider has no state provider", v1alpha2.BadConfig)
+	}
+	entry, err := m.StateProvider.Get(ctx, states.GetRequest{
+		ID:       entryID(name),
+		Metadata: metadata(namespace),
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			return secretEntry{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret %s is not found", name), v1alpha2.NotFound)
+		}
+		return secretEntry{}, err
+	}
+	ret, err := toSecretEntry(entry.Body)
+	if err != nil {
+		return secretEntry{}, v1alpha2.NewCOAError(err, fmt.Sprintf("secret %s is not a sealed secret", name), v1alpha2.InternalError)
+	}
+	return ret, nil
+}
+
+func toSecretEntry(body interface{}) (secretEntry, error) {
+	var ret secretEntry
+	jData, err := json.Marshal(body)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(jData, &ret)
+	return ret, err
+}
+
+// validVersions drops the versions whose grace period is over
+func validVersions(entry secretEntry) []sealedSecret {
+	ret := make([]sealedSecret, 0, len(entry.Versions))
+	now := time.Now().UTC()
+	for _, v := range entry.Versions {
+		if v.ExpiresAt != "" {
+			if t, err := time.Parse(time.RFC3339, v.ExpiresAt); err != nil || !now.Before(t) {
+				continue
+			}
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func findVersion(entry secretEntry, name string, version string) (sealedSecret, error) {
+	versions := validVersions(entry)
+	n := len(entry.Versions)
+	switch version {
+	case secret.CurrentVersion:
+		if n > 0 {
+			return entry.Versions[n-1], nil
+		}
+	case secret.PreviousVersion:
+		if n > 1 && len(versions) > 1 {
+			return versions[len(versions)-2], nil
+		}
+	default:
+		number, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return sealedSecret{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid version '%s' of secret %s", version, name), v1alpha2.BadRequest)
+		}
+		for _, v := range versions {
+			if v.Version == number {
+				return v, nil
+			}
+		}
+	}
+	return sealedSecret{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("version %s of secret %s is not found", version, name), v1alpha2.NotFound)
+}
+
+func (m *EncryptedSecretProvider) open(name string, namespace string, sealed sealedSecret) (map[string]string, error) {
+	if sealed.KeyID != m.keyID {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("secret %s is sealed with key %s, not the key of the provider", name, sealed.KeyID), v1alpha2.InternalError)
+	}
+	aad := additionalData(name, namespace, sealed.Version)
+	dataKey, err := decrypt(m.key, sealed.WrappedKey, aad)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to unseal secret %s", name), v1alpha2.InternalError)
+	}
+	data, err := decrypt(dataKey, sealed.Data, aad)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to unseal secret %s", name), v1alpha2.InternalError)
+	}
+	var fields map[string]string
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to unseal secret %s", name), v1alpha2.InternalError)
+	}
+	return fields, nil
+}
+
+func (m *EncryptedSecretProvider) seal(name string, namespace string, version secret.SecretVersion, fields map[string]string) (sealedSecret, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	dataKey := make([]byte, keySize)
+	if _, err = rand.Read(dataKey); err != nil {
+		return sealedSecret{}, err
+	}
+	aad := additionalData(name, namespace, version.Version)
+	sealedData, err := encrypt(dataKey, data, aad)
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	wrappedKey, err := encrypt(m.key, dataKey, aad)
+	if err != nil {
+		return sealedSecret{}, err
+	}
+	return sealedSecret{
+		SecretVersion: version,
+		KeyID:         m.keyID,
+		WrappedKey:    wrappedKey,
+		Data:          sealedData,
+	}, nil
+}
+
+// encrypt seals data with AES-GCM, and encodes the nonce followed by the sealed data
+func encrypt(key []byte, data []byte, additionalData []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, additionalData)), nil
+}
+
+func decrypt(key []byte, sealed string, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed data is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(name string, namespace string, version int64) []byte {
+	return []byte(fmt.Sprintf("%s/%s:%d", namespace, name, version))
+}
+
+// entryID keeps the secrets apart from other entries in state providers that key entries by id only
+func entryID(name string) string {
+	return "s_" + name
+}
+
+func metadata(namespace string) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace": namespace,
+		"group":     "secret.symphony",
+		"version":   "v1",
+		"resource":  "secrets",
+		"kind":      "Secret",
+	}
+}
+
+func namespaceFromContext(localContext interface{}) string {
+	if ltx, ok := localContext.(utils.EvaluationContext); ok && ltx.Namespace != "" {
+		return ltx.Namespace
+	}
+	return "default"
+}
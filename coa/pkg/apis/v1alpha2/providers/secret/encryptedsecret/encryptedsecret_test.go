@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package encryptedsecret
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func TestInit(t *testing.T) {
+	provider := EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(EncryptedSecretProviderConfig{Key: base64.StdEncoding.EncodeToString([]byte("short"))})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(EncryptedSecretProviderConfig{KeyFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(EncryptedSecretProviderConfig{Key: testKey, GracePeriod: "a day"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	assert.Nil(t, os.WriteFile(keyFile, []byte(testKey+"\n"), 0600))
+	err = provider.Init(EncryptedSecretProviderConfig{KeyFile: keyFile})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.StateProvider)
+}
+
+func TestInitWithMap(t *testing.T) {
+	provider := EncryptedSecretProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":          "test",
+		"key":           testKey,
+		"stateProvider": "redis",
+		"gracePeriod":   "1h",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "test", provider.ID())
+	assert.Equal(t, "redis", provider.StateProviderName())
+	assert.Equal(t, time.Hour, provider.gracePeriod)
+}
+
+func TestSetContext(t *testing.T) {
+	provider := EncryptedSecretProvider{}
+	provider.SetContext(&contexts.ManagerContext{})
+	assert.NotNil(t, provider.Context)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	provider := EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{Key: testKey})
+	assert.Nil(t, err)
+
+	version, err := provider.Write(context.Background(), "db", map[string]string{"user": "admin", "password": "p@ss"}, 0, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), version.Version)
+	val, err := provider.Read(context.Background(), "db", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", val)
+	val, err = provider.Get(context.Background(), "db:current", "user", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", val)
+	_, err = provider.Read(context.Background(), "db", "token", nil)
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+	_, err = provider.Read(context.Background(), "db:previous", "password", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = provider.Read(context.Background(), "db:latest", "password", nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	_, err = provider.Write(context.Background(), "db", map[string]string{}, 0, nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	_, err = provider.Write(context.Background(), "db:2", map[string]string{"user": "admin"}, 0, nil)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	// Secrets are kept per namespace
+	val, err = provider.Read(context.Background(), "db", "password", utils.EvaluationContext{Namespace: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	_, err = provider.Read(context.Background(), "db:previous", "password", utils.EvaluationContext{Namespace: "other"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+	secrets, err := provider.List(context.Background(), utils.EvaluationContext{Namespace: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(secrets))
+}
+
+func TestGracePeriod(t *testing.T) {
+	provider := EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{Key: testKey})
+	assert.Nil(t, err)
+
+	_, err = provider.Write(context.Background(), "db", map[string]string{"password": "old"}, 0, nil)
+	assert.Nil(t, err)
+	_, err = provider.Write(context.Background(), "db", map[string]string{"password": "new"}, 50*time.Millisecond, nil)
+	assert.Nil(t, err)
+	secrets, err := provider.List(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(secrets))
+	assert.Equal(t, "db", secrets[0].Name)
+	assert.Equal(t, 2, len(secrets[0].Versions))
+	assert.NotEqual(t, "", secrets[0].Versions[0].ExpiresAt)
+	val, err := provider.Read(context.Background(), "db:previous", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", val)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = provider.Read(context.Background(), "db:previous", "password", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = provider.Read(context.Background(), "db:1", "password", nil)
+	assert.True(t, v1alpha2.IsNotFound(err))
+	val, err = provider.Read(context.Background(), "db:2", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", val)
+	secrets, err = provider.List(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(secrets[0].Versions))
+}
+
+func TestSealedAtRest(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	provider := EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{Key: testKey, StateProvider: "memory"})
+	assert.Nil(t, err)
+	provider.SetStateProvider(stateProvider)
+
+	_, err = provider.Write(context.Background(), "db", map[string]string{"password": "p@ss"}, 0, nil)
+	assert.Nil(t, err)
+	entry, err := stateProvider.Get(context.Background(), states.GetRequest{ID: "s_db", Metadata: metadata("default")})
+	assert.Nil(t, err)
+	data, _ := json.Marshal(entry.Body)
+	assert.False(t, strings.Contains(string(data), "p@ss"))
+
+	// A sealed secret can't be read as another one
+	_, err = stateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "s_cache", Body: entry.Body},
+		Metadata: metadata("default"),
+	})
+	assert.Nil(t, err)
+	_, err = provider.Read(context.Background(), "cache", "password", nil)
+	assert.Equal(t, v1alpha2.InternalError, err.(v1alpha2.COAError).State)
+
+	// Nor with another key
+	other := EncryptedSecretProvider{}
+	err = other.Init(EncryptedSecretProviderConfig{Key: base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))})
+	assert.Nil(t, err)
+	other.SetStateProvider(stateProvider)
+	_, err = other.Read(context.Background(), "db", "password", nil)
+	assert.Equal(t, v1alpha2.InternalError, err.(v1alpha2.COAError).State)
+}
+
+func TestManagerSetsStateProvider(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	provider := &EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{Key: testKey, StateProvider: "memory"})
+	assert.Nil(t, err)
+	assert.Nil(t, provider.StateProvider)
+
+	manager := managers.Manager{}
+	err = manager.Init(nil, managers.ManagerConfig{}, map[string]providers.IProvider{
+		"secret": provider,
+		"memory": stateProvider,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, stateProvider, provider.StateProvider)
+
+	provider.Config.StateProvider = "redis"
+	err = manager.Init(nil, managers.ManagerConfig{}, map[string]providers.IProvider{
+		"secret": provider,
+	})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &EncryptedSecretProvider{}
+	err := provider.Init(EncryptedSecretProviderConfig{Key: testKey})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filesecret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+type FileSecretProviderConfig struct {
+	Name string `json:"name"`
+	// Path is the directory of the secrets. Each secret is a subdirectory, with a file for each of its fields,
+	// which is how Kubernetes mounts secrets as volumes.
+	Path string `json:"path"`
+	// DisableReload stops the provider from watching the directory and reloading the secrets when they change
+	DisableReload bool `json:"disableReload,omitempty"`
+}
+
+func FileSecretProviderConfigFromMap(properties map[string]string) (FileSecretProviderConfig, error) {
+	ret := FileSecretProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["path"]; ok {
+		ret.Path = utils.ParseProperty(v)
+	}
+	if v, ok := properties["disableReload"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(v)
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'disableReload' setting of file secret provider", v1alpha2.BadConfig)
+		}
+		ret.DisableReload = bVal
+	}
+	return ret, nil
+}
+
+// FileSecretProvider reads secrets from a directory of files. The secrets are loaded when the provider is
+// initialized, and reloaded when the files change.
+type FileSecretProvider struct {
+	Config  FileSecretProviderConfig
+	Context *contexts.ManagerContext
+	lock    sync.RWMutex
+	secrets map[string]map[string]string
+	watcher *fsnotify.Watcher
+}
+
+func (i *FileSecretProvider) InitWithMap(properties map[string]string) error {
+	config, err := FileSecretProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (m *FileSecretProvider) ID() string {
+	return m.Config.Name
+}
+
+func (a *FileSecretProvider) SetContext(context *contexts.ManagerContext) {
+	a.Context = context
+}
+
+func (m *FileSecretProvider) Init(config providers.IProviderConfig) error {
+	aConfig, err := toFileSecretProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid file secret provider config", v1alpha2.BadConfig)
+	}
+	if aConfig.Path == "" {
+		return v1alpha2.NewCOAError(nil, "file secret provider path is not set", v1alpha2.BadConfig)
+	}
+	m.Config = aConfig
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+	if err = m.reload(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read secrets from '%s'", m.Config.Path), v1alpha2.BadConfig)
+	}
+	if m.Config.DisableReload {
+		return nil
+	}
+	m.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to watch secret files", v1alpha2.InternalError)
+	}
+	m.watchDirectories(m.watcher)
+	go m.watch(m.watcher)
+	return nil
+}
+
+func toFileSecretProviderConfig(config providers.IProviderConfig) (FileSecretProviderConfig, error) {
+	ret := FileSecretProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	ret.Name = utils.ParseProperty(ret.Name)
+	ret.Path = utils.ParseProperty(ret.Path)
+	return ret, err
+}
+
+// watch reloads the secrets on any change under the directory. Kubernetes updates a mounted secret by
+// swapping a symbolic link, so the events of individual files aren't reliable enough to reload only them.
+func (m *FileSecretProvider) watch(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if err := m.reload(); err != nil {
+				sLog.Errorf("  P (File Secret): failed to reload secrets from '%s', keeping the current secrets: %+v", m.Config.Path, err)
+				continue
+			}
+			m.watchDirectories(watcher)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			sLog.Errorf("  P (File Secret): failed to watch secret files: %+v", err)
+		}
+	}
+}
+
+// watchDirectories watches the directory and the directory of each secret, including the ones added since
+// the last reload. Directories that are removed drop out of the watch list by themselves.
+func (m *FileSecretProvider) watchDirectories(watcher *fsnotify.Watcher) {
+	dirs := []string{m.Config.Path}
+	m.lock.RLock()
+	for name := range m.secrets {
+		dirs = append(dirs, filepath.Join(m.Config.Path, name))
+	}
+	m.lock.RUnlock()
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			sLog.Errorf("  P (File Secret): failed to watch '%s': %+v", dir, err)
+		}
+	}
+}
+
+func (m *FileSecretProvider) reload() error {
+	secrets, err := readSecrets(m.Config.Path)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	m.secrets = secrets
+	m.lock.Unlock()
+	sLog.Debugf("  P (File Secret): loaded %d secrets from '%s'", len(secrets), m.Config.Path)
+	return nil
+}
+
+// readSecrets reads each subdirectory of a directory as a secret. Hidden entries, such as the ..data link of
+// a Kubernetes secret volume, are skipped.
+func readSecrets(path string) (map[string]map[string]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]map[string]string)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(path, entry.Name())
+		// Stat follows symbolic links, which mounted secrets are made of
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]string)
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), ".") {
+				continue
+			}
+			filePath := filepath.Join(dir, file.Name())
+			if info, err := os.Stat(filePath); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, err
+			}
+			fields[file.Name()] = string(data)
+		}
+		ret[entry.Name()] = fields
+	}
+	return ret, nil
+}
+
+// Read reads a field of a secret. A secret that doesn't exist reads as an empty value, as the conformance
+// suite expects.
+func (m *FileSecretProvider) Read(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	ctx, span := observability.StartSpan("File Secret Provider", ctx, &map[string]string{
+		"method": "Read",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	secret, ok := m.secrets[name]
+	if !ok {
+		sLog.InfofCtx(ctx, "  P (File Secret): secret %s is not found", name)
+		return "", nil
+	}
+	value, ok := secret[field]
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("field %s not found in secret %s", field, name), v1alpha2.MissingConfig)
+		sLog.ErrorfCtx(ctx, "  P (File Secret): %+v", err)
+		return "", err
+	}
+	return value, nil
+}
+
+func (m *FileSecretProvider) Get(ctx context.Context, name string, field string, localContext interface{}) (string, error) {
+	return m.Read(ctx, name, field, localContext)
+}
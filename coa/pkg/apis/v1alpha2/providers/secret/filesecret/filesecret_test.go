@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package filesecret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/conformance"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSecret(t *testing.T, path string, name string, fields map[string]string) {
+	dir := filepath.Join(path, name)
+	assert.Nil(t, os.MkdirAll(dir, 0700))
+	for field, value := range fields {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, field), []byte(value), 0600))
+	}
+}
+
+func TestInit(t *testing.T) {
+	provider := FileSecretProvider{}
+	err := provider.Init(FileSecretProviderConfig{})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(FileSecretProviderConfig{Path: filepath.Join(t.TempDir(), "missing")})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+	err = provider.Init(FileSecretProviderConfig{Path: t.TempDir()})
+	assert.Nil(t, err)
+}
+
+func TestInitWithMap(t *testing.T) {
+	provider := FileSecretProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":          "test",
+		"path":          t.TempDir(),
+		"disableReload": "true",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "test", provider.ID())
+	assert.True(t, provider.Config.DisableReload)
+
+	err = provider.InitWithMap(map[string]string{
+		"path":          t.TempDir(),
+		"disableReload": "sometimes",
+	})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestSetContext(t *testing.T) {
+	provider := FileSecretProvider{}
+	provider.SetContext(&contexts.ManagerContext{})
+	assert.NotNil(t, provider.Context)
+}
+
+func TestRead(t *testing.T) {
+	path := t.TempDir()
+	writeSecret(t, path, "db", map[string]string{"user": "admin", "password": "p@ss"})
+	writeSecret(t, path, ".hidden", map[string]string{"user": "nobody"})
+	provider := FileSecretProvider{}
+	err := provider.Init(FileSecretProviderConfig{Path: path, DisableReload: true})
+	assert.Nil(t, err)
+
+	val, err := provider.Read(context.Background(), "db", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", val)
+	val, err = provider.Get(context.Background(), "db", "user", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", val)
+
+	_, err = provider.Read(context.Background(), "db", "token", nil)
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+	val, err = provider.Read(context.Background(), ".hidden", "user", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestReload(t *testing.T) {
+	path := t.TempDir()
+	writeSecret(t, path, "db", map[string]string{"password": "old"})
+	provider := FileSecretProvider{}
+	err := provider.Init(FileSecretProviderConfig{Path: path})
+	assert.Nil(t, err)
+
+	writeSecret(t, path, "db", map[string]string{"password": "new"})
+	writeSecret(t, path, "api", map[string]string{"token": "abc"})
+	assert.Eventually(t, func() bool {
+		val, err := provider.Read(context.Background(), "db", "password", nil)
+		return err == nil && val == "new"
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		val, err := provider.Read(context.Background(), "api", "token", nil)
+		return err == nil && val == "abc"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Nil(t, os.RemoveAll(filepath.Join(path, "api")))
+	assert.Eventually(t, func() bool {
+		val, err := provider.Read(context.Background(), "api", "token", nil)
+		return err == nil && val == ""
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReloadSymlinkSwap(t *testing.T) {
+	// Kubernetes updates a mounted secret by pointing the ..data link to a new directory
+	path := t.TempDir()
+	dir := filepath.Join(path, "db")
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "..v1"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "..v1", "password"), []byte("old"), 0600))
+	assert.Nil(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	assert.Nil(t, os.Symlink(filepath.Join("..data", "password"), filepath.Join(dir, "password")))
+	provider := FileSecretProvider{}
+	err := provider.Init(FileSecretProviderConfig{Path: path})
+	assert.Nil(t, err)
+	val, err := provider.Read(context.Background(), "db", "password", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", val)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "..v2"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "..v2", "password"), []byte("new"), 0600))
+	assert.Nil(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	assert.Nil(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	assert.Eventually(t, func() bool {
+		val, err := provider.Read(context.Background(), "db", "password", nil)
+		return err == nil && val == "new"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestConformanceSuite(t *testing.T) {
+	provider := &FileSecretProvider{}
+	err := provider.Init(FileSecretProviderConfig{Path: t.TempDir()})
+	assert.Nil(t, err)
+	conformance.ConformanceSuite(t, provider)
+}
@@ -8,10 +8,17 @@ package secret
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 )
 
+const (
+	CurrentVersion  = "current"
+	PreviousVersion = "previous"
+)
+
 type ISecretProvider interface {
 	Init(config providers.IProviderConfig) error
 	Read(ctx context.Context, name string, field string, localContext interface{}) (string, error)
@@ -20,3 +27,40 @@ type ISecretProvider interface {
 type IExtSecretProvider interface {
 	Get(ctx context.Context, name string, field string, localContext interface{}) (string, error)
 }
+
+// ISecretWriter is a secret provider that secrets can be written to. Writing a secret adds a version of it,
+// which becomes the current version. The version it replaces stays valid for a grace period, so that what
+// still uses it can move to the new one.
+type ISecretWriter interface {
+	// Write adds a version of a secret with the given fields. The provider's default grace period is used
+	// when gracePeriod is 0.
+	Write(ctx context.Context, name string, fields map[string]string, gracePeriod time.Duration, localContext interface{}) (SecretVersion, error)
+	// Delete deletes a secret with all its versions
+	Delete(ctx context.Context, name string, localContext interface{}) error
+	// List lists the secrets with their valid versions, without their values
+	List(ctx context.Context, localContext interface{}) ([]SecretInfo, error)
+}
+
+type SecretVersion struct {
+	Version   int64  `json:"version"`
+	CreatedAt string `json:"createdAt"`
+	// ExpiresAt is when a replaced version stops being valid
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+type SecretInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	// Versions are the valid versions, the current one last
+	Versions []SecretVersion `json:"versions"`
+}
+
+// ParseVersion splits a secret name in the <name>:<version> form, where the version is a version number,
+// current or previous. The version is current when it isn't given.
+func ParseVersion(name string) (string, string) {
+	if i := strings.LastIndex(name, ":"); i > 0 && i < len(name)-1 && name[i-1] != ':' {
+		return name[:i], name[i+1:]
+	}
+	return name, CurrentVersion
+}
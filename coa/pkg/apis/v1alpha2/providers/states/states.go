@@ -32,6 +32,13 @@ type IStateProvider interface {
 	List(context.Context, ListRequest) ([]StateEntry, string, error)
 	SetContext(context *contexts.ManagerContext)
 }
+
+// IWithStateProvider is a provider that keeps its data in one of the state providers of its manager
+type IWithStateProvider interface {
+	// StateProviderName is the name of the state provider to use, if any
+	StateProviderName() string
+	SetStateProvider(provider IStateProvider)
+}
 type GetOption struct {
 	Consistency string `json:"consistency"` //eventual or strong
 }
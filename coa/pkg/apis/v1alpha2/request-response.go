@@ -7,6 +7,7 @@
 package v1alpha2
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -18,6 +19,11 @@ type ContextKey string
 
 const (
 	COAFastHTTPContextKey ContextKey = "coa-fasthttp-context"
+	// COAPeerCertificatesKey holds the []*x509.Certificate a client presented over TLS. Bindings don't verify
+	// them, so handlers need to before trusting them.
+	COAPeerCertificatesKey ContextKey = "coa-peer-certificates"
+	// COAUserKey holds the name of the user a request was authenticated as
+	COAUserKey ContextKey = "coa-user"
 )
 
 type COARequest struct {
@@ -226,6 +232,9 @@ type COAResponse struct {
 	State       State             `json:"state"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	RedirectUri string            `json:"redirectUri,omitempty"`
+	// BodyStream writes the body while the connection stays open, for long-lived streams. Bindings that
+	// support streaming use it instead of Body.
+	BodyStream func(w *bufio.Writer) `json:"-"`
 }
 
 func (c COAResponse) String() string {
@@ -16,4 +16,12 @@ type SiteConnection struct {
 	BaseUrl  string `json:"baseUrl"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// CertFile and KeyFile hold the client certificate the site authenticates to its parent with, once enrolled
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CAFile holds the CA certificate of the parent, which offline bundles from the parent are verified with. It's
+	// written when the site enrolls or rotates its certificate.
+	CAFile string `json:"caFile,omitempty"`
+	// JoinToken is the one-time token the site enrolls with when it doesn't have a certificate yet
+	JoinToken string `json:"joinToken,omitempty"`
 }
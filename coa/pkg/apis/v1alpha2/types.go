@@ -59,6 +59,7 @@ const (
 	Updated        State = 8004
 	Deleted        State = 8005
 	// Workflow status
+	Cancelled      State = 9993
 	Running        State = 9994
 	Paused         State = 9995
 	Done           State = 9996
@@ -131,6 +132,13 @@ const (
 	TargetGetFailed                 State = 10060
 	DeleteSolutionFailed            State = 10061
 	CreateSolutionFailed            State = 10062
+	ApplyConflict                   State = 10063
+	PruneResourceFailed             State = 10064
+	KustomizeRenderFailed           State = 10065
+	HelmChartTestFailed             State = 10066
+	OCIArtifactPullFailed           State = 10067
+	TargetPluginUnavailable         State = 10068
+	GitOperationFailed              State = 10069
 	GetARMDeploymentPropertyFailed  State = 10071
 	EnsureARMResourceGroupFailed    State = 10072
 	CreateARMDeploymentFailed       State = 10073
@@ -229,6 +237,8 @@ func (s State) String() string {
 		return "Updated"
 	case Deleted:
 		return "Deleted"
+	case Cancelled:
+		return "Cancelled"
 	case Running:
 		return "Running"
 	case Paused:
@@ -371,6 +381,20 @@ func (s State) String() string {
 		return "Target list does not exist"
 	case ObjectInstanceCoversionFailed:
 		return "Object to Instance conversion failed"
+	case ApplyConflict:
+		return "Apply Conflict"
+	case PruneResourceFailed:
+		return "Prune Resource Failed"
+	case KustomizeRenderFailed:
+		return "Kustomize Render Failed"
+	case HelmChartTestFailed:
+		return "Helm Chart Test Failed"
+	case OCIArtifactPullFailed:
+		return "OCI Artifact Pull Failed"
+	case TargetPluginUnavailable:
+		return "Target Plugin Unavailable"
+	case GitOperationFailed:
+		return "Git Operation Failed"
 	case TimedOut:
 		return "Timed Out"
 	case TargetPropertyNotFound:
@@ -390,6 +390,7 @@ type TaskSpec struct {
 type StageSpec struct {
 	Name     string `json:"name,omitempty"`
 	Contexts string `json:"contexts,omitempty"`
+	ForEach  string `json:"forEach,omitempty"`
 	Provider string `json:"provider,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
@@ -403,6 +404,8 @@ type StageSpec struct {
 	Target          string               `json:"target,omitempty"`
 	Tasks           []TaskSpec           `json:"tasks,omitempty"`
 	TaskOption      model.TaskOption     `json:"taskOption,omitempty"`
+	NextStages      []string             `json:"nextStages,omitempty"`
+	Join            *model.JoinSpec      `json:"join,omitempty"`
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for StageSpec
@@ -456,6 +459,9 @@ type ActivationSpec struct {
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
 	Inputs runtime.RawExtension `json:"inputs,omitempty"`
+	// State pauses, resumes or cancels a running activation
+	// +kubebuilder:validation:Enum=running;paused;cancelled
+	State string `json:"state,omitempty"`
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for ActivationSpec
@@ -495,6 +501,31 @@ type CampaignSpec struct {
 	SelfDriving  bool                 `json:"selfDriving,omitempty"`
 	Version      string               `json:"version,omitempty"`
 	RootResource string               `json:"rootResource,omitempty"`
+	// Notifications are sent on campaign-level events, such as an activation failing
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+	// Hooks are stages that run once an activation is finished
+	Hooks *CampaignHooks `json:"hooks,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type CampaignHooks struct {
+	OnSuccess []string `json:"onSuccess,omitempty"`
+	OnFailure []string `json:"onFailure,omitempty"`
+	Finally   []string `json:"finally,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type NotificationSpec struct {
+	Name string `json:"name,omitempty"`
+	// +kubebuilder:validation:items:Enum=success;failure;cancelled;finished
+	On       []string `json:"on,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Config runtime.RawExtension `json:"config,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Inputs runtime.RawExtension `json:"inputs,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
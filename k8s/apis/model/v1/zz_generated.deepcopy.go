@@ -45,6 +45,36 @@ func (in *CampaignContainerSpec) DeepCopy() *CampaignContainerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CampaignHooks) DeepCopyInto(out *CampaignHooks) {
+	*out = *in
+	if in.OnSuccess != nil {
+		in, out := &in.OnSuccess, &out.OnSuccess
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OnFailure != nil {
+		in, out := &in.OnFailure, &out.OnFailure
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Finally != nil {
+		in, out := &in.Finally, &out.Finally
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CampaignHooks.
+func (in *CampaignHooks) DeepCopy() *CampaignHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(CampaignHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CampaignSpec) DeepCopyInto(out *CampaignSpec) {
 	*out = *in
@@ -55,6 +85,18 @@ func (in *CampaignSpec) DeepCopyInto(out *CampaignSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(CampaignHooks)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CampaignSpec.
@@ -366,6 +408,28 @@ func (in *InstanceSpec) DeepCopy() *InstanceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationSpec) DeepCopyInto(out *NotificationSpec) {
+	*out = *in
+	if in.On != nil {
+		in, out := &in.On, &out.On
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Config.DeepCopyInto(&out.Config)
+	in.Inputs.DeepCopyInto(&out.Inputs)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationSpec.
+func (in *NotificationSpec) DeepCopy() *NotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReconciliationPolicySpec) DeepCopyInto(out *ReconciliationPolicySpec) {
 	*out = *in
@@ -459,6 +523,16 @@ func (in *StageSpec) DeepCopyInto(out *StageSpec) {
 		}
 	}
 	out.TaskOption = in.TaskOption
+	if in.NextStages != nil {
+		in, out := &in.NextStages, &out.NextStages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Join != nil {
+		in, out := &in.Join, &out.Join
+		*out = new(model.JoinSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageSpec.
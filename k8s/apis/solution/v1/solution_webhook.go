@@ -88,10 +88,13 @@ func (r *Solution) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	uniqueNameSolutionLookupFunc := func(ctx context.Context, displayName string, namespace string) (interface{}, error) {
 		return dynamicclient.GetObjectWithUniqueName(ctx, validation.Solution, displayName, namespace)
 	}
+	catalogLookupFunc := func(ctx context.Context, name string, namespace string) (interface{}, error) {
+		return dynamicclient.Get(ctx, validation.Catalog, name, namespace)
+	}
 	if projectConfig.UniqueDisplayNameForSolution {
-		solutionValidator = validation.NewSolutionValidator(solutionInstanceLookupFunc, solutionContainerLookupFunc, uniqueNameSolutionLookupFunc)
+		solutionValidator = validation.NewSolutionValidator(solutionInstanceLookupFunc, solutionContainerLookupFunc, uniqueNameSolutionLookupFunc, catalogLookupFunc)
 	} else {
-		solutionValidator = validation.NewSolutionValidator(solutionInstanceLookupFunc, solutionContainerLookupFunc, nil)
+		solutionValidator = validation.NewSolutionValidator(solutionInstanceLookupFunc, solutionContainerLookupFunc, nil, catalogLookupFunc)
 	}
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
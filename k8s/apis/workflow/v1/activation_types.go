@@ -23,8 +23,10 @@ type ActivationStatus struct {
 }
 
 type StageStatus struct {
-	Stage     string `json:"stage,omitempty"`
-	NextStage string `json:"nextStage,omitempty"`
+	Stage      string   `json:"stage,omitempty"`
+	NextStage  string   `json:"nextStage,omitempty"`
+	NextStages []string `json:"nextStages,omitempty"`
+	Branch     string   `json:"branch,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
 	Inputs runtime.RawExtension `json:"inputs,omitempty"`
@@ -35,6 +37,8 @@ type StageStatus struct {
 	StatusMessage string               `json:"statusMessage,omitempty"`
 	ErrorMessage  string               `json:"errorMessage,omitempty"`
 	IsActive      bool                 `json:"isActive,omitempty"`
+	// Hook is set when the stage runs as a lifecycle hook of the activation
+	Hook string `json:"hook,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -230,6 +230,11 @@ func (in *CampaignStatus) DeepCopy() *CampaignStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StageStatus) DeepCopyInto(out *StageStatus) {
 	*out = *in
+	if in.NextStages != nil {
+		in, out := &in.NextStages, &out.NextStages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Inputs.DeepCopyInto(&out.Inputs)
 	in.Outputs.DeepCopyInto(&out.Outputs)
 }
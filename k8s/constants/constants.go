@@ -26,6 +26,8 @@ const (
 	OperationId              = "operationId"
 	DeleteTimeout            = "delete-timeout"
 
+	AppliedStateKey = FullGroupName + "/appliedState" // activation
+
 	SolutionContainerOperationNamePrefix = "solutioncontainers.solution." + FullGroupName
 	SolutionOperationNamePrefix          = "solutions.solution." + FullGroupName
 	TargetOperationNamePrefix            = "targets.fabric." + FullGroupName
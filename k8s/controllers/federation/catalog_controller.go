@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -62,8 +63,16 @@ func (r *CatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 	ctx = configutils.PopulateActivityAndDiagnosticsContextFromAnnotations(catalog.GetNamespace(), resourceK8SId, catalog.GetAnnotations(), operationName, r, ctx, ctrlLog)
 	if err := r.Client.Get(ctx, req.NamespacedName, catalog); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The catalog is deleted, which is synced to child sites
+			if err := r.ApiClient.CatalogDeleteHook(ctx, req.Name, req.Namespace, "", ""); err != nil {
+				diagnostic.ErrorWithCtx(ctrlLog, ctx, err, "unable to delete Catalog when calling catalogHook")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
 		diagnostic.ErrorWithCtx(ctrlLog, ctx, err, "unable to fetch Catalog")
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return ctrl.Result{}, err
 	}
 
 	if catalog.ObjectMeta.DeletionTimestamp.IsZero() { // update
@@ -13,6 +13,7 @@ import (
 	"strconv"
 
 	api_constants "github.com/eclipse-symphony/symphony/api/constants"
+	api_model "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -92,6 +93,23 @@ func (r *ActivationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				diagnostic.ErrorWithCtx(log, ctx, err, "unable to publish activation event")
 				return ctrl.Result{}, err
 			}
+		} else if needsControl(activation) {
+			diagnostic.InfoWithCtx(log, ctx, "Controlling activation", "Name", activation.Name, "Namespace", activation.Namespace, "State", activation.Spec.State)
+			err := r.ApiClient.ControlActivation(ctx, activation.Name, activation.Namespace, activation.Spec.State, "", "")
+			if err != nil {
+				diagnostic.ErrorWithCtx(log, ctx, err, "unable to control activation")
+				return ctrl.Result{}, err
+			}
+			// Record the state that was applied, so that the next change of the spec is forwarded as well
+			patch := client.MergeFrom(activation.DeepCopy())
+			if activation.Annotations == nil {
+				activation.Annotations = make(map[string]string)
+			}
+			activation.Annotations[constants.AppliedStateKey] = desiredState(activation)
+			if err := r.Patch(ctx, activation, patch); err != nil {
+				diagnostic.ErrorWithCtx(log, ctx, err, "unable to record the applied state of activation")
+				return ctrl.Result{}, err
+			}
 		}
 	} else {
 		diagnostic.InfoWithCtx(log, ctx, "Deleting activation", "name", activation.ObjectMeta.Name, "namespace", activation.ObjectMeta.Namespace)
@@ -101,6 +119,27 @@ func (r *ActivationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// needsControl checks if the state in the spec of a started activation differs from the last state that
+// was applied to it
+func needsControl(activation *workflowv1.Activation) bool {
+	if activation.Status.Status == v1alpha2.Done || activation.Status.Status == v1alpha2.Cancelled {
+		return false
+	}
+	applied := activation.Annotations[constants.AppliedStateKey]
+	if applied == "" {
+		applied = api_model.ActivationStateRunning
+	}
+	return desiredState(activation) != applied
+}
+
+// desiredState returns the state in the spec of an activation, an empty state is the same as running
+func desiredState(activation *workflowv1.Activation) string {
+	if activation.Spec.State == "" {
+		return api_model.ActivationStateRunning
+	}
+	return activation.Spec.State
+}
+
 func convertRawExtensionToMap(raw *runtime.RawExtension) map[string]interface{} {
 	if raw == nil {
 		return nil
@@ -307,6 +307,12 @@ func (c *MockApiClient) QueueDeploymentJob(ctx context.Context, namespace string
 	return args.Error(0)
 }
 
+// ControlActivation implements ApiClient.
+func (c *MockApiClient) ControlActivation(ctx context.Context, activation string, namespace string, state string, user string, password string) error {
+	args := c.Called(ctx, activation, namespace, state)
+	return args.Error(0)
+}
+
 // QueueJob implements ApiClient.
 // Deprecated and not used.
 func (c *MockApiClient) QueueJob(ctx context.Context, id string, scope string, isDelete bool, isTarget bool, user string, password string) error {